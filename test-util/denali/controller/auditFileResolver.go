@@ -0,0 +1,40 @@
+package denalicontroller
+
+import (
+	fr "github.com/numbatx/gn-vm-util/test-util/denali/json/fileresolver"
+)
+
+var _ fr.FileResolver = (*auditFileResolver)(nil)
+
+// auditFileResolver records all files loaded by the wrapped resolver in the audit log.
+type auditFileResolver struct {
+	fr.FileResolver
+	auditLog *AuditLog
+}
+
+// Clone creates new instance of the same type.
+func (afr *auditFileResolver) Clone() fr.FileResolver {
+	return &auditFileResolver{
+		FileResolver: afr.FileResolver.Clone(),
+		auditLog:     afr.auditLog,
+	}
+}
+
+// ResolveFileValue converts a value prefixed with "file:" and replaces it with the file contents.
+func (afr *auditFileResolver) ResolveFileValue(value string) ([]byte, error) {
+	content, err := afr.FileResolver.ResolveFileValue(value)
+	if len(value) == 0 {
+		return content, err
+	}
+	entry := &AuditEntry{
+		Event: AuditFileRead,
+		Path:  afr.FileResolver.ResolveAbsolutePath(value),
+	}
+	if err != nil {
+		entry.Error = err.Error()
+	} else {
+		entry.ContentHash = contentHash(content)
+	}
+	_ = afr.auditLog.Record(entry)
+	return content, err
+}
@@ -0,0 +1,173 @@
+package denalicontroller
+
+import (
+	"bufio"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"sync"
+	"time"
+)
+
+// AuditEvent identifies the kind of action recorded in the audit log.
+type AuditEvent string
+
+const (
+	// AuditScenarioParsed is recorded after a scenario file was read and parsed.
+	AuditScenarioParsed AuditEvent = "scenarioParsed"
+
+	// AuditFileRead is recorded whenever the file resolver loads a "file:" value.
+	AuditFileRead AuditEvent = "fileRead"
+
+	// AuditStepDispatched is recorded by executors, via the execution context, before running a step.
+	AuditStepDispatched AuditEvent = "stepDispatched"
+
+	// AuditScenarioResult is recorded after a scenario ran, or failed to parse.
+	AuditScenarioResult AuditEvent = "scenarioResult"
+
+	// AuditScenarioSkipped is recorded for scenarios excluded from a directory run.
+	AuditScenarioSkipped AuditEvent = "scenarioSkipped"
+)
+
+// AuditEntry is a single line of the audit log.
+type AuditEntry struct {
+	Seq   int        `json:"seq"`
+	Time  time.Time  `json:"time"`
+	Event AuditEvent `json:"event"`
+
+	// Path is the scenario file, or the resolved file for AuditFileRead.
+	Path string `json:"path,omitempty"`
+
+	// ContentHash is the hex encoded sha256 of the file contents, to detect fixtures changing between runs.
+	ContentHash string `json:"contentHash,omitempty"`
+
+	StepIndex *int   `json:"stepIndex,omitempty"`
+	StepType  string `json:"stepType,omitempty"`
+	NrSteps   int    `json:"nrSteps,omitempty"`
+
+	Status ScenarioStatus `json:"status,omitempty"`
+	Error  string         `json:"error,omitempty"`
+}
+
+// AuditLog writes an append-only log of everything the runner does, one JSON object per line (NDJSON).
+// All methods can be called on a nil *AuditLog, in which case nothing gets recorded.
+type AuditLog struct {
+	mutex  sync.Mutex
+	writer io.Writer
+	closer io.Closer
+	seq    int
+	err    error
+}
+
+// NewAuditLog creates an audit log that writes to the given writer.
+func NewAuditLog(writer io.Writer) *AuditLog {
+	return &AuditLog{
+		writer: writer,
+	}
+}
+
+// OpenAuditLogFile creates an audit log that appends to a file, creating it if needed.
+func OpenAuditLogFile(path string) (*AuditLog, error) {
+	file, err := os.OpenFile(path, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0644)
+	if err != nil {
+		return nil, err
+	}
+	return &AuditLog{
+		writer: file,
+		closer: file,
+	}, nil
+}
+
+// Close closes the underlying file, if the log was opened with OpenAuditLogFile.
+func (al *AuditLog) Close() error {
+	if al == nil || al.closer == nil {
+		return nil
+	}
+	return al.closer.Close()
+}
+
+// Record appends an entry to the log, filling in its sequence number and timestamp.
+func (al *AuditLog) Record(entry *AuditEntry) error {
+	if al == nil {
+		return nil
+	}
+	al.mutex.Lock()
+	defer al.mutex.Unlock()
+
+	al.seq++
+	entry.Seq = al.seq
+	if entry.Time.IsZero() {
+		entry.Time = time.Now().UTC()
+	}
+	line, err := json.Marshal(entry)
+	if err != nil {
+		return err
+	}
+	_, err = al.writer.Write(append(line, '\n'))
+	if err != nil && al.err == nil {
+		al.err = err
+	}
+	return err
+}
+
+// Err yields the first error encountered while writing the log.
+// The runner does not fail scenarios because of audit log errors, so callers should check it at the end.
+func (al *AuditLog) Err() error {
+	if al == nil {
+		return nil
+	}
+	al.mutex.Lock()
+	defer al.mutex.Unlock()
+	return al.err
+}
+
+// RecordStepDispatched is meant for executors, to log each step just before running it.
+func (al *AuditLog) RecordStepDispatched(scenarioPath string, stepIndex int, stepType string) error {
+	return al.Record(&AuditEntry{
+		Event:     AuditStepDispatched,
+		Path:      scenarioPath,
+		StepIndex: &stepIndex,
+		StepType:  stepType,
+	})
+}
+
+// ReadAuditLog parses all entries of an audit log.
+func ReadAuditLog(reader io.Reader) ([]*AuditEntry, error) {
+	var entries []*AuditEntry
+	scanner := bufio.NewScanner(reader)
+	scanner.Buffer(make([]byte, 64*1024), 16*1024*1024)
+	lineNr := 0
+	for scanner.Scan() {
+		lineNr++
+		if len(scanner.Bytes()) == 0 {
+			continue
+		}
+		entry := &AuditEntry{}
+		if err := json.Unmarshal(scanner.Bytes(), entry); err != nil {
+			return nil, fmt.Errorf("invalid audit log entry on line %d: %w", lineNr, err)
+		}
+		entries = append(entries, entry)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	return entries, nil
+}
+
+// ReadAuditLogFile parses all entries of an audit log file.
+func ReadAuditLogFile(path string) ([]*AuditEntry, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+	return ReadAuditLog(file)
+}
+
+func contentHash(content []byte) string {
+	hash := sha256.Sum256(content)
+	return hex.EncodeToString(hash[:])
+}
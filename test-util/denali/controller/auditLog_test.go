@@ -0,0 +1,130 @@
+package denalicontroller
+
+import (
+	"bytes"
+	"errors"
+	"io/ioutil"
+	"path/filepath"
+	"testing"
+
+	fr "github.com/numbatx/gn-vm-util/test-util/denali/json/fileresolver"
+	mj "github.com/numbatx/gn-vm-util/test-util/denali/json/model"
+	"github.com/stretchr/testify/require"
+)
+
+type auditTestExecutor struct {
+	fail map[string]bool
+}
+
+func (e *auditTestExecutor) Reset() {}
+
+func (e *auditTestExecutor) ExecuteScenario(scenario *mj.Scenario, _ fr.FileResolver) error {
+	if e.fail[scenario.Name] {
+		return errors.New("scenario failed")
+	}
+	return nil
+}
+
+func (e *auditTestExecutor) ExecuteScenarioWithContext(scenario *mj.Scenario, ctx *ExecutionContext) error {
+	for stepIndex, step := range scenario.Steps {
+		ctx.StepDispatched(stepIndex, step)
+	}
+	return e.ExecuteScenario(scenario, ctx.FileResolver)
+}
+
+func writeAuditTestScenario(t *testing.T, dir string, name string, code string) string {
+	require.Nil(t, ioutil.WriteFile(filepath.Join(dir, name+".code"), []byte(code), 0644))
+	scenarioPath := filepath.Join(dir, name+".scen.json")
+	scenarioJSON := `{
+		"name": "` + name + `",
+		"steps": [
+			{
+				"step": "setState",
+				"accounts": {
+					"address:owner": {
+						"code": "file:` + name + `.code"
+					}
+				}
+			}
+		]
+	}`
+	require.Nil(t, ioutil.WriteFile(scenarioPath, []byte(scenarioJSON), 0644))
+	return scenarioPath
+}
+
+func TestAuditLogAndReplay(t *testing.T) {
+	dir := t.TempDir()
+	pathA := writeAuditTestScenario(t, dir, "a", "code a")
+	pathB := writeAuditTestScenario(t, dir, "b", "code b")
+
+	executor := &auditTestExecutor{fail: map[string]bool{"b": true}}
+	runner := NewScenarioRunner(executor, NewDefaultFileResolver())
+	var logBuffer bytes.Buffer
+	runner.EnableAuditLog(NewAuditLog(&logBuffer))
+
+	require.Nil(t, runner.RunSingleJSONScenario(pathA))
+	require.NotNil(t, runner.RunSingleJSONScenario(pathB))
+	require.Nil(t, runner.AuditLog.Err())
+
+	entries, err := ReadAuditLog(&logBuffer)
+	require.Nil(t, err)
+	var events []AuditEvent
+	for i, entry := range entries {
+		require.Equal(t, i+1, entry.Seq)
+		events = append(events, entry.Event)
+	}
+	require.Equal(t, []AuditEvent{
+		AuditFileRead, AuditScenarioParsed, AuditStepDispatched, AuditScenarioResult,
+		AuditFileRead, AuditScenarioParsed, AuditStepDispatched, AuditScenarioResult,
+	}, events)
+	require.Equal(t, filepath.Join(dir, "a.code"), entries[0].Path)
+	require.Equal(t, 0, *entries[2].StepIndex)
+	require.Equal(t, "setState", entries[2].StepType)
+	require.Equal(t, ScenarioPassed, entries[3].Status)
+	require.Equal(t, ScenarioFailed, entries[7].Status)
+	require.Equal(t, "scenario failed", entries[7].Error)
+
+	// same outcome
+	replayRunner := NewScenarioRunner(executor, NewDefaultFileResolver())
+	require.Empty(t, replayRunner.ReplayAuditLog(entries))
+
+	// "b" got fixed, in the meantime its code changed
+	executor.fail = map[string]bool{}
+	require.Nil(t, ioutil.WriteFile(filepath.Join(dir, "b.code"), []byte("fixed code b"), 0644))
+	mismatches := replayRunner.ReplayAuditLog(entries)
+	require.Equal(t, 1, len(mismatches))
+	require.Equal(t, pathB, mismatches[0].Path)
+	require.Equal(t, ScenarioFailed, mismatches[0].RecordedStatus)
+	require.Equal(t, ScenarioPassed, mismatches[0].ReplayedStatus)
+	require.Equal(t, []string{filepath.Join(dir, "b.code")}, mismatches[0].ChangedFiles)
+}
+
+func TestReplayAuditLogFile(t *testing.T) {
+	dir := t.TempDir()
+	pathA := writeAuditTestScenario(t, dir, "a", "code a")
+	logPath := filepath.Join(dir, "audit.ndjson")
+
+	executor := &auditTestExecutor{}
+	auditLog, err := OpenAuditLogFile(logPath)
+	require.Nil(t, err)
+	runner := NewScenarioRunner(executor, NewDefaultFileResolver())
+	runner.EnableAuditLog(auditLog)
+	require.Nil(t, runner.RunSingleJSONScenario(pathA))
+	require.Nil(t, auditLog.Close())
+
+	replayRunner := NewScenarioRunner(executor, NewDefaultFileResolver())
+	require.Nil(t, replayRunner.ReplayAuditLogFile(logPath))
+
+	executor.fail = map[string]bool{"a": true}
+	err = replayRunner.ReplayAuditLogFile(logPath)
+	require.NotNil(t, err)
+	require.Contains(t, err.Error(), "recorded pass, replayed fail")
+}
+
+func TestNilAuditLog(t *testing.T) {
+	var auditLog *AuditLog
+	require.Nil(t, auditLog.Record(&AuditEntry{Event: AuditScenarioResult}))
+	require.Nil(t, auditLog.RecordStepDispatched("a.scen.json", 0, "setState"))
+	require.Nil(t, auditLog.Err())
+	require.Nil(t, auditLog.Close())
+}
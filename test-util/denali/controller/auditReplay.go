@@ -0,0 +1,105 @@
+package denalicontroller
+
+import (
+	"fmt"
+	"io/ioutil"
+	"sort"
+	"strings"
+)
+
+// ReplayMismatch describes a scenario whose replayed outcome differs from the one in the audit log.
+type ReplayMismatch struct {
+	Path           string
+	RecordedStatus ScenarioStatus
+	ReplayedStatus ScenarioStatus
+	RecordedError  string
+	ReplayedError  string
+
+	// ChangedFiles lists the scenario file and fixtures whose contents changed since the recorded run,
+	// a likely explanation for the mismatch.
+	ChangedFiles []string
+}
+
+// String yields a human-readable description of the mismatch.
+func (m *ReplayMismatch) String() string {
+	var sb strings.Builder
+	sb.WriteString(fmt.Sprintf("%s: recorded %s, replayed %s", m.Path, m.RecordedStatus, m.ReplayedStatus))
+	if len(m.RecordedError) > 0 {
+		sb.WriteString(fmt.Sprintf("\n  recorded error: %s", m.RecordedError))
+	}
+	if len(m.ReplayedError) > 0 {
+		sb.WriteString(fmt.Sprintf("\n  replayed error: %s", m.ReplayedError))
+	}
+	if len(m.ChangedFiles) > 0 {
+		sb.WriteString(fmt.Sprintf("\n  changed since recorded: %s", strings.Join(m.ChangedFiles, ", ")))
+	}
+	return sb.String()
+}
+
+// ReplayAuditLog re-executes all scenarios recorded in an audit log, in the original order,
+// and yields the ones whose outcome differs from the recorded one.
+// Skipped scenarios are not replayed.
+func (r *ScenarioRunner) ReplayAuditLog(entries []*AuditEntry) []*ReplayMismatch {
+	var mismatches []*ReplayMismatch
+	recordedHashes := make(map[string]string)
+	for _, entry := range entries {
+		switch entry.Event {
+		case AuditFileRead, AuditScenarioParsed:
+			if len(entry.ContentHash) > 0 {
+				recordedHashes[entry.Path] = entry.ContentHash
+			}
+		case AuditScenarioResult:
+			r.Executor.Reset()
+			replayErr := r.RunSingleJSONScenario(entry.Path)
+			replayedStatus := ScenarioPassed
+			replayedError := ""
+			if replayErr != nil {
+				replayedStatus = ScenarioFailed
+				replayedError = replayErr.Error()
+			}
+			if replayedStatus != entry.Status {
+				mismatches = append(mismatches, &ReplayMismatch{
+					Path:           entry.Path,
+					RecordedStatus: entry.Status,
+					ReplayedStatus: replayedStatus,
+					RecordedError:  entry.Error,
+					ReplayedError:  replayedError,
+					ChangedFiles:   changedFiles(recordedHashes),
+				})
+			}
+			recordedHashes = make(map[string]string)
+		}
+	}
+	return mismatches
+}
+
+// ReplayAuditLogFile re-executes the scenarios of an audit log file, see ReplayAuditLog.
+// Returns an error if any of the outcomes differ.
+func (r *ScenarioRunner) ReplayAuditLogFile(auditLogPath string) error {
+	entries, err := ReadAuditLogFile(auditLogPath)
+	if err != nil {
+		return err
+	}
+	mismatches := r.ReplayAuditLog(entries)
+	if len(mismatches) == 0 {
+		return nil
+	}
+	descriptions := make([]string, len(mismatches))
+	for i, mismatch := range mismatches {
+		descriptions[i] = mismatch.String()
+	}
+	return fmt.Errorf("%d scenario(s) replayed with a different outcome:\n%s",
+		len(mismatches), strings.Join(descriptions, "\n"))
+}
+
+func changedFiles(recordedHashes map[string]string) []string {
+	var changed []string
+	for path, recordedHash := range recordedHashes {
+		content, err := ioutil.ReadFile(path)
+		if err != nil || contentHash(content) != recordedHash {
+			changed = append(changed, path)
+		}
+	}
+	sort.Strings(changed)
+	return changed
+}
@@ -0,0 +1,172 @@
+package denalicontroller
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+
+	mj "github.com/numbatx/gn-vm-util/test-util/denali/json/model"
+	vi "github.com/numbatx/gn-vm-util/test-util/denali/json/valueinterpreter"
+	oj "github.com/numbatx/gn-vm-util/test-util/orderedjson"
+)
+
+const (
+	ansiReset = "\x1b[0m"
+	ansiRed   = "\x1b[31m"
+	ansiGreen = "\x1b[32m"
+	ansiBold  = "\x1b[1m"
+)
+
+// CheckError is a failed check of a transaction result.
+// Besides the first mismatch, it holds every compared value, so that failures can be rendered as a table.
+type CheckError struct {
+	TxIdent string
+
+	// Summary describes the first mismatch, it is the error message.
+	Summary string
+
+	Rows []*CheckRow
+}
+
+// CheckRow is a compared value. Values are formatted as Denali expressions.
+type CheckRow struct {
+	Field    string
+	Expected string
+	Actual   string
+	Mismatch bool
+}
+
+// Error yields the first mismatch, on a single line.
+func (ce *CheckError) Error() string {
+	return ce.Summary
+}
+
+func (ce *CheckError) addRow(field string, expected string, actual string, mismatch bool) {
+	ce.Rows = append(ce.Rows, &CheckRow{
+		Field:    field,
+		Expected: expected,
+		Actual:   actual,
+		Mismatch: mismatch,
+	})
+}
+
+// setSummary only keeps the first mismatch.
+func (ce *CheckError) setSummary(summary string) {
+	if len(ce.Summary) == 0 {
+		ce.Summary = summary
+	}
+}
+
+// expectedText yields an expected value as written in the scenario, or formatted, if written as a JSON subtree.
+func (ctx *ExecutionContext) expectedText(expected mj.JSONCheckBytes) string {
+	if expected.Matcher != nil {
+		return expected.Matcher.String()
+	}
+	if str, isStr := expected.Original.(*oj.OJsonString); isStr && (expected.IsStar || len(str.Value) > 0) {
+		return str.Value
+	}
+	return ctx.Pretty(expected.Value)
+}
+
+// RenderOptions configures RenderError.
+type RenderOptions struct {
+	// Color enables ANSI colors, only for terminals.
+	Color bool
+}
+
+// RenderError describes an error over multiple lines, for terminals and test output:
+// check failures as aligned expected/actual columns, with mismatching rows marked,
+// and values that could not be interpreted with the failing sub-expression underlined.
+// Other errors are rendered as their message.
+func RenderError(err error, options RenderOptions) string {
+	if err == nil {
+		return ""
+	}
+	var sb strings.Builder
+	sb.WriteString(err.Error())
+
+	var checkErr *CheckError
+	if errors.As(err, &checkErr) {
+		renderCheckRows(&sb, checkErr.Rows, options)
+	}
+
+	var valueErr *vi.ValueError
+	if errors.As(err, &valueErr) {
+		renderValueError(&sb, valueErr, options)
+	}
+	return sb.String()
+}
+
+func renderCheckRows(sb *strings.Builder, rows []*CheckRow, options RenderOptions) {
+	fieldWidth, expectedWidth := len("field"), len("expected")
+	for _, row := range rows {
+		fieldWidth = maxInt(fieldWidth, len(row.Field))
+		expectedWidth = maxInt(expectedWidth, len(row.Expected))
+	}
+
+	fmt.Fprintf(sb, "\n  %s  %s  %s",
+		colorize(padRight("field", fieldWidth), ansiBold, options),
+		colorize(padRight("expected", expectedWidth), ansiBold, options),
+		colorize("actual", ansiBold, options))
+	for _, row := range rows {
+		marker := " "
+		expected := padRight(row.Expected, expectedWidth)
+		actual := row.Actual
+		if row.Mismatch {
+			marker = "!"
+			expected = colorize(expected, ansiGreen, options)
+			actual = colorize(actual, ansiRed, options)
+		}
+		fmt.Fprintf(sb, "\n%s %s  %s  %s", marker, padRight(row.Field, fieldWidth), expected, actual)
+	}
+}
+
+func renderValueError(sb *strings.Builder, valueErr *vi.ValueError, options RenderOptions) {
+	offset := strings.Index(valueErr.Expression, valueErr.SubExpression)
+	if len(valueErr.Expression) == 0 || len(valueErr.SubExpression) == 0 || offset < 0 {
+		return
+	}
+	end := offset + len(valueErr.SubExpression)
+	fmt.Fprintf(sb, "\n  %s%s%s",
+		valueErr.Expression[:offset],
+		colorize(valueErr.Expression[offset:end], ansiRed, options),
+		valueErr.Expression[end:])
+	fmt.Fprintf(sb, "\n  %s%s", strings.Repeat(" ", offset), strings.Repeat("^", end-offset))
+}
+
+func colorize(text string, color string, options RenderOptions) string {
+	if !options.Color {
+		return text
+	}
+	return color + text + ansiReset
+}
+
+func padRight(text string, width int) string {
+	if len(text) >= width {
+		return text
+	}
+	return text + strings.Repeat(" ", width-len(text))
+}
+
+func maxInt(a, b int) int {
+	if a > b {
+		return a
+	}
+	return b
+}
+
+// TestingT is the part of *testing.T used by RequireScenarioPasses.
+type TestingT interface {
+	Helper()
+	Fatalf(format string, args ...interface{})
+}
+
+// RequireScenarioPasses runs a scenario file and fails the test if the scenario fails,
+// with the error rendered by RenderError.
+func (r *ScenarioRunner) RequireScenarioPasses(t TestingT, scenarioPath string) {
+	t.Helper()
+	err := r.RunSingleJSONScenario(scenarioPath)
+	if err != nil {
+		t.Fatalf("scenario %s failed:\n%s", scenarioPath, RenderError(err, RenderOptions{}))
+	}
+}
@@ -0,0 +1,70 @@
+package denalicontroller
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestRenderCheckError(t *testing.T) {
+	err := runViewScenario(t, "getSum", "6")
+	var checkErr *CheckError
+	require.True(t, errors.As(err, &checkErr))
+	require.Equal(t, []*CheckRow{
+		{Field: "status", Expected: "0", Actual: "0"},
+		{Field: "out[0]", Expected: "6", Actual: "5", Mismatch: true},
+	}, checkErr.Rows)
+
+	rendered := RenderError(err, RenderOptions{})
+	lines := strings.Split(rendered, "\n")
+	require.Equal(t, err.Error(), lines[0])
+	require.Equal(t, []string{
+		"  field   expected  actual",
+		"  status  0         0",
+		"! out[0]  6         5",
+	}, lines[1:])
+
+	colored := RenderError(err, RenderOptions{Color: true})
+	require.Contains(t, colored, ansiRed+"5"+ansiReset)
+	require.NotContains(t, rendered, "\x1b[")
+}
+
+func TestRenderValueError(t *testing.T) {
+	err := runViewScenario(t, "getSum", "str:a|u8:256")
+	require.NotNil(t, err)
+	lines := strings.Split(RenderError(err, RenderOptions{}), "\n")
+	require.Equal(t, 3, len(lines))
+	require.Equal(t, "  str:a|u8:256", lines[1])
+	require.Equal(t, "        ^^^^^^", lines[2])
+
+	require.Equal(t, "plain", RenderError(errors.New("plain"), RenderOptions{}))
+	require.Equal(t, "", RenderError(nil, RenderOptions{}))
+}
+
+type fatalRecorder struct {
+	message string
+}
+
+func (f *fatalRecorder) Helper() {}
+
+func (f *fatalRecorder) Fatalf(format string, args ...interface{}) {
+	f.message = fmt.Sprintf(format, args...)
+}
+
+func TestRequireScenarioPasses(t *testing.T) {
+	scenarioJSON := strings.ReplaceAll(strings.ReplaceAll(viewScenarioTemplate, "FUNCTION", "getSum"), "OUT", "6")
+	scenarioPath := filepath.Join(t.TempDir(), "view.scen.json")
+	require.Nil(t, os.WriteFile(scenarioPath, []byte(scenarioJSON), 0644))
+
+	executor := &storageQueryExecutor{}
+	executor.Reset()
+	runner := NewViewScenarioRunner(executor, NewDefaultFileResolver())
+	recorder := &fatalRecorder{}
+	runner.RequireScenarioPasses(recorder, scenarioPath)
+	require.Contains(t, recorder.message, "! out[0]  6         5")
+}
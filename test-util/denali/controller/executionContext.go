@@ -0,0 +1,348 @@
+package denalicontroller
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math/big"
+	"strings"
+	"sync"
+	"time"
+
+	abi "github.com/numbatx/gn-vm-util/test-util/denali/json/abi"
+	fr "github.com/numbatx/gn-vm-util/test-util/denali/json/fileresolver"
+	mj "github.com/numbatx/gn-vm-util/test-util/denali/json/model"
+	vi "github.com/numbatx/gn-vm-util/test-util/denali/json/valueinterpreter"
+	oj "github.com/numbatx/gn-vm-util/test-util/orderedjson"
+)
+
+// ExecutionContext groups everything the runner provides to an executor, besides the scenario itself.
+type ExecutionContext struct {
+	// FileResolver helps with resolving external steps.
+	FileResolver fr.FileResolver
+
+	// Formatter prints values in the canonical human-readable Denali dialect.
+	// Its alias table is pre-populated with the addresses used in the scenario.
+	Formatter *vi.ValueFormatter
+
+	// ABI is the ABI referenced by the scenario, if any.
+	ABI *abi.ABI
+
+	// GasSchedule is the gas schedule the scenario pins, nil if it does not, in which case
+	// executors use their default schedule. The runner already handed it to executors implementing
+	// GasScheduleExecutor, and failed the scenario if it checks gas and the executor cannot apply it.
+	GasSchedule *mj.GasScheduleReference
+
+	// Config holds the defaults of the scenario, nil if it has none. The parser already filled in
+	// the transaction fields they stand for, executors apply the rest, e.g. the chain ID.
+	Config *mj.ScenarioConfig
+
+	// ValueInterpreter interprets values the way the parser did for this scenario,
+	// i.e. knowing its constants, gas presets and ABI.
+	ValueInterpreter *vi.ValueInterpreter
+
+	// ScenarioPath is the absolute path of the scenario file.
+	ScenarioPath string
+
+	// AuditLog can be used by executors to record the steps they run. It is nil if auditing is disabled,
+	// but can be used regardless.
+	AuditLog *AuditLog
+
+	// Context is canceled once the scenario times out, see ScenarioRunner.ScenarioTimeout and StepTimeout.
+	// Executors should then stop running the scenario, and pass it on to whatever can block.
+	Context context.Context
+
+	// timeouts, if set, times the steps reported through StepDispatched.
+	timeouts *scenarioTimeouts
+
+	// mutex guards what the executor reports, since executors that time out keep running in the background.
+	mutex sync.Mutex
+
+	// outputs collects the results reported by the executor, for the run report.
+	outputs []*StepOutput
+
+	// warnings collects the tolerated setState failures, for the run report.
+	warnings []string
+
+	// stepsExecuted counts the steps reported through StepDispatched, for the run report.
+	// lastStepIndex is the index of the last one, the step that failed if the scenario fails.
+	stepsExecuted int
+	lastStepIndex int
+
+	// hooks are the StepHooks of the runner. stepStartedAt and stepResult describe the step running, for them.
+	hooks         []StepHook
+	stepStartedAt time.Time
+	stepResult    *QueryResult
+
+	// invariants are checked by CheckInvariants, invariantSums holds the initial sums, by invariant name.
+	invariants    []*mj.Invariant
+	invariantSums map[string]*big.Int
+}
+
+// StepDispatched is meant for executors, to call just before running each step.
+// It counts the step for the run report, records it in the audit log, if enabled,
+// and calls the step hooks of the runner, see StepDone.
+func (ctx *ExecutionContext) StepDispatched(stepIndex int, step mj.Step) {
+	ctx.timeouts.stepStarted(stepIndex, step)
+	ctx.mutex.Lock()
+	ctx.stepsExecuted++
+	ctx.lastStepIndex = stepIndex
+	ctx.stepStartedAt = time.Now()
+	ctx.stepResult = nil
+	ctx.mutex.Unlock()
+	_ = ctx.AuditLog.RecordStepDispatched(ctx.ScenarioPath, stepIndex, step.StepTypeName())
+	ctx.beforeStep(stepIndex, step)
+}
+
+// SetStateError is a failure of a single setState operation, one of the mj.SetStateOperation... constants.
+// Executors return it for operations that scenarios can declare best-effort, through "allowedErrors".
+type SetStateError struct {
+	Operation string
+	Err       error
+}
+
+// Error yields the operation and the cause.
+func (e *SetStateError) Error() string {
+	return fmt.Sprintf("setState %s failed: %s", e.Operation, e.Err)
+}
+
+// Unwrap yields the cause.
+func (e *SetStateError) Unwrap() error {
+	return e.Err
+}
+
+// HandleSetStateError lets the executor tolerate the failures that the step allows.
+// Allowed failures are recorded as warnings of the run report, and nil is returned.
+// Other errors are returned as they are.
+func (ctx *ExecutionContext) HandleSetStateError(stepIndex int, step *mj.SetStateStep, err error) error {
+	var setStateErr *SetStateError
+	if err == nil || !errors.As(err, &setStateErr) || !step.IsErrorAllowed(setStateErr.Operation) {
+		return err
+	}
+	ctx.mutex.Lock()
+	defer ctx.mutex.Unlock()
+	ctx.warnings = append(ctx.warnings, fmt.Sprintf("%s: allowed error: %s", mj.DescribeStep(stepIndex, step), err))
+	return nil
+}
+
+// RecordStepOutput lets the executor report what a transaction step returned,
+// so that the run report includes it, even if all checks pass.
+func (ctx *ExecutionContext) RecordStepOutput(stepIndex int, step *mj.TxStep, result *QueryResult) {
+	ctx.mutex.Lock()
+	defer ctx.mutex.Unlock()
+	ctx.outputs = append(ctx.outputs, newStepOutput(stepIndex, step, result))
+	ctx.stepResult = result
+}
+
+// Pretty formats a value, to be used by executors when constructing error messages.
+func (ctx *ExecutionContext) Pretty(value []byte) string {
+	return ctx.Formatter.Format(value)
+}
+
+// FormatOutMismatch describes the difference between an expected and an actual "out" value.
+// Values expected as typed literals, e.g. "abi:MyStruct:{...}", are decoded through the ABI,
+// so that the differences are shown field by field, instead of as raw bytes.
+func (ctx *ExecutionContext) FormatOutMismatch(expected mj.JSONCheckBytes, actual []byte) string {
+	if expected.Matcher != nil {
+		return fmt.Sprintf("expected %s, actual %s", expected.Matcher, ctx.Pretty(actual))
+	}
+	if expectedStr, isStr := expected.Original.(*oj.OJsonString); isStr {
+		if typeName, _, isTyped := abi.ParseLiteral(expectedStr.Value); isTyped {
+			expectedDecoded, expectedErr := ctx.ABI.DecodeTopLevel(typeName, expected.Value, ctx.Pretty)
+			actualDecoded, actualErr := ctx.ABI.DecodeTopLevel(typeName, actual, ctx.Pretty)
+			if expectedErr == nil && actualErr == nil {
+				return fmt.Sprintf("%s mismatch: %s", typeName,
+					strings.Join(abi.Diff(expectedDecoded, actualDecoded), "; "))
+			}
+			if expectedErr == nil {
+				return fmt.Sprintf("expected %s, actual value cannot be decoded as %s (%s): %s",
+					expectedDecoded, typeName, actualErr, ctx.Pretty(actual))
+			}
+		}
+	}
+	return fmt.Sprintf("expected %s, actual %s", ctx.Pretty(expected.Value), ctx.Pretty(actual))
+}
+
+// Explain describes, as an indented tree, how a value from the scenario was interpreted.
+// Executors can add it to error messages when a check fails, see ValueInterpreter.ExplainString.
+func (ctx *ExecutionContext) Explain(expression string) string {
+	explanation, _ := ctx.ValueInterpreter.ExplainString(expression)
+	return explanation.String()
+}
+
+// ScenarioContextExecutor is a ScenarioExecutor that can also receive the full execution context.
+// The runner prefers ExecuteScenarioWithContext whenever the executor implements it.
+type ScenarioContextExecutor interface {
+	ScenarioExecutor
+
+	// ExecuteScenarioWithContext executes the scenario and checks if it passed.
+	// Failure is signaled by returning an error.
+	ExecuteScenarioWithContext(*mj.Scenario, *ExecutionContext) error
+}
+
+// NewExecutionContext creates the execution context for a scenario.
+func NewExecutionContext(scenario *mj.Scenario, fileResolver fr.FileResolver) *ExecutionContext {
+	return &ExecutionContext{
+		FileResolver:     fileResolver,
+		ABI:              scenario.ABI,
+		GasSchedule:      scenario.GasSchedule,
+		Config:           scenario.Config,
+		Formatter:        newScenarioFormatter(scenario),
+		ValueInterpreter: newScenarioInterpreter(&vi.ValueInterpreter{FileResolver: fileResolver}, scenario),
+		Context:          context.Background(),
+		invariants:       scenario.Invariants,
+	}
+}
+
+// newScenarioInterpreter yields an interpreter that also knows the values declared by the scenario,
+// on top of the ones configured for the whole suite.
+func newScenarioInterpreter(suite *vi.ValueInterpreter, scenario *mj.Scenario) *vi.ValueInterpreter {
+	interpreter := &vi.ValueInterpreter{
+		FileResolver: suite.FileResolver,
+		Seed:         suite.Seed,
+		PercentScale: suite.PercentScale,
+		ABI:          scenario.ABI,
+	}
+	if scenario.Config != nil && len(scenario.Config.Seed) > 0 {
+		interpreter.Seed = scenario.Config.Seed
+	}
+	for name, value := range suite.Constants {
+		interpreter.SetConstant(name, value)
+	}
+	for _, constant := range scenario.Constants {
+		interpreter.SetConstant(constant.Name, constant.Value.Value)
+	}
+	for name, value := range suite.Defines {
+		interpreter.SetDefine(name, value)
+	}
+	for _, parameter := range scenario.Parameters {
+		interpreter.SetDefine(parameter.Name, parameter.Value.Value)
+	}
+	for _, define := range scenario.Defines {
+		interpreter.SetDefine(define.Name, define.Value.Value)
+	}
+	for name, value := range suite.GasPresets {
+		interpreter.SetGasPreset(name, value)
+	}
+	for _, preset := range scenario.GasPresets {
+		interpreter.SetGasPreset(preset.Name, preset.Value.Value)
+	}
+	return interpreter
+}
+
+func newScenarioFormatter(scenario *mj.Scenario) *vi.ValueFormatter {
+	formatter := vi.NewValueFormatter()
+	addAddressAlias := func(address mj.JSONBytesFromString) {
+		formatter.AddAlias(address.Value, address.Original)
+	}
+	for _, generalStep := range scenario.Steps {
+		switch step := generalStep.(type) {
+		case *mj.SetStateStep:
+			for _, account := range step.Accounts {
+				addAddressAlias(account.Address)
+			}
+			for _, newAddressMock := range step.NewAddressMocks {
+				addAddressAlias(newAddressMock.CreatorAddress)
+				addAddressAlias(newAddressMock.NewAddress)
+			}
+		case *mj.CheckStateStep:
+			for _, checkAccount := range step.CheckAccounts.Accounts {
+				addAddressAlias(checkAccount.Address)
+			}
+		case *mj.TxStep:
+			addAddressAlias(step.Tx.From)
+			addAddressAlias(step.Tx.To)
+		}
+	}
+	return formatter
+}
+
+func (r *ScenarioRunner) executeScenario(scenarioPath string, scenario *mj.Scenario) (err error) {
+	defer recoverExecutorPanic(&err)
+	if len(r.contextPaths) <= 1 {
+		r.scenarioName = scenario.Name
+	}
+	if !r.isSelected(scenario) {
+		return errScenarioNotSelected
+	}
+	// only the scenarios that run are seeded, after the filters
+	err = r.seedStateOfOutermost()
+	if err != nil {
+		return err
+	}
+	err = r.applyGasSchedule(scenario)
+	if err != nil {
+		return err
+	}
+	fileResolver := r.Parser.ValueInterpreter.FileResolver
+	if contextExecutor, hasContext := r.Executor.(ScenarioContextExecutor); hasContext {
+		ctx := NewExecutionContext(scenario, fileResolver)
+		ctx.ScenarioPath = scenarioPath
+		ctx.ValueInterpreter = newScenarioInterpreter(&r.Parser.ValueInterpreter, scenario)
+		ctx.AuditLog = r.AuditLog
+		ctx.hooks = r.StepHooks
+		defer r.collectStepOutputs(ctx)
+		defer r.keepLastStep(scenario, ctx)
+		err = r.runWithTimeouts(ctx, func() error {
+			return contextExecutor.ExecuteScenarioWithContext(scenario, ctx)
+		})
+		if err == nil && ctx.stepsExecuted == 0 {
+			// the executor does not report its steps
+			ctx.stepsExecuted = len(scenario.Steps)
+		}
+		return err
+	}
+	err = r.runWithTimeouts(nil, func() error {
+		return r.Executor.ExecuteScenario(scenario, fileResolver)
+	})
+	if err == nil {
+		r.stepsExecuted += len(scenario.Steps)
+	}
+	return err
+}
+
+// keepLastStep remembers the type of the last dispatched step of the outermost scenario, and where it is,
+// see StepDispatched. If the scenario fails, that is the step that failed,
+// or the externalSteps step including the failing scenario.
+func (r *ScenarioRunner) keepLastStep(scenario *mj.Scenario, ctx *ExecutionContext) {
+	ctx.mutex.Lock()
+	defer ctx.mutex.Unlock()
+	isOutermost := len(r.contextPaths) > 0 && ctx.ScenarioPath == r.contextPaths[0]
+	if !isOutermost || ctx.stepsExecuted == 0 || ctx.lastStepIndex >= len(scenario.Steps) {
+		return
+	}
+	r.lastStepType = scenario.Steps[ctx.lastStepIndex].StepTypeName()
+	r.lastStepIndex = ctx.lastStepIndex
+	if ctx.lastStepIndex >= len(scenario.StepPositions) {
+		return
+	}
+	position := scenario.StepPositions[ctx.lastStepIndex]
+	if position.Line > 0 {
+		r.lastStep = &SourceLocation{
+			Path:   ctx.ScenarioPath,
+			Line:   position.Line,
+			Column: position.Column,
+		}
+	}
+}
+
+// collectStepOutputs keeps the outputs, warnings and step count reported while running a scenario, for its report.
+// Those of included scenarios are kept together with the ones of the including scenario.
+func (r *ScenarioRunner) collectStepOutputs(ctx *ExecutionContext) {
+	ctx.mutex.Lock()
+	defer ctx.mutex.Unlock()
+	r.stepsExecuted += ctx.stepsExecuted
+	isIncluded := len(r.contextPaths) > 0 && ctx.ScenarioPath != r.contextPaths[0]
+	for _, output := range ctx.outputs {
+		if isIncluded {
+			output.Path = ctx.ScenarioPath
+		}
+		r.stepOutputs = append(r.stepOutputs, output)
+	}
+	for _, warning := range ctx.warnings {
+		if isIncluded {
+			warning = ctx.ScenarioPath + ": " + warning
+		}
+		r.stepWarnings = append(r.stepWarnings, warning)
+	}
+}
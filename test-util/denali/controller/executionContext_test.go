@@ -0,0 +1,68 @@
+package denalicontroller
+
+import (
+	"testing"
+
+	abi "github.com/numbatx/gn-vm-util/test-util/denali/json/abi"
+	mj "github.com/numbatx/gn-vm-util/test-util/denali/json/model"
+	vi "github.com/numbatx/gn-vm-util/test-util/denali/json/valueinterpreter"
+	oj "github.com/numbatx/gn-vm-util/test-util/orderedjson"
+	"github.com/stretchr/testify/require"
+)
+
+func TestFormatOutMismatch(t *testing.T) {
+	scenarioABI, err := abi.LoadABI([]byte(`{
+		"types": {
+			"Pair": {
+				"type": "struct",
+				"fields": [
+					{"name": "first", "type": "u32"},
+					{"name": "second", "type": "u32"}
+				]
+			}
+		}
+	}`))
+	require.Nil(t, err)
+	ctx := NewExecutionContext(&mj.Scenario{ABI: scenarioABI}, nil)
+
+	interpreter := vi.ValueInterpreter{ABI: scenarioABI}
+	expectedStr := "abi:Pair:{first: 1, second: 2}"
+	expectedValue, err := interpreter.InterpretString(expectedStr)
+	require.Nil(t, err)
+	expected := mj.JSONCheckBytes{
+		Value:    expectedValue,
+		Original: &oj.OJsonString{Value: expectedStr},
+	}
+
+	actual := []byte{0, 0, 0, 1, 0, 0, 0, 3}
+	require.Equal(t, "Pair mismatch: second: expected 2, actual 3", ctx.FormatOutMismatch(expected, actual))
+
+	require.Equal(t,
+		"expected Pair{first: 1, second: 2}, actual value cannot be decoded as Pair (field second: cannot decode u32 from 2 bytes): 0x00000001ffff",
+		ctx.FormatOutMismatch(expected, []byte{0, 0, 0, 1, 0xff, 0xff}))
+
+	untyped := mj.JSONCheckBytes{
+		Value:    []byte{5},
+		Original: &oj.OJsonString{Value: "5"},
+	}
+	require.Equal(t, "expected 5, actual 6", ctx.FormatOutMismatch(untyped, []byte{6}))
+}
+
+func TestExplainUsesScenarioValues(t *testing.T) {
+	scenario := &mj.Scenario{
+		Constants: []*mj.NamedConstant{
+			{Name: "OWNER", Value: mj.JSONBytesFromTree{Value: []byte("owner")}},
+		},
+	}
+	runner := NewScenarioRunner(nil, NewDefaultFileResolver())
+	runner.Parser.ValueInterpreter.SetConstant("SUITE", []byte{0x07})
+	ctx := NewExecutionContext(scenario, nil)
+	ctx.ValueInterpreter = newScenarioInterpreter(&runner.Parser.ValueInterpreter, scenario)
+
+	require.Equal(t, `"const:OWNER|u8:const:SUITE" concat = 0x6f776e657207
+  "const:OWNER" const = 0x6f776e6572
+  "u8:const:SUITE" fixed-width = 0x07
+    "const:SUITE" const = 0x07`, ctx.Explain("const:OWNER|u8:const:SUITE"))
+
+	require.Equal(t, `"const:OTHER" const error: unknown constant: OTHER`, ctx.Explain("const:OTHER"))
+}
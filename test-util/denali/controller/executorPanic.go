@@ -0,0 +1,36 @@
+package denalicontroller
+
+import (
+	"fmt"
+	"runtime/debug"
+)
+
+// ExecutorPanicError is returned instead of crashing when the executor panics while running a scenario.
+type ExecutorPanicError struct {
+	// Value is the value the executor panicked with.
+	Value interface{}
+
+	// Stack is the stack trace of the panicking goroutine.
+	Stack string
+}
+
+// Error yields the panic value, without the stack trace.
+func (e *ExecutorPanicError) Error() string {
+	return fmt.Sprintf("executor panicked: %v", e.Value)
+}
+
+// recoverExecutorPanic converts a panic into an *ExecutorPanicError. Must be deferred.
+func recoverExecutorPanic(err *error) {
+	if recovered := recover(); recovered != nil {
+		*err = &ExecutorPanicError{
+			Value: recovered,
+			Stack: string(debug.Stack()),
+		}
+	}
+}
+
+func (r *ScenarioRunner) resetExecutor() (err error) {
+	defer recoverExecutorPanic(&err)
+	r.Executor.Reset()
+	return nil
+}
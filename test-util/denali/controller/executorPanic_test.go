@@ -0,0 +1,95 @@
+package denalicontroller
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+
+	fr "github.com/numbatx/gn-vm-util/test-util/denali/json/fileresolver"
+	mj "github.com/numbatx/gn-vm-util/test-util/denali/json/model"
+	"github.com/stretchr/testify/require"
+)
+
+type panickingExecutor struct {
+	executed []string
+}
+
+func (e *panickingExecutor) Reset() {}
+
+func (e *panickingExecutor) ExecuteScenario(scenario *mj.Scenario, _ fr.FileResolver) error {
+	e.executed = append(e.executed, scenario.Name)
+	switch scenario.Name {
+	case "panics":
+		var accounts map[string]int
+		accounts["x"]++ // nil map
+	case "fails":
+		return errors.New("check failed")
+	}
+	return nil
+}
+
+func writePanicTestScenarios(t *testing.T) string {
+	dir := t.TempDir()
+	for _, name := range []string{"a_passes", "b_panics", "c_fails"} {
+		scenarioJSON := `{"name": "` + name[2:] + `", "steps": []}`
+		require.Nil(t, os.WriteFile(filepath.Join(dir, name+".scen.json"), []byte(scenarioJSON), 0644))
+	}
+	return dir
+}
+
+func TestExecutorPanicStopsRun(t *testing.T) {
+	dir := writePanicTestScenarios(t)
+	executor := &panickingExecutor{}
+	runner := NewScenarioRunner(executor, NewDefaultFileResolver())
+
+	report, err := runner.RunAllJSONScenariosInDirectoryWithReport(dir, "", ".scen.json", nil)
+	require.NotNil(t, err)
+	var panicErr *ExecutorPanicError
+	require.True(t, errors.As(err, &panicErr))
+	require.Contains(t, panicErr.Stack, "panickingExecutor")
+	require.Equal(t, []string{"passes", "panics"}, executor.executed)
+
+	require.Equal(t, 2, len(report.Scenarios))
+	require.Equal(t, ScenarioPassed, report.Scenarios[0].Status)
+	require.Equal(t, ScenarioFailed, report.Scenarios[1].Status)
+	require.Contains(t, report.Scenarios[1].Error, "executor panicked")
+	require.Contains(t, report.Scenarios[1].StackTrace, "panickingExecutor")
+}
+
+func TestExecutorPanicContinueOnError(t *testing.T) {
+	dir := writePanicTestScenarios(t)
+	executor := &panickingExecutor{}
+	runner := NewScenarioRunner(executor, NewDefaultFileResolver())
+	runner.ContinueOnError = true
+
+	report, err := runner.RunAllJSONScenariosInDirectoryWithReport(dir, "", ".scen.json", nil)
+	require.NotNil(t, err)
+	require.Equal(t, []string{"passes", "panics", "fails"}, executor.executed)
+
+	require.Equal(t, 3, len(report.Scenarios))
+	require.Equal(t, ScenarioFailed, report.Scenarios[1].Status)
+	require.NotEmpty(t, report.Scenarios[1].StackTrace)
+	require.Equal(t, ScenarioFailed, report.Scenarios[2].Status)
+	require.Equal(t, "check failed", report.Scenarios[2].Error)
+	require.Empty(t, report.Scenarios[2].StackTrace)
+}
+
+type panickingResetExecutor struct {
+	recordingExecutor
+}
+
+func (e *panickingResetExecutor) Reset() {
+	panic("cannot reset")
+}
+
+func TestExecutorPanicOnResetFromCleanState(t *testing.T) {
+	executor := &panickingResetExecutor{}
+	runner := NewScenarioRunner(executor, NewDefaultFileResolver())
+
+	err := runner.runFromCleanState(filepath.Join(t.TempDir(), "a.scen.json"), &mj.Scenario{Name: "a"})
+	var panicErr *ExecutorPanicError
+	require.True(t, errors.As(err, &panicErr))
+	require.Equal(t, "cannot reset", panicErr.Value)
+	require.Empty(t, executor.executed)
+}
@@ -0,0 +1,144 @@
+package denalicontroller
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	fr "github.com/numbatx/gn-vm-util/test-util/denali/json/fileresolver"
+	mj "github.com/numbatx/gn-vm-util/test-util/denali/json/model"
+	"github.com/stretchr/testify/require"
+)
+
+// externalStepsExecutor runs externalSteps the usual way, by calling back into the runner.
+type externalStepsExecutor struct {
+	runner        *ScenarioRunner
+	resolvedPaths []string
+	codes         []string
+	balances      []int64
+}
+
+func (e *externalStepsExecutor) Reset() {}
+
+func (e *externalStepsExecutor) ExecuteScenario(scenario *mj.Scenario, fileResolver fr.FileResolver) error {
+	for _, generalStep := range scenario.Steps {
+		switch step := generalStep.(type) {
+		case *mj.ExternalStepsStep:
+			e.resolvedPaths = append(e.resolvedPaths, step.ResolvedPath)
+			err := e.runner.RunExternalSteps(step)
+			if err != nil {
+				return err
+			}
+		case *mj.SetStateStep:
+			for _, account := range step.Accounts {
+				e.codes = append(e.codes, string(account.Code.Value))
+				e.balances = append(e.balances, account.Balance.Value.Int64())
+			}
+		}
+	}
+	return nil
+}
+
+func writeExternalStepsScenario(t *testing.T, path string, steps string) {
+	require.Nil(t, os.MkdirAll(filepath.Dir(path), os.ModePerm))
+	require.Nil(t, os.WriteFile(path, []byte(`{"steps": [`+steps+`]}`), 0644))
+}
+
+func setStateWithCode(code string) string {
+	return `{"step": "setState", "accounts": {"address:a": {
+		"nonce": "0", "balance": "0", "storage": {}, "code": "file:` + code + `"}}}`
+}
+
+func TestNestedExternalStepsResolveRelativeToIncludingFile(t *testing.T) {
+	dir := t.TempDir()
+	writeExternalStepsScenario(t, filepath.Join(dir, "main.scen.json"),
+		`{"step": "externalSteps", "path": "sub/inner.scen.json"},
+		{"step": "externalSteps", "path": "other.scen.json"}`)
+	writeExternalStepsScenario(t, filepath.Join(dir, "sub", "inner.scen.json"),
+		`{"step": "externalSteps", "path": "deeper/innermost.scen.json"},`+setStateWithCode("code.txt"))
+	writeExternalStepsScenario(t, filepath.Join(dir, "sub", "deeper", "innermost.scen.json"),
+		setStateWithCode("code.txt"))
+	writeExternalStepsScenario(t, filepath.Join(dir, "other.scen.json"),
+		setStateWithCode("code.txt"))
+	require.Nil(t, os.WriteFile(filepath.Join(dir, "code.txt"), []byte("main dir"), 0644))
+	require.Nil(t, os.WriteFile(filepath.Join(dir, "sub", "code.txt"), []byte("sub dir"), 0644))
+	require.Nil(t, os.WriteFile(filepath.Join(dir, "sub", "deeper", "code.txt"), []byte("deeper dir"), 0644))
+
+	executor := &externalStepsExecutor{}
+	runner := NewScenarioRunner(executor, NewDefaultFileResolver())
+	executor.runner = runner
+
+	err := runner.RunSingleJSONScenario(filepath.Join(dir, "main.scen.json"))
+	require.Nil(t, err)
+	require.Equal(t, []string{
+		filepath.Join(dir, "sub", "inner.scen.json"),
+		filepath.Join(dir, "sub", "deeper", "innermost.scen.json"),
+		filepath.Join(dir, "other.scen.json"),
+	}, executor.resolvedPaths)
+	require.Equal(t, []string{"deeper dir", "sub dir", "main dir"}, executor.codes)
+	require.Empty(t, runner.contextPaths)
+}
+
+func TestParseErrorInIncludedScenarioNamesItsFile(t *testing.T) {
+	dir := t.TempDir()
+	writeExternalStepsScenario(t, filepath.Join(dir, "main.scen.json"),
+		`{"step": "externalSteps", "path": "sub/inner.scen.json"}`)
+	innerPath := filepath.Join(dir, "sub", "inner.scen.json")
+	writeExternalStepsScenario(t, innerPath, `{"step": "setState"}, {"step": "unknownStep"}`)
+
+	runner := NewScenarioRunner(nil, NewDefaultFileResolver())
+	runner.Executor = &externalStepsExecutor{runner: runner}
+	err := runner.RunSingleJSONScenario(filepath.Join(dir, "main.scen.json"))
+	require.NotNil(t, err)
+	require.Contains(t, err.Error(), innerPath+":1:34: steps[1]: ")
+}
+
+func TestExternalStepsArguments(t *testing.T) {
+	dir := t.TempDir()
+	require.Nil(t, os.WriteFile(filepath.Join(dir, "main.scen.json"), []byte(`{
+		"defines": {"amount": "1,000"},
+		"steps": [
+			{"step": "externalSteps", "path": "fund.steps.json", "arguments": {"amount": "$amount"}},
+			{"step": "externalSteps", "path": "fund.steps.json", "arguments": {"amount": "5"}}
+		]
+	}`), 0644))
+	require.Nil(t, os.WriteFile(filepath.Join(dir, "fund.steps.json"), []byte(`{
+		"parameters": ["amount"],
+		"steps": [
+			{"step": "setState", "accounts": {"address:user": {"balance": "$amount"}}}
+		]
+	}`), 0644))
+
+	executor := &externalStepsExecutor{}
+	runner := NewScenarioRunner(executor, NewDefaultFileResolver())
+	executor.runner = runner
+
+	err := runner.RunSingleJSONScenario(filepath.Join(dir, "main.scen.json"))
+	require.Nil(t, err)
+	require.Equal(t, []int64{1000, 5}, executor.balances)
+
+	// libraries cannot run on their own
+	err = runner.RunSingleJSONScenario(filepath.Join(dir, "fund.steps.json"))
+	require.NotNil(t, err)
+	require.Contains(t, err.Error(), "missing argument for parameter amount")
+}
+
+func TestRunScenarioBuiltInCode(t *testing.T) {
+	dir := t.TempDir()
+	writeExternalStepsScenario(t, filepath.Join(dir, "setup.steps.json"), setStateWithCode("code.txt"))
+	require.Nil(t, os.WriteFile(filepath.Join(dir, "code.txt"), []byte("main dir"), 0644))
+
+	executor := &externalStepsExecutor{}
+	runner := NewScenarioRunner(executor, NewDefaultFileResolver())
+	executor.runner = runner
+
+	scenario := &mj.Scenario{
+		Name:  "built in code",
+		Steps: []mj.Step{&mj.ExternalStepsStep{Path: "setup.steps.json"}},
+	}
+	err := runner.RunScenario(filepath.Join(dir, "generated_test.go"), scenario)
+	// resolved by the runner, relative to the context path, since there was no parser to do it
+	require.Nil(t, err)
+	require.Equal(t, []string{"main dir"}, executor.codes)
+	require.Empty(t, runner.contextPaths)
+}
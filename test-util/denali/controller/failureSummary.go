@@ -0,0 +1,108 @@
+package denalicontroller
+
+import (
+	"fmt"
+	"io"
+	"regexp"
+	"sort"
+	"strings"
+)
+
+// FailureGroup gathers the scenarios of a run that failed the same way, see FailureSignature.
+type FailureGroup struct {
+	Signature string            `json:"signature"`
+	Scenarios []*ScenarioReport `json:"scenarios"`
+}
+
+var (
+	// stepDescription matches what mj.DescribeStep yields, the ids of steps differ from scenario to scenario
+	stepDescription = regexp.MustCompile(`step [0-9]+( \([^)]*\))?`)
+
+	// signatureValues matches the values in error messages, which differ even when the cause is the same
+	signatureValues = regexp.MustCompile(`0x[0-9a-fA-F]+|[0-9]+`)
+)
+
+// FailureSignature identifies how a scenario failed: the type of the step that failed, if known,
+// followed by the error message, without the scenario path, step indexes and ids, and numbers.
+// Scenarios broken by the same change, e.g. of the VM, tend to share it.
+func FailureSignature(scenarioReport *ScenarioReport) string {
+	message := strings.ReplaceAll(scenarioReport.Error, scenarioFilePath(scenarioReport.Path), "<scenario>")
+	message = stepDescription.ReplaceAllString(message, "step #")
+	message = signatureValues.ReplaceAllString(message, "#")
+	if len(scenarioReport.FailedStepType) > 0 {
+		return scenarioReport.FailedStepType + ": " + message
+	}
+	return message
+}
+
+// FailureGroups groups the failed scenarios by their FailureSignature, the largest groups first.
+// Scenarios are listed in the order they ran.
+func (r *RunReport) FailureGroups() []*FailureGroup {
+	var groups []*FailureGroup
+	groupsBySignature := make(map[string]*FailureGroup)
+	for _, scenarioReport := range r.Scenarios {
+		if scenarioReport.Status != ScenarioFailed {
+			continue
+		}
+		signature := FailureSignature(scenarioReport)
+		group, found := groupsBySignature[signature]
+		if !found {
+			group = &FailureGroup{Signature: signature}
+			groupsBySignature[signature] = group
+			groups = append(groups, group)
+		}
+		group.Scenarios = append(group.Scenarios, scenarioReport)
+	}
+	sort.SliceStable(groups, func(i, j int) bool {
+		return len(groups[i].Scenarios) > len(groups[j].Scenarios)
+	})
+	return groups
+}
+
+// FailureSummaryReportSink prints the failures of the run grouped by signature, see RunReport.FailureGroups,
+// so that mass breakages can be triaged cause by cause, rather than scenario by scenario.
+// Meant to be combined with ContinueOnError, so that all scenarios run.
+type FailureSummaryReportSink struct {
+	writer   io.Writer
+	basePath string
+
+	// MaxScenarios limits how many scenarios are listed for each failure, 5 by default, 0 for all.
+	MaxScenarios int
+}
+
+// NewFailureSummaryReportSink creates a sink that prints the summary to the given writer, at the end of the run.
+// Scenario paths are printed relative to basePath.
+func NewFailureSummaryReportSink(writer io.Writer, basePath string) *FailureSummaryReportSink {
+	return &FailureSummaryReportSink{
+		writer:       writer,
+		basePath:     basePath,
+		MaxScenarios: 5,
+	}
+}
+
+// ScenarioDone does nothing, the summary is only printed once the run is complete.
+func (s *FailureSummaryReportSink) ScenarioDone(_ *ScenarioReport) error {
+	return nil
+}
+
+// RunDone prints a section per failure signature, the number of scenarios first, followed by some of them.
+func (s *FailureSummaryReportSink) RunDone(report *RunReport) error {
+	groups := report.FailureGroups()
+	if len(groups) == 0 {
+		return nil
+	}
+	var sb strings.Builder
+	fmt.Fprintf(&sb, "Failures: %d scenarios, %d distinct causes.\n", report.Count(ScenarioFailed), len(groups))
+	for _, group := range groups {
+		fmt.Fprintf(&sb, "\n%d x %s\n", len(group.Scenarios), group.Signature)
+		for i, scenarioReport := range group.Scenarios {
+			if s.MaxScenarios > 0 && i == s.MaxScenarios {
+				fmt.Fprintf(&sb, "    ... and %d more\n", len(group.Scenarios)-i)
+				break
+			}
+			fmt.Fprintf(&sb, "    %s\n", shortenTestPath(scenarioReport.Path, s.basePath))
+		}
+	}
+	_, err := io.WriteString(s.writer, sb.String())
+	return err
+}
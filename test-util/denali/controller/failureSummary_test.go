@@ -0,0 +1,60 @@
+package denalicontroller
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestFailureSignature(t *testing.T) {
+	signature := FailureSignature(&ScenarioReport{
+		Path:           "/dir/a.scen.json#2",
+		Error:          "/dir/a.scen.json: step 3 (deposit): wrong out[0]: expected 0x0a, actual 12",
+		FailedStepType: "scCall",
+	})
+	require.Equal(t, "scCall: <scenario>: step #: wrong out[#]: expected #, actual #", signature)
+}
+
+func TestFailureSummaryReportSink(t *testing.T) {
+	report := &RunReport{}
+	for i, scenarioError := range []string{
+		"step 1: wrong status: expected 0, actual 4",
+		"",
+		"invalid scenario",
+		"step 2 (swap): wrong status: expected 0, actual 10",
+		"step 4: wrong status: expected 0, actual 4",
+	} {
+		scenarioReport := &ScenarioReport{
+			Path:   "/dir/" + string(rune('a'+i)) + ".scen.json",
+			Status: ScenarioFailed,
+			Error:  scenarioError,
+		}
+		if len(scenarioError) == 0 {
+			scenarioReport.Status = ScenarioPassed
+		}
+		report.Scenarios = append(report.Scenarios, scenarioReport)
+	}
+
+	groups := report.FailureGroups()
+	require.Equal(t, 2, len(groups))
+	require.Equal(t, "step #: wrong status: expected #, actual #", groups[0].Signature)
+	require.Equal(t, 3, len(groups[0].Scenarios))
+
+	var out bytes.Buffer
+	sink := NewFailureSummaryReportSink(&out, "/dir")
+	sink.MaxScenarios = 2
+	require.Nil(t, sink.RunDone(report))
+	require.Equal(t, `Failures: 4 scenarios, 2 distinct causes.
+
+3 x step #: wrong status: expected #, actual #
+    a.scen.json
+    d.scen.json
+    ... and 1 more
+
+1 x invalid scenario
+    c.scen.json
+`, out.String())
+
+	require.Nil(t, NewFailureSummaryReportSink(&out, "/dir").RunDone(&RunReport{}))
+}
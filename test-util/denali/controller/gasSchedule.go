@@ -0,0 +1,44 @@
+package denalicontroller
+
+import (
+	"fmt"
+
+	mj "github.com/numbatx/gn-vm-util/test-util/denali/json/model"
+)
+
+// GasScheduleExecutor is a ScenarioExecutor that can run scenarios with the gas schedule they pin,
+// see mj.Scenario.GasSchedule.
+type GasScheduleExecutor interface {
+	ScenarioExecutor
+
+	// ApplyGasSchedule switches to the schedule of the scenario about to run.
+	// It is called before every scenario that pins one, included scenarios too.
+	// Errors, e.g. for versions the executor does not know, fail the scenario.
+	ApplyGasSchedule(schedule *mj.GasScheduleReference) error
+}
+
+// applyGasSchedule hands the gas schedule the scenario pins to the executor.
+// Executors that cannot apply it fail the scenarios that check gas, since their expectations
+// were written for that schedule. The other scenarios only get a warning.
+func (r *ScenarioRunner) applyGasSchedule(scenario *mj.Scenario) (err error) {
+	if scenario.GasSchedule == nil {
+		return nil
+	}
+	gasScheduleExecutor, canApply := r.Executor.(GasScheduleExecutor)
+	if !canApply {
+		if scenario.CheckGas {
+			return fmt.Errorf("cannot apply gas schedule %s: executor does not support it",
+				scenario.GasSchedule.Original)
+		}
+		r.stepWarnings = append(r.stepWarnings, fmt.Sprintf(
+			"gas schedule %s not applied, executor does not support it", scenario.GasSchedule.Original))
+		return nil
+	}
+
+	defer recoverExecutorPanic(&err)
+	err = gasScheduleExecutor.ApplyGasSchedule(scenario.GasSchedule)
+	if err != nil {
+		return fmt.Errorf("cannot apply gas schedule %s: %w", scenario.GasSchedule.Original, err)
+	}
+	return nil
+}
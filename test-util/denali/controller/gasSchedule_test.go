@@ -0,0 +1,77 @@
+package denalicontroller
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	fr "github.com/numbatx/gn-vm-util/test-util/denali/json/fileresolver"
+	mj "github.com/numbatx/gn-vm-util/test-util/denali/json/model"
+	"github.com/stretchr/testify/require"
+)
+
+type gasScheduleRecordingExecutor struct {
+	applied []string
+	err     error
+}
+
+func (e *gasScheduleRecordingExecutor) Reset() {}
+
+func (e *gasScheduleRecordingExecutor) ApplyGasSchedule(schedule *mj.GasScheduleReference) error {
+	e.applied = append(e.applied, schedule.Original)
+	return e.err
+}
+
+func (e *gasScheduleRecordingExecutor) ExecuteScenario(*mj.Scenario, fr.FileResolver) error {
+	return nil
+}
+
+type gasScheduleIgnoringExecutor struct{}
+
+func (gasScheduleIgnoringExecutor) Reset() {}
+
+func (gasScheduleIgnoringExecutor) ExecuteScenario(*mj.Scenario, fr.FileResolver) error {
+	return nil
+}
+
+func TestApplyGasSchedule(t *testing.T) {
+	dir := t.TempDir()
+	pinnedPath := filepath.Join(dir, "pinned.scen.json")
+	require.Nil(t, os.WriteFile(pinnedPath, []byte(`{"gasSchedule": "v4", "steps": []}`), 0644))
+	unpinnedPath := filepath.Join(dir, "unpinned.scen.json")
+	require.Nil(t, os.WriteFile(unpinnedPath, []byte(`{"steps": []}`), 0644))
+
+	executor := &gasScheduleRecordingExecutor{}
+	runner := NewScenarioRunner(executor, NewDefaultFileResolver())
+	require.Nil(t, runner.RunSingleJSONScenario(pinnedPath))
+	require.Nil(t, runner.RunSingleJSONScenario(unpinnedPath))
+	require.Equal(t, []string{"v4"}, executor.applied)
+
+	executor.err = os.ErrNotExist
+	err := runner.RunSingleJSONScenario(pinnedPath)
+	require.NotNil(t, err)
+	require.Contains(t, err.Error(), "cannot apply gas schedule v4")
+}
+
+func TestApplyGasScheduleUnsupported(t *testing.T) {
+	dir := t.TempDir()
+	checkedPath := filepath.Join(dir, "checked.scen.json")
+	require.Nil(t, os.WriteFile(checkedPath, []byte(`{"gasSchedule": "v4", "steps": []}`), 0644))
+	uncheckedPath := filepath.Join(dir, "unchecked.scen.json")
+	require.Nil(t, os.WriteFile(uncheckedPath, []byte(`{"gasSchedule": "v4", "checkGas": false, "steps": []}`), 0644))
+
+	runner := NewScenarioRunner(gasScheduleIgnoringExecutor{}, NewDefaultFileResolver())
+	err := runner.RunSingleJSONScenario(checkedPath)
+	require.NotNil(t, err)
+	require.Contains(t, err.Error(), "cannot apply gas schedule v4: executor does not support it")
+
+	report, err := runner.RunAllJSONScenariosInDirectoryWithReport(dir, "", ".scen.json", nil)
+	require.NotNil(t, err)
+	require.Equal(t, 1, report.Count(ScenarioFailed))
+	require.Equal(t, 1, report.Count(ScenarioPassed))
+	for _, scenarioReport := range report.Scenarios {
+		if scenarioReport.Status == ScenarioPassed {
+			require.Equal(t, []string{"gas schedule v4 not applied, executor does not support it"}, scenarioReport.Warnings)
+		}
+	}
+}
@@ -0,0 +1,110 @@
+package denalicontroller
+
+import (
+	"errors"
+	"fmt"
+	"math/big"
+
+	mj "github.com/numbatx/gn-vm-util/test-util/denali/json/model"
+)
+
+// WorldStateReader gives read access to the world of an executor, so that scenario invariants can be checked.
+// Accounts and storage keys that do not exist read as zero, or empty.
+type WorldStateReader interface {
+	GetBalance(address []byte) (*big.Int, error)
+	GetNonce(address []byte) (uint64, error)
+	GetStorage(address []byte, key []byte) ([]byte, error)
+}
+
+// InvariantError is a scenario invariant that does not hold after a step.
+type InvariantError struct {
+	StepIndex int
+	Invariant string
+	Message   string
+}
+
+// Error yields the invariant and what is wrong. Executors report the step.
+func (e *InvariantError) Error() string {
+	return fmt.Sprintf("invariant %s violated: %s", e.Invariant, e.Message)
+}
+
+// CheckInvariants checks the invariants of the scenario, if any, against the world state.
+// Executors call it after every step. The sums that have to stay constant are taken on the first call.
+func (ctx *ExecutionContext) CheckInvariants(stepIndex int, state WorldStateReader) error {
+	if len(ctx.invariants) == 0 {
+		return nil
+	}
+	if state == nil {
+		return errors.New("scenario has invariants, but the executor cannot read the world state")
+	}
+	if ctx.invariantSums == nil {
+		ctx.invariantSums = make(map[string]*big.Int)
+	}
+	for _, invariant := range ctx.invariants {
+		err := ctx.checkInvariant(stepIndex, invariant, state)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (ctx *ExecutionContext) checkInvariant(stepIndex int, invariant *mj.Invariant, state WorldStateReader) error {
+	if invariant.IsConstantSum() {
+		sum := big.NewInt(0)
+		for _, quantity := range invariant.ConstantSum {
+			value, err := readInvariantQuantity(quantity, state)
+			if err != nil {
+				return fmt.Errorf("invariant %s: %w", invariant.Name, err)
+			}
+			sum.Add(sum, value)
+		}
+		initialSum, found := ctx.invariantSums[invariant.Name]
+		if !found {
+			ctx.invariantSums[invariant.Name] = sum
+			return nil
+		}
+		if sum.Cmp(initialSum) != 0 {
+			return &InvariantError{
+				StepIndex: stepIndex,
+				Invariant: invariant.Name,
+				Message:   fmt.Sprintf("sum changed from %s to %s", initialSum, sum),
+			}
+		}
+		return nil
+	}
+
+	value, err := readInvariantQuantity(invariant.Value, state)
+	if err != nil {
+		return fmt.Errorf("invariant %s: %w", invariant.Name, err)
+	}
+	if !invariant.Expect.Check(value) {
+		return &InvariantError{
+			StepIndex: stepIndex,
+			Invariant: invariant.Name,
+			Message: fmt.Sprintf("%s of %s is %s, expected %s",
+				invariant.Value.Field, invariant.Value.Account.Original, value, invariant.Expect.Original),
+		}
+	}
+	return nil
+}
+
+func readInvariantQuantity(quantity *mj.InvariantQuantity, state WorldStateReader) (*big.Int, error) {
+	address := quantity.Account.Value
+	switch quantity.Field {
+	case mj.InvariantFieldBalance:
+		balance, err := state.GetBalance(address)
+		if err != nil || balance == nil {
+			return big.NewInt(0), err
+		}
+		return balance, nil
+	case mj.InvariantFieldNonce:
+		nonce, err := state.GetNonce(address)
+		return big.NewInt(0).SetUint64(nonce), err
+	case mj.InvariantFieldStorage:
+		value, err := state.GetStorage(address, quantity.Key.Value)
+		return big.NewInt(0).SetBytes(value), err
+	default:
+		return nil, fmt.Errorf("unknown invariant field: %s", quantity.Field)
+	}
+}
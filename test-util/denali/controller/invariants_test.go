@@ -0,0 +1,95 @@
+package denalicontroller
+
+import (
+	"math/big"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	mj "github.com/numbatx/gn-vm-util/test-util/denali/json/model"
+	"github.com/stretchr/testify/require"
+)
+
+// worldQueryExecutor also keeps balances, and exposes its state for invariants.
+type worldQueryExecutor struct {
+	storageQueryExecutor
+	balances map[string]*big.Int
+}
+
+func (e *worldQueryExecutor) Reset() {
+	e.storageQueryExecutor.Reset()
+	e.balances = make(map[string]*big.Int)
+}
+
+func (e *worldQueryExecutor) SetState(step *mj.SetStateStep) error {
+	for _, account := range step.Accounts {
+		e.balances[string(account.Address.Value)] = account.Balance.Value
+	}
+	return e.storageQueryExecutor.SetState(step)
+}
+
+func (e *worldQueryExecutor) GetBalance(address []byte) (*big.Int, error) {
+	return e.balances[string(address)], nil
+}
+
+func (e *worldQueryExecutor) GetNonce(address []byte) (uint64, error) {
+	return 0, nil
+}
+
+func (e *worldQueryExecutor) GetStorage(address []byte, key []byte) ([]byte, error) {
+	return e.storage[string(key)], nil
+}
+
+const invariantsScenario = `{
+	"metadata": {"flavor": "view"},
+	"invariants": {
+		"supply": {"constantSum": [
+			{"account": "address:alice", "field": "balance"},
+			{"account": "address:bob", "field": "balance"}
+		]},
+		"cap": {"value": {"account": "address:alice", "field": "storage", "key": "str:total"},
+			"expect": "match:range:..1000"}
+	},
+	"steps": [
+		{"step": "setState", "accounts": {
+			"address:alice": {"nonce": "0", "balance": "100", "storage": {"str:total": "10"}, "code": ""}}},
+		{"step": "setState", "accounts": {
+			"address:alice": {"nonce": "0", "balance": "60", "storage": {"str:total": "1000"}, "code": ""},
+			"address:bob": {"nonce": "0", "balance": "40", "storage": {}, "code": ""}}},
+		{"step": "setState", "accounts": {
+			"address:bob": {"nonce": "0", "balance": "STEP_3_BALANCE", "storage": {"str:total": "STEP_3_TOTAL"}, "code": ""}}}
+	]
+}`
+
+func runInvariantsScenario(t *testing.T, executor QueryExecutor, balance string, total string) error {
+	scenarioJSON := invariantsScenario
+	for old, new := range map[string]string{"STEP_3_BALANCE": balance, "STEP_3_TOTAL": total} {
+		scenarioJSON = strings.ReplaceAll(scenarioJSON, old, new)
+	}
+	scenarioPath := filepath.Join(t.TempDir(), "invariants.scen.json")
+	require.Nil(t, os.WriteFile(scenarioPath, []byte(scenarioJSON), 0644))
+
+	executor.Reset()
+	runner := NewViewScenarioRunner(executor, NewDefaultFileResolver())
+	return runner.RunSingleJSONScenario(scenarioPath)
+}
+
+func TestInvariants(t *testing.T) {
+	executor := &worldQueryExecutor{}
+	require.Nil(t, runInvariantsScenario(t, executor, "40", "5"))
+
+	err := runInvariantsScenario(t, executor, "50", "5")
+	require.NotNil(t, err)
+	require.Contains(t, err.Error(), "step 2: invariant supply violated: sum changed from 100 to 110")
+
+	err = runInvariantsScenario(t, executor, "40", "1001")
+	require.NotNil(t, err)
+	require.Contains(t, err.Error(), "step 2: invariant cap violated: storage of address:alice is 1001")
+}
+
+func TestInvariantsRequireWorldState(t *testing.T) {
+	err := runInvariantsScenario(t, &storageQueryExecutor{}, "40", "5")
+	require.NotNil(t, err)
+	require.Contains(t, err.Error(), "cannot read the world state")
+}
@@ -0,0 +1,165 @@
+package denalicontroller
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+)
+
+// ReportSink receives the results of a directory run, as they become available.
+// Sinks turn them into an output format, e.g. JUnit XML for CI servers.
+// Errors returned by sinks do not stop the run, but make it fail.
+type ReportSink interface {
+	// ScenarioDone is called after each scenario ran, or got skipped.
+	ScenarioDone(scenarioReport *ScenarioReport) error
+
+	// RunDone is called once, after the last scenario, with the complete report.
+	RunDone(report *RunReport) error
+}
+
+// StdoutReportSink prints a line per scenario, and a summary at the end.
+// It is what directory runs use if the runner has no ReportSinks.
+type StdoutReportSink struct {
+	writer    io.Writer
+	basePath  string
+	workspace *Workspace
+}
+
+// NewStdoutReportSink creates a sink that prints to stdout.
+// Scenario paths are printed relative to basePath.
+func NewStdoutReportSink(basePath string) *StdoutReportSink {
+	return &StdoutReportSink{
+		writer:   os.Stdout,
+		basePath: basePath,
+	}
+}
+
+// NewWorkspaceStdoutReportSink creates a sink that prints to stdout, naming scenarios across the workspace.
+func NewWorkspaceStdoutReportSink(workspace *Workspace) *StdoutReportSink {
+	return &StdoutReportSink{
+		writer:    os.Stdout,
+		workspace: workspace,
+	}
+}
+
+// ScenarioDone prints the outcome of the scenario, followed by its warnings and, for panics, the stack trace.
+func (s *StdoutReportSink) ScenarioDone(scenarioReport *ScenarioReport) error {
+	shortPath := shortenTestPath(scenarioReport.Path, s.basePath)
+	if s.workspace != nil {
+		shortPath = s.workspace.WorkspacePath(scenarioReport.Path)
+	}
+	var sb strings.Builder
+	fmt.Fprintf(&sb, "Scenario: %s ... ", shortPath)
+	switch {
+	case scenarioReport.Status == ScenarioSkipped:
+		sb.WriteString("  skip\n")
+	case scenarioReport.Passed():
+		sb.WriteString("  ok\n")
+		writeWarnings(&sb, scenarioReport.Warnings)
+	case len(scenarioReport.StackTrace) > 0:
+		fmt.Fprintf(&sb, "  PANIC: %s\n%s\n", scenarioReport.Error, scenarioReport.StackTrace)
+	default:
+		fmt.Fprintf(&sb, "  FAIL: %s\n", scenarioReport.Error)
+		writeWarnings(&sb, scenarioReport.Warnings)
+	}
+	_, err := io.WriteString(s.writer, sb.String())
+	return err
+}
+
+// RunDone prints the number of passed, failed and skipped scenarios.
+func (s *StdoutReportSink) RunDone(report *RunReport) error {
+	outcome := "Done"
+	if report.Stopped {
+		outcome = "Stopped"
+	}
+	_, err := fmt.Fprintf(s.writer, "%s. Passed: %d. Failed: %d. Skipped: %d.\n",
+		outcome, report.Count(ScenarioPassed), report.Count(ScenarioFailed), report.Count(ScenarioSkipped))
+	return err
+}
+
+func writeWarnings(sb *strings.Builder, warnings []string) {
+	for _, warning := range warnings {
+		fmt.Fprintf(sb, "    %s\n", warning)
+	}
+}
+
+// JSONReportSink writes the complete report as JSON, as read by LoadRunReports.
+type JSONReportSink struct {
+	writer io.Writer
+}
+
+// NewJSONReportSink creates a sink that writes the report to the given writer, at the end of the run.
+func NewJSONReportSink(writer io.Writer) *JSONReportSink {
+	return &JSONReportSink{
+		writer: writer,
+	}
+}
+
+// ScenarioDone does nothing, the report is only written once complete.
+func (s *JSONReportSink) ScenarioDone(_ *ScenarioReport) error {
+	return nil
+}
+
+// RunDone writes the report.
+func (s *JSONReportSink) RunDone(report *RunReport) error {
+	encoder := json.NewEncoder(s.writer)
+	encoder.SetIndent("", "    ")
+	return encoder.Encode(report)
+}
+
+// TAPReportSink writes the results in the Test Anything Protocol, version 13, one test point per scenario,
+// as soon as it completes. The plan comes last, since the number of scenarios is only known at the end.
+type TAPReportSink struct {
+	writer      io.Writer
+	testPointNr int
+}
+
+// NewTAPReportSink creates a sink that streams TAP to the given writer.
+func NewTAPReportSink(writer io.Writer) *TAPReportSink {
+	return &TAPReportSink{
+		writer: writer,
+	}
+}
+
+// ScenarioDone writes the test point of the scenario. Failures get their error as a YAML diagnostic,
+// located at the first of their locations, if any.
+func (s *TAPReportSink) ScenarioDone(scenarioReport *ScenarioReport) error {
+	var sb strings.Builder
+	if s.testPointNr == 0 {
+		sb.WriteString("TAP version 13\n")
+	}
+	s.testPointNr++
+	switch scenarioReport.Status {
+	case ScenarioSkipped:
+		fmt.Fprintf(&sb, "ok %d - %s # SKIP\n", s.testPointNr, scenarioReport.Path)
+	case ScenarioPassed:
+		fmt.Fprintf(&sb, "ok %d - %s\n", s.testPointNr, scenarioReport.Path)
+	default:
+		fmt.Fprintf(&sb, "not ok %d - %s\n", s.testPointNr, scenarioReport.Path)
+		sb.WriteString("  ---\n")
+		fmt.Fprintf(&sb, "  message: %q\n", scenarioReport.Error)
+		if len(scenarioReport.Locations) > 0 {
+			location := scenarioReport.Locations[0]
+			fmt.Fprintf(&sb, "  at:\n    file: %q\n    line: %d\n    column: %d\n", location.Path, location.Line, location.Column)
+		}
+		sb.WriteString("  ...\n")
+	}
+	_, err := io.WriteString(s.writer, sb.String())
+	return err
+}
+
+// RunDone writes the plan.
+func (s *TAPReportSink) RunDone(report *RunReport) error {
+	var sb strings.Builder
+	if s.testPointNr == 0 {
+		sb.WriteString("TAP version 13\n")
+	}
+	fmt.Fprintf(&sb, "1..%d\n", s.testPointNr)
+	if report.Stopped {
+		sb.WriteString("Bail out! The executor panicked or timed out.\n")
+	}
+	_, err := io.WriteString(s.writer, sb.String())
+	return err
+}
@@ -0,0 +1,108 @@
+package denalicontroller
+
+import (
+	"fmt"
+	"io"
+	"path/filepath"
+	"strings"
+)
+
+// GitHubReportSink writes GitHub Actions workflow commands, so that failures show up inline on pull requests,
+// at the failing step, or at the problems of scenarios that cannot be parsed. Warnings are annotated too.
+// Annotations are written as soon as each scenario completes.
+type GitHubReportSink struct {
+	writer   io.Writer
+	basePath string
+}
+
+// NewGitHubReportSink creates a sink that writes annotations to the given writer, usually stdout.
+// GitHub expects file paths relative to the repository root, basePath, e.g. $GITHUB_WORKSPACE.
+func NewGitHubReportSink(writer io.Writer, basePath string) *GitHubReportSink {
+	return &GitHubReportSink{
+		writer:   writer,
+		basePath: basePath,
+	}
+}
+
+// ScenarioDone writes an error annotation per failure location, or a single one for the file if none is known,
+// followed by a warning annotation per warning.
+func (s *GitHubReportSink) ScenarioDone(scenarioReport *ScenarioReport) error {
+	if scenarioReport.Status == ScenarioSkipped {
+		return nil
+	}
+	title := scenarioReport.Name
+	if len(title) == 0 {
+		title = s.relativePath(scenarioReport.Path)
+	}
+	var sb strings.Builder
+	if scenarioReport.Status == ScenarioFailed {
+		for _, location := range scenarioReport.Locations {
+			writeGitHubCommand(&sb, "error", []string{
+				"file", s.relativePath(location.Path),
+				"line", fmt.Sprint(location.Line),
+				"col", fmt.Sprint(location.Column),
+				"title", title,
+			}, location.Message)
+		}
+		if len(scenarioReport.Locations) == 0 {
+			writeGitHubCommand(&sb, "error", []string{
+				"file", s.relativePath(scenarioFilePath(scenarioReport.Path)),
+				"title", title,
+			}, scenarioReport.Error)
+		}
+	}
+	for _, warning := range scenarioReport.Warnings {
+		writeGitHubCommand(&sb, "warning", []string{
+			"file", s.relativePath(scenarioFilePath(scenarioReport.Path)),
+			"title", title,
+		}, warning)
+	}
+	_, err := io.WriteString(s.writer, sb.String())
+	return err
+}
+
+// RunDone does nothing, GitHub counts the annotations itself.
+func (s *GitHubReportSink) RunDone(_ *RunReport) error {
+	return nil
+}
+
+func (s *GitHubReportSink) relativePath(path string) string {
+	if len(s.basePath) == 0 {
+		return filepath.ToSlash(path)
+	}
+	relativePath, err := filepath.Rel(s.basePath, path)
+	if err != nil || strings.HasPrefix(relativePath, "..") {
+		return filepath.ToSlash(path)
+	}
+	return filepath.ToSlash(relativePath)
+}
+
+// scenarioFilePath strips the MultiScenarioSuffix from a report path.
+func scenarioFilePath(reportPath string) string {
+	if separatorIndex := strings.LastIndex(reportPath, "#"); separatorIndex >= 0 && isDigits(reportPath[separatorIndex+1:]) {
+		return reportPath[:separatorIndex]
+	}
+	return reportPath
+}
+
+// writeGitHubCommand writes a workflow command, e.g. "::error file=a.scen.json,line=3::message",
+// the properties given as name, value pairs.
+func writeGitHubCommand(sb *strings.Builder, command string, properties []string, message string) {
+	fmt.Fprintf(sb, "::%s", command)
+	for i := 0; i < len(properties); i += 2 {
+		separator := ","
+		if i == 0 {
+			separator = " "
+		}
+		fmt.Fprintf(sb, "%s%s=%s", separator, properties[i], escapeGitHubProperty(properties[i+1]))
+	}
+	fmt.Fprintf(sb, "::%s\n", escapeGitHubData(message))
+}
+
+func escapeGitHubData(data string) string {
+	return strings.NewReplacer("%", "%25", "\r", "%0D", "\n", "%0A").Replace(data)
+}
+
+func escapeGitHubProperty(value string) string {
+	return strings.NewReplacer("%", "%25", "\r", "%0D", "\n", "%0A", ":", "%3A", ",", "%2C").Replace(value)
+}
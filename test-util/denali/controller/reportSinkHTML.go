@@ -0,0 +1,65 @@
+package denalicontroller
+
+import (
+	"html/template"
+	"io"
+)
+
+// HTMLReportSink writes the complete report as a standalone HTML page, for humans to browse.
+type HTMLReportSink struct {
+	writer io.Writer
+}
+
+// NewHTMLReportSink creates a sink that writes an HTML page to the given writer, at the end of the run.
+func NewHTMLReportSink(writer io.Writer) *HTMLReportSink {
+	return &HTMLReportSink{
+		writer: writer,
+	}
+}
+
+var htmlReportTemplate = template.Must(template.New("report").Parse(`<!DOCTYPE html>
+<html>
+<head>
+<meta charset="utf-8">
+<title>Scenario run {{.StartedAt.Format "2006-01-02 15:04:05"}}</title>
+<style>
+body { font-family: sans-serif; }
+table { border-collapse: collapse; }
+td, th { border: 1px solid #ccc; padding: 4px 8px; text-align: left; vertical-align: top; }
+.pass { color: #070; }
+.fail { color: #b00; }
+.skip { color: #777; }
+pre { margin: 0; white-space: pre-wrap; }
+</style>
+</head>
+<body>
+<h1>Scenario run {{.StartedAt.Format "2006-01-02 15:04:05"}}</h1>
+<p>{{if .Stopped}}Stopped{{else}}Done{{end}} in {{.Duration}}. Passed: {{.Count "pass"}}. Failed: {{.Count "fail"}}. Skipped: {{.Count "skip"}}.</p>
+<table>
+<tr><th>Scenario</th><th>Status</th><th>Duration</th><th>Details</th></tr>
+{{- range .Scenarios}}
+<tr class="{{.Status}}">
+<td>{{.Path}}</td>
+<td class="{{.Status}}">{{.Status}}</td>
+<td>{{.Duration}}</td>
+<td>
+{{- if .Error}}<pre>{{.Error}}</pre>{{end}}
+{{- if .StackTrace}}<details><summary>stack trace</summary><pre>{{.StackTrace}}</pre></details>{{end}}
+{{- range .Warnings}}<pre>{{.}}</pre>{{end -}}
+</td>
+</tr>
+{{- end}}
+</table>
+</body>
+</html>
+`))
+
+// ScenarioDone does nothing, the report is only written once complete.
+func (s *HTMLReportSink) ScenarioDone(_ *ScenarioReport) error {
+	return nil
+}
+
+// RunDone writes the page.
+func (s *HTMLReportSink) RunDone(report *RunReport) error {
+	return htmlReportTemplate.Execute(s.writer, report)
+}
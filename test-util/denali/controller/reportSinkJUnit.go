@@ -0,0 +1,115 @@
+package denalicontroller
+
+import (
+	"encoding/xml"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+)
+
+// JUnitReportSink writes the complete report as JUnit XML, understood by most CI servers.
+// The run is a single test suite, each scenario a test case.
+type JUnitReportSink struct {
+	writer io.Writer
+
+	// SuiteName names the test suite, "denali" by default.
+	SuiteName string
+}
+
+// NewJUnitReportSink creates a sink that writes JUnit XML to the given writer, at the end of the run.
+func NewJUnitReportSink(writer io.Writer) *JUnitReportSink {
+	return &JUnitReportSink{
+		writer:    writer,
+		SuiteName: "denali",
+	}
+}
+
+type junitTestSuites struct {
+	XMLName xml.Name         `xml:"testsuites"`
+	Suites  []junitTestSuite `xml:"testsuite"`
+}
+
+type junitTestSuite struct {
+	Name      string          `xml:"name,attr"`
+	Tests     int             `xml:"tests,attr"`
+	Failures  int             `xml:"failures,attr"`
+	Skipped   int             `xml:"skipped,attr"`
+	Time      string          `xml:"time,attr"`
+	Timestamp string          `xml:"timestamp,attr"`
+	TestCases []junitTestCase `xml:"testcase"`
+}
+
+type junitTestCase struct {
+	Name       string          `xml:"name,attr"`
+	ClassName  string          `xml:"classname,attr"`
+	Time       string          `xml:"time,attr"`
+	Properties []junitProperty `xml:"properties>property,omitempty"`
+	Failure    *junitFailure   `xml:"failure,omitempty"`
+	Skipped    *struct{}       `xml:"skipped,omitempty"`
+	SystemOut  string          `xml:"system-out,omitempty"`
+}
+
+type junitProperty struct {
+	Name  string `xml:"name,attr"`
+	Value string `xml:"value,attr"`
+}
+
+type junitFailure struct {
+	Message string `xml:"message,attr"`
+	Text    string `xml:",chardata"`
+}
+
+// ScenarioDone does nothing, the report is only written once complete.
+func (s *JUnitReportSink) ScenarioDone(_ *ScenarioReport) error {
+	return nil
+}
+
+// RunDone writes the report. Failures carry the stack trace of executor panics, warnings go to system-out.
+// The scenario name and the number of steps executed are properties of the test case.
+func (s *JUnitReportSink) RunDone(report *RunReport) error {
+	suite := junitTestSuite{
+		Name:      s.SuiteName,
+		Tests:     len(report.Scenarios),
+		Failures:  report.Count(ScenarioFailed),
+		Skipped:   report.Count(ScenarioSkipped),
+		Time:      junitSeconds(report.Duration.Seconds()),
+		Timestamp: report.StartedAt.Format("2006-01-02T15:04:05"),
+	}
+	for _, scenarioReport := range report.Scenarios {
+		testCase := junitTestCase{
+			Name:      scenarioReport.Path,
+			ClassName: s.SuiteName,
+			Time:      junitSeconds(scenarioReport.Duration.Seconds()),
+			SystemOut: strings.Join(scenarioReport.Warnings, "\n"),
+		}
+		if scenarioReport.Status != ScenarioSkipped {
+			if len(scenarioReport.Name) > 0 {
+				testCase.Properties = append(testCase.Properties, junitProperty{Name: "name", Value: scenarioReport.Name})
+			}
+			testCase.Properties = append(testCase.Properties,
+				junitProperty{Name: "steps", Value: strconv.Itoa(scenarioReport.Steps)})
+		}
+		switch scenarioReport.Status {
+		case ScenarioSkipped:
+			testCase.Skipped = &struct{}{}
+		case ScenarioFailed:
+			testCase.Failure = &junitFailure{
+				Message: scenarioReport.Error,
+				Text:    scenarioReport.StackTrace,
+			}
+		}
+		suite.TestCases = append(suite.TestCases, testCase)
+	}
+
+	output, err := xml.MarshalIndent(junitTestSuites{Suites: []junitTestSuite{suite}}, "", "    ")
+	if err != nil {
+		return err
+	}
+	_, err = fmt.Fprintf(s.writer, "%s%s\n", xml.Header, output)
+	return err
+}
+
+func junitSeconds(seconds float64) string {
+	return fmt.Sprintf("%.3f", seconds)
+}
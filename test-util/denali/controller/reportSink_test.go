@@ -0,0 +1,145 @@
+package denalicontroller
+
+import (
+	"bytes"
+	"encoding/json"
+	"encoding/xml"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+type failingSink struct{}
+
+func (s *failingSink) ScenarioDone(_ *ScenarioReport) error {
+	return nil
+}
+
+func (s *failingSink) RunDone(_ *RunReport) error {
+	return errors.New("disk full")
+}
+
+func TestReportSinks(t *testing.T) {
+	dir := writePanicTestScenarios(t)
+	runner := NewScenarioRunner(&panickingExecutor{}, NewDefaultFileResolver())
+	runner.ContinueOnError = true
+	var stdout, jsonOut, junitOut, tapOut, htmlOut bytes.Buffer
+	runner.ReportSinks = []ReportSink{
+		&StdoutReportSink{writer: &stdout, basePath: dir},
+		NewJSONReportSink(&jsonOut),
+		NewJUnitReportSink(&junitOut),
+		NewTAPReportSink(&tapOut),
+		NewHTMLReportSink(&htmlOut),
+	}
+
+	report, err := runner.RunAllJSONScenariosInDirectoryWithReport(dir, "", ".scen.json", nil)
+	require.NotNil(t, err)
+	require.Equal(t, 3, len(report.Scenarios))
+
+	require.Contains(t, stdout.String(), "Scenario: a_passes.scen.json ...   ok\n")
+	require.Contains(t, stdout.String(), "Scenario: c_fails.scen.json ...   FAIL: check failed\n")
+	require.Contains(t, stdout.String(), "Done. Passed: 1. Failed: 2. Skipped: 0.\n")
+
+	decoded := &RunReport{}
+	require.Nil(t, json.Unmarshal(jsonOut.Bytes(), decoded))
+	require.Equal(t, 3, len(decoded.Scenarios))
+	require.Equal(t, ScenarioFailed, decoded.Scenarios[2].Status)
+	require.Equal(t, "fails", decoded.Scenarios[2].Name)
+
+	junit := &junitTestSuites{}
+	require.Nil(t, xml.Unmarshal(junitOut.Bytes(), junit))
+	require.Equal(t, 1, len(junit.Suites))
+	require.Equal(t, 3, junit.Suites[0].Tests)
+	require.Equal(t, 2, junit.Suites[0].Failures)
+	require.Nil(t, junit.Suites[0].TestCases[0].Failure)
+	require.Equal(t, "check failed", junit.Suites[0].TestCases[2].Failure.Message)
+	require.Contains(t, junit.Suites[0].TestCases[1].Failure.Text, "panickingExecutor")
+
+	tap := tapOut.String()
+	require.Contains(t, tap, "TAP version 13\nok 1 - ")
+	require.Contains(t, tap, "not ok 3 - ")
+	require.Contains(t, tap, "  message: \"check failed\"\n")
+	require.Contains(t, tap, "1..3\n")
+
+	require.Contains(t, htmlOut.String(), "Passed: 1. Failed: 2. Skipped: 0.")
+	require.Contains(t, htmlOut.String(), "check failed")
+}
+
+func TestReportNameAndSteps(t *testing.T) {
+	dir := t.TempDir()
+	writeAuditTestScenario(t, dir, "a", "code a")
+	writeAuditTestScenario(t, dir, "b", "code b")
+	// reports its steps, even those of failing scenarios
+	runner := NewScenarioRunner(&auditTestExecutor{fail: map[string]bool{"b": true}}, NewDefaultFileResolver())
+	var junitOut bytes.Buffer
+	runner.ReportSinks = []ReportSink{NewJUnitReportSink(&junitOut)}
+
+	report, err := runner.RunAllJSONScenariosInDirectoryWithReport(dir, "", ".scen.json", nil)
+	require.NotNil(t, err)
+	require.Equal(t, "b", report.Scenarios[1].Name)
+	require.Equal(t, 1, report.Scenarios[1].Steps)
+	require.Equal(t, "setState", report.Scenarios[1].FailedStepType)
+	junit := &junitTestSuites{}
+	require.Nil(t, xml.Unmarshal(junitOut.Bytes(), junit))
+	require.Equal(t, []junitProperty{{Name: "name", Value: "a"}, {Name: "steps", Value: "1"}},
+		junit.Suites[0].TestCases[0].Properties)
+
+	// does not report its steps, only those of passing scenarios count
+	runner = NewScenarioRunner(&panickingExecutor{}, NewDefaultFileResolver())
+	runner.ReportSinks = []ReportSink{NewJSONReportSink(&bytes.Buffer{})}
+	report, err = runner.RunAllJSONScenariosInDirectoryWithReport(dir, "", ".scen.json", nil)
+	require.Nil(t, err)
+	require.Equal(t, 1, report.Scenarios[0].Steps)
+}
+
+func TestGitHubReportSink(t *testing.T) {
+	dir := t.TempDir()
+	require.Nil(t, os.WriteFile(filepath.Join(dir, "a_fails.scen.json"), []byte(`{
+	"name": "fails",
+	"steps": [
+		{"step": "setState"},
+		{"step": "setState"}
+	]
+}`), 0644))
+	require.Nil(t, os.WriteFile(filepath.Join(dir, "b_invalid.scen.json"), []byte(`{
+	"steps": [
+		{"step": "unknown"},
+		{"step": "unknown"}
+	]
+}`), 0644))
+	runner := NewScenarioRunner(&auditTestExecutor{fail: map[string]bool{"fails": true}}, NewDefaultFileResolver())
+	var githubOut, tapOut bytes.Buffer
+	runner.ReportSinks = []ReportSink{NewGitHubReportSink(&githubOut, dir), NewTAPReportSink(&tapOut)}
+	_, err := runner.RunAllJSONScenariosInDirectoryWithReport(dir, "", ".scen.json", nil)
+	require.NotNil(t, err)
+
+	annotations := strings.Split(strings.TrimSpace(githubOut.String()), "\n")
+	require.Equal(t, 3, len(annotations))
+	// at the last step the executor dispatched
+	require.Equal(t, "::error file=a_fails.scen.json,line=5,col=3,title=fails::scenario failed", annotations[0])
+	require.True(t, strings.HasPrefix(annotations[1], "::error file=b_invalid.scen.json,line=3,col=3,title=b_invalid.scen.json::steps[0]: "))
+	require.True(t, strings.HasPrefix(annotations[2], "::error file=b_invalid.scen.json,line=4,col=3,"))
+	require.Contains(t, tapOut.String(), "  at:\n    file: \""+filepath.Join(dir, "a_fails.scen.json")+"\"\n    line: 5\n")
+}
+
+func TestWriteGitHubCommand(t *testing.T) {
+	var sb strings.Builder
+	writeGitHubCommand(&sb, "error", []string{"file", "a,b:c.json", "title", "100%"}, "first\nsecond")
+	require.Equal(t, "::error file=a%2Cb%3Ac.json,title=100%25::first%0Asecond\n", sb.String())
+}
+
+func TestReportSinkErrorFailsRun(t *testing.T) {
+	dir := t.TempDir()
+	runner := NewScenarioRunner(&panickingExecutor{}, NewDefaultFileResolver())
+	var tapOut bytes.Buffer
+	runner.ReportSinks = []ReportSink{&failingSink{}, NewTAPReportSink(&tapOut)}
+
+	_, err := runner.RunAllJSONScenariosInDirectoryWithReport(dir, "", ".scen.json", nil)
+	require.NotNil(t, err)
+	require.Equal(t, "disk full", err.Error())
+	require.Equal(t, "TAP version 13\n1..0\n", tapOut.String())
+}
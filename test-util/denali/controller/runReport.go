@@ -0,0 +1,161 @@
+package denalicontroller
+
+import (
+	"encoding/hex"
+	"strconv"
+	"time"
+
+	mj "github.com/numbatx/gn-vm-util/test-util/denali/json/model"
+)
+
+// ScenarioStatus is the outcome of running a single scenario.
+type ScenarioStatus string
+
+const (
+	// ScenarioPassed means the scenario ran and all checks held.
+	ScenarioPassed ScenarioStatus = "pass"
+
+	// ScenarioFailed means the scenario could not be parsed, or its execution failed.
+	ScenarioFailed ScenarioStatus = "fail"
+
+	// ScenarioSkipped means the scenario was excluded from the run.
+	ScenarioSkipped ScenarioStatus = "skip"
+)
+
+// RunReport is the structured result of running a suite of scenarios.
+type RunReport struct {
+	StartedAt time.Time         `json:"startedAt"`
+	Duration  time.Duration     `json:"duration"`
+	Scenarios []*ScenarioReport `json:"scenarios"`
+
+	// Stopped is set if the run ended early, because the executor panicked, or did not stop once timed out.
+	Stopped bool `json:"stopped,omitempty"`
+}
+
+// Count returns the number of scenarios with the given status.
+func (r *RunReport) Count(status ScenarioStatus) int {
+	count := 0
+	for _, scenarioReport := range r.Scenarios {
+		if scenarioReport.Status == status {
+			count++
+		}
+	}
+	return count
+}
+
+// ScenarioReport is the structured result of running a single scenario.
+type ScenarioReport struct {
+	Path      string         `json:"path"`
+	Name      string         `json:"name,omitempty"`
+	Status    ScenarioStatus `json:"status"`
+	StartedAt time.Time      `json:"startedAt"`
+	Duration  time.Duration  `json:"duration"`
+	Error     string         `json:"error,omitempty"`
+
+	// Steps is the number of steps executed, those of included scenarios included. Executors report them
+	// through ExecutionContext.StepDispatched, for the others only scenarios that pass count their steps.
+	Steps int `json:"steps"`
+
+	// FailedStepType is the type of the step that failed, e.g. "scCall",
+	// empty if unknown, see ExecutionContext.StepDispatched.
+	FailedStepType string `json:"failedStepType,omitempty"`
+
+	// Locations point to the causes of a failure in the scenario files, if known:
+	// the problems of a scenario that cannot be parsed, or the step that failed.
+	Locations []*SourceLocation `json:"locations,omitempty"`
+
+	// StackTrace is only set if the executor panicked.
+	StackTrace string `json:"stackTrace,omitempty"`
+
+	// Warnings are the setState failures tolerated through "allowedErrors",
+	// and the diagnostics about suspicious values, only collected in lint or strict mode.
+	Warnings []string `json:"warnings,omitempty"`
+
+	// Outputs holds what the transaction steps returned, whether checks passed or not.
+	// Only executors that report their results fill it in, see ExecutionContext.RecordStepOutput.
+	Outputs []*StepOutput `json:"outputs,omitempty"`
+}
+
+// SourceLocation points to a place in a scenario file.
+type SourceLocation struct {
+	// Path is the absolute path of the file, which can be a scenario included by the one that failed.
+	Path    string `json:"path"`
+	Line    int    `json:"line"`
+	Column  int    `json:"column"`
+	Message string `json:"message"`
+}
+
+// StepOutput is what a transaction step actually returned.
+type StepOutput struct {
+	// Path is only set for steps of included scenarios, i.e. run through externalSteps.
+	Path      string `json:"path,omitempty"`
+	StepIndex int    `json:"stepIndex"`
+	StepID    string `json:"stepId,omitempty"`
+	TxIdent   string `json:"txId,omitempty"`
+
+	// Out values are hex, "0x...".
+	Out     []string `json:"out"`
+	Status  string   `json:"status"`
+	Message string   `json:"message,omitempty"`
+
+	// Gas, Refund and Logs are only set if the executor reported them, see QueryResult.
+	Gas    string     `json:"gas,omitempty"`
+	Refund string     `json:"refund,omitempty"`
+	Logs   []*StepLog `json:"logs,omitempty"`
+}
+
+// StepLog is a log emitted by a transaction step. Values are hex, "0x...".
+type StepLog struct {
+	Address    string   `json:"address"`
+	Identifier string   `json:"identifier"`
+	Topics     []string `json:"topics"`
+	Data       string   `json:"data"`
+}
+
+// Passed returns true if the scenario ran successfully.
+func (sr *ScenarioReport) Passed() bool {
+	return sr.Status == ScenarioPassed
+}
+
+// Failed returns true if the scenario failed.
+func (sr *ScenarioReport) Failed() bool {
+	return sr.Status == ScenarioFailed
+}
+
+func newStepOutput(stepIndex int, step *mj.TxStep, result *QueryResult) *StepOutput {
+	output := &StepOutput{
+		StepIndex: stepIndex,
+		StepID:    step.ID,
+		TxIdent:   step.TxIdent,
+		Out:       make([]string, len(result.Out)),
+		Status:    "0",
+		Message:   string(result.Message),
+	}
+	for i, out := range result.Out {
+		output.Out[i] = "0x" + hex.EncodeToString(out)
+	}
+	if result.Status != nil {
+		output.Status = result.Status.String()
+	}
+	if result.Gas != nil {
+		output.Gas = strconv.FormatUint(*result.Gas, 10)
+	}
+	if result.Refund != nil {
+		output.Refund = result.Refund.String()
+	}
+	if result.Logs != nil {
+		output.Logs = make([]*StepLog, len(result.Logs))
+		for i, log := range result.Logs {
+			output.Logs[i] = &StepLog{
+				Address:    "0x" + hex.EncodeToString(log.Address),
+				Identifier: "0x" + hex.EncodeToString(log.Identifier),
+				Topics:     make([]string, len(log.Topics)),
+				Data:       "0x" + hex.EncodeToString(log.Data),
+			}
+			for j, topic := range log.Topics {
+				output.Logs[i].Topics[j] = "0x" + hex.EncodeToString(topic)
+			}
+		}
+	}
+	return output
+}
@@ -0,0 +1,124 @@
+package denalicontroller
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+)
+
+// ScenarioHistory aggregates the results of a scenario over many historical runs.
+type ScenarioHistory struct {
+	Path string
+
+	// Runs counts the runs in which the scenario was executed (skips excluded).
+	Runs int
+
+	// Failures counts the runs in which the scenario failed.
+	Failures int
+
+	// FlakinessRate is the fraction of consecutive runs in which the outcome changed,
+	// 0 for scenarios that always pass or always fail, up to 1 for scenarios that alternate.
+	FlakinessRate float64
+
+	// AverageDuration is the mean duration over all executed runs.
+	AverageDuration time.Duration
+
+	// FirstFailedAt is the start time of the earliest failed run, zero if it never failed.
+	FirstFailedAt time.Time
+}
+
+// IsFlaky returns true if the flakiness rate reaches the given threshold.
+// Useful for deciding whether a failing scenario is worth retrying.
+func (sh *ScenarioHistory) IsFlaky(threshold float64) bool {
+	return sh.Runs > 1 && sh.FlakinessRate >= threshold
+}
+
+// LoadRunReports reads all JSON run reports found directly in a directory.
+func LoadRunReports(dirPath string) ([]*RunReport, error) {
+	fileInfos, err := ioutil.ReadDir(dirPath)
+	if err != nil {
+		return nil, err
+	}
+
+	var reports []*RunReport
+	for _, fileInfo := range fileInfos {
+		if fileInfo.IsDir() || !strings.HasSuffix(fileInfo.Name(), ".json") {
+			continue
+		}
+		reportPath := filepath.Join(dirPath, fileInfo.Name())
+		report, err := loadRunReport(reportPath)
+		if err != nil {
+			return nil, fmt.Errorf("cannot load run report %s: %w", reportPath, err)
+		}
+		reports = append(reports, report)
+	}
+	return reports, nil
+}
+
+func loadRunReport(reportPath string) (*RunReport, error) {
+	reportFile, err := os.Open(reportPath)
+	if err != nil {
+		return nil, err
+	}
+	defer reportFile.Close()
+
+	report := &RunReport{}
+	err = json.NewDecoder(reportFile).Decode(report)
+	if err != nil {
+		return nil, err
+	}
+	return report, nil
+}
+
+// ComputeScenarioHistory aggregates per-scenario statistics from a set of run reports,
+// in any order. The result is keyed by scenario path.
+func ComputeScenarioHistory(reports []*RunReport) map[string]*ScenarioHistory {
+	var scenarioReports []*ScenarioReport
+	for _, report := range reports {
+		for _, scenarioReport := range report.Scenarios {
+			if scenarioReport.Status != ScenarioSkipped {
+				scenarioReports = append(scenarioReports, scenarioReport)
+			}
+		}
+	}
+	sort.SliceStable(scenarioReports, func(i, j int) bool {
+		return scenarioReports[i].StartedAt.Before(scenarioReports[j].StartedAt)
+	})
+
+	history := make(map[string]*ScenarioHistory)
+	totalDurations := make(map[string]time.Duration)
+	outcomeChanges := make(map[string]int)
+	lastFailed := make(map[string]bool)
+	for _, scenarioReport := range scenarioReports {
+		sh, found := history[scenarioReport.Path]
+		if !found {
+			sh = &ScenarioHistory{Path: scenarioReport.Path}
+			history[scenarioReport.Path] = sh
+		} else if lastFailed[sh.Path] != scenarioReport.Failed() {
+			outcomeChanges[sh.Path]++
+		}
+		lastFailed[sh.Path] = scenarioReport.Failed()
+
+		sh.Runs++
+		totalDurations[sh.Path] += scenarioReport.Duration
+		if scenarioReport.Failed() {
+			if sh.Failures == 0 {
+				sh.FirstFailedAt = scenarioReport.StartedAt
+			}
+			sh.Failures++
+		}
+	}
+
+	for path, sh := range history {
+		sh.AverageDuration = totalDurations[path] / time.Duration(sh.Runs)
+		if sh.Runs > 1 {
+			sh.FlakinessRate = float64(outcomeChanges[path]) / float64(sh.Runs-1)
+		}
+	}
+	return history
+}
@@ -0,0 +1,50 @@
+package denalicontroller
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestComputeScenarioHistory(t *testing.T) {
+	start := time.Date(2020, 1, 1, 0, 0, 0, 0, time.UTC)
+	newReport := func(run int, flakyStatus ScenarioStatus) *RunReport {
+		startedAt := start.Add(time.Duration(run) * time.Hour)
+		return &RunReport{
+			StartedAt: startedAt,
+			Scenarios: []*ScenarioReport{
+				{Path: "stable.scen.json", Status: ScenarioPassed, StartedAt: startedAt, Duration: time.Second},
+				{Path: "flaky.scen.json", Status: flakyStatus, StartedAt: startedAt, Duration: 3 * time.Second},
+				{Path: "skipped.scen.json", Status: ScenarioSkipped, StartedAt: startedAt},
+			},
+		}
+	}
+
+	// deliberately out of order
+	reports := []*RunReport{
+		newReport(2, ScenarioPassed),
+		newReport(0, ScenarioPassed),
+		newReport(1, ScenarioFailed),
+		newReport(3, ScenarioFailed),
+	}
+
+	history := ComputeScenarioHistory(reports)
+	require.Equal(t, 2, len(history))
+
+	stable := history["stable.scen.json"]
+	require.Equal(t, 4, stable.Runs)
+	require.Equal(t, 0, stable.Failures)
+	require.Equal(t, 0.0, stable.FlakinessRate)
+	require.Equal(t, time.Second, stable.AverageDuration)
+	require.True(t, stable.FirstFailedAt.IsZero())
+	require.False(t, stable.IsFlaky(0.1))
+
+	flaky := history["flaky.scen.json"]
+	require.Equal(t, 4, flaky.Runs)
+	require.Equal(t, 2, flaky.Failures)
+	require.Equal(t, 1.0, flaky.FlakinessRate)
+	require.Equal(t, 3*time.Second, flaky.AverageDuration)
+	require.Equal(t, start.Add(time.Hour), flaky.FirstFailedAt)
+	require.True(t, flaky.IsFlaky(0.5))
+}
@@ -0,0 +1,262 @@
+package denalicontroller
+
+import (
+	"bytes"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"math/big"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+
+	mj "github.com/numbatx/gn-vm-util/test-util/denali/json/model"
+	oj "github.com/numbatx/gn-vm-util/test-util/orderedjson"
+)
+
+// acceptActualValues is what runSingleJSONScenario does with a failed scenario when AcceptActualValues is set:
+// the expected values of the failing step are replaced with the actual ones, and the scenario runs again
+// from a clean state, until it passes, which is when the file gets rewritten. It fails with the original error
+// if the failure is not a mismatch of values it can accept, or if the same step fails again.
+func (r *ScenarioRunner) acceptActualValues(scenarioPath string, scenario *mj.Scenario, testErr error) error {
+	acceptedSteps := make(map[int]bool)
+	var accepted []string
+	for testErr != nil {
+		if errors.Is(testErr, errScenarioNotSelected) || r.stopsRun(testErr) || len(r.lastStepType) == 0 {
+			return testErr
+		}
+		stepIndex := r.lastStepIndex
+		if acceptedSteps[stepIndex] || !r.acceptStep(scenario, stepIndex) {
+			return testErr
+		}
+		acceptedSteps[stepIndex] = true
+		accepted = append(accepted, mj.DescribeStep(stepIndex, scenario.Steps[stepIndex]))
+
+		r.resetScenarioState()
+		if err := r.resetExecutor(); err != nil {
+			return err
+		}
+		r.Parser.ValueInterpreter.FileResolver.SetContext(scenarioPath)
+		testErr = r.executeScenario(scenarioPath, scenario)
+	}
+
+	_, err := WriteScenarioFile(scenarioPath, scenario, SaveScenarioOptions{MinimalRewrite: true})
+	if err != nil {
+		return fmt.Errorf("cannot accept actual values: %w", err)
+	}
+	for _, step := range accepted {
+		r.stepWarnings = append(r.stepWarnings, step+": accepted actual values")
+	}
+	return nil
+}
+
+// acceptStep replaces the expected values of a step with the actual ones, returning false if none changed.
+// The results of transactions are only known if the executor reports them, see ExecutionContext.RecordStepOutput,
+// the world state, checked by checkState steps, if the executor is a WorldStateReader.
+func (r *ScenarioRunner) acceptStep(scenario *mj.Scenario, stepIndex int) bool {
+	switch step := scenario.Steps[stepIndex].(type) {
+	case *mj.TxStep:
+		if step.ExpectedResult == nil {
+			return false
+		}
+		for _, output := range r.stepOutputs {
+			if len(output.Path) == 0 && output.StepIndex == stepIndex {
+				return acceptTxResult(step.ExpectedResult, output)
+			}
+		}
+	case *mj.CheckStateStep:
+		if worldState, canRead := r.Executor.(WorldStateReader); canRead {
+			changed, err := acceptCheckState(step, worldState)
+			return err == nil && changed
+		}
+	}
+	return false
+}
+
+// acceptTxResult sets the expected out values, status and message to the actual ones, and the gas, refund and logs,
+// if the executor reported them. Values that are not checked, or checked with a matcher, are kept,
+// unless the number of out values differs, in which case all of them are replaced.
+func acceptTxResult(expected *mj.TransactionResult, output *StepOutput) bool {
+	changed := false
+	if len(output.Gas) > 0 && isExact(expected.Gas.IsStar, expected.Gas.Matcher) {
+		gas, err := strconv.ParseUint(output.Gas, 10, 64)
+		if err == nil && expected.Gas.Value != gas {
+			expected.Gas = mj.JSONCheckUint64{Value: gas, Original: output.Gas}
+			changed = true
+		}
+	}
+	refund, _ := big.NewInt(0).SetString(output.Refund, 10)
+	if refund != nil && isExact(expected.Refund.IsStar, expected.Refund.Matcher) && expected.Refund.Value.Cmp(refund) != 0 {
+		expected.Refund = exactBigInt(refund)
+		changed = true
+	}
+	if output.Logs != nil && !expected.IgnoreLogs && len(expected.LogHash) == 0 {
+		logs, err := acceptedLogs(output.Logs)
+		if err != nil {
+			return false
+		}
+		if !equalLogs(expected.Logs, logs) {
+			expected.Logs = logs
+			changed = true
+		}
+	}
+	status, _ := big.NewInt(0).SetString(output.Status, 10)
+	if isExact(expected.Status.IsStar, expected.Status.Matcher) && status != nil && expected.Status.Value.Cmp(status) != 0 {
+		expected.Status = exactBigInt(status)
+		changed = true
+	}
+	if isExact(expected.Message.IsStar, expected.Message.Matcher) && !bytes.Equal(expected.Message.Value, []byte(output.Message)) {
+		expected.Message = mj.JSONCheckBytes{
+			Value:    []byte(output.Message),
+			Original: &oj.OJsonString{Value: messageLiteral(output.Message)},
+		}
+		changed = true
+	}
+
+	out := make([][]byte, len(output.Out))
+	for i, outHex := range output.Out {
+		value, err := hex.DecodeString(strings.TrimPrefix(outHex, "0x"))
+		if err != nil {
+			return false
+		}
+		out[i] = value
+	}
+	if len(expected.Out) != len(out) {
+		expected.Out = make([]mj.JSONCheckBytes, len(out))
+		for i, value := range out {
+			expected.Out[i] = exactBytes(value)
+		}
+		return true
+	}
+	for i, value := range out {
+		if isExact(expected.Out[i].IsStar, expected.Out[i].Matcher) && !bytes.Equal(expected.Out[i].Value, value) {
+			expected.Out[i] = exactBytes(value)
+			changed = true
+		}
+	}
+	return changed
+}
+
+// acceptedLogs converts the reported logs to expected ones.
+func acceptedLogs(stepLogs []*StepLog) ([]*mj.LogEntry, error) {
+	logs := make([]*mj.LogEntry, len(stepLogs))
+	for i, stepLog := range stepLogs {
+		values := append([]string{stepLog.Address, stepLog.Identifier, stepLog.Data}, stepLog.Topics...)
+		decoded := make([]mj.JSONBytesFromString, len(values))
+		for j, value := range values {
+			valueBytes, err := hex.DecodeString(strings.TrimPrefix(value, "0x"))
+			if err != nil {
+				return nil, err
+			}
+			decoded[j] = mj.NewJSONBytesFromString(valueBytes, hexLiteral(valueBytes))
+		}
+		logs[i] = &mj.LogEntry{
+			Address:    decoded[0],
+			Identifier: decoded[1],
+			Data:       decoded[2],
+			Topics:     decoded[3:],
+		}
+	}
+	return logs, nil
+}
+
+// equalLogs compares the values of log entries, not their original forms.
+func equalLogs(expected []*mj.LogEntry, actual []*mj.LogEntry) bool {
+	if len(expected) != len(actual) {
+		return false
+	}
+	for i := range expected {
+		if !bytes.Equal(expected[i].Address.Value, actual[i].Address.Value) ||
+			!bytes.Equal(expected[i].Identifier.Value, actual[i].Identifier.Value) ||
+			!bytes.Equal(expected[i].Data.Value, actual[i].Data.Value) ||
+			len(expected[i].Topics) != len(actual[i].Topics) {
+			return false
+		}
+		for j := range expected[i].Topics {
+			if !bytes.Equal(expected[i].Topics[j].Value, actual[i].Topics[j].Value) {
+				return false
+			}
+		}
+	}
+	return true
+}
+
+// acceptCheckState sets the expected balances, nonces and storage values of the checked accounts
+// to the ones in the world state. Storage keys that are not listed stay unlisted.
+func acceptCheckState(step *mj.CheckStateStep, worldState WorldStateReader) (bool, error) {
+	changed := false
+	for _, account := range step.CheckAccounts.Accounts {
+		address := account.Address.Value
+		if isExact(account.Balance.IsStar, account.Balance.Matcher) {
+			balance, err := worldState.GetBalance(address)
+			if err != nil {
+				return false, err
+			}
+			if account.Balance.Value.Cmp(balance) != 0 {
+				account.Balance = exactBigInt(balance)
+				changed = true
+			}
+		}
+		if isExact(account.Nonce.IsStar, account.Nonce.Matcher) {
+			nonce, err := worldState.GetNonce(address)
+			if err != nil {
+				return false, err
+			}
+			if account.Nonce.Value != nonce {
+				account.Nonce = mj.JSONCheckUint64{Value: nonce, Original: fmt.Sprint(nonce)}
+				changed = true
+			}
+		}
+		for _, kvp := range account.CheckStorage {
+			if !isExact(kvp.AnyValue, kvp.Matcher) {
+				continue
+			}
+			value, err := worldState.GetStorage(address, kvp.Key.Value)
+			if err != nil {
+				return false, err
+			}
+			if !bytes.Equal(kvp.Value.Value, value) {
+				kvp.Value = mj.JSONBytesFromTree{Value: value, Original: &oj.OJsonString{Value: hexLiteral(value)}}
+				changed = true
+			}
+		}
+	}
+	return changed, nil
+}
+
+// isExact returns true for the checks that expect an exact value, the only ones accepting actual values replaces.
+func isExact(isStar bool, matcher mj.ValueMatcher) bool {
+	return !isStar && matcher == nil
+}
+
+func exactBigInt(value *big.Int) mj.JSONCheckBigInt {
+	return mj.JSONCheckBigInt{Value: value, Original: value.String()}
+}
+
+func exactBytes(value []byte) mj.JSONCheckBytes {
+	return mj.JSONCheckBytes{Value: value, Original: &oj.OJsonString{Value: hexLiteral(value)}}
+}
+
+// hexLiteral writes bytes as "0x...", empty values as "".
+func hexLiteral(value []byte) string {
+	if len(value) == 0 {
+		return ""
+	}
+	return "0x" + hex.EncodeToString(value)
+}
+
+// messageLiteral writes messages as "str:...", unless they are not printable text.
+func messageLiteral(message string) string {
+	if len(message) == 0 {
+		return ""
+	}
+	if !utf8.ValidString(message) {
+		return hexLiteral([]byte(message))
+	}
+	for _, c := range message {
+		if c < ' ' || c == 0x7f {
+			return hexLiteral([]byte(message))
+		}
+	}
+	return "str:" + message
+}
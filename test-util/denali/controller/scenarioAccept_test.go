@@ -0,0 +1,131 @@
+package denalicontroller
+
+import (
+	"math/big"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	mj "github.com/numbatx/gn-vm-util/test-util/denali/json/model"
+	"github.com/stretchr/testify/require"
+)
+
+func TestAcceptActualValues(t *testing.T) {
+	scenarioJSON := `{
+	"metadata": {"flavor": "view"},
+	"steps": [
+		{"step": "setState", "accounts": {"address:contract": {
+			"nonce": "0", "balance": "0", "storage": {"str:getSum": "5", "str:getMax": "9"}, "code": ""}}},
+		{"step": "scQuery", "txId": "sum", "tx": {"to": "address:contract", "function": "getSum", "arguments": []},
+			"expect": {"out": ["6"], "status": "0"}},
+		{"step": "scQuery", "txId": "max", "tx": {"to": "address:contract", "function": "getMax", "arguments": []},
+			"expect": {"out": ["match:range:1..10"], "status": "0"}},
+		{"step": "scQuery", "txId": "unknown", "tx": {"to": "address:contract", "function": "getMin", "arguments": []},
+			"expect": {"out": ["*"], "status": "0"}}
+	]
+}`
+	scenarioPath := filepath.Join(t.TempDir(), "view.scen.json")
+	require.Nil(t, os.WriteFile(scenarioPath, []byte(scenarioJSON), 0644))
+
+	executor := &storageQueryExecutor{}
+	executor.Reset()
+	runner := NewViewScenarioRunner(executor, NewDefaultFileResolver())
+	require.NotNil(t, runner.RunSingleJSONScenario(scenarioPath))
+
+	runner.AcceptActualValues = true
+	require.Nil(t, runner.RunSingleJSONScenario(scenarioPath))
+	require.Equal(t, []string{
+		"step 1: accepted actual values",
+		"step 3: accepted actual values",
+	}, runner.stepWarnings)
+
+	accepted, err := os.ReadFile(scenarioPath)
+	require.Nil(t, err)
+	require.Contains(t, string(accepted), `"out": ["0x05"]`)
+	require.Contains(t, string(accepted), `"out": ["match:range:1..10"]`)
+	require.Contains(t, string(accepted), `"out": [], "status": "4"`)
+
+	runner.AcceptActualValues = false
+	require.Nil(t, runner.RunSingleJSONScenario(scenarioPath))
+}
+
+func TestAcceptActualValuesKeepsMatchers(t *testing.T) {
+	err := runViewScenario(t, "getSum", "match:range:6..")
+	require.NotNil(t, err)
+
+	scenarioJSON := strings.ReplaceAll(strings.ReplaceAll(viewScenarioTemplate, "FUNCTION", "getSum"), "OUT", "match:range:6..")
+	scenarioPath := filepath.Join(t.TempDir(), "view.scen.json")
+	require.Nil(t, os.WriteFile(scenarioPath, []byte(scenarioJSON), 0644))
+	executor := &storageQueryExecutor{}
+	executor.Reset()
+	runner := NewViewScenarioRunner(executor, NewDefaultFileResolver())
+	runner.AcceptActualValues = true
+	require.Equal(t, err.Error(), runner.RunSingleJSONScenario(scenarioPath).Error())
+}
+
+func TestAcceptCheckState(t *testing.T) {
+	parser := NewScenarioRunner(nil, NewDefaultFileResolver()).Parser
+	scenario, err := parser.ParseScenarioFile([]byte(`{"steps": [{"step": "checkState", "accounts": {
+		"address:a": {"nonce": "*", "balance": "100", "storage": {"str:x": "1", "str:y": "*"}, "code": "*"},
+		"+": ""}}]}`))
+	require.Nil(t, err)
+	step := scenario.Steps[0].(*mj.CheckStateStep)
+
+	state := &worldQueryExecutor{}
+	state.Reset()
+	address := step.CheckAccounts.Accounts[0].Address.Value
+	state.balances[string(address)] = big.NewInt(150)
+	state.storage["x"] = []byte{2}
+
+	changed, err := acceptCheckState(step, state)
+	require.Nil(t, err)
+	require.True(t, changed)
+	account := step.CheckAccounts.Accounts[0]
+	require.Equal(t, "150", account.Balance.Original)
+	require.True(t, account.Nonce.IsStar)
+	require.Equal(t, []byte{2}, account.CheckStorage[0].Value.Value)
+	require.True(t, account.CheckStorage[1].AnyValue)
+
+	changed, err = acceptCheckState(step, state)
+	require.Nil(t, err)
+	require.False(t, changed)
+}
+
+func TestAcceptTxResultGasRefundAndLogs(t *testing.T) {
+	parser := NewScenarioRunner(nil, NewDefaultFileResolver()).Parser
+	scenario, err := parser.ParseScenarioFile([]byte(`{"steps": [
+		{"step": "scCall", "txId": "1", "tx": {"from": "address:a", "to": "address:b", "function": "f",
+			"arguments": [], "gasLimit": "1000", "gasPrice": "0"},
+			"expect": {"out": [], "status": "0", "gas": "10", "refund": "0", "logs": []}},
+		{"step": "scCall", "txId": "2", "tx": {"from": "address:a", "to": "address:b", "function": "f",
+			"arguments": [], "gasLimit": "1000", "gasPrice": "0"},
+			"expect": {"out": [], "status": "0", "gas": "*", "refund": "*", "logs": "*"}}
+	]}`))
+	require.Nil(t, err)
+	gas := uint64(990)
+	result := &QueryResult{
+		Gas:    &gas,
+		Refund: big.NewInt(3),
+		Logs:   []*QueryLog{{Address: []byte{1}, Identifier: []byte("event"), Topics: [][]byte{{2}}, Data: []byte{3}}},
+	}
+
+	expected := scenario.Steps[0].(*mj.TxStep).ExpectedResult
+	output := newStepOutput(0, scenario.Steps[0].(*mj.TxStep), result)
+	require.True(t, acceptTxResult(expected, output))
+	require.Equal(t, uint64(990), expected.Gas.Value)
+	require.Equal(t, int64(3), expected.Refund.Value.Int64())
+	require.Equal(t, 1, len(expected.Logs))
+	require.Equal(t, "0x6576656e74", expected.Logs[0].Identifier.Original)
+	require.Equal(t, []byte{2}, expected.Logs[0].Topics[0].Value)
+	require.False(t, acceptTxResult(expected, output))
+
+	unchecked := scenario.Steps[1].(*mj.TxStep).ExpectedResult
+	require.False(t, acceptTxResult(unchecked, newStepOutput(1, scenario.Steps[1].(*mj.TxStep), result)))
+	require.True(t, unchecked.Gas.IsStar)
+	require.True(t, unchecked.IgnoreLogs)
+
+	notReported := &StepOutput{Status: "0"}
+	require.False(t, acceptTxResult(expected, notReported))
+	require.Equal(t, 1, len(expected.Logs))
+}
@@ -0,0 +1,346 @@
+package denalicontroller
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"math"
+	"regexp"
+	"strings"
+
+	mj "github.com/numbatx/gn-vm-util/test-util/denali/json/model"
+	vi "github.com/numbatx/gn-vm-util/test-util/denali/json/valueinterpreter"
+	mjwrite "github.com/numbatx/gn-vm-util/test-util/denali/json/write"
+	oj "github.com/numbatx/gn-vm-util/test-util/orderedjson"
+)
+
+// AnonymizeOptions configures AnonymizeScenario.
+type AnonymizeOptions struct {
+	// SameFailure decides whether the anonymized scenario fails the way the original one does.
+	// The replacements are applied to the original error first. Defaults to SameFailureMessage.
+	SameFailure func(originalErr error, anonymizedErr error) bool
+
+	// InlineCode writes contract code inline, as hex, in the anonymized scenario, instead of synthetic placeholders,
+	// for failures that depend on the code. The code then gets shared with the scenario: only meant for code
+	// that may be disclosed, e.g. open source contracts.
+	InlineCode bool
+
+	// Save configures how the anonymized scenario is written.
+	Save SaveScenarioOptions
+}
+
+// ScenarioAnonymization is the result of AnonymizeScenario.
+type ScenarioAnonymization struct {
+	Scenario *mj.Scenario
+
+	// Replacements maps what got replaced, as written in the original scenario, to its synthetic equivalent,
+	// e.g. "address:alice" to "address:account1". Meant for the reporter, not to be shared with the scenario.
+	Replacements map[string]string
+
+	// Failure is the error of the anonymized scenario, nil if it passes, like the original one.
+	Failure error
+}
+
+// AnonymizeScenario writes a copy of a scenario file that can be shared in bug reports: address names,
+// token identifiers and addresses written as raw hex are replaced with synthetic ones, consistently,
+// keeping the bytes that decide the kind and shard of accounts. Contract code is replaced by synthetic placeholders,
+// "str:code1" and so on, unless AnonymizeOptions.InlineCode is set. Names, comments and metadata are dropped,
+// and the other values loaded from files are written inline, so that no file names remain.
+// The scenarios referenced by externalSteps are not anonymized. Both scenarios are run,
+// and the copy is only written if it fails the same way as the original, or passes like it.
+func (r *ScenarioRunner) AnonymizeScenario(scenarioPath string, anonymizedPath string, options AnonymizeOptions) (*ScenarioAnonymization, error) {
+	if options.SameFailure == nil {
+		options.SameFailure = SameFailureMessage
+	}
+	absolutePath, err := r.absolutePath(scenarioPath)
+	if err != nil {
+		return nil, err
+	}
+	content, err := r.readScenarioFile(absolutePath)
+	if err != nil {
+		return nil, err
+	}
+	scenario, err := r.parseScenario(absolutePath, content, nil)
+	if err != nil {
+		return nil, err
+	}
+	originalErr := r.runFromCleanState(absolutePath, scenario)
+
+	a := newScenarioAnonymizer(&r.Parser.ValueInterpreter, scenario)
+	withCode := scenario
+	if !options.InlineCode {
+		var codeReplacements map[string]string
+		withCode, codeReplacements = mjwrite.PlaceholderCode(scenario)
+		for original, placeholder := range codeReplacements {
+			a.replacements[original] = placeholder
+		}
+	}
+	stripped := mjwrite.InlineBlobs(withCode, math.MaxInt32)
+	stripped.Name = ""
+	stripped.Comment = ""
+	stripped.Metadata = nil
+	stripped.Comments = nil
+	tree := mjwrite.ScenarioToOrderedJSON(stripped)
+	a.rewriteTree(tree, a.rewriteNames)
+	a.mapNamedAddresses()
+	a.rewriteTree(tree, a.rewriteRawAddresses)
+
+	absoluteAnonymizedPath, err := r.absolutePath(anonymizedPath)
+	if err != nil {
+		return nil, err
+	}
+	anonymized, err := r.parseScenario(absoluteAnonymizedPath, []byte(oj.JSONString(tree)), nil)
+	if err != nil {
+		return nil, fmt.Errorf("anonymized scenario is invalid: %w", err)
+	}
+	anonymizedErr := r.runFromCleanState(absoluteAnonymizedPath, anonymized)
+	var behaviourErr error
+	switch {
+	case originalErr == nil && anonymizedErr != nil:
+		behaviourErr = fmt.Errorf("anonymized scenario fails, unlike the original one: %w", anonymizedErr)
+	case originalErr != nil && anonymizedErr == nil:
+		behaviourErr = fmt.Errorf("anonymized scenario passes, unlike the original one, failing with: %w", originalErr)
+	case originalErr != nil && !options.SameFailure(errors.New(a.rewriteMessage(originalErr.Error())), anonymizedErr):
+		behaviourErr = fmt.Errorf("anonymized scenario fails with \"%s\", instead of \"%s\"", anonymizedErr, originalErr)
+	}
+	if behaviourErr != nil && !options.InlineCode {
+		return nil, fmt.Errorf("%w (contract code was replaced by placeholders, see AnonymizeOptions.InlineCode)", behaviourErr)
+	}
+	if behaviourErr != nil {
+		return nil, behaviourErr
+	}
+
+	err = SaveScenario(anonymizedPath, anonymized, options.Save)
+	if err != nil {
+		return nil, err
+	}
+	return &ScenarioAnonymization{
+		Scenario:     anonymized,
+		Replacements: a.replacements,
+		Failure:      anonymizedErr,
+	}, nil
+}
+
+var (
+	// anonymizedAddressName matches "address:NAME", the name ending where the enclosing expression continues
+	anonymizedAddressName = regexp.MustCompile(`address:[^|,()\s"]+`)
+	// anonymizedToken matches "token:TICKER" and token identifiers, "TICKER-abcdef", however they are written
+	anonymizedToken  = regexp.MustCompile(`(token:)?\b([A-Z0-9]{3,10})(-[0-9a-f]{6})?\b`)
+	anonymizedRawHex = regexp.MustCompile(`0x[0-9a-fA-F]+`)
+)
+
+// scenarioAnonymizer holds the replacements made so far, so that every occurrence of a value is replaced the same way.
+type scenarioAnonymizer struct {
+	interpreter *vi.ValueInterpreter
+
+	// accounts are the addresses of the accounts in the scenario, as bytes, since they can be written in many ways
+	accounts     map[string]bool
+	addressNames map[string]string
+	tickers      map[string]string
+	suffixes     map[string]string
+	// addresses maps the bytes of the replaced addresses to those of their replacements
+	addresses    map[string][]byte
+	replacements map[string]string
+}
+
+func newScenarioAnonymizer(interpreter *vi.ValueInterpreter, scenario *mj.Scenario) *scenarioAnonymizer {
+	a := &scenarioAnonymizer{
+		interpreter:  interpreter,
+		accounts:     make(map[string]bool),
+		addressNames: make(map[string]string),
+		tickers:      make(map[string]string),
+		suffixes:     make(map[string]string),
+		addresses:    make(map[string][]byte),
+		replacements: make(map[string]string),
+	}
+	addAccount := func(address mj.JSONBytesFromString) {
+		if len(address.Value) == vi.AddressLength {
+			a.accounts[string(address.Value)] = true
+		}
+	}
+	for _, generalStep := range scenario.Steps {
+		switch step := generalStep.(type) {
+		case *mj.SetStateStep:
+			for _, account := range step.Accounts {
+				addAccount(account.Address)
+			}
+			for _, newAddress := range step.NewAddressMocks {
+				addAccount(newAddress.CreatorAddress)
+				addAccount(newAddress.NewAddress)
+			}
+		case *mj.CheckStateStep:
+			if step.CheckAccounts != nil {
+				for _, account := range step.CheckAccounts.Accounts {
+					addAccount(account.Address)
+				}
+			}
+		case *mj.TxStep:
+			if step.Tx != nil {
+				addAccount(step.Tx.From)
+				addAccount(step.Tx.To)
+			}
+		}
+	}
+	return a
+}
+
+// rewriteTree rewrites the strings of a tree in place, map keys included, since accounts and storage are keyed by value.
+// Comments are dropped.
+func (a *scenarioAnonymizer) rewriteTree(obj oj.OJsonObject, rewrite func(string) string) {
+	switch specificObj := obj.(type) {
+	case *oj.OJsonMap:
+		kept := specificObj.OrderedKV[:0]
+		for _, kvp := range specificObj.OrderedKV {
+			if _, isString := kvp.Value.(*oj.OJsonString); isString && kvp.Key == "comment" {
+				continue
+			}
+			kvp.Key = rewrite(kvp.Key)
+			a.rewriteTree(kvp.Value, rewrite)
+			kept = append(kept, kvp)
+		}
+		specificObj.OrderedKV = kept
+		specificObj.RefreshKeySet()
+	case *oj.OJsonList:
+		for _, elem := range specificObj.AsList() {
+			a.rewriteTree(elem, rewrite)
+		}
+	case *oj.OJsonString:
+		specificObj.Value = rewrite(specificObj.Value)
+	}
+}
+
+// rewriteNames replaces address names and token identifiers.
+func (a *scenarioAnonymizer) rewriteNames(value string) string {
+	value = anonymizedAddressName.ReplaceAllStringFunc(value, a.replaceAddressName)
+	return anonymizedToken.ReplaceAllStringFunc(value, a.replaceToken)
+}
+
+func (a *scenarioAnonymizer) replaceAddressName(written string) string {
+	name := strings.TrimPrefix(written, vi.AddressPrefix)
+	shardSuffix := ""
+	if separatorIndex := strings.LastIndex(name, "#"); separatorIndex >= 0 && isDigits(name[separatorIndex+1:]) {
+		name, shardSuffix = name[:separatorIndex], name[separatorIndex:]
+	}
+	replacement, found := a.addressNames[name]
+	if !found {
+		replacement = fmt.Sprintf("account%d", len(a.addressNames)+1)
+		a.addressNames[name] = replacement
+		a.replacements[vi.AddressPrefix+name] = vi.AddressPrefix + replacement
+	}
+	if len(shardSuffix) == 0 && len(name) >= vi.AddressLength {
+		// short names are padded with "_", long ones decide the last byte of the address, hence the shard
+		address, err := a.interpreter.InterpretString(vi.AddressPrefix + name)
+		if err == nil && len(address) > 0 {
+			shardSuffix = fmt.Sprintf("#%d", address[len(address)-1])
+		}
+	}
+	return vi.AddressPrefix + replacement + shardSuffix
+}
+
+func (a *scenarioAnonymizer) replaceToken(written string) string {
+	parts := anonymizedToken.FindStringSubmatch(written)
+	prefix, ticker, suffix := parts[1], parts[2], parts[3]
+	if len(prefix) == 0 && len(suffix) == 0 {
+		// a word, not a token
+		return written
+	}
+	replacement, found := a.tickers[ticker]
+	if !found {
+		replacement = "TKN" + letters(len(a.tickers)+1)
+		a.tickers[ticker] = replacement
+		a.replacements[ticker] = replacement
+	}
+	if len(suffix) > 0 {
+		suffixReplacement, found := a.suffixes[suffix]
+		if !found {
+			suffixReplacement = fmt.Sprintf("-%06x", len(a.suffixes)+1)
+			a.suffixes[suffix] = suffixReplacement
+		}
+		a.replacements[ticker+suffix] = replacement + suffixReplacement
+		replacement += suffixReplacement
+	}
+	return prefix + replacement
+}
+
+// mapNamedAddresses records how the bytes of the replaced address names change,
+// for the accounts that are also written as raw hex, and for error messages.
+func (a *scenarioAnonymizer) mapNamedAddresses() {
+	for name, replacement := range a.addressNames {
+		original, err := a.interpreter.InterpretString(vi.AddressPrefix + name)
+		if err != nil {
+			continue
+		}
+		synthetic, err := a.interpreter.InterpretString(vi.AddressPrefix + replacement)
+		if err != nil || len(synthetic) != len(original) {
+			continue
+		}
+		if len(name) >= vi.AddressLength {
+			synthetic[len(synthetic)-1] = original[len(original)-1]
+		}
+		a.addresses[string(original)] = synthetic
+	}
+}
+
+// rewriteRawAddresses replaces the account addresses written as raw hex.
+func (a *scenarioAnonymizer) rewriteRawAddresses(value string) string {
+	return anonymizedRawHex.ReplaceAllStringFunc(value, func(written string) string {
+		address, err := hex.DecodeString(written[2:])
+		if err != nil || !a.accounts[string(address)] {
+			return written
+		}
+		replacement, found := a.addresses[string(address)]
+		if !found {
+			replacement = syntheticAddress(address, len(a.addresses)+1)
+			a.addresses[string(address)] = replacement
+		}
+		a.replacements[written] = "0x" + hex.EncodeToString(replacement)
+		return a.replacements[written]
+	})
+}
+
+// rewriteMessage applies the replacements to an error message of the original scenario,
+// where addresses can also appear as hex, without the "0x" prefix.
+func (a *scenarioAnonymizer) rewriteMessage(message string) string {
+	message = a.rewriteNames(message)
+	for original, replacement := range a.addresses {
+		message = strings.ReplaceAll(message, hex.EncodeToString([]byte(original)), hex.EncodeToString(replacement))
+	}
+	return message
+}
+
+// syntheticAddress keeps the leading zeros and VM type of contract addresses, and the last byte, which decides the shard.
+func syntheticAddress(original []byte, index int) []byte {
+	const contractPrefixLength = 10
+	result := make([]byte, len(original))
+	copy(result, original)
+	start := 0
+	if len(original) > contractPrefixLength && bytes.Equal(original[:8], make([]byte, 8)) {
+		start = contractPrefixLength
+	}
+	filler := sha256.Sum256([]byte(fmt.Sprintf("account%d", index)))
+	copy(result[start:len(result)-1], filler[:])
+	return result
+}
+
+// letters numbers as spreadsheet columns do: A to Z, then AA, AB, and so on.
+func letters(n int) string {
+	result := ""
+	for ; n > 0; n = (n - 1) / 26 {
+		result = string(rune('A'+(n-1)%26)) + result
+	}
+	return result
+}
+
+func isDigits(text string) bool {
+	if len(text) == 0 {
+		return false
+	}
+	for _, c := range text {
+		if c < '0' || c > '9' {
+			return false
+		}
+	}
+	return true
+}
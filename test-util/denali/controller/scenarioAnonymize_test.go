@@ -0,0 +1,127 @@
+package denalicontroller
+
+import (
+	"bytes"
+	"encoding/hex"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	fr "github.com/numbatx/gn-vm-util/test-util/denali/json/fileresolver"
+	mj "github.com/numbatx/gn-vm-util/test-util/denali/json/model"
+	"github.com/stretchr/testify/require"
+)
+
+// anonymizeTestExecutor fails the step with id "bad" if it calls a contract, naming the sender, token and contract.
+// If sender is set, only transactions from that sender fail.
+type anonymizeTestExecutor struct {
+	sender string
+}
+
+func (e *anonymizeTestExecutor) Reset() {}
+
+func (e *anonymizeTestExecutor) ExecuteScenario(scenario *mj.Scenario, _ fr.FileResolver) error {
+	for i, generalStep := range scenario.Steps {
+		step, isTx := generalStep.(*mj.TxStep)
+		if !isTx || step.ID != "bad" || !bytes.HasPrefix(step.Tx.To.Value, make([]byte, 8)) {
+			continue
+		}
+		if len(e.sender) > 0 && step.Tx.From.Original != e.sender {
+			continue
+		}
+		return fmt.Errorf("step %d: %s cannot send %s to %s",
+			i, step.Tx.From.Original, step.Tx.Arguments[0].Value, hex.EncodeToString(step.Tx.To.Value))
+	}
+	return nil
+}
+
+const anonymizedContract = "0x0000000000000000050011111111111111111111111111111111111111111122"
+
+func writeAnonymizeTestScenario(t *testing.T, dir string) string {
+	scenarioPath := filepath.Join(dir, "secret.scen.json")
+	require.Nil(t, os.WriteFile(filepath.Join(dir, "secret-project.wasm"), []byte("secret code"), 0644))
+	require.Nil(t, os.WriteFile(scenarioPath, []byte(`{
+	"name": "secret project",
+	"comment": "internal",
+	"steps": [
+		{
+			"step": "setState",
+			"comment": "the actors",
+			"accounts": {
+				"address:alice": {"balance": "1000", "storage": {"str:token": "str:WEGLD-abcdef"}},
+				"`+anonymizedContract+`": {"code": "file:secret-project.wasm", "storage": {"str:owner": "address:alice"}}
+			}
+		},
+		{
+			"step": "scCall",
+			"id": "bad",
+			"tx": {
+				"from": "address:alice",
+				"to": "`+anonymizedContract+`",
+				"function": "deposit",
+				"arguments": ["str:WEGLD-abcdef", "token:MEX"],
+				"gasLimit": "1000",
+				"gasPrice": "0"
+			}
+		}
+	]
+}`), 0644))
+	return scenarioPath
+}
+
+func TestAnonymizeScenario(t *testing.T) {
+	dir := t.TempDir()
+	scenarioPath := writeAnonymizeTestScenario(t, dir)
+	runner := NewScenarioRunner(&anonymizeTestExecutor{}, NewDefaultFileResolver())
+	anonymizedPath := filepath.Join(dir, "shared", "bug.scen.json")
+	anonymization, err := runner.AnonymizeScenario(scenarioPath, anonymizedPath, AnonymizeOptions{})
+	require.Nil(t, err)
+
+	require.Equal(t, "address:account1", anonymization.Replacements["address:alice"])
+	require.Equal(t, "TKNA-000001", anonymization.Replacements["WEGLD-abcdef"])
+	require.Equal(t, "TKNB", anonymization.Replacements["MEX"])
+	syntheticContract := anonymization.Replacements[anonymizedContract]
+	require.True(t, strings.HasPrefix(syntheticContract, "0x00000000000000000500"))
+	require.True(t, strings.HasSuffix(syntheticContract, "22"))
+	require.NotEqual(t, anonymizedContract, syntheticContract)
+	require.Equal(t, fmt.Sprintf("step 1: address:account1 cannot send TKNA-000001 to %s", syntheticContract[2:]),
+		anonymization.Failure.Error())
+
+	written := string(mustReadFile(t, anonymizedPath))
+	for _, secret := range []string{"alice", "WEGLD", "MEX", "secret", "internal", "actors", "1111"} {
+		require.NotContains(t, written, secret)
+	}
+	require.Contains(t, written, `"str:owner": "address:account1"`)
+	require.Contains(t, written, `"code": "str:code1"`)
+	require.Equal(t, "str:code1", anonymization.Replacements["file:secret-project.wasm"])
+
+	// the written scenario fails the same way
+	require.Equal(t, anonymization.Failure.Error(), runner.RunSingleJSONScenario(anonymizedPath).Error())
+}
+
+func TestAnonymizeScenarioInlineCode(t *testing.T) {
+	dir := t.TempDir()
+	scenarioPath := writeAnonymizeTestScenario(t, dir)
+	runner := NewScenarioRunner(&anonymizeTestExecutor{}, NewDefaultFileResolver())
+	anonymizedPath := filepath.Join(dir, "bug.scen.json")
+	_, err := runner.AnonymizeScenario(scenarioPath, anonymizedPath, AnonymizeOptions{InlineCode: true})
+	require.Nil(t, err)
+
+	written := string(mustReadFile(t, anonymizedPath))
+	require.Contains(t, written, "0x"+hex.EncodeToString([]byte("secret code")))
+	require.NotContains(t, written, "secret-project")
+}
+
+func TestAnonymizeScenarioChangingBehaviour(t *testing.T) {
+	dir := t.TempDir()
+	scenarioPath := writeAnonymizeTestScenario(t, dir)
+	runner := NewScenarioRunner(&anonymizeTestExecutor{sender: "address:alice"}, NewDefaultFileResolver())
+	anonymizedPath := filepath.Join(dir, "bug.scen.json")
+	_, err := runner.AnonymizeScenario(scenarioPath, anonymizedPath, AnonymizeOptions{})
+	require.NotNil(t, err)
+	require.Contains(t, err.Error(), "passes, unlike the original one")
+	_, err = os.Stat(anonymizedPath)
+	require.True(t, os.IsNotExist(err))
+}
@@ -0,0 +1,75 @@
+package denalicontroller
+
+import (
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"testing"
+)
+
+// ScenarioFileSuffix is the suffix of the files picked up when running scenario directories.
+const ScenarioFileSuffix = ".scen.json"
+
+// RunScenariosAsGoBenchmarks registers each scenario file in a directory as a Go sub-benchmark,
+// so that scenario performance shows up in standard `go test -bench` tooling, e.g. benchstat.
+// Meant to be called from a Benchmark function:
+//
+//	func BenchmarkScenarios(b *testing.B) {
+//		runner := NewScenarioRunner(executor, NewDefaultFileResolver())
+//		runner.RunScenariosAsGoBenchmarks(b, "scenarios")
+//	}
+//
+// Scenarios are parsed once, only executing them is timed. The executor is reset before each run, untimed.
+// A failing scenario fails its benchmark.
+func (r *ScenarioRunner) RunScenariosAsGoBenchmarks(b *testing.B, dir string) {
+	b.Helper()
+	dir, err := filepath.Abs(dir)
+	if err != nil {
+		b.Fatal(err)
+	}
+	scenarioPaths, err := findScenarioFiles(dir)
+	if err != nil {
+		b.Fatal(err)
+	}
+	if len(scenarioPaths) == 0 {
+		b.Fatalf("no scenarios found in %s", dir)
+	}
+
+	for _, scenarioPath := range scenarioPaths {
+		name := strings.TrimSuffix(shortenTestPath(scenarioPath, dir), ScenarioFileSuffix)
+		scenarioPath := scenarioPath
+		b.Run(name, func(b *testing.B) {
+			scenario, err := r.parseScenarioFile(scenarioPath, nil)
+			if err != nil {
+				b.Fatal(err)
+			}
+			b.ResetTimer()
+			for i := 0; i < b.N; i++ {
+				b.StopTimer()
+				r.Executor.Reset()
+				b.StartTimer()
+				err = r.executeScenario(scenarioPath, scenario)
+				if err != nil {
+					b.Fatal(err)
+				}
+			}
+		})
+	}
+}
+
+// findScenarioFiles yields the paths of all scenarios in a directory, recursively, sorted.
+func findScenarioFiles(dir string) ([]string, error) {
+	var scenarioPaths []string
+	err := filepath.Walk(dir, func(path string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+		if !info.IsDir() && strings.HasSuffix(path, ScenarioFileSuffix) {
+			scenarioPaths = append(scenarioPaths, path)
+		}
+		return nil
+	})
+	sort.Strings(scenarioPaths)
+	return scenarioPaths, err
+}
@@ -0,0 +1,52 @@
+package denalicontroller
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	fr "github.com/numbatx/gn-vm-util/test-util/denali/json/fileresolver"
+	mj "github.com/numbatx/gn-vm-util/test-util/denali/json/model"
+	"github.com/stretchr/testify/require"
+)
+
+type countingExecutor struct {
+	executed map[string]int
+	resets   int
+}
+
+func (e *countingExecutor) Reset() {
+	e.resets++
+}
+
+func (e *countingExecutor) ExecuteScenario(scenario *mj.Scenario, _ fr.FileResolver) error {
+	e.executed[scenario.Name]++
+	return nil
+}
+
+func writeBenchTestScenarios(tb testing.TB) string {
+	dir := tb.TempDir()
+	require.Nil(tb, os.MkdirAll(filepath.Join(dir, "sub"), 0755))
+	require.Nil(tb, os.WriteFile(filepath.Join(dir, "a.scen.json"), []byte(`{"name": "a", "steps": []}`), 0644))
+	require.Nil(tb, os.WriteFile(filepath.Join(dir, "sub", "b.scen.json"), []byte(`{"name": "b", "steps": []}`), 0644))
+	require.Nil(tb, os.WriteFile(filepath.Join(dir, "ignored.json"), []byte(`{}`), 0644))
+	return dir
+}
+
+func TestFindScenarioFiles(t *testing.T) {
+	dir := writeBenchTestScenarios(t)
+	scenarioPaths, err := findScenarioFiles(dir)
+	require.Nil(t, err)
+	require.Equal(t, []string{
+		filepath.Join(dir, "a.scen.json"),
+		filepath.Join(dir, "sub", "b.scen.json"),
+	}, scenarioPaths)
+}
+
+func BenchmarkRunScenariosAsGoBenchmarks(b *testing.B) {
+	dir := writeBenchTestScenarios(b)
+	executor := &countingExecutor{executed: make(map[string]int)}
+	runner := NewScenarioRunner(executor, NewDefaultFileResolver())
+	runner.RunScenariosAsGoBenchmarks(b, dir)
+	require.Equal(b, 2, len(executor.executed))
+}
@@ -0,0 +1,217 @@
+package denalicontroller
+
+import (
+	"fmt"
+	"strings"
+)
+
+// diffContextLines is the number of unchanged lines shown around each change.
+const diffContextLines = 3
+
+// maxDiffEdits bounds the work spent on finding a minimal diff. Beyond it, the lines that differ
+// are shown as removed, then added, which is still a correct diff.
+const maxDiffEdits = 2000
+
+type diffOperation int
+
+const (
+	diffEqual diffOperation = iota
+	diffDelete
+	diffInsert
+)
+
+type diffLine struct {
+	operation diffOperation
+	text      string
+}
+
+// unifiedDiff yields the differences between two versions of a file, in the unified format of diff -u,
+// empty if they are the same.
+func unifiedDiff(fileName string, from string, to string) string {
+	if from == to {
+		return ""
+	}
+	lines := diffLines(splitLines(from), splitLines(to))
+	var changes []int
+	for i, line := range lines {
+		if line.operation != diffEqual {
+			changes = append(changes, i)
+		}
+	}
+
+	var sb strings.Builder
+	sb.WriteString("--- " + fileName + "\n")
+	sb.WriteString("+++ " + fileName + "\n")
+	// line numbers, in both versions, of the next line to look at
+	fromLine, toLine, next := 1, 1, 0
+	for i := 0; i < len(changes); {
+		// changes separated by few unchanged lines share a hunk, so that their context does not overlap
+		last := i
+		for last+1 < len(changes) && changes[last+1]-changes[last]-1 <= 2*diffContextLines {
+			last++
+		}
+		hunkStart := changes[i] - diffContextLines
+		if hunkStart < next {
+			hunkStart = next
+		}
+		hunkEnd := changes[last] + diffContextLines + 1
+		if hunkEnd > len(lines) {
+			hunkEnd = len(lines)
+		}
+		for _, line := range lines[next:hunkStart] {
+			fromLine, toLine = advanceLines(line, fromLine, toLine)
+		}
+
+		hunkFromStart, hunkToStart := fromLine, toLine
+		var body strings.Builder
+		for _, line := range lines[hunkStart:hunkEnd] {
+			switch line.operation {
+			case diffEqual:
+				body.WriteString(" " + line.text + "\n")
+			case diffDelete:
+				body.WriteString("-" + line.text + "\n")
+			case diffInsert:
+				body.WriteString("+" + line.text + "\n")
+			}
+			fromLine, toLine = advanceLines(line, fromLine, toLine)
+		}
+		sb.WriteString(fmt.Sprintf("@@ -%s +%s @@\n",
+			hunkRange(hunkFromStart, fromLine-hunkFromStart), hunkRange(hunkToStart, toLine-hunkToStart)))
+		sb.WriteString(body.String())
+
+		next = hunkEnd
+		i = last + 1
+	}
+	return sb.String()
+}
+
+func advanceLines(line diffLine, fromLine int, toLine int) (int, int) {
+	if line.operation != diffInsert {
+		fromLine++
+	}
+	if line.operation != diffDelete {
+		toLine++
+	}
+	return fromLine, toLine
+}
+
+// hunkRange is written as diff does: empty ranges start at the line before.
+func hunkRange(start int, count int) string {
+	if count == 0 {
+		return fmt.Sprintf("%d,0", start-1)
+	}
+	if count == 1 {
+		return fmt.Sprintf("%d", start)
+	}
+	return fmt.Sprintf("%d,%d", start, count)
+}
+
+func splitLines(text string) []string {
+	if len(text) == 0 {
+		return nil
+	}
+	return strings.Split(strings.TrimSuffix(text, "\n"), "\n")
+}
+
+// diffLines finds a minimal edit script with the Myers algorithm, after setting aside the common prefix and suffix.
+func diffLines(from []string, to []string) []diffLine {
+	prefix := 0
+	for prefix < len(from) && prefix < len(to) && from[prefix] == to[prefix] {
+		prefix++
+	}
+	suffix := 0
+	for suffix < len(from)-prefix && suffix < len(to)-prefix && from[len(from)-1-suffix] == to[len(to)-1-suffix] {
+		suffix++
+	}
+
+	var result []diffLine
+	for _, line := range from[:prefix] {
+		result = append(result, diffLine{operation: diffEqual, text: line})
+	}
+	result = append(result, myersDiff(from[prefix:len(from)-suffix], to[prefix:len(to)-suffix])...)
+	for _, line := range from[len(from)-suffix:] {
+		result = append(result, diffLine{operation: diffEqual, text: line})
+	}
+	return result
+}
+
+func myersDiff(from []string, to []string) []diffLine {
+	n, m := len(from), len(to)
+	offset := n + m + 1
+	v := make([]int, 2*offset+1)
+	var trace [][]int
+	for d := 0; d <= n+m; d++ {
+		if d > maxDiffEdits {
+			return replaceAll(from, to)
+		}
+		trace = append(trace, append([]int{}, v[offset-d-1:offset+d+2]...))
+		for k := -d; k <= d; k += 2 {
+			var x int
+			if k == -d || (k != d && v[offset+k-1] < v[offset+k+1]) {
+				x = v[offset+k+1]
+			} else {
+				x = v[offset+k-1] + 1
+			}
+			y := x - k
+			for x < n && y < m && from[x] == to[y] {
+				x++
+				y++
+			}
+			v[offset+k] = x
+			if x >= n && y >= m {
+				return backtrackDiff(from, to, trace)
+			}
+		}
+	}
+	return replaceAll(from, to)
+}
+
+// backtrackDiff walks the trace back from the end. trace[d] holds v[-d-1 .. d+1] as it was before step d.
+func backtrackDiff(from []string, to []string, trace [][]int) []diffLine {
+	var reversed []diffLine
+	x, y := len(from), len(to)
+	for d := len(trace) - 1; d >= 0; d-- {
+		v := func(k int) int {
+			return trace[d][k+d+1]
+		}
+		k := x - y
+		var previousK int
+		if k == -d || (k != d && v(k-1) < v(k+1)) {
+			previousK = k + 1
+		} else {
+			previousK = k - 1
+		}
+		previousX := v(previousK)
+		previousY := previousX - previousK
+		for x > previousX && y > previousY {
+			reversed = append(reversed, diffLine{operation: diffEqual, text: from[x-1]})
+			x--
+			y--
+		}
+		if d > 0 {
+			if x == previousX {
+				reversed = append(reversed, diffLine{operation: diffInsert, text: to[y-1]})
+			} else {
+				reversed = append(reversed, diffLine{operation: diffDelete, text: from[x-1]})
+			}
+		}
+		x, y = previousX, previousY
+	}
+
+	result := make([]diffLine, len(reversed))
+	for i, line := range reversed {
+		result[len(reversed)-1-i] = line
+	}
+	return result
+}
+
+func replaceAll(from []string, to []string) []diffLine {
+	var result []diffLine
+	for _, line := range from {
+		result = append(result, diffLine{operation: diffDelete, text: line})
+	}
+	for _, line := range to {
+		result = append(result, diffLine{operation: diffInsert, text: line})
+	}
+	return result
+}
@@ -3,50 +3,391 @@ package denalicontroller
 import (
 	"errors"
 	"fmt"
+	"io/fs"
 	"os"
 	"path"
 	"path/filepath"
 	"strings"
+	"time"
+
+	mjparse "github.com/numbatx/gn-vm-util/test-util/denali/json/parse"
+	oj "github.com/numbatx/gn-vm-util/test-util/orderedjson"
 )
 
 // RunAllJSONScenariosInDirectory walks directory, parses and prepares all json scenarios,
-// then calls scenarioExecutor for each of them.
+// then calls scenarioExecutor for each of them, in the order given by the runner Order.
 func (r *ScenarioRunner) RunAllJSONScenariosInDirectory(
 	generalTestPath string,
 	specificTestPath string,
 	allowedSuffix string,
 	excludedFilePatterns []string) error {
 
+	_, err := r.RunAllJSONScenariosInDirectoryWithReport(
+		generalTestPath,
+		specificTestPath,
+		allowedSuffix,
+		excludedFilePatterns)
+	return err
+}
+
+// RunAllJSONScenariosInDirectoryWithReport is RunAllJSONScenariosInDirectory, also yielding a structured report.
+// The report is returned even if some scenarios failed, or the run was stopped because the executor panicked,
+// or did not stop once timed out.
+func (r *ScenarioRunner) RunAllJSONScenariosInDirectoryWithReport(
+	generalTestPath string,
+	specificTestPath string,
+	allowedSuffix string,
+	excludedFilePatterns []string) (*RunReport, error) {
+
 	mainDirPath := path.Join(generalTestPath, specificTestPath)
-	var nrPassed, nrFailed, nrSkipped int
-
-	err := filepath.Walk(mainDirPath, func(testFilePath string, info os.FileInfo, err error) error {
-		if strings.HasSuffix(testFilePath, allowedSuffix) {
-			fmt.Printf("Scenario: %s ... ", shortenTestPath(testFilePath, generalTestPath))
-			if isExcluded(excludedFilePatterns, testFilePath, generalTestPath) {
-				nrSkipped++
-				fmt.Print("  skip\n")
-			} else {
-				r.Executor.Reset()
-				testErr := r.RunSingleJSONScenario(testFilePath)
-				if testErr == nil {
-					nrPassed++
-					fmt.Print("  ok\n")
-				} else {
-					nrFailed++
-					fmt.Printf("  FAIL: %s\n", testErr.Error())
-				}
+	report := &RunReport{
+		StartedAt: time.Now(),
+	}
+
+	scenarioPaths, err := r.listScenarioFiles(mainDirPath, func(testFilePath string) bool {
+		return strings.HasSuffix(testFilePath, allowedSuffix)
+	})
+	if err != nil {
+		return report, err
+	}
+
+	return r.runScenarios(report, scenarioPaths, &scenarioRunNaming{
+		defaultSink: func() ReportSink {
+			return NewStdoutReportSink(generalTestPath)
+		},
+		isExcluded: func(testFilePath string) bool {
+			return isExcluded(excludedFilePatterns, testFilePath, generalTestPath)
+		},
+		shortPath: func(testFilePath string) string {
+			return shortenTestPath(testFilePath, generalTestPath)
+		},
+	})
+}
+
+// DirectoryRunOptions configures RunAllJSONScenariosInDirectoryWithOptions.
+type DirectoryRunOptions struct {
+	// Glob selects the scenario files, matching their names, e.g. "*.scen.json",
+	// or their paths relative to the directory if it has a "/", e.g. "*/features/*.scen.json".
+	// Defaults to DefaultScenarioGlob. Globs follow path.Match: "*" does not cross "/",
+	// and there is no "**", each directory level needs its own "*".
+	Glob string
+
+	// ExcludedFilePatterns are patterns of paths relative to the directory, of the files to skip,
+	// following path.Match like the glob.
+	ExcludedFilePatterns []string
+
+	// NamePattern, if set, is a regular expression that the "name" of the scenarios to run must match,
+	// e.g. "^staking". The others are reported as skipped.
+	NamePattern string
+
+	// Tags, if set, only run the scenarios tagged with at least one of them, ExcludedTags skip
+	// those tagged with any of them, e.g. "slow". The tags of a scenario include those of its steps.
+	Tags         []string
+	ExcludedTags []string
+}
+
+// DefaultScenarioGlob selects the files that directory runs consider scenarios, unless configured otherwise.
+const DefaultScenarioGlob = "*" + ScenarioFileSuffix
+
+// RunAllJSONScenariosInDirectoryWithOptions is RunAllJSONScenariosInDirectoryWithReport,
+// selecting the scenarios of the directory tree with a glob, instead of a suffix, and by their names and tags.
+// All the selected scenarios run, failing or not, their results are in the report.
+func (r *ScenarioRunner) RunAllJSONScenariosInDirectoryWithOptions(dirPath string, options DirectoryRunOptions) (*RunReport, error) {
+	report := &RunReport{
+		StartedAt: time.Now(),
+	}
+	scenarioPaths, err := r.listDirectoryScenarios(dirPath, options)
+	if err != nil {
+		return report, err
+	}
+	return r.runDirectoryScenarios(report, dirPath, scenarioPaths, options)
+}
+
+// listDirectoryScenarios lists the scenario files of the directory tree that match the glob of the options.
+func (r *ScenarioRunner) listDirectoryScenarios(dirPath string, options DirectoryRunOptions) ([]string, error) {
+	glob := options.Glob
+	if len(glob) == 0 {
+		glob = DefaultScenarioGlob
+	}
+	if _, err := path.Match(glob, ""); err != nil {
+		return nil, fmt.Errorf("invalid scenario glob %s: %w", glob, err)
+	}
+	if err := validateExcludedFilePatterns(options.ExcludedFilePatterns); err != nil {
+		return nil, err
+	}
+	return r.listScenarioFiles(dirPath, func(testFilePath string) bool {
+		name := filepath.Base(testFilePath)
+		if strings.Contains(glob, "/") {
+			name = filepath.ToSlash(shortenTestPath(testFilePath, dirPath))
+		}
+		match, _ := path.Match(glob, name)
+		return match
+	})
+}
+
+// validateExcludedFilePatterns rejects malformed patterns before the run starts,
+// so that matching them against scenario paths cannot fail.
+func validateExcludedFilePatterns(excludedFilePatterns []string) error {
+	for _, pattern := range excludedFilePatterns {
+		if _, err := path.Match(pattern, ""); err != nil {
+			return fmt.Errorf("invalid excluded file pattern %s: %w", pattern, err)
+		}
+	}
+	return nil
+}
+
+// runDirectoryScenarios runs scenario files of the directory tree, applying the exclusions and filters of the options.
+func (r *ScenarioRunner) runDirectoryScenarios(
+	report *RunReport,
+	dirPath string,
+	scenarioPaths []string,
+	options DirectoryRunOptions) (*RunReport, error) {
+
+	filter, err := newScenarioFilter(options)
+	if err != nil {
+		return report, err
+	}
+	r.filter = filter
+	defer func() {
+		r.filter = nil
+	}()
+
+	return r.runScenarios(report, scenarioPaths, &scenarioRunNaming{
+		defaultSink: func() ReportSink {
+			return NewStdoutReportSink(dirPath)
+		},
+		isExcluded: func(testFilePath string) bool {
+			return isExcluded(options.ExcludedFilePatterns, testFilePath, dirPath)
+		},
+		shortPath: func(testFilePath string) string {
+			return shortenTestPath(testFilePath, dirPath)
+		},
+	})
+}
+
+// listScenarioFiles walks a directory of the runner FS, if set, otherwise of the OS file system,
+// listing the files that are selected.
+func (r *ScenarioRunner) listScenarioFiles(dirPath string, selected func(testFilePath string) bool) ([]string, error) {
+	var scenarioPaths []string
+	if r.FS != nil {
+		err := fs.WalkDir(r.FS, dirPath, func(testFilePath string, entry fs.DirEntry, err error) error {
+			if err == nil && !entry.IsDir() && selected(testFilePath) {
+				scenarioPaths = append(scenarioPaths, testFilePath)
 			}
+			return nil
+		})
+		return scenarioPaths, err
+	}
+	err := filepath.Walk(dirPath, func(testFilePath string, info os.FileInfo, err error) error {
+		// unreadable files are listed, so that their runs report the error
+		if (err != nil || !info.IsDir()) && selected(testFilePath) {
+			scenarioPaths = append(scenarioPaths, testFilePath)
 		}
 		return nil
 	})
-	if err != nil {
-		return err
+	return scenarioPaths, err
+}
+
+// scenarioRunNaming adapts runScenarios to how the scenarios of a run are named,
+// relative to a directory, or across a workspace.
+type scenarioRunNaming struct {
+	defaultSink func() ReportSink
+	isExcluded  func(testFilePath string) bool
+	shortPath   func(testFilePath string) string
+}
+
+// runScenarios runs the scenarios in the order given by the runner Order, reporting them to the sinks.
+func (r *ScenarioRunner) runScenarios(
+	report *RunReport,
+	scenarioPaths []string,
+	naming *scenarioRunNaming) (*RunReport, error) {
+
+	sinks := r.ReportSinks
+	if len(sinks) == 0 {
+		sinks = []ReportSink{naming.defaultSink()}
+	}
+	var sinkErrs []error
+
+	r.orderScenarios(scenarioPaths)
+
+	var stopErr error
+	for _, testFilePath := range scenarioPaths {
+		testErr := r.runScenarioFileOfDirectory(testFilePath, naming.isExcluded(testFilePath),
+			func(scenarioReport *ScenarioReport) {
+				report.Scenarios = append(report.Scenarios, scenarioReport)
+				for _, sink := range sinks {
+					if sinkErr := sink.ScenarioDone(scenarioReport); sinkErr != nil {
+						sinkErrs = append(sinkErrs, sinkErr)
+					}
+				}
+			})
+		if r.stopsRun(testErr) {
+			stopErr = fmt.Errorf("run stopped, %s: %w", naming.shortPath(testFilePath), testErr)
+			report.Stopped = true
+			break
+		}
+	}
+	return report, finishRun(report, sinks, stopErr, sinkErrs)
+}
+
+// finishRun hands the complete report to the sinks, and yields the error of the run, if any.
+// The other errors, e.g. of the sinks, are joined to it.
+func finishRun(report *RunReport, sinks []ReportSink, stopErr error, otherErrs []error) error {
+	report.Duration = time.Since(report.StartedAt)
+	for _, sink := range sinks {
+		if sinkErr := sink.RunDone(report); sinkErr != nil {
+			otherErrs = append(otherErrs, sinkErr)
+		}
+	}
+
+	var err error
+	switch {
+	case stopErr != nil:
+		err = stopErr
+	case report.Count(ScenarioFailed) > 0:
+		err = errors.New("Some tests failed")
+	}
+	if len(otherErrs) > 0 {
+		err = errors.Join(append([]error{err}, otherErrs...)...)
 	}
-	fmt.Printf("Done. Passed: %d. Failed: %d. Skipped: %d.\n", nrPassed, nrFailed, nrSkipped)
-	if nrFailed > 0 {
-		return errors.New("Some tests failed")
+	return err
+}
+
+// runScenarioFileOfDirectory runs or skips the scenarios of a file, handing over the report of each.
+// Files holding several scenarios, see mjparse.SplitScenarioDocuments, yield a report per scenario,
+// named by MultiScenarioSuffix. Yields the error that stops the run, if any, see ContinueOnError.
+func (r *ScenarioRunner) runScenarioFileOfDirectory(
+	testFilePath string,
+	excluded bool,
+	scenarioDone func(*ScenarioReport)) error {
+
+	var content []byte
+	if !excluded {
+		var splitErr error
+		content, splitErr = r.readScenarioFile(testFilePath)
+		var documents []*mjparse.ScenarioDocument
+		if splitErr == nil {
+			documents, splitErr = mjparse.SplitScenarioDocuments(content)
+		}
+		if splitErr != nil {
+			// reported by the regular run
+			content = nil
+		}
+		if len(documents) > 0 {
+			return r.runScenarioDocumentsOfDirectory(testFilePath, documents, scenarioDone)
+		}
 	}
 
+	scenarioReport, testErr := r.runScenarioOfDirectory(testFilePath, excluded, func() error {
+		return r.runScenarioWithArguments(testFilePath, content, nil)
+	})
+	scenarioDone(scenarioReport)
+	return testErr
+}
+
+// runScenarioDocumentsOfDirectory runs the scenarios of a multi-document file, each as a test of its own.
+func (r *ScenarioRunner) runScenarioDocumentsOfDirectory(
+	testFilePath string,
+	documents []*mjparse.ScenarioDocument,
+	scenarioDone func(*ScenarioReport)) error {
+
+	for _, document := range documents {
+		document := document
+		scenarioReport, testErr := r.runScenarioOfDirectory(testFilePath+MultiScenarioSuffix(document.Index), false,
+			func() error {
+				return r.runScenarioDocument(testFilePath, document)
+			})
+		scenarioDone(scenarioReport)
+		if r.stopsRun(testErr) {
+			return testErr
+		}
+	}
 	return nil
 }
+
+// runScenarioOfDirectory runs or skips a scenario, yielding its report, and the error it failed with.
+func (r *ScenarioRunner) runScenarioOfDirectory(reportPath string, excluded bool, run func() error) (*ScenarioReport, error) {
+
+	scenarioReport := &ScenarioReport{
+		Path:      reportPath,
+		StartedAt: time.Now(),
+	}
+	if excluded {
+		scenarioReport.Status = ScenarioSkipped
+		if absPath, absErr := r.absolutePath(reportPath); absErr == nil {
+			_ = r.AuditLog.Record(&AuditEntry{
+				Event:  AuditScenarioSkipped,
+				Path:   absPath,
+				Status: ScenarioSkipped,
+			})
+		}
+		return scenarioReport, nil
+	}
+
+	r.Parser.ValueInterpreter.TakeDiagnostics()
+	testErr := r.resetExecutor()
+	if testErr == nil {
+		testErr = run()
+	}
+	scenarioReport.Duration = time.Since(scenarioReport.StartedAt)
+	scenarioReport.Name = r.scenarioName
+	scenarioReport.Steps = r.stepsExecuted
+	scenarioReport.Outputs = r.stepOutputs
+	scenarioReport.Warnings = append(scenarioReport.Warnings, r.stepWarnings...)
+	for _, diagnostic := range r.Parser.ValueInterpreter.TakeDiagnostics() {
+		scenarioReport.Warnings = append(scenarioReport.Warnings, diagnostic.String())
+	}
+	switch {
+	case testErr == nil:
+		scenarioReport.Status = ScenarioPassed
+		return scenarioReport, nil
+	case errors.Is(testErr, errScenarioNotSelected):
+		scenarioReport.Status = ScenarioSkipped
+		return scenarioReport, nil
+	}
+	scenarioReport.Status = ScenarioFailed
+	scenarioReport.Error = testErr.Error()
+	scenarioReport.Locations = r.failureLocations(testErr)
+	scenarioReport.FailedStepType = r.lastStepType
+	var panicErr *ExecutorPanicError
+	if errors.As(testErr, &panicErr) {
+		scenarioReport.StackTrace = panicErr.Stack
+	}
+	return scenarioReport, testErr
+}
+
+// failureLocations locates the error a scenario failed with: the problems of parse errors,
+// otherwise the last step that ran, if the executor reports its steps.
+func (r *ScenarioRunner) failureLocations(testErr error) []*SourceLocation {
+	var validationErr *mjparse.ValidationError
+	var parseErr *mjparse.ParseError
+	var problems []*mjparse.ParseError
+	switch {
+	case errors.As(testErr, &validationErr):
+		problems = validationErr.Problems
+	case errors.As(testErr, &parseErr):
+		problems = []*mjparse.ParseError{parseErr}
+	case r.lastStep != nil:
+		location := *r.lastStep
+		location.Message = testErr.Error()
+		return []*SourceLocation{&location}
+	}
+
+	var locations []*SourceLocation
+	for _, problem := range problems {
+		if len(problem.File) == 0 || problem.Position.Line == 0 {
+			continue
+		}
+		// the message without the location
+		cause := *problem
+		cause.File = ""
+		cause.Position = oj.Position{}
+		locations = append(locations, &SourceLocation{
+			Path:    problem.File,
+			Line:    problem.Position.Line,
+			Column:  problem.Position.Column,
+			Message: cause.Error(),
+		})
+	}
+	return locations
+}
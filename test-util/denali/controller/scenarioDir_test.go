@@ -0,0 +1,101 @@
+package denalicontroller
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func writeScenarioTree(t *testing.T) string {
+	dir := t.TempDir()
+	for _, name := range []string{"a_passes.scen.json", "nested/b_fails.scen.json", "nested/deeper/c_passes.scen.json",
+		"nested/d_fails.steps.json", "e_fails.json"} {
+		scenarioPath := filepath.Join(dir, filepath.FromSlash(name))
+		require.Nil(t, os.MkdirAll(filepath.Dir(scenarioPath), os.ModePerm))
+		// named "passes" or "fails", after the file
+		scenarioName := strings.Split(filepath.Base(name)[2:], ".")[0]
+		scenarioJSON := `{"name": "` + scenarioName + `", "steps": []}`
+		require.Nil(t, os.WriteFile(scenarioPath, []byte(scenarioJSON), 0644))
+	}
+	return dir
+}
+
+func TestRunAllJSONScenariosInDirectoryWithOptions(t *testing.T) {
+	dir := writeScenarioTree(t)
+	executor := &panickingExecutor{}
+	runner := NewScenarioRunner(executor, NewDefaultFileResolver())
+	var stdout bytes.Buffer
+	runner.ReportSinks = []ReportSink{&StdoutReportSink{writer: &stdout, basePath: dir}}
+
+	// failures do not stop the run
+	report, err := runner.RunAllJSONScenariosInDirectoryWithOptions(dir, DirectoryRunOptions{})
+	require.NotNil(t, err)
+	require.Equal(t, 3, len(report.Scenarios))
+	require.Equal(t, 2, report.Count(ScenarioPassed))
+	require.Equal(t, 1, report.Count(ScenarioFailed))
+	require.Contains(t, stdout.String(), "Scenario: nested/b_fails.scen.json ...   FAIL: check failed\n")
+
+	report, err = runner.RunAllJSONScenariosInDirectoryWithOptions(dir, DirectoryRunOptions{
+		Glob:                 "*.json",
+		ExcludedFilePatterns: []string{"nested/*"},
+	})
+	require.NotNil(t, err)
+	require.Equal(t, 5, len(report.Scenarios))
+	require.Equal(t, 2, report.Count(ScenarioSkipped))
+
+	// globs with a "/" match paths relative to the directory
+	report, err = runner.RunAllJSONScenariosInDirectoryWithOptions(dir, DirectoryRunOptions{Glob: "nested/*/*.scen.json"})
+	require.Nil(t, err)
+	require.Equal(t, 1, len(report.Scenarios))
+	require.Equal(t, filepath.Join(dir, "nested", "deeper", "c_passes.scen.json"), report.Scenarios[0].Path)
+
+	_, err = runner.RunAllJSONScenariosInDirectoryWithOptions(dir, DirectoryRunOptions{Glob: "[.scen.json"})
+	require.NotNil(t, err)
+
+	// invalid exclusions fail the run before anything runs, instead of panicking in the middle of it
+	executor.executed = nil
+	_, err = runner.RunAllJSONScenariosInDirectoryWithOptions(dir, DirectoryRunOptions{ExcludedFilePatterns: []string{"nested/["}})
+	require.NotNil(t, err)
+	require.Empty(t, executor.executed)
+}
+
+func TestRunAllJSONScenariosInDirectoryFiltered(t *testing.T) {
+	dir := t.TempDir()
+	for fileName, scenarioJSON := range map[string]string{
+		"staking_passes.scen.json": `{"name": "staking rewards", "metadata": {"tags": ["staking"]}, "steps": []}`,
+		"staking_slow.scen.json": `{"name": "staking slow", "steps": [
+			{"step": "setState", "tags": ["slow"]}
+		]}`,
+		"swap_fails.scen.json": `{"name": "fails", "steps": []}`,
+	} {
+		require.Nil(t, os.WriteFile(filepath.Join(dir, fileName), []byte(scenarioJSON), 0644))
+	}
+	runner := NewScenarioRunner(&panickingExecutor{}, NewDefaultFileResolver())
+	runner.ReportSinks = []ReportSink{NewJSONReportSink(&bytes.Buffer{})}
+
+	report, err := runner.RunAllJSONScenariosInDirectoryWithOptions(dir, DirectoryRunOptions{
+		NamePattern:  "^staking",
+		ExcludedTags: []string{"slow"},
+	})
+	require.Nil(t, err)
+	require.Equal(t, 1, report.Count(ScenarioPassed))
+	require.Equal(t, 2, report.Count(ScenarioSkipped))
+	require.Equal(t, "staking rewards", report.Scenarios[0].Name)
+
+	// step tags count as tags of the scenario
+	report, err = runner.RunAllJSONScenariosInDirectoryWithOptions(dir, DirectoryRunOptions{Tags: []string{"staking", "slow"}})
+	require.Nil(t, err)
+	require.Equal(t, 2, report.Count(ScenarioPassed))
+
+	// the filters only apply to that run
+	report, err = runner.RunAllJSONScenariosInDirectoryWithOptions(dir, DirectoryRunOptions{})
+	require.NotNil(t, err)
+	require.Equal(t, 1, report.Count(ScenarioFailed))
+
+	_, err = runner.RunAllJSONScenariosInDirectoryWithOptions(dir, DirectoryRunOptions{NamePattern: "("})
+	require.NotNil(t, err)
+}
@@ -0,0 +1,154 @@
+package denalicontroller
+
+import (
+	"fmt"
+	"strings"
+
+	mj "github.com/numbatx/gn-vm-util/test-util/denali/json/model"
+	mjparse "github.com/numbatx/gn-vm-util/test-util/denali/json/parse"
+)
+
+// ScenarioRunOptions configures RunSingleJSONScenarioWithOptions.
+type ScenarioRunOptions struct {
+	// DryRun checks the scenario without running it: it gets parsed, with all its values interpreted
+	// and the files they load read, and so do the scenarios it includes, recursively, and the SeedStatePath file.
+	// The executor is never called. Meant as a fast check for scenario authors, e.g. before committing.
+	DryRun bool
+}
+
+// DryRunError lists all the problems that a dry run found, see ScenarioRunOptions.DryRun.
+type DryRunError struct {
+	Problems []error
+}
+
+// Error yields the number of problems, followed by each of them, on its own line.
+func (e *DryRunError) Error() string {
+	var sb strings.Builder
+	if len(e.Problems) == 1 {
+		sb.WriteString("dry run found 1 problem:")
+	} else {
+		fmt.Fprintf(&sb, "dry run found %d problems:", len(e.Problems))
+	}
+	for _, problem := range e.Problems {
+		sb.WriteString("\n    ")
+		sb.WriteString(strings.ReplaceAll(problem.Error(), "\n", "\n    "))
+	}
+	return sb.String()
+}
+
+// Unwrap yields the problems, so that errors.As finds them.
+func (e *DryRunError) Unwrap() []error {
+	return e.Problems
+}
+
+// RunSingleJSONScenarioWithOptions is RunSingleJSONScenario, unless the options ask for a dry run,
+// in which case the problems found are returned as a *DryRunError, nil if there are none.
+func (r *ScenarioRunner) RunSingleJSONScenarioWithOptions(contextPath string, options ScenarioRunOptions) error {
+	if !options.DryRun {
+		return r.RunSingleJSONScenario(contextPath)
+	}
+	absolutePath, err := r.absolutePath(contextPath)
+	if err != nil {
+		return err
+	}
+
+	dryRun := &scenarioDryRun{runner: r}
+	if len(r.SeedStatePath) > 0 {
+		dryRun.checkSeedState()
+	}
+	dryRun.checkScenarioFile(absolutePath, nil)
+	if len(dryRun.problems) > 0 {
+		return &DryRunError{Problems: dryRun.problems}
+	}
+	return nil
+}
+
+// scenarioDryRun collects the problems of a scenario and of the files it depends on.
+type scenarioDryRun struct {
+	runner   *ScenarioRunner
+	problems []error
+}
+
+func (d *scenarioDryRun) checkSeedState() {
+	r := d.runner
+	statePath, err := r.absolutePath(r.SeedStatePath)
+	if err != nil {
+		d.problems = append(d.problems, err)
+		return
+	}
+	if _, canSeed := r.Executor.(StateSeedExecutor); !canSeed {
+		d.problems = append(d.problems, fmt.Errorf("cannot seed state from %s: executor does not support it", statePath))
+	}
+	if _, err := r.loadSeedState(statePath); err != nil {
+		d.problems = append(d.problems, err)
+	}
+}
+
+// checkScenarioFile parses a scenario file, or each scenario of a multi-document file, see mjparse.SplitScenarioDocuments.
+// The arguments are those of the externalSteps step including the scenario, if any.
+func (d *scenarioDryRun) checkScenarioFile(scenarioPath string, arguments []*mj.NamedConstant) {
+	r := d.runner
+	r.contextPaths = append(r.contextPaths, scenarioPath)
+	defer r.restoreContext()
+
+	content, err := r.readScenarioFile(scenarioPath)
+	if err != nil {
+		d.problems = append(d.problems, err)
+		return
+	}
+	documents, err := mjparse.SplitScenarioDocuments(content)
+	if err != nil {
+		d.problems = append(d.problems, withErrorFile(err, scenarioPath))
+		return
+	}
+	if len(documents) == 0 {
+		scenario, err := r.parseScenario(scenarioPath, content, arguments)
+		d.checkScenario(scenarioPath, "", scenario, err)
+		return
+	}
+	for _, document := range documents {
+		r.Parser.ValueInterpreter.FileResolver.SetContext(scenarioPath)
+		scenario, err := r.Parser.ParseScenarioDocument(document)
+		d.checkScenario(scenarioPath, MultiScenarioSuffix(document.Index), scenario, withErrorFile(err, scenarioPath))
+	}
+}
+
+// checkScenario records the parse error, if any, otherwise checks the scenarios included by the parsed one.
+// Problems of included scenarios are prefixed with the steps including them.
+func (d *scenarioDryRun) checkScenario(scenarioPath string, documentSuffix string, scenario *mj.Scenario, parseErr error) {
+	if parseErr != nil {
+		d.problems = append(d.problems, parseErr)
+		return
+	}
+	for stepIndex, step := range scenario.Steps {
+		externalSteps, isExternal := step.(*mj.ExternalStepsStep)
+		if !isExternal {
+			continue
+		}
+		includedPath := externalSteps.ResolvedPath
+		if len(includedPath) == 0 {
+			includedPath = d.runner.Parser.ValueInterpreter.FileResolver.ResolveAbsolutePath(externalSteps.Path)
+		}
+		prefix := fmt.Sprintf("%s%s: %s", scenarioPath, documentSuffix, mj.DescribeStep(stepIndex, step))
+		if d.isBeingChecked(includedPath) {
+			d.problems = append(d.problems, fmt.Errorf("%s: %s includes itself", prefix, includedPath))
+			continue
+		}
+
+		problemsBefore := len(d.problems)
+		d.checkScenarioFile(includedPath, externalSteps.Arguments)
+		for i := problemsBefore; i < len(d.problems); i++ {
+			d.problems[i] = fmt.Errorf("%s: %w", prefix, d.problems[i])
+		}
+	}
+}
+
+// isBeingChecked returns true for the scenarios that include the one at hand, directly or not.
+func (d *scenarioDryRun) isBeingChecked(scenarioPath string) bool {
+	for _, contextPath := range d.runner.contextPaths {
+		if contextPath == scenarioPath {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,62 @@
+package denalicontroller
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+
+	mjparse "github.com/numbatx/gn-vm-util/test-util/denali/json/parse"
+	"github.com/stretchr/testify/require"
+)
+
+func TestDryRun(t *testing.T) {
+	dir := t.TempDir()
+	for name, content := range map[string]string{
+		"main.scen.json": `{"steps": [
+			{"step": "externalSteps", "path": "included.steps.json"},
+			{"step": "externalSteps", "path": "missing.steps.json"},
+			{"step": "externalSteps", "path": "main.scen.json"}
+		]}`,
+		"included.steps.json": `{"steps": [{"step": "setState", "accounts": {"address:a": {"code": "file:missing.wasm"}}}]}`,
+		"valid.scen.json":     `{"steps": [{"step": "externalSteps", "path": "empty.steps.json"}]}`,
+		"empty.steps.json":    `{"steps": []}`,
+	} {
+		require.Nil(t, os.WriteFile(filepath.Join(dir, name), []byte(content), 0644))
+	}
+
+	// the executor is never called
+	runner := NewScenarioRunner(nil, NewDefaultFileResolver())
+	options := ScenarioRunOptions{DryRun: true}
+	require.Nil(t, runner.RunSingleJSONScenarioWithOptions(filepath.Join(dir, "valid.scen.json"), options))
+
+	err := runner.RunSingleJSONScenarioWithOptions(filepath.Join(dir, "main.scen.json"), options)
+	var dryRunErr *DryRunError
+	require.True(t, errors.As(err, &dryRunErr))
+	require.Equal(t, 3, len(dryRunErr.Problems))
+	mainPath := filepath.Join(dir, "main.scen.json")
+	require.Contains(t, dryRunErr.Problems[0].Error(), mainPath+": step 0: ")
+	require.Contains(t, dryRunErr.Problems[0].Error(), "missing.wasm")
+	require.Contains(t, dryRunErr.Problems[1].Error(), mainPath+": step 1: ")
+	require.Contains(t, dryRunErr.Problems[1].Error(), "missing.steps.json")
+	require.Equal(t, mainPath+": step 2: "+mainPath+" includes itself", dryRunErr.Problems[2].Error())
+	require.Empty(t, runner.contextPaths)
+
+	runner.SeedStatePath = filepath.Join(dir, "main.scen.json")
+	err = runner.RunSingleJSONScenarioWithOptions(filepath.Join(dir, "valid.scen.json"), options)
+	require.True(t, errors.As(err, &dryRunErr))
+	require.Equal(t, 2, len(dryRunErr.Problems))
+	require.Contains(t, dryRunErr.Problems[0].Error(), "executor does not support it")
+	require.Contains(t, dryRunErr.Problems[1].Error(), "only setState steps are allowed")
+}
+
+func TestDryRunLocatesParseErrors(t *testing.T) {
+	scenarioPath := filepath.Join(t.TempDir(), "invalid.scen.json")
+	require.Nil(t, os.WriteFile(scenarioPath, []byte(`{"steps": [{"step": "setState", "acounts": {}}]}`), 0644))
+
+	runner := NewScenarioRunner(nil, NewDefaultFileResolver())
+	err := runner.RunSingleJSONScenarioWithOptions(scenarioPath, ScenarioRunOptions{DryRun: true})
+	var parseErr *mjparse.ParseError
+	require.True(t, errors.As(err, &parseErr))
+	require.Equal(t, scenarioPath, parseErr.File)
+}
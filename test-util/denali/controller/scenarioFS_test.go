@@ -0,0 +1,84 @@
+package denalicontroller
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+	"testing/fstest"
+
+	"github.com/stretchr/testify/require"
+)
+
+func scenarioFSFile(steps string) *fstest.MapFile {
+	return &fstest.MapFile{Data: []byte(`{"steps": [` + steps + `]}`)}
+}
+
+func TestRunScenariosFromFS(t *testing.T) {
+	fsys := fstest.MapFS{
+		"scenarios/main.scen.json": scenarioFSFile(
+			`{"step": "externalSteps", "path": "lib/setup.steps.json"},` + setStateWithCode("../code.txt")),
+		"scenarios/lib/setup.steps.json": scenarioFSFile(setStateWithCode("../../code.txt")),
+		"scenarios/other.scen.json":      scenarioFSFile(setStateWithCode("missing.txt")),
+		"code.txt":                       &fstest.MapFile{Data: []byte("embedded")},
+	}
+	executor := &externalStepsExecutor{}
+	runner := NewScenarioRunnerFS(executor, fsys)
+	executor.runner = runner
+
+	err := runner.RunSingleJSONScenario("scenarios/main.scen.json")
+	require.Nil(t, err)
+	require.Equal(t, []string{"scenarios/lib/setup.steps.json"}, executor.resolvedPaths)
+	require.Equal(t, []string{"embedded", "embedded"}, executor.codes)
+
+	var stdout bytes.Buffer
+	runner.ReportSinks = []ReportSink{&StdoutReportSink{writer: &stdout, basePath: "scenarios"}}
+	report, err := runner.RunAllJSONScenariosInDirectoryWithReport("scenarios", "", ".scen.json", nil)
+	require.NotNil(t, err)
+	require.Equal(t, 2, len(report.Scenarios))
+	require.Contains(t, stdout.String(), "Scenario: main.scen.json ...   ok\n")
+	require.Contains(t, stdout.String(), "Scenario: other.scen.json ...   FAIL: ")
+}
+
+func TestRunSingleJSONScenarioReader(t *testing.T) {
+	fsys := fstest.MapFS{
+		"code.txt": &fstest.MapFile{Data: []byte("embedded")},
+	}
+	executor := &externalStepsExecutor{}
+	runner := NewScenarioRunnerFS(executor, fsys)
+	executor.runner = runner
+
+	err := runner.RunSingleJSONScenarioReader("streamed.scen.json",
+		strings.NewReader(`{"steps": [`+setStateWithCode("code.txt")+`]}`))
+	require.Nil(t, err)
+	require.Equal(t, []string{"embedded"}, executor.codes)
+}
+
+func TestRunMultiDocumentScenarios(t *testing.T) {
+	ndjsonLine := strings.NewReplacer("\n", " ", "\t", "").Replace(`{"steps": [` + setStateWithCode("code.txt") + `]}`)
+	fsys := fstest.MapFS{
+		"scenarios/generated.scen.json": &fstest.MapFile{Data: []byte(`[
+	{"steps": [` + setStateWithCode("code.txt") + `]},
+	{"steps": [` + setStateWithCode("missing.txt") + `]},
+	{"steps": [` + setStateWithCode("code.txt") + `]}
+]`)},
+		"scenarios/lines.scen.json":  &fstest.MapFile{Data: []byte(ndjsonLine + "\n\n" + ndjsonLine + "\n")},
+		"scenarios/single.scen.json": scenarioFSFile(setStateWithCode("code.txt")),
+		"scenarios/code.txt":         &fstest.MapFile{Data: []byte("embedded")},
+	}
+	executor := &externalStepsExecutor{}
+	runner := NewScenarioRunnerFS(executor, fsys)
+	executor.runner = runner
+
+	var stdout bytes.Buffer
+	runner.ReportSinks = []ReportSink{&StdoutReportSink{writer: &stdout, basePath: "scenarios"}}
+	report, err := runner.RunAllJSONScenariosInDirectoryWithReport("scenarios", "", ".scen.json", nil)
+	require.NotNil(t, err)
+	require.Equal(t, 6, len(report.Scenarios))
+	require.Equal(t, 5, report.Count(ScenarioPassed))
+	require.Contains(t, stdout.String(), "Scenario: generated.scen.json#0 ...   ok\n")
+	require.Contains(t, stdout.String(), "Scenario: generated.scen.json#1 ...   FAIL: scenario 1: ")
+	require.Contains(t, stdout.String(), "Scenario: generated.scen.json#2 ...   ok\n")
+	require.Contains(t, stdout.String(), "Scenario: lines.scen.json#1 ...   ok\n")
+	require.Contains(t, stdout.String(), "Scenario: single.scen.json ...   ok\n")
+	require.Equal(t, []string{"embedded", "embedded", "embedded", "embedded", "embedded"}, executor.codes)
+}
@@ -0,0 +1,72 @@
+package denalicontroller
+
+import (
+	"errors"
+	"fmt"
+	"regexp"
+
+	mj "github.com/numbatx/gn-vm-util/test-util/denali/json/model"
+)
+
+// errScenarioNotSelected is what parsed scenarios that the filters of a directory run reject fail with,
+// so that they get reported as skipped, see DirectoryRunOptions.
+var errScenarioNotSelected = errors.New("scenario not selected")
+
+// scenarioFilter selects scenarios by their content, once parsed.
+type scenarioFilter func(scenario *mj.Scenario) bool
+
+// newScenarioFilter yields the filter of the name pattern and tags of the options, nil if they select all scenarios.
+func newScenarioFilter(options DirectoryRunOptions) (scenarioFilter, error) {
+	if len(options.NamePattern) == 0 && len(options.Tags) == 0 && len(options.ExcludedTags) == 0 {
+		return nil, nil
+	}
+	var namePattern *regexp.Regexp
+	if len(options.NamePattern) > 0 {
+		var err error
+		namePattern, err = regexp.Compile(options.NamePattern)
+		if err != nil {
+			return nil, fmt.Errorf("invalid scenario name pattern %s: %w", options.NamePattern, err)
+		}
+	}
+	return func(scenario *mj.Scenario) bool {
+		if namePattern != nil && !namePattern.MatchString(scenario.Name) {
+			return false
+		}
+		tags := scenarioTags(scenario)
+		if len(options.Tags) > 0 && !hasAnyTag(tags, options.Tags) {
+			return false
+		}
+		return !hasAnyTag(tags, options.ExcludedTags)
+	}, nil
+}
+
+// scenarioTags yields the tags of the scenario, followed by those of its steps.
+func scenarioTags(scenario *mj.Scenario) []string {
+	var tags []string
+	if scenario.Metadata != nil {
+		tags = append(tags, scenario.Metadata.Tags...)
+	}
+	for _, step := range scenario.Steps {
+		if metadata := mj.StepMetadataOf(step); metadata != nil {
+			tags = append(tags, metadata.Tags...)
+		}
+	}
+	return tags
+}
+
+func hasAnyTag(tags []string, wanted []string) bool {
+	for _, tag := range tags {
+		for _, wantedTag := range wanted {
+			if tag == wantedTag {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// isSelected returns false for scenarios rejected by the filter of the current directory run.
+// Scenarios included through externalSteps always run.
+func (r *ScenarioRunner) isSelected(scenario *mj.Scenario) bool {
+	return r.filter == nil || len(r.contextPaths) > 1 || r.filter(scenario)
+}
@@ -0,0 +1,169 @@
+package denalicontroller
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"path/filepath"
+	"strings"
+
+	mj "github.com/numbatx/gn-vm-util/test-util/denali/json/model"
+	mjparse "github.com/numbatx/gn-vm-util/test-util/denali/json/parse"
+	mjwrite "github.com/numbatx/gn-vm-util/test-util/denali/json/write"
+	oj "github.com/numbatx/gn-vm-util/test-util/orderedjson"
+)
+
+// ScenarioFormatOptions configures how scenario files get rewritten in canonical form.
+type ScenarioFormatOptions struct {
+	// Writer is the canonical layout, the default one if nil.
+	Writer *mjwrite.WriterOptions
+
+	// CheckOnly reports the files that are not in canonical form without rewriting them, e.g. for pre-commit hooks.
+	CheckOnly bool
+}
+
+// ScenarioFormatting is the outcome of formatting a scenario file.
+type ScenarioFormatting struct {
+	ScenarioPath string
+
+	// Changed is set if the file was not in canonical form. It was rewritten, unless only checking.
+	Changed bool
+
+	// Skipped explains why the file was left as it is, e.g. because it holds several scenarios.
+	Skipped string
+
+	// Error is set if the file could not be parsed or written.
+	Error error
+}
+
+// FormatAllJSONScenariosInDirectory rewrites every scenario in a directory, recursively, in canonical form:
+// parsed and written back, with the comments, as SaveScenario would. Scenarios written for older format versions
+// are migrated along the way. Files that cannot be formatted do not stop the batch, they are reported
+// in their ScenarioFormatting. Formatting is stable, formatted files are left unchanged.
+// Files are replaced atomically, and only if the formatted scenario parses back to the same model:
+// files that the writer cannot express without losing something are reported instead.
+func (r *ScenarioRunner) FormatAllJSONScenariosInDirectory(dir string, options ScenarioFormatOptions) ([]*ScenarioFormatting, error) {
+	scenarioPaths, err := findScenarioFiles(dir)
+	if err != nil {
+		return nil, err
+	}
+
+	var formattings []*ScenarioFormatting
+	failed := false
+	for _, scenarioPath := range scenarioPaths {
+		formatting := r.FormatJSONScenarioFile(scenarioPath, options)
+		if formatting.Error != nil {
+			failed = true
+		}
+		formattings = append(formattings, formatting)
+	}
+	if failed {
+		return formattings, errors.New("some scenarios could not be formatted")
+	}
+	return formattings, nil
+}
+
+// FormatJSONScenarioFile rewrites a single scenario file in canonical form, see FormatAllJSONScenariosInDirectory.
+func (r *ScenarioRunner) FormatJSONScenarioFile(scenarioPath string, options ScenarioFormatOptions) *ScenarioFormatting {
+	formatting := &ScenarioFormatting{ScenarioPath: scenarioPath}
+	content, err := ioutil.ReadFile(scenarioPath)
+	if err != nil {
+		formatting.Error = err
+		return formatting
+	}
+	documents, err := mjparse.SplitScenarioDocuments(content)
+	if err != nil {
+		formatting.Error = withErrorFile(err, scenarioPath)
+		return formatting
+	}
+	if documents != nil {
+		formatting.Skipped = "holds several scenarios"
+		return formatting
+	}
+
+	absolutePath, err := filepath.Abs(scenarioPath)
+	if err != nil {
+		formatting.Error = err
+		return formatting
+	}
+	r.Parser.ValueInterpreter.FileResolver.SetContext(absolutePath)
+	scenario, err := r.Parser.ParseScenarioFileWithArguments(content, placeholderArguments(content))
+	if err != nil {
+		formatting.Error = withErrorFile(err, scenarioPath)
+		return formatting
+	}
+
+	formatted := mjwrite.ScenarioToJSONString(scenario)
+	if options.Writer != nil {
+		formatted = mjwrite.ScenarioToJSONStringWithOptions(scenario, *options.Writer)
+	}
+	formatting.Changed = !bytes.Equal(content, []byte(formatted))
+	if !formatting.Changed {
+		return formatting
+	}
+
+	// never rewrite a file with something that means less, see VerifyRoundTrip
+	lostFields, err := r.writtenLoss(scenario, formatted, "")
+	if err != nil {
+		formatting.Error = withErrorFile(err, scenarioPath)
+		return formatting
+	}
+	if options.Writer != nil && options.Writer.ReadableValues {
+		// rewriting the original forms is the point, only the values must stay the same
+		lostFields = withoutOriginalForms(lostFields)
+	}
+	if len(lostFields) > 0 {
+		formatting.Error = fmt.Errorf("%s: formatting would lose %s", scenarioPath, strings.Join(lostFields, ", "))
+		return formatting
+	}
+
+	if !options.CheckOnly {
+		err = writeFileAtomically(scenarioPath, []byte(formatted))
+		if err != nil {
+			formatting.Error = fmt.Errorf("cannot write %s: %w", scenarioPath, err)
+		}
+	}
+	return formatting
+}
+
+// withoutOriginalForms leaves out the differences in the original forms of values, e.g. "Steps[0].Tx.Value.Original".
+func withoutOriginalForms(fieldPaths []string) []string {
+	var result []string
+	for _, fieldPath := range fieldPaths {
+		if !strings.HasSuffix(fieldPath, ".Original") {
+			result = append(result, fieldPath)
+		}
+	}
+	return result
+}
+
+// placeholderArguments supplies arguments for the parameters of a scenario, if it has some,
+// so that scenarios only meant to be included can be parsed on their own. They are zero addresses,
+// which also pass for amounts. Values keep their original form when written, e.g. "$NAME", so they do not show.
+func placeholderArguments(content []byte) []*mj.NamedConstant {
+	document, err := oj.ParseDocument(content)
+	if err != nil {
+		return nil
+	}
+	topMap, isMap := document.Root.(*oj.OJsonMap)
+	if !isMap {
+		return nil
+	}
+	var arguments []*mj.NamedConstant
+	for _, kvp := range topMap.OrderedKV {
+		parameterList, isList := kvp.Value.(*oj.OJsonList)
+		if kvp.Key != "parameters" || !isList {
+			continue
+		}
+		for _, parameter := range parameterList.AsList() {
+			if name, isString := parameter.(*oj.OJsonString); isString {
+				arguments = append(arguments, &mj.NamedConstant{
+					Name:  name.Value,
+					Value: mj.JSONBytesFromTree{Value: make([]byte, 32)},
+				})
+			}
+		}
+	}
+	return arguments
+}
@@ -0,0 +1,108 @@
+package denalicontroller
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	mjwrite "github.com/numbatx/gn-vm-util/test-util/denali/json/write"
+	"github.com/stretchr/testify/require"
+)
+
+func TestFormatAllJSONScenariosInDirectory(t *testing.T) {
+	dir := t.TempDir()
+	require.Nil(t, os.MkdirAll(filepath.Join(dir, "sub"), os.ModePerm))
+	writeFile := func(name string, content string) {
+		require.Nil(t, os.WriteFile(filepath.Join(dir, name), []byte(content), 0644))
+	}
+	writeFile("a.scen.json", `{"name": "a", "steps": [{"step": "dumpState", "comment": "x"}]}`)
+	writeFile("b.scen.json", "{\n    \"name\": \"b\",\n    \"steps\": []\n}\n")
+	writeFile("broken.scen.json", `{"name": "broken", "steps": [{"step": "unknown"}]}`)
+	writeFile("several.scen.json", "{\"name\": \"first\", \"steps\": []}\n{\"name\": \"second\", \"steps\": []}\n")
+	writeFile("sub/included.scen.json", `{
+	"parameters": ["owner"],
+	// the caller of the included steps
+	"config": {"caller": "$owner", "gasPrice": "1"},
+	"steps": [
+		{"step": "transfer", "tx": {"to": "address:b", "value": "1", "gasLimit": "1"}}
+	]
+}`)
+
+	runner := NewScenarioRunner(nil, NewDefaultFileResolver())
+	formattings, err := runner.FormatAllJSONScenariosInDirectory(dir, ScenarioFormatOptions{CheckOnly: true})
+	require.NotNil(t, err)
+	require.Equal(t, 5, len(formattings))
+	require.True(t, formattings[0].Changed)
+	require.False(t, formattings[1].Changed)
+	require.NotNil(t, formattings[2].Error)
+	require.Equal(t, "holds several scenarios", formattings[3].Skipped)
+	require.Nil(t, formattings[4].Error, "%v", formattings[4].Error)
+	require.True(t, formattings[4].Changed)
+	unformatted, err := os.ReadFile(filepath.Join(dir, "a.scen.json"))
+	require.Nil(t, err)
+	require.Equal(t, `{"name": "a", "steps": [{"step": "dumpState", "comment": "x"}]}`, string(unformatted))
+
+	require.Nil(t, os.Remove(filepath.Join(dir, "broken.scen.json")))
+	_, err = runner.FormatAllJSONScenariosInDirectory(dir, ScenarioFormatOptions{})
+	require.Nil(t, err)
+	included, err := os.ReadFile(filepath.Join(dir, "sub", "included.scen.json"))
+	require.Nil(t, err)
+	require.Equal(t, `{
+    // the caller of the included steps
+    "config": {
+        "gasPrice": "1",
+        "caller": "$owner"
+    },
+    "parameters": [
+        "owner"
+    ],
+    "steps": [
+        {
+            "step": "transfer",
+            "tx": {
+                "to": "address:b",
+                "value": "1",
+                "gasLimit": "1"
+            }
+        }
+    ]
+}
+`, string(included))
+
+	// stable, also with another layout
+	formattings, err = runner.FormatAllJSONScenariosInDirectory(dir, ScenarioFormatOptions{CheckOnly: true})
+	require.Nil(t, err)
+	for _, formatting := range formattings {
+		require.False(t, formatting.Changed, formatting.ScenarioPath)
+	}
+	compact := &mjwrite.CompactWriterOptions
+	_, err = runner.FormatAllJSONScenariosInDirectory(dir, ScenarioFormatOptions{Writer: compact})
+	require.Nil(t, err)
+	formattings, err = runner.FormatAllJSONScenariosInDirectory(dir, ScenarioFormatOptions{Writer: compact, CheckOnly: true})
+	require.Nil(t, err)
+	for _, formatting := range formattings {
+		require.False(t, formatting.Changed, formatting.ScenarioPath)
+	}
+}
+
+func TestFormatRefusesLossyOutput(t *testing.T) {
+	runner := NewScenarioRunner(nil, NewDefaultFileResolver())
+	scenario, err := runner.Parser.ParseScenarioFile([]byte(`{"steps": [{"step": "transfer", "tx": {"from": "address:a", "to": "address:b", "value": "1", "gasLimit": "7"}}]}`))
+	require.Nil(t, err)
+	lostFields, err := runner.writtenLoss(scenario, `{"steps": [{"step": "transfer", "tx": {"from": "address:a", "to": "address:b", "value": "1"}}]}`, "")
+	require.Nil(t, err)
+	require.Equal(t, []string{"Steps[0].Tx.GasLimit.Value", "Steps[0].Tx.GasLimit.Original"}, lostFields)
+
+	// readable values only change the original forms
+	dir := t.TempDir()
+	scenarioPath := filepath.Join(dir, "readable.scen.json")
+	require.Nil(t, os.WriteFile(scenarioPath, []byte(`{"steps": [{"step": "transfer", "tx": {"from": "0x6100000000000000000000000000000000000000000000000000000000000000", "to": "address:b", "value": "0x10"}}]}`), 0600))
+	formatting := runner.FormatJSONScenarioFile(scenarioPath, ScenarioFormatOptions{
+		Writer: &mjwrite.WriterOptions{IndentWidth: 4, ReadableValues: true},
+	})
+	require.Nil(t, formatting.Error)
+	require.True(t, formatting.Changed)
+	formatted, err := os.ReadFile(scenarioPath)
+	require.Nil(t, err)
+	require.Contains(t, string(formatted), `"value": "16"`)
+}
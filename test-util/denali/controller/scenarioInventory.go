@@ -0,0 +1,250 @@
+package denalicontroller
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	mj "github.com/numbatx/gn-vm-util/test-util/denali/json/model"
+)
+
+// ManifestFormatVersion identifies the layout of the exported scenario manifest.
+const ManifestFormatVersion = 1
+
+// ManifestHashAlgorithm is the algorithm used for all content hashes in the inventory.
+const ManifestHashAlgorithm = "sha256"
+
+// InventoryEntry describes a scenario file, together with its metadata and the contracts it uses.
+// All paths are relative to the inventoried directory, using forward slashes.
+type InventoryEntry struct {
+	Path        string               `json:"path"`
+	Name        string               `json:"name,omitempty"`
+	Owner       string               `json:"owner,omitempty"`
+	License     string               `json:"license,omitempty"`
+	Tags        []string             `json:"tags,omitempty"`
+	ContentHash string               `json:"contentHash"`
+	Contracts   []*InventoryContract `json:"contracts,omitempty"`
+
+	// Error is set if the scenario could not be parsed, in which case only the path and hash are known.
+	Error string `json:"error,omitempty"`
+}
+
+// InventoryContract is a contract code file loaded by a scenario.
+type InventoryContract struct {
+	Path        string `json:"path"`
+	ContentHash string `json:"contentHash,omitempty"`
+	Error       string `json:"error,omitempty"`
+}
+
+// ScenarioManifest is the exported inventory of a scenario directory, consumed by compliance tooling.
+type ScenarioManifest struct {
+	FormatVersion int               `json:"formatVersion"`
+	HashAlgorithm string            `json:"hashAlgorithm"`
+	Scenarios     []*InventoryEntry `json:"scenarios"`
+}
+
+// ScenarioInventory parses all scenarios in a directory, recursively, and describes them, sorted by path.
+// Scenarios that cannot be parsed are still listed, with an error.
+func (r *ScenarioRunner) ScenarioInventory(dir string) ([]*InventoryEntry, error) {
+	dir, err := filepath.Abs(dir)
+	if err != nil {
+		return nil, err
+	}
+	scenarioPaths, err := findScenarioFiles(dir)
+	if err != nil {
+		return nil, err
+	}
+	return r.inventory(scenarioPaths, func(path string) string {
+		return inventoryPath(dir, path)
+	})
+}
+
+// ScenarioInventoryOfWorkspace is ScenarioInventory for all workspace roots, with workspace paths, see Workspace.
+func (r *ScenarioRunner) ScenarioInventoryOfWorkspace(workspace *Workspace) ([]*InventoryEntry, error) {
+	r.UseWorkspace(workspace)
+	var scenarioPaths []string
+	listed := make(map[string]bool)
+	for _, root := range workspace.Roots {
+		rootScenarioPaths, err := findScenarioFiles(root.Path)
+		if err != nil {
+			return nil, fmt.Errorf("cannot list scenarios of workspace root %s: %w", root.Name, err)
+		}
+		for _, scenarioPath := range rootScenarioPaths {
+			if !listed[scenarioPath] {
+				listed[scenarioPath] = true
+				scenarioPaths = append(scenarioPaths, scenarioPath)
+			}
+		}
+	}
+	entries, err := r.inventory(scenarioPaths, workspace.WorkspacePath)
+	if err != nil {
+		return nil, err
+	}
+	sort.Slice(entries, func(i, j int) bool {
+		return entries[i].Path < entries[j].Path
+	})
+	return entries, nil
+}
+
+// inventory describes the scenarios, naming all files with nameFile.
+func (r *ScenarioRunner) inventory(scenarioPaths []string, nameFile func(string) string) ([]*InventoryEntry, error) {
+	var entries []*InventoryEntry
+	for _, scenarioPath := range scenarioPaths {
+		content, err := ioutil.ReadFile(scenarioPath)
+		if err != nil {
+			return nil, err
+		}
+		entry := &InventoryEntry{
+			Path:        nameFile(scenarioPath),
+			ContentHash: contentHash(content),
+		}
+		entries = append(entries, entry)
+
+		scenario, err := r.parseScenarioFile(scenarioPath, nil)
+		if err != nil {
+			entry.Error = err.Error()
+			continue
+		}
+		entry.Name = scenario.Name
+		if scenario.Metadata != nil {
+			entry.Owner = scenario.Metadata.Owner
+			entry.License = scenario.Metadata.License
+			entry.Tags = scenario.Metadata.Tags
+		}
+		entry.Contracts = r.inventoryContracts(scenario, nameFile)
+	}
+	return entries, nil
+}
+
+// inventoryContracts hashes the code files referenced by a scenario, in its accounts and deploys.
+// Relies on the file resolver context having been set by parsing the scenario.
+func (r *ScenarioRunner) inventoryContracts(scenario *mj.Scenario, nameFile func(string) string) []*InventoryContract {
+	codePaths := make(map[string]bool)
+	addCode := func(code mj.JSONBytesFromString) {
+		for _, prefix := range []string{"file:", "code:"} {
+			if strings.HasPrefix(code.Original, prefix) {
+				codePaths[r.Parser.ValueInterpreter.FileResolver.ResolveAbsolutePath(code.Original[len(prefix):])] = true
+			}
+		}
+	}
+	for _, generalStep := range scenario.Steps {
+		switch step := generalStep.(type) {
+		case *mj.SetStateStep:
+			for _, account := range step.Accounts {
+				addCode(account.Code)
+			}
+		case *mj.TxStep:
+			if step.Tx.Type == mj.ScDeploy {
+				addCode(step.Tx.Code)
+			}
+		}
+	}
+
+	var contracts []*InventoryContract
+	for codePath := range codePaths {
+		contract := &InventoryContract{Path: nameFile(codePath)}
+		code, err := ioutil.ReadFile(codePath)
+		if err != nil {
+			contract.Error = err.Error()
+		} else {
+			contract.ContentHash = contentHash(code)
+		}
+		contracts = append(contracts, contract)
+	}
+	sort.Slice(contracts, func(i, j int) bool {
+		return contracts[i].Path < contracts[j].Path
+	})
+	return contracts
+}
+
+func inventoryPath(dir string, path string) string {
+	relativePath, err := filepath.Rel(dir, path)
+	if err != nil {
+		return filepath.ToSlash(path)
+	}
+	return filepath.ToSlash(relativePath)
+}
+
+// ExportScenarioManifest writes the inventory of a scenario directory as a single JSON document.
+func (r *ScenarioRunner) ExportScenarioManifest(dir string, w io.Writer) error {
+	entries, err := r.ScenarioInventory(dir)
+	if err != nil {
+		return err
+	}
+	return writeScenarioManifest(entries, w)
+}
+
+// ExportWorkspaceManifest writes the inventory of all workspace roots as a single JSON document.
+func (r *ScenarioRunner) ExportWorkspaceManifest(workspace *Workspace, w io.Writer) error {
+	entries, err := r.ScenarioInventoryOfWorkspace(workspace)
+	if err != nil {
+		return err
+	}
+	return writeScenarioManifest(entries, w)
+}
+
+func writeScenarioManifest(entries []*InventoryEntry, w io.Writer) error {
+	manifest := &ScenarioManifest{
+		FormatVersion: ManifestFormatVersion,
+		HashAlgorithm: ManifestHashAlgorithm,
+		Scenarios:     entries,
+	}
+	manifestJSON, err := json.MarshalIndent(manifest, "", "  ")
+	if err != nil {
+		return err
+	}
+	_, err = w.Write(append(manifestJSON, '\n'))
+	return err
+}
+
+// VerifyScenarioManifest checks that the files listed in an exported manifest still have the recorded contents.
+// Yields one description per file that changed or disappeared, no descriptions if all match.
+func VerifyScenarioManifest(dir string, manifestJSON []byte) ([]string, error) {
+	return verifyScenarioManifest(manifestJSON, func(path string) (string, error) {
+		return filepath.Join(dir, filepath.FromSlash(path)), nil
+	})
+}
+
+// VerifyWorkspaceManifest is VerifyScenarioManifest for a manifest exported by ExportWorkspaceManifest.
+func VerifyWorkspaceManifest(workspace *Workspace, manifestJSON []byte) ([]string, error) {
+	return verifyScenarioManifest(manifestJSON, workspace.AbsolutePath)
+}
+
+func verifyScenarioManifest(manifestJSON []byte, resolvePath func(string) (string, error)) ([]string, error) {
+	manifest := &ScenarioManifest{}
+	if err := json.Unmarshal(manifestJSON, manifest); err != nil {
+		return nil, fmt.Errorf("invalid scenario manifest: %w", err)
+	}
+	if manifest.FormatVersion != ManifestFormatVersion || manifest.HashAlgorithm != ManifestHashAlgorithm {
+		return nil, fmt.Errorf("unsupported scenario manifest, format version %d, hash algorithm %s",
+			manifest.FormatVersion, manifest.HashAlgorithm)
+	}
+
+	var problems []string
+	verify := func(path string, expectedHash string) {
+		absolutePath, err := resolvePath(path)
+		var content []byte
+		if err == nil {
+			content, err = ioutil.ReadFile(absolutePath)
+		}
+		switch {
+		case err != nil:
+			problems = append(problems, fmt.Sprintf("%s: %s", path, err))
+		case contentHash(content) != expectedHash:
+			problems = append(problems, fmt.Sprintf("%s: content changed", path))
+		}
+	}
+	for _, entry := range manifest.Scenarios {
+		verify(entry.Path, entry.ContentHash)
+		for _, contract := range entry.Contracts {
+			if len(contract.ContentHash) > 0 {
+				verify(contract.Path, contract.ContentHash)
+			}
+		}
+	}
+	return problems, nil
+}
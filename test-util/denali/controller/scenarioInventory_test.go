@@ -0,0 +1,72 @@
+package denalicontroller
+
+import (
+	"bytes"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func writeInventoryTestDir(t *testing.T) string {
+	dir := t.TempDir()
+	require.Nil(t, os.MkdirAll(filepath.Join(dir, "staking"), os.ModePerm))
+	require.Nil(t, os.WriteFile(filepath.Join(dir, "contract.wasm"), []byte("code"), 0644))
+	require.Nil(t, os.WriteFile(filepath.Join(dir, "staking", "stake.scen.json"), []byte(`{
+		"name": "stake",
+		"metadata": {"owner": "staking-team", "license": "GPL-3.0", "tags": ["staking", "slow"]},
+		"steps": [
+			{"step": "setState", "accounts": {"address:sc": {
+				"nonce": "0", "balance": "0", "storage": {}, "code": "file:../contract.wasm"}}},
+			{"step": "scDeploy", "tx": {"from": "address:owner", "value": "0",
+				"contractCode": "file:../contract.wasm", "arguments": [], "gasLimit": "0", "gasPrice": "0"}}
+		]
+	}`), 0644))
+	require.Nil(t, os.WriteFile(filepath.Join(dir, "broken.scen.json"), []byte(`{"steps": 5}`), 0644))
+	return dir
+}
+
+func TestExportAndVerifyScenarioManifest(t *testing.T) {
+	dir := writeInventoryTestDir(t)
+	runner := NewScenarioRunner(nil, NewDefaultFileResolver())
+
+	var buffer bytes.Buffer
+	require.Nil(t, runner.ExportScenarioManifest(dir, &buffer))
+
+	manifest := &ScenarioManifest{}
+	require.Nil(t, json.Unmarshal(buffer.Bytes(), manifest))
+	require.Equal(t, ManifestHashAlgorithm, manifest.HashAlgorithm)
+	require.Equal(t, 2, len(manifest.Scenarios))
+
+	broken := manifest.Scenarios[0]
+	require.Equal(t, "broken.scen.json", broken.Path)
+	require.NotEmpty(t, broken.Error)
+	require.NotEmpty(t, broken.ContentHash)
+
+	stake := manifest.Scenarios[1]
+	require.Equal(t, "staking/stake.scen.json", stake.Path)
+	require.Equal(t, "stake", stake.Name)
+	require.Equal(t, "staking-team", stake.Owner)
+	require.Equal(t, "GPL-3.0", stake.License)
+	require.Equal(t, []string{"staking", "slow"}, stake.Tags)
+	require.Equal(t, []*InventoryContract{
+		{Path: "contract.wasm", ContentHash: contentHash([]byte("code"))},
+	}, stake.Contracts)
+
+	problems, err := VerifyScenarioManifest(dir, buffer.Bytes())
+	require.Nil(t, err)
+	require.Empty(t, problems)
+
+	require.Nil(t, os.WriteFile(filepath.Join(dir, "contract.wasm"), []byte("changed"), 0644))
+	require.Nil(t, os.Remove(filepath.Join(dir, "broken.scen.json")))
+	problems, err = VerifyScenarioManifest(dir, buffer.Bytes())
+	require.Nil(t, err)
+	require.Equal(t, 2, len(problems))
+	require.Contains(t, problems[0], "broken.scen.json")
+	require.Equal(t, "contract.wasm: content changed", problems[1])
+
+	_, err = VerifyScenarioManifest(dir, []byte(`{"formatVersion": 99}`))
+	require.NotNil(t, err)
+}
@@ -1,58 +1,207 @@
 package denalicontroller
 
 import (
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
 	"io/ioutil"
-	"os"
+	"path"
 	"path/filepath"
 
 	mj "github.com/numbatx/gn-vm-util/test-util/denali/json/model"
-	mjwrite "github.com/numbatx/gn-vm-util/test-util/denali/json/write"
+	mjparse "github.com/numbatx/gn-vm-util/test-util/denali/json/parse"
 )
 
 // RunSingleJSONScenario parses and prepares test, then calls testCallback.
 func (r *ScenarioRunner) RunSingleJSONScenario(contextPath string) error {
-	var err error
-	contextPath, err = filepath.Abs(contextPath)
+	return r.runScenarioWithArguments(contextPath, nil, nil)
+}
+
+// RunSingleJSONScenarioReader is RunSingleJSONScenario, reading the scenario from a stream, e.g. an archive entry.
+// The context path still says where the scenario comes from, to resolve the files it references.
+func (r *ScenarioRunner) RunSingleJSONScenarioReader(contextPath string, reader io.Reader) error {
+	content, err := io.ReadAll(reader)
 	if err != nil {
 		return err
 	}
+	return r.runScenarioWithArguments(contextPath, content, nil)
+}
+
+// RunExternalSteps runs the scenario included by an externalSteps step, passing on its arguments.
+// Executors should prefer it to calling RunSingleJSONScenario, which cannot run scenarios with parameters.
+func (r *ScenarioRunner) RunExternalSteps(step *mj.ExternalStepsStep) error {
+	includedPath := step.ResolvedPath
+	if len(includedPath) == 0 {
+		includedPath = r.Parser.ValueInterpreter.FileResolver.ResolveAbsolutePath(step.Path)
+	}
+	return r.runScenarioWithArguments(includedPath, nil, step.Arguments)
+}
+
+// RunScenario runs a scenario built in code, e.g. by the Go tests generated with mjwrite.ScenarioToGoTest.
+// The context path says where the files it references, e.g. through externalSteps, are resolved from.
+func (r *ScenarioRunner) RunScenario(contextPath string, scenario *mj.Scenario) error {
+	return r.runInContext(contextPath, "", func(absolutePath string) error {
+		r.Parser.ValueInterpreter.FileResolver.SetContext(absolutePath)
+		return r.executeScenario(absolutePath, scenario)
+	})
+}
+
+// runScenarioWithArguments reads the scenario from the context path, unless its content is given.
+func (r *ScenarioRunner) runScenarioWithArguments(contextPath string, content []byte, arguments []*mj.NamedConstant) error {
+	return r.runInContext(contextPath, "", func(absolutePath string) error {
+		return r.runSingleJSONScenario(absolutePath, content, arguments)
+	})
+}
 
-	// Open our jsonFile
-	var jsonFile *os.File
-	jsonFile, err = os.Open(contextPath)
-	// if we os.Open returns an error then handle it
+// runScenarioDocument runs one of the scenarios of a multi-document file, see mjparse.SplitScenarioDocuments.
+func (r *ScenarioRunner) runScenarioDocument(contextPath string, document *mjparse.ScenarioDocument) error {
+	return r.runInContext(contextPath, MultiScenarioSuffix(document.Index), func(absolutePath string) error {
+		r.Parser.ValueInterpreter.FileResolver.SetContext(absolutePath)
+		scenario, err := r.Parser.ParseScenarioDocument(document)
+		if err != nil {
+			return withErrorFile(err, absolutePath)
+		}
+		_ = r.AuditLog.Record(&AuditEntry{
+			Event:   AuditScenarioParsed,
+			Path:    absolutePath + MultiScenarioSuffix(document.Index),
+			NrSteps: len(scenario.Steps),
+		})
+		return r.executeScenario(absolutePath, scenario)
+	})
+}
+
+// MultiScenarioSuffix is appended to the path of a multi-document file, in reports and audit logs,
+// to name one of its scenarios, e.g. "generated.scen.json#3".
+func MultiScenarioSuffix(index int) string {
+	return fmt.Sprintf("#%d", index)
+}
+
+// runInContext runs a scenario of the file at the context path, with the file resolver pointing there,
+// and audits the result. The document suffix names a scenario of a multi-document file, empty otherwise.
+func (r *ScenarioRunner) runInContext(contextPath string, documentSuffix string, run func(absolutePath string) error) error {
+	var err error
+	contextPath, err = r.absolutePath(contextPath)
 	if err != nil {
 		return err
 	}
 
-	// defer the closing of our jsonFile so that we can parse it later on
-	defer jsonFile.Close()
+	if len(r.contextPaths) == 0 {
+		r.scenarioName = ""
+		r.resetScenarioState()
+	}
+	r.contextPaths = append(r.contextPaths, contextPath)
+	err = run(contextPath)
+	r.restoreContext()
 
-	byteValue, err := ioutil.ReadAll(jsonFile)
+	resultEntry := &AuditEntry{
+		Event:  AuditScenarioResult,
+		Path:   contextPath + documentSuffix,
+		Status: ScenarioPassed,
+	}
+	switch {
+	case errors.Is(err, errScenarioNotSelected):
+		resultEntry.Event = AuditScenarioSkipped
+		resultEntry.Status = ScenarioSkipped
+	case err != nil:
+		resultEntry.Status = ScenarioFailed
+		resultEntry.Error = err.Error()
+	}
+	_ = r.AuditLog.Record(resultEntry)
+
+	return err
+}
+
+// resetScenarioState forgets what was collected while running the outermost scenario, before running it, or running it again.
+func (r *ScenarioRunner) resetScenarioState() {
+	r.stepOutputs = nil
+	r.stepWarnings = nil
+	r.stepsExecuted = 0
+	r.lastStep = nil
+	r.lastStepType = ""
+	r.lastStepIndex = 0
+}
+
+// restoreContext points the file resolver back to the including scenario, if any,
+// once an included scenario has finished running.
+func (r *ScenarioRunner) restoreContext() {
+	r.contextPaths = r.contextPaths[:len(r.contextPaths)-1]
+	if len(r.contextPaths) > 0 {
+		r.Parser.ValueInterpreter.FileResolver.SetContext(r.contextPaths[len(r.contextPaths)-1])
+	}
+}
+
+func (r *ScenarioRunner) runSingleJSONScenario(contextPath string, content []byte, arguments []*mj.NamedConstant) error {
+	canRewrite := content == nil && r.FS == nil && len(r.contextPaths) == 1
+	if content == nil {
+		var err error
+		content, err = r.readScenarioFile(contextPath)
+		if err != nil {
+			return err
+		}
+	}
+	scenario, err := r.parseScenario(contextPath, content, arguments)
 	if err != nil {
 		return err
 	}
+	err = r.executeScenario(contextPath, scenario)
+	if err != nil && r.AcceptActualValues && canRewrite {
+		return r.acceptActualValues(contextPath, scenario, err)
+	}
+	return err
+}
+
+// parseScenarioFile reads and parses a scenario, the context path must be absolute.
+// The arguments are only supplied to scenarios included through externalSteps.
+func (r *ScenarioRunner) parseScenarioFile(contextPath string, arguments []*mj.NamedConstant) (*mj.Scenario, error) {
+	byteValue, err := r.readScenarioFile(contextPath)
+	if err != nil {
+		return nil, err
+	}
+	return r.parseScenario(contextPath, byteValue, arguments)
+}
 
+// parseScenario parses the content of a scenario file, locating errors in it.
+func (r *ScenarioRunner) parseScenario(contextPath string, byteValue []byte, arguments []*mj.NamedConstant) (*mj.Scenario, error) {
 	r.Parser.ValueInterpreter.FileResolver.SetContext(contextPath)
-	scenario, parseErr := r.Parser.ParseScenarioFile(byteValue)
+	scenario, parseErr := r.Parser.ParseScenarioFileWithArguments(byteValue, arguments)
 	if parseErr != nil {
-		return parseErr
+		return nil, withErrorFile(parseErr, contextPath)
 	}
-
-	return r.Executor.ExecuteScenario(scenario, r.Parser.ValueInterpreter.FileResolver)
+	_ = r.AuditLog.Record(&AuditEntry{
+		Event:       AuditScenarioParsed,
+		Path:        contextPath,
+		ContentHash: contentHash(byteValue),
+		NrSteps:     len(scenario.Steps),
+	})
+	return scenario, nil
 }
 
-// tool to modify scenarios
-// use with extreme caution
-func saveModifiedScenario(toPath string, scenario *mj.Scenario) {
-	resultJSON := mjwrite.ScenarioToJSONString(scenario)
+// withErrorFile sets the file of located parse errors.
+func withErrorFile(parseErr error, contextPath string) error {
+	var validationErr *mjparse.ValidationError
+	var locatedErr *mjparse.ParseError
+	if errors.As(parseErr, &validationErr) {
+		validationErr.SetFile(contextPath)
+	} else if errors.As(parseErr, &locatedErr) {
+		locatedErr.File = contextPath
+	}
+	return parseErr
+}
 
-	err := os.MkdirAll(filepath.Dir(toPath), os.ModePerm)
-	if err != nil {
-		panic(err)
+// absolutePath makes scenario paths absolute, unless they are paths of the runner FS, which are always relative.
+func (r *ScenarioRunner) absolutePath(scenarioPath string) (string, error) {
+	if r.FS != nil {
+		return path.Clean(scenarioPath), nil
 	}
-	err = ioutil.WriteFile(toPath, []byte(resultJSON), 0644)
-	if err != nil {
-		panic(err)
+	return filepath.Abs(scenarioPath)
+}
+
+// readScenarioFile reads a scenario from the runner FS, if set, otherwise from the OS file system.
+func (r *ScenarioRunner) readScenarioFile(scenarioPath string) ([]byte, error) {
+	r.recordFileRead(scenarioPath)
+	if r.FS != nil {
+		return fs.ReadFile(r.FS, scenarioPath)
 	}
+	return ioutil.ReadFile(scenarioPath)
 }
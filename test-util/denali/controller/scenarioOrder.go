@@ -0,0 +1,91 @@
+package denalicontroller
+
+import (
+	"sort"
+)
+
+// ScenarioOrder is a policy for the order in which directory runs execute scenarios.
+// Whatever the policy, scenarios with a higher metadata priority run first.
+type ScenarioOrder int
+
+const (
+	// OrderAlphabetical runs scenarios sorted by path. The default.
+	OrderAlphabetical ScenarioOrder = iota
+
+	// OrderFailuresFirst runs the scenarios that failed most often in previous runs first,
+	// so that likely failures surface early. Needs the runner History.
+	OrderFailuresFirst
+
+	// OrderSlowestFirst runs the scenarios that took longest in previous runs first,
+	// which packs parallel runs better. Needs the runner History.
+	OrderSlowestFirst
+)
+
+// orderScenarios sorts scenario paths according to their priority and to the runner order policy.
+// Scenarios without history count as never failed and instant.
+func (r *ScenarioRunner) orderScenarios(scenarioPaths []string) {
+	priorities := make(map[string]uint64)
+	for _, scenarioPath := range scenarioPaths {
+		priorities[scenarioPath] = r.readScenarioPriority(scenarioPath)
+	}
+	history := func(scenarioPath string) *ScenarioHistory {
+		if sh, found := r.History[scenarioPath]; found {
+			return sh
+		}
+		return &ScenarioHistory{Path: scenarioPath}
+	}
+
+	sort.SliceStable(scenarioPaths, func(i, j int) bool {
+		first, second := scenarioPaths[i], scenarioPaths[j]
+		if priorities[first] != priorities[second] {
+			return priorities[first] > priorities[second]
+		}
+		switch r.Order {
+		case OrderFailuresFirst:
+			firstRate, secondRate := failureRate(history(first)), failureRate(history(second))
+			if firstRate != secondRate {
+				return firstRate > secondRate
+			}
+		case OrderSlowestFirst:
+			firstDuration, secondDuration := history(first).AverageDuration, history(second).AverageDuration
+			if firstDuration != secondDuration {
+				return firstDuration > secondDuration
+			}
+		}
+		return first < second
+	})
+}
+
+// readScenarioPriority only parses the scenario-level fields, see mjparse.Parser.ParseScenarioHeaders,
+// so that priorities can be written in any form, e.g. "$NAME". Files holding several scenarios
+// get the highest priority among them. Scenarios whose priority cannot be read get none:
+// they cannot be parsed either, and their run fails with the error.
+func (r *ScenarioRunner) readScenarioPriority(scenarioPath string) uint64 {
+	content, err := r.readScenarioFile(scenarioPath)
+	if err != nil {
+		return 0
+	}
+	absolutePath, err := r.absolutePath(scenarioPath)
+	if err != nil {
+		return 0
+	}
+	r.Parser.ValueInterpreter.FileResolver.SetContext(absolutePath)
+	headers, err := r.Parser.ParseScenarioHeaders(content)
+	if err != nil {
+		return 0
+	}
+	priority := uint64(0)
+	for _, header := range headers {
+		if header.Metadata != nil && header.Metadata.Priority.Value > priority {
+			priority = header.Metadata.Priority.Value
+		}
+	}
+	return priority
+}
+
+func failureRate(sh *ScenarioHistory) float64 {
+	if sh.Runs == 0 {
+		return 0
+	}
+	return float64(sh.Failures) / float64(sh.Runs)
+}
@@ -0,0 +1,94 @@
+package denalicontroller
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	fr "github.com/numbatx/gn-vm-util/test-util/denali/json/fileresolver"
+	mj "github.com/numbatx/gn-vm-util/test-util/denali/json/model"
+	"github.com/stretchr/testify/require"
+)
+
+type recordingExecutor struct {
+	executed []string
+}
+
+func (e *recordingExecutor) Reset() {}
+
+func (e *recordingExecutor) ExecuteScenario(scenario *mj.Scenario, _ fr.FileResolver) error {
+	e.executed = append(e.executed, scenario.Name)
+	return nil
+}
+
+func writeOrderTestScenarios(t *testing.T) string {
+	dir := t.TempDir()
+	scenarios := map[string]string{
+		"a": `{"name": "a", "steps": []}`,
+		"b": `{"name": "b", "steps": []}`,
+		"c": `{"name": "c", "metadata": {"priority": "10"}, "steps": []}`,
+		"d": `{"name": "d", "steps": []}`,
+		"e": `{"name": "e", "metadata": {"priority": "0x01"}, "steps": []}`,
+		// read like the rest of the scenario, comments and defines included
+		"f": `{
+			// below c
+			"name": "f",
+			"defines": {"PRIORITY": "5"},
+			"metadata": {"priority": "$PRIORITY"},
+			"steps": []
+		}`,
+	}
+	for name, scenarioJSON := range scenarios {
+		require.Nil(t, os.WriteFile(filepath.Join(dir, name+".scen.json"), []byte(scenarioJSON), 0644))
+	}
+	return dir
+}
+
+func runInOrder(t *testing.T, dir string, order ScenarioOrder, history map[string]*ScenarioHistory) []string {
+	executor := &recordingExecutor{}
+	runner := NewScenarioRunner(executor, NewDefaultFileResolver())
+	runner.Order = order
+	runner.History = history
+	_, err := runner.RunAllJSONScenariosInDirectoryWithReport(dir, "", ".scen.json", nil)
+	require.Nil(t, err)
+	return executor.executed
+}
+
+func TestScenarioOrder(t *testing.T) {
+	dir := writeOrderTestScenarios(t)
+	scenarioPath := func(name string) string {
+		return filepath.Join(dir, name+".scen.json")
+	}
+
+	require.Equal(t, []string{"c", "f", "e", "a", "b", "d"}, runInOrder(t, dir, OrderAlphabetical, nil))
+
+	// without history, the policies fall back to alphabetical
+	require.Equal(t, []string{"c", "f", "e", "a", "b", "d"}, runInOrder(t, dir, OrderFailuresFirst, nil))
+
+	history := map[string]*ScenarioHistory{
+		scenarioPath("a"): {Runs: 4, Failures: 1, AverageDuration: time.Second},
+		scenarioPath("b"): {Runs: 2, Failures: 0, AverageDuration: 3 * time.Second},
+		scenarioPath("c"): {Runs: 2, Failures: 0, AverageDuration: time.Millisecond},
+		scenarioPath("d"): {Runs: 2, Failures: 2, AverageDuration: 2 * time.Second},
+	}
+	// priority still comes first
+	require.Equal(t, []string{"c", "f", "e", "d", "a", "b"}, runInOrder(t, dir, OrderFailuresFirst, history))
+	require.Equal(t, []string{"c", "f", "e", "b", "d", "a"}, runInOrder(t, dir, OrderSlowestFirst, history))
+}
+
+func TestScenarioOrderInvalidPriority(t *testing.T) {
+	dir := t.TempDir()
+	require.Nil(t, os.WriteFile(filepath.Join(dir, "a.scen.json"),
+		[]byte(`{"name": "a", "steps": []}`), 0644))
+	require.Nil(t, os.WriteFile(filepath.Join(dir, "b.scen.json"),
+		[]byte(`{"name": "b", "metadata": {"priority": "high"}, "steps": []}`), 0644))
+
+	executor := &recordingExecutor{}
+	runner := NewScenarioRunner(executor, NewDefaultFileResolver())
+	report, err := runner.RunAllJSONScenariosInDirectoryWithReport(dir, "", ".scen.json", nil)
+	require.NotNil(t, err)
+	require.Equal(t, []string{"a"}, executor.executed)
+	require.Equal(t, ScenarioFailed, report.Scenarios[1].Status)
+	require.Contains(t, report.Scenarios[1].Error, "bad priority")
+}
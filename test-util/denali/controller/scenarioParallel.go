@@ -0,0 +1,190 @@
+package denalicontroller
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"runtime"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// ExecutorFactory creates independent executors, so that scenarios can run in parallel, see RunScenariosParallel.
+// NewExecutor can be called from several goroutines at once. Executors that are also an io.Closer
+// are closed once the run no longer needs them.
+type ExecutorFactory interface {
+	NewExecutor() (ScenarioExecutor, error)
+}
+
+// ExecutorFactoryFunc adapts a function to the ExecutorFactory interface.
+type ExecutorFactoryFunc func() (ScenarioExecutor, error)
+
+// NewExecutor calls the function.
+func (f ExecutorFactoryFunc) NewExecutor() (ScenarioExecutor, error) {
+	return f()
+}
+
+// RunScenariosParallel runs scenario files on concurrency workers, runtime.NumCPU() if not positive,
+// each with its own executor, created by the runner ExecutorFactory, and its own parser.
+// Scenarios are independent, so the speedup is nearly linear, as long as the executors share no state.
+// Files are started in the order given by the runner Order. Sinks receive the scenarios as they complete,
+// the report lists them in the order they were started. After an executor panics, its worker continues
+// with a new executor if ContinueOnError is set, otherwise no more scenarios are started.
+// No more scenarios are started either after an executor does not stop once its scenario timed out.
+// Workers that cannot get an executor do not run, the others go on, and the run fails with the factory error.
+func (r *ScenarioRunner) RunScenariosParallel(scenarioPaths []string, concurrency int) (*RunReport, error) {
+	report := &RunReport{
+		StartedAt: time.Now(),
+	}
+	if r.ExecutorFactory == nil {
+		return report, errors.New("parallel runs require an executor factory")
+	}
+	if concurrency <= 0 {
+		concurrency = runtime.NumCPU()
+	}
+	if concurrency > len(scenarioPaths) {
+		concurrency = len(scenarioPaths)
+	}
+	workers := make([]*ScenarioRunner, 0, concurrency)
+	var workerErrs []error
+	for len(workers) < concurrency {
+		executor, err := r.ExecutorFactory.NewExecutor()
+		if err != nil {
+			workerErrs = append(workerErrs, fmt.Errorf("cannot create executor: %w", err))
+			break
+		}
+		workers = append(workers, r.workerRunner(executor))
+	}
+	if len(workers) == 0 && len(workerErrs) > 0 {
+		return report, workerErrs[0]
+	}
+
+	scenarioPaths = append([]string{}, scenarioPaths...)
+	r.orderScenarios(scenarioPaths)
+	sinks := r.ReportSinks
+	if len(sinks) == 0 {
+		sinks = []ReportSink{NewStdoutReportSink(".")}
+	}
+
+	type fileRun struct {
+		index   int
+		reports []*ScenarioReport
+		err     error
+		// set when the worker stops, for want of an executor
+		workerErr error
+	}
+	// buffered, so that the files are left to the other workers when one stops
+	started := make(chan int, len(scenarioPaths))
+	for i := range scenarioPaths {
+		started <- i
+	}
+	close(started)
+	done := make(chan *fileRun)
+	// set by the worker whose executor panicked, before it takes another file, unless ContinueOnError
+	var stopped atomic.Bool
+	var wg sync.WaitGroup
+	for _, worker := range workers {
+		wg.Add(1)
+		go func(worker *ScenarioRunner) {
+			defer wg.Done()
+			defer func() {
+				closeExecutor(worker.Executor)
+			}()
+			for index := range started {
+				if stopped.Load() {
+					continue
+				}
+				run := &fileRun{index: index}
+				run.err = worker.runScenarioFileOfDirectory(scenarioPaths[index], false, func(scenarioReport *ScenarioReport) {
+					run.reports = append(run.reports, scenarioReport)
+				})
+				var panicErr *ExecutorPanicError
+				if r.stopsRun(run.err) {
+					stopped.Store(true)
+				} else if errors.As(run.err, &panicErr) {
+					// the state of the executor can no longer be trusted
+					closeExecutor(worker.Executor)
+					worker.Executor = nil
+					executor, err := r.ExecutorFactory.NewExecutor()
+					if err != nil {
+						run.workerErr = fmt.Errorf("worker stopped, cannot replace the executor that panicked: %w", err)
+						done <- run
+						return
+					}
+					worker.Executor = executor
+				}
+				done <- run
+			}
+		}(worker)
+	}
+	go func() {
+		wg.Wait()
+		close(done)
+	}()
+
+	reportsByFile := make([][]*ScenarioReport, len(scenarioPaths))
+	var stopErr error
+	var sinkErrs []error
+	for run := range done {
+		reportsByFile[run.index] = run.reports
+		for _, scenarioReport := range run.reports {
+			for _, sink := range sinks {
+				if sinkErr := sink.ScenarioDone(scenarioReport); sinkErr != nil {
+					sinkErrs = append(sinkErrs, sinkErr)
+				}
+			}
+		}
+		if r.stopsRun(run.err) && stopErr == nil {
+			stopErr = fmt.Errorf("run stopped, %s: %w", scenarioPaths[run.index], run.err)
+			report.Stopped = true
+		}
+		if run.workerErr != nil {
+			workerErrs = append(workerErrs, run.workerErr)
+		}
+	}
+	// only left if every worker stopped
+	if notRun := len(started); notRun > 0 && !report.Stopped {
+		workerErrs = append(workerErrs, fmt.Errorf("%d scenario files not run, no worker left", notRun))
+	}
+	for _, reports := range reportsByFile {
+		report.Scenarios = append(report.Scenarios, reports...)
+	}
+	return report, finishRun(report, sinks, stopErr, append(workerErrs, sinkErrs...))
+}
+
+// closeExecutor closes the executors of parallel runs that need it, see ExecutorFactory.
+func closeExecutor(executor ScenarioExecutor) {
+	if closer, isCloser := executor.(io.Closer); isCloser {
+		_ = closer.Close()
+	}
+}
+
+// workerRunner yields a runner for one worker of a parallel run, sharing the configuration of the runner,
+// but not the state of the scenario being run.
+func (r *ScenarioRunner) workerRunner(executor ScenarioExecutor) *ScenarioRunner {
+	worker := *r
+	worker.Executor = executor
+	worker.ReportSinks = nil
+	worker.contextPaths = nil
+	worker.stepOutputs = nil
+	worker.stepWarnings = nil
+	worker.scenarioName = ""
+	worker.stepsExecuted = 0
+	worker.lastStep = nil
+	worker.lastStepType = ""
+	worker.lastStepIndex = 0
+	worker.timeouts = nil
+
+	interpreter := &worker.Parser.ValueInterpreter
+	interpreter.FileResolver = r.Parser.ValueInterpreter.FileResolver.Clone()
+	interpreter.Diagnostics = nil
+	interpreter.FileReferences = nil
+	if interpreter.CodeHashes != nil {
+		interpreter.CodeHashes = make(map[string][]byte, len(r.Parser.ValueInterpreter.CodeHashes))
+		for codePath, hash := range r.Parser.ValueInterpreter.CodeHashes {
+			interpreter.CodeHashes[codePath] = hash
+		}
+	}
+	return &worker
+}
@@ -0,0 +1,161 @@
+package denalicontroller
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	fr "github.com/numbatx/gn-vm-util/test-util/denali/json/fileresolver"
+	mj "github.com/numbatx/gn-vm-util/test-util/denali/json/model"
+	"github.com/stretchr/testify/require"
+)
+
+// meetingExecutor only passes the scenarios named "meet" once all of them run at the same time,
+// which serial runs never achieve.
+type meetingExecutor struct {
+	meeting *sync.WaitGroup
+}
+
+func (e *meetingExecutor) Reset() {}
+
+func (e *meetingExecutor) ExecuteScenario(scenario *mj.Scenario, _ fr.FileResolver) error {
+	if scenario.Name != "meet" {
+		return (&panickingExecutor{}).ExecuteScenario(scenario, nil)
+	}
+	e.meeting.Done()
+	met := make(chan struct{})
+	go func() {
+		e.meeting.Wait()
+		close(met)
+	}()
+	select {
+	case <-met:
+		return nil
+	case <-time.After(5 * time.Second):
+		return errors.New("scenarios did not run in parallel")
+	}
+}
+
+func TestRunScenariosParallel(t *testing.T) {
+	dir := t.TempDir()
+	var scenarioPaths []string
+	for i, name := range []string{"meet", "meet", "meet", "fails", "passes"} {
+		scenarioPath := filepath.Join(dir, fmt.Sprintf("%d.scen.json", i))
+		require.Nil(t, os.WriteFile(scenarioPath, []byte(`{"name": "`+name+`", "steps": []}`), 0644))
+		scenarioPaths = append(scenarioPaths, scenarioPath)
+	}
+
+	meeting := &sync.WaitGroup{}
+	meeting.Add(3)
+	var mutex sync.Mutex
+	created := 0
+	runner := NewScenarioRunner(nil, NewDefaultFileResolver())
+	runner.ExecutorFactory = ExecutorFactoryFunc(func() (ScenarioExecutor, error) {
+		mutex.Lock()
+		defer mutex.Unlock()
+		created++
+		return &meetingExecutor{meeting: meeting}, nil
+	})
+	runner.ReportSinks = []ReportSink{NewJSONReportSink(&lockedBuffer{})}
+
+	report, err := runner.RunScenariosParallel(scenarioPaths, 3)
+	require.NotNil(t, err)
+	require.Equal(t, 3, created)
+	require.Equal(t, 5, len(report.Scenarios))
+	for i, scenarioReport := range report.Scenarios {
+		require.Equal(t, scenarioPaths[i], scenarioReport.Path)
+	}
+	require.Equal(t, 4, report.Count(ScenarioPassed))
+	require.Equal(t, "check failed", report.Scenarios[3].Error)
+
+	runner.ExecutorFactory = nil
+	_, err = runner.RunScenariosParallel(scenarioPaths, 3)
+	require.NotNil(t, err)
+}
+
+func TestRunScenariosParallelPanic(t *testing.T) {
+	dir := writePanicTestScenarios(t)
+	scenarioPaths, err := filepath.Glob(filepath.Join(dir, "*.scen.json"))
+	require.Nil(t, err)
+	created := 0
+	runner := NewScenarioRunner(nil, NewDefaultFileResolver())
+	runner.ExecutorFactory = ExecutorFactoryFunc(func() (ScenarioExecutor, error) {
+		created++
+		return &panickingExecutor{}, nil
+	})
+	runner.ReportSinks = []ReportSink{NewJSONReportSink(&lockedBuffer{})}
+
+	// with a single worker, the scenarios after the panic do not start
+	report, err := runner.RunScenariosParallel(scenarioPaths, 1)
+	require.NotNil(t, err)
+	require.True(t, report.Stopped)
+	require.Equal(t, 2, len(report.Scenarios))
+
+	// the worker continues with a new executor
+	created = 0
+	runner.ContinueOnError = true
+	report, err = runner.RunScenariosParallel(scenarioPaths, 1)
+	require.NotNil(t, err)
+	require.False(t, report.Stopped)
+	require.Equal(t, 3, len(report.Scenarios))
+	require.Equal(t, 2, created)
+}
+
+// closingExecutor counts how many of the executors of a run are closed.
+type closingExecutor struct {
+	panickingExecutor
+	closed *atomic.Int32
+}
+
+func (e *closingExecutor) Close() error {
+	e.closed.Add(1)
+	return nil
+}
+
+func TestRunScenariosParallelExecutorFactoryFails(t *testing.T) {
+	dir := writePanicTestScenarios(t)
+	scenarioPaths, err := filepath.Glob(filepath.Join(dir, "*.scen.json"))
+	require.Nil(t, err)
+	var created, closed atomic.Int32
+	runner := NewScenarioRunner(nil, NewDefaultFileResolver())
+	runner.ExecutorFactory = ExecutorFactoryFunc(func() (ScenarioExecutor, error) {
+		if created.Add(1) > 1 {
+			return nil, errors.New("out of memory")
+		}
+		return &closingExecutor{closed: &closed}, nil
+	})
+	runner.ReportSinks = []ReportSink{NewJSONReportSink(&lockedBuffer{})}
+	runner.ContinueOnError = true
+
+	// the second worker gets no executor, the first one stops after the panic, for want of a new one
+	report, err := runner.RunScenariosParallel(scenarioPaths, 2)
+	require.NotNil(t, err)
+	require.Contains(t, err.Error(), "cannot create executor: out of memory")
+	require.Contains(t, err.Error(), "worker stopped, cannot replace the executor that panicked: out of memory")
+	require.Contains(t, err.Error(), "1 scenario files not run, no worker left")
+	require.Equal(t, 2, len(report.Scenarios))
+	require.Equal(t, int32(1), closed.Load())
+
+	// without any executor, nothing runs
+	_, err = runner.RunScenariosParallel(scenarioPaths, 2)
+	require.NotNil(t, err)
+	require.Equal(t, int32(1), closed.Load())
+}
+
+// lockedBuffer discards what sinks write, checking that they are not called concurrently.
+type lockedBuffer struct {
+	mutex sync.Mutex
+}
+
+func (b *lockedBuffer) Write(p []byte) (int, error) {
+	if !b.mutex.TryLock() {
+		return 0, errors.New("concurrent write")
+	}
+	defer b.mutex.Unlock()
+	return len(p), nil
+}
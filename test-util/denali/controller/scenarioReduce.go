@@ -0,0 +1,251 @@
+package denalicontroller
+
+import (
+	"errors"
+	"fmt"
+	"regexp"
+
+	mj "github.com/numbatx/gn-vm-util/test-util/denali/json/model"
+	mjwrite "github.com/numbatx/gn-vm-util/test-util/denali/json/write"
+)
+
+// ReduceOptions configures ReduceFailingScenario.
+type ReduceOptions struct {
+	// SameFailure decides whether a reduced scenario still fails the way the original one does.
+	// Defaults to SameFailureMessage.
+	SameFailure func(originalErr error, reducedErr error) bool
+
+	// MaxRuns limits how many reduced scenarios get run, 0 for no limit.
+	// Once reached, the smallest failing scenario found so far is the result.
+	MaxRuns int
+
+	// Save configures how the reduced scenario is written.
+	Save SaveScenarioOptions
+}
+
+// ScenarioReduction is the result of ReduceFailingScenario.
+type ScenarioReduction struct {
+	// Scenario is the smallest scenario found that still fails the same way.
+	Scenario *mj.Scenario
+
+	// Failure is the error of the reduced scenario.
+	Failure error
+
+	OriginalSteps int
+	ReducedSteps  int
+
+	// Runs counts the reduced scenarios that were run, including those that did not fail the same way.
+	Runs int
+}
+
+// digits matches the numbers in error messages, which change when steps are removed, e.g. step indexes.
+var digits = regexp.MustCompile(`[0-9]+`)
+
+// SameFailureMessage considers two failures the same if their messages only differ in numbers.
+func SameFailureMessage(originalErr error, reducedErr error) bool {
+	return digits.ReplaceAllString(originalErr.Error(), "#") == digits.ReplaceAllString(reducedErr.Error(), "#")
+}
+
+// ReduceFailingScenario looks for a smaller scenario failing the same way as a failing scenario file,
+// and writes it to the reduced path. Steps are removed, then the accounts of setState and checkState steps,
+// re-running the scenario after each change, which is kept only if the scenario still fails the same way.
+// Meant for debugging long generated scenarios. Yields an error if the scenario does not fail in the first place.
+func (r *ScenarioRunner) ReduceFailingScenario(scenarioPath string, reducedPath string, options ReduceOptions) (*ScenarioReduction, error) {
+	if options.SameFailure == nil {
+		options.SameFailure = SameFailureMessage
+	}
+	absolutePath, err := r.absolutePath(scenarioPath)
+	if err != nil {
+		return nil, err
+	}
+	content, err := r.readScenarioFile(absolutePath)
+	if err != nil {
+		return nil, err
+	}
+	// scenarios with parameters only run through externalSteps, with arguments
+	scenario, err := r.parseScenario(absolutePath, content, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	reducer := &scenarioReducer{
+		runner:       r,
+		absolutePath: absolutePath,
+		options:      options,
+	}
+	reducer.failure = reducer.run(scenario)
+	if reducer.failure == nil {
+		return nil, fmt.Errorf("%s: scenario passes, there is no failure to reduce", scenarioPath)
+	}
+	reducer.scenario = scenario
+	reducer.reduceSteps()
+	reducer.reduceAccounts()
+
+	// the nonces assigned to the removed transactions must not shift to the remaining ones
+	err = SaveScenario(reducedPath, mjwrite.FreezeAssignedNonces(reducer.scenario), options.Save)
+	if err != nil {
+		return nil, err
+	}
+	return &ScenarioReduction{
+		Scenario:      reducer.scenario,
+		Failure:       reducer.failure,
+		OriginalSteps: len(scenario.Steps),
+		ReducedSteps:  len(reducer.scenario.Steps),
+		Runs:          reducer.runs,
+	}, nil
+}
+
+// scenarioReducer holds the smallest failing scenario found so far.
+type scenarioReducer struct {
+	runner       *ScenarioRunner
+	absolutePath string
+	options      ReduceOptions
+
+	scenario *mj.Scenario
+	failure  error
+	runs     int
+}
+
+// errMaxRunsReached stops the reduction, once ReduceOptions.MaxRuns reduced scenarios have run.
+var errMaxRunsReached = errors.New("maximum number of runs reached")
+
+// run executes a scenario from a clean state. The original one is run the same way, for a fair comparison.
+func (rd *scenarioReducer) run(scenario *mj.Scenario) error {
+	return rd.runner.runFromCleanState(rd.absolutePath, scenario)
+}
+
+// runFromCleanState executes a scenario after resetting the executor, without recording it in the audit log,
+// for tools that run many variants of a scenario. A panicking reset fails the run, as an *ExecutorPanicError.
+func (r *ScenarioRunner) runFromCleanState(absolutePath string, scenario *mj.Scenario) error {
+	// the many variants would drown the actual runs in the audit log
+	auditLog := r.AuditLog
+	r.AuditLog = nil
+	defer func() { r.AuditLog = auditLog }()
+
+	if r.Executor != nil {
+		err := r.resetExecutor()
+		if err != nil {
+			return err
+		}
+	}
+	return r.runInContext(absolutePath, "", func(absolutePath string) error {
+		r.Parser.ValueInterpreter.FileResolver.SetContext(absolutePath)
+		return r.executeScenario(absolutePath, scenario)
+	})
+}
+
+// tryCandidate runs a reduced scenario, and keeps it if it still fails the same way.
+func (rd *scenarioReducer) tryCandidate(candidate *mj.Scenario) (bool, error) {
+	if rd.options.MaxRuns > 0 && rd.runs >= rd.options.MaxRuns {
+		return false, errMaxRunsReached
+	}
+	rd.runs++
+	err := rd.run(candidate)
+	if err == nil || !rd.options.SameFailure(rd.failure, err) {
+		return false, nil
+	}
+	rd.scenario = candidate
+	rd.failure = err
+	return true, nil
+}
+
+func (rd *scenarioReducer) reduceSteps() {
+	_ = reduceList(len(rd.scenario.Steps), func(kept []int) (bool, error) {
+		candidate := *rd.scenario
+		candidate.Steps = make([]mj.Step, len(kept))
+		for i, index := range kept {
+			candidate.Steps[i] = rd.scenario.Steps[index]
+		}
+		return rd.tryCandidate(&candidate)
+	})
+}
+
+// reduceAccounts removes the accounts that the remaining setState and checkState steps do not need.
+// Steps generating their accounts are left alone, since the writer writes the template, not the accounts.
+func (rd *scenarioReducer) reduceAccounts() {
+	for stepIndex := 0; stepIndex < len(rd.scenario.Steps); stepIndex++ {
+		var err error
+		switch step := rd.scenario.Steps[stepIndex].(type) {
+		case *mj.SetStateStep:
+			if step.GenerateAccounts != nil {
+				continue
+			}
+			err = reduceList(len(step.Accounts), func(kept []int) (bool, error) {
+				current := rd.scenario.Steps[stepIndex].(*mj.SetStateStep)
+				reducedStep := *current
+				reducedStep.Accounts = make([]*mj.Account, len(kept))
+				for i, index := range kept {
+					reducedStep.Accounts[i] = current.Accounts[index]
+				}
+				return rd.tryCandidate(rd.withStep(stepIndex, &reducedStep))
+			})
+		case *mj.CheckStateStep:
+			if step.CheckAccounts == nil {
+				continue
+			}
+			err = reduceList(len(step.CheckAccounts.Accounts), func(kept []int) (bool, error) {
+				current := rd.scenario.Steps[stepIndex].(*mj.CheckStateStep)
+				reducedAccounts := *current.CheckAccounts
+				reducedAccounts.Accounts = make([]*mj.CheckAccount, len(kept))
+				for i, index := range kept {
+					reducedAccounts.Accounts[i] = current.CheckAccounts.Accounts[index]
+				}
+				reducedStep := *current
+				reducedStep.CheckAccounts = &reducedAccounts
+				return rd.tryCandidate(rd.withStep(stepIndex, &reducedStep))
+			})
+		}
+		if err != nil {
+			return
+		}
+	}
+}
+
+// withStep yields a copy of the current scenario, with one of its steps replaced.
+func (rd *scenarioReducer) withStep(stepIndex int, step mj.Step) *mj.Scenario {
+	candidate := *rd.scenario
+	candidate.Steps = make([]mj.Step, len(rd.scenario.Steps))
+	copy(candidate.Steps, rd.scenario.Steps)
+	candidate.Steps[stepIndex] = step
+	return &candidate
+}
+
+// reduceList removes as many elements as possible from a list of the given length, delta debugging style:
+// chunks are removed from the largest, half the list, down to single elements.
+// The try function receives the indexes of the elements to keep, and says whether it kept the candidate.
+// The indexes always refer to the last candidate kept. Stops at the first error.
+func reduceList(length int, try func(kept []int) (bool, error)) error {
+	chunkSize := length / 2
+	if chunkSize == 0 {
+		chunkSize = length
+	}
+	for chunkSize > 0 && length > 0 {
+		removedAny := false
+		for start := 0; start < length; {
+			end := start + chunkSize
+			if end > length {
+				end = length
+			}
+			candidate := make([]int, 0, length-(end-start))
+			for i := 0; i < length; i++ {
+				if i < start || i >= end {
+					candidate = append(candidate, i)
+				}
+			}
+			kept, err := try(candidate)
+			if err != nil {
+				return err
+			}
+			if kept {
+				length = len(candidate)
+				removedAny = true
+			} else {
+				start = end
+			}
+		}
+		if !removedAny || chunkSize > length {
+			chunkSize /= 2
+		}
+	}
+	return nil
+}
@@ -0,0 +1,135 @@
+package denalicontroller
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"testing"
+
+	fr "github.com/numbatx/gn-vm-util/test-util/denali/json/fileresolver"
+	mj "github.com/numbatx/gn-vm-util/test-util/denali/json/model"
+	"github.com/stretchr/testify/require"
+)
+
+// reduceTestExecutor fails the step with id "bad", but only if the account "address:needed" was set before it.
+type reduceTestExecutor struct {
+	resets int
+}
+
+func (e *reduceTestExecutor) Reset() {
+	e.resets++
+}
+
+func (e *reduceTestExecutor) ExecuteScenario(scenario *mj.Scenario, _ fr.FileResolver) error {
+	neededIsSet := false
+	for i, generalStep := range scenario.Steps {
+		switch step := generalStep.(type) {
+		case *mj.SetStateStep:
+			for _, account := range step.Accounts {
+				if account.Address.Original == "address:needed" {
+					neededIsSet = true
+				}
+			}
+		case *mj.TxStep:
+			if step.ID == "bad" && neededIsSet {
+				return fmt.Errorf("step %d: bad transaction", i)
+			}
+		}
+	}
+	return nil
+}
+
+func TestReduceFailingScenario(t *testing.T) {
+	dir := t.TempDir()
+	scenarioPath := filepath.Join(dir, "long.scen.json")
+	require.Nil(t, os.WriteFile(scenarioPath, []byte(`{
+	"name": "long",
+	"autoNonces": true,
+	"steps": [
+		{
+			"step": "setState",
+			"accounts": {
+				"address:a": {"balance": "1000"},
+				"address:needed": {"balance": "1000"},
+				"address:b": {"balance": "1000"}
+			}
+		},
+		{"step": "transfer", "id": "1", "tx": {"from": "address:a", "to": "address:b", "value": "1"}},
+		{"step": "transfer", "id": "2", "tx": {"from": "address:a", "to": "address:b", "value": "1"}},
+		{"step": "transfer", "id": "3", "tx": {"from": "address:a", "to": "address:b", "value": "1"}},
+		{"step": "transfer", "id": "bad", "tx": {"from": "address:a", "to": "address:b", "value": "1"}},
+		{"step": "transfer", "id": "5", "tx": {"from": "address:a", "to": "address:b", "value": "1"}},
+		{"step": "checkState", "accounts": {"address:a": {"balance": "*"}, "address:b": {"balance": "*"}}}
+	]
+}`), 0644))
+
+	executor := &reduceTestExecutor{}
+	runner := NewScenarioRunner(executor, NewDefaultFileResolver())
+	reducedPath := filepath.Join(dir, "reduced", "long.scen.json")
+	reduction, err := runner.ReduceFailingScenario(scenarioPath, reducedPath, ReduceOptions{})
+	require.Nil(t, err)
+	require.Equal(t, 7, reduction.OriginalSteps)
+	require.Equal(t, 2, reduction.ReducedSteps)
+	require.Equal(t, "step 1: bad transaction", reduction.Failure.Error())
+	require.Equal(t, reduction.Runs+1, executor.resets)
+
+	setState := reduction.Scenario.Steps[0].(*mj.SetStateStep)
+	require.Equal(t, 1, len(setState.Accounts))
+	require.Equal(t, "address:needed", setState.Accounts[0].Address.Original)
+
+	// the written scenario fails the same way, with the nonce it had in the original
+	require.Equal(t, reduction.Failure.Error(), runner.RunSingleJSONScenario(reducedPath).Error())
+	reduced, err := runner.Parser.ParseScenarioFile(mustReadFile(t, reducedPath))
+	require.Nil(t, err)
+	require.Equal(t, uint64(3), reduced.Steps[1].(*mj.TxStep).Tx.Nonce.Value)
+
+	// runs are limited, the result is the smallest scenario found so far
+	limited, err := runner.ReduceFailingScenario(scenarioPath, reducedPath, ReduceOptions{MaxRuns: 1})
+	require.Nil(t, err)
+	require.Equal(t, 1, limited.Runs)
+	require.Equal(t, 7, limited.ReducedSteps)
+}
+
+func TestReducePassingScenario(t *testing.T) {
+	dir := t.TempDir()
+	scenarioPath := filepath.Join(dir, "passing.scen.json")
+	require.Nil(t, os.WriteFile(scenarioPath, []byte(`{"steps": []}`), 0644))
+
+	runner := NewScenarioRunner(&reduceTestExecutor{}, NewDefaultFileResolver())
+	_, err := runner.ReduceFailingScenario(scenarioPath, filepath.Join(dir, "reduced.scen.json"), ReduceOptions{})
+	require.NotNil(t, err)
+}
+
+func TestReduceList(t *testing.T) {
+	elements := []int{0, 1, 2, 3, 4, 5, 6, 7, 8, 9}
+	err := reduceList(len(elements), func(kept []int) (bool, error) {
+		candidate := make([]int, len(kept))
+		for i, index := range kept {
+			candidate[i] = elements[index]
+		}
+		hasThree, hasSeven := false, false
+		for _, element := range candidate {
+			hasThree = hasThree || element == 3
+			hasSeven = hasSeven || element == 7
+		}
+		if !hasThree || !hasSeven {
+			return false, nil
+		}
+		elements = candidate
+		return true, nil
+	})
+	require.Nil(t, err)
+	require.Equal(t, []int{3, 7}, elements)
+
+	stop := errors.New("stop")
+	require.Equal(t, stop, reduceList(3, func(kept []int) (bool, error) {
+		return false, stop
+	}))
+}
+
+func mustReadFile(t *testing.T, path string) []byte {
+	content, err := os.ReadFile(path)
+	require.Nil(t, err)
+	return content
+}
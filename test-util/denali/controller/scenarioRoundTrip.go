@@ -0,0 +1,186 @@
+package denalicontroller
+
+import (
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"path/filepath"
+	"reflect"
+	"sort"
+
+	mj "github.com/numbatx/gn-vm-util/test-util/denali/json/model"
+	mjparse "github.com/numbatx/gn-vm-util/test-util/denali/json/parse"
+	mjwrite "github.com/numbatx/gn-vm-util/test-util/denali/json/write"
+)
+
+// RoundTripLoss lists what did not survive writing a scenario file and parsing it again, see VerifyRoundTrip.
+type RoundTripLoss struct {
+	ScenarioPath string
+
+	// Fields are the paths of the model fields that changed, e.g. "Steps[2].Tx.GasPrice.Value".
+	Fields []string
+
+	// Error is set if the scenario could not be parsed, or its output could not be parsed again.
+	Error error
+}
+
+// roundTripIgnoredFields are the scenario fields that depend on the parser options rather than on the JSON.
+var roundTripIgnoredFields = map[string]bool{
+	"InlinedFiles":  true,
+	"Source":        true,
+	"StepPositions": true,
+}
+
+// VerifyRoundTrip parses a scenario file, writes it, parses the output again, and compares the two models.
+// Yields the paths of the fields that did not survive, none if the writer lost nothing.
+// Files holding several scenarios are verified scenario by scenario, the paths are then prefixed with the index.
+func (r *ScenarioRunner) VerifyRoundTrip(scenarioPath string) ([]string, error) {
+	content, err := ioutil.ReadFile(scenarioPath)
+	if err != nil {
+		return nil, err
+	}
+	absolutePath, err := filepath.Abs(scenarioPath)
+	if err != nil {
+		return nil, err
+	}
+	r.Parser.ValueInterpreter.FileResolver.SetContext(absolutePath)
+
+	documents, err := mjparse.SplitScenarioDocuments(content)
+	if err != nil {
+		return nil, withErrorFile(err, scenarioPath)
+	}
+	if documents == nil {
+		scenario, err := r.Parser.ParseScenarioFileWithArguments(content, placeholderArguments(content))
+		if err != nil {
+			return nil, withErrorFile(err, scenarioPath)
+		}
+		return r.roundTripLoss(scenario, "")
+	}
+
+	var lostFields []string
+	for _, document := range documents {
+		scenario, err := r.Parser.ParseScenarioDocument(document)
+		if err != nil {
+			return nil, withErrorFile(err, scenarioPath)
+		}
+		documentLostFields, err := r.roundTripLoss(scenario, MultiScenarioSuffix(document.Index)+":")
+		if err != nil {
+			return nil, fmt.Errorf("scenario %d: %w", document.Index, err)
+		}
+		lostFields = append(lostFields, documentLostFields...)
+	}
+	return lostFields, nil
+}
+
+// VerifyRoundTripsInDirectory is VerifyRoundTrip for every scenario in a directory, recursively.
+// Only the scenarios that lost something, or could not be verified, are listed. Yields an error if there are any.
+func (r *ScenarioRunner) VerifyRoundTripsInDirectory(dir string) ([]*RoundTripLoss, error) {
+	scenarioPaths, err := findScenarioFiles(dir)
+	if err != nil {
+		return nil, err
+	}
+	var losses []*RoundTripLoss
+	for _, scenarioPath := range scenarioPaths {
+		lostFields, err := r.VerifyRoundTrip(scenarioPath)
+		if err != nil || len(lostFields) > 0 {
+			losses = append(losses, &RoundTripLoss{
+				ScenarioPath: scenarioPath,
+				Fields:       lostFields,
+				Error:        err,
+			})
+		}
+	}
+	if len(losses) > 0 {
+		return losses, errors.New("some scenarios do not survive a round trip through the writer")
+	}
+	return nil, nil
+}
+
+func (r *ScenarioRunner) roundTripLoss(scenario *mj.Scenario, pathPrefix string) ([]string, error) {
+	return r.writtenLoss(scenario, mjwrite.ScenarioToJSONString(scenario), pathPrefix)
+}
+
+// writtenLoss parses the written form of a scenario and compares the result with the scenario.
+func (r *ScenarioRunner) writtenLoss(scenario *mj.Scenario, written string, pathPrefix string) ([]string, error) {
+	var arguments []*mj.NamedConstant
+	if len(scenario.Parameters) > 0 {
+		arguments = scenario.Parameters
+	}
+	reparsed, err := r.Parser.ParseScenarioFileWithArguments([]byte(written), arguments)
+	if err != nil {
+		return nil, fmt.Errorf("written scenario cannot be parsed again: %w", err)
+	}
+
+	var lostFields []string
+	original, again := reflect.ValueOf(scenario).Elem(), reflect.ValueOf(reparsed).Elem()
+	for i := 0; i < original.NumField(); i++ {
+		field := original.Type().Field(i)
+		if !field.IsExported() || roundTripIgnoredFields[field.Name] {
+			continue
+		}
+		lostFields = diffValues(pathPrefix+field.Name, original.Field(i), again.Field(i), lostFields)
+	}
+	return lostFields, nil
+}
+
+// diffValues compares two values of the same type, recursively, and appends the paths where they differ.
+// Unexported fields are not compared.
+func diffValues(path string, a reflect.Value, b reflect.Value, diffs []string) []string {
+	switch a.Kind() {
+	case reflect.Ptr, reflect.Interface:
+		if a.IsNil() || b.IsNil() {
+			if a.IsNil() != b.IsNil() {
+				return append(diffs, path)
+			}
+			return diffs
+		}
+		if a.Kind() == reflect.Interface && a.Elem().Type() != b.Elem().Type() {
+			return append(diffs, path)
+		}
+		return diffValues(path, a.Elem(), b.Elem(), diffs)
+	case reflect.Struct:
+		for i := 0; i < a.NumField(); i++ {
+			field := a.Type().Field(i)
+			if !field.IsExported() {
+				continue
+			}
+			fieldPath := path + "." + field.Name
+			if field.Anonymous {
+				// promoted, e.g. the step metadata
+				fieldPath = path
+			}
+			diffs = diffValues(fieldPath, a.Field(i), b.Field(i), diffs)
+		}
+		return diffs
+	case reflect.Slice, reflect.Array:
+		if a.Len() != b.Len() {
+			return append(diffs, path)
+		}
+		for i := 0; i < a.Len(); i++ {
+			diffs = diffValues(fmt.Sprintf("%s[%d]", path, i), a.Index(i), b.Index(i), diffs)
+		}
+		return diffs
+	case reflect.Map:
+		if a.Len() != b.Len() {
+			return append(diffs, path)
+		}
+		var keyDiffs []string
+		for _, key := range a.MapKeys() {
+			bValue := b.MapIndex(key)
+			keyPath := fmt.Sprintf("%s[%v]", path, key.Interface())
+			if !bValue.IsValid() {
+				keyDiffs = append(keyDiffs, keyPath)
+				continue
+			}
+			keyDiffs = diffValues(keyPath, a.MapIndex(key), bValue, keyDiffs)
+		}
+		// map iteration order is random
+		sort.Strings(keyDiffs)
+		return append(diffs, keyDiffs...)
+	default:
+		if !reflect.DeepEqual(a.Interface(), b.Interface()) {
+			return append(diffs, path)
+		}
+		return diffs
+	}
+}
@@ -0,0 +1,62 @@
+package denalicontroller
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	mj "github.com/numbatx/gn-vm-util/test-util/denali/json/model"
+	"github.com/stretchr/testify/require"
+)
+
+func TestVerifyRoundTripsInDirectory(t *testing.T) {
+	dir := t.TempDir()
+	writeFile := func(name string, content string) {
+		require.Nil(t, os.WriteFile(filepath.Join(dir, name), []byte(content), 0644))
+	}
+	writeFile("transfer.scen.json", `{
+	"name": "transfer",
+	"steps": [
+		{
+			"step": "transfer",
+			"id": "pay",
+			"tx": {"from": "address:a", "to": "address:b", "value": "1", "gasLimit": "7", "gasPrice": "3"}
+		}
+	]
+}`)
+	writeFile("several.scen.json", "{\"name\": \"first\", \"steps\": []}\n{\"name\": \"second\", \"autoNonces\": true, \"steps\": []}\n")
+	writeFile("included.scen.json", `{"parameters": ["to"], "steps": [{"step": "transfer", "tx": {"from": "address:a", "to": "$to", "value": "1"}}]}`)
+
+	runner := NewScenarioRunner(nil, NewDefaultFileResolver())
+	losses, err := runner.VerifyRoundTripsInDirectory(dir)
+	require.Nil(t, err)
+	require.Empty(t, losses)
+
+	writeFile("broken.scen.json", `{"steps": [{"step": "unknown"}]}`)
+	losses, err = runner.VerifyRoundTripsInDirectory(dir)
+	require.NotNil(t, err)
+	require.Equal(t, 1, len(losses))
+	require.Equal(t, filepath.Join(dir, "broken.scen.json"), losses[0].ScenarioPath)
+	require.NotNil(t, losses[0].Error)
+}
+
+func TestRoundTripDiff(t *testing.T) {
+	scenarioJSON := []byte(`{"steps": [{"step": "transfer", "tx": {"from": "address:a", "to": "address:b", "value": "1"}}]}`)
+	runner := NewScenarioRunner(nil, NewDefaultFileResolver())
+	original, err := runner.Parser.ParseScenarioFile(scenarioJSON)
+	require.Nil(t, err)
+	changed, err := runner.Parser.ParseScenarioFile(scenarioJSON)
+	require.Nil(t, err)
+	require.Empty(t, diffValues("", reflect.ValueOf(original), reflect.ValueOf(changed), nil))
+
+	changedStep := changed.Steps[0].(*mj.TxStep)
+	changedStep.ID = "renamed"
+	changedStep.Tx.Value.Original = "2"
+	changed.Steps = append(changed.Steps, changedStep)
+	require.Equal(t, []string{"Steps"},
+		diffValues("Steps", reflect.ValueOf(original.Steps), reflect.ValueOf(changed.Steps), nil))
+	changed.Steps = changed.Steps[:1]
+	require.Equal(t, []string{"Steps[0].ID", "Steps[0].Tx.Value.Original"},
+		diffValues("Steps", reflect.ValueOf(original.Steps), reflect.ValueOf(changed.Steps), nil))
+}
@@ -1,6 +1,9 @@
 package denalicontroller
 
 import (
+	"io/fs"
+	"time"
+
 	fr "github.com/numbatx/gn-vm-util/test-util/denali/json/fileresolver"
 	mj "github.com/numbatx/gn-vm-util/test-util/denali/json/model"
 	mjparse "github.com/numbatx/gn-vm-util/test-util/denali/json/parse"
@@ -13,7 +16,7 @@ type ScenarioExecutor interface {
 
 	// ExecuteScenario executes the scenario and checks if it passed. Failure is signaled by returning an error.
 	// The FileResolver helps with resolving external steps.
-	// TODO: group into a "execution context" param.
+	// Executors that need the full execution context implement ScenarioContextExecutor.
 	ExecuteScenario(*mj.Scenario, fr.FileResolver) error
 }
 
@@ -21,6 +24,89 @@ type ScenarioExecutor interface {
 type ScenarioRunner struct {
 	Executor ScenarioExecutor
 	Parser   mjparse.Parser
+
+	// ContinueOnError keeps directory runs going after the executor panics.
+	// By default the run stops, since the state of the executor can no longer be trusted.
+	// Regular scenario failures never stop the run.
+	ContinueOnError bool
+
+	// ScenarioTimeout and StepTimeout, if set, fail the scenarios that run longer, or have a step that does,
+	// with a *TimeoutError, instead of hanging. Steps are only timed if the executor reports them,
+	// see ExecutionContext.StepDispatched. The scenario then gets its context canceled, see ExecutionContext.Context.
+	ScenarioTimeout time.Duration
+	StepTimeout     time.Duration
+
+	// AuditLog, if set, records everything the runner does. Set it using EnableAuditLog.
+	AuditLog *AuditLog
+
+	// Order decides in which order directory runs execute scenarios.
+	Order ScenarioOrder
+
+	// History holds the results of previous runs, keyed by scenario path, see ComputeScenarioHistory.
+	// Only needed by the order policies based on history.
+	History map[string]*ScenarioHistory
+
+	// SeedStatePath, if set, is a state file that every scenario starts from, instead of an empty world,
+	// e.g. a snapshot of production state. The file is a scenario consisting of setState steps only,
+	// as written by SaveScenario. Requires the executor to be a StateSeedExecutor.
+	SeedStatePath string
+
+	// FS, if set, holds the scenarios instead of the OS file system, e.g. scenarios embedded with go:embed.
+	// Scenario paths are then slash-separated and relative to its root. Set it using NewScenarioRunnerFS,
+	// so that the file resolver reads from it too. Inventories and benchmarks only support the OS file system.
+	FS fs.FS
+
+	// ExecutorFactory, if set, creates the executors of parallel runs, one per worker, see RunScenariosParallel.
+	ExecutorFactory ExecutorFactory
+
+	// AcceptActualValues, if set, makes scenarios that fail on a mismatch of expected values pass instead,
+	// by writing the actual values into the scenario file: the out values, status and message of transactions,
+	// their gas, refund and logs if the executor reports them, see QueryResult, and the balances, nonces
+	// and storage values of checkState steps. Only the values that are checked exactly are replaced,
+	// "*" and matchers are kept. Meant for updating the expectations of many scenarios at once,
+	// after an intended change, the files need reviewing afterwards. Scenarios read from streams,
+	// from the runner FS, or from multi-document files, and the scenarios they include, are never rewritten.
+	AcceptActualValues bool
+
+	// StepHooks observe every step of the scenarios run, see StepHook. Only executors that report their steps
+	// call them, through ExecutionContext.StepDispatched and StepDone. In parallel runs, they are shared by the workers,
+	// so they have to be safe for concurrent use.
+	StepHooks []StepHook
+
+	// ReportSinks receive the results of directory runs, e.g. to write JUnit XML.
+	// If there are none, results are printed to stdout, see StdoutReportSink.
+	ReportSinks []ReportSink
+
+	// contextPaths holds the scenario files currently running, the outermost first.
+	// Executors run externalSteps by calling back into the runner, which then
+	// needs to restore the file resolver context of the including file.
+	contextPaths []string
+
+	// stepOutputs collects the outputs reported by the executor, for the report of the current scenario.
+	stepOutputs []*StepOutput
+
+	// stepWarnings collects the tolerated setState failures, for the report of the current scenario.
+	stepWarnings []string
+
+	// scenarioName and stepsExecuted describe the current scenario, for its report.
+	scenarioName  string
+	stepsExecuted int
+
+	// filter selects the scenarios of the current directory run, nil if all of them run, see DirectoryRunOptions.
+	filter scenarioFilter
+
+	// fileReads, if set, collects the absolute paths of the files read while running scenarios,
+	// by outermost scenario path, see WatchDirectory.
+	fileReads map[string]map[string]bool
+
+	// timeouts times the outermost scenario running, nil if the runner has no timeouts.
+	timeouts *scenarioTimeouts
+
+	// lastStep locates the last step of the current scenario that the executor dispatched, nil if unknown.
+	// lastStepType is its type, empty if unknown, and lastStepIndex its index.
+	lastStep      *SourceLocation
+	lastStepType  string
+	lastStepIndex int
 }
 
 // NewScenarioRunner creates new ScenarioRunner instance.
@@ -30,3 +116,27 @@ func NewScenarioRunner(executor ScenarioExecutor, fileResolver fr.FileResolver)
 		Parser:   mjparse.NewParser(fileResolver),
 	}
 }
+
+// NewScenarioRunnerFS creates a ScenarioRunner that reads scenarios, and the files they load, from a file system
+// other than the OS one, see ScenarioRunner.FS.
+func NewScenarioRunnerFS(executor ScenarioExecutor, fsys fs.FS) *ScenarioRunner {
+	return &ScenarioRunner{
+		Executor: executor,
+		Parser:   mjparse.NewFSParser(fsys),
+		FS:       fsys,
+	}
+}
+
+// EnableAuditLog makes the runner record all its actions to the audit log,
+// including the files loaded by the file resolver.
+func (r *ScenarioRunner) EnableAuditLog(auditLog *AuditLog) {
+	r.AuditLog = auditLog
+	fileResolver := r.Parser.ValueInterpreter.FileResolver
+	if wrapped, isWrapped := fileResolver.(*auditFileResolver); isWrapped {
+		fileResolver = wrapped.FileResolver
+	}
+	r.Parser.ValueInterpreter.FileResolver = &auditFileResolver{
+		FileResolver: fileResolver,
+		auditLog:     auditLog,
+	}
+}
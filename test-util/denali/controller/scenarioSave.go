@@ -0,0 +1,166 @@
+package denalicontroller
+
+import (
+	"bytes"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path"
+	"path/filepath"
+
+	mj "github.com/numbatx/gn-vm-util/test-util/denali/json/model"
+	mjwrite "github.com/numbatx/gn-vm-util/test-util/denali/json/write"
+)
+
+// SaveScenarioOptions configures how SaveScenario writes a scenario.
+type SaveScenarioOptions struct {
+	// BackupOriginal keeps a copy of the file being overwritten, with the ".bak" suffix appended.
+	BackupOriginal bool
+
+	// Compatibility, if set, downgrades the scenario to an older format before writing it.
+	Compatibility *mjwrite.CompatibilityOptions
+
+	// Format, if set, is the layout of the written JSON, instead of the default one.
+	Format *mjwrite.WriterOptions
+
+	// MinimalRewrite only rewrites the values that changed in the file being overwritten,
+	// keeping the rest of its text as it is, see mjwrite.ScenarioToJSONStringPatched.
+	// Files that do not exist yet are written whole.
+	MinimalRewrite bool
+
+	// ExternalizeBlobsFrom, if set, is the size in bytes from which values written inline, e.g. contract code,
+	// are moved to files in BlobDir and referenced from the scenario, see mjwrite.ExternalizeBlobs.
+	ExternalizeBlobsFrom int
+
+	// BlobDir is where externalized values are saved, relative to the scenario. Defaults to DefaultBlobDir.
+	BlobDir string
+
+	// DryRun writes nothing, not even backups or externalized values: WriteScenarioFile only yields the diff.
+	DryRun bool
+}
+
+// DefaultBlobDir is where SaveScenario externalizes values, unless configured otherwise.
+const DefaultBlobDir = "blobs"
+
+// SaveScenario serializes a scenario and writes it to a file, creating directories as needed.
+// Used by tools that modify or migrate scenarios. See WriteScenarioFile.
+func SaveScenario(toPath string, scenario *mj.Scenario, options SaveScenarioOptions) error {
+	_, err := WriteScenarioFile(toPath, scenario, options)
+	return err
+}
+
+// WriteScenarioFile is SaveScenario, also yielding the changes made to the file as a unified diff,
+// empty if there are none, in which case the file is left alone. Files are replaced atomically:
+// the scenario is written to a temporary file, renamed over the original once complete,
+// so that a failed write never leaves a truncated scenario behind. With DryRun, the diff is only a preview.
+func WriteScenarioFile(toPath string, scenario *mj.Scenario, options SaveScenarioOptions) (string, error) {
+	original, err := ioutil.ReadFile(toPath)
+	exists := err == nil
+	if err != nil && !os.IsNotExist(err) {
+		return "", err
+	}
+
+	if options.Compatibility != nil {
+		scenario, err = mjwrite.DowngradeScenario(scenario, *options.Compatibility)
+		if err != nil {
+			return "", err
+		}
+	}
+	if options.ExternalizeBlobsFrom > 0 {
+		scenario, err = externalizeBlobs(toPath, scenario, options)
+		if err != nil {
+			return "", err
+		}
+	}
+	format := mjwrite.DefaultWriterOptions
+	if options.Format != nil {
+		format = *options.Format
+	}
+	resultJSON := mjwrite.ScenarioToJSONStringWithOptions(scenario, format)
+	if options.MinimalRewrite && exists {
+		resultJSON, err = mjwrite.ScenarioToJSONStringPatched(original, scenario, format)
+		if err != nil {
+			return "", fmt.Errorf("cannot rewrite %s: %w", toPath, err)
+		}
+	}
+
+	if exists && bytes.Equal(original, []byte(resultJSON)) {
+		return "", nil
+	}
+	diff := unifiedDiff(toPath, string(original), resultJSON)
+	if options.DryRun {
+		return diff, nil
+	}
+
+	err = os.MkdirAll(filepath.Dir(toPath), os.ModePerm)
+	if err != nil {
+		return "", err
+	}
+	if options.BackupOriginal && exists {
+		err = writeFileAtomically(toPath+".bak", original)
+		if err != nil {
+			return "", fmt.Errorf("cannot back up %s: %w", toPath, err)
+		}
+	}
+	err = writeFileAtomically(toPath, []byte(resultJSON))
+	if err != nil {
+		return "", err
+	}
+	return diff, nil
+}
+
+func externalizeBlobs(toPath string, scenario *mj.Scenario, options SaveScenarioOptions) (*mj.Scenario, error) {
+	blobDir := options.BlobDir
+	if len(blobDir) == 0 {
+		blobDir = DefaultBlobDir
+	}
+	blobOptions := mjwrite.BlobOptions{
+		MinSize:   options.ExternalizeBlobsFrom,
+		SaveCode:  mjwrite.DirectoryCodeSaver(filepath.Dir(toPath), blobDir),
+		SaveValue: mjwrite.DirectoryBlobSaver(filepath.Dir(toPath), blobDir),
+	}
+	if options.DryRun {
+		blobOptions.SaveCode = previewSaver(blobDir, ".wasm")
+		blobOptions.SaveValue = previewSaver(blobDir, ".bin")
+	}
+	return mjwrite.ExternalizeBlobs(scenario, blobOptions)
+}
+
+// previewSaver names the files that values would be saved to, without saving them.
+// Unlike the directory savers, it does not check for existing files.
+func previewSaver(dir string, extension string) mjwrite.CodeSaver {
+	return func(_ []byte, nameHint string) (string, error) {
+		return path.Join(filepath.ToSlash(dir), nameHint+extension), nil
+	}
+}
+
+// writeFileAtomically writes to a temporary file in the same directory, then renames it over the target.
+// Existing files keep their mode, new ones are created with 0644.
+func writeFileAtomically(toPath string, content []byte) error {
+	mode := os.FileMode(0644)
+	if info, statErr := os.Stat(toPath); statErr == nil {
+		mode = info.Mode().Perm()
+	}
+
+	tempFile, err := ioutil.TempFile(filepath.Dir(toPath), "."+filepath.Base(toPath)+".*.tmp")
+	if err != nil {
+		return err
+	}
+	defer func() {
+		// only left behind if something failed
+		_ = os.Remove(tempFile.Name())
+	}()
+
+	_, err = tempFile.Write(content)
+	if err == nil {
+		err = tempFile.Chmod(mode)
+	}
+	closeErr := tempFile.Close()
+	if err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		return err
+	}
+	return os.Rename(tempFile.Name(), toPath)
+}
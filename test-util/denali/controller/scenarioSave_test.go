@@ -0,0 +1,90 @@
+package denalicontroller
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	mj "github.com/numbatx/gn-vm-util/test-util/denali/json/model"
+	"github.com/stretchr/testify/require"
+)
+
+func TestWriteScenarioFile(t *testing.T) {
+	dir := t.TempDir()
+	scenarioPath := filepath.Join(dir, "transfer.scen.json")
+	runner := NewScenarioRunner(nil, NewDefaultFileResolver())
+	scenario, err := runner.Parser.ParseScenarioFile([]byte(`{"name": "transfer", "steps": [{"step": "transfer", "tx": {"from": "address:a", "to": "address:b", "value": "1"}}]}`))
+	require.Nil(t, err)
+
+	diff, err := WriteScenarioFile(scenarioPath, scenario, SaveScenarioOptions{})
+	require.Nil(t, err)
+	require.Contains(t, diff, "@@ -0,0 +1,13 @@\n")
+	original, err := os.ReadFile(scenarioPath)
+	require.Nil(t, err)
+
+	// writing the same scenario changes nothing
+	diff, err = WriteScenarioFile(scenarioPath, scenario, SaveScenarioOptions{})
+	require.Nil(t, err)
+	require.Equal(t, "", diff)
+
+	scenario.Steps[0].(*mj.TxStep).Tx.Value.Original = "2"
+	options := SaveScenarioOptions{BackupOriginal: true, DryRun: true}
+	diff, err = WriteScenarioFile(scenarioPath, scenario, options)
+	require.Nil(t, err)
+	require.Equal(t, "--- "+scenarioPath+"\n"+
+		"+++ "+scenarioPath+"\n"+
+		"@@ -6,7 +6,7 @@\n"+
+		"             \"tx\": {\n"+
+		"                 \"from\": \"address:a\",\n"+
+		"                 \"to\": \"address:b\",\n"+
+		"-                \"value\": \"1\"\n"+
+		"+                \"value\": \"2\"\n"+
+		"             }\n"+
+		"         }\n"+
+		"     ]\n", diff)
+	// a dry run writes nothing
+	unchanged, err := os.ReadFile(scenarioPath)
+	require.Nil(t, err)
+	require.Equal(t, original, unchanged)
+	require.NoFileExists(t, scenarioPath+".bak")
+
+	options.DryRun = false
+	writtenDiff, err := WriteScenarioFile(scenarioPath, scenario, options)
+	require.Nil(t, err)
+	require.Equal(t, diff, writtenDiff)
+	backup, err := os.ReadFile(scenarioPath + ".bak")
+	require.Nil(t, err)
+	require.Equal(t, original, backup)
+
+	// no temporary files are left behind
+	entries, err := os.ReadDir(dir)
+	require.Nil(t, err)
+	require.Equal(t, 2, len(entries))
+}
+
+func TestWriteFileAtomicallyKeepsMode(t *testing.T) {
+	dir := t.TempDir()
+	newPath := filepath.Join(dir, "new.scen.json")
+	require.Nil(t, writeFileAtomically(newPath, []byte("{}")))
+	info, err := os.Stat(newPath)
+	require.Nil(t, err)
+	require.Equal(t, os.FileMode(0644), info.Mode().Perm())
+
+	executablePath := filepath.Join(dir, "executable.scen.json")
+	require.Nil(t, os.WriteFile(executablePath, []byte("{}"), 0755))
+	require.Nil(t, os.Chmod(executablePath, 0755))
+	require.Nil(t, writeFileAtomically(executablePath, []byte(`{"steps": []}`)))
+	info, err = os.Stat(executablePath)
+	require.Nil(t, err)
+	require.Equal(t, os.FileMode(0755), info.Mode().Perm())
+}
+
+func TestUnifiedDiff(t *testing.T) {
+	from := "a\nb\nc\nd\ne\nf\ng\nh\ni\nj\nk\nl\nm\n"
+	to := "a\nB\nc\nd\ne\nf\ng\nh\ni\nj\nk\nm\nn\n"
+	require.Equal(t, "--- f\n+++ f\n"+
+		"@@ -1,5 +1,5 @@\n a\n-b\n+B\n c\n d\n e\n"+
+		"@@ -9,5 +9,5 @@\n i\n j\n k\n-l\n m\n+n\n",
+		unifiedDiff("f", from, to))
+	require.Equal(t, "", unifiedDiff("f", from, from))
+}
@@ -0,0 +1,152 @@
+package denalicontroller
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	mj "github.com/numbatx/gn-vm-util/test-util/denali/json/model"
+)
+
+// TimeoutError is what scenarios that run longer than the runner ScenarioTimeout or StepTimeout fail with.
+type TimeoutError struct {
+	// Step describes the step that timed out, e.g. "step 3 (deposit)", empty if the whole scenario did.
+	Step string
+
+	Timeout time.Duration
+
+	// Abandoned is set if the executor did not return once the scenario timed out, even after the grace period.
+	// It then keeps running in the background, so the run stops, whatever ContinueOnError says.
+	Abandoned bool
+}
+
+// Error says what timed out, and after how long.
+func (e *TimeoutError) Error() string {
+	what := "scenario"
+	if len(e.Step) > 0 {
+		what = e.Step
+	}
+	message := fmt.Sprintf("%s timed out after %s", what, e.Timeout)
+	if e.Abandoned {
+		message += ", the executor did not stop"
+	}
+	return message
+}
+
+// timeoutGracePeriod is how long the runner waits for executors to return, once it has canceled their context.
+var timeoutGracePeriod = time.Second
+
+// scenarioTimeouts enforces the timeouts of an outermost scenario. The steps of the scenarios it includes
+// are timed like its own, the time they take counts towards the scenario timeout.
+type scenarioTimeouts struct {
+	ctx         context.Context
+	cancel      context.CancelCauseFunc
+	stepTimeout time.Duration
+
+	mutex     sync.Mutex
+	stepTimer *time.Timer
+}
+
+// stepStarted restarts the step timeout, if any.
+func (t *scenarioTimeouts) stepStarted(stepIndex int, step mj.Step) {
+	if t == nil || t.stepTimeout == 0 {
+		return
+	}
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+	if t.stepTimer != nil {
+		t.stepTimer.Stop()
+	}
+	timeoutErr := &TimeoutError{
+		Step:    mj.DescribeStep(stepIndex, step),
+		Timeout: t.stepTimeout,
+	}
+	t.stepTimer = time.AfterFunc(t.stepTimeout, func() {
+		t.cancel(timeoutErr)
+	})
+}
+
+func (t *scenarioTimeouts) stop() {
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+	if t.stepTimer != nil {
+		t.stepTimer.Stop()
+	}
+	t.cancel(nil)
+}
+
+// runWithTimeouts executes an outermost scenario on a goroutine of its own, so that it can be abandoned
+// once it times out. The execution context, nil for executors without one, gets the context to cancel.
+// Included scenarios run within the timeouts of the outermost one, and so do scenarios of runners without timeouts.
+func (r *ScenarioRunner) runWithTimeouts(ctx *ExecutionContext, execute func() error) error {
+	if r.timeouts != nil || (r.ScenarioTimeout == 0 && r.StepTimeout == 0) {
+		if ctx != nil && r.timeouts != nil {
+			ctx.Context = r.timeouts.ctx
+			ctx.timeouts = r.timeouts
+		}
+		return execute()
+	}
+
+	timeouts := &scenarioTimeouts{stepTimeout: r.StepTimeout}
+	timeouts.ctx, timeouts.cancel = context.WithCancelCause(context.Background())
+	defer timeouts.stop()
+	if r.ScenarioTimeout > 0 {
+		scenarioTimer := time.AfterFunc(r.ScenarioTimeout, func() {
+			timeouts.cancel(&TimeoutError{Timeout: r.ScenarioTimeout})
+		})
+		defer scenarioTimer.Stop()
+	}
+	if ctx != nil {
+		ctx.Context = timeouts.ctx
+		ctx.timeouts = timeouts
+	}
+	r.timeouts = timeouts
+	defer func() {
+		r.timeouts = nil
+	}()
+
+	done := make(chan error, 1)
+	go func() {
+		var err error
+		defer func() {
+			done <- err
+		}()
+		defer recoverExecutorPanic(&err)
+		err = execute()
+	}()
+	select {
+	case err := <-done:
+		return err
+	case <-timeouts.ctx.Done():
+	}
+
+	var timeoutErr *TimeoutError
+	if !errors.As(context.Cause(timeouts.ctx), &timeoutErr) {
+		return context.Cause(timeouts.ctx)
+	}
+	select {
+	case <-done:
+		// whatever the executor failed with, most likely the cancellation
+		return timeoutErr
+	case <-time.After(timeoutGracePeriod):
+		abandonedErr := *timeoutErr
+		abandonedErr.Abandoned = true
+		return &abandonedErr
+	}
+}
+
+// stopsRun returns true for the errors after which directory runs cannot go on with the same executor:
+// executor panics, unless ContinueOnError is set, and timeouts of executors that did not stop.
+func (r *ScenarioRunner) stopsRun(testErr error) bool {
+	var panicErr *ExecutorPanicError
+	var timeoutErr *TimeoutError
+	switch {
+	case errors.As(testErr, &timeoutErr):
+		return timeoutErr.Abandoned
+	case errors.As(testErr, &panicErr):
+		return !r.ContinueOnError
+	}
+	return false
+}
@@ -0,0 +1,85 @@
+package denalicontroller
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	fr "github.com/numbatx/gn-vm-util/test-util/denali/json/fileresolver"
+	mj "github.com/numbatx/gn-vm-util/test-util/denali/json/model"
+	"github.com/stretchr/testify/require"
+)
+
+// slowExecutor blocks in the steps with id "slow": until their context is canceled if it is cooperative,
+// until released otherwise.
+type slowExecutor struct {
+	cooperative bool
+	released    chan struct{}
+}
+
+func (e *slowExecutor) Reset() {}
+
+func (e *slowExecutor) ExecuteScenario(scenario *mj.Scenario, fileResolver fr.FileResolver) error {
+	return e.ExecuteScenarioWithContext(scenario, NewExecutionContext(scenario, fileResolver))
+}
+
+func (e *slowExecutor) ExecuteScenarioWithContext(scenario *mj.Scenario, ctx *ExecutionContext) error {
+	for stepIndex, step := range scenario.Steps {
+		ctx.StepDispatched(stepIndex, step)
+		if mj.StepMetadataOf(step).ID != "slow" {
+			continue
+		}
+		if e.cooperative {
+			<-ctx.Context.Done()
+			return ctx.Context.Err()
+		}
+		<-e.released
+	}
+	return nil
+}
+
+func writeTimeoutTestScenarios(t *testing.T) string {
+	dir := t.TempDir()
+	for name, stepID := range map[string]string{"a_slow": "slow", "b_fast": "fast"} {
+		scenarioJSON := `{"steps": [{"step": "setState"}, {"step": "setState", "id": "` + stepID + `"}]}`
+		require.Nil(t, os.WriteFile(filepath.Join(dir, name+".scen.json"), []byte(scenarioJSON), 0644))
+	}
+	return dir
+}
+
+func TestStepTimeout(t *testing.T) {
+	dir := writeTimeoutTestScenarios(t)
+	runner := NewScenarioRunner(&slowExecutor{cooperative: true}, NewDefaultFileResolver())
+	runner.StepTimeout = 20 * time.Millisecond
+	runner.ReportSinks = []ReportSink{NewJSONReportSink(&bytes.Buffer{})}
+
+	report, err := runner.RunAllJSONScenariosInDirectoryWithOptions(dir, DirectoryRunOptions{})
+	require.NotNil(t, err)
+	require.False(t, report.Stopped)
+	require.Equal(t, "step 1 (slow) timed out after 20ms", report.Scenarios[0].Error)
+	require.Equal(t, ScenarioPassed, report.Scenarios[1].Status)
+}
+
+func TestScenarioTimeoutAbandoned(t *testing.T) {
+	defaultGracePeriod := timeoutGracePeriod
+	timeoutGracePeriod = 10 * time.Millisecond
+	defer func() {
+		timeoutGracePeriod = defaultGracePeriod
+	}()
+	dir := writeTimeoutTestScenarios(t)
+	executor := &slowExecutor{released: make(chan struct{})}
+	defer close(executor.released)
+	runner := NewScenarioRunner(executor, NewDefaultFileResolver())
+	runner.ScenarioTimeout = 20 * time.Millisecond
+	runner.ContinueOnError = true
+	runner.ReportSinks = []ReportSink{NewJSONReportSink(&bytes.Buffer{})}
+
+	// the executor keeps running, so the run cannot go on
+	report, err := runner.RunAllJSONScenariosInDirectoryWithOptions(dir, DirectoryRunOptions{})
+	require.NotNil(t, err)
+	require.True(t, report.Stopped)
+	require.Equal(t, 1, len(report.Scenarios))
+	require.Equal(t, "scenario timed out after 20ms, the executor did not stop", report.Scenarios[0].Error)
+}
@@ -0,0 +1,207 @@
+package denalicontroller
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"time"
+
+	fr "github.com/numbatx/gn-vm-util/test-util/denali/json/fileresolver"
+)
+
+// DefaultWatchPollInterval is how often WatchDirectory checks files for changes, unless configured otherwise.
+const DefaultWatchPollInterval = 500 * time.Millisecond
+
+// WatchOptions configures WatchDirectory.
+type WatchOptions struct {
+	// DirectoryRunOptions select the scenarios to watch, as for a regular directory run.
+	DirectoryRunOptions
+
+	// PollInterval is how often files are checked for changes, DefaultWatchPollInterval if not positive.
+	PollInterval time.Duration
+}
+
+// WatchDirectory runs the scenarios of a directory tree, as RunAllJSONScenariosInDirectoryWithOptions does,
+// then re-runs the ones affected whenever files change, for a fast edit and run loop: scenarios whose file changed,
+// or any file they read when they last ran, e.g. contract code loaded with "file:", or scenarios included
+// through externalSteps. New scenario files run too. Each round of scenarios is a run of its own, reported
+// to the ReportSinks. Files are polled, rather than watched through notifications of the operating system.
+// Returns once the context is canceled, or if the scenarios cannot be listed. Only supports the OS file system.
+func (r *ScenarioRunner) WatchDirectory(ctx context.Context, dirPath string, options WatchOptions) error {
+	if r.FS != nil {
+		return errors.New("watching requires the OS file system")
+	}
+	dirPath, err := filepath.Abs(dirPath)
+	if err != nil {
+		return err
+	}
+	pollInterval := options.PollInterval
+	if pollInterval <= 0 {
+		pollInterval = DefaultWatchPollInterval
+	}
+
+	if _, err := newScenarioFilter(options.DirectoryRunOptions); err != nil {
+		return err
+	}
+
+	fileResolver := r.Parser.ValueInterpreter.FileResolver
+	r.Parser.ValueInterpreter.FileResolver = &watchFileResolver{
+		FileResolver: fileResolver,
+		runner:       r,
+	}
+	r.fileReads = make(map[string]map[string]bool)
+	defer func() {
+		r.Parser.ValueInterpreter.FileResolver = fileResolver
+		r.fileReads = nil
+	}()
+
+	watch := &scenarioWatch{
+		dependencies: make(map[string][]string),
+		stamps:       make(map[string]fileStamp),
+	}
+	for {
+		scenarioPaths, err := r.listDirectoryScenarios(dirPath, options.DirectoryRunOptions)
+		if err != nil {
+			return err
+		}
+		if affected := watch.affectedScenarios(scenarioPaths); len(affected) > 0 {
+			report := &RunReport{
+				StartedAt: time.Now(),
+			}
+			// failures are reported to the sinks, the options were checked beforehand
+			_, _ = r.runDirectoryScenarios(report, dirPath, affected, options.DirectoryRunOptions)
+			watch.recordRun(affected, r.fileReads)
+		}
+
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-time.After(pollInterval):
+		}
+	}
+}
+
+// recordFileRead remembers a file read while running a scenario, see WatchDirectory.
+func (r *ScenarioRunner) recordFileRead(filePath string) {
+	if r.fileReads == nil || len(r.contextPaths) == 0 {
+		return
+	}
+	scenarioPath := r.contextPaths[0]
+	if r.fileReads[scenarioPath] == nil {
+		r.fileReads[scenarioPath] = make(map[string]bool)
+	}
+	if absolutePath, err := filepath.Abs(filePath); err == nil {
+		r.fileReads[scenarioPath][absolutePath] = true
+	}
+}
+
+var _ fr.FileResolver = (*watchFileResolver)(nil)
+
+// watchFileResolver records all files loaded by the wrapped resolver, as dependencies of the scenario running.
+type watchFileResolver struct {
+	fr.FileResolver
+	runner *ScenarioRunner
+}
+
+// Clone creates new instance of the same type.
+func (wfr *watchFileResolver) Clone() fr.FileResolver {
+	return &watchFileResolver{
+		FileResolver: wfr.FileResolver.Clone(),
+		runner:       wfr.runner,
+	}
+}
+
+// ResolveFileValue converts a value prefixed with "file:" and replaces it with the file contents.
+func (wfr *watchFileResolver) ResolveFileValue(value string) ([]byte, error) {
+	if len(value) > 0 {
+		wfr.runner.recordFileRead(wfr.FileResolver.ResolveAbsolutePath(value))
+	}
+	return wfr.FileResolver.ResolveFileValue(value)
+}
+
+// fileStamp tells whether a file changed, without reading it.
+type fileStamp struct {
+	exists  bool
+	size    int64
+	modTime time.Time
+}
+
+func stampFile(filePath string) fileStamp {
+	info, err := os.Stat(filePath)
+	if err != nil {
+		return fileStamp{}
+	}
+	return fileStamp{
+		exists:  true,
+		size:    info.Size(),
+		modTime: info.ModTime(),
+	}
+}
+
+// scenarioWatch keeps track of the files that the scenarios of a watched directory depend on.
+type scenarioWatch struct {
+	// dependencies holds the files each scenario read when it last ran, besides the scenario file itself
+	dependencies map[string][]string
+
+	// stamps holds the state of all the files watched, as it was before the scenarios depending on them last ran
+	stamps map[string]fileStamp
+}
+
+// affectedScenarios yields the scenarios to run: new ones, and those with a file changed since they last ran.
+// Scenarios that no longer exist are forgotten.
+func (w *scenarioWatch) affectedScenarios(scenarioPaths []string) []string {
+	listed := make(map[string]bool, len(scenarioPaths))
+	for _, scenarioPath := range scenarioPaths {
+		listed[scenarioPath] = true
+	}
+	for scenarioPath := range w.dependencies {
+		if !listed[scenarioPath] {
+			delete(w.dependencies, scenarioPath)
+		}
+	}
+
+	changed := make(map[string]bool)
+	isChanged := func(filePath string) bool {
+		isFileChanged, checked := changed[filePath]
+		if !checked {
+			stamp := stampFile(filePath)
+			previous, watched := w.stamps[filePath]
+			isFileChanged = !watched || stamp != previous
+			changed[filePath] = isFileChanged
+			w.stamps[filePath] = stamp
+		}
+		return isFileChanged
+	}
+	var affected []string
+	for _, scenarioPath := range scenarioPaths {
+		isAffected := isChanged(scenarioPath)
+		for _, dependency := range w.dependencies[scenarioPath] {
+			// all checked, so that the stamps are up to date
+			isAffected = isChanged(dependency) || isAffected
+		}
+		if isAffected {
+			affected = append(affected, scenarioPath)
+		}
+	}
+	return affected
+}
+
+// recordRun replaces the dependencies of the scenarios that ran, with the files they read.
+// Files read for the first time are watched from now on.
+func (w *scenarioWatch) recordRun(scenarioPaths []string, fileReads map[string]map[string]bool) {
+	for _, scenarioPath := range scenarioPaths {
+		var dependencies []string
+		for filePath := range fileReads[scenarioPath] {
+			if filePath == scenarioPath {
+				continue
+			}
+			dependencies = append(dependencies, filePath)
+			if _, watched := w.stamps[filePath]; !watched {
+				w.stamps[filePath] = stampFile(filePath)
+			}
+		}
+		w.dependencies[scenarioPath] = dependencies
+		delete(fileReads, scenarioPath)
+	}
+}
@@ -0,0 +1,78 @@
+package denalicontroller
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+// roundSink hands over the report of each run.
+type roundSink struct {
+	reports chan *RunReport
+}
+
+func (s *roundSink) ScenarioDone(_ *ScenarioReport) error {
+	return nil
+}
+
+func (s *roundSink) RunDone(report *RunReport) error {
+	s.reports <- report
+	return nil
+}
+
+func TestWatchDirectory(t *testing.T) {
+	dir := t.TempDir()
+	pathA := writeAuditTestScenario(t, dir, "a", "code a")
+	pathB := writeAuditTestScenario(t, dir, "b", "code b")
+	runner := NewScenarioRunner(&auditTestExecutor{}, NewDefaultFileResolver())
+	sink := &roundSink{reports: make(chan *RunReport)}
+	runner.ReportSinks = []ReportSink{sink}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	watchErr := make(chan error)
+	go func() {
+		watchErr <- runner.WatchDirectory(ctx, dir, WatchOptions{PollInterval: 10 * time.Millisecond})
+	}()
+	nextRound := func() []string {
+		var scenarioPaths []string
+		select {
+		case report := <-sink.reports:
+			for _, scenarioReport := range report.Scenarios {
+				scenarioPaths = append(scenarioPaths, scenarioReport.Path)
+			}
+		case <-time.After(5 * time.Second):
+			require.Fail(t, "no run")
+		}
+		return scenarioPaths
+	}
+
+	require.Equal(t, []string{pathA, pathB}, nextRound())
+
+	// files are moved in, so that they are never seen half written
+	stagingDir := t.TempDir()
+	moveIn := func(name string) {
+		require.Nil(t, os.Rename(filepath.Join(stagingDir, name), filepath.Join(dir, name)))
+	}
+
+	// the code loaded by "a" changed
+	require.Nil(t, os.WriteFile(filepath.Join(stagingDir, "a.code"), []byte("changed code a"), 0644))
+	moveIn("a.code")
+	require.Equal(t, []string{pathA}, nextRound())
+
+	writeAuditTestScenario(t, stagingDir, "c", "code c")
+	moveIn("c.code")
+	moveIn("c.scen.json")
+	require.Equal(t, []string{filepath.Join(dir, "c.scen.json")}, nextRound())
+
+	cancel()
+	require.Nil(t, <-watchErr)
+
+	err := runner.WatchDirectory(context.Background(), dir, WatchOptions{
+		DirectoryRunOptions: DirectoryRunOptions{NamePattern: "("},
+	})
+	require.NotNil(t, err)
+}
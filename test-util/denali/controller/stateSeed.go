@@ -0,0 +1,63 @@
+package denalicontroller
+
+import (
+	"fmt"
+
+	mj "github.com/numbatx/gn-vm-util/test-util/denali/json/model"
+)
+
+// StateSeedExecutor is a ScenarioExecutor that can start scenarios from a saved world state,
+// instead of an empty one, see ScenarioRunner.SeedStatePath.
+type StateSeedExecutor interface {
+	ScenarioExecutor
+
+	// SeedState sets up the world described by the setState steps of a state file, in order.
+	// It is called before the scenario runs, after Reset in directory runs.
+	SeedState(state []*mj.SetStateStep) error
+}
+
+// seedStateOfOutermost seeds the state of the scenario being run, if configured.
+// Included scenarios continue from the state of the including one, so they are not seeded.
+func (r *ScenarioRunner) seedStateOfOutermost() error {
+	if len(r.contextPaths) != 1 || len(r.SeedStatePath) == 0 {
+		return nil
+	}
+	return r.seedState()
+}
+
+// seedState feeds the state file to the executor, before running an outermost scenario.
+func (r *ScenarioRunner) seedState() (err error) {
+	statePath, err := r.absolutePath(r.SeedStatePath)
+	if err != nil {
+		return err
+	}
+	seedExecutor, canSeed := r.Executor.(StateSeedExecutor)
+	if !canSeed {
+		return fmt.Errorf("cannot seed state from %s: executor does not support it", statePath)
+	}
+	state, err := r.loadSeedState(statePath)
+	if err != nil {
+		return err
+	}
+
+	defer recoverExecutorPanic(&err)
+	return seedExecutor.SeedState(state)
+}
+
+// loadSeedState parses the state file, which can only hold setState steps.
+func (r *ScenarioRunner) loadSeedState(statePath string) ([]*mj.SetStateStep, error) {
+	stateScenario, err := r.parseScenarioFile(statePath, nil)
+	if err != nil {
+		return nil, fmt.Errorf("cannot seed state from %s: %w", statePath, err)
+	}
+	state := make([]*mj.SetStateStep, 0, len(stateScenario.Steps))
+	for stepIndex, step := range stateScenario.Steps {
+		setStateStep, isSetState := step.(*mj.SetStateStep)
+		if !isSetState {
+			return nil, fmt.Errorf("cannot seed state from %s: step %d is %s, only %s steps are allowed",
+				statePath, stepIndex, step.StepTypeName(), mj.StepNameSetState)
+		}
+		state = append(state, setStateStep)
+	}
+	return state, nil
+}
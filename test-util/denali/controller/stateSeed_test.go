@@ -0,0 +1,104 @@
+package denalicontroller
+
+import (
+	"io"
+	"os"
+	"path/filepath"
+	"testing"
+
+	fr "github.com/numbatx/gn-vm-util/test-util/denali/json/fileresolver"
+	mj "github.com/numbatx/gn-vm-util/test-util/denali/json/model"
+	"github.com/stretchr/testify/require"
+)
+
+type seedingExecutor struct {
+	events []string
+}
+
+func (e *seedingExecutor) Reset() {
+	e.events = append(e.events, "reset")
+}
+
+func (e *seedingExecutor) SeedState(state []*mj.SetStateStep) error {
+	for _, step := range state {
+		e.events = append(e.events, "seed "+step.Comment)
+	}
+	return nil
+}
+
+func (e *seedingExecutor) ExecuteScenario(scenario *mj.Scenario, _ fr.FileResolver) error {
+	e.events = append(e.events, "run "+scenario.Name)
+	return nil
+}
+
+const seedTestStateJSON = `{
+	"steps": [
+		{"step": "setState", "comment": "accounts", "accounts": {"address:owner": {"balance": "100"}}},
+		{"step": "setState", "comment": "blocks", "currentBlockInfo": {"blockNonce": "5"}}
+	]
+}`
+
+func TestSeedState(t *testing.T) {
+	dir := t.TempDir()
+	require.Nil(t, os.WriteFile(filepath.Join(dir, "snapshot.json"), []byte(seedTestStateJSON), 0644))
+	require.Nil(t, os.WriteFile(filepath.Join(dir, "a.scen.json"), []byte(`{"name": "a", "steps": []}`), 0644))
+	require.Nil(t, os.WriteFile(filepath.Join(dir, "b.scen.json"), []byte(`{"name": "b", "steps": []}`), 0644))
+
+	executor := &seedingExecutor{}
+	runner := NewScenarioRunner(executor, NewDefaultFileResolver())
+	runner.SeedStatePath = filepath.Join(dir, "snapshot.json")
+	runner.ReportSinks = []ReportSink{NewTAPReportSink(io.Discard)}
+
+	_, err := runner.RunAllJSONScenariosInDirectoryWithReport(dir, "", ".scen.json", nil)
+	require.Nil(t, err)
+	require.Equal(t, []string{
+		"reset", "seed accounts", "seed blocks", "run a",
+		"reset", "seed accounts", "seed blocks", "run b",
+	}, executor.events)
+}
+
+func TestSeedStateErrors(t *testing.T) {
+	dir := t.TempDir()
+	scenarioPath := filepath.Join(dir, "a.scen.json")
+	require.Nil(t, os.WriteFile(scenarioPath, []byte(`{"name": "a", "steps": []}`), 0644))
+	require.Nil(t, os.WriteFile(filepath.Join(dir, "tx.json"), []byte(`{"steps": [
+		{"step": "transfer", "tx": {"from": "address:a", "to": "address:b", "value": "1"}}
+	]}`), 0644))
+
+	runner := NewScenarioRunner(&seedingExecutor{}, NewDefaultFileResolver())
+	runner.SeedStatePath = filepath.Join(dir, "tx.json")
+	err := runner.RunSingleJSONScenario(scenarioPath)
+	require.NotNil(t, err)
+	require.Contains(t, err.Error(), "step 0 is transfer, only setState steps are allowed")
+
+	runner.SeedStatePath = filepath.Join(dir, "missing.json")
+	err = runner.RunSingleJSONScenario(scenarioPath)
+	require.NotNil(t, err)
+	require.Contains(t, err.Error(), "cannot seed state from")
+
+	runner = NewScenarioRunner(&panickingExecutor{}, NewDefaultFileResolver())
+	runner.SeedStatePath = filepath.Join(dir, "tx.json")
+	err = runner.RunSingleJSONScenario(scenarioPath)
+	require.NotNil(t, err)
+	require.Contains(t, err.Error(), "executor does not support it")
+}
+
+func TestSeedStateOnlyOfSelectedScenarios(t *testing.T) {
+	dir := t.TempDir()
+	require.Nil(t, os.WriteFile(filepath.Join(dir, "snapshot.json"), []byte(seedTestStateJSON), 0644))
+	require.Nil(t, os.WriteFile(filepath.Join(dir, "a.scen.json"), []byte(`{"name": "a", "steps": []}`), 0644))
+	require.Nil(t, os.WriteFile(filepath.Join(dir, "b.scen.json"), []byte(`{"name": "b", "steps": []}`), 0644))
+
+	executor := &seedingExecutor{}
+	runner := NewScenarioRunner(executor, NewDefaultFileResolver())
+	runner.SeedStatePath = filepath.Join(dir, "snapshot.json")
+	runner.ReportSinks = []ReportSink{NewTAPReportSink(io.Discard)}
+
+	report, err := runner.RunAllJSONScenariosInDirectoryWithOptions(dir, DirectoryRunOptions{NamePattern: "^b$"})
+	require.Nil(t, err)
+	require.Equal(t, 1, report.Count(ScenarioSkipped))
+	require.Equal(t, []string{
+		"reset",
+		"reset", "seed accounts", "seed blocks", "run b",
+	}, executor.events)
+}
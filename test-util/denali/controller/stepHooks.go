@@ -0,0 +1,109 @@
+package denalicontroller
+
+import (
+	"errors"
+	"time"
+
+	mj "github.com/numbatx/gn-vm-util/test-util/denali/json/model"
+)
+
+// StepHook observes the steps of the scenarios a runner executes, e.g. to log, trace or time them,
+// and can add assertions of its own. See ScenarioRunner.StepHooks.
+// Embed BaseStepHook to only implement some of the methods.
+type StepHook interface {
+	// BeforeStep is called just before a step runs.
+	BeforeStep(event *StepEvent)
+
+	// AfterStep is called once a step has run, whether it passed or not.
+	// Returning an error fails a step that passed, which is how custom assertions are made.
+	AfterStep(event *StepEvent) error
+
+	// OnCheckFailure is called when the expectations of a step do not hold, before AfterStep:
+	// for a mismatch of transaction results, see CheckError, or a violated invariant, see InvariantError.
+	OnCheckFailure(event *StepEvent)
+}
+
+// StepEvent describes a step to the hooks.
+type StepEvent struct {
+	// ScenarioPath is the absolute path of the scenario, which can be one included through externalSteps.
+	ScenarioPath string
+
+	StepIndex int
+	Step      mj.Step
+
+	// Result is what a transaction step returned, if the executor reported it, see ExecutionContext.RecordStepOutput.
+	// It is nil before the step runs.
+	Result *QueryResult
+
+	// Duration is how long the step took, zero before it runs.
+	Duration time.Duration
+
+	// Err is what the step failed with, nil if it passed, or before it runs.
+	Err error
+}
+
+// BaseStepHook is a StepHook that does nothing.
+type BaseStepHook struct{}
+
+// BeforeStep does nothing.
+func (BaseStepHook) BeforeStep(_ *StepEvent) {}
+
+// AfterStep does nothing.
+func (BaseStepHook) AfterStep(_ *StepEvent) error {
+	return nil
+}
+
+// OnCheckFailure does nothing.
+func (BaseStepHook) OnCheckFailure(_ *StepEvent) {}
+
+// StepDone is meant for executors, to call once each step has run, with what it failed with, if anything.
+// It calls the hooks of the runner, and yields the error to fail the step with: the one given,
+// or the first error returned by the hooks, if the step passed.
+func (ctx *ExecutionContext) StepDone(stepIndex int, step mj.Step, err error) error {
+	if len(ctx.hooks) == 0 {
+		return err
+	}
+	ctx.mutex.Lock()
+	event := &StepEvent{
+		ScenarioPath: ctx.ScenarioPath,
+		StepIndex:    stepIndex,
+		Step:         step,
+		Result:       ctx.stepResult,
+		Duration:     time.Since(ctx.stepStartedAt),
+		Err:          err,
+	}
+	ctx.mutex.Unlock()
+
+	if isCheckFailure(err) {
+		for _, hook := range ctx.hooks {
+			hook.OnCheckFailure(event)
+		}
+	}
+	for _, hook := range ctx.hooks {
+		if hookErr := hook.AfterStep(event); hookErr != nil && event.Err == nil {
+			event.Err = hookErr
+		}
+	}
+	return event.Err
+}
+
+// beforeStep calls the hooks of the runner, as a step is dispatched.
+func (ctx *ExecutionContext) beforeStep(stepIndex int, step mj.Step) {
+	if len(ctx.hooks) == 0 {
+		return
+	}
+	event := &StepEvent{
+		ScenarioPath: ctx.ScenarioPath,
+		StepIndex:    stepIndex,
+		Step:         step,
+	}
+	for _, hook := range ctx.hooks {
+		hook.BeforeStep(event)
+	}
+}
+
+func isCheckFailure(err error) bool {
+	var checkErr *CheckError
+	var invariantErr *InvariantError
+	return errors.As(err, &checkErr) || errors.As(err, &invariantErr)
+}
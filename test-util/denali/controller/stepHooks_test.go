@@ -0,0 +1,71 @@
+package denalicontroller
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+// recordingStepHook records the hooks called, and fails the steps that return the out value to reject.
+type recordingStepHook struct {
+	BaseStepHook
+	calls  []string
+	reject string
+}
+
+func (h *recordingStepHook) BeforeStep(event *StepEvent) {
+	h.calls = append(h.calls, fmt.Sprintf("before %d %s", event.StepIndex, event.Step.StepTypeName()))
+}
+
+func (h *recordingStepHook) AfterStep(event *StepEvent) error {
+	h.calls = append(h.calls, fmt.Sprintf("after %d %v", event.StepIndex, event.Err != nil))
+	if event.Result != nil && len(event.Result.Out) > 0 && string(event.Result.Out[0]) == h.reject {
+		return errors.New("rejected")
+	}
+	return nil
+}
+
+func (h *recordingStepHook) OnCheckFailure(event *StepEvent) {
+	h.calls = append(h.calls, fmt.Sprintf("check failure %d", event.StepIndex))
+}
+
+func runHookedViewScenario(t *testing.T, hook StepHook, out string) error {
+	scenarioJSON := strings.ReplaceAll(strings.ReplaceAll(viewScenarioTemplate, "FUNCTION", "getSum"), "OUT", out)
+	scenarioPath := filepath.Join(t.TempDir(), "view.scen.json")
+	require.Nil(t, os.WriteFile(scenarioPath, []byte(scenarioJSON), 0644))
+
+	executor := &storageQueryExecutor{}
+	executor.Reset()
+	runner := NewViewScenarioRunner(executor, NewDefaultFileResolver())
+	runner.StepHooks = []StepHook{hook}
+	return runner.RunSingleJSONScenario(scenarioPath)
+}
+
+func TestStepHooks(t *testing.T) {
+	hook := &recordingStepHook{}
+	require.Nil(t, runHookedViewScenario(t, hook, "5"))
+	require.Equal(t, []string{
+		"before 0 setState", "after 0 false",
+		"before 1 scQuery", "after 1 false",
+	}, hook.calls)
+
+	hook = &recordingStepHook{}
+	err := runHookedViewScenario(t, hook, "6")
+	require.NotNil(t, err)
+	require.Equal(t, []string{
+		"before 0 setState", "after 0 false",
+		"before 1 scQuery", "check failure 1", "after 1 true",
+	}, hook.calls)
+}
+
+func TestStepHookAssertion(t *testing.T) {
+	hook := &recordingStepHook{reject: "\x05"}
+	err := runHookedViewScenario(t, hook, "*")
+	require.NotNil(t, err)
+	require.Equal(t, "step 1: rejected", err.Error())
+}
@@ -0,0 +1,123 @@
+package denalicontroller
+
+import (
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// TestFileSuffix is the suffix of files in the older test format.
+const TestFileSuffix = ".test.json"
+
+// TestConversion is the outcome of converting a file in the older test format.
+type TestConversion struct {
+	TestPath string
+
+	// ScenarioPaths are the files written, one per test.
+	ScenarioPaths []string
+
+	// Error is set if the file could not be converted. Only the ScenarioPaths listed were written.
+	Error error
+}
+
+// ConvertAllJSONTestsInDirectory converts every test file in a directory, recursively, to scenarios,
+// written next to it: "x.test.json" becomes "x.scen.json", or "x.<test name>.scen.json" for files with several tests.
+// Tests whose names are empty, or end up the same once made safe for file names, are numbered instead, e.g. "x.2.scen.json",
+// and scenario paths that another file of the batch already got are suffixed with "-2", "-3", and so on.
+// Existing scenario files are only replaced if overwrite is set. Files that cannot be converted do not stop the batch,
+// they are reported in their TestConversion.
+func (r *TestRunner) ConvertAllJSONTestsInDirectory(dir string, overwrite bool) ([]*TestConversion, error) {
+	var testPaths []string
+	err := filepath.Walk(dir, func(testFilePath string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+		if !info.IsDir() && strings.HasSuffix(testFilePath, TestFileSuffix) {
+			testPaths = append(testPaths, testFilePath)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	var conversions []*TestConversion
+	failed := false
+	batchPaths := make(map[string]bool)
+	for _, testPath := range testPaths {
+		conversion := &TestConversion{TestPath: testPath}
+		conversion.ScenarioPaths, conversion.Error = r.convertJSONTest(testPath, overwrite, batchPaths)
+		if conversion.Error != nil {
+			failed = true
+		}
+		conversions = append(conversions, conversion)
+	}
+	if failed {
+		return conversions, errors.New("some tests could not be converted")
+	}
+	return conversions, nil
+}
+
+// convertJSONTest converts a test file, avoiding the scenario paths already taken by the batch, and adding its own.
+func (r *TestRunner) convertJSONTest(testPath string, overwrite bool, batchPaths map[string]bool) ([]string, error) {
+	content, err := ioutil.ReadFile(testPath)
+	if err != nil {
+		return nil, err
+	}
+	absolutePath, err := filepath.Abs(testPath)
+	if err != nil {
+		return nil, err
+	}
+	r.Parser.ValueInterpreter.FileResolver.SetContext(absolutePath)
+	scenarios, err := r.Parser.ConvertTestToScenario(content)
+	if err != nil {
+		return nil, err
+	}
+
+	basePath := strings.TrimSuffix(testPath, TestFileSuffix)
+	scenarioPaths := make([]string, len(scenarios))
+	names := make(map[string]bool)
+	paths := make(map[string]bool)
+	for i, scenario := range scenarios {
+		scenarioPath := basePath
+		if len(scenarios) > 1 {
+			name := fileNameSafe(scenario.Name)
+			if len(name) == 0 || names[name] {
+				name = fmt.Sprintf("%d", i+1)
+			}
+			names[name] = true
+			scenarioPath = basePath + "." + name
+		}
+		scenarioPaths[i] = scenarioPath + ScenarioFileSuffix
+		for n := 2; batchPaths[scenarioPaths[i]] || paths[scenarioPaths[i]]; n++ {
+			scenarioPaths[i] = fmt.Sprintf("%s-%d%s", scenarioPath, n, ScenarioFileSuffix)
+		}
+		paths[scenarioPaths[i]] = true
+		if _, statErr := os.Stat(scenarioPaths[i]); statErr == nil && !overwrite {
+			return nil, fmt.Errorf("%s already exists", scenarioPaths[i])
+		}
+	}
+	for _, scenarioPath := range scenarioPaths {
+		batchPaths[scenarioPath] = true
+	}
+	for i, scenario := range scenarios {
+		err = SaveScenario(scenarioPaths[i], scenario, SaveScenarioOptions{})
+		if err != nil {
+			return scenarioPaths[:i], err
+		}
+	}
+	return scenarioPaths, nil
+}
+
+// fileNameSafe replaces the characters of a test name that are not safe in file names.
+func fileNameSafe(name string) string {
+	return strings.Map(func(c rune) rune {
+		if (c >= 'a' && c <= 'z') || (c >= 'A' && c <= 'Z') || (c >= '0' && c <= '9') || c == '-' || c == '_' {
+			return c
+		}
+		return '_'
+	}, name)
+}
@@ -0,0 +1,67 @@
+package denalicontroller
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+const minimalLegacyTest = `{"pre": {"address:a": {"nonce": "0", "balance": "5", "storage": {}, "code": ""}}, "blocks": [], "postState": {}}`
+
+func TestConvertAllJSONTestsInDirectory(t *testing.T) {
+	dir := t.TempDir()
+	require.Nil(t, os.MkdirAll(filepath.Join(dir, "sub"), os.ModePerm))
+	require.Nil(t, os.WriteFile(filepath.Join(dir, "single.test.json"),
+		[]byte(`{"single": `+minimalLegacyTest+`}`), 0644))
+	require.Nil(t, os.WriteFile(filepath.Join(dir, "sub", "several.test.json"),
+		[]byte(`{"first one": `+minimalLegacyTest+`, "second": `+minimalLegacyTest+`}`), 0644))
+	require.Nil(t, os.WriteFile(filepath.Join(dir, "broken.test.json"), []byte(`{"broken": {"unknown": 1}}`), 0644))
+
+	runner := NewTestRunner(nil, NewDefaultFileResolver())
+	conversions, err := runner.ConvertAllJSONTestsInDirectory(dir, false)
+	require.NotNil(t, err)
+	require.Equal(t, 3, len(conversions))
+	require.NotNil(t, conversions[0].Error)
+	require.Nil(t, conversions[1].Error)
+	require.Equal(t, []string{filepath.Join(dir, "single.scen.json")}, conversions[1].ScenarioPaths)
+	require.Nil(t, conversions[2].Error)
+	require.Equal(t, []string{
+		filepath.Join(dir, "sub", "several.first_one.scen.json"),
+		filepath.Join(dir, "sub", "several.second.scen.json"),
+	}, conversions[2].ScenarioPaths)
+
+	scenarioRunner := NewScenarioRunner(nil, NewDefaultFileResolver())
+	scenario, err := scenarioRunner.parseScenarioFile(filepath.Join(dir, "single.scen.json"), nil)
+	require.Nil(t, err)
+	require.Equal(t, "single", scenario.Name)
+
+	// existing scenarios are kept, unless asked otherwise
+	require.Nil(t, os.Remove(filepath.Join(dir, "broken.test.json")))
+	conversions, err = runner.ConvertAllJSONTestsInDirectory(dir, false)
+	require.NotNil(t, err)
+	require.Contains(t, conversions[0].Error.Error(), "already exists")
+	_, err = runner.ConvertAllJSONTestsInDirectory(dir, true)
+	require.Nil(t, err)
+}
+
+func TestConvertJSONTestsWithCollidingNames(t *testing.T) {
+	dir := t.TempDir()
+	require.Nil(t, os.WriteFile(filepath.Join(dir, "x.test.json"),
+		[]byte(`{"a b": `+minimalLegacyTest+`, "a_b": `+minimalLegacyTest+`, "": `+minimalLegacyTest+`}`), 0644))
+	// converted first, gets the path that the first test above would get
+	require.Nil(t, os.WriteFile(filepath.Join(dir, "x.a_b.test.json"),
+		[]byte(`{"single": `+minimalLegacyTest+`}`), 0644))
+
+	runner := NewTestRunner(nil, NewDefaultFileResolver())
+	conversions, err := runner.ConvertAllJSONTestsInDirectory(dir, false)
+	require.Nil(t, err)
+	require.Equal(t, 2, len(conversions))
+	require.Equal(t, []string{filepath.Join(dir, "x.a_b.scen.json")}, conversions[0].ScenarioPaths)
+	require.Equal(t, []string{
+		filepath.Join(dir, "x.a_b-2.scen.json"),
+		filepath.Join(dir, "x.2.scen.json"),
+		filepath.Join(dir, "x.3.scen.json"),
+	}, conversions[1].ScenarioPaths)
+}
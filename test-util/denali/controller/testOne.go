@@ -4,7 +4,6 @@ import (
 	"io/ioutil"
 	"os"
 	"path/filepath"
-	"strings"
 
 	mj "github.com/numbatx/gn-vm-util/test-util/denali/json/model"
 	mjwrite "github.com/numbatx/gn-vm-util/test-util/denali/json/write"
@@ -50,40 +49,14 @@ func (r *TestRunner) RunSingleJSONTest(contextPath string) error {
 	return nil
 }
 
-// tool to convert .test.json -> .scen.json
-// use with extreme caution
-func convertTestToScenario(contextPath string, top []*mj.Test) {
-	if strings.HasSuffix(contextPath, ".test.json") {
-		scenario, err := mj.ConvertTestToScenario(top)
-		if err != nil {
-			panic(err)
-		}
-		scenarioSerialized := mjwrite.ScenarioToJSONString(scenario)
-
-		newPath := contextPath[:len(contextPath)-len(".test.json")] + ".scen.json"
-		err = os.MkdirAll(filepath.Dir(newPath), os.ModePerm)
-		if err != nil {
-			panic(err)
-		}
-
-		err = ioutil.WriteFile(newPath, []byte(scenarioSerialized), 0644)
-		if err != nil {
-			panic(err)
-		}
-	}
-}
-
 // tool to modify tests
 // use with extreme caution
-func saveModifiedTest(toPath string, top []*mj.Test) {
+func saveModifiedTest(toPath string, top []*mj.Test) error {
 	resultJSON := mjwrite.TestToJSONString(top)
 
 	err := os.MkdirAll(filepath.Dir(toPath), os.ModePerm)
 	if err != nil {
-		panic(err)
-	}
-	err = ioutil.WriteFile(toPath, []byte(resultJSON), 0644)
-	if err != nil {
-		panic(err)
+		return err
 	}
+	return writeFileAtomically(toPath, []byte(resultJSON))
 }
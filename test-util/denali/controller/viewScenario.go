@@ -0,0 +1,166 @@
+package denalicontroller
+
+import (
+	"errors"
+	"fmt"
+	"math/big"
+
+	fr "github.com/numbatx/gn-vm-util/test-util/denali/json/fileresolver"
+	mj "github.com/numbatx/gn-vm-util/test-util/denali/json/model"
+)
+
+// QueryExecutor is a read-only executor, only able to run view scenarios.
+// It needs no transaction pipeline: it sets up state and answers queries.
+// Executors that also implement WorldStateReader can run scenarios with invariants.
+type QueryExecutor interface {
+	// Reset clears state/world.
+	Reset()
+
+	// SetState saves the accounts and block info of a setState step.
+	// Failures of single operations should be returned as *SetStateError, so that steps can allow them.
+	SetState(step *mj.SetStateStep) error
+
+	// Query calls a view function and yields its results.
+	// Errors are reserved for queries that could not be run at all.
+	Query(tx *mj.Transaction) (*QueryResult, error)
+}
+
+// QueryResult is what a view function returned.
+// Executors also use it to report the results of other transactions, see ExecutionContext.RecordStepOutput.
+type QueryResult struct {
+	Out     [][]byte
+	Status  *big.Int
+	Message []byte
+
+	// Gas, Refund and Logs are optional, only transactions have them. Executors that report them
+	// get them accepted too, see ScenarioRunner.AcceptActualValues. Logs are only reported if not nil.
+	Gas    *uint64
+	Refund *big.Int
+	Logs   []*QueryLog
+}
+
+// QueryLog is a log emitted by a transaction.
+type QueryLog struct {
+	Address    []byte
+	Identifier []byte
+	Topics     [][]byte
+	Data       []byte
+}
+
+// NewViewScenarioRunner creates a ScenarioRunner for view scenarios, backed by a read-only executor.
+// The runner checks the query results itself. Regular scenarios fail.
+func NewViewScenarioRunner(executor QueryExecutor, fileResolver fr.FileResolver) *ScenarioRunner {
+	return NewScenarioRunner(&viewScenarioExecutor{queryExecutor: executor}, fileResolver)
+}
+
+var _ ScenarioContextExecutor = (*viewScenarioExecutor)(nil)
+
+// viewScenarioExecutor adapts a QueryExecutor to the regular executor interface.
+type viewScenarioExecutor struct {
+	queryExecutor QueryExecutor
+}
+
+// Reset clears state/world.
+func (e *viewScenarioExecutor) Reset() {
+	e.queryExecutor.Reset()
+}
+
+// ExecuteScenario runs a view scenario, with default formatting for error messages.
+func (e *viewScenarioExecutor) ExecuteScenario(scenario *mj.Scenario, fileResolver fr.FileResolver) error {
+	return e.ExecuteScenarioWithContext(scenario, NewExecutionContext(scenario, fileResolver))
+}
+
+// ExecuteScenarioWithContext runs a view scenario, step by step.
+func (e *viewScenarioExecutor) ExecuteScenarioWithContext(scenario *mj.Scenario, ctx *ExecutionContext) error {
+	if !scenario.IsView() {
+		return errors.New("only view scenarios can be run by a read-only executor")
+	}
+	// executors that cannot read the world state can only run scenarios without invariants
+	worldState, _ := e.queryExecutor.(WorldStateReader)
+	for i, generalStep := range scenario.Steps {
+		if err := ctx.Context.Err(); err != nil {
+			return err
+		}
+		ctx.StepDispatched(i, generalStep)
+		var err error
+		switch step := generalStep.(type) {
+		case *mj.SetStateStep:
+			err = ctx.HandleSetStateError(i, step, e.queryExecutor.SetState(step))
+		case *mj.TxStep:
+			err = e.executeQueryStep(i, step, ctx)
+		default:
+			err = fmt.Errorf("step type %s not allowed in view scenarios", generalStep.StepTypeName())
+		}
+		if err == nil {
+			err = ctx.CheckInvariants(i, worldState)
+		}
+		err = ctx.StepDone(i, generalStep, err)
+		if err != nil {
+			return fmt.Errorf("%s: %w", mj.DescribeStep(i, generalStep), err)
+		}
+	}
+	return nil
+}
+
+func (e *viewScenarioExecutor) executeQueryStep(stepIndex int, step *mj.TxStep, ctx *ExecutionContext) error {
+	result, err := e.queryExecutor.Query(step.Tx)
+	if err != nil {
+		return err
+	}
+	ctx.RecordStepOutput(stepIndex, step, result)
+	if step.ExpectedResult == nil {
+		return nil
+	}
+	return checkQueryResult(step, result, ctx)
+}
+
+// checkQueryResult compares all values, so that a failure can show every one of them, see CheckError.
+func checkQueryResult(step *mj.TxStep, result *QueryResult, ctx *ExecutionContext) error {
+	expected := step.ExpectedResult
+	checkErr := &CheckError{TxIdent: step.TxIdent}
+	status := result.Status
+	if status == nil {
+		status = big.NewInt(0)
+	}
+	statusMatches := expected.Status.Check(status)
+	checkErr.addRow("status", expected.Status.Original, status.String(), !statusMatches)
+	if !statusMatches {
+		checkErr.setSummary(fmt.Sprintf("query %s: wrong status, expected %s, actual %s, message: %s",
+			step.TxIdent, expected.Status.Original, status, result.Message))
+	}
+
+	messageMatches := expected.Message.Check(result.Message)
+	if !expected.Message.IsDefault() || !messageMatches {
+		checkErr.addRow("message", ctx.expectedText(expected.Message), ctx.Pretty(result.Message), !messageMatches)
+	}
+	if !messageMatches {
+		checkErr.setSummary(fmt.Sprintf("query %s: wrong message, expected %s, actual %s",
+			step.TxIdent, ctx.Pretty(expected.Message.Value), ctx.Pretty(result.Message)))
+	}
+
+	if len(expected.Out) != len(result.Out) {
+		checkErr.setSummary(fmt.Sprintf("query %s: expected %d out values, actual %d",
+			step.TxIdent, len(expected.Out), len(result.Out)))
+	}
+	for i := 0; i < len(expected.Out) || i < len(result.Out); i++ {
+		field := fmt.Sprintf("out[%d]", i)
+		switch {
+		case i >= len(expected.Out):
+			checkErr.addRow(field, "", ctx.Pretty(result.Out[i]), true)
+		case i >= len(result.Out):
+			checkErr.addRow(field, ctx.expectedText(expected.Out[i]), "", true)
+		default:
+			outMatches := expected.Out[i].Check(result.Out[i])
+			checkErr.addRow(field, ctx.expectedText(expected.Out[i]), ctx.Pretty(result.Out[i]), !outMatches)
+			if !outMatches {
+				checkErr.setSummary(fmt.Sprintf("query %s: out[%d] mismatch, %s",
+					step.TxIdent, i, ctx.FormatOutMismatch(expected.Out[i], result.Out[i])))
+			}
+		}
+	}
+
+	if len(checkErr.Summary) == 0 {
+		return nil
+	}
+	return checkErr
+}
@@ -0,0 +1,152 @@
+package denalicontroller
+
+import (
+	"errors"
+	"math/big"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	mj "github.com/numbatx/gn-vm-util/test-util/denali/json/model"
+	"github.com/stretchr/testify/require"
+)
+
+// storageQueryExecutor answers queries with the storage value named like the function.
+type storageQueryExecutor struct {
+	storage map[string][]byte
+}
+
+func (e *storageQueryExecutor) Reset() {
+	e.storage = make(map[string][]byte)
+}
+
+func (e *storageQueryExecutor) SetState(step *mj.SetStateStep) error {
+	for _, account := range step.Accounts {
+		for _, kvp := range account.Storage {
+			e.storage[string(kvp.Key.Value)] = kvp.Value.Value
+		}
+	}
+	if len(step.NewAddressMocks) > 0 {
+		return &SetStateError{
+			Operation: mj.SetStateOperationNewAddress,
+			Err:       errors.New("new address mocks not supported"),
+		}
+	}
+	return nil
+}
+
+func (e *storageQueryExecutor) Query(tx *mj.Transaction) (*QueryResult, error) {
+	value, found := e.storage[tx.Function]
+	if !found {
+		return &QueryResult{Status: big.NewInt(4), Message: []byte("unknown function")}, nil
+	}
+	return &QueryResult{Out: [][]byte{value}}, nil
+}
+
+const viewScenarioTemplate = `{
+	"metadata": {"flavor": "view"},
+	"steps": [
+		{"step": "setState", "accounts": {"address:contract": {
+			"nonce": "0", "balance": "0", "storage": {"str:getSum": "5"}, "code": ""}}},
+		{"step": "scQuery", "txId": "q", "tx": {"to": "address:contract", "function": "FUNCTION", "arguments": []},
+			"expect": {"out": ["OUT"], "status": "0"}}
+	]
+}`
+
+func runViewScenario(t *testing.T, function string, out string) error {
+	scenarioJSON := viewScenarioTemplate
+	for old, new := range map[string]string{"FUNCTION": function, "OUT": out} {
+		scenarioJSON = strings.ReplaceAll(scenarioJSON, old, new)
+	}
+	scenarioPath := filepath.Join(t.TempDir(), "view.scen.json")
+	require.Nil(t, os.WriteFile(scenarioPath, []byte(scenarioJSON), 0644))
+
+	executor := &storageQueryExecutor{}
+	executor.Reset()
+	runner := NewViewScenarioRunner(executor, NewDefaultFileResolver())
+	return runner.RunSingleJSONScenario(scenarioPath)
+}
+
+func TestViewScenario(t *testing.T) {
+	require.Nil(t, runViewScenario(t, "getSum", "5"))
+
+	err := runViewScenario(t, "getSum", "6")
+	require.NotNil(t, err)
+	require.Contains(t, err.Error(), "out[0] mismatch")
+
+	require.Nil(t, runViewScenario(t, "getSum", "match:range:1..10"))
+	err = runViewScenario(t, "getSum", "match:range:6..")
+	require.NotNil(t, err)
+	require.Contains(t, err.Error(), "expected match:range:6..")
+
+	err = runViewScenario(t, "getOther", "5")
+	require.NotNil(t, err)
+	require.Contains(t, err.Error(), "wrong status")
+}
+
+func TestViewRunnerRejectsRegularScenarios(t *testing.T) {
+	scenarioPath := filepath.Join(t.TempDir(), "regular.scen.json")
+	require.Nil(t, os.WriteFile(scenarioPath, []byte(`{"steps": []}`), 0644))
+
+	executor := &storageQueryExecutor{}
+	runner := NewViewScenarioRunner(executor, NewDefaultFileResolver())
+	require.NotNil(t, runner.RunSingleJSONScenario(scenarioPath))
+}
+
+func TestViewScenarioOutputsInReport(t *testing.T) {
+	dir := t.TempDir()
+	scenarioJSON := strings.ReplaceAll(strings.ReplaceAll(viewScenarioTemplate, "FUNCTION", "getSum"), "OUT", "*")
+	require.Nil(t, os.WriteFile(filepath.Join(dir, "view.scen.json"), []byte(scenarioJSON), 0644))
+
+	executor := &storageQueryExecutor{}
+	runner := NewViewScenarioRunner(executor, NewDefaultFileResolver())
+	report, err := runner.RunAllJSONScenariosInDirectoryWithReport(dir, "", ".scen.json", nil)
+	require.Nil(t, err)
+	require.Equal(t, 1, len(report.Scenarios))
+	require.True(t, report.Scenarios[0].Passed())
+	require.Equal(t, []*StepOutput{{
+		StepIndex: 1,
+		TxIdent:   "q",
+		Out:       []string{"0x05"},
+		Status:    "0",
+	}}, report.Scenarios[0].Outputs)
+}
+
+func TestLintWarningsInReport(t *testing.T) {
+	dir := t.TempDir()
+	scenarioJSON := strings.ReplaceAll(strings.ReplaceAll(viewScenarioTemplate, "FUNCTION", "getSum"), "OUT", "5|")
+	require.Nil(t, os.WriteFile(filepath.Join(dir, "view.scen.json"), []byte(scenarioJSON), 0644))
+
+	executor := &storageQueryExecutor{}
+	runner := NewViewScenarioRunner(executor, NewDefaultFileResolver())
+	runner.Parser.ValueInterpreter.Lint = true
+	report, err := runner.RunAllJSONScenariosInDirectoryWithReport(dir, "", ".scen.json", nil)
+	require.Nil(t, err)
+	require.Equal(t, 1, len(report.Scenarios[0].Warnings))
+	require.Contains(t, report.Scenarios[0].Warnings[0], "empty part in concatenation")
+}
+
+func TestSetStateAllowedErrors(t *testing.T) {
+	const setStateField = `{"step": "setState",`
+	newAddresses := setStateField + ` "newAddresses": [{"creatorAddress": "address:owner",
+			"creatorNonce": "0", "newAddress": "address:contract"}],`
+	scenarioJSON := strings.ReplaceAll(strings.ReplaceAll(viewScenarioTemplate, "FUNCTION", "getSum"), "OUT", "5")
+
+	dir := t.TempDir()
+	require.Nil(t, os.WriteFile(filepath.Join(dir, "view.scen.json"),
+		[]byte(strings.Replace(scenarioJSON, setStateField, newAddresses, 1)), 0644))
+	executor := &storageQueryExecutor{}
+	runner := NewViewScenarioRunner(executor, NewDefaultFileResolver())
+	report, err := runner.RunAllJSONScenariosInDirectoryWithReport(dir, "", ".scen.json", nil)
+	require.NotNil(t, err)
+	require.Contains(t, report.Scenarios[0].Error, "setState newAddress failed")
+
+	allowed := newAddresses + ` "allowedErrors": ["newAddress"],`
+	require.Nil(t, os.WriteFile(filepath.Join(dir, "view.scen.json"),
+		[]byte(strings.Replace(scenarioJSON, setStateField, allowed, 1)), 0644))
+	report, err = runner.RunAllJSONScenariosInDirectoryWithReport(dir, "", ".scen.json", nil)
+	require.Nil(t, err)
+	require.Equal(t, []string{"step 0: allowed error: setState newAddress failed: new address mocks not supported"},
+		report.Scenarios[0].Warnings)
+}
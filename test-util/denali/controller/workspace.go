@@ -0,0 +1,241 @@
+package denalicontroller
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+	"time"
+
+	fr "github.com/numbatx/gn-vm-util/test-util/denali/json/fileresolver"
+)
+
+// WorkspaceRootPrefix starts the paths that scenarios use to reference files of a workspace root,
+// e.g. "file:@fixtures/token.wasm", or "@fixtures/deploy.steps.json" in externalSteps.
+const WorkspaceRootPrefix = "@"
+
+// Workspace links several scenario roots, e.g. the scenarios of a contract repository and
+// a repository of shared fixtures, so that they can be resolved, inventoried and run together.
+// Across the workspace, files are named by their root name, followed by their path relative to the root,
+// e.g. "fixtures/token/deploy.steps.json".
+type Workspace struct {
+	Roots []*WorkspaceRoot `json:"roots"`
+}
+
+// WorkspaceRoot is a directory holding scenarios, named so that its files get the same name on every machine.
+type WorkspaceRoot struct {
+	Name string `json:"name"`
+
+	// Path is relative to the workspace config file in the config, absolute once loaded.
+	Path string `json:"path"`
+}
+
+// LoadWorkspace reads a workspace config, a JSON document listing the roots, e.g.
+// {"roots": [{"name": "contract", "path": "scenarios"}, {"name": "fixtures", "path": "../fixtures"}]}.
+func LoadWorkspace(configPath string) (*Workspace, error) {
+	configJSON, err := ioutil.ReadFile(configPath)
+	if err != nil {
+		return nil, err
+	}
+	workspace := &Workspace{}
+	if err := json.Unmarshal(configJSON, workspace); err != nil {
+		return nil, fmt.Errorf("invalid workspace config %s: %w", configPath, err)
+	}
+	configDir, err := filepath.Abs(filepath.Dir(configPath))
+	if err != nil {
+		return nil, err
+	}
+	for _, root := range workspace.Roots {
+		if !filepath.IsAbs(root.Path) {
+			root.Path = filepath.Join(configDir, root.Path)
+		}
+	}
+	if err := workspace.validate(); err != nil {
+		return nil, fmt.Errorf("invalid workspace config %s: %w", configPath, err)
+	}
+	return workspace, nil
+}
+
+func (w *Workspace) validate() error {
+	if len(w.Roots) == 0 {
+		return errors.New("no roots")
+	}
+	names := make(map[string]bool, len(w.Roots))
+	for _, root := range w.Roots {
+		if len(root.Name) == 0 || strings.ContainsAny(root.Name, "/\\") {
+			return fmt.Errorf("invalid root name \"%s\"", root.Name)
+		}
+		if names[root.Name] {
+			return fmt.Errorf("duplicate root name \"%s\"", root.Name)
+		}
+		names[root.Name] = true
+		if len(root.Path) == 0 {
+			return fmt.Errorf("root %s has no path", root.Name)
+		}
+	}
+	return nil
+}
+
+// WorkspacePath names a file across the workspace, see Workspace.
+// Files of nested roots belong to the innermost one. Files outside all roots keep their path.
+func (w *Workspace) WorkspacePath(path string) string {
+	bestRoot := ""
+	bestRelativePath := ""
+	for _, root := range w.Roots {
+		relativePath, err := filepath.Rel(root.Path, path)
+		if err != nil || relativePath == ".." || strings.HasPrefix(relativePath, ".."+string(filepath.Separator)) {
+			continue
+		}
+		if len(bestRoot) == 0 || len(relativePath) < len(bestRelativePath) {
+			bestRoot = root.Name
+			bestRelativePath = relativePath
+		}
+	}
+	if len(bestRoot) == 0 {
+		return filepath.ToSlash(path)
+	}
+	return bestRoot + "/" + filepath.ToSlash(bestRelativePath)
+}
+
+// AbsolutePath converts a name given by WorkspacePath back to a path.
+func (w *Workspace) AbsolutePath(workspacePath string) (string, error) {
+	rootName := workspacePath
+	relativePath := ""
+	if separator := strings.Index(workspacePath, "/"); separator >= 0 {
+		rootName = workspacePath[:separator]
+		relativePath = workspacePath[separator+1:]
+	}
+	for _, root := range w.Roots {
+		if root.Name == rootName {
+			return filepath.Join(root.Path, filepath.FromSlash(relativePath)), nil
+		}
+	}
+	return "", fmt.Errorf("unknown workspace root %s in %s", rootName, workspacePath)
+}
+
+// FileResolver wraps a file resolver, so that it also resolves paths starting with WorkspaceRootPrefix.
+// All other paths are left to the wrapped resolver.
+func (w *Workspace) FileResolver(fileResolver fr.FileResolver) fr.FileResolver {
+	return &workspaceFileResolver{
+		FileResolver: fileResolver,
+		workspace:    w,
+	}
+}
+
+var _ fr.FileResolver = (*workspaceFileResolver)(nil)
+
+type workspaceFileResolver struct {
+	fr.FileResolver
+	workspace *Workspace
+}
+
+// Clone creates new instance of the same type.
+func (wfr *workspaceFileResolver) Clone() fr.FileResolver {
+	return &workspaceFileResolver{
+		FileResolver: wfr.FileResolver.Clone(),
+		workspace:    wfr.workspace,
+	}
+}
+
+// ResolveAbsolutePath yields absolute value based on context, or on the workspace root.
+// Paths of unknown roots are left to the wrapped resolver, so that they fail to load.
+func (wfr *workspaceFileResolver) ResolveAbsolutePath(value string) string {
+	if strings.HasPrefix(value, WorkspaceRootPrefix) {
+		absolutePath, err := wfr.workspace.AbsolutePath(value[len(WorkspaceRootPrefix):])
+		if err == nil {
+			return absolutePath
+		}
+	}
+	return wfr.FileResolver.ResolveAbsolutePath(value)
+}
+
+// ResolveFileValue converts a value prefixed with "file:" and replaces it with the file contents.
+func (wfr *workspaceFileResolver) ResolveFileValue(value string) ([]byte, error) {
+	if !strings.HasPrefix(value, WorkspaceRootPrefix) {
+		return wfr.FileResolver.ResolveFileValue(value)
+	}
+	absolutePath, err := wfr.workspace.AbsolutePath(value[len(WorkspaceRootPrefix):])
+	if err != nil {
+		return []byte{}, err
+	}
+	return ioutil.ReadFile(absolutePath)
+}
+
+// UseWorkspace makes the runner resolve the files of all workspace roots, see WorkspaceRootPrefix.
+// Replaces the workspace used before, if any.
+func (r *ScenarioRunner) UseWorkspace(workspace *Workspace) {
+	fileResolver := r.Parser.ValueInterpreter.FileResolver
+	audited, isAudited := fileResolver.(*auditFileResolver)
+	if isAudited {
+		// files of other roots get audited too
+		fileResolver = audited.FileResolver
+	}
+	if previous, isWorkspace := fileResolver.(*workspaceFileResolver); isWorkspace {
+		fileResolver = previous.FileResolver
+	}
+	fileResolver = workspace.FileResolver(fileResolver)
+	if isAudited {
+		audited.FileResolver = fileResolver
+		return
+	}
+	r.Parser.ValueInterpreter.FileResolver = fileResolver
+}
+
+// RunAllJSONScenariosInWorkspace runs the scenarios of all workspace roots, as one run,
+// in the order given by the runner Order. Excluded file patterns match workspace paths, see Workspace.
+func (r *ScenarioRunner) RunAllJSONScenariosInWorkspace(
+	workspace *Workspace,
+	allowedSuffix string,
+	excludedFilePatterns []string) (*RunReport, error) {
+
+	report := &RunReport{
+		StartedAt: time.Now(),
+	}
+	if err := validateExcludedFilePatterns(excludedFilePatterns); err != nil {
+		return report, err
+	}
+	r.UseWorkspace(workspace)
+
+	// nested roots would otherwise list their scenarios twice
+	var scenarioPaths []string
+	listed := make(map[string]bool)
+	for _, root := range workspace.Roots {
+		err := filepath.Walk(root.Path, func(testFilePath string, info os.FileInfo, err error) error {
+			if err != nil {
+				return err
+			}
+			if !info.IsDir() && strings.HasSuffix(testFilePath, allowedSuffix) && !listed[testFilePath] {
+				listed[testFilePath] = true
+				scenarioPaths = append(scenarioPaths, testFilePath)
+			}
+			return nil
+		})
+		if err != nil {
+			return report, fmt.Errorf("cannot list scenarios of workspace root %s: %w", root.Name, err)
+		}
+	}
+
+	return r.runScenarios(report, scenarioPaths, &scenarioRunNaming{
+		defaultSink: func() ReportSink {
+			return NewWorkspaceStdoutReportSink(workspace)
+		},
+		isExcluded: func(testFilePath string) bool {
+			return isExcludedFromWorkspace(excludedFilePatterns, workspace.WorkspacePath(testFilePath))
+		},
+		shortPath: workspace.WorkspacePath,
+	})
+}
+
+func isExcludedFromWorkspace(excludedFilePatterns []string, workspacePath string) bool {
+	for _, pattern := range excludedFilePatterns {
+		// validated before the run, see validateExcludedFilePatterns
+		if match, _ := path.Match(pattern, workspacePath); match {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,114 @@
+package denalicontroller
+
+import (
+	"bytes"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+// writeWorkspaceTestDir lays out a contract repository, whose scenarios use the fixtures of another repository.
+func writeWorkspaceTestDir(t *testing.T) (string, *Workspace) {
+	dir := t.TempDir()
+	writeExternalStepsScenario(t, filepath.Join(dir, "contract", "scenarios", "main.scen.json"),
+		`{"step": "externalSteps", "path": "@fixtures/fund.steps.json"},`+setStateWithCode("@fixtures/code.txt"))
+	writeExternalStepsScenario(t, filepath.Join(dir, "contract", "scenarios", "legacy", "old.scen.json"),
+		setStateWithCode("missing.txt"))
+	writeExternalStepsScenario(t, filepath.Join(dir, "shared", "fixtures", "fund.steps.json"),
+		setStateWithCode("code.txt"))
+	writeExternalStepsScenario(t, filepath.Join(dir, "shared", "fixtures", "fixture.scen.json"),
+		setStateWithCode("code.txt"))
+	require.Nil(t, os.WriteFile(filepath.Join(dir, "shared", "fixtures", "code.txt"), []byte("fixture"), 0644))
+
+	configPath := filepath.Join(dir, "contract", "workspace.json")
+	require.Nil(t, os.WriteFile(configPath, []byte(`{"roots": [
+		{"name": "contract", "path": "scenarios"},
+		{"name": "fixtures", "path": "../shared/fixtures"}
+	]}`), 0644))
+	workspace, err := LoadWorkspace(configPath)
+	require.Nil(t, err)
+	return dir, workspace
+}
+
+func TestWorkspacePaths(t *testing.T) {
+	dir, workspace := writeWorkspaceTestDir(t)
+	fixturePath := filepath.Join(dir, "shared", "fixtures", "fund.steps.json")
+	require.Equal(t, "fixtures/fund.steps.json", workspace.WorkspacePath(fixturePath))
+	absolutePath, err := workspace.AbsolutePath("fixtures/fund.steps.json")
+	require.Nil(t, err)
+	require.Equal(t, fixturePath, absolutePath)
+	_, err = workspace.AbsolutePath("other/fund.steps.json")
+	require.NotNil(t, err)
+
+	outsidePath := filepath.Join(dir, "contract", "workspace.json")
+	require.Equal(t, filepath.ToSlash(outsidePath), workspace.WorkspacePath(outsidePath))
+
+	configPath := filepath.Join(dir, "duplicate.json")
+	require.Nil(t, os.WriteFile(configPath, []byte(`{"roots": [{"name": "a", "path": "x"}, {"name": "a", "path": "y"}]}`), 0644))
+	_, err = LoadWorkspace(configPath)
+	require.NotNil(t, err)
+	require.Contains(t, err.Error(), "duplicate root name")
+}
+
+func TestRunAllJSONScenariosInWorkspace(t *testing.T) {
+	_, workspace := writeWorkspaceTestDir(t)
+	executor := &externalStepsExecutor{}
+	runner := NewScenarioRunner(executor, NewDefaultFileResolver())
+	executor.runner = runner
+	var stdout bytes.Buffer
+	runner.ReportSinks = []ReportSink{&StdoutReportSink{writer: &stdout, workspace: workspace}}
+
+	report, err := runner.RunAllJSONScenariosInWorkspace(workspace, ".scen.json", []string{"contract/legacy/*"})
+	require.Nil(t, err)
+	require.Equal(t, 3, len(report.Scenarios))
+	require.Equal(t, 1, report.Count(ScenarioSkipped))
+	require.Contains(t, stdout.String(), "Scenario: contract/main.scen.json ...   ok\n")
+	require.Contains(t, stdout.String(), "Scenario: contract/legacy/old.scen.json ...   skip\n")
+	require.Contains(t, stdout.String(), "Scenario: fixtures/fixture.scen.json ...   ok\n")
+	require.Equal(t, []string{"fixture", "fixture", "fixture"}, executor.codes)
+}
+
+func TestRunAllJSONScenariosInWorkspaceInvalidExcludedPattern(t *testing.T) {
+	_, workspace := writeWorkspaceTestDir(t)
+	executor := &externalStepsExecutor{}
+	runner := NewScenarioRunner(executor, NewDefaultFileResolver())
+	executor.runner = runner
+
+	report, err := runner.RunAllJSONScenariosInWorkspace(workspace, ".scen.json", []string{"contract/[legacy"})
+	require.NotNil(t, err)
+	require.Contains(t, err.Error(), "invalid excluded file pattern contract/[legacy")
+	require.Empty(t, report.Scenarios)
+	require.Empty(t, executor.codes)
+}
+
+func TestExportAndVerifyWorkspaceManifest(t *testing.T) {
+	dir, workspace := writeWorkspaceTestDir(t)
+	runner := NewScenarioRunner(nil, NewDefaultFileResolver())
+
+	var buffer bytes.Buffer
+	require.Nil(t, runner.ExportWorkspaceManifest(workspace, &buffer))
+	manifest := &ScenarioManifest{}
+	require.Nil(t, json.Unmarshal(buffer.Bytes(), manifest))
+	var paths []string
+	for _, entry := range manifest.Scenarios {
+		paths = append(paths, entry.Path)
+	}
+	require.Equal(t, []string{
+		"contract/legacy/old.scen.json",
+		"contract/main.scen.json",
+		"fixtures/fixture.scen.json",
+	}, paths)
+	require.Equal(t, "fixtures/code.txt", manifest.Scenarios[1].Contracts[0].Path)
+
+	problems, err := VerifyWorkspaceManifest(workspace, buffer.Bytes())
+	require.Nil(t, err)
+	require.Empty(t, problems)
+
+	require.Nil(t, os.WriteFile(filepath.Join(dir, "shared", "fixtures", "code.txt"), []byte("changed"), 0644))
+	problems, err = VerifyWorkspaceManifest(workspace, buffer.Bytes())
+	require.Nil(t, err)
+	require.Equal(t, []string{"fixtures/code.txt: content changed", "fixtures/code.txt: content changed"}, problems)
+}
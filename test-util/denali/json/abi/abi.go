@@ -0,0 +1,151 @@
+package denaliabi
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// LiteralPrefix marks values written as typed literals, e.g. "abi:u64:5" or "abi:MyStruct:{...}".
+const LiteralPrefix = "abi:"
+
+// ABI holds the custom type descriptions of a contract ABI file.
+// Only the parts needed for encoding and decoding values are loaded.
+type ABI struct {
+	Name  string                      `json:"name"`
+	Types map[string]*TypeDescription `json:"types"`
+}
+
+// TypeDescription describes a custom type, either a struct or an enum.
+type TypeDescription struct {
+	Type     string         `json:"type"`
+	Fields   []*StructField `json:"fields"`
+	Variants []*EnumVariant `json:"variants"`
+}
+
+// StructField is a named field of a struct type.
+type StructField struct {
+	Name string `json:"name"`
+	Type string `json:"type"`
+}
+
+// EnumVariant is a variant of an enum type. Only variants without fields are supported.
+type EnumVariant struct {
+	Name         string         `json:"name"`
+	Discriminant int            `json:"discriminant"`
+	Fields       []*StructField `json:"fields"`
+}
+
+// LoadABI parses the contents of an ABI JSON file.
+func LoadABI(data []byte) (*ABI, error) {
+	abi := &ABI{}
+	if err := json.Unmarshal(data, abi); err != nil {
+		return nil, fmt.Errorf("invalid ABI: %w", err)
+	}
+	for name, typeDescription := range abi.Types {
+		if typeDescription.Type != "struct" && typeDescription.Type != "enum" {
+			return nil, fmt.Errorf("invalid ABI: type %s is neither struct nor enum", name)
+		}
+	}
+	return abi, nil
+}
+
+// ParseLiteral splits a typed literal such as "abi:MyStruct:{...}" into the type name and the literal value.
+func ParseLiteral(expression string) (string, string, bool) {
+	if !strings.HasPrefix(expression, LiteralPrefix) {
+		return "", "", false
+	}
+	rest := expression[len(LiteralPrefix):]
+	typeEnd := strings.Index(rest, ":")
+	if typeEnd <= 0 {
+		return "", "", false
+	}
+	return rest[:typeEnd], rest[typeEnd+1:], true
+}
+
+// typeRef is a parsed type name, e.g. "List<Option<u8>>".
+type typeRef struct {
+	name string
+	args []*typeRef
+}
+
+func (t *typeRef) String() string {
+	if len(t.args) == 0 {
+		return t.name
+	}
+	argNames := make([]string, len(t.args))
+	for i, arg := range t.args {
+		argNames[i] = arg.String()
+	}
+	return t.name + "<" + strings.Join(argNames, ",") + ">"
+}
+
+func parseTypeRef(typeName string) (*typeRef, error) {
+	t, rest, err := parseTypeRefPrefix(strings.TrimSpace(typeName))
+	if err != nil {
+		return nil, err
+	}
+	if len(rest) > 0 {
+		return nil, fmt.Errorf("invalid type name: %s", typeName)
+	}
+	return t, nil
+}
+
+func parseTypeRefPrefix(str string) (*typeRef, string, error) {
+	nameEnd := strings.IndexAny(str, "<,>")
+	if nameEnd < 0 {
+		nameEnd = len(str)
+	}
+	t := &typeRef{name: strings.TrimSpace(str[:nameEnd])}
+	if len(t.name) == 0 {
+		return nil, "", errors.New("empty type name")
+	}
+	rest := str[nameEnd:]
+	if !strings.HasPrefix(rest, "<") {
+		return t, rest, nil
+	}
+	rest = rest[1:]
+	for {
+		arg, afterArg, err := parseTypeRefPrefix(strings.TrimSpace(rest))
+		if err != nil {
+			return nil, "", err
+		}
+		t.args = append(t.args, arg)
+		afterArg = strings.TrimSpace(afterArg)
+		switch {
+		case strings.HasPrefix(afterArg, ","):
+			rest = afterArg[1:]
+		case strings.HasPrefix(afterArg, ">"):
+			return t, afterArg[1:], nil
+		default:
+			return nil, "", fmt.Errorf("unclosed type arguments in %s", str)
+		}
+	}
+}
+
+func (abi *ABI) customType(name string) (*TypeDescription, bool) {
+	if abi == nil {
+		return nil, false
+	}
+	typeDescription, found := abi.Types[name]
+	return typeDescription, found
+}
+
+func (abi *ABI) enumVariantByName(typeDescription *TypeDescription, name string) (*EnumVariant, error) {
+	for _, variant := range typeDescription.Variants {
+		if variant.Name == name {
+			return variant, nil
+		}
+	}
+	return nil, fmt.Errorf("unknown enum variant: %s", name)
+}
+
+func (abi *ABI) enumVariantByDiscriminant(typeDescription *TypeDescription, discriminant int) (*EnumVariant, error) {
+	for _, variant := range typeDescription.Variants {
+		if variant.Discriminant == discriminant {
+			return variant, nil
+		}
+	}
+	return nil, fmt.Errorf("unknown enum discriminant: %d", discriminant)
+}
@@ -0,0 +1,295 @@
+package denaliabi
+
+import (
+	"encoding/binary"
+	"encoding/hex"
+	"fmt"
+	"math/big"
+	"strings"
+
+	twos "github.com/numbatx/gn-bigint/twos-complement"
+)
+
+// BytesFormatter converts decoded byte values (addresses, buffers) to text.
+type BytesFormatter func([]byte) string
+
+// DecodedValue is a value decoded through the ABI, kept as a tree so that values can be compared field by field.
+type DecodedValue struct {
+	TypeName string
+
+	// Text is set for everything except structs and lists.
+	Text string
+
+	// Fields is set for structs.
+	Fields []*DecodedField
+
+	// Items is set for lists.
+	Items []*DecodedValue
+
+	isList bool
+}
+
+// DecodedField is a named field of a decoded struct.
+type DecodedField struct {
+	Name  string
+	Value *DecodedValue
+}
+
+// String yields a readable representation, e.g. MyStruct{a: 5, b: [1, 2]}.
+func (dv *DecodedValue) String() string {
+	switch {
+	case dv.isList:
+		items := make([]string, len(dv.Items))
+		for i, item := range dv.Items {
+			items[i] = item.String()
+		}
+		return "[" + strings.Join(items, ", ") + "]"
+	case dv.Fields != nil:
+		fields := make([]string, len(dv.Fields))
+		for i, field := range dv.Fields {
+			fields[i] = field.Name + ": " + field.Value.String()
+		}
+		return dv.TypeName + "{" + strings.Join(fields, ", ") + "}"
+	default:
+		return dv.Text
+	}
+}
+
+// DecodeTopLevel decodes a value returned by a contract, as the given type.
+// If formatBytes is nil, byte values are shown as hex.
+// Can be called on a nil *ABI, in which case only basic types are known.
+func (abi *ABI) DecodeTopLevel(typeName string, data []byte, formatBytes BytesFormatter) (*DecodedValue, error) {
+	t, err := parseTypeRef(typeName)
+	if err != nil {
+		return nil, err
+	}
+	if formatBytes == nil {
+		formatBytes = func(b []byte) string { return "0x" + hex.EncodeToString(b) }
+	}
+	decoder := &abiDecoder{abi: abi, formatBytes: formatBytes}
+	value, rest, err := decoder.decode(t, data, false)
+	if err != nil {
+		return nil, err
+	}
+	if len(rest) > 0 {
+		return nil, fmt.Errorf("%d unexpected bytes after %s", len(rest), t)
+	}
+	return value, nil
+}
+
+type abiDecoder struct {
+	abi         *ABI
+	formatBytes BytesFormatter
+}
+
+// decode consumes the value from the start of data and yields the remaining bytes.
+// Top-level values always consume all data.
+func (d *abiDecoder) decode(t *typeRef, data []byte, nested bool) (*DecodedValue, []byte, error) {
+	typeName := t.String()
+	leaf := func(text string, rest []byte) (*DecodedValue, []byte, error) {
+		return &DecodedValue{TypeName: typeName, Text: text}, rest, nil
+	}
+
+	if numericType, isNumeric := numericTypes[t.name]; isNumeric {
+		size := len(data)
+		if nested {
+			size = numericType.size
+		}
+		if len(data) < size || size > numericType.size {
+			return nil, nil, fmt.Errorf("cannot decode %s from %d bytes", typeName, len(data))
+		}
+		return leaf(formatNumber(data[:size], numericType.signed), data[size:])
+	}
+
+	switch t.name {
+	case typeBigUint, typeBigInt:
+		value, rest, err := takeLengthPrefixed(data, nested)
+		if err != nil {
+			return nil, nil, err
+		}
+		return leaf(formatNumber(value, t.name == typeBigInt), rest)
+	case typeBool:
+		size := len(data)
+		if nested {
+			size = 1
+		}
+		if len(data) < size || size > 1 || (size == 1 && data[0] > 1) {
+			return nil, nil, fmt.Errorf("cannot decode bool from 0x%s", hex.EncodeToString(data))
+		}
+		return leaf(fmt.Sprintf("%t", size == 1 && data[0] == 1), data[size:])
+	case typeAddress:
+		if len(data) < addressLength || (!nested && len(data) != addressLength) {
+			return nil, nil, fmt.Errorf("cannot decode address from %d bytes", len(data))
+		}
+		return leaf(d.formatBytes(data[:addressLength]), data[addressLength:])
+	case typeList:
+		return d.decodeList(t, data, nested)
+	case typeOption:
+		return d.decodeOption(t, data, nested)
+	}
+	if bytesTypes[t.name] {
+		value, rest, err := takeLengthPrefixed(data, nested)
+		if err != nil {
+			return nil, nil, err
+		}
+		return leaf(d.formatBytes(value), rest)
+	}
+	if typeDescription, isCustom := d.abi.customType(t.name); isCustom {
+		if typeDescription.Type == "enum" {
+			return d.decodeEnum(typeName, typeDescription, data, nested)
+		}
+		return d.decodeStruct(typeName, typeDescription, data)
+	}
+	return nil, nil, fmt.Errorf("unknown type: %s", typeName)
+}
+
+func (d *abiDecoder) decodeList(t *typeRef, data []byte, nested bool) (*DecodedValue, []byte, error) {
+	if len(t.args) != 1 {
+		return nil, nil, fmt.Errorf("invalid list type: %s", t)
+	}
+	result := &DecodedValue{TypeName: t.String(), isList: true}
+	if nested {
+		count, rest, err := takeLength(data)
+		if err != nil {
+			return nil, nil, err
+		}
+		data = rest
+		for i := 0; i < count; i++ {
+			var item *DecodedValue
+			item, data, err = d.decode(t.args[0], data, true)
+			if err != nil {
+				return nil, nil, fmt.Errorf("item %d: %w", i, err)
+			}
+			result.Items = append(result.Items, item)
+		}
+		return result, data, nil
+	}
+	for len(data) > 0 {
+		var item *DecodedValue
+		var err error
+		item, data, err = d.decode(t.args[0], data, true)
+		if err != nil {
+			return nil, nil, fmt.Errorf("item %d: %w", len(result.Items), err)
+		}
+		result.Items = append(result.Items, item)
+	}
+	return result, data, nil
+}
+
+func (d *abiDecoder) decodeOption(t *typeRef, data []byte, nested bool) (*DecodedValue, []byte, error) {
+	if len(t.args) != 1 {
+		return nil, nil, fmt.Errorf("invalid option type: %s", t)
+	}
+	if len(data) == 0 && !nested {
+		return &DecodedValue{TypeName: t.String(), Text: "None"}, data, nil
+	}
+	if len(data) == 0 || data[0] > 1 {
+		return nil, nil, fmt.Errorf("cannot decode %s", t)
+	}
+	if data[0] == 0 {
+		return &DecodedValue{TypeName: t.String(), Text: "None"}, data[1:], nil
+	}
+	return d.decode(t.args[0], data[1:], true)
+}
+
+func (d *abiDecoder) decodeStruct(typeName string, typeDescription *TypeDescription, data []byte) (*DecodedValue, []byte, error) {
+	result := &DecodedValue{TypeName: typeName, Fields: []*DecodedField{}}
+	for _, field := range typeDescription.Fields {
+		fieldType, err := parseTypeRef(field.Type)
+		if err != nil {
+			return nil, nil, err
+		}
+		var fieldValue *DecodedValue
+		fieldValue, data, err = d.decode(fieldType, data, true)
+		if err != nil {
+			return nil, nil, fmt.Errorf("field %s: %w", field.Name, err)
+		}
+		result.Fields = append(result.Fields, &DecodedField{Name: field.Name, Value: fieldValue})
+	}
+	return result, data, nil
+}
+
+func (d *abiDecoder) decodeEnum(typeName string, typeDescription *TypeDescription, data []byte, nested bool) (*DecodedValue, []byte, error) {
+	size := len(data)
+	if nested {
+		size = 1
+	}
+	if len(data) < size || size > 1 {
+		return nil, nil, fmt.Errorf("cannot decode enum %s from %d bytes", typeName, len(data))
+	}
+	discriminant := 0
+	if size == 1 {
+		discriminant = int(data[0])
+	}
+	variant, err := d.abi.enumVariantByDiscriminant(typeDescription, discriminant)
+	if err != nil {
+		return nil, nil, err
+	}
+	return &DecodedValue{TypeName: typeName, Text: variant.Name}, data[size:], nil
+}
+
+func formatNumber(data []byte, signed bool) string {
+	if signed {
+		return twos.FromBytes(data).String()
+	}
+	return big.NewInt(0).SetBytes(data).String()
+}
+
+func takeLength(data []byte) (int, []byte, error) {
+	if len(data) < 4 {
+		return 0, nil, fmt.Errorf("cannot decode length from %d bytes", len(data))
+	}
+	return int(binary.BigEndian.Uint32(data[:4])), data[4:], nil
+}
+
+// takeLengthPrefixed yields a length-prefixed byte array when nested, or all data at top level.
+func takeLengthPrefixed(data []byte, nested bool) ([]byte, []byte, error) {
+	if !nested {
+		return data, nil, nil
+	}
+	length, rest, err := takeLength(data)
+	if err != nil {
+		return nil, nil, err
+	}
+	if len(rest) < length {
+		return nil, nil, fmt.Errorf("expected %d bytes, only %d left", length, len(rest))
+	}
+	return rest[:length], rest[length:], nil
+}
+
+// Diff compares two decoded values and describes each differing leaf, e.g. "amount: expected 5, actual 6".
+// Values of different shapes are compared as a whole.
+func Diff(expected, actual *DecodedValue) []string {
+	var diffs []string
+	diffDecoded("", expected, actual, &diffs)
+	return diffs
+}
+
+func diffDecoded(path string, expected, actual *DecodedValue, diffs *[]string) {
+	switch {
+	case expected.Fields != nil && actual.Fields != nil && len(expected.Fields) == len(actual.Fields):
+		for i, field := range expected.Fields {
+			diffDecoded(joinPath(path, field.Name), field.Value, actual.Fields[i].Value, diffs)
+		}
+	case expected.isList && actual.isList && len(expected.Items) == len(actual.Items):
+		for i, item := range expected.Items {
+			diffDecoded(fmt.Sprintf("%s[%d]", path, i), item, actual.Items[i], diffs)
+		}
+	default:
+		expectedStr := expected.String()
+		actualStr := actual.String()
+		if expectedStr != actualStr {
+			if len(path) == 0 {
+				path = "value"
+			}
+			*diffs = append(*diffs, fmt.Sprintf("%s: expected %s, actual %s", path, expectedStr, actualStr))
+		}
+	}
+}
+
+func joinPath(path string, name string) string {
+	if len(path) == 0 {
+		return name
+	}
+	return path + "." + name
+}
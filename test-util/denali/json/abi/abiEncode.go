@@ -0,0 +1,271 @@
+package denaliabi
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"math/big"
+	"strings"
+
+	twos "github.com/numbatx/gn-bigint/twos-complement"
+)
+
+// LeafInterpreter converts leaf values, such as "address:owner" or "str:abc", to bytes.
+// The value interpreter provides it, so that typed literals can use the usual Denali syntax for leaves.
+type LeafInterpreter func(string) ([]byte, error)
+
+// EncodeTopLevel encodes a literal of the given type, the way a contract returns it.
+// Literals are objects for structs, arrays for lists, null for missing options,
+// variant names for enums and Denali values for everything else, see parseLiteralValue.
+// Can be called on a nil *ABI, in which case only basic types are known.
+func (abi *ABI) EncodeTopLevel(typeName string, literal string, interpretLeaf LeafInterpreter) ([]byte, error) {
+	t, err := parseTypeRef(typeName)
+	if err != nil {
+		return nil, err
+	}
+	value, err := parseLiteralValue(literal)
+	if err != nil {
+		return nil, err
+	}
+	encoder := &abiEncoder{abi: abi, interpretLeaf: interpretLeaf}
+	return encoder.encode(t, value, false)
+}
+
+type abiEncoder struct {
+	abi           *ABI
+	interpretLeaf LeafInterpreter
+}
+
+func (e *abiEncoder) encode(t *typeRef, value interface{}, nested bool) ([]byte, error) {
+	if numericType, isNumeric := numericTypes[t.name]; isNumeric {
+		return e.encodeNumber(value, numericType.size, numericType.signed, nested)
+	}
+	switch t.name {
+	case typeBigUint:
+		return e.encodeNumber(value, 0, false, nested)
+	case typeBigInt:
+		return e.encodeNumber(value, 0, true, nested)
+	case typeBool:
+		return encodeBool(value, nested)
+	case typeAddress:
+		address, err := e.leafBytes(value)
+		if err != nil {
+			return nil, err
+		}
+		if len(address) != addressLength {
+			return nil, fmt.Errorf("address must be %d bytes long, got %d", addressLength, len(address))
+		}
+		return address, nil
+	case typeList:
+		return e.encodeList(t, value, nested)
+	case typeOption:
+		return e.encodeOption(t, value, nested)
+	}
+	if bytesTypes[t.name] {
+		data, err := e.leafBytes(value)
+		if err != nil {
+			return nil, err
+		}
+		if nested {
+			return append(lengthPrefix(len(data)), data...), nil
+		}
+		return data, nil
+	}
+	if typeDescription, isCustom := e.abi.customType(t.name); isCustom {
+		if typeDescription.Type == "enum" {
+			return e.encodeEnum(typeDescription, value, nested)
+		}
+		return e.encodeStruct(t.name, typeDescription, value)
+	}
+	return nil, fmt.Errorf("unknown type: %s", t)
+}
+
+func (e *abiEncoder) encodeNumber(value interface{}, size int, signed bool, nested bool) ([]byte, error) {
+	n, err := e.leafBigInt(value)
+	if err != nil {
+		return nil, err
+	}
+	if !signed && n.Sign() < 0 {
+		return nil, fmt.Errorf("negative value not allowed for unsigned type: %s", n)
+	}
+
+	var data []byte
+	switch {
+	case size > 0 && signed:
+		data, err = twos.ToBytesOfLength(n, size)
+		if err != nil {
+			return nil, fmt.Errorf("value %s does not fit in %d bytes", n, size)
+		}
+		if !nested {
+			data = twos.ToBytes(n)
+		}
+		return data, nil
+	case size > 0:
+		if n.BitLen() > size*8 {
+			return nil, fmt.Errorf("value %s does not fit in %d bytes", n, size)
+		}
+		if nested {
+			return twos.CopyAlignRight(n.Bytes(), size), nil
+		}
+		return n.Bytes(), nil
+	case signed:
+		data = twos.ToBytes(n)
+	default:
+		data = n.Bytes()
+	}
+	if nested {
+		return append(lengthPrefix(len(data)), data...), nil
+	}
+	return data, nil
+}
+
+func encodeBool(value interface{}, nested bool) ([]byte, error) {
+	var b bool
+	switch value {
+	case "true":
+		b = true
+	case "false":
+		b = false
+	default:
+		return nil, fmt.Errorf("invalid bool: %v", value)
+	}
+	if b {
+		return []byte{1}, nil
+	}
+	if nested {
+		return []byte{0}, nil
+	}
+	return []byte{}, nil
+}
+
+func (e *abiEncoder) encodeList(t *typeRef, value interface{}, nested bool) ([]byte, error) {
+	if len(t.args) != 1 {
+		return nil, fmt.Errorf("invalid list type: %s", t)
+	}
+	items, isList := value.([]interface{})
+	if !isList {
+		return nil, fmt.Errorf("expected a JSON array for %s", t)
+	}
+	var buffer bytes.Buffer
+	if nested {
+		buffer.Write(lengthPrefix(len(items)))
+	}
+	for i, item := range items {
+		itemBytes, err := e.encode(t.args[0], item, true)
+		if err != nil {
+			return nil, fmt.Errorf("item %d: %w", i, err)
+		}
+		buffer.Write(itemBytes)
+	}
+	return buffer.Bytes(), nil
+}
+
+func (e *abiEncoder) encodeOption(t *typeRef, value interface{}, nested bool) ([]byte, error) {
+	if len(t.args) != 1 {
+		return nil, fmt.Errorf("invalid option type: %s", t)
+	}
+	if value == nil {
+		if nested {
+			return []byte{0}, nil
+		}
+		return []byte{}, nil
+	}
+	inner, err := e.encode(t.args[0], value, true)
+	if err != nil {
+		return nil, err
+	}
+	return append([]byte{1}, inner...), nil
+}
+
+func (e *abiEncoder) encodeStruct(typeName string, typeDescription *TypeDescription, value interface{}) ([]byte, error) {
+	fieldValues, isMap := value.(map[string]interface{})
+	if !isMap {
+		return nil, fmt.Errorf("expected a JSON object for struct %s", typeName)
+	}
+	var buffer bytes.Buffer
+	for _, field := range typeDescription.Fields {
+		fieldValue, found := fieldValues[field.Name]
+		if !found {
+			return nil, fmt.Errorf("missing field %s of struct %s", field.Name, typeName)
+		}
+		fieldType, err := parseTypeRef(field.Type)
+		if err != nil {
+			return nil, err
+		}
+		fieldBytes, err := e.encode(fieldType, fieldValue, true)
+		if err != nil {
+			return nil, fmt.Errorf("field %s: %w", field.Name, err)
+		}
+		buffer.Write(fieldBytes)
+	}
+	if len(fieldValues) > len(typeDescription.Fields) {
+		for name := range fieldValues {
+			if !hasField(typeDescription, name) {
+				return nil, fmt.Errorf("unknown field %s of struct %s", name, typeName)
+			}
+		}
+	}
+	return buffer.Bytes(), nil
+}
+
+func (e *abiEncoder) encodeEnum(typeDescription *TypeDescription, value interface{}, nested bool) ([]byte, error) {
+	name, isString := value.(string)
+	if !isString {
+		return nil, fmt.Errorf("expected an enum variant name, got %v", value)
+	}
+	variant, err := e.abi.enumVariantByName(typeDescription, name)
+	if err != nil {
+		return nil, err
+	}
+	if len(variant.Fields) > 0 {
+		return nil, fmt.Errorf("enum variants with fields are not supported: %s", name)
+	}
+	if nested {
+		return []byte{byte(variant.Discriminant)}, nil
+	}
+	return big.NewInt(int64(variant.Discriminant)).Bytes(), nil
+}
+
+func (e *abiEncoder) leafBigInt(value interface{}) (*big.Int, error) {
+	str, isString := value.(string)
+	if !isString {
+		return nil, fmt.Errorf("invalid number: %v", value)
+	}
+	n, ok := big.NewInt(0).SetString(strings.ReplaceAll(str, "_", ""), 0)
+	if ok {
+		return n, nil
+	}
+	// other Denali expressions, e.g. "const:AMOUNT", are taken as unsigned
+	data, err := e.leafBytes(str)
+	if err != nil {
+		return nil, fmt.Errorf("invalid number: %s", str)
+	}
+	return big.NewInt(0).SetBytes(data), nil
+}
+
+func (e *abiEncoder) leafBytes(value interface{}) ([]byte, error) {
+	str, isString := value.(string)
+	if !isString {
+		return nil, fmt.Errorf("expected a string, got %v", value)
+	}
+	if e.interpretLeaf == nil {
+		return nil, errors.New("no leaf interpreter provided")
+	}
+	return e.interpretLeaf(str)
+}
+
+func hasField(typeDescription *TypeDescription, name string) bool {
+	for _, field := range typeDescription.Fields {
+		if field.Name == name {
+			return true
+		}
+	}
+	return false
+}
+
+func lengthPrefix(length int) []byte {
+	prefix := make([]byte, 4)
+	binary.BigEndian.PutUint32(prefix, uint32(length))
+	return prefix
+}
@@ -0,0 +1,134 @@
+package denaliabi
+
+import (
+	"encoding/hex"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+const testABIJSON = `{
+	"name": "Test",
+	"types": {
+		"Status": {
+			"type": "enum",
+			"variants": [
+				{"name": "Inactive", "discriminant": 0},
+				{"name": "Active", "discriminant": 1}
+			]
+		},
+		"Deposit": {
+			"type": "struct",
+			"fields": [
+				{"name": "amount", "type": "BigUint"},
+				{"name": "nonce", "type": "u16"},
+				{"name": "status", "type": "Status"},
+				{"name": "memo", "type": "Option<ManagedBuffer>"},
+				{"name": "tags", "type": "List<u8>"}
+			]
+		}
+	}
+}`
+
+func testLeafInterpreter(value string) ([]byte, error) {
+	if strings.HasPrefix(value, "str:") {
+		return []byte(value[4:]), nil
+	}
+	return hex.DecodeString(strings.TrimPrefix(value, "0x"))
+}
+
+func loadTestABI(t *testing.T) *ABI {
+	abi, err := LoadABI([]byte(testABIJSON))
+	require.Nil(t, err)
+	return abi
+}
+
+func TestEncodeBasicTypes(t *testing.T) {
+	var abi *ABI
+	encode := func(typeName string, literal string) []byte {
+		result, err := abi.EncodeTopLevel(typeName, literal, testLeafInterpreter)
+		require.Nil(t, err)
+		return result
+	}
+	require.Equal(t, []byte{0x05}, encode("u64", "5"))
+	require.Equal(t, []byte{}, encode("u64", "0"))
+	require.Equal(t, []byte{0xff}, encode("i32", "-1"))
+	require.Equal(t, []byte{0x01, 0x00}, encode("BigUint", "256"))
+	require.Equal(t, []byte{0x01}, encode("bool", "true"))
+	require.Equal(t, []byte{}, encode("bool", "false"))
+	require.Equal(t, []byte("abc"), encode("ManagedBuffer", "str:abc"))
+	require.Equal(t, []byte{}, encode("Option<u8>", "null"))
+	require.Equal(t, []byte{0x01, 0x07}, encode("Option<u8>", "7"))
+	require.Equal(t, []byte{0x00, 0x01, 0x00, 0x02}, encode("List<u16>", "[1, 2]"))
+	require.Equal(t,
+		[]byte{0, 0, 0, 2, 0, 0, 0, 1, 'a', 0, 0, 0, 3, 'b', ',', 'c'},
+		encode("List<List<ManagedBuffer>>", `[[str:a, "str:b,c"]]`))
+
+	_, err := abi.EncodeTopLevel("u8", "256", testLeafInterpreter)
+	require.NotNil(t, err)
+	_, err = abi.EncodeTopLevel("u8", "-1", testLeafInterpreter)
+	require.NotNil(t, err)
+	_, err = abi.EncodeTopLevel("Deposit", "{}", testLeafInterpreter)
+	require.NotNil(t, err)
+	_, err = abi.EncodeTopLevel("List<u8", "[]", testLeafInterpreter)
+	require.NotNil(t, err)
+	_, err = abi.EncodeTopLevel("List<u8>", "[1, 2", testLeafInterpreter)
+	require.NotNil(t, err)
+}
+
+func TestEncodeDecodeStruct(t *testing.T) {
+	abi := loadTestABI(t)
+	literal := "{amount: 1000, nonce: 3, status: Active, memo: str:hi, tags: [1, 2]}"
+	encoded, err := abi.EncodeTopLevel("Deposit", literal, testLeafInterpreter)
+	require.Nil(t, err)
+	require.Equal(t, "00000002"+"03e8"+"0003"+"01"+"01"+"00000002"+"6869"+"00000002"+"0102", hex.EncodeToString(encoded))
+
+	decoded, err := abi.DecodeTopLevel("Deposit", encoded, nil)
+	require.Nil(t, err)
+	require.Equal(t, "Deposit{amount: 1000, nonce: 3, status: Active, memo: 0x6869, tags: [1, 2]}", decoded.String())
+
+	_, err = abi.EncodeTopLevel("Deposit", "{amount: 1, nonce: 3, status: Active, memo: null, tags: [], extra: 1}", testLeafInterpreter)
+	require.NotNil(t, err)
+	_, err = abi.EncodeTopLevel("Deposit", "{amount: 1, nonce: 3, status: Unknown, memo: null, tags: []}", testLeafInterpreter)
+	require.NotNil(t, err)
+
+	_, err = abi.DecodeTopLevel("Deposit", encoded[:len(encoded)-1], nil)
+	require.NotNil(t, err)
+	_, err = abi.DecodeTopLevel("Deposit", append(encoded, 0x00), nil)
+	require.NotNil(t, err)
+}
+
+func TestDiff(t *testing.T) {
+	abi := loadTestABI(t)
+	decode := func(literal string) *DecodedValue {
+		encoded, err := abi.EncodeTopLevel("Deposit", literal, testLeafInterpreter)
+		require.Nil(t, err)
+		decoded, err := abi.DecodeTopLevel("Deposit", encoded, nil)
+		require.Nil(t, err)
+		return decoded
+	}
+	expected := decode("{amount: 1000, nonce: 3, status: Active, memo: null, tags: [1, 2]}")
+	actual := decode("{amount: 1000, nonce: 4, status: Inactive, memo: null, tags: [1, 3]}")
+	require.Equal(t, []string{
+		"nonce: expected 3, actual 4",
+		"status: expected Active, actual Inactive",
+		"tags[1]: expected 2, actual 3",
+	}, Diff(expected, actual))
+	require.Empty(t, Diff(expected, expected))
+
+	shorter := decode("{amount: 1000, nonce: 3, status: Active, memo: null, tags: [1]}")
+	require.Equal(t, []string{"tags: expected [1, 2], actual [1]"}, Diff(expected, shorter))
+}
+
+func TestParseLiteral(t *testing.T) {
+	typeName, literal, ok := ParseLiteral("abi:Deposit:{amount: 5}")
+	require.True(t, ok)
+	require.Equal(t, "Deposit", typeName)
+	require.Equal(t, "{amount: 5}", literal)
+
+	_, _, ok = ParseLiteral("abi:u64")
+	require.False(t, ok)
+	_, _, ok = ParseLiteral("u64:5")
+	require.False(t, ok)
+}
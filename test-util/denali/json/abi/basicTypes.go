@@ -0,0 +1,38 @@
+package denaliabi
+
+// numericTypes maps fixed width number types to their size in bytes.
+var numericTypes = map[string]struct {
+	size   int
+	signed bool
+}{
+	"u8":    {1, false},
+	"u16":   {2, false},
+	"u32":   {4, false},
+	"u64":   {8, false},
+	"usize": {4, false},
+	"i8":    {1, true},
+	"i16":   {2, true},
+	"i32":   {4, true},
+	"i64":   {8, true},
+	"isize": {4, true},
+}
+
+const (
+	typeBigUint = "BigUint"
+	typeBigInt  = "BigInt"
+	typeBool    = "bool"
+	typeAddress = "Address"
+	typeList    = "List"
+	typeOption  = "Option"
+
+	addressLength = 32
+)
+
+// bytesTypes are encoded as a length-prefixed byte array when nested.
+var bytesTypes = map[string]bool{
+	"bytes":           true,
+	"BoxedBytes":      true,
+	"ManagedBuffer":   true,
+	"TokenIdentifier": true,
+	"utf-8 string":    true,
+}
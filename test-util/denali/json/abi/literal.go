@@ -0,0 +1,183 @@
+package denaliabi
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+)
+
+// parseLiteralValue parses typed literal values. The syntax is relaxed JSON, so that literals
+// fit inside JSON strings without escaping: keys and scalars need no quotes,
+// e.g. {depositor: address:owner, amount: 5, tags: [str:a, str:b]}.
+// Scalars end at the next ',', '}' or ']', quote them if they contain any of these.
+// Scalars are kept as strings, "null" is nil.
+func parseLiteralValue(literal string) (interface{}, error) {
+	trimmed := strings.TrimSpace(literal)
+	if !strings.HasPrefix(trimmed, "{") && !strings.HasPrefix(trimmed, "[") {
+		// a single scalar, separators are allowed
+		if trimmed == "null" {
+			return nil, nil
+		}
+		return literal, nil
+	}
+	lp := &literalParser{input: trimmed}
+	value, err := lp.parseValue()
+	if err != nil {
+		return nil, fmt.Errorf("invalid literal %s: %w", literal, err)
+	}
+	lp.skipSpaces()
+	if lp.pos < len(lp.input) {
+		return nil, fmt.Errorf("invalid literal %s: unexpected characters after position %d", literal, lp.pos)
+	}
+	return value, nil
+}
+
+type literalParser struct {
+	input string
+	pos   int
+}
+
+func (lp *literalParser) skipSpaces() {
+	for lp.pos < len(lp.input) && strings.ContainsRune(" \t\r\n", rune(lp.input[lp.pos])) {
+		lp.pos++
+	}
+}
+
+func (lp *literalParser) peek() byte {
+	if lp.pos >= len(lp.input) {
+		return 0
+	}
+	return lp.input[lp.pos]
+}
+
+func (lp *literalParser) expect(c byte) error {
+	lp.skipSpaces()
+	if lp.peek() != c {
+		return fmt.Errorf("expected '%c' at position %d", c, lp.pos)
+	}
+	lp.pos++
+	return nil
+}
+
+func (lp *literalParser) parseValue() (interface{}, error) {
+	lp.skipSpaces()
+	switch lp.peek() {
+	case '{':
+		return lp.parseObject()
+	case '[':
+		return lp.parseArray()
+	case '"':
+		return lp.parseQuoted()
+	default:
+		scalar := lp.parseScalar(",}]")
+		if scalar == "null" {
+			return nil, nil
+		}
+		return scalar, nil
+	}
+}
+
+func (lp *literalParser) parseObject() (interface{}, error) {
+	lp.pos++ // '{'
+	result := make(map[string]interface{})
+	lp.skipSpaces()
+	if lp.peek() == '}' {
+		lp.pos++
+		return result, nil
+	}
+	for {
+		lp.skipSpaces()
+		var key string
+		if lp.peek() == '"' {
+			quoted, err := lp.parseQuoted()
+			if err != nil {
+				return nil, err
+			}
+			key = quoted
+		} else {
+			key = lp.parseScalar(":,}]")
+		}
+		if len(key) == 0 {
+			return nil, fmt.Errorf("missing key at position %d", lp.pos)
+		}
+		if err := lp.expect(':'); err != nil {
+			return nil, err
+		}
+		value, err := lp.parseValue()
+		if err != nil {
+			return nil, err
+		}
+		if _, duplicate := result[key]; duplicate {
+			return nil, fmt.Errorf("duplicate key: %s", key)
+		}
+		result[key] = value
+
+		lp.skipSpaces()
+		switch lp.peek() {
+		case ',':
+			lp.pos++
+		case '}':
+			lp.pos++
+			return result, nil
+		default:
+			return nil, fmt.Errorf("expected ',' or '}' at position %d", lp.pos)
+		}
+	}
+}
+
+func (lp *literalParser) parseArray() (interface{}, error) {
+	lp.pos++ // '['
+	result := make([]interface{}, 0)
+	lp.skipSpaces()
+	if lp.peek() == ']' {
+		lp.pos++
+		return result, nil
+	}
+	for {
+		value, err := lp.parseValue()
+		if err != nil {
+			return nil, err
+		}
+		result = append(result, value)
+
+		lp.skipSpaces()
+		switch lp.peek() {
+		case ',':
+			lp.pos++
+		case ']':
+			lp.pos++
+			return result, nil
+		default:
+			return nil, fmt.Errorf("expected ',' or ']' at position %d", lp.pos)
+		}
+	}
+}
+
+func (lp *literalParser) parseQuoted() (string, error) {
+	start := lp.pos
+	lp.pos++ // opening quote
+	for lp.pos < len(lp.input) {
+		switch lp.input[lp.pos] {
+		case '\\':
+			lp.pos += 2
+			continue
+		case '"':
+			lp.pos++
+			var result string
+			if err := json.Unmarshal([]byte(lp.input[start:lp.pos]), &result); err != nil {
+				return "", fmt.Errorf("invalid quoted string at position %d: %w", start, err)
+			}
+			return result, nil
+		}
+		lp.pos++
+	}
+	return "", fmt.Errorf("unterminated quoted string at position %d", start)
+}
+
+func (lp *literalParser) parseScalar(terminators string) string {
+	start := lp.pos
+	for lp.pos < len(lp.input) && !strings.ContainsRune(terminators, rune(lp.input[lp.pos])) {
+		lp.pos++
+	}
+	return strings.TrimSpace(lp.input[start:lp.pos])
+}
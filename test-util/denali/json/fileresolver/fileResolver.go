@@ -1,16 +1,27 @@
 package denalifileresolver
 
-// FileResolver resolves Denali values starting with "file:"
-type FileResolver interface {
-	// Clone creates new instance of the same type.
-	Clone() FileResolver
+import (
+	"io/fs"
 
-	// SetContext sets directory where the test runs, to help resolve relative paths.
-	SetContext(contextPath string)
+	fr "github.com/numbatx/gn-vm-util/test-util/fileresolver"
+)
 
-	// ResolveAbsolutePath yields absolute value based on context.
-	ResolveAbsolutePath(value string) string
+// FileResolver resolves Denali values starting with "file:".
+// It is the same interface that all test dialects use, kept here for backwards compatibility.
+type FileResolver = fr.FileResolver
 
-	// ResolveFileValue converts a value prefixed with "file:" and replaces it with the file contents.
-	ResolveFileValue(value string) ([]byte, error)
+// DefaultFileResolver loads file contents for the test parser.
+type DefaultFileResolver = fr.DefaultFileResolver
+
+// NewDefaultFileResolver yields a new DefaultFileResolver instance.
+func NewDefaultFileResolver() *DefaultFileResolver {
+	return fr.NewDefaultFileResolver()
+}
+
+// FSFileResolver loads file contents from an fs.FS, e.g. files embedded with go:embed.
+type FSFileResolver = fr.FSFileResolver
+
+// NewFSFileResolver yields a new FSFileResolver instance.
+func NewFSFileResolver(fsys fs.FS) *FSFileResolver {
+	return fr.NewFSFileResolver(fsys)
 }
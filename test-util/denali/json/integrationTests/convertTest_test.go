@@ -0,0 +1,44 @@
+package denalijsontest
+
+import (
+	"testing"
+
+	fr "github.com/numbatx/gn-vm-util/test-util/denali/json/fileresolver"
+	mj "github.com/numbatx/gn-vm-util/test-util/denali/json/model"
+	mjparse "github.com/numbatx/gn-vm-util/test-util/denali/json/parse"
+	mjwrite "github.com/numbatx/gn-vm-util/test-util/denali/json/write"
+	"github.com/stretchr/testify/require"
+)
+
+func TestConvertTestToScenario(t *testing.T) {
+	contents, err := loadExampleFile("example.test.json")
+	require.Nil(t, err)
+	p := mjparse.NewParser(
+		fr.NewDefaultFileResolver().ReplacePath(
+			"smart-contract.wasm",
+			"exampleFile.txt"))
+
+	scenarios, err := p.ConvertTestToScenario(contents)
+	require.Nil(t, err)
+	require.Equal(t, 1, len(scenarios))
+	scenario := scenarios[0]
+	require.Equal(t, "ERC20_IELE", scenario.Name)
+
+	var stepTypes []string
+	for _, step := range scenario.Steps {
+		stepTypes = append(stepTypes, step.StepTypeName())
+	}
+	require.Equal(t, []string{
+		mj.StepNameSetState, mj.StepNameScCall, mj.StepNameScDeploy, mj.StepNameCheckState,
+	}, stepTypes)
+	initialState := scenario.Steps[0].(*mj.SetStateStep)
+	require.Equal(t, 2, len(initialState.Accounts))
+	require.Equal(t, uint64(1), initialState.CurrentBlockInfo.BlockNonce.Value)
+	require.Equal(t, "2", scenario.Steps[2].(*mj.TxStep).TxIdent)
+
+	// the converted scenario is a valid scenario
+	scenarioJSON := mjwrite.ScenarioToJSONString(scenario)
+	reparsed, err := p.ParseScenarioFile([]byte(scenarioJSON))
+	require.Nil(t, err)
+	require.Equal(t, scenarioJSON, mjwrite.ScenarioToJSONString(reparsed))
+}
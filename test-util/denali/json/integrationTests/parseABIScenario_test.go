@@ -0,0 +1,42 @@
+package denalijsontest
+
+import (
+	"testing"
+
+	fr "github.com/numbatx/gn-vm-util/test-util/denali/json/fileresolver"
+	mj "github.com/numbatx/gn-vm-util/test-util/denali/json/model"
+	mjparse "github.com/numbatx/gn-vm-util/test-util/denali/json/parse"
+	mjwrite "github.com/numbatx/gn-vm-util/test-util/denali/json/write"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseWriteABIScenario(t *testing.T) {
+	contents, err := loadExampleFile("exampleABI.scen.json")
+	require.Nil(t, err)
+
+	p := mjparse.NewParser(fr.NewDefaultFileResolver())
+	scenario, parseErr := p.ParseScenarioFile(contents)
+	require.Nil(t, parseErr)
+	require.Equal(t, "example.abi.json", scenario.ABIPath)
+	require.NotNil(t, scenario.ABI)
+	require.Nil(t, p.ValueInterpreter.ABI)
+
+	out := scenario.Steps[0].(*mj.TxStep).ExpectedResult.Out
+	require.Equal(t, 2, len(out))
+	depositor, _ := p.ValueInterpreter.InterpretString("address:owner")
+	expectedDeposit := append([]byte{}, depositor...)
+	expectedDeposit = append(expectedDeposit, 0, 0, 0, 2, 0x03, 0xe8) // amount
+	expectedDeposit = append(expectedDeposit, 0, 0, 0, 0, 0, 0, 0, 3) // nonce
+	expectedDeposit = append(expectedDeposit, 1)                      // status
+	expectedDeposit = append(expectedDeposit, 0, 0, 0, 2, 0, 0, 0, 1, 'a', 0, 0, 0, 1, 'b')
+	require.Equal(t, expectedDeposit, out[0].Value)
+	require.Equal(t, []byte{5}, out[1].Value)
+
+	serialized := mjwrite.ScenarioToJSONString(scenario)
+	require.Equal(t, string(contents), serialized)
+
+	_, err = mjwrite.DowngradeScenario(scenario, mjwrite.CompatibilityOptions{
+		TargetVersion: mj.FormatVersionRequiresFormatVersion,
+	})
+	require.NotNil(t, err)
+}
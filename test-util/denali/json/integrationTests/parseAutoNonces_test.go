@@ -0,0 +1,80 @@
+package denalijsontest
+
+import (
+	"testing"
+
+	fr "github.com/numbatx/gn-vm-util/test-util/denali/json/fileresolver"
+	mj "github.com/numbatx/gn-vm-util/test-util/denali/json/model"
+	mjparse "github.com/numbatx/gn-vm-util/test-util/denali/json/parse"
+	mjwrite "github.com/numbatx/gn-vm-util/test-util/denali/json/write"
+	"github.com/stretchr/testify/require"
+)
+
+func txNonces(scenario *mj.Scenario) []uint64 {
+	var nonces []uint64
+	for _, step := range scenario.Steps {
+		if txStep, isTx := step.(*mj.TxStep); isTx {
+			nonces = append(nonces, txStep.Tx.Nonce.Value)
+		}
+	}
+	return nonces
+}
+
+func TestParseWriteAutoNonces(t *testing.T) {
+	contents, err := loadExampleFile("exampleAutoNonces.scen.json")
+	require.Nil(t, err)
+
+	p := mjparse.NewParser(fr.NewDefaultFileResolver())
+	scenario, parseErr := p.ParseScenarioFile(contents)
+	require.Nil(t, parseErr)
+	require.True(t, scenario.AutoNonces)
+
+	// the wrong explicit nonce does not count
+	require.Equal(t, []uint64{5, 0, 100, 6}, txNonces(scenario))
+
+	serialized := mjwrite.ScenarioToJSONString(scenario)
+	require.Equal(t, string(contents), serialized)
+}
+
+func TestAutoNoncesDowngrade(t *testing.T) {
+	contents, err := loadExampleFile("exampleAutoNonces.scen.json")
+	require.Nil(t, err)
+	p := mjparse.NewParser(fr.NewDefaultFileResolver())
+	scenario, err := p.ParseScenarioFile(contents)
+	require.Nil(t, err)
+
+	downgraded, err := mjwrite.DowngradeScenario(scenario, mjwrite.CompatibilityOptions{
+		TargetVersion: mj.FormatVersionViewScenarios,
+	})
+	require.Nil(t, err)
+	require.False(t, downgraded.AutoNonces)
+	for _, step := range downgraded.Steps {
+		if txStep, isTx := step.(*mj.TxStep); isTx {
+			require.NotEmpty(t, txStep.Tx.Nonce.Original)
+		}
+	}
+
+	// the original is untouched
+	require.Empty(t, scenario.Steps[1].(*mj.TxStep).Tx.Nonce.Original)
+
+	// older parsers read the same nonces
+	reparsed, err := p.ParseScenarioFile([]byte(mjwrite.ScenarioToJSONString(downgraded)))
+	require.Nil(t, err)
+	require.Equal(t, txNonces(scenario), txNonces(reparsed))
+}
+
+func TestAutoNoncesOff(t *testing.T) {
+	p := mjparse.NewParser(fr.NewDefaultFileResolver())
+	scenario, err := p.ParseScenarioFile([]byte(`{
+		"steps": [
+			{"step": "setState", "accounts": {"address:a": {"nonce": "3", "balance": "0"}}},
+			{"step": "transfer", "tx": {"from": "address:a", "to": "address:b", "value": "0"}}
+		]
+	}`))
+	require.Nil(t, err)
+	require.False(t, scenario.AutoNonces)
+	require.Equal(t, []uint64{0}, txNonces(scenario))
+
+	_, err = p.ParseScenarioFile([]byte(`{"autoNonces": "yes", "steps": []}`))
+	require.NotNil(t, err)
+}
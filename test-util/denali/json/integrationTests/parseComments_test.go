@@ -0,0 +1,62 @@
+package denalijsontest
+
+import (
+	"testing"
+
+	fr "github.com/numbatx/gn-vm-util/test-util/denali/json/fileresolver"
+	mj "github.com/numbatx/gn-vm-util/test-util/denali/json/model"
+	mjparse "github.com/numbatx/gn-vm-util/test-util/denali/json/parse"
+	mjwrite "github.com/numbatx/gn-vm-util/test-util/denali/json/write"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseWriteScenarioWithComments(t *testing.T) {
+	contents, err := loadExampleFile("exampleComments.scen.json")
+	require.Nil(t, err)
+
+	p := mjparse.NewParser(fr.NewDefaultFileResolver())
+	scenario, parseErr := p.ParseScenarioFile(contents)
+	require.Nil(t, parseErr)
+	require.Equal(t, 2, len(scenario.Steps))
+	require.Equal(t, []string{"// end of scenario"}, scenario.Comments.Trailing)
+	require.Equal(t, 1, len(scenario.Comments.Before["steps[1].accounts.address:contract"]))
+
+	serialized := mjwrite.ScenarioToJSONString(scenario)
+	require.Equal(t, string(contents), serialized)
+
+	downgraded, err := mjwrite.DowngradeScenario(scenario, mjwrite.CompatibilityOptions{
+		TargetVersion: mj.FormatVersionDefines,
+	})
+	require.Nil(t, err)
+	require.Nil(t, downgraded.Comments)
+	require.NotNil(t, scenario.Comments)
+}
+
+func TestCommentsAttachToNextValue(t *testing.T) {
+	p := mjparse.NewParser(fr.NewDefaultFileResolver())
+	scenario, err := p.ParseScenarioFile([]byte(`{
+		"name": /* inline */ "commented", // after the name
+		"steps": [] /* no steps */ }`))
+	require.Nil(t, err)
+	require.Equal(t, "commented", scenario.Name)
+	require.Equal(t, []string{"/* inline */"}, scenario.Comments.Before["name"])
+	require.Equal(t, []string{"// after the name"}, scenario.Comments.Before["steps"])
+	require.Equal(t, []string{"/* no steps */"}, scenario.Comments.End[""])
+
+	require.Equal(t, `{
+    /* inline */
+    "name": "commented",
+    // after the name
+    "steps": []
+    /* no steps */
+}
+`, mjwrite.ScenarioToJSONString(scenario))
+
+	scenario, err = p.ParseScenarioFile([]byte(`{"steps": []}`))
+	require.Nil(t, err)
+	require.Nil(t, scenario.Comments)
+
+	_, err = p.ParseScenarioFile([]byte("{\"steps\": [] /* unterminated }"))
+	require.NotNil(t, err)
+	require.Equal(t, "line 1, column 14: unterminated comment", err.Error())
+}
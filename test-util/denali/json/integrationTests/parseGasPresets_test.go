@@ -0,0 +1,67 @@
+package denalijsontest
+
+import (
+	"testing"
+
+	fr "github.com/numbatx/gn-vm-util/test-util/denali/json/fileresolver"
+	mj "github.com/numbatx/gn-vm-util/test-util/denali/json/model"
+	mjparse "github.com/numbatx/gn-vm-util/test-util/denali/json/parse"
+	mjwrite "github.com/numbatx/gn-vm-util/test-util/denali/json/write"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseWriteGasPresets(t *testing.T) {
+	contents, err := loadExampleFile("exampleGasPresets.scen.json")
+	require.Nil(t, err)
+
+	p := mjparse.NewParser(fr.NewDefaultFileResolver())
+	scenario, parseErr := p.ParseScenarioFile(contents)
+	require.Nil(t, parseErr)
+	require.Equal(t, 3, len(scenario.GasPresets))
+	require.Equal(t, &mj.ScenarioMetadata{
+		Owner:   "vm-team",
+		License: "GPL-3.0",
+		Tags:    []string{"gas", "example"},
+	}, scenario.Metadata)
+	require.Empty(t, p.ValueInterpreter.GasPresets)
+
+	tx := scenario.Steps[0].(*mj.TxStep).Tx
+	require.Equal(t, uint64(5000000), tx.GasLimit.Value)
+	require.Equal(t, uint64(0), tx.GasPrice.Value)
+
+	serialized := mjwrite.ScenarioToJSONString(scenario)
+	require.Equal(t, string(contents), serialized)
+
+	_, err = mjwrite.DowngradeScenario(scenario, mjwrite.CompatibilityOptions{
+		TargetVersion: mj.FormatVersionGenerateAccounts,
+	})
+	require.NotNil(t, err)
+}
+
+func TestSuiteGasPresets(t *testing.T) {
+	p := mjparse.NewParser(fr.NewDefaultFileResolver())
+	p.ValueInterpreter.SetGasPreset("call", 1000)
+	scenarioJSON := `{
+		"gasPresets": {"heavy": "2000"},
+		"steps": [
+			{"step": "transfer", "tx": {"from": "address:a", "to": "address:b", "value": "0", "gasLimit": "preset:call", "gasPrice": "preset:heavy"}}
+		]
+	}`
+	scenario, err := p.ParseScenarioFile([]byte(scenarioJSON))
+	require.Nil(t, err)
+	tx := scenario.Steps[0].(*mj.TxStep).Tx
+	require.Equal(t, uint64(1000), tx.GasLimit.Value)
+	require.Equal(t, uint64(2000), tx.GasPrice.Value)
+
+	// scenario presets do not leak into the suite
+	_, found := p.ValueInterpreter.GasPresets["heavy"]
+	require.False(t, found)
+
+	_, err = p.ParseScenarioFile([]byte(`{"steps": [
+		{"step": "transfer", "tx": {"from": "address:a", "to": "address:b", "value": "0", "gasLimit": "preset:unknown", "gasPrice": "0"}}
+	]}`))
+	require.NotNil(t, err)
+
+	_, err = p.ParseScenarioFile([]byte(`{"gasPresets": {"huge": "0x010000000000000000"}, "steps": []}`))
+	require.NotNil(t, err)
+}
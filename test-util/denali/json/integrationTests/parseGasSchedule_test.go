@@ -0,0 +1,84 @@
+package denalijsontest
+
+import (
+	"testing"
+	"testing/fstest"
+
+	mj "github.com/numbatx/gn-vm-util/test-util/denali/json/model"
+	mjparse "github.com/numbatx/gn-vm-util/test-util/denali/json/parse"
+	mjwrite "github.com/numbatx/gn-vm-util/test-util/denali/json/write"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseWriteGasSchedule(t *testing.T) {
+	p := mjparse.NewFSParser(fstest.MapFS{
+		"gasSchedules/gasScheduleV4.toml": &fstest.MapFile{Data: []byte("[BaseOperationCost]\nStorePerByte = 50\n")},
+	})
+
+	scenarioJSON := `{
+    "gasSchedule": "v4",
+    "steps": []
+}
+`
+	scenario, err := p.ParseScenarioFile([]byte(scenarioJSON))
+	require.Nil(t, err)
+	require.Equal(t, "v4", scenario.GasSchedule.Version)
+	require.Empty(t, scenario.GasSchedule.Path)
+	require.Equal(t, scenarioJSON, mjwrite.ScenarioToJSONString(scenario))
+
+	scenarioJSON = `{
+    "gasSchedule": "gasSchedules/gasScheduleV4.toml",
+    "steps": []
+}
+`
+	scenario, err = p.ParseScenarioFile([]byte(scenarioJSON))
+	require.Nil(t, err)
+	require.Empty(t, scenario.GasSchedule.Version)
+	require.Equal(t, "gasSchedules/gasScheduleV4.toml", scenario.GasSchedule.Path)
+	require.Contains(t, string(scenario.GasSchedule.Content), "StorePerByte = 50")
+	require.Equal(t, uint64(50), scenario.GasSchedule.Costs["BaseOperationCost"]["StorePerByte"])
+	require.Equal(t, scenarioJSON, mjwrite.ScenarioToJSONString(scenario))
+
+	_, err = mjwrite.DowngradeScenario(scenario, mjwrite.CompatibilityOptions{
+		TargetVersion: mj.FormatVersionStepMetadata,
+	})
+	require.NotNil(t, err)
+
+	_, err = p.ParseScenarioFile([]byte(`{"gasSchedule": "latest", "steps": []}`))
+	require.NotNil(t, err)
+	require.Contains(t, err.Error(), `or the path of a .toml gas config, got "latest"`)
+
+	_, err = p.ParseScenarioFile([]byte(`{"gasSchedule": "missing.toml", "steps": []}`))
+	require.NotNil(t, err)
+	require.Contains(t, err.Error(), "cannot load gas schedule")
+}
+
+func TestParseGasScheduleRejectsInvalidTOML(t *testing.T) {
+	p := mjparse.NewFSParser(fstest.MapFS{
+		"comments.toml":  &fstest.MapFile{Data: []byte("# v4\n[BaseOperationCost]\nStorePerByte = 1_000 # per byte\n\n[MaxPerTransaction]\nMaxBuiltInCallsPerTx = 100\n")},
+		"string.toml":    &fstest.MapFile{Data: []byte("[BaseOperationCost]\nStorePerByte = \"50\"\n")},
+		"negative.toml":  &fstest.MapFile{Data: []byte("[BaseOperationCost]\nStorePerByte = -50\n")},
+		"header.toml":    &fstest.MapFile{Data: []byte("[BaseOperationCost\nStorePerByte = 50\n")},
+		"duplicate.toml": &fstest.MapFile{Data: []byte("[BaseOperationCost]\nStorePerByte = 50\nStorePerByte = 60\n")},
+		"empty.toml":     &fstest.MapFile{Data: []byte("# nothing here\n")},
+	})
+
+	scenario, err := p.ParseScenarioFile([]byte(`{"gasSchedule": "comments.toml", "steps": []}`))
+	require.Nil(t, err)
+	require.Equal(t, map[string]map[string]uint64{
+		"BaseOperationCost": {"StorePerByte": 1000},
+		"MaxPerTransaction": {"MaxBuiltInCallsPerTx": 100},
+	}, scenario.GasSchedule.Costs)
+
+	for path, expectedError := range map[string]string{
+		"string.toml":    `line 2: StorePerByte is not an unsigned integer, got ""50""`,
+		"negative.toml":  `line 2: StorePerByte is not an unsigned integer, got "-50"`,
+		"header.toml":    `line 1: expected a [Table] header or a Key = value entry, got "[BaseOperationCost"`,
+		"duplicate.toml": "line 3: duplicate key StorePerByte",
+		"empty.toml":     "no gas costs",
+	} {
+		_, err = p.ParseScenarioFile([]byte(`{"gasSchedule": "` + path + `", "steps": []}`))
+		require.NotNil(t, err, path)
+		require.Contains(t, err.Error(), "invalid gas schedule "+path+": "+expectedError)
+	}
+}
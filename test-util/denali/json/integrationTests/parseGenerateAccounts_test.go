@@ -0,0 +1,79 @@
+package denalijsontest
+
+import (
+	"errors"
+	"fmt"
+	"math/big"
+	"testing"
+
+	fr "github.com/numbatx/gn-vm-util/test-util/denali/json/fileresolver"
+	mj "github.com/numbatx/gn-vm-util/test-util/denali/json/model"
+	mjparse "github.com/numbatx/gn-vm-util/test-util/denali/json/parse"
+	mjwrite "github.com/numbatx/gn-vm-util/test-util/denali/json/write"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseWriteGenerateAccounts(t *testing.T) {
+	contents, err := loadExampleFile("exampleGenerateAccounts.scen.json")
+	require.Nil(t, err)
+
+	p := mjparse.NewParser(fr.NewDefaultFileResolver())
+	scenario, parseErr := p.ParseScenarioFile(contents)
+	require.Nil(t, parseErr)
+
+	step := scenario.Steps[0].(*mj.SetStateStep)
+	require.Equal(t, 4, len(step.Accounts))
+	require.False(t, step.Accounts[0].Generated)
+	for i, acct := range step.Accounts[1:] {
+		expectedAddress, _ := p.ValueInterpreter.InterpretString(fmt.Sprintf("address:user%d", i))
+		require.Equal(t, expectedAddress, acct.Address.Value)
+		require.Equal(t, big.NewInt(1000), acct.Balance.Value)
+		require.True(t, acct.Generated)
+	}
+
+	serialized := mjwrite.ScenarioToJSONString(scenario)
+	require.Equal(t, string(contents), serialized)
+
+	downgraded, err := mjwrite.DowngradeScenario(scenario, mjwrite.CompatibilityOptions{
+		TargetVersion: mj.FormatVersionABI,
+	})
+	require.Nil(t, err)
+	require.Nil(t, downgraded.Steps[0].(*mj.SetStateStep).GenerateAccounts)
+	require.NotNil(t, step.GenerateAccounts)
+
+	reparsed, parseErr := p.ParseScenarioFile([]byte(mjwrite.ScenarioToJSONString(downgraded)))
+	require.Nil(t, parseErr)
+	require.Equal(t, 4, len(reparsed.Steps[0].(*mj.SetStateStep).Accounts))
+}
+
+func TestGenerateAccountsCollision(t *testing.T) {
+	p := mjparse.NewParser(fr.NewDefaultFileResolver())
+	_, err := p.ParseScenarioFile([]byte(`{
+		"steps": [
+			{
+				"step": "setState",
+				"generateAccounts": {"count": "2", "prefix": "user"},
+				"accounts": {
+					"address:user1": {"nonce": "0", "balance": "0", "storage": {}, "code": ""}
+				}
+			}
+		]
+	}`))
+	require.NotNil(t, err)
+
+	_, err = p.ParseScenarioFile([]byte(`{
+		"steps": [{"step": "setState", "generateAccounts": {"prefix": "user"}}]
+	}`))
+	require.NotNil(t, err)
+}
+
+func TestGenerateAccountsCountLimit(t *testing.T) {
+	p := mjparse.NewParser(fr.NewDefaultFileResolver())
+	_, err := p.ParseScenarioFile([]byte(`{
+		"steps": [{"step": "setState", "generateAccounts": {"count": "18446744073709551615", "prefix": "user"}}]
+	}`))
+	require.NotNil(t, err)
+	var parseErr *mjparse.ParseError
+	require.True(t, errors.As(err, &parseErr))
+	require.Contains(t, err.Error(), "too many generated accounts")
+}
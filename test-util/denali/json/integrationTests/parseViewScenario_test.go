@@ -0,0 +1,57 @@
+package denalijsontest
+
+import (
+	"testing"
+
+	fr "github.com/numbatx/gn-vm-util/test-util/denali/json/fileresolver"
+	mj "github.com/numbatx/gn-vm-util/test-util/denali/json/model"
+	mjparse "github.com/numbatx/gn-vm-util/test-util/denali/json/parse"
+	mjwrite "github.com/numbatx/gn-vm-util/test-util/denali/json/write"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseWriteViewScenario(t *testing.T) {
+	contents, err := loadExampleFile("exampleView.scen.json")
+	require.Nil(t, err)
+
+	p := mjparse.NewParser(fr.NewDefaultFileResolver())
+	scenario, parseErr := p.ParseScenarioFile(contents)
+	require.Nil(t, parseErr)
+	require.True(t, scenario.IsView())
+
+	query := scenario.Steps[1].(*mj.TxStep)
+	require.Equal(t, mj.StepNameScQuery, query.StepTypeName())
+	require.Equal(t, "getSum", query.Tx.Function)
+
+	serialized := mjwrite.ScenarioToJSONString(scenario)
+	require.Equal(t, string(contents), serialized)
+
+	_, err = mjwrite.DowngradeScenario(scenario, mjwrite.CompatibilityOptions{
+		TargetVersion: mj.FormatVersionMetadata,
+	})
+	require.NotNil(t, err)
+}
+
+func TestViewScenarioRestrictions(t *testing.T) {
+	p := mjparse.NewParser(fr.NewDefaultFileResolver())
+	_, err := p.ParseScenarioFile([]byte(`{
+		"metadata": {"flavor": "view"},
+		"steps": [
+			{"step": "transfer", "tx": {"from": "address:a", "to": "address:b", "value": "1"}}
+		]
+	}`))
+	require.NotNil(t, err)
+
+	_, err = p.ParseScenarioFile([]byte(`{"metadata": {"flavor": "unknown"}, "steps": []}`))
+	require.NotNil(t, err)
+
+	_, err = p.ParseScenarioFile([]byte(`{"steps": [
+		{"step": "scQuery", "tx": {"to": "address:a", "function": "f", "arguments": [], "value": "1"}}
+	]}`))
+	require.NotNil(t, err)
+
+	_, err = p.ParseScenarioFile([]byte(`{"steps": [
+		{"step": "scQuery", "tx": {"from": "address:a", "to": "address:a", "function": "f", "arguments": []}}
+	]}`))
+	require.NotNil(t, err)
+}
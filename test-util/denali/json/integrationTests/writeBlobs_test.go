@@ -0,0 +1,108 @@
+package denalijsontest
+
+import (
+	"io/ioutil"
+	"path/filepath"
+	"testing"
+
+	fr "github.com/numbatx/gn-vm-util/test-util/denali/json/fileresolver"
+	mj "github.com/numbatx/gn-vm-util/test-util/denali/json/model"
+	mjparse "github.com/numbatx/gn-vm-util/test-util/denali/json/parse"
+	mjwrite "github.com/numbatx/gn-vm-util/test-util/denali/json/write"
+	oj "github.com/numbatx/gn-vm-util/test-util/orderedjson"
+	"github.com/stretchr/testify/require"
+)
+
+const blobScenario = `{
+	"steps": [
+		{
+			"step": "setState",
+			"accounts": {
+				"address:contract": {
+					"nonce": "0",
+					"balance": "0",
+					"storage": {
+						"str:large": "0x0102030405060708090a",
+						"str:small": "0x0102",
+						"str:text": "str:a readable value"
+					},
+					"code": "0x0061736d01000000"
+				}
+			}
+		},
+		{
+			"step": "scCall",
+			"txId": "call",
+			"tx": {
+				"from": "address:contract",
+				"to": "address:contract",
+				"function": "store",
+				"arguments": ["0x0102030405060708090a", "0x01"],
+				"gasLimit": "0",
+				"gasPrice": "0"
+			}
+		}
+	]
+}`
+
+func originalString(value mj.JSONBytesFromTree) string {
+	return value.Original.(*oj.OJsonString).Value
+}
+
+func TestExternalizeBlobs(t *testing.T) {
+	p := mjparse.NewParser(fr.NewDefaultFileResolver())
+	scenario, err := p.ParseScenarioFile([]byte(blobScenario))
+	require.Nil(t, err)
+
+	dir := t.TempDir()
+	externalized, err := mjwrite.ExternalizeBlobs(scenario, mjwrite.BlobOptions{
+		MinSize:   8,
+		SaveCode:  mjwrite.DirectoryCodeSaver(dir, "blobs"),
+		SaveValue: mjwrite.DirectoryBlobSaver(dir, "blobs"),
+	})
+	require.Nil(t, err)
+
+	account := externalized.Steps[0].(*mj.SetStateStep).Accounts[0]
+	require.Equal(t, "file:blobs/contract.wasm", account.Code.Original)
+	require.Equal(t, "file:blobs/contract-str_large.bin", originalString(account.Storage[0].Value))
+	require.Equal(t, "0x0102", originalString(account.Storage[1].Value))
+	require.Equal(t, "str:a readable value", originalString(account.Storage[2].Value))
+	arguments := externalized.Steps[1].(*mj.TxStep).Tx.Arguments
+	// same value, saved once
+	require.Equal(t, "file:blobs/contract-str_large.bin", originalString(arguments[0]))
+	require.Equal(t, "0x01", originalString(arguments[1]))
+	saved, err := ioutil.ReadFile(filepath.Join(dir, "blobs", "contract-str_large.bin"))
+	require.Nil(t, err)
+	require.Equal(t, []byte{1, 2, 3, 4, 5, 6, 7, 8, 9, 10}, saved)
+
+	// the original is untouched
+	require.Equal(t, "0x0102030405060708090a",
+		originalString(scenario.Steps[0].(*mj.SetStateStep).Accounts[0].Storage[0].Value))
+
+	// the externalized scenario loads the same values
+	resolver := fr.NewDefaultFileResolver()
+	resolver.SetContext(filepath.Join(dir, "test.scen.json"))
+	reparser := mjparse.NewParser(resolver)
+	reparsed, err := reparser.ParseScenarioFile([]byte(mjwrite.ScenarioToJSONString(externalized)))
+	require.Nil(t, err)
+	reparsedAccount := reparsed.Steps[0].(*mj.SetStateStep).Accounts[0]
+	require.Equal(t, saved, reparsedAccount.Storage[0].Value.Value)
+	require.Equal(t, saved, reparsed.Steps[1].(*mj.TxStep).Tx.Arguments[0].Value)
+
+	// and inlining them back yields the original representation
+	inlined := mjwrite.InlineBlobs(reparsed, 1024)
+	require.Equal(t, mjwrite.ScenarioToJSONString(scenario), mjwrite.ScenarioToJSONString(inlined))
+}
+
+func TestExternalizeBlobsRequiresSavers(t *testing.T) {
+	p := mjparse.NewParser(fr.NewDefaultFileResolver())
+	scenario, err := p.ParseScenarioFile([]byte(blobScenario))
+	require.Nil(t, err)
+
+	_, err = mjwrite.ExternalizeBlobs(scenario, mjwrite.BlobOptions{SaveCode: mjwrite.DirectoryCodeSaver(t.TempDir(), "blobs")})
+	require.NotNil(t, err)
+	_, err = mjwrite.ExternalizeBlobs(scenario, mjwrite.BlobOptions{SaveValue: mjwrite.DirectoryBlobSaver(t.TempDir(), "blobs")})
+	require.NotNil(t, err)
+	_, err = mjwrite.ExternalizeCode(scenario, nil)
+	require.NotNil(t, err)
+}
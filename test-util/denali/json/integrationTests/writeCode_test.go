@@ -0,0 +1,110 @@
+package denalijsontest
+
+import (
+	"io/ioutil"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	fr "github.com/numbatx/gn-vm-util/test-util/denali/json/fileresolver"
+	mj "github.com/numbatx/gn-vm-util/test-util/denali/json/model"
+	mjparse "github.com/numbatx/gn-vm-util/test-util/denali/json/parse"
+	mjwrite "github.com/numbatx/gn-vm-util/test-util/denali/json/write"
+	"github.com/stretchr/testify/require"
+)
+
+const inlineCodeScenario = `{
+	"steps": [
+		{
+			"step": "setState",
+			"accounts": {
+				"address:owner": {"nonce": "0", "balance": "0"},
+				"address:contract": {"nonce": "0", "balance": "0", "code": "0x0061736d01000000"},
+				"address:contract-copy": {"nonce": "0", "balance": "0", "code": "0x0061736d01000000"}
+			}
+		},
+		{
+			"step": "scDeploy",
+			"txId": "1",
+			"tx": {"from": "address:owner", "value": "0", "contractCode": "0x0061736d02", "arguments": [], "gasLimit": "0", "gasPrice": "0"}
+		}
+	]
+}`
+
+func TestExternalizeInlineCode(t *testing.T) {
+	p := mjparse.NewParser(fr.NewDefaultFileResolver())
+	scenario, err := p.ParseScenarioFile([]byte(inlineCodeScenario))
+	require.Nil(t, err)
+	require.Equal(t, []byte("\x00asm\x01\x00\x00\x00"), scenario.Steps[0].(*mj.SetStateStep).Accounts[1].Code.Value)
+
+	dir := t.TempDir()
+	externalized, err := mjwrite.ExternalizeCode(scenario, mjwrite.DirectoryCodeSaver(dir, "code"))
+	require.Nil(t, err)
+
+	accounts := externalized.Steps[0].(*mj.SetStateStep).Accounts
+	require.Equal(t, "", accounts[0].Code.Original)
+	require.Equal(t, "file:code/contract.wasm", accounts[1].Code.Original)
+	// same code, saved once
+	require.Equal(t, "file:code/contract.wasm", accounts[2].Code.Original)
+	require.Equal(t, "file:code/deploy-1.wasm", externalized.Steps[1].(*mj.TxStep).Tx.Code.Original)
+	savedCode, err := ioutil.ReadFile(filepath.Join(dir, "code", "contract.wasm"))
+	require.Nil(t, err)
+	require.Equal(t, []byte("\x00asm\x01\x00\x00\x00"), savedCode)
+
+	// the original is untouched
+	require.Equal(t, "0x0061736d01000000", scenario.Steps[0].(*mj.SetStateStep).Accounts[1].Code.Original)
+
+	// the externalized scenario loads the same code
+	resolver := fr.NewDefaultFileResolver()
+	resolver.SetContext(filepath.Join(dir, "test.scen.json"))
+	reparser := mjparse.NewParser(resolver)
+	reparsed, err := reparser.ParseScenarioFile([]byte(mjwrite.ScenarioToJSONString(externalized)))
+	require.Nil(t, err)
+	require.Equal(t, savedCode, reparsed.Steps[0].(*mj.SetStateStep).Accounts[1].Code.Value)
+	require.Equal(t, []byte("\x00asm\x02"), reparsed.Steps[1].(*mj.TxStep).Tx.Code.Value)
+
+	// and inlining it back yields the original representation, since it was written as hex
+	inlined := mjwrite.InlineCode(reparsed, 1024)
+	require.Equal(t, "0x0061736d01000000", inlined.Steps[0].(*mj.SetStateStep).Accounts[1].Code.Original)
+	require.Equal(t, "0x0061736d02", inlined.Steps[1].(*mj.TxStep).Tx.Code.Original)
+}
+
+func TestInlineSmallCodeFiles(t *testing.T) {
+	contents, err := loadExampleFile("example.scen.json")
+	require.Nil(t, err)
+	p := mjparse.NewParser(
+		fr.NewDefaultFileResolver().ReplacePath(
+			"smart-contract.wasm",
+			"exampleFile.txt"))
+	scenario, err := p.ParseScenarioFile(contents)
+	require.Nil(t, err)
+	fileContents, err := ioutil.ReadFile("exampleFile.txt")
+	require.Nil(t, err)
+
+	inlined := mjwrite.ScenarioToJSONString(mjwrite.InlineCode(scenario, len(fileContents)))
+	require.False(t, strings.Contains(inlined, "file:smart-contract.wasm"))
+
+	tooSmall := mjwrite.ScenarioToJSONString(mjwrite.InlineCode(scenario, len(fileContents)-1))
+	require.Equal(t, string(contents), tooSmall)
+}
+
+func TestPlaceholderCode(t *testing.T) {
+	p := mjparse.NewParser(fr.NewDefaultFileResolver())
+	scenario, err := p.ParseScenarioFile([]byte(inlineCodeScenario))
+	require.Nil(t, err)
+
+	placeheld, replacements := mjwrite.PlaceholderCode(scenario)
+	accounts := placeheld.Steps[0].(*mj.SetStateStep).Accounts
+	require.Equal(t, "", accounts[0].Code.Original)
+	require.Equal(t, "str:code1", accounts[1].Code.Original)
+	// same code, same placeholder
+	require.Equal(t, "str:code1", accounts[2].Code.Original)
+	require.Equal(t, "str:code2", placeheld.Steps[1].(*mj.TxStep).Tx.Code.Original)
+	require.Equal(t, map[string]string{
+		"0x0061736d01000000": "str:code1",
+		"0x0061736d02":       "str:code2",
+	}, replacements)
+
+	// the original is untouched
+	require.Equal(t, "0x0061736d01000000", scenario.Steps[0].(*mj.SetStateStep).Accounts[1].Code.Original)
+}
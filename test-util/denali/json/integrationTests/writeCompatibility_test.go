@@ -0,0 +1,438 @@
+package denalijsontest
+
+import (
+	"testing"
+
+	fr "github.com/numbatx/gn-vm-util/test-util/denali/json/fileresolver"
+	mj "github.com/numbatx/gn-vm-util/test-util/denali/json/model"
+	mjparse "github.com/numbatx/gn-vm-util/test-util/denali/json/parse"
+	mjwrite "github.com/numbatx/gn-vm-util/test-util/denali/json/write"
+	"github.com/stretchr/testify/require"
+)
+
+func TestDowngradeScenarioInlinesExternalSteps(t *testing.T) {
+	contents, err := loadExampleFile("example.scen.json")
+	require.Nil(t, err)
+
+	p := mjparse.NewParser(
+		fr.NewDefaultFileResolver().ReplacePath(
+			"smart-contract.wasm",
+			"exampleFile.txt"))
+
+	scenario, parseErr := p.ParseScenarioFile(contents)
+	require.Nil(t, parseErr)
+
+	loader := func(path string) (*mj.Scenario, error) {
+		require.Equal(t, "other.scen.json", path)
+		return &mj.Scenario{
+			Steps: []mj.Step{&mj.DumpStateStep{StepMetadata: mj.StepMetadata{Comment: "from other"}}},
+		}, nil
+	}
+
+	downgraded, err := mjwrite.DowngradeScenario(scenario, mjwrite.CompatibilityOptions{
+		TargetVersion:     mj.FormatVersionInitial,
+		LoadExternalSteps: loader,
+	})
+	require.Nil(t, err)
+	require.Equal(t, len(scenario.Steps), len(downgraded.Steps))
+	require.Equal(t, mj.StepNameDumpState, downgraded.Steps[0].StepTypeName())
+	require.Equal(t, mj.StepNameExternalSteps, scenario.Steps[0].StepTypeName())
+
+	_, err = mjwrite.DowngradeScenario(scenario, mjwrite.CompatibilityOptions{
+		TargetVersion: mj.FormatVersionInitial,
+	})
+	require.NotNil(t, err)
+
+	unchanged, err := mjwrite.DowngradeScenario(scenario, mjwrite.CompatibilityOptions{
+		TargetVersion: mj.CurrentFormatVersion,
+	})
+	require.Nil(t, err)
+	require.Equal(t, scenario.Steps, unchanged.Steps)
+}
+
+func TestDowngradeDropsPriority(t *testing.T) {
+	p := mjparse.NewParser(fr.NewDefaultFileResolver())
+	scenarioJSON := `{
+    "metadata": {
+        "owner": "vm-team",
+        "priority": "5"
+    },
+    "steps": []
+}
+`
+	scenario, err := p.ParseScenarioFile([]byte(scenarioJSON))
+	require.Nil(t, err)
+	require.Equal(t, uint64(5), scenario.Metadata.Priority.Value)
+	require.Equal(t, scenarioJSON, mjwrite.ScenarioToJSONString(scenario))
+
+	downgraded, err := mjwrite.DowngradeScenario(scenario, mjwrite.CompatibilityOptions{
+		TargetVersion: mj.FormatVersionAutoNonces,
+	})
+	require.Nil(t, err)
+	require.Equal(t, "vm-team", downgraded.Metadata.Owner)
+	require.Empty(t, downgraded.Metadata.Priority.Original)
+	require.Equal(t, "5", scenario.Metadata.Priority.Original)
+}
+
+func TestTransferData(t *testing.T) {
+	p := mjparse.NewParser(fr.NewDefaultFileResolver())
+	scenarioJSON := `{
+    "steps": [
+        {
+            "step": "transfer",
+            "txId": "1",
+            "tx": {
+                "from": "address:alice",
+                "to": "address:bob",
+                "value": "1000",
+                "data": "str:rent for march"
+            }
+        }
+    ]
+}
+`
+	scenario, err := p.ParseScenarioFile([]byte(scenarioJSON))
+	require.Nil(t, err)
+	tx := scenario.Steps[0].(*mj.TxStep).Tx
+	require.Equal(t, mj.Transfer, tx.Type)
+	require.Equal(t, []byte("rent for march"), tx.Data.Value)
+	require.Equal(t, scenarioJSON, mjwrite.ScenarioToJSONString(scenario))
+
+	_, err = mjwrite.DowngradeScenario(scenario, mjwrite.CompatibilityOptions{
+		TargetVersion: mj.FormatVersionPriority,
+	})
+	require.NotNil(t, err)
+
+	_, err = p.ParseScenarioFile([]byte(`{
+    "steps": [
+        {
+            "step": "scCall",
+            "txId": "1",
+            "tx": {
+                "from": "address:alice",
+                "to": "address:bob",
+                "function": "f",
+                "data": "str:not here"
+            }
+        }
+    ]
+}`))
+	require.NotNil(t, err)
+}
+
+func TestSetStateAllowedErrors(t *testing.T) {
+	p := mjparse.NewParser(fr.NewDefaultFileResolver())
+	scenarioJSON := `{
+    "steps": [
+        {
+            "step": "setState",
+            "blockHashes": [
+                "0x01"
+            ],
+            "allowedErrors": [
+                "createAccount",
+                "blockHashes"
+            ]
+        }
+    ]
+}
+`
+	scenario, err := p.ParseScenarioFile([]byte(scenarioJSON))
+	require.Nil(t, err)
+	step := scenario.Steps[0].(*mj.SetStateStep)
+	require.True(t, step.IsErrorAllowed(mj.SetStateOperationBlockHashes))
+	require.False(t, step.IsErrorAllowed(mj.SetStateOperationBlockInfo))
+	require.Equal(t, scenarioJSON, mjwrite.ScenarioToJSONString(scenario))
+
+	_, err = mjwrite.DowngradeScenario(scenario, mjwrite.CompatibilityOptions{
+		TargetVersion: mj.FormatVersionTransferData,
+	})
+	require.NotNil(t, err)
+
+	_, err = p.ParseScenarioFile([]byte(`{
+    "steps": [
+        {
+            "step": "setState",
+            "allowedErrors": ["createAcount"]
+        }
+    ]
+}`))
+	require.NotNil(t, err)
+	require.Contains(t, err.Error(), "unknown setState operation: createAcount")
+}
+
+func TestInvariants(t *testing.T) {
+	p := mjparse.NewParser(fr.NewDefaultFileResolver())
+	scenarioJSON := `{
+    "invariants": {
+        "supply": {
+            "comment": "transfers only move tokens around",
+            "constantSum": [
+                {
+                    "account": "address:alice",
+                    "field": "balance"
+                },
+                {
+                    "account": "address:bob",
+                    "field": "balance"
+                }
+            ]
+        },
+        "cap": {
+            "value": {
+                "account": "address:contract",
+                "field": "storage",
+                "key": "str:total"
+            },
+            "expect": "match:range:..1000"
+        }
+    },
+    "steps": []
+}
+`
+	scenario, err := p.ParseScenarioFile([]byte(scenarioJSON))
+	require.Nil(t, err)
+	require.Equal(t, 2, len(scenario.Invariants))
+	require.True(t, scenario.Invariants[0].IsConstantSum())
+	require.Equal(t, mj.InvariantFieldStorage, scenario.Invariants[1].Value.Field)
+	require.Equal(t, scenarioJSON, mjwrite.ScenarioToJSONString(scenario))
+
+	_, err = mjwrite.DowngradeScenario(scenario, mjwrite.CompatibilityOptions{
+		TargetVersion: mj.FormatVersionAllowedErrors,
+	})
+	require.NotNil(t, err)
+
+	for _, invalid := range []string{
+		`{"value": {"account": "address:a", "field": "balance"}}`,
+		`{"value": {"account": "address:a", "field": "storage"}, "expect": "5"}`,
+		`{"value": {"account": "address:a", "field": "code"}, "expect": "5"}`,
+		`{"constantSum": [{"account": "address:a", "field": "nonce"}], "expect": "5"}`,
+		`{"comment": "nothing to check"}`,
+	} {
+		_, err = p.ParseScenarioFile([]byte(`{"invariants": {"i": ` + invalid + `}, "steps": []}`))
+		require.NotNil(t, err, invalid)
+	}
+}
+
+func TestDefines(t *testing.T) {
+	p := mjparse.NewParser(fr.NewDefaultFileResolver())
+	scenarioJSON := `{
+    "defines": {
+        "alice": "address:alice",
+        "fee": "percent:2.5"
+    },
+    "steps": [
+        {
+            "step": "transfer",
+            "txId": "1",
+            "tx": {
+                "from": "$alice",
+                "to": "address:bob",
+                "value": "$fee"
+            }
+        }
+    ]
+}
+`
+	scenario, err := p.ParseScenarioFile([]byte(scenarioJSON))
+	require.Nil(t, err)
+	require.Equal(t, scenarioJSON, mjwrite.ScenarioToJSONString(scenario))
+
+	_, err = mjwrite.DowngradeScenario(scenario, mjwrite.CompatibilityOptions{
+		TargetVersion: mj.FormatVersionInvariants,
+	})
+	require.NotNil(t, err)
+}
+
+func TestStepParameters(t *testing.T) {
+	p := mjparse.NewParser(fr.NewDefaultFileResolver())
+	includingJSON := `{
+    "steps": [
+        {
+            "step": "externalSteps",
+            "path": "deploy.steps.json",
+            "arguments": {
+                "owner": "address:owner",
+                "supply": "1,000,000"
+            }
+        }
+    ]
+}
+`
+	includingScenario, err := p.ParseScenarioFile([]byte(includingJSON))
+	require.Nil(t, err)
+	require.Equal(t, includingJSON, mjwrite.ScenarioToJSONString(includingScenario))
+
+	libraryJSON := `{
+    "parameters": [
+        "owner",
+        "supply"
+    ],
+    "steps": [
+        {
+            "step": "transfer",
+            "txId": "1",
+            "tx": {
+                "from": "$owner",
+                "to": "address:bob",
+                "value": "$supply"
+            }
+        }
+    ]
+}
+`
+	arguments := includingScenario.Steps[0].(*mj.ExternalStepsStep).Arguments
+	library, err := p.ParseScenarioFileWithArguments([]byte(libraryJSON), arguments)
+	require.Nil(t, err)
+	require.Equal(t, libraryJSON, mjwrite.ScenarioToJSONString(library))
+
+	for _, scenario := range []*mj.Scenario{includingScenario, library} {
+		_, err = mjwrite.DowngradeScenario(scenario, mjwrite.CompatibilityOptions{
+			TargetVersion: mj.FormatVersionComments,
+		})
+		require.NotNil(t, err)
+	}
+}
+
+func TestStepMetadata(t *testing.T) {
+	p := mjparse.NewParser(fr.NewDefaultFileResolver())
+	scenarioJSON := `{
+    "steps": [
+        {
+            "step": "externalSteps",
+            "id": "init",
+            "comment": "deploys the adder",
+            "path": "other.scen.json"
+        },
+        {
+            "step": "dumpState",
+            "id": "after-init",
+            "tags": [
+                "debug"
+            ],
+            "comment": "for inspection"
+        },
+        {
+            "step": "checkState",
+            "tags": [
+                "slow",
+                "storage"
+            ],
+            "accounts": {}
+        }
+    ]
+}
+`
+	scenario, err := p.ParseScenarioFile([]byte(scenarioJSON))
+	require.Nil(t, err)
+	require.Equal(t, "deploys the adder", scenario.Steps[0].(*mj.ExternalStepsStep).Comment)
+	require.True(t, mj.StepMetadataOf(scenario.Steps[1]).HasTag("debug"))
+	require.False(t, mj.StepMetadataOf(scenario.Steps[2]).HasTag("debug"))
+	require.Equal(t, "step 1 (after-init)", mj.DescribeStep(1, scenario.Steps[1]))
+	require.Equal(t, "step 2", mj.DescribeStep(2, scenario.Steps[2]))
+	require.Equal(t, scenarioJSON, mjwrite.ScenarioToJSONString(scenario))
+
+	downgraded, err := mjwrite.DowngradeScenario(scenario, mjwrite.CompatibilityOptions{
+		TargetVersion: mj.FormatVersionSchemaVersion,
+	})
+	require.Nil(t, err)
+	require.Equal(t, `{
+    "steps": [
+        {
+            "step": "externalSteps",
+            "path": "other.scen.json"
+        },
+        {
+            "step": "dumpState",
+            "comment": "for inspection"
+        },
+        {
+            "step": "checkState",
+            "accounts": {}
+        }
+    ]
+}
+`, mjwrite.ScenarioToJSONString(downgraded))
+	require.Equal(t, "init", mj.StepMetadataOf(scenario.Steps[0]).ID)
+}
+
+func TestScenarioConfig(t *testing.T) {
+	p := mjparse.NewParser(fr.NewDefaultFileResolver())
+	scenarioJSON := `{
+    "config": {
+        "gasPrice": "10",
+        "caller": "address:owner"
+    },
+    "steps": [
+        {
+            "step": "scCall",
+            "tx": {
+                "to": "address:adder",
+                "function": "add",
+                "arguments": [],
+                "gasLimit": "5,000,000"
+            }
+        }
+    ]
+}
+`
+	scenario, err := p.ParseScenarioFile([]byte(scenarioJSON))
+	require.Nil(t, err)
+
+	downgraded, err := mjwrite.DowngradeScenario(scenario, mjwrite.CompatibilityOptions{
+		TargetVersion: mj.FormatVersionGasSchedule,
+	})
+	require.Nil(t, err)
+	require.Equal(t, `{
+    "steps": [
+        {
+            "step": "scCall",
+            "tx": {
+                "from": "address:owner",
+                "to": "address:adder",
+                "value": "",
+                "function": "add",
+                "arguments": [],
+                "gasLimit": "5,000,000",
+                "gasPrice": "10"
+            }
+        }
+    ]
+}
+`, mjwrite.ScenarioToJSONString(downgraded))
+
+	scenario.Config.Seed = "config"
+	_, err = mjwrite.DowngradeScenario(scenario, mjwrite.CompatibilityOptions{
+		TargetVersion: mj.FormatVersionGasSchedule,
+	})
+	require.NotNil(t, err)
+}
+
+func TestDowngradeNewerValueSyntax(t *testing.T) {
+	p := mjparse.NewParser(fr.NewDefaultFileResolver())
+	downgrade := func(scenarioJSON string, targetVersion mj.FormatVersion) error {
+		scenario, err := p.ParseScenarioFile([]byte(scenarioJSON))
+		require.Nil(t, err)
+		_, err = mjwrite.DowngradeScenario(scenario, mjwrite.CompatibilityOptions{TargetVersion: targetVersion})
+		return err
+	}
+
+	tokenJSON := `{"steps": [{"step": "setState", "accounts": {"address:owner": {"balance": "0", "storage": {"str:ticker": "token:WEGLD"}}}}]}`
+	require.Nil(t, downgrade(tokenJSON, mj.FormatVersionTokens))
+	err := downgrade(tokenJSON, mj.FormatVersionAddressShard)
+	require.NotNil(t, err)
+	require.Contains(t, err.Error(), "values using token: cannot be expressed in format version 22, they need format version 23")
+
+	for _, checkJSON := range []string{
+		`{"nonce": "any"}`,
+		`{"storage": {"str:key": "*"}}`,
+		`{"balance": "match:range:1..100"}`,
+	} {
+		scenarioJSON := `{"steps": [{"step": "checkState", "accounts": {"address:owner": ` + checkJSON + `, "+": ""}}]}`
+		require.NotNil(t, downgrade(scenarioJSON, mj.FormatVersionConfig), checkJSON)
+	}
+
+	// "*" for entire fields is as old as the format
+	require.Nil(t, downgrade(`{"steps": [{"step": "checkState", "accounts": {"address:owner": {"nonce": "*", "storage": "*"}, "+": ""}}]}`,
+		mj.FormatVersionInitial))
+}
@@ -0,0 +1,100 @@
+package denalijsontest
+
+import (
+	"go/ast"
+	"go/importer"
+	"go/parser"
+	"go/token"
+	"go/types"
+	"testing"
+
+	fr "github.com/numbatx/gn-vm-util/test-util/denali/json/fileresolver"
+	mjparse "github.com/numbatx/gn-vm-util/test-util/denali/json/parse"
+	mjwrite "github.com/numbatx/gn-vm-util/test-util/denali/json/write"
+	"github.com/stretchr/testify/require"
+)
+
+const goTestScenario = `{
+	"name": "deposit twice",
+	"steps": [
+		{
+			"step": "setState",
+			"accounts": {
+				"address:owner": {"nonce": "0", "balance": "1,000,000,000,000,000,000,000"},
+				"address:bank": {"nonce": "0", "balance": "0", "storage": {"str:total": "0"}}
+			}
+		},
+		{
+			"step": "scCall",
+			"txId": "deposit",
+			"tx": {"from": "address:owner", "to": "address:bank", "value": "5", "function": "deposit", "arguments": ["u32:2"], "gasLimit": "5,000,000", "gasPrice": "0"},
+			"expect": {"out": [], "status": "0", "gas": "*", "refund": "*"}
+		},
+		{
+			"step": "checkState",
+			"accounts": {
+				"address:bank": {"nonce": "*", "balance": "5", "storage": {"str:total": "5"}, "code": "*"},
+				"+": ""
+			}
+		}
+	]
+}`
+
+// goTestRunnerStub stands in for the runner of the test package, so that the generated code typechecks on its own.
+const goTestRunnerStub = `
+type stubRunner struct{}
+
+func (stubRunner) RunScenario(contextPath string, scenario *mj.Scenario) error { return nil }
+
+func newScenarioRunner(t *testing.T) stubRunner { return stubRunner{} }
+`
+
+func TestScenarioToGoTest(t *testing.T) {
+	p := mjparse.NewParser(fr.NewDefaultFileResolver())
+	scenario, err := p.ParseScenarioFile([]byte(goTestScenario))
+	require.Nil(t, err)
+
+	code, err := mjwrite.ScenarioToGoTest(scenario, mjwrite.GoTestOptions{ScenarioPath: "deposit.scen.json"})
+	require.Nil(t, err)
+	require.Contains(t, code, "// Code generated from deposit.scen.json.")
+	require.Contains(t, code, "package scenarios\n")
+	require.Contains(t, code, "func TestDepositTwice(t *testing.T) {")
+	require.Contains(t, code, "mj.ScCall")
+	require.Contains(t, code, `newScenarioRunner(t).RunScenario("deposit.scen.json", scenario)`)
+
+	fset := token.NewFileSet()
+	file, err := parser.ParseFile(fset, "deposit_test.go", code+goTestRunnerStub, 0)
+	require.Nil(t, err)
+	config := types.Config{Importer: importer.ForCompiler(fset, "source", nil)}
+	_, err = config.Check("scenarios", fset, []*ast.File{file}, nil)
+	require.Nil(t, err)
+}
+
+func TestScenarioToGoTestOptions(t *testing.T) {
+	p := mjparse.NewParser(fr.NewDefaultFileResolver())
+	scenario, err := p.ParseScenarioFile([]byte(`{"steps": [{"step": "setState", "accounts": {}}]}`))
+	require.Nil(t, err)
+
+	code, err := mjwrite.ScenarioToGoTest(scenario, mjwrite.GoTestOptions{
+		PackageName:    "bank",
+		TestName:       "TestEmpty",
+		RunnerFunction: "bankRunner",
+	})
+	require.Nil(t, err)
+	require.NotContains(t, code, "Code generated")
+	require.Contains(t, code, "package bank\n")
+	require.Contains(t, code, "func TestEmpty(t *testing.T) {")
+	require.Contains(t, code, `bankRunner(t).RunScenario("", scenario)`)
+}
+
+func TestScenarioToGoTestRejectsMatchChecks(t *testing.T) {
+	p := mjparse.NewParser(fr.NewDefaultFileResolver())
+	scenario, err := p.ParseScenarioFile([]byte(`{"steps": [{
+		"step": "checkState",
+		"accounts": {"address:owner": {"storage": {"str:total": "match:range:..1000"}}, "+": ""}
+	}]}`))
+	require.Nil(t, err)
+
+	_, err = mjwrite.ScenarioToGoTest(scenario, mjwrite.GoTestOptions{})
+	require.NotNil(t, err)
+}
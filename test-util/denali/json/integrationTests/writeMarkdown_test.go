@@ -0,0 +1,80 @@
+package denalijsontest
+
+import (
+	"testing"
+
+	fr "github.com/numbatx/gn-vm-util/test-util/denali/json/fileresolver"
+	mjparse "github.com/numbatx/gn-vm-util/test-util/denali/json/parse"
+	mjwrite "github.com/numbatx/gn-vm-util/test-util/denali/json/write"
+	"github.com/stretchr/testify/require"
+)
+
+func TestWriteMarkdown(t *testing.T) {
+	p := mjparse.NewParser(fr.NewDefaultFileResolver())
+	scenario, err := p.ParseScenarioFile([]byte(`{
+	"name": "deposit",
+	"comment": "a deposit, then a withdrawal",
+	"steps": [
+		{
+			"step": "setState",
+			"accounts": {
+				"address:owner": {"nonce": "0", "balance": "1000"},
+				"0x7661756c745f5f5f5f5f5f5f5f5f5f5f5f5f5f5f5f5f5f5f5f5f5f5f5f5f5f5f": {"nonce": "0", "balance": "0"}
+			}
+		},
+		{
+			"step": "scCall",
+			"id": "deposit",
+			"tx": {
+				"from": "address:owner",
+				"to": "address:vault",
+				"function": "deposit",
+				"arguments": ["0x746f6b656e", "5"],
+				"value": "100",
+				"gasLimit": "5000000",
+				"gasPrice": "0"
+			},
+			"expect": {"out": ["0x64"], "status": "0"}
+		},
+		{
+			"step": "transfer",
+			"tx": {"from": "address:owner", "to": "address:vault", "value": "50"}
+		},
+		{
+			"step": "checkState",
+			"accounts": {
+				"address:owner": {"balance": "850"},
+				"address:vault": {"balance": "*"}
+			}
+		}
+	]
+}`))
+	require.Nil(t, err)
+
+	require.Equal(t, "# deposit\n"+
+		"\n"+
+		"a deposit, then a withdrawal\n"+
+		"\n"+
+		"## Steps\n"+
+		"\n"+
+		"| # | Step | Id | Description |\n"+
+		"| --- | --- | --- | --- |\n"+
+		"| 0 | setState |  | sets 2 accounts |\n"+
+		"| 1 | scCall | deposit | `address:owner` calls `deposit(str:token, 5)` on `address:vault`, with value 100 |\n"+
+		"| 2 | transfer |  | `address:owner` sends 50 to `address:vault` |\n"+
+		"| 3 | checkState |  | checks 2 accounts |\n"+
+		"\n"+
+		"## Expected results\n"+
+		"\n"+
+		"| # | Id | Status | Message | Out |\n"+
+		"| --- | --- | --- | --- | --- |\n"+
+		"| 1 | deposit | 0 |  | 100 |\n"+
+		"\n"+
+		"## Balances\n"+
+		"\n"+
+		"| Account | Before | After |\n"+
+		"| --- | --- | --- |\n"+
+		"| `address:owner` | 1000 | 850 |\n"+
+		"| `address:vault` | 0 | * |\n",
+		mjwrite.ScenarioToMarkdown(scenario))
+}
@@ -0,0 +1,219 @@
+package denalijsontest
+
+import (
+	"testing"
+
+	fr "github.com/numbatx/gn-vm-util/test-util/denali/json/fileresolver"
+	mj "github.com/numbatx/gn-vm-util/test-util/denali/json/model"
+	mjparse "github.com/numbatx/gn-vm-util/test-util/denali/json/parse"
+	mjwrite "github.com/numbatx/gn-vm-util/test-util/denali/json/write"
+	"github.com/stretchr/testify/require"
+)
+
+const writeOptionsScenario = `{
+    "name": "options",
+    "steps": [
+        {
+            "step": "setState",
+            "accounts": {
+                "address:owner": {
+                    "nonce": "0",
+                    "balance": "0",
+                    // the counter
+                    "storage": {
+                        "str:count": "1"
+                    },
+                    "code": ""
+                }
+            }
+        },
+        {
+            "step": "scQuery",
+            "tx": {
+                "to": "address:owner",
+                "function": "get",
+                "arguments": [
+                    "1",
+                    "2"
+                ]
+            },
+            "expect": {
+                "out": [
+                    "str:a very long value that does not fit on the line of the expected output"
+                ]
+            }
+        }
+    ]
+}
+`
+
+func TestWriteWithDefaultOptions(t *testing.T) {
+	p := mjparse.NewParser(fr.NewDefaultFileResolver())
+	scenario, err := p.ParseScenarioFile([]byte(writeOptionsScenario))
+	require.Nil(t, err)
+
+	require.Equal(t, mjwrite.ScenarioToJSONString(scenario),
+		mjwrite.ScenarioToJSONStringWithOptions(scenario, mjwrite.WriterOptions{}))
+	require.Equal(t, mjwrite.ScenarioToJSONString(scenario),
+		mjwrite.ScenarioToJSONStringWithOptions(scenario, mjwrite.DefaultWriterOptions))
+}
+
+func TestWriteWithOptions(t *testing.T) {
+	p := mjparse.NewParser(fr.NewDefaultFileResolver())
+	scenario, err := p.ParseScenarioFile([]byte(writeOptionsScenario))
+	require.Nil(t, err)
+
+	written := mjwrite.ScenarioToJSONStringWithOptions(scenario, mjwrite.WriterOptions{
+		IndentWidth:            2,
+		MaxLineLength:          60,
+		KeyOrder:               mjwrite.KeyOrderAlphabetical,
+		CompactSingleValueMaps: true,
+	})
+	require.Equal(t, `{
+  "name": "options",
+  "steps": [
+    {
+      "accounts": {
+        "address:owner": {
+          "balance": "0",
+          "code": "",
+          "nonce": "0",
+          // the counter
+          "storage": {"str:count": "1"}
+        }
+      },
+      "step": "setState"
+    },
+    {
+      "expect": {
+        "logs": [],
+        "out": [
+          "str:a very long value that does not fit on the line of the expected output"
+        ]
+      },
+      "step": "scQuery",
+      "tx": {
+        "arguments": ["1", "2"],
+        "function": "get",
+        "to": "address:owner"
+      }
+    }
+  ]
+}
+`, written)
+
+	reparsed, err := p.ParseScenarioFile([]byte(written))
+	require.Nil(t, err)
+	require.Equal(t, written, mjwrite.ScenarioToJSONStringWithOptions(reparsed, mjwrite.WriterOptions{
+		IndentWidth:            2,
+		MaxLineLength:          60,
+		KeyOrder:               mjwrite.KeyOrderAlphabetical,
+		CompactSingleValueMaps: true,
+	}))
+}
+
+func TestWriteReadableValues(t *testing.T) {
+	p := mjparse.NewParser(fr.NewDefaultFileResolver())
+	scenarioJSON := `{
+    "steps": [
+        {
+            "step": "setState",
+            "accounts": {
+                // the owner
+                "0x6f776e65725f5f5f5f5f5f5f5f5f5f5f5f5f5f5f5f5f5f5f5f5f5f5f5f5f5f5f": {
+                    "nonce": "0x05",
+                    "balance": "0x0de0b6b3a7640000",
+                    "storage": {
+                        "0x636f756e74": "0x2a",
+                        "str:raw": "0x0005"
+                    },
+                    "code": ""
+                }
+            }
+        },
+        {
+            "step": "scCall",
+            "txId": "0x01",
+            "tx": {
+                "from": "0x6f776e65725f5f5f5f5f5f5f5f5f5f5f5f5f5f5f5f5f5f5f5f5f5f5f5f5f5f5f",
+                "to": "address:adder",
+                "value": "0",
+                "function": "add",
+                "arguments": [
+                    "0x",
+                    "0x68656c6c6f",
+                    "u32:7"
+                ],
+                "gasLimit": "0x989680",
+                "gasPrice": "0"
+            },
+            "expect": {
+                "out": [],
+                "status": "0x04",
+                "logs": []
+            }
+        }
+    ]
+}
+`
+	scenario, err := p.ParseScenarioFile([]byte(scenarioJSON))
+	require.Nil(t, err)
+
+	readable := mjwrite.ScenarioToJSONStringWithOptions(scenario, mjwrite.WriterOptions{ReadableValues: true})
+	require.Equal(t, `{
+    "steps": [
+        {
+            "step": "setState",
+            "accounts": {
+                // the owner
+                "address:owner": {
+                    "nonce": "5",
+                    "balance": "1000000000000000000",
+                    "storage": {
+                        "str:count": "42",
+                        "str:raw": "0x0005"
+                    },
+                    "code": ""
+                }
+            }
+        },
+        {
+            "step": "scCall",
+            "txId": "0x01",
+            "tx": {
+                "from": "address:owner",
+                "to": "address:adder",
+                "value": "0",
+                "function": "add",
+                "arguments": [
+                    "",
+                    "str:hello",
+                    "u32:7"
+                ],
+                "gasLimit": "10000000",
+                "gasPrice": "0"
+            },
+            "expect": {
+                "out": [],
+                "status": "4",
+                "logs": []
+            }
+        }
+    ]
+}
+`, readable)
+
+	// the scenario itself is unchanged
+	require.Equal(t, scenarioJSON, mjwrite.ScenarioToJSONString(scenario))
+
+	reparsed, err := p.ParseScenarioFile([]byte(readable))
+	require.Nil(t, err)
+	setState, reparsedSetState := scenario.Steps[0].(*mj.SetStateStep), reparsed.Steps[0].(*mj.SetStateStep)
+	require.Equal(t, setState.Accounts[0].Address.Value, reparsedSetState.Accounts[0].Address.Value)
+	require.Equal(t, setState.Accounts[0].Storage[0].Key.Value, reparsedSetState.Accounts[0].Storage[0].Key.Value)
+	require.Equal(t, setState.Accounts[0].Storage[0].Value.Value, reparsedSetState.Accounts[0].Storage[0].Value.Value)
+	call, reparsedCall := scenario.Steps[1].(*mj.TxStep), reparsed.Steps[1].(*mj.TxStep)
+	for i := range call.Tx.Arguments {
+		require.Equal(t, call.Tx.Arguments[i].Value, reparsedCall.Tx.Arguments[i].Value)
+	}
+}
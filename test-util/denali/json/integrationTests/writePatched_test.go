@@ -0,0 +1,87 @@
+package denalijsontest
+
+import (
+	"testing"
+
+	fr "github.com/numbatx/gn-vm-util/test-util/denali/json/fileresolver"
+	mj "github.com/numbatx/gn-vm-util/test-util/denali/json/model"
+	mjparse "github.com/numbatx/gn-vm-util/test-util/denali/json/parse"
+	mjwrite "github.com/numbatx/gn-vm-util/test-util/denali/json/write"
+	"github.com/stretchr/testify/require"
+)
+
+// laid out by hand, unlike the writer would
+const patchedScenario = `{
+  "name": "patched",
+  "steps": [
+    {
+      "step": "setState",
+      "accounts": {
+        "address:a": { "nonce": "0", "balance": "1000", "storage": {}, "code": "" },
+        // to be removed
+        "address:unused": { "nonce": "0", "balance": "5", "storage": {}, "code": "" },
+        "address:b": { "nonce": "0", "balance": "1000", "storage": {}, "code": "" }
+      }
+    },
+    /* the payment */
+    {
+      "tx": { "value": "10", "to": "address:b", "from": "address:a" },
+      "step": "transfer",
+      "id": "pay"
+    },
+    { "step": "checkState", "accounts": { "address:b": { "balance": "1010", "storage": "*" } } }
+  ]
+}
+`
+
+func TestWritePatchedUnchanged(t *testing.T) {
+	p := mjparse.NewParser(fr.NewDefaultFileResolver())
+	scenario, err := p.ParseScenarioFile([]byte(patchedScenario))
+	require.Nil(t, err)
+
+	patched, err := mjwrite.ScenarioToJSONStringPatched([]byte(patchedScenario), scenario, mjwrite.DefaultWriterOptions)
+	require.Nil(t, err)
+	require.Equal(t, patchedScenario, patched)
+}
+
+func TestWritePatched(t *testing.T) {
+	p := mjparse.NewParser(fr.NewDefaultFileResolver())
+	scenario, err := p.ParseScenarioFile([]byte(patchedScenario))
+	require.Nil(t, err)
+
+	setState := scenario.Steps[0].(*mj.SetStateStep)
+	setState.Accounts = []*mj.Account{setState.Accounts[0], setState.Accounts[2]}
+	transfer := scenario.Steps[1].(*mj.TxStep)
+	transfer.Tx.Value = mj.JSONBigInt{Original: "20"}
+	transfer.Tx.GasLimit = mj.JSONUint64{Value: 5, Original: "5"}
+	scenario.Steps = append(scenario.Steps, &mj.DumpStateStep{})
+
+	patched, err := mjwrite.ScenarioToJSONStringPatched([]byte(patchedScenario), scenario, mjwrite.DefaultWriterOptions)
+	require.Nil(t, err)
+	require.Equal(t, `{
+  "name": "patched",
+  "steps": [
+    {
+      "step": "setState",
+      "accounts": {
+        "address:a": { "nonce": "0", "balance": "1000", "storage": {}, "code": "" },
+        "address:b": { "nonce": "0", "balance": "1000", "storage": {}, "code": "" }
+      }
+    },
+    /* the payment */
+    {
+      "tx": { "value": "20", "to": "address:b", "from": "address:a", "gasLimit": "5" },
+      "step": "transfer",
+      "id": "pay"
+    },
+    { "step": "checkState", "accounts": { "address:b": { "balance": "1010", "storage": "*" } } },
+    {
+        "step": "dumpState"
+    }
+  ]
+}
+`, patched)
+
+	_, err = p.ParseScenarioFile([]byte(patched))
+	require.Nil(t, err)
+}
@@ -0,0 +1,111 @@
+package denaliinterpreterbench
+
+import "fmt"
+
+// baseline was measured with Run, for the corpus and NewCorpusInterpreter, on linux/amd64.
+// Timings depend on the machine, allocations are a steadier yardstick.
+// Update it together with the interpreter changes that improve on it.
+var baseline = map[string]*Result{
+	"decimal-zero":       {NsPerOp: 140, AllocsPerOp: 0, BytesPerOp: 0},
+	"decimal-separators": {NsPerOp: 785, AllocsPerOp: 2, BytesPerOp: 40},
+	"decimal-negative":   {NsPerOp: 814, AllocsPerOp: 5, BytesPerOp: 64},
+	"hex-32-bytes":       {NsPerOp: 971, AllocsPerOp: 1, BytesPerOp: 32},
+	"binary":             {NsPerOp: 395, AllocsPerOp: 1, BytesPerOp: 8},
+	"fixed-u64":          {NsPerOp: 274, AllocsPerOp: 2, BytesPerOp: 16},
+	"fixed-i64-negative": {NsPerOp: 531, AllocsPerOp: 5, BytesPerOp: 64},
+	"bool":               {NsPerOp: 35, AllocsPerOp: 1, BytesPerOp: 1},
+	"str":                {NsPerOp: 87, AllocsPerOp: 1, BytesPerOp: 8},
+	"str-quotes":         {NsPerOp: 77, AllocsPerOp: 1, BytesPerOp: 8},
+	"address":            {NsPerOp: 183, AllocsPerOp: 2, BytesPerOp: 40},
+	"address-long":       {NsPerOp: 386, AllocsPerOp: 3, BytesPerOp: 144},
+	"address-shard":      {NsPerOp: 196, AllocsPerOp: 2, BytesPerOp: 40},
+	"keccak256":          {NsPerOp: 225, AllocsPerOp: 3, BytesPerOp: 64},
+	"concat-fixed-width": {NsPerOp: 637, AllocsPerOp: 6, BytesPerOp: 64},
+	"concat-storage-key": {NsPerOp: 682, AllocsPerOp: 7, BytesPerOp: 160},
+	"const":              {NsPerOp: 121, AllocsPerOp: 1, BytesPerOp: 8},
+	"define":             {NsPerOp: 99, AllocsPerOp: 1, BytesPerOp: 8},
+	"preset":             {NsPerOp: 138, AllocsPerOp: 2, BytesPerOp: 16},
+	"left-pad":           {NsPerOp: 340, AllocsPerOp: 3, BytesPerOp: 72},
+	"repeat":             {NsPerOp: 476, AllocsPerOp: 3, BytesPerOp: 65},
+	"bitor":              {NsPerOp: 869, AllocsPerOp: 7, BytesPerOp: 112},
+	"token":              {NsPerOp: 146, AllocsPerOp: 1, BytesPerOp: 16},
+	"base64":             {NsPerOp: 186, AllocsPerOp: 1, BytesPerOp: 5},
+	"percent":            {NsPerOp: 1636, AllocsPerOp: 19, BytesPerOp: 296},
+	"timestamp":          {NsPerOp: 640, AllocsPerOp: 3, BytesPerOp: 104},
+	"multi":              {NsPerOp: 1344, AllocsPerOp: 10, BytesPerOp: 240},
+	"keccak256-concat":   {NsPerOp: 843, AllocsPerOp: 9, BytesPerOp: 240},
+}
+
+// Baseline yields the reference results, by expression name. The result can be modified freely.
+func Baseline() map[string]*Result {
+	result := make(map[string]*Result, len(baseline))
+	for name, reference := range baseline {
+		referenceCopy := *reference
+		referenceCopy.Name = name
+		result[name] = &referenceCopy
+	}
+	return result
+}
+
+// Tolerance is how much worse than the baseline results can be, before they count as regressions.
+type Tolerance struct {
+	// TimeFactor allows results slower by that factor, e.g. 1.5 for 50%. Zero disables the time comparison,
+	// e.g. when the machine is not comparable to the one of the baseline.
+	TimeFactor float64
+
+	// AllocsFactor allows that many times the allocations per operation of the baseline, e.g. 1.25 for 25%.
+	// Zero or less means 1, the count of the baseline.
+	AllocsFactor float64
+
+	// ExtraAllocs allows that many more allocations per operation, on top of AllocsFactor.
+	ExtraAllocs int64
+}
+
+// maxAllocs yields the most allocations per operation allowed, given the count of the baseline.
+func (t Tolerance) maxAllocs(baselineAllocs int64) float64 {
+	factor := t.AllocsFactor
+	if factor <= 0 {
+		factor = 1
+	}
+	return float64(baselineAllocs)*factor + float64(t.ExtraAllocs)
+}
+
+// Regression is a result worse than its baseline.
+type Regression struct {
+	Actual   *Result
+	Baseline *Result
+	Reason   string
+}
+
+// String describes the regression, e.g. "percent: 25 allocs/op, baseline 19".
+func (r *Regression) String() string {
+	return fmt.Sprintf("%s: %s", r.Actual.Name, r.Reason)
+}
+
+// Compare yields the results worse than their baseline, beyond the tolerance.
+// Results without a baseline, e.g. of expressions added to the corpus by a downstream project, are skipped.
+func Compare(results []*Result, baseline map[string]*Result, tolerance Tolerance) []*Regression {
+	var regressions []*Regression
+	for _, result := range results {
+		reference, found := baseline[result.Name]
+		if !found {
+			continue
+		}
+		if float64(result.AllocsPerOp) > tolerance.maxAllocs(reference.AllocsPerOp) {
+			regressions = append(regressions, &Regression{
+				Actual:   result,
+				Baseline: reference,
+				Reason:   fmt.Sprintf("%d allocs/op, baseline %d", result.AllocsPerOp, reference.AllocsPerOp),
+			})
+			continue
+		}
+		if tolerance.TimeFactor > 0 && float64(result.NsPerOp) > float64(reference.NsPerOp)*tolerance.TimeFactor {
+			regressions = append(regressions, &Regression{
+				Actual:   result,
+				Baseline: reference,
+				Reason:   fmt.Sprintf("%d ns/op, baseline %d", result.NsPerOp, reference.NsPerOp),
+			})
+		}
+	}
+	return regressions
+}
@@ -0,0 +1,75 @@
+// Package denaliinterpreterbench measures the value interpreter against a canonical corpus of expressions,
+// so that performance-sensitive changes can be compared with a shared baseline.
+// It does not depend on the testing package: downstream projects call Run and Compare from their own tests,
+// or loop over the corpus in their benchmarks.
+package denaliinterpreterbench
+
+import (
+	"fmt"
+	"runtime"
+	"time"
+
+	vi "github.com/numbatx/gn-vm-util/test-util/denali/json/valueinterpreter"
+)
+
+// DefaultMinDuration is how long each expression is measured, unless configured otherwise.
+const DefaultMinDuration = 100 * time.Millisecond
+
+// maxIterations bounds the measurement of expressions so cheap that the clock barely sees them.
+const maxIterations = 1 << 30
+
+// Result is the cost of interpreting an expression once.
+type Result struct {
+	Name        string
+	NsPerOp     int64
+	AllocsPerOp int64
+	BytesPerOp  int64
+}
+
+// Options configures Run.
+type Options struct {
+	// MinDuration is how long each expression is measured. Defaults to DefaultMinDuration.
+	MinDuration time.Duration
+}
+
+// Run measures each expression of the corpus, in order, with the given interpreter.
+// Expressions that cannot be interpreted are an error, since their cost says nothing about valid ones.
+func Run(interpreter *vi.ValueInterpreter, corpus []*Expression, options Options) ([]*Result, error) {
+	if options.MinDuration <= 0 {
+		options.MinDuration = DefaultMinDuration
+	}
+	results := make([]*Result, 0, len(corpus))
+	for _, expression := range corpus {
+		_, err := interpreter.InterpretString(expression.Value)
+		if err != nil {
+			return nil, fmt.Errorf("corpus expression %s: %w", expression.Name, err)
+		}
+		result := measure(interpreter, expression.Value, options.MinDuration)
+		result.Name = expression.Name
+		results = append(results, result)
+	}
+	return results, nil
+}
+
+// measure doubles the number of iterations until they take long enough.
+func measure(interpreter *vi.ValueInterpreter, value string, minDuration time.Duration) *Result {
+	var before, after runtime.MemStats
+	for n := 1; ; n *= 2 {
+		runtime.GC()
+		runtime.ReadMemStats(&before)
+		start := time.Now()
+		for i := 0; i < n; i++ {
+			_, _ = interpreter.InterpretString(value)
+		}
+		elapsed := time.Since(start)
+		runtime.ReadMemStats(&after)
+
+		if elapsed >= minDuration || n >= maxIterations {
+			return &Result{
+				NsPerOp:     elapsed.Nanoseconds() / int64(n),
+				AllocsPerOp: int64(after.Mallocs-before.Mallocs) / int64(n),
+				BytesPerOp:  int64(after.TotalAlloc-before.TotalAlloc) / int64(n),
+			}
+		}
+	}
+}
@@ -0,0 +1,81 @@
+package denaliinterpreterbench
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestCorpus(t *testing.T) {
+	interpreter := NewCorpusInterpreter()
+	names := make(map[string]bool)
+	for _, expression := range Corpus() {
+		require.False(t, names[expression.Name], expression.Name)
+		names[expression.Name] = true
+		require.NotEmpty(t, expression.Category, expression.Name)
+
+		_, err := interpreter.InterpretString(expression.Value)
+		require.Nil(t, err, expression.Name)
+	}
+
+	for name := range Baseline() {
+		require.True(t, names[name], name)
+	}
+}
+
+func TestNoAllocationRegressions(t *testing.T) {
+	results, err := Run(NewCorpusInterpreter(), Corpus(), Options{MinDuration: time.Millisecond})
+	require.Nil(t, err)
+	require.Equal(t, len(Corpus()), len(results))
+
+	// timings of this machine are not comparable to the baseline, allocation counts vary a little
+	// with the Go version and the architecture
+	regressions := Compare(results, Baseline(), Tolerance{AllocsFactor: 1.25, ExtraAllocs: 1})
+	require.Empty(t, regressions)
+}
+
+func TestCompare(t *testing.T) {
+	baseline := map[string]*Result{
+		"a": {Name: "a", NsPerOp: 100, AllocsPerOp: 2},
+		"b": {Name: "b", NsPerOp: 100, AllocsPerOp: 2},
+	}
+	results := []*Result{
+		{Name: "a", NsPerOp: 140, AllocsPerOp: 3},
+		{Name: "b", NsPerOp: 160, AllocsPerOp: 2},
+		{Name: "new", NsPerOp: 1000, AllocsPerOp: 100},
+	}
+
+	regressions := Compare(results, baseline, Tolerance{TimeFactor: 1.5})
+	require.Equal(t, 2, len(regressions))
+	require.Equal(t, "a: 3 allocs/op, baseline 2", regressions[0].String())
+	require.Equal(t, "b: 160 ns/op, baseline 100", regressions[1].String())
+
+	regressions = Compare(results, baseline, Tolerance{ExtraAllocs: 1})
+	require.Empty(t, regressions)
+	regressions = Compare(results, baseline, Tolerance{AllocsFactor: 1.5})
+	require.Empty(t, regressions)
+	regressions = Compare(results, baseline, Tolerance{AllocsFactor: 1.25})
+	require.Equal(t, 1, len(regressions))
+
+	_, err := Run(NewCorpusInterpreter(), []*Expression{{Name: "invalid", Value: "const:UNKNOWN"}}, Options{})
+	require.NotNil(t, err)
+	require.Contains(t, err.Error(), "corpus expression invalid")
+}
+
+// BenchmarkCorpus runs the corpus as sub-benchmarks, one per expression, for "go test -bench".
+func BenchmarkCorpus(b *testing.B) {
+	interpreter := NewCorpusInterpreter()
+	for _, expression := range Corpus() {
+		value := expression.Value
+		b.Run(expression.Name, func(b *testing.B) {
+			b.ReportAllocs()
+			for i := 0; i < b.N; i++ {
+				_, err := interpreter.InterpretString(value)
+				if err != nil {
+					b.Fatal(err)
+				}
+			}
+		})
+	}
+}
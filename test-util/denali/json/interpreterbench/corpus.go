@@ -0,0 +1,98 @@
+package denaliinterpreterbench
+
+import (
+	vi "github.com/numbatx/gn-vm-util/test-util/denali/json/valueinterpreter"
+)
+
+// Expression is a value of the corpus.
+type Expression struct {
+	// Name identifies the expression in results and baselines. Names are unique within the corpus.
+	Name string
+
+	// Category groups expressions by the part of the interpreter they exercise, e.g. "number".
+	Category string
+
+	Value string
+}
+
+// Categories of the corpus expressions.
+const (
+	CategoryNumber    = "number"
+	CategoryString    = "string"
+	CategoryAddress   = "address"
+	CategoryHash      = "hash"
+	CategoryConcat    = "concat"
+	CategoryNamed     = "named"
+	CategoryOperator  = "operator"
+	CategoryEncoding  = "encoding"
+	CategoryComposite = "composite"
+)
+
+// Names and values defined by NewCorpusInterpreter, referenced by the corpus.
+const (
+	corpusConstantName = "OWNER"
+	corpusDefineName   = "owner"
+	corpusPresetName   = "DEFAULT_GAS"
+	corpusPresetValue  = 5000000
+)
+
+var corpus = []*Expression{
+	{Name: "decimal-zero", Category: CategoryNumber, Value: "0"},
+	{Name: "decimal-separators", Category: CategoryNumber, Value: "1,000,000,000,000,000,000"},
+	{Name: "decimal-negative", Category: CategoryNumber, Value: "-1234567890"},
+	{Name: "hex-32-bytes", Category: CategoryNumber,
+		Value: "0x0000000000000000000000000000000000000000000000000000000000000001"},
+	{Name: "binary", Category: CategoryNumber, Value: "0b1010"},
+	{Name: "fixed-u64", Category: CategoryNumber, Value: "u64:1000"},
+	{Name: "fixed-i64-negative", Category: CategoryNumber, Value: "i64:-1"},
+	{Name: "bool", Category: CategoryNumber, Value: "true"},
+
+	{Name: "str", Category: CategoryString, Value: "str:balance"},
+	{Name: "str-quotes", Category: CategoryString, Value: "''deposit"},
+
+	{Name: "address", Category: CategoryAddress, Value: "address:owner"},
+	{Name: "address-long", Category: CategoryAddress, Value: "address:a_rather_long_account_name_that_gets_cut"},
+	{Name: "address-shard", Category: CategoryAddress, Value: "address:owner#1"},
+
+	{Name: "keccak256", Category: CategoryHash, Value: "keccak256:str:storage_key"},
+
+	{Name: "concat-fixed-width", Category: CategoryConcat, Value: "u32:5|u64:1000|str:abc"},
+	{Name: "concat-storage-key", Category: CategoryConcat, Value: "str:counter|address:owner|u8:1"},
+
+	{Name: "const", Category: CategoryNamed, Value: vi.ConstPrefix + corpusConstantName},
+	{Name: "define", Category: CategoryNamed, Value: vi.DefinePrefix + corpusDefineName},
+	{Name: "preset", Category: CategoryNamed, Value: vi.PresetPrefix + corpusPresetName},
+
+	{Name: "left-pad", Category: CategoryOperator, Value: "left-pad:32:1"},
+	{Name: "repeat", Category: CategoryOperator, Value: "repeat:32:0xff"},
+	{Name: "bitor", Category: CategoryOperator, Value: "bitor:(u8:1),(u8:2)"},
+
+	{Name: "token", Category: CategoryEncoding, Value: "token:WEGLD-abcdef"},
+	{Name: "base64", Category: CategoryEncoding, Value: "base64:aGVsbG8="},
+	{Name: "percent", Category: CategoryEncoding, Value: "percent:2.5"},
+	{Name: "timestamp", Category: CategoryEncoding, Value: "timestamp:2024-01-01"},
+
+	{Name: "multi", Category: CategoryComposite, Value: "multi:1;str:a;address:owner"},
+	{Name: "keccak256-concat", Category: CategoryComposite, Value: "keccak256:str:balance|address:owner|u32:7"},
+}
+
+// Corpus yields the canonical expressions, typical of large scenario suites.
+// They only need the interpreter to be set up by NewCorpusInterpreter, no files.
+// The result can be modified freely.
+func Corpus() []*Expression {
+	result := make([]*Expression, len(corpus))
+	for i, expression := range corpus {
+		expressionCopy := *expression
+		result[i] = &expressionCopy
+	}
+	return result
+}
+
+// NewCorpusInterpreter yields an interpreter that knows the constant, define and gas preset used in the corpus.
+func NewCorpusInterpreter() *vi.ValueInterpreter {
+	interpreter := &vi.ValueInterpreter{}
+	interpreter.SetConstant(corpusConstantName, []byte("owner"))
+	interpreter.SetDefine(corpusDefineName, []byte("owner"))
+	interpreter.SetGasPreset(corpusPresetName, corpusPresetValue)
+	return interpreter
+}
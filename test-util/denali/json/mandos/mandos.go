@@ -0,0 +1,363 @@
+package denalijsonmandos
+
+import (
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+
+	fr "github.com/numbatx/gn-vm-util/test-util/denali/json/fileresolver"
+	mj "github.com/numbatx/gn-vm-util/test-util/denali/json/model"
+	mjparse "github.com/numbatx/gn-vm-util/test-util/denali/json/parse"
+	vi "github.com/numbatx/gn-vm-util/test-util/denali/json/valueinterpreter"
+	mjwrite "github.com/numbatx/gn-vm-util/test-util/denali/json/write"
+	oj "github.com/numbatx/gn-vm-util/test-util/orderedjson"
+)
+
+// Dialect names one of the scenario JSON dialects.
+type Dialect string
+
+const (
+	// Denali is the scenario dialect of this repository.
+	Denali Dialect = "Denali"
+
+	// Mandos is the scenario dialect that Denali was forked from, still used by the other VM stack.
+	Mandos Dialect = "Mandos"
+)
+
+// ConversionError lists the constructs of a scenario that the target dialect cannot express, by JSON path.
+// Nothing is dropped or approximated silently: such scenarios have to be adapted by hand.
+type ConversionError struct {
+	Target   Dialect
+	Problems []string
+}
+
+func (e *ConversionError) Error() string {
+	return fmt.Sprintf("scenario cannot be converted to %s:\n\t%s", e.Target, strings.Join(e.Problems, "\n\t"))
+}
+
+// txFieldRenames maps the transaction fields that the dialects name differently, from Denali to Mandos.
+var txFieldRenames = map[string]string{
+	"value": "egldValue",
+}
+
+// denaliOnlyScenarioFields are the top-level fields that Denali added since the fork.
+var denaliOnlyScenarioFields = []string{
+	"schemaVersion",
+	"requiresFormatVersion",
+	"abi",
+	"gasSchedule",
+	"config",
+	"autoNonces",
+	"metadata",
+	"constants",
+	"defines",
+	"parameters",
+	"gasPresets",
+	"invariants",
+}
+
+// denaliOnlyStepFields are the step fields that Denali added since the fork, by step type.
+var denaliOnlyStepFields = map[string][]string{
+	mj.StepNameSetState:      {"allowedErrors", "generateAccounts"},
+	mj.StepNameExternalSteps: {"arguments"},
+}
+
+// denaliOnlyCommonStepFields are the fields that Denali added to all steps since the fork.
+var denaliOnlyCommonStepFields = []string{"id", "tags"}
+
+// denaliOnlyTxFields are the transaction fields that Denali added since the fork, by step type.
+var denaliOnlyTxFields = map[string][]string{
+	mj.StepNameTransfer: {"data"},
+}
+
+// mandosOnlyTxFields are the transaction fields of Mandos that Denali has no equivalent for.
+var mandosOnlyTxFields = []string{"esdtValue"}
+
+// mandosOnlyAccountFields are the account fields of Mandos that Denali has no equivalent for.
+var mandosOnlyAccountFields = []string{"esdt", "username", "owner", "shard", "developerRewards"}
+
+// sharedPrefixes are the value prefixes, and special values, that both dialects interpret the same way.
+// Denali supports more, see vi.ValueInterpreter.SupportedPrefixes, which Mandos cannot read.
+var sharedPrefixes = map[string]bool{
+	vi.StrPrefix:       true,
+	"``":               true,
+	"''":               true,
+	vi.AddressPrefix:   true,
+	vi.FilePrefix:      true,
+	vi.Keccak256Prefix: true,
+	vi.U64Prefix:       true,
+	vi.U32Prefix:       true,
+	vi.U16Prefix:       true,
+	vi.U8Prefix:        true,
+	vi.I64Prefix:       true,
+	vi.I32Prefix:       true,
+	vi.I16Prefix:       true,
+	vi.I8Prefix:        true,
+	vi.AnyValueStar:    true,
+}
+
+// nestingPrefixes are the shared prefixes followed by another value, which has to be converted too,
+// e.g. "u32:1,000" or "keccak256:u32:$ID".
+var nestingPrefixes = map[string]bool{
+	vi.Keccak256Prefix: true,
+	vi.U64Prefix:       true,
+	vi.U32Prefix:       true,
+	vi.U16Prefix:       true,
+	vi.U8Prefix:        true,
+	vi.I64Prefix:       true,
+	vi.I32Prefix:       true,
+	vi.I16Prefix:       true,
+	vi.I8Prefix:        true,
+}
+
+// digitSeparators group the digits of numbers in Denali, e.g. "1,000". Mandos numbers are written without them.
+const digitSeparators = "_,' "
+
+// textFields hold free text or names, not values, so they are not checked for prefixes.
+var textFields = map[string]bool{
+	"step":     true,
+	"comment":  true,
+	"name":     true,
+	"txId":     true,
+	"path":     true,
+	"function": true,
+}
+
+// prefixedValue matches values that start with a prefix, e.g. "sc:adder" or "left-pad:4:5".
+var prefixedValue = regexp.MustCompile(`^[a-z][a-z0-9-]*:`)
+
+// DenaliToMandos converts a Denali scenario to Mandos. Comments are kept, digit separators are removed from numbers.
+// Yields a *ConversionError if the scenario uses Denali features that Mandos does not have.
+func DenaliToMandos(denaliJSON []byte) ([]byte, error) {
+	return convert(denaliJSON, Mandos)
+}
+
+// MandosToDenali converts a Mandos scenario to Denali. Comments are kept.
+// Yields a *ConversionError if the scenario uses Mandos features that Denali does not have.
+func MandosToDenali(mandosJSON []byte) ([]byte, error) {
+	return convert(mandosJSON, Denali)
+}
+
+// ScenarioToMandosJSON writes a parsed scenario in the Mandos dialect.
+func ScenarioToMandosJSON(scenario *mj.Scenario) ([]byte, error) {
+	return DenaliToMandos([]byte(mjwrite.ScenarioToJSONString(scenario)))
+}
+
+// ParseMandosScenario parses a scenario written in the Mandos dialect.
+func ParseMandosScenario(p *mjparse.Parser, mandosJSON []byte) (*mj.Scenario, error) {
+	denaliJSON, err := MandosToDenali(mandosJSON)
+	if err != nil {
+		return nil, err
+	}
+	return p.ParseScenarioFile(denaliJSON)
+}
+
+type converter struct {
+	target      Dialect
+	comments    *oj.Comments
+	interpreter *vi.ValueInterpreter
+	problems    []string
+}
+
+func convert(input []byte, target Dialect) ([]byte, error) {
+	document, err := oj.ParseDocument(input)
+	if err != nil {
+		return nil, err
+	}
+	scenario, isMap := document.Root.(*oj.OJsonMap)
+	if !isMap {
+		return nil, fmt.Errorf("unmarshalled scenario object is not a map")
+	}
+
+	c := &converter{
+		target:   target,
+		comments: document.Comments,
+		// only consulted for the prefixes, no files get loaded
+		interpreter: &vi.ValueInterpreter{FileResolver: fr.NewDefaultFileResolver()},
+	}
+	c.convertScenario(scenario)
+	c.convertValues(scenario, "")
+	if len(c.problems) > 0 {
+		sort.Strings(c.problems)
+		return nil, &ConversionError{Target: target, Problems: c.problems}
+	}
+	return []byte(oj.JSONStringWithComments(scenario, c.comments)), nil
+}
+
+func (c *converter) addProblem(path string, format string, args ...interface{}) {
+	c.problems = append(c.problems, path+": "+fmt.Sprintf(format, args...))
+}
+
+func (c *converter) convertScenario(scenario *oj.OJsonMap) {
+	if c.target == Mandos {
+		c.rejectFields(scenario, "", denaliOnlyScenarioFields)
+	}
+	for _, kv := range scenario.OrderedKV {
+		if kv.Key != "steps" {
+			continue
+		}
+		steps, isList := kv.Value.(*oj.OJsonList)
+		if !isList {
+			// left to the parser
+			continue
+		}
+		for i, stepObj := range steps.AsList() {
+			if step, isMap := stepObj.(*oj.OJsonMap); isMap {
+				c.convertStep(step, oj.ElementPath("steps", i))
+			}
+		}
+	}
+}
+
+func (c *converter) convertStep(step *oj.OJsonMap, path string) {
+	stepType := ""
+	for _, kv := range step.OrderedKV {
+		if kv.Key == "step" {
+			if str, isStr := kv.Value.(*oj.OJsonString); isStr {
+				stepType = str.Value
+			}
+		}
+	}
+	if c.target == Mandos {
+		c.rejectFields(step, path, denaliOnlyCommonStepFields)
+		c.rejectFields(step, path, denaliOnlyStepFields[stepType])
+	}
+
+	for _, kv := range step.OrderedKV {
+		fieldPath := oj.ChildPath(path, kv.Key)
+		switch kv.Key {
+		case "tx":
+			if tx, isMap := kv.Value.(*oj.OJsonMap); isMap {
+				c.convertTx(tx, fieldPath, stepType)
+			}
+		case "accounts":
+			if c.target != Denali {
+				continue
+			}
+			accounts, isMap := kv.Value.(*oj.OJsonMap)
+			if !isMap {
+				continue
+			}
+			for _, accountKV := range accounts.OrderedKV {
+				if account, isMap := accountKV.Value.(*oj.OJsonMap); isMap {
+					c.rejectFields(account, oj.ChildPath(fieldPath, accountKV.Key), mandosOnlyAccountFields)
+				}
+			}
+		}
+	}
+}
+
+func (c *converter) convertTx(tx *oj.OJsonMap, path string, stepType string) {
+	if c.target == Denali {
+		c.rejectFields(tx, path, mandosOnlyTxFields)
+	} else {
+		c.rejectFields(tx, path, denaliOnlyTxFields[stepType])
+	}
+	for denaliName, mandosName := range txFieldRenames {
+		from, to := denaliName, mandosName
+		if c.target == Denali {
+			from, to = mandosName, denaliName
+		}
+		c.renameField(tx, path, from, to)
+	}
+}
+
+func (c *converter) rejectFields(obj *oj.OJsonMap, path string, fields []string) {
+	for _, field := range fields {
+		if obj.KeySet[field] {
+			c.addProblem(oj.ChildPath(path, field), "not supported in %s", c.target)
+		}
+	}
+}
+
+// renameField renames a map key in place, keeping its position and the comments of the value and of its children.
+func (c *converter) renameField(obj *oj.OJsonMap, path string, from string, to string) {
+	if !obj.KeySet[from] {
+		return
+	}
+	if obj.KeySet[to] {
+		c.addProblem(oj.ChildPath(path, from), "cannot be renamed to %s, which is also present", to)
+		return
+	}
+	for _, kv := range obj.OrderedKV {
+		if kv.Key == from {
+			kv.Key = to
+		}
+	}
+	obj.RefreshKeySet()
+	c.comments.MovePath(oj.ChildPath(path, from), oj.ChildPath(path, to))
+}
+
+// convertValues looks for prefixes that the target dialect cannot interpret, in all values and in map keys,
+// since accounts and storage are keyed by values. Numbers are written without digit separators for Mandos.
+func (c *converter) convertValues(obj oj.OJsonObject, path string) {
+	switch value := obj.(type) {
+	case *oj.OJsonMap:
+		renamedKeys := false
+		for _, kv := range value.OrderedKV {
+			childPath := oj.ChildPath(path, kv.Key)
+			if textFields[kv.Key] {
+				continue
+			}
+			convertedKey := c.convertValue(kv.Key, childPath)
+			c.convertValues(kv.Value, childPath)
+			if convertedKey != kv.Key {
+				c.comments.MovePath(childPath, oj.ChildPath(path, convertedKey))
+				kv.Key = convertedKey
+				renamedKeys = true
+			}
+		}
+		if renamedKeys {
+			value.RefreshKeySet()
+		}
+	case *oj.OJsonList:
+		for i, element := range value.AsList() {
+			c.convertValues(element, oj.ElementPath(path, i))
+		}
+	case *oj.OJsonString:
+		value.Value = c.convertValue(value.Value, path)
+	}
+}
+
+// convertValue yields the value as written in the target dialect, noting a problem if it cannot be.
+func (c *converter) convertValue(expression string, path string) string {
+	return c.convertOperand(expression, expression, path)
+}
+
+// convertOperand converts the expression, the whole value or the operand of one of its prefixes.
+func (c *converter) convertOperand(value string, expression string, path string) string {
+	info, isDenali := c.interpreter.LookupPrefix(expression)
+	switch c.target {
+	case Mandos:
+		if !isDenali {
+			return withoutDigitSeparators(expression)
+		}
+		if !sharedPrefixes[info.Name] {
+			c.addProblem(path, "value \"%s\" uses %s, which is not supported in Mandos", value, info.Name)
+			return expression
+		}
+		if nestingPrefixes[info.Name] {
+			return info.Name + c.convertOperand(value, expression[len(info.Name):], path)
+		}
+	case Denali:
+		if !isDenali && prefixedValue.MatchString(expression) {
+			prefix := prefixedValue.FindString(expression)
+			c.addProblem(path, "value \"%s\" uses %s, which is not supported in Denali", value, prefix)
+		}
+	}
+	return expression
+}
+
+// withoutDigitSeparators removes the digit separators of number literals, other values are kept.
+func withoutDigitSeparators(expression string) string {
+	if len(expression) == 0 || !strings.ContainsAny(expression[:1], "0123456789+-") {
+		return expression
+	}
+	return strings.Map(func(r rune) rune {
+		if strings.ContainsRune(digitSeparators, r) {
+			return -1
+		}
+		return r
+	}, expression)
+}
@@ -0,0 +1,122 @@
+package denalijsonmandos
+
+import (
+	"testing"
+
+	mjparse "github.com/numbatx/gn-vm-util/test-util/denali/json/parse"
+	"github.com/stretchr/testify/require"
+)
+
+const denaliScenario = `{
+    "name": "transfer",
+    "steps": [
+        {
+            "step": "transfer",
+            "txId": "1",
+            "tx": {
+                "from": "address:owner",
+                "to": "address:other",
+                // paid by the owner
+                "value": "1000"
+            }
+        }
+    ]
+}
+`
+
+func TestDenaliToMandosAndBack(t *testing.T) {
+	mandosJSON, err := DenaliToMandos([]byte(denaliScenario))
+	require.Nil(t, err)
+	require.Contains(t, string(mandosJSON), `"egldValue": "1000"`)
+	require.NotContains(t, string(mandosJSON), `"value"`)
+	require.Contains(t, string(mandosJSON), "// paid by the owner\n                \"egldValue\"")
+
+	denaliJSON, err := MandosToDenali(mandosJSON)
+	require.Nil(t, err)
+	require.Equal(t, denaliScenario, string(denaliJSON))
+
+	scenario, err := ParseMandosScenario(&mjparse.Parser{}, mandosJSON)
+	require.Nil(t, err)
+	require.Equal(t, "transfer", scenario.Name)
+}
+
+func TestDenaliToMandosProblems(t *testing.T) {
+	_, err := DenaliToMandos([]byte(`{
+		"constants": {"FEE": "5"},
+		"steps": [
+			{"step": "setState", "comment": "fee: paid $1", "allowedErrors": ["str:x"]},
+			{"step": "scCall", "tx": {"to": "address:a", "function": "f", "arguments": ["u32:1", "left-pad:4:5", "const:FEE"]}},
+			{"step": "transfer", "tx": {"from": "address:a", "to": "address:b", "value": "$AMOUNT", "data": "str:memo"}},
+			{"step": "scCall", "tx": {"to": "address:a", "function": "f", "arguments": ["keccak256:u32:$ID"]}}
+		]
+	}`))
+	conversionErr, isConversionErr := err.(*ConversionError)
+	require.True(t, isConversionErr)
+	require.Equal(t, Mandos, conversionErr.Target)
+	require.Equal(t, []string{
+		"constants: not supported in Mandos",
+		`steps[0].allowedErrors: not supported in Mandos`,
+		`steps[1].tx.arguments[1]: value "left-pad:4:5" uses left-pad:, which is not supported in Mandos`,
+		`steps[1].tx.arguments[2]: value "const:FEE" uses const:, which is not supported in Mandos`,
+		`steps[2].tx.data: not supported in Mandos`,
+		`steps[2].tx.egldValue: value "$AMOUNT" uses $, which is not supported in Mandos`,
+		`steps[3].tx.arguments[0]: value "keccak256:u32:$ID" uses $, which is not supported in Mandos`,
+	}, conversionErr.Problems)
+}
+
+func TestDenaliToMandosDigitSeparators(t *testing.T) {
+	mandosJSON, err := DenaliToMandos([]byte(`{
+    "steps": [
+        {
+            "step": "setState",
+            "accounts": {
+                "address:owner": {
+                    "balance": "1,000,000",
+                    "storage": {
+                        // grouped keys are numbers too
+                        "1_000": "u64:0x00ff_ffff",
+                        "str:a,b": "-1'000"
+                    }
+                }
+            }
+        }
+    ]
+}`))
+	require.Nil(t, err)
+	require.Equal(t, `{
+    "steps": [
+        {
+            "step": "setState",
+            "accounts": {
+                "address:owner": {
+                    "balance": "1000000",
+                    "storage": {
+                        // grouped keys are numbers too
+                        "1000": "u64:0x00ffffff",
+                        "str:a,b": "-1000"
+                    }
+                }
+            }
+        }
+    ]
+}
+`, string(mandosJSON))
+}
+
+func TestMandosToDenaliProblems(t *testing.T) {
+	_, err := MandosToDenali([]byte(`{
+		"steps": [
+			{"step": "setState", "accounts": {"sc:adder": {"balance": "0", "esdt": {"str:TOK": "5"}, "code": "file:adder.wasm"}}},
+			{"step": "scCall", "tx": {"to": "sc:adder", "function": "add", "esdtValue": [], "arguments": ["biguint:5"]}}
+		]
+	}`))
+	conversionErr, isConversionErr := err.(*ConversionError)
+	require.True(t, isConversionErr)
+	require.Equal(t, []string{
+		`steps[0].accounts.sc:adder.esdt: not supported in Denali`,
+		`steps[0].accounts.sc:adder: value "sc:adder" uses sc:, which is not supported in Denali`,
+		`steps[1].tx.arguments[0]: value "biguint:5" uses biguint:, which is not supported in Denali`,
+		`steps[1].tx.esdtValue: not supported in Denali`,
+		`steps[1].tx.to: value "sc:adder" uses sc:, which is not supported in Denali`,
+	}, conversionErr.Problems)
+}
@@ -11,12 +11,35 @@ type Account struct {
 	Storage       []*StorageKeyValuePair
 	Code          JSONBytesFromString
 	AsyncCallData string
+
+	// Generated is set for accounts expanded from a generateAccounts template.
+	// They are not written out individually.
+	Generated bool
 }
 
 // StorageKeyValuePair is a json key value pair in the storage map.
 type StorageKeyValuePair struct {
 	Key   JSONBytesFromString
 	Value JSONBytesFromTree
+
+	// AnyValue is only set in checks, for "*" or "any" values:
+	// the key must be set, but its value is not checked.
+	AnyValue bool
+
+	// Matcher is only set in checks, for "match:..." values.
+	Matcher ValueMatcher
+}
+
+// CheckValue returns true if the actual storage value satisfies a checkState storage entry.
+// Keys that are not set have empty values, so "*" only requires the value to be non-empty.
+func (kvp *StorageKeyValuePair) CheckValue(actual []byte) bool {
+	if kvp.AnyValue {
+		return len(actual) > 0
+	}
+	if kvp.Matcher != nil {
+		return kvp.Matcher.MatchBytes(actual)
+	}
+	return bytes.Equal(kvp.Value.Value, actual)
 }
 
 // CheckAccount is a json object representing checks for an account.
@@ -6,36 +6,56 @@ import (
 )
 
 // ConvertTestToScenario converts the old test format to the new scenario format for tests.
+// Only files with a single test can be converted to a single scenario, see ConvertTest.
 func ConvertTestToScenario(top []*Test) (*Scenario, error) {
 	if len(top) != 1 {
 		return nil, errors.New("only one test per test file supported")
 	}
+	return ConvertTest(top[0])
+}
 
-	test := top[0]
-
+// ConvertTest maps a test onto scenario steps: a setState step with the pre-state,
+// the transactions of each block, each with its expected result, then a checkState step with the post-state.
+// Blocks with a header set the block info of their transactions, in a setState step before them.
+func ConvertTest(test *Test) (*Scenario, error) {
 	scenario := &Scenario{
+		Name:     test.TestName,
 		CheckGas: test.CheckGas,
 	}
 
-	if len(test.Blocks) != 1 {
-		return nil, errors.New("only one block per test file supported")
-	}
-	block := test.Blocks[0]
-
-	scenario.Steps = append(scenario.Steps, &SetStateStep{
+	initialState := &SetStateStep{
 		Accounts:    test.Pre,
 		BlockHashes: test.BlockHashes,
-	})
-
-	if len(block.Transactions) != len(block.Results) {
-		return nil, errors.New("transactions must match results")
 	}
-	for txIndex, tx := range block.Transactions {
-		scenario.Steps = append(scenario.Steps, &TxStep{
-			TxIdent:        fmt.Sprintf("%d", txIndex+1),
-			Tx:             tx,
-			ExpectedResult: block.Results[txIndex],
-		})
+	scenario.Steps = append(scenario.Steps, initialState)
+
+	txIndex := 0
+	for blockIndex, block := range test.Blocks {
+		if len(block.Transactions) != len(block.Results) {
+			return nil, fmt.Errorf("block %d: transactions must match results", blockIndex)
+		}
+		blockInfo, err := blockHeaderToBlockInfo(block.BlockHeader)
+		if err != nil {
+			return nil, fmt.Errorf("block %d: %w", blockIndex, err)
+		}
+		if blockInfo != nil {
+			if blockIndex == 0 {
+				initialState.CurrentBlockInfo = blockInfo
+			} else {
+				scenario.Steps = append(scenario.Steps, &SetStateStep{
+					PreviousBlockInfo: scenarioCurrentBlockInfo(scenario),
+					CurrentBlockInfo:  blockInfo,
+				})
+			}
+		}
+		for i, tx := range block.Transactions {
+			txIndex++
+			scenario.Steps = append(scenario.Steps, &TxStep{
+				TxIdent:        fmt.Sprintf("%d", txIndex),
+				Tx:             tx,
+				ExpectedResult: block.Results[i],
+			})
+		}
 	}
 
 	scenario.Steps = append(scenario.Steps, &CheckStateStep{
@@ -44,3 +64,37 @@ func ConvertTestToScenario(top []*Test) (*Scenario, error) {
 
 	return scenario, nil
 }
+
+// blockHeaderToBlockInfo keeps the parts of the header that scenarios can express, nil if there are none.
+func blockHeaderToBlockInfo(header *BlockHeader) (*BlockInfo, error) {
+	if header == nil {
+		return nil, nil
+	}
+	hasNumber := header.Number.Value != nil && header.Number.Value.Sign() != 0
+	if header.Timestamp.Value == 0 && !hasNumber {
+		return nil, nil
+	}
+	blockInfo := &BlockInfo{
+		BlockTimestamp: header.Timestamp,
+	}
+	if hasNumber {
+		if !header.Number.Value.IsUint64() {
+			return nil, fmt.Errorf("block number %s does not fit a block nonce", header.Number.Value)
+		}
+		blockInfo.BlockNonce = JSONUint64{
+			Value:    header.Number.Value.Uint64(),
+			Original: header.Number.Original,
+		}
+	}
+	return blockInfo, nil
+}
+
+// scenarioCurrentBlockInfo yields the block info set last, nil if none was.
+func scenarioCurrentBlockInfo(scenario *Scenario) *BlockInfo {
+	for i := len(scenario.Steps) - 1; i >= 0; i-- {
+		if setState, isSetState := scenario.Steps[i].(*SetStateStep); isSetState && setState.CurrentBlockInfo != nil {
+			return setState.CurrentBlockInfo
+		}
+	}
+	return nil
+}
@@ -0,0 +1,142 @@
+package denalijsonmodel
+
+// FormatVersion identifies a revision of the scenario format.
+// Writers can target an older version, so that scenarios can still be read by older parsers.
+type FormatVersion int
+
+const (
+	// FormatVersionInitial is the original scenario format, without externalSteps.
+	FormatVersionInitial FormatVersion = 1
+
+	// FormatVersionExternalSteps introduced the externalSteps step.
+	FormatVersionExternalSteps FormatVersion = 2
+
+	// FormatVersionConstants introduced scenario-level constants and the "const:" prefix.
+	FormatVersionConstants FormatVersion = 3
+
+	// FormatVersionRequiresFormatVersion introduced the scenario-level "requiresFormatVersion" field.
+	FormatVersionRequiresFormatVersion FormatVersion = 4
+
+	// FormatVersionABI introduced the scenario-level "abi" field and "abi:TYPE:..." typed literals.
+	FormatVersionABI FormatVersion = 5
+
+	// FormatVersionGenerateAccounts introduced the "generateAccounts" setState field.
+	FormatVersionGenerateAccounts FormatVersion = 6
+
+	// FormatVersionGasPresets introduced the scenario-level "gasPresets" field and the "preset:" prefix.
+	FormatVersionGasPresets FormatVersion = 7
+
+	// FormatVersionMetadata introduced the scenario-level "metadata" field.
+	FormatVersionMetadata FormatVersion = 8
+
+	// FormatVersionViewScenarios introduced the scQuery step and the "view" scenario flavor.
+	FormatVersionViewScenarios FormatVersion = 9
+
+	// FormatVersionAutoNonces introduced the scenario-level "autoNonces" flag.
+	FormatVersionAutoNonces FormatVersion = 10
+
+	// FormatVersionPriority introduced the "priority" metadata field.
+	FormatVersionPriority FormatVersion = 11
+
+	// FormatVersionTransferData introduced the "data" field of transfer transactions.
+	FormatVersionTransferData FormatVersion = 12
+
+	// FormatVersionAllowedErrors introduced the "allowedErrors" setState field.
+	FormatVersionAllowedErrors FormatVersion = 13
+
+	// FormatVersionInvariants introduced the scenario-level "invariants" field.
+	FormatVersionInvariants FormatVersion = 14
+
+	// FormatVersionDefines introduced the scenario-level "defines" field and "$NAME" references.
+	FormatVersionDefines FormatVersion = 15
+
+	// FormatVersionComments introduced "//" and "/* */" comments.
+	FormatVersionComments FormatVersion = 16
+
+	// FormatVersionStepParameters introduced the scenario-level "parameters" field
+	// and the "arguments" field of externalSteps.
+	FormatVersionStepParameters FormatVersion = 17
+
+	// FormatVersionSchemaVersion introduced the scenario-level "schemaVersion" field,
+	// which tells the parser how to migrate scenarios written for older versions.
+	FormatVersionSchemaVersion FormatVersion = 18
+
+	// FormatVersionStepMetadata introduced the "id" and "tags" fields of all steps,
+	// and the "comment" field of externalSteps.
+	FormatVersionStepMetadata FormatVersion = 19
+
+	// FormatVersionGasSchedule introduced the scenario-level "gasSchedule" field.
+	FormatVersionGasSchedule FormatVersion = 20
+
+	// FormatVersionConfig introduced the scenario-level "config" field.
+	FormatVersionConfig FormatVersion = 21
+
+	// FormatVersionAddressShard introduced the "#SHARD" suffix of "address:" values.
+	FormatVersionAddressShard FormatVersion = 22
+
+	// FormatVersionTokens introduced the "token:" prefix.
+	FormatVersionTokens FormatVersion = 23
+
+	// FormatVersionPercent introduced the "percent:" and "bp:" prefixes.
+	FormatVersionPercent FormatVersion = 24
+
+	// FormatVersionByteOperators introduced the "left-pad:", "right-pad:", "slice:" and "repeat:" operators.
+	FormatVersionByteOperators FormatVersion = 25
+
+	// FormatVersionBitOperators introduced the "bitand:", "bitor:", "bitxor:", "shl:" and "shr:" operators.
+	FormatVersionBitOperators FormatVersion = 26
+
+	// FormatVersionFileRanges introduced the byte and line ranges of "file:" values, e.g. "file:data.txt#L3-L5".
+	FormatVersionFileRanges FormatVersion = 27
+
+	// FormatVersionBase64 introduced the "base64:" prefix.
+	FormatVersionBase64 FormatVersion = 28
+
+	// FormatVersionTime introduced the "timestamp:" and "duration:" prefixes.
+	FormatVersionTime FormatVersion = 29
+
+	// FormatVersionCode introduced the "code:" prefix.
+	FormatVersionCode FormatVersion = 30
+
+	// FormatVersionFileJSON introduced the "file:json:" prefix.
+	FormatVersionFileJSON FormatVersion = 31
+
+	// FormatVersionAnyValue introduced "any", and "*" for checkState storage values.
+	FormatVersionAnyValue FormatVersion = 32
+
+	// FormatVersionMatchers introduced the "match:regex:" and "match:range:" check values.
+	FormatVersionMatchers FormatVersion = 33
+
+	// FormatVersionMulti introduced the "multi:" prefix.
+	FormatVersionMulti FormatVersion = 34
+
+	// CurrentFormatVersion is the latest format version this library can parse and write.
+	CurrentFormatVersion = FormatVersionMulti
+)
+
+// TextFields are the scenario fields holding text, such as names, paths and tags, rather than values.
+// Their strings are not interpreted, so they never need a newer format version, whatever they look like.
+var TextFields = map[string]bool{
+	"abi":           true,
+	"allowedErrors": true,
+	"asyncCallData": true,
+	"comment":       true,
+	"field":         true,
+	"function":      true,
+	"gasSchedule":   true,
+	"id":            true,
+	"metadata":      true,
+	"name":          true,
+	"network":       true,
+	"path":          true,
+	"prefix":        true,
+	"seed":          true,
+	"step":          true,
+	"tags":          true,
+	"txId":          true,
+}
+
+// IsValid returns true if the version is one that this library knows about.
+func (v FormatVersion) IsValid() bool {
+	return v >= FormatVersionInitial && v <= CurrentFormatVersion
+}
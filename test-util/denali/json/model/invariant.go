@@ -0,0 +1,37 @@
+package denalijsonmodel
+
+// Invariant is a condition on the world state, checked after every step of the scenario,
+// so that violations are caught at the step where they occur.
+// Exactly one of ConstantSum and Value is set:
+// the sum of the ConstantSum quantities must stay what it was after the first step,
+// the Value quantity must pass the Expect check after every step.
+type Invariant struct {
+	Name        string
+	Comment     string
+	ConstantSum []*InvariantQuantity
+	Value       *InvariantQuantity
+	Expect      JSONCheckBigInt
+}
+
+// Fields of an account that invariants can refer to.
+const (
+	InvariantFieldBalance = "balance"
+	InvariantFieldNonce   = "nonce"
+
+	// InvariantFieldStorage is a storage value, taken as an unsigned number. Requires a key.
+	InvariantFieldStorage = "storage"
+)
+
+// InvariantQuantity is a number read from the world state.
+type InvariantQuantity struct {
+	Account JSONBytesFromString
+	Field   string
+
+	// Key is only set for storage values.
+	Key JSONBytesFromString
+}
+
+// IsConstantSum returns true for invariants on the sum of several quantities.
+func (inv *Invariant) IsConstantSum() bool {
+	return len(inv.ConstantSum) > 0
+}
@@ -0,0 +1,77 @@
+package denalijsonmodel
+
+import (
+	"reflect"
+
+	oj "github.com/numbatx/gn-vm-util/test-util/orderedjson"
+)
+
+// ScenarioSource is the ordered JSON tree that a scenario was parsed from, for tools that need to map
+// model objects back to their exact JSON nodes, e.g. formatters and IDE plugins.
+// Only kept when parsing with the KeepSource option. Not part of the JSON format.
+type ScenarioSource struct {
+	// Document holds the tree, the positions of its values, and the comments.
+	Document *oj.Document
+
+	nodes map[interface{}]oj.OJsonObject
+	paths map[oj.OJsonObject]string
+}
+
+// SourceNode is the JSON node that a model object was parsed from.
+type SourceNode struct {
+	Node oj.OJsonObject
+
+	// Path is the JSON path of the node, e.g. "steps[3].tx", as used by oj.Comments.
+	Path string
+
+	Position oj.Position
+}
+
+// NewScenarioSource prepares the source of a scenario, before its model objects get recorded.
+func NewScenarioSource(document *oj.Document) *ScenarioSource {
+	source := &ScenarioSource{
+		Document: document,
+		nodes:    make(map[interface{}]oj.OJsonObject),
+		paths:    make(map[oj.OJsonObject]string),
+	}
+	source.indexPaths(document.Root, "")
+	return source
+}
+
+func (s *ScenarioSource) indexPaths(node oj.OJsonObject, path string) {
+	s.paths[node] = path
+	switch specificNode := node.(type) {
+	case *oj.OJsonMap:
+		for _, kvp := range specificNode.OrderedKV {
+			s.indexPaths(kvp.Value, oj.ChildPath(path, kvp.Key))
+		}
+	case *oj.OJsonList:
+		for i, element := range specificNode.AsList() {
+			s.indexPaths(element, oj.ElementPath(path, i))
+		}
+	}
+}
+
+// Record remembers the node that a model object was parsed from. The object must be a pointer.
+func (s *ScenarioSource) Record(modelObject interface{}, node oj.OJsonObject) {
+	s.nodes[modelObject] = node
+}
+
+// Node yields the JSON node that a model object was parsed from: the scenario, its steps, transactions,
+// expected results, accounts, checked accounts and invariants. False for other objects,
+// and for the ones that were not written in the scenario, e.g. accounts expanded from generateAccounts.
+func (s *ScenarioSource) Node(modelObject interface{}) (*SourceNode, bool) {
+	if s == nil || reflect.ValueOf(modelObject).Kind() != reflect.Ptr {
+		// values are not recorded, and some of them cannot be map keys
+		return nil, false
+	}
+	node, found := s.nodes[modelObject]
+	if !found {
+		return nil, false
+	}
+	return &SourceNode{
+		Node:     node,
+		Path:     s.paths[node],
+		Position: s.Document.Positions[node],
+	}, true
+}
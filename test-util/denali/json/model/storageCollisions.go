@@ -0,0 +1,82 @@
+package denalijsonmodel
+
+import (
+	"encoding/hex"
+	"fmt"
+	"strings"
+)
+
+// StorageKeyCollision describes storage entries of the same account whose keys
+// are written differently, but resolve to the same bytes, e.g. "0x01", "u8:1" and "1".
+// Only one of them takes effect, which makes for confusing results.
+type StorageKeyCollision struct {
+	StepIndex      int
+	StepType       string
+	AccountAddress string
+	Key            []byte
+	Originals      []string
+}
+
+// String yields a human-readable description of the collision.
+func (c *StorageKeyCollision) String() string {
+	return fmt.Sprintf("step %d (%s), account %s: storage keys %s all resolve to 0x%s",
+		c.StepIndex,
+		c.StepType,
+		c.AccountAddress,
+		strings.Join(c.Originals, ", "),
+		hex.EncodeToString(c.Key))
+}
+
+// FindStorageKeyCollisions checks all setState and checkState steps of a scenario
+// for storage keys that collide within the same account.
+func FindStorageKeyCollisions(scenario *Scenario) []*StorageKeyCollision {
+	var collisions []*StorageKeyCollision
+	for stepIndex, generalStep := range scenario.Steps {
+		switch step := generalStep.(type) {
+		case *SetStateStep:
+			for _, account := range step.Accounts {
+				collisions = appendStorageKeyCollisions(collisions, stepIndex, step, account.Address, account.Storage)
+			}
+		case *CheckStateStep:
+			if step.CheckAccounts == nil {
+				continue
+			}
+			for _, checkAccount := range step.CheckAccounts.Accounts {
+				collisions = appendStorageKeyCollisions(collisions, stepIndex, step, checkAccount.Address, checkAccount.CheckStorage)
+			}
+		}
+	}
+	return collisions
+}
+
+func appendStorageKeyCollisions(
+	collisions []*StorageKeyCollision,
+	stepIndex int,
+	step Step,
+	address JSONBytesFromString,
+	storage []*StorageKeyValuePair) []*StorageKeyCollision {
+
+	originalsByKey := make(map[string][]string)
+	var keyOrder []string
+	for _, kvp := range storage {
+		key := string(kvp.Key.Value)
+		if _, seen := originalsByKey[key]; !seen {
+			keyOrder = append(keyOrder, key)
+		}
+		originalsByKey[key] = append(originalsByKey[key], kvp.Key.Original)
+	}
+
+	for _, key := range keyOrder {
+		originals := originalsByKey[key]
+		if len(originals) > 1 {
+			collisions = append(collisions, &StorageKeyCollision{
+				StepIndex:      stepIndex,
+				StepType:       step.StepTypeName(),
+				AccountAddress: address.Original,
+				Key:            []byte(key),
+				Originals:      originals,
+			})
+		}
+	}
+	return collisions
+}
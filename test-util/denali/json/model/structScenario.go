@@ -1,11 +1,128 @@
 package denalijsonmodel
 
+import (
+	"fmt"
+
+	abi "github.com/numbatx/gn-vm-util/test-util/denali/json/abi"
+	oj "github.com/numbatx/gn-vm-util/test-util/orderedjson"
+)
+
 // Scenario is a json object representing a test scenario with steps.
 type Scenario struct {
-	Name     string
-	Comment  string
-	CheckGas bool
-	Steps    []Step
+	Name                  string
+	Comment               string
+	Metadata              *ScenarioMetadata     // nil if unspecified
+	SchemaVersion         FormatVersion         // 0 if unspecified, the current version once parsed, see the parser migrations
+	RequiresFormatVersion FormatVersion         // 0 if unspecified
+	ABIPath               string                // as written in the scenario, relative to it
+	ABI                   *abi.ABI              // loaded from ABIPath, nil if unspecified
+	GasSchedule           *GasScheduleReference // nil if unspecified, executors then use their default schedule
+	Config                *ScenarioConfig       // nil if unspecified
+	CheckGas              bool
+	AutoNonces            bool // transaction nonces can be omitted, see Transaction.Nonce
+	Constants             []*NamedConstant
+	Defines               []*NamedConstant // referenced as "$NAME"
+	Parameters            []*NamedConstant // referenced as "$NAME", with the values supplied by the including scenario
+	GasPresets            []*GasPreset
+	Invariants            []*Invariant // checked after every step
+	Steps                 []Step
+
+	// Comments are the "//" and "/* */" comments of the scenario file, nil if there are none.
+	// They are attached to JSON paths, so the writer can put them back.
+	Comments *oj.Comments
+
+	// InlinedFiles lists the values that loaded files and were replaced by their contents,
+	// only when parsing with the InlineFiles option. Not part of the JSON format.
+	InlinedFiles []*InlinedFile
+
+	// Source is the JSON tree the scenario was parsed from, only when parsing with the KeepSource option.
+	Source *ScenarioSource
+
+	// StepPositions holds where each step starts in the scenario file, so that reports can point to failing steps.
+	// Empty for scenarios built in code. Not part of the JSON format.
+	StepPositions []oj.Position
+}
+
+// InlinedFile records a value that was written inline instead of loading files, e.g. "file:adder.wasm".
+type InlinedFile struct {
+	// Original is the value as written in the scenario, in JSON for values that are not strings.
+	Original string
+
+	// Paths are the absolute paths of the files that the value loaded, in order.
+	Paths []string
+}
+
+// GasScheduleReference pins the gas schedule that the gas expectations of a scenario were written for,
+// so that they do not break whenever the default schedule changes.
+type GasScheduleReference struct {
+	// Original is the value as written in the scenario, e.g. "v4" or "gasSchedules/gasScheduleV4.toml".
+	Original string
+
+	// Version names one of the schedules built into the executor, e.g. "v4". Empty for files.
+	Version string
+
+	// Path is the TOML gas config file, as written, relative to the scenario. Empty for versions.
+	Path string
+
+	// Content is the TOML gas config, loaded from Path by the parser. Nil for versions.
+	Content []byte
+
+	// Costs are the gas costs of Content, by TOML table and key, e.g. Costs["BaseOperationCost"]["StorePerByte"].
+	// Nil for versions.
+	Costs map[string]map[string]uint64
+}
+
+// ScenarioConfig holds the defaults of a scenario, so that they are not repeated in every step.
+// The parser fills in the transaction fields they stand for, the executor receives the rest.
+type ScenarioConfig struct {
+	// GasPrice is the gas price of the transactions that omit it. Empty original if unspecified.
+	GasPrice JSONUint64
+
+	// Caller is the sender of the transactions that omit "from". Empty original if unspecified.
+	Caller JSONBytesFromString
+
+	// ChainID is the chain ID that executors run the scenario with. Empty original if unspecified.
+	ChainID JSONBytesFromString
+
+	// Seed is the interpreter seed of the scenario, instead of the one of the suite, see vi.ValueInterpreter.Seed.
+	// Empty if unspecified.
+	Seed string
+}
+
+// ScenarioMetadata describes a scenario for tooling, e.g. compliance manifests. It does not affect execution.
+type ScenarioMetadata struct {
+	Owner   string
+	License string
+	Tags    []string
+
+	// Flavor restricts what the scenario can contain, empty for regular scenarios. See ScenarioFlavorView.
+	Flavor string
+
+	// Priority is a hint for runners, scenarios with higher priority run first. Empty original if unspecified.
+	Priority JSONUint64
+}
+
+// ScenarioFlavorView marks view scenarios, which only contain setState and scQuery steps.
+// They can be run by read-only executors, without a transaction pipeline.
+const ScenarioFlavorView = "view"
+
+// IsView returns true for view scenarios, see ScenarioFlavorView.
+func (s *Scenario) IsView() bool {
+	return s.Metadata != nil && s.Metadata.Flavor == ScenarioFlavorView
+}
+
+// NamedConstant is a value defined once per scenario, referenced in steps as "const:NAME",
+// or as "$NAME" for defines.
+type NamedConstant struct {
+	Name  string
+	Value JSONBytesFromTree
+}
+
+// GasPreset is a named gas limit or price, referenced in transactions as "preset:NAME",
+// so that gas can be tuned for many scenarios in one place.
+type GasPreset struct {
+	Name  string
+	Value JSONUint64
 }
 
 // Step is the basic block of a scenario.
@@ -28,36 +145,139 @@ type BlockInfo struct {
 	BlockEpoch     JSONUint64
 }
 
+// StepMetadata is what any step can carry besides what it does, embedded in all step types.
+type StepMetadata struct {
+	// ID names the step, unique in its scenario, so that failures, reports and tools can refer to it.
+	ID string
+
+	// Tags group steps, e.g. to only run or report some of them.
+	Tags []string
+
+	Comment string
+}
+
+// Metadata yields the metadata of the step, for reading or changing it whatever the step type.
+func (m *StepMetadata) Metadata() *StepMetadata {
+	return m
+}
+
+// HasTag returns true if the step has the given tag.
+func (m *StepMetadata) HasTag(tag string) bool {
+	for _, stepTag := range m.Tags {
+		if stepTag == tag {
+			return true
+		}
+	}
+	return false
+}
+
+// StepMetadataOf yields the metadata of a step, nil for step types defined elsewhere, without metadata.
+func StepMetadataOf(step Step) *StepMetadata {
+	withMetadata, hasMetadata := step.(interface{ Metadata() *StepMetadata })
+	if !hasMetadata {
+		return nil
+	}
+	return withMetadata.Metadata()
+}
+
+// DescribeStep names a step in messages, by index, and by id if it has one, e.g. "step 3 (deposit)".
+func DescribeStep(stepIndex int, step Step) string {
+	if metadata := StepMetadataOf(step); metadata != nil && len(metadata.ID) > 0 {
+		return fmt.Sprintf("step %d (%s)", stepIndex, metadata.ID)
+	}
+	return fmt.Sprintf("step %d", stepIndex)
+}
+
 // ExternalStepsStep allows including steps from another file
 type ExternalStepsStep struct {
+	StepMetadata
 	Path string
+
+	// ResolvedPath is the path of the included file, resolved at parse time relative to the including file.
+	// Executors should prefer it to resolving Path later, when the resolver context might have changed.
+	ResolvedPath string
+
+	// Arguments supply the parameters of the included scenario, see Scenario.Parameters.
+	// Interpreted in the including scenario, so they can reference its own defines.
+	Arguments []*NamedConstant
 }
 
 // SetStateStep is a step where data is saved to the blockchain mock.
+// Accounts also contains the accounts expanded from GenerateAccounts, after the explicit ones.
 type SetStateStep struct {
-	Comment           string
+	StepMetadata
 	Accounts          []*Account
+	GenerateAccounts  *GenerateAccounts
 	PreviousBlockInfo *BlockInfo
 	CurrentBlockInfo  *BlockInfo
 	BlockHashes       []JSONBytesFromString
 	NewAddressMocks   []*NewAddressMock
+
+	// AllowedErrors lists the operations, among the SetStateOperation... constants,
+	// that executors may fail to perform without failing the step. Such failures are reported as warnings.
+	AllowedErrors []string
+}
+
+// Operations of a setState step that can be declared best-effort, through "allowedErrors".
+// Executors with a different world model might not support all of them.
+const (
+	// SetStateOperationCreateAccount is the creation of an account, e.g. one that already exists.
+	SetStateOperationCreateAccount = "createAccount"
+
+	// SetStateOperationNewAddress is the registration of a mocked new address.
+	SetStateOperationNewAddress = "newAddress"
+
+	// SetStateOperationBlockInfo is the setting of the previous or current block info.
+	SetStateOperationBlockInfo = "blockInfo"
+
+	// SetStateOperationBlockHashes is the setting of the block hashes.
+	SetStateOperationBlockHashes = "blockHashes"
+)
+
+// IsSetStateOperation returns true for the operation names that can be given in "allowedErrors".
+func IsSetStateOperation(operation string) bool {
+	switch operation {
+	case SetStateOperationCreateAccount, SetStateOperationNewAddress,
+		SetStateOperationBlockInfo, SetStateOperationBlockHashes:
+		return true
+	default:
+		return false
+	}
+}
+
+// IsErrorAllowed returns true if a failure of the given operation should not fail the step.
+func (step *SetStateStep) IsErrorAllowed(operation string) bool {
+	for _, allowed := range step.AllowedErrors {
+		if allowed == operation {
+			return true
+		}
+	}
+	return false
+}
+
+// GenerateAccounts is a template for many similar accounts, useful for load scenarios.
+// Generated account i, counting from 0, gets the address "address:<prefix><i>".
+type GenerateAccounts struct {
+	Count   JSONUint64
+	Prefix  string
+	Balance JSONBigInt
 }
 
 // CheckStateStep is a step where the state of the blockchain mock is verified.
 type CheckStateStep struct {
-	Comment       string
+	StepMetadata
 	CheckAccounts *CheckAccounts
 }
 
 // DumpStateStep is a step that simply prints the entire state to console. Useful for debugging.
 type DumpStateStep struct {
-	Comment string
+	StepMetadata
 }
 
 // TxStep is a step where a transaction is executed.
 type TxStep struct {
+	StepMetadata
 	TxIdent        string
-	Comment        string
 	Tx             *Transaction
 	ExpectedResult *TransactionResult
 }
@@ -112,6 +332,9 @@ const StepNameTransfer = "transfer"
 // StepNameValidatorReward is a json step type name.
 const StepNameValidatorReward = "validatorReward"
 
+// StepNameScQuery is a json step type name.
+const StepNameScQuery = "scQuery"
+
 // StepTypeName type as string
 func (t *TxStep) StepTypeName() string {
 	switch t.Tx.Type {
@@ -123,6 +346,8 @@ func (t *TxStep) StepTypeName() string {
 		return StepNameTransfer
 	case ValidatorReward:
 		return StepNameValidatorReward
+	case ScQuery:
+		return StepNameScQuery
 	default:
 		panic("unknown TransactionType")
 	}
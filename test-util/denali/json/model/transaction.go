@@ -16,11 +16,14 @@ const (
 	// ValidatorReward is when the protocol sends a validator reward to the target account.
 	// It increases the balance, but also increments "NUMBAT_Reward" in storage.
 	ValidatorReward
+
+	// ScQuery calls a view function, without changing the state. It has no sender, value or gas.
+	ScQuery
 )
 
 // HasSender is a helper function to indicate if transaction has `to` field.
 func (tt TransactionType) HasSender() bool {
-	return tt != ValidatorReward
+	return tt != ValidatorReward && tt != ScQuery
 }
 
 // HasReceiver is a helper function to indicate if transaction has receiver.
@@ -30,13 +33,22 @@ func (tt TransactionType) HasReceiver() bool {
 
 // IsSmartContractTx indicates whether tx type allows an `expect` field.
 func (tt TransactionType) IsSmartContractTx() bool {
-	return tt == ScDeploy || tt == ScCall
+	return tt == ScDeploy || tt == ScCall || tt == ScQuery
+}
+
+// HasValueAndGas indicates whether the transaction transfers value and pays for gas.
+func (tt TransactionType) HasValueAndGas() bool {
+	return tt != ScQuery
 }
 
 // Transaction is a json object representing a transaction.
 type Transaction struct {
-	Type      TransactionType
-	Nonce     JSONUint64
+	Type TransactionType
+
+	// Nonce is assigned by the parser when omitted in a scenario with AutoNonces,
+	// in which case Nonce.Original stays empty.
+	Nonce JSONUint64
+
 	Value     JSONBigInt
 	From      JSONBytesFromString
 	To        JSONBytesFromString
@@ -45,6 +57,15 @@ type Transaction struct {
 	Arguments []JSONBytesFromTree
 	GasPrice  JSONUint64
 	GasLimit  JSONUint64
+
+	// Data is the optional payload of transfer transactions, e.g. a note for the receiver.
+	// Original is nil if there is none.
+	Data JSONBytesFromTree
+
+	// FromConfig and GasPriceConfig are set when From and GasPrice were omitted, and filled in by the parser
+	// from the scenario config. They are not written then, see ScenarioConfig.
+	FromConfig     bool
+	GasPriceConfig bool
 }
 
 // TransactionResult is a json object representing an expected transaction result.
@@ -7,12 +7,21 @@ import (
 	oj "github.com/numbatx/gn-vm-util/test-util/orderedjson"
 )
 
+// ValueMatcher is a check condition more tolerant than equality, e.g. "match:range:100..200".
+// The value interpreter produces them.
+type ValueMatcher interface {
+	MatchBytes(actual []byte) bool
+	MatchBigInt(actual *big.Int) bool
+	String() string
+}
+
 // JSONCheckBytes holds a byte slice condition.
-// Values are checked for equality.
+// Values are checked for equality, or using the matcher, if there is one.
 // "*" allows all values.
 type JSONCheckBytes struct {
 	Value    []byte
 	IsStar   bool
+	Matcher  ValueMatcher
 	Original oj.OJsonObject
 }
 
@@ -53,15 +62,19 @@ func (jcbytes JSONCheckBytes) Check(other []byte) bool {
 	if jcbytes.IsStar {
 		return true
 	}
+	if jcbytes.Matcher != nil {
+		return jcbytes.Matcher.MatchBytes(other)
+	}
 	return bytes.Equal(jcbytes.Value, other)
 }
 
 // JSONCheckBigInt holds a big int condition.
-// Values are checked for equality.
+// Values are checked for equality, or using the matcher, if there is one.
 // "*" allows all values.
 type JSONCheckBigInt struct {
 	Value    *big.Int
 	IsStar   bool
+	Matcher  ValueMatcher
 	Original string
 }
 
@@ -85,15 +98,19 @@ func (jcbi JSONCheckBigInt) Check(other *big.Int) bool {
 	if jcbi.IsStar {
 		return true
 	}
+	if jcbi.Matcher != nil {
+		return jcbi.Matcher.MatchBigInt(other)
+	}
 	return jcbi.Value.Cmp(other) == 0
 }
 
 // JSONCheckUint64 holds a uint64 condition.
-// Values are checked for equality.
+// Values are checked for equality, or using the matcher, if there is one.
 // "*" allows all values.
 type JSONCheckUint64 struct {
 	Value    uint64
 	IsStar   bool
+	Matcher  ValueMatcher
 	Original string
 }
 
@@ -117,5 +134,8 @@ func (jcu JSONCheckUint64) Check(other uint64) bool {
 	if jcu.IsStar {
 		return true
 	}
+	if jcu.Matcher != nil {
+		return jcu.Matcher.MatchBigInt(big.NewInt(0).SetUint64(other))
+	}
 	return jcu.Value == other
 }
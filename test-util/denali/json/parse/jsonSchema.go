@@ -0,0 +1,214 @@
+package denalijsonparse
+
+import (
+	"sort"
+	"strings"
+
+	oj "github.com/numbatx/gn-vm-util/test-util/orderedjson"
+)
+
+// JSONSchemaDialect is the version of JSON Schema that the generated schemas conform to.
+const JSONSchemaDialect = "https://json-schema.org/draft/2020-12/schema"
+
+// testAccountMapSchema, testBlockSchema and testSchema describe the older test format.
+// Unlike the scenario schema, they are not used for validation, only for generating its JSON Schema.
+var testAccountMapSchema = mapOf(accountSchema)
+
+var testBlockSchema = &schema{
+	name: "block",
+	fields: map[string]*schema{
+		"results": listOf(txResultSchema),
+		"transactions": listOf(&schema{
+			name: "block transaction",
+			fields: map[string]*schema{
+				"nonce":        nil,
+				"from":         nil,
+				"to":           nil,
+				"function":     nil,
+				"value":        nil,
+				"arguments":    nil,
+				"data":         nil,
+				"contractCode": nil,
+				"gasPrice":     nil,
+				"gasLimit":     nil,
+			},
+		}),
+		"blockHeader": {
+			name: "block header",
+			fields: map[string]*schema{
+				"gasLimit":   nil,
+				"number":     nil,
+				"difficulty": nil,
+				"timestamp":  nil,
+				"coinbase":   nil,
+			},
+		},
+	},
+}
+
+var testSchema = &schema{
+	name: "test",
+	fields: map[string]*schema{
+		"checkGas":    nil,
+		"pre":         testAccountMapSchema,
+		"blocks":      listOf(testBlockSchema),
+		"network":     nil,
+		"blockHashes": nil,
+		"postState":   testAccountMapSchema,
+	},
+}
+
+// ScenarioJSONSchema yields a JSON Schema describing scenario files, for editors to offer completion and validation.
+// It is generated from the tables that the parser validates scenarios against, so it accepts the same fields.
+// Like them, it only describes maps and lists: the values themselves, e.g. "biguint:5", are left unconstrained.
+func ScenarioJSONSchema() string {
+	g := newJSONSchemaGenerator()
+	root := g.describeMap(scenarioSchema)
+
+	stepTypes := make([]string, 0, len(stepSchemas))
+	for stepType := range stepSchemas {
+		stepTypes = append(stepTypes, stepType)
+	}
+	sort.Strings(stepTypes)
+	var stepList oj.OJsonList
+	for _, stepType := range stepTypes {
+		step := g.describeMap(stepSchemas[stepType])
+		setField(step, "required", stringList(append([]string{"step"}, stepSchemas[stepType].required...)))
+		setProperty(step, "step", objectOf("const", &oj.OJsonString{Value: stepType}))
+		stepList = append(stepList, g.define(stepSchemas[stepType].name, step))
+	}
+	// the "step" field selects exactly one of the alternatives
+	setProperty(root, "steps", objectOf("items", objectOf("oneOf", &stepList)))
+
+	return g.document("scenario", root)
+}
+
+// TestJSONSchema is ScenarioJSONSchema, for the older test format: a map of tests, keyed by name.
+func TestJSONSchema() string {
+	g := newJSONSchemaGenerator()
+	return g.document("test", objectOf("additionalProperties", g.describe(testSchema)))
+}
+
+type jsonSchemaGenerator struct {
+	defs    *oj.OJsonMap
+	defined map[*schema]oj.OJsonObject
+}
+
+func newJSONSchemaGenerator() *jsonSchemaGenerator {
+	return &jsonSchemaGenerator{
+		defs:    oj.NewMap(),
+		defined: make(map[*schema]oj.OJsonObject),
+	}
+}
+
+// document wraps the description of the top level, with the definitions it references.
+func (g *jsonSchemaGenerator) document(title string, root *oj.OJsonMap) string {
+	doc := oj.NewMap()
+	doc.Put("$schema", &oj.OJsonString{Value: JSONSchemaDialect})
+	doc.Put("title", &oj.OJsonString{Value: title})
+	doc.Put("type", &oj.OJsonString{Value: "object"})
+	for _, kvp := range root.OrderedKV {
+		doc.Put(kvp.Key, kvp.Value)
+	}
+	if g.defs.Size() > 0 {
+		doc.Put("$defs", g.defs)
+	}
+	return oj.JSONString(doc) + "\n"
+}
+
+// describe yields the JSON Schema of a value. Named maps are defined once, then referenced.
+// No "type" is given: as in validateScenario, values of an unexpected kind are left to the parser.
+func (g *jsonSchemaGenerator) describe(s *schema) oj.OJsonObject {
+	if s == nil {
+		return oj.NewMap()
+	}
+	if s.elements != nil {
+		return objectOf("items", g.describe(s.elements))
+	}
+	if s.entries != nil {
+		return objectOf("additionalProperties", g.describe(s.entries))
+	}
+	if ref, found := g.defined[s]; found {
+		return ref
+	}
+	ref := g.define(s.name, g.describeMap(s))
+	g.defined[s] = ref
+	return ref
+}
+
+// describeMap yields the JSON Schema of a map with fixed keys, inline.
+func (g *jsonSchemaGenerator) describeMap(s *schema) *oj.OJsonMap {
+	result := oj.NewMap()
+	if s.fields == nil {
+		return result
+	}
+	fieldNames := make([]string, 0, len(s.fields))
+	for field := range s.fields {
+		fieldNames = append(fieldNames, field)
+	}
+	sort.Strings(fieldNames)
+	properties := oj.NewMap()
+	for _, field := range fieldNames {
+		properties.Put(field, g.describe(s.fields[field]))
+	}
+	result.Put("properties", properties)
+	if len(s.required) > 0 {
+		result.Put("required", stringList(s.required))
+	}
+	ojFalse := oj.OJsonBool(false)
+	result.Put("additionalProperties", &ojFalse)
+	if len(s.exclusive) > 0 {
+		var exclusions oj.OJsonList
+		for _, pair := range s.exclusive {
+			exclusions = append(exclusions, objectOf("not", objectOf("required", stringList(pair[:]))))
+		}
+		result.Put("allOf", &exclusions)
+	}
+	return result
+}
+
+// define adds a definition, named after the schema, e.g. "block info" is defined as "blockInfo",
+// and yields a reference to it.
+func (g *jsonSchemaGenerator) define(name string, description *oj.OJsonMap) oj.OJsonObject {
+	words := strings.Fields(name)
+	for i := 1; i < len(words); i++ {
+		words[i] = strings.ToUpper(words[i][:1]) + words[i][1:]
+	}
+	defName := strings.Join(words, "")
+	g.defs.Put(defName, description)
+	return objectOf("$ref", &oj.OJsonString{Value: "#/$defs/" + defName})
+}
+
+// setProperty replaces the schema of a field of a map described by describeMap.
+func setProperty(description *oj.OJsonMap, field string, value oj.OJsonObject) {
+	for _, kvp := range description.OrderedKV {
+		if kvp.Key == "properties" {
+			setField(kvp.Value.(*oj.OJsonMap), field, value)
+		}
+	}
+}
+
+// setField is Put, replacing the value of existing keys.
+func setField(obj *oj.OJsonMap, key string, value oj.OJsonObject) {
+	for _, kvp := range obj.OrderedKV {
+		if kvp.Key == key {
+			kvp.Value = value
+			return
+		}
+	}
+	obj.Put(key, value)
+}
+
+func objectOf(key string, value oj.OJsonObject) *oj.OJsonMap {
+	result := oj.NewMap()
+	result.Put(key, value)
+	return result
+}
+
+func stringList(values []string) *oj.OJsonList {
+	list := make(oj.OJsonList, len(values))
+	for i, value := range values {
+		list[i] = &oj.OJsonString{Value: value}
+	}
+	return &list
+}
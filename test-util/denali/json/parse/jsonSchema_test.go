@@ -0,0 +1,57 @@
+package denalijsonparse
+
+import (
+	"encoding/json"
+	"regexp"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestScenarioJSONSchema(t *testing.T) {
+	var doc map[string]interface{}
+	require.Nil(t, json.Unmarshal([]byte(ScenarioJSONSchema()), &doc))
+	require.Equal(t, JSONSchemaDialect, doc["$schema"])
+	requireRefsDefined(t, ScenarioJSONSchema(), doc)
+
+	defs := doc["$defs"].(map[string]interface{})
+	for stepType, stepSchema := range stepSchemas {
+		step := defs[stepType+"Step"].(map[string]interface{})
+		properties := step["properties"].(map[string]interface{})
+		require.Equal(t, map[string]interface{}{"const": stepType}, properties["step"])
+		require.Equal(t, len(stepSchema.fields), len(properties))
+		require.Contains(t, step["required"], "step")
+	}
+	require.Equal(t, []interface{}{"step", "tx"}, defs["scCallStep"].(map[string]interface{})["required"])
+
+	account := defs["account"].(map[string]interface{})
+	require.Contains(t, account["properties"], "balance")
+	require.Equal(t, false, account["additionalProperties"])
+
+	invariant := defs["invariant"].(map[string]interface{})
+	require.Equal(t, []interface{}{
+		map[string]interface{}{"not": map[string]interface{}{"required": []interface{}{"constantSum", "value"}}},
+		map[string]interface{}{"not": map[string]interface{}{"required": []interface{}{"constantSum", "expect"}}},
+	}, invariant["allOf"])
+}
+
+func TestTestJSONSchema(t *testing.T) {
+	var doc map[string]interface{}
+	require.Nil(t, json.Unmarshal([]byte(TestJSONSchema()), &doc))
+	requireRefsDefined(t, TestJSONSchema(), doc)
+	require.Equal(t, map[string]interface{}{"$ref": "#/$defs/test"}, doc["additionalProperties"])
+
+	test := doc["$defs"].(map[string]interface{})["test"].(map[string]interface{})
+	require.Equal(t, map[string]interface{}{
+		"additionalProperties": map[string]interface{}{"$ref": "#/$defs/account"},
+	}, test["properties"].(map[string]interface{})["pre"])
+}
+
+func requireRefsDefined(t *testing.T, jsonSchema string, doc map[string]interface{}) {
+	defs := doc["$defs"].(map[string]interface{})
+	refs := regexp.MustCompile(`"#/\$defs/([^"]*)"`).FindAllStringSubmatch(jsonSchema, -1)
+	require.NotEmpty(t, refs)
+	for _, ref := range refs {
+		require.Contains(t, defs, ref[1])
+	}
+}
@@ -3,6 +3,7 @@ package denalijsonparse
 import (
 	"errors"
 	"fmt"
+	"math/big"
 
 	mj "github.com/numbatx/gn-vm-util/test-util/denali/json/model"
 	oj "github.com/numbatx/gn-vm-util/test-util/orderedjson"
@@ -12,11 +13,15 @@ func (p *Parser) parseAccountAddress(addrRaw string) (mj.JSONBytesFromString, er
 	if len(addrRaw) == 0 {
 		return mj.JSONBytesFromString{}, errors.New("missing account address")
 	}
-	addrBytes, err := p.ValueInterpreter.InterpretString(addrRaw)
-	if err == nil && len(addrBytes) != 32 {
+	referencesBefore := p.fileReferenceCount()
+	addrBytes, err := p.interpretString(addrRaw)
+	if err != nil {
+		return mj.NewJSONBytesFromString(addrBytes, addrRaw), err
+	}
+	if len(addrBytes) != 32 {
 		return mj.JSONBytesFromString{}, errors.New("account addressis not 32 bytes in length")
 	}
-	return mj.NewJSONBytesFromString(addrBytes, addrRaw), err
+	return mj.NewJSONBytesFromString(addrBytes, p.inlinedString(addrRaw, addrBytes, referencesBefore)), nil
 }
 
 func (p *Parser) processAccount(acctRaw oj.OJsonObject) (*mj.Account, error) {
@@ -51,16 +56,18 @@ func (p *Parser) processAccount(acctRaw oj.OJsonObject) (*mj.Account, error) {
 				return nil, errors.New("invalid account storage")
 			}
 			for _, storageKvp := range storageMap.OrderedKV {
-				byteKey, err := p.ValueInterpreter.InterpretString(storageKvp.Key)
+				referencesBefore := p.fileReferenceCount()
+				byteKey, err := p.interpretString(storageKvp.Key)
 				if err != nil {
 					return nil, fmt.Errorf("invalid account storage key: %w", err)
 				}
+				inlinedKey := p.inlinedString(storageKvp.Key, byteKey, referencesBefore)
 				byteVal, err := p.processSubTreeAsByteArray(storageKvp.Value)
 				if err != nil {
 					return nil, fmt.Errorf("invalid account storage value: %w", err)
 				}
 				stElem := mj.StorageKeyValuePair{
-					Key:   mj.NewJSONBytesFromString(byteKey, storageKvp.Key),
+					Key:   mj.NewJSONBytesFromString(byteKey, inlinedKey),
 					Value: byteVal,
 				}
 				acct.Storage = append(acct.Storage, &stElem)
@@ -99,6 +106,7 @@ func (p *Parser) processAccountMap(acctMapRaw oj.OJsonObject) ([]*mj.Account, er
 			return nil, hexErr
 		}
 		acct.Address = acctAddr
+		p.recordSource(acct, acctKVP.Value)
 		accounts = append(accounts, acct)
 
 	}
@@ -146,17 +154,25 @@ func (p *Parser) processCheckAccount(acctRaw oj.OJsonObject) (*mj.CheckAccount,
 					return nil, errors.New("invalid account storage")
 				}
 				for _, storageKvp := range storageMap.OrderedKV {
-					byteKey, err := p.ValueInterpreter.InterpretString(storageKvp.Key)
+					referencesBefore := p.fileReferenceCount()
+					byteKey, err := p.interpretString(storageKvp.Key)
 					if err != nil {
 						return nil, fmt.Errorf("invalid account storage key: %w", err)
 					}
-					byteVal, err := p.processSubTreeAsByteArray(storageKvp.Value)
+					inlinedKey := p.inlinedString(storageKvp.Key, byteKey, referencesBefore)
+					referencesBefore = p.fileReferenceCount()
+					checkVal, err := p.ValueInterpreter.InterpretCheckSubTree(storageKvp.Value)
 					if err != nil {
 						return nil, fmt.Errorf("invalid account storage value: %w", err)
 					}
 					stElem := mj.StorageKeyValuePair{
-						Key:   mj.NewJSONBytesFromString(byteKey, storageKvp.Key),
-						Value: byteVal,
+						Key: mj.NewJSONBytesFromString(byteKey, inlinedKey),
+						Value: mj.JSONBytesFromTree{
+							Value:    checkVal.Value,
+							Original: p.inlinedTree(storageKvp.Value, checkVal.Value, referencesBefore),
+						},
+						AnyValue: checkVal.Any,
+						Matcher:  checkVal.Matcher,
 					}
 					acct.CheckStorage = append(acct.CheckStorage, &stElem)
 				}
@@ -202,8 +218,94 @@ func (p *Parser) processCheckAccountMap(acctMapRaw oj.OJsonObject) (*mj.CheckAcc
 				return nil, hexErr
 			}
 			acct.Address = acctAddr
+			p.recordSource(acct, acctKVP.Value)
 			checkAccounts.Accounts = append(checkAccounts.Accounts, acct)
 		}
 	}
 	return checkAccounts, nil
 }
+
+// maxGeneratedAccounts is the largest count generateAccounts accepts, each account is created up front.
+const maxGeneratedAccounts = 1000000
+
+func (p *Parser) processGenerateAccounts(generateRaw oj.OJsonObject) (*mj.GenerateAccounts, error) {
+	generateMap, isMap := generateRaw.(*oj.OJsonMap)
+	if !isMap {
+		return nil, errors.New("unmarshalled generateAccounts object is not a map")
+	}
+
+	generate := mj.GenerateAccounts{}
+	hasCount := false
+	var err error
+
+	for _, kvp := range generateMap.OrderedKV {
+		switch kvp.Key {
+		case "count":
+			generate.Count, err = p.processUint64(kvp.Value)
+			if err != nil {
+				return nil, errors.New("invalid generateAccounts count")
+			}
+			if generate.Count.Value > maxGeneratedAccounts {
+				return nil, p.locate("count", kvp.Value,
+					fmt.Errorf("too many generated accounts, at most %d allowed: %d", maxGeneratedAccounts, generate.Count.Value))
+			}
+			hasCount = true
+		case "prefix":
+			generate.Prefix, err = p.parseString(kvp.Value)
+			if err != nil {
+				return nil, fmt.Errorf("invalid generateAccounts prefix: %w", err)
+			}
+		case "balance":
+			generate.Balance, err = p.processBigInt(kvp.Value, bigIntUnsignedBytes)
+			if err != nil {
+				return nil, errors.New("invalid generateAccounts balance")
+			}
+		default:
+			return nil, fmt.Errorf("unknown generateAccounts field: %s", kvp.Key)
+		}
+	}
+
+	if !hasCount {
+		return nil, errors.New("missing generateAccounts count")
+	}
+	if len(generate.Prefix) == 0 {
+		return nil, errors.New("missing generateAccounts prefix")
+	}
+	return &generate, nil
+}
+
+// expandGenerateAccounts appends the accounts described by the template to the explicit ones.
+func (p *Parser) expandGenerateAccounts(generate *mj.GenerateAccounts, accounts []*mj.Account) ([]*mj.Account, error) {
+	usedAddresses := make(map[string]bool, len(accounts)+int(generate.Count.Value))
+	for _, acct := range accounts {
+		usedAddresses[string(acct.Address.Value)] = true
+	}
+
+	balance := big.NewInt(0)
+	if generate.Balance.Value != nil {
+		balance = generate.Balance.Value
+	}
+
+	for i := uint64(0); i < generate.Count.Value; i++ {
+		acctAddr, err := p.parseAccountAddress(fmt.Sprintf("address:%s%d", generate.Prefix, i))
+		if err != nil {
+			return nil, err
+		}
+		if usedAddresses[string(acctAddr.Value)] {
+			return nil, fmt.Errorf("generated account address already in use: %s", acctAddr.Original)
+		}
+		usedAddresses[string(acctAddr.Value)] = true
+
+		accounts = append(accounts, &mj.Account{
+			Address: acctAddr,
+			Nonce:   mj.JSONUint64{Value: 0, Original: "0"},
+			Balance: mj.JSONBigInt{
+				Value:    new(big.Int).Set(balance),
+				Original: balance.String(),
+			},
+			Code:      mj.JSONBytesFromString{Value: []byte{}, Original: ""},
+			Generated: true,
+		})
+	}
+	return accounts, nil
+}
@@ -0,0 +1,93 @@
+package denalijsonparse
+
+import (
+	"errors"
+	"fmt"
+
+	mj "github.com/numbatx/gn-vm-util/test-util/denali/json/model"
+	oj "github.com/numbatx/gn-vm-util/test-util/orderedjson"
+)
+
+// processConfig parses the scenario defaults, needed before the steps, wherever the field is.
+// Values can reference the suite constants and the parameters, the scenario constants are not known yet.
+func (p *Parser) processConfig(topMap *oj.OJsonMap) (*mj.ScenarioConfig, error) {
+	for _, kvp := range topMap.OrderedKV {
+		if kvp.Key != "config" {
+			continue
+		}
+		config, err := p.processScenarioConfig(kvp.Value)
+		if err != nil {
+			return nil, p.locate(kvp.Key, kvp.Value, fmt.Errorf("bad scenario config: %w", err))
+		}
+		return config, nil
+	}
+	return nil, nil
+}
+
+func (p *Parser) processScenarioConfig(obj oj.OJsonObject) (*mj.ScenarioConfig, error) {
+	configMap, isMap := obj.(*oj.OJsonMap)
+	if !isMap {
+		return nil, errors.New("scenario config is not a map")
+	}
+	config := &mj.ScenarioConfig{}
+	var err error
+	for _, kvp := range configMap.OrderedKV {
+		switch kvp.Key {
+		case "gasPrice":
+			config.GasPrice, err = p.processUint64(kvp.Value)
+			if err != nil {
+				return nil, fmt.Errorf("invalid gasPrice: %w", err)
+			}
+		case "caller":
+			callerStr, err := p.parseString(kvp.Value)
+			if err != nil {
+				return nil, fmt.Errorf("invalid caller: %w", err)
+			}
+			config.Caller, err = p.parseAccountAddress(callerStr)
+			if err != nil {
+				return nil, fmt.Errorf("invalid caller: %w", err)
+			}
+		case "chainID":
+			config.ChainID, err = p.processStringAsByteArray(kvp.Value)
+			if err != nil {
+				return nil, fmt.Errorf("invalid chainID: %w", err)
+			}
+		case "seed":
+			config.Seed, err = p.parseString(kvp.Value)
+			if err != nil {
+				return nil, fmt.Errorf("invalid seed: %w", err)
+			}
+		default:
+			return nil, fmt.Errorf("unknown scenario config field: %s", kvp.Key)
+		}
+	}
+	return config, nil
+}
+
+// startScenarioConfig applies the defaults of a scenario while parsing it, including the interpreter seed,
+// since generated values depend on it. The returned function restores the previous state.
+func (p *Parser) startScenarioConfig(config *mj.ScenarioConfig) func() {
+	outerConfig, suiteSeed := p.config, p.ValueInterpreter.Seed
+	p.config = config
+	if config != nil && len(config.Seed) > 0 {
+		p.ValueInterpreter.Seed = config.Seed
+	}
+	return func() {
+		p.config, p.ValueInterpreter.Seed = outerConfig, suiteSeed
+	}
+}
+
+// applyConfigDefaults fills in the transaction fields that the transaction omits and the scenario config provides.
+func (p *Parser) applyConfigDefaults(tx *mj.Transaction, txMap *oj.OJsonMap) {
+	if p.config == nil {
+		return
+	}
+	if tx.Type.HasSender() && !txMap.KeySet["from"] && len(p.config.Caller.Original) > 0 {
+		tx.From = p.config.Caller
+		tx.FromConfig = true
+	}
+	if tx.Type.HasValueAndGas() && !txMap.KeySet["gasPrice"] && len(p.config.GasPrice.Original) > 0 {
+		tx.GasPrice = p.config.GasPrice
+		tx.GasPriceConfig = true
+	}
+}
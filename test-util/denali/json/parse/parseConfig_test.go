@@ -0,0 +1,71 @@
+package denalijsonparse
+
+import (
+	"testing"
+
+	mj "github.com/numbatx/gn-vm-util/test-util/denali/json/model"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseScenarioConfigDefaults(t *testing.T) {
+	scenarioJSON := `
+	{
+		"config": {
+			"gasPrice": "10",
+			"caller": "address:owner",
+			"chainID": "str:T",
+			"seed": "config"
+		},
+		"steps": [
+			{
+				"step": "scCall",
+				"tx": {
+					"to": "address:adder",
+					"function": "add",
+					"arguments": ["token:WEGLD"],
+					"gasLimit": "5,000,000"
+				}
+			},
+			{
+				"step": "scCall",
+				"tx": {
+					"from": "address:other",
+					"to": "address:adder",
+					"function": "add",
+					"arguments": [],
+					"gasLimit": "5,000,000",
+					"gasPrice": "0"
+				}
+			}
+		]
+	}`
+
+	p := Parser{}
+	p.ValueInterpreter.Seed = "suite"
+	scenario, err := p.ParseScenarioFile([]byte(scenarioJSON))
+	require.Nil(t, err)
+	require.NotNil(t, scenario.Config)
+	require.Equal(t, []byte("T"), scenario.Config.ChainID.Value)
+	require.Equal(t, "config", scenario.Config.Seed)
+
+	defaulted := scenario.Steps[0].(*mj.TxStep).Tx
+	require.Equal(t, []byte("owner___________________________"), defaulted.From.Value)
+	require.Equal(t, uint64(10), defaulted.GasPrice.Value)
+
+	explicit := scenario.Steps[1].(*mj.TxStep).Tx
+	require.Equal(t, []byte("other___________________________"), explicit.From.Value)
+	require.Equal(t, uint64(0), explicit.GasPrice.Value)
+
+	// the scenario seed generated the token identifier, and was not kept for the suite
+	require.Equal(t, "suite", p.ValueInterpreter.Seed)
+	suiteToken, err := p.ValueInterpreter.InterpretString("token:WEGLD")
+	require.Nil(t, err)
+	require.NotEqual(t, suiteToken, defaulted.Arguments[0].Value)
+}
+
+func TestParseScenarioConfigUnknownField(t *testing.T) {
+	p := Parser{}
+	_, err := p.ParseScenarioFile([]byte(`{"config": {"gasLimit": "1"}, "steps": []}`))
+	require.NotNil(t, err)
+	require.Contains(t, err.Error(), "gasLimit")
+}
@@ -0,0 +1,171 @@
+package denalijsonparse
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	mj "github.com/numbatx/gn-vm-util/test-util/denali/json/model"
+	vi "github.com/numbatx/gn-vm-util/test-util/denali/json/valueinterpreter"
+	oj "github.com/numbatx/gn-vm-util/test-util/orderedjson"
+)
+
+// DuplicateKeyMode decides what the parser does with keys that appear more than once in the same map.
+type DuplicateKeyMode int
+
+const (
+	// DuplicateKeyDefault applies the default of the section, see Parser.DuplicateKeys.
+	DuplicateKeyDefault DuplicateKeyMode = iota
+
+	// DuplicateKeyError rejects the scenario.
+	DuplicateKeyError
+
+	// DuplicateKeyWarn keeps the first value, with a warning diagnostic in lint and strict mode.
+	DuplicateKeyWarn
+
+	// DuplicateKeyLastWins keeps the last value, like most JSON parsers do.
+	DuplicateKeyLastWins
+)
+
+// Sections of a scenario, which can handle duplicate keys differently, see Parser.DuplicateKeys.
+const (
+	// DuplicateKeySectionScenario holds the top-level fields, e.g. constants, and the steps of no other section.
+	DuplicateKeySectionScenario = "scenario"
+	DuplicateKeySectionSetState = "setState"
+
+	// DuplicateKeySectionCheckState defaults to DuplicateKeyError, since a repeated account or storage key
+	// means that one assertion silently shadows another.
+	DuplicateKeySectionCheckState = "checkState"
+
+	// DuplicateKeySectionTx holds the transactions of all transaction steps.
+	DuplicateKeySectionTx = "tx"
+
+	// DuplicateKeySectionExpect holds the expected transaction results, it defaults to DuplicateKeyError too.
+	DuplicateKeySectionExpect = "expect"
+)
+
+// defaultDuplicateKeyModes keep the first value elsewhere, as the parser always did, but warn about it.
+var defaultDuplicateKeyModes = map[string]DuplicateKeyMode{
+	DuplicateKeySectionScenario:   DuplicateKeyWarn,
+	DuplicateKeySectionSetState:   DuplicateKeyWarn,
+	DuplicateKeySectionCheckState: DuplicateKeyError,
+	DuplicateKeySectionTx:         DuplicateKeyWarn,
+	DuplicateKeySectionExpect:     DuplicateKeyError,
+}
+
+func (p *Parser) duplicateKeyMode(section string) DuplicateKeyMode {
+	if mode := p.DuplicateKeys[section]; mode != DuplicateKeyDefault {
+		return mode
+	}
+	return defaultDuplicateKeyModes[section]
+}
+
+// processDuplicateKeys handles the duplicate keys of a document, before validation, which only sees the kept values.
+// isStep is set for documents holding a single step, instead of a scenario.
+// All the duplicates rejected are reported together.
+func (p *Parser) processDuplicateKeys(document *oj.Document, isStep bool) *ValidationError {
+	var problems []*ParseError
+	var lastWins []*oj.DuplicateKey
+	for _, duplicate := range document.DuplicateKeys {
+		section, selected := p.duplicateKeySection(document.Root, duplicate.Path, isStep)
+		if !selected {
+			continue
+		}
+		position := document.Positions[duplicate.Value]
+		switch p.duplicateKeyMode(section) {
+		case DuplicateKeyError:
+			problems = append(problems, &ParseError{
+				Position: position,
+				JSONPath: duplicate.Path,
+				Err:      fmt.Errorf("duplicate key %s", duplicate.Key),
+			})
+		case DuplicateKeyWarn:
+			p.ValueInterpreter.AddDiagnosticAt(vi.SeverityWarning, position, duplicate.Key,
+				fmt.Sprintf("duplicate key at %s, only the first value is used", duplicate.Path))
+		case DuplicateKeyLastWins:
+			lastWins = append(lastWins, duplicate)
+		}
+	}
+	if len(problems) > 0 {
+		return &ValidationError{Problems: problems}
+	}
+
+	// in document order, so the last occurrence is applied last
+	for _, duplicate := range lastWins {
+		for _, kvp := range duplicate.Map.OrderedKV {
+			if kvp.Key == duplicate.Key {
+				kvp.Value = duplicate.Value
+			}
+		}
+	}
+	return nil
+}
+
+// duplicateKeySection finds the section of a duplicate key from its path, e.g. "steps[2].accounts.address:a".
+// Not selected for steps left out of a partial parse, see parsePartial.
+func (p *Parser) duplicateKeySection(root oj.OJsonObject, path string, isStep bool) (string, bool) {
+	stepObj, stepPath := root, ""
+	if !isStep {
+		stepIndex, isInStep := stepIndexOfPath(path)
+		if !isInStep {
+			return DuplicateKeySectionScenario, true
+		}
+		stepObj = scenarioStepAt(root, stepIndex)
+		if !p.isStepSelected(stepIndex, stepObj) {
+			return "", false
+		}
+		stepPath = oj.ElementPath("steps", stepIndex)
+	}
+	stepMap, isMap := stepObj.(*oj.OJsonMap)
+	if !isMap {
+		return DuplicateKeySectionScenario, true
+	}
+
+	stepType, _ := rawStepType(stepMap)
+	switch stepType {
+	case mj.StepNameSetState:
+		return DuplicateKeySectionSetState, true
+	case mj.StepNameCheckState:
+		return DuplicateKeySectionCheckState, true
+	case mj.StepNameScCall, mj.StepNameScDeploy, mj.StepNameTransfer, mj.StepNameValidatorReward, mj.StepNameScQuery:
+		if strings.HasPrefix(path, oj.ChildPath(stepPath, "expect")) {
+			return DuplicateKeySectionExpect, true
+		}
+		return DuplicateKeySectionTx, true
+	default:
+		return DuplicateKeySectionScenario, true
+	}
+}
+
+// stepIndexOfPath yields 2 for "steps[2]" and the paths under it.
+func stepIndexOfPath(path string) (int, bool) {
+	const stepsPrefix = "steps["
+	if !strings.HasPrefix(path, stepsPrefix) {
+		return 0, false
+	}
+	end := strings.Index(path, "]")
+	if end < 0 {
+		return 0, false
+	}
+	index, err := strconv.Atoi(path[len(stepsPrefix):end])
+	return index, err == nil
+}
+
+// scenarioStepAt yields the raw step with the given index, nil if there is none.
+func scenarioStepAt(root oj.OJsonObject, index int) oj.OJsonObject {
+	topMap, isMap := root.(*oj.OJsonMap)
+	if !isMap {
+		return nil
+	}
+	for _, kvp := range topMap.OrderedKV {
+		if kvp.Key != "steps" {
+			continue
+		}
+		steps, isList := kvp.Value.(*oj.OJsonList)
+		if !isList || index >= len(steps.AsList()) {
+			return nil
+		}
+		return steps.AsList()[index]
+	}
+	return nil
+}
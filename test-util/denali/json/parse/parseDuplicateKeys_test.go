@@ -0,0 +1,51 @@
+package denalijsonparse
+
+import (
+	"errors"
+	"testing"
+
+	mj "github.com/numbatx/gn-vm-util/test-util/denali/json/model"
+	"github.com/stretchr/testify/require"
+)
+
+const duplicateKeysScenario = `{
+	"steps": [
+		{"step": "setState", "accounts": {"address:a": {"nonce": "1", "nonce": "2"}}},
+		{"step": "checkState", "accounts": {"address:a": {"nonce": "1", "balance": "0", "nonce": "2"}}}
+	]
+}`
+
+func TestDuplicateKeysDefaults(t *testing.T) {
+	p := Parser{}
+	_, err := p.ParseScenarioFile([]byte(duplicateKeysScenario))
+	var validationErr *ValidationError
+	require.True(t, errors.As(err, &validationErr))
+	require.Equal(t, 1, len(validationErr.Problems))
+	require.Equal(t, "line 4, column 92: steps[1].accounts.address:a.nonce: duplicate key nonce",
+		validationErr.Problems[0].Error())
+}
+
+func TestDuplicateKeysModes(t *testing.T) {
+	p := Parser{}
+	p.ValueInterpreter.Lint = true
+	p.DuplicateKeys = map[string]DuplicateKeyMode{
+		DuplicateKeySectionCheckState: DuplicateKeyLastWins,
+	}
+	scenario, err := p.ParseScenarioFile([]byte(duplicateKeysScenario))
+	require.Nil(t, err)
+	require.Equal(t, uint64(1), scenario.Steps[0].(*mj.SetStateStep).Accounts[0].Nonce.Value)
+	require.Equal(t, uint64(2), scenario.Steps[1].(*mj.CheckStateStep).CheckAccounts.Accounts[0].Nonce.Value)
+
+	diagnostics := p.ValueInterpreter.TakeDiagnostics()
+	require.Equal(t, 1, len(diagnostics))
+	require.Equal(t, `line 3, column 74: warning: duplicate key at steps[0].accounts.address:a.nonce, only the first value is used (in "nonce")`,
+		diagnostics[0].String())
+
+	p.DuplicateKeys[DuplicateKeySectionSetState] = DuplicateKeyError
+	_, err = p.ParseScenarioFile([]byte(duplicateKeysScenario))
+	require.NotNil(t, err)
+
+	_, err = p.ParseScenarioStep(`{"step": "scCall", "tx": {"to": "address:a"}, "expect": {"status": "0", "status": "4"}}`)
+	require.NotNil(t, err)
+	require.Contains(t, err.Error(), "expect.status: duplicate key status")
+}
@@ -0,0 +1,93 @@
+package denalijsonparse
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+
+	oj "github.com/numbatx/gn-vm-util/test-util/orderedjson"
+)
+
+// ParseError locates an error in a scenario file, so that users can jump straight to it.
+type ParseError struct {
+	// File is the path of the scenario file, only set by callers that know it, e.g. the runner.
+	File string
+
+	// Position is where the offending value starts. Zero if unknown.
+	Position oj.Position
+
+	// JSONPath leads to the offending value, e.g. "steps[3]". Empty for JSON syntax errors.
+	JSONPath string
+
+	Err error
+}
+
+// Error yields the location, followed by the cause, e.g. "scenario.json:45:9: steps[3]: ...".
+func (e *ParseError) Error() string {
+	var sb strings.Builder
+	switch {
+	case len(e.File) > 0 && e.Position.Line > 0:
+		fmt.Fprintf(&sb, "%s:%d:%d: ", e.File, e.Position.Line, e.Position.Column)
+	case len(e.File) > 0:
+		fmt.Fprintf(&sb, "%s: ", e.File)
+	case e.Position.Line > 0:
+		fmt.Fprintf(&sb, "%s: ", e.Position)
+	}
+	if len(e.JSONPath) > 0 {
+		fmt.Fprintf(&sb, "%s: ", e.JSONPath)
+	}
+
+	// syntax errors already carry the position
+	var syntaxErr *oj.SyntaxError
+	if errors.As(e.Err, &syntaxErr) {
+		sb.WriteString(syntaxErr.Message)
+	} else {
+		sb.WriteString(e.Err.Error())
+	}
+	return sb.String()
+}
+
+// Unwrap yields the cause.
+func (e *ParseError) Unwrap() error {
+	return e.Err
+}
+
+// locate points an error to a value of the scenario being parsed.
+// Errors that already point to values, nested in this one, are kept as they are.
+func (p *Parser) locate(jsonPath string, obj oj.OJsonObject, err error) error {
+	switch err.(type) {
+	case nil, *ParseError, *ValidationError:
+		return err
+	}
+	return &ParseError{
+		Position: p.positions[obj],
+		JSONPath: jsonPath,
+		Err:      err,
+	}
+}
+
+// appendProblems adds the located errors of a part of the scenario to the ones found so far,
+// so that parsing can go on, and all broken parts get reported together, see problemsError.
+func appendProblems(problems []*ParseError, err error) []*ParseError {
+	switch specificErr := err.(type) {
+	case *ValidationError:
+		return append(problems, specificErr.Problems...)
+	case *ParseError:
+		return append(problems, specificErr)
+	default:
+		return append(problems, &ParseError{Err: err})
+	}
+}
+
+// problemsError yields nil if there are no problems, the problem itself if there is a single one,
+// a *ValidationError listing all of them otherwise.
+func problemsError(problems []*ParseError) error {
+	switch len(problems) {
+	case 0:
+		return nil
+	case 1:
+		return problems[0]
+	default:
+		return &ValidationError{Problems: problems}
+	}
+}
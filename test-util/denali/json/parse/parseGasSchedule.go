@@ -0,0 +1,71 @@
+package denalijsonparse
+
+import (
+	"errors"
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+var (
+	// gasScheduleTable matches the table headers of gas configs, e.g. "[BaseOperationCost]".
+	gasScheduleTable = regexp.MustCompile(`^\[\s*([A-Za-z0-9_-]+)\s*\]$`)
+
+	// gasScheduleEntry matches the cost entries of gas configs, e.g. "StorePerByte = 50".
+	gasScheduleEntry = regexp.MustCompile(`^([A-Za-z0-9_-]+)\s*=\s*(\S+)$`)
+)
+
+// parseGasScheduleTOML reads a TOML gas config, the subset of TOML that gas configs use:
+// table headers, with entries whose values are unsigned integers, and "#" comments.
+// The costs are returned by table and key; entries before the first table are in the "" table.
+func parseGasScheduleTOML(content []byte) (map[string]map[string]uint64, error) {
+	costs := make(map[string]map[string]uint64)
+	table := ""
+	for lineIndex, line := range strings.Split(string(content), "\n") {
+		lineNr := lineIndex + 1
+		line = strings.TrimSpace(stripTOMLComment(line))
+		if len(line) == 0 {
+			continue
+		}
+		if match := gasScheduleTable.FindStringSubmatch(line); match != nil {
+			table = match[1]
+			if _, exists := costs[table]; exists {
+				return nil, fmt.Errorf("line %d: duplicate table [%s]", lineNr, table)
+			}
+			costs[table] = make(map[string]uint64)
+			continue
+		}
+		match := gasScheduleEntry.FindStringSubmatch(line)
+		if match == nil {
+			return nil, fmt.Errorf("line %d: expected a [Table] header or a Key = value entry, got \"%s\"",
+				lineNr, line)
+		}
+		key := match[1]
+		cost, err := strconv.ParseUint(strings.ReplaceAll(match[2], "_", ""), 10, 64)
+		if err != nil || strings.HasPrefix(match[2], "_") || strings.HasSuffix(match[2], "_") {
+			return nil, fmt.Errorf("line %d: %s is not an unsigned integer, got \"%s\"", lineNr, key, match[2])
+		}
+		if costs[table] == nil {
+			costs[table] = make(map[string]uint64)
+		}
+		if _, exists := costs[table][key]; exists {
+			return nil, fmt.Errorf("line %d: duplicate key %s", lineNr, key)
+		}
+		costs[table][key] = cost
+	}
+	if len(costs) == 0 {
+		return nil, errors.New("no gas costs")
+	}
+	return costs, nil
+}
+
+// stripTOMLComment removes the "#" comment at the end of a line, if any.
+// Gas configs hold no strings, so there are no quoted "#" to skip.
+func stripTOMLComment(line string) string {
+	commentStart := strings.IndexByte(line, '#')
+	if commentStart < 0 {
+		return line
+	}
+	return line[:commentStart]
+}
@@ -0,0 +1,68 @@
+package denalijsonparse
+
+import (
+	"encoding/hex"
+
+	mj "github.com/numbatx/gn-vm-util/test-util/denali/json/model"
+	oj "github.com/numbatx/gn-vm-util/test-util/orderedjson"
+)
+
+// startInliningFiles prepares the interpreter to report the files that values load, see Parser.InlineFiles.
+// The returned function restores the previous state.
+func (p *Parser) startInliningFiles() func() {
+	outerInlinedFiles := p.inlinedFiles
+	outerRecord := p.ValueInterpreter.RecordFileReferences
+	p.inlinedFiles = nil
+	p.ValueInterpreter.RecordFileReferences = p.InlineFiles || outerRecord
+	return func() {
+		p.inlinedFiles = outerInlinedFiles
+		p.ValueInterpreter.RecordFileReferences = outerRecord
+		if !outerRecord {
+			p.ValueInterpreter.FileReferences = nil
+		}
+	}
+}
+
+// fileReferenceCount is taken before interpreting a value, to find out whether the value loaded files.
+func (p *Parser) fileReferenceCount() int {
+	return len(p.ValueInterpreter.FileReferences)
+}
+
+// inlinedString yields the original to keep for a string value: its contents in hex if it loaded files.
+func (p *Parser) inlinedString(original string, value []byte, referencesBefore int) string {
+	if !p.inlineFileReferences(original, referencesBefore) {
+		return original
+	}
+	if len(value) == 0 {
+		return ""
+	}
+	return "0x" + hex.EncodeToString(value)
+}
+
+// inlinedTree is inlinedString, for values that can also be lists or maps.
+func (p *Parser) inlinedTree(original oj.OJsonObject, value []byte, referencesBefore int) oj.OJsonObject {
+	originalString := ""
+	if str, isStr := original.(*oj.OJsonString); isStr {
+		originalString = str.Value
+	} else {
+		originalString = oj.JSONString(original)
+	}
+	inlined := p.inlinedString(originalString, value, referencesBefore)
+	if inlined == originalString {
+		return original
+	}
+	return &oj.OJsonString{Value: inlined}
+}
+
+// inlineFileReferences records a value that loaded files, if it has to be inlined.
+func (p *Parser) inlineFileReferences(original string, referencesBefore int) bool {
+	references := p.ValueInterpreter.FileReferences
+	if !p.InlineFiles || len(references) <= referencesBefore {
+		return false
+	}
+	p.inlinedFiles = append(p.inlinedFiles, &mj.InlinedFile{
+		Original: original,
+		Paths:    append([]string{}, references[referencesBefore:]...),
+	})
+	return true
+}
@@ -0,0 +1,51 @@
+package denalijsonparse
+
+import (
+	"testing"
+	"testing/fstest"
+
+	mj "github.com/numbatx/gn-vm-util/test-util/denali/json/model"
+	mjwrite "github.com/numbatx/gn-vm-util/test-util/denali/json/write"
+	oj "github.com/numbatx/gn-vm-util/test-util/orderedjson"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseInlineFiles(t *testing.T) {
+	fsys := fstest.MapFS{
+		"scenarios/deploy.scen.json": &fstest.MapFile{Data: []byte(`{"steps": [
+			{"step": "setState", "accounts": {"address:sc": {
+				"code": "code:../output/contract.wasm",
+				"storage": {"str:args": "file:json:args.json"}
+			}}},
+			{"step": "scCall", "tx": {"from": "address:owner", "to": "address:sc", "value": "0", "function": "f", "arguments": ["str:file:not-a-file", "keccak256:code:../output/contract.wasm"], "gasLimit": "0", "gasPrice": "0"}}
+		]}`)},
+		"scenarios/args.json":  &fstest.MapFile{Data: []byte(`["u8:1", "file:data.txt"]`)},
+		"scenarios/data.txt":   &fstest.MapFile{Data: []byte("ab")},
+		"output/contract.wasm": &fstest.MapFile{Data: []byte("\x00asm")},
+	}
+	p := NewFSParser(fsys)
+	p.InlineFiles = true
+	p.ValueInterpreter.RecordCodeHashes = true
+	scenario, err := p.ParseScenarioFS(fsys, "scenarios/deploy.scen.json")
+	require.Nil(t, err)
+
+	account := scenario.Steps[0].(*mj.SetStateStep).Accounts[0]
+	require.Equal(t, "0x0061736d", account.Code.Original)
+	require.Equal(t, "0x016162", account.Storage[0].Value.Original.(*oj.OJsonString).Value)
+	arguments := scenario.Steps[1].(*mj.TxStep).Tx.Arguments
+	require.Equal(t, "str:file:not-a-file", arguments[0].Original.(*oj.OJsonString).Value)
+	require.Equal(t, 3, len(scenario.InlinedFiles))
+	require.Equal(t, "code:../output/contract.wasm", scenario.InlinedFiles[0].Original)
+	require.Equal(t, "file:json:args.json", scenario.InlinedFiles[1].Original)
+	require.Equal(t, 2, len(scenario.InlinedFiles[1].Paths))
+	require.Equal(t, "keccak256:code:../output/contract.wasm", scenario.InlinedFiles[2].Original)
+	require.Empty(t, p.ValueInterpreter.FileReferences)
+
+	// the written scenario no longer needs the files
+	standalone := Parser{}
+	inlined, err := standalone.ParseScenarioFile([]byte(mjwrite.ScenarioToJSONString(scenario)))
+	require.Nil(t, err)
+	require.Equal(t, account.Code.Value, inlined.Steps[0].(*mj.SetStateStep).Accounts[0].Code.Value)
+	require.Equal(t, arguments[1].Value, inlined.Steps[1].(*mj.TxStep).Tx.Arguments[1].Value)
+	require.Empty(t, inlined.InlinedFiles)
+}
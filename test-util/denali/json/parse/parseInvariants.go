@@ -0,0 +1,145 @@
+package denalijsonparse
+
+import (
+	"errors"
+	"fmt"
+
+	mj "github.com/numbatx/gn-vm-util/test-util/denali/json/model"
+	oj "github.com/numbatx/gn-vm-util/test-util/orderedjson"
+)
+
+func (p *Parser) processInvariants(obj oj.OJsonObject) ([]*mj.Invariant, error) {
+	invariantsMap, isMap := obj.(*oj.OJsonMap)
+	if !isMap {
+		return nil, errors.New("invariants not a JSON map")
+	}
+	var invariants []*mj.Invariant
+	for _, kvp := range invariantsMap.OrderedKV {
+		invariant, err := p.processInvariant(kvp.Key, kvp.Value)
+		if err != nil {
+			return nil, fmt.Errorf("invalid invariant %s: %w", kvp.Key, err)
+		}
+		p.recordSource(invariant, kvp.Value)
+		invariants = append(invariants, invariant)
+	}
+	return invariants, nil
+}
+
+func (p *Parser) processInvariant(name string, obj oj.OJsonObject) (*mj.Invariant, error) {
+	invariantMap, isMap := obj.(*oj.OJsonMap)
+	if !isMap {
+		return nil, errors.New("not a JSON map")
+	}
+	invariant := &mj.Invariant{Name: name}
+	hasExpect := false
+	var err error
+	for _, kvp := range invariantMap.OrderedKV {
+		switch kvp.Key {
+		case "comment":
+			invariant.Comment, err = p.parseString(kvp.Value)
+			if err != nil {
+				return nil, fmt.Errorf("bad comment: %w", err)
+			}
+		case "constantSum":
+			invariant.ConstantSum, err = p.processInvariantQuantityList(kvp.Value)
+			if err != nil {
+				return nil, fmt.Errorf("bad constantSum: %w", err)
+			}
+			if len(invariant.ConstantSum) == 0 {
+				return nil, errors.New("empty constantSum")
+			}
+		case "value":
+			invariant.Value, err = p.processInvariantQuantity(kvp.Value)
+			if err != nil {
+				return nil, fmt.Errorf("bad value: %w", err)
+			}
+		case "expect":
+			invariant.Expect, err = p.processCheckBigInt(kvp.Value, bigIntUnsignedBytes)
+			if err != nil {
+				return nil, fmt.Errorf("bad expect: %w", err)
+			}
+			hasExpect = true
+		default:
+			return nil, fmt.Errorf("unknown invariant field: %s", kvp.Key)
+		}
+	}
+
+	switch {
+	case invariant.IsConstantSum() && invariant.Value != nil:
+		return nil, errors.New("constantSum and value cannot be used together")
+	case invariant.IsConstantSum() && hasExpect:
+		return nil, errors.New("expect is only allowed together with value")
+	case invariant.Value != nil && !hasExpect:
+		return nil, errors.New("missing expect")
+	case !invariant.IsConstantSum() && invariant.Value == nil:
+		return nil, errors.New("either constantSum or value is required")
+	}
+	return invariant, nil
+}
+
+func (p *Parser) processInvariantQuantityList(obj oj.OJsonObject) ([]*mj.InvariantQuantity, error) {
+	listRaw, isList := obj.(*oj.OJsonList)
+	if !isList {
+		return nil, errors.New("not a JSON list")
+	}
+	var quantities []*mj.InvariantQuantity
+	for _, elemRaw := range listRaw.AsList() {
+		quantity, err := p.processInvariantQuantity(elemRaw)
+		if err != nil {
+			return nil, err
+		}
+		quantities = append(quantities, quantity)
+	}
+	return quantities, nil
+}
+
+func (p *Parser) processInvariantQuantity(obj oj.OJsonObject) (*mj.InvariantQuantity, error) {
+	quantityMap, isMap := obj.(*oj.OJsonMap)
+	if !isMap {
+		return nil, errors.New("quantity not a JSON map")
+	}
+	quantity := &mj.InvariantQuantity{}
+	hasAccount := false
+	var err error
+	for _, kvp := range quantityMap.OrderedKV {
+		switch kvp.Key {
+		case "account":
+			quantity.Account, err = p.processStringAsByteArray(kvp.Value)
+			if err != nil {
+				return nil, fmt.Errorf("bad account: %w", err)
+			}
+			p.lintAddressSized(kvp.Value)
+			hasAccount = true
+		case "field":
+			quantity.Field, err = p.parseString(kvp.Value)
+			if err != nil {
+				return nil, fmt.Errorf("bad field: %w", err)
+			}
+		case "key":
+			quantity.Key, err = p.processStringAsByteArray(kvp.Value)
+			if err != nil {
+				return nil, fmt.Errorf("bad storage key: %w", err)
+			}
+		default:
+			return nil, fmt.Errorf("unknown quantity field: %s", kvp.Key)
+		}
+	}
+
+	if !hasAccount {
+		return nil, errors.New("missing quantity account")
+	}
+	switch quantity.Field {
+	case mj.InvariantFieldBalance, mj.InvariantFieldNonce:
+		if len(quantity.Key.Original) > 0 {
+			return nil, fmt.Errorf("key is only allowed for field %s", mj.InvariantFieldStorage)
+		}
+	case mj.InvariantFieldStorage:
+		if len(quantity.Key.Original) == 0 {
+			return nil, errors.New("missing storage key")
+		}
+	default:
+		return nil, fmt.Errorf("quantity field must be %s, %s or %s, not: %s",
+			mj.InvariantFieldBalance, mj.InvariantFieldNonce, mj.InvariantFieldStorage, quantity.Field)
+	}
+	return quantity, nil
+}
@@ -0,0 +1,131 @@
+package denalijsonparse
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+
+	mj "github.com/numbatx/gn-vm-util/test-util/denali/json/model"
+	vi "github.com/numbatx/gn-vm-util/test-util/denali/json/valueinterpreter"
+	oj "github.com/numbatx/gn-vm-util/test-util/orderedjson"
+)
+
+// addressSize is the size of addresses and hashes, in bytes.
+const addressSize = 32
+
+// locateDiagnostics points the diagnostics collected since diagnosticsBefore that have no position yet to obj.
+// Called for steps and top-level fields, so that every diagnostic is located at least roughly.
+func (p *Parser) locateDiagnostics(diagnosticsBefore int, obj oj.OJsonObject) {
+	diagnostics := p.ValueInterpreter.Diagnostics
+	if diagnosticsBefore >= len(diagnostics) {
+		return
+	}
+	position := p.positions[obj]
+	for _, diagnostic := range diagnostics[diagnosticsBefore:] {
+		if diagnostic.Position.Line == 0 {
+			diagnostic.Position = position
+		}
+	}
+}
+
+// lintArguments flags decimal arguments next to fixed width ones. Contracts decode numbers of fixed width types
+// from exactly that many bytes, but decimals only take as many bytes as their value needs,
+// so they were probably meant to be fixed width too.
+func (p *Parser) lintArguments(argumentsObj oj.OJsonObject, arguments []mj.JSONBytesFromTree) {
+	argumentList, isList := argumentsObj.(*oj.OJsonList)
+	if !isList || !p.ValueInterpreter.IsCollectingDiagnostics() {
+		return
+	}
+	argumentObjs := argumentList.AsList()
+
+	minSiblingWidth := 0
+	for _, argumentObj := range argumentObjs {
+		if width, isFixed := vi.FixedWidth(stringValue(argumentObj)); isFixed {
+			if minSiblingWidth == 0 || width < minSiblingWidth {
+				minSiblingWidth = width
+			}
+		}
+	}
+	if minSiblingWidth == 0 {
+		return
+	}
+
+	for i, argumentObj := range argumentObjs {
+		expression := stringValue(argumentObj)
+		if !isDecimalLiteral(expression) || len(arguments[i].Value) >= minSiblingWidth {
+			continue
+		}
+		p.ValueInterpreter.AddDiagnosticAt(vi.SeverityWarning, p.positions[argumentObj], expression,
+			fmt.Sprintf("decimal argument is shorter than the fixed width arguments next to it, did you mean u%d:%s?",
+				minSiblingWidth*8, expression))
+	}
+}
+
+// lintAddressSized flags small fixed width numbers in fields that hold addresses or hashes,
+// where the parser does not check the length.
+func (p *Parser) lintAddressSized(obj oj.OJsonObject) {
+	expression := stringValue(obj)
+	width, isFixed := vi.FixedWidth(expression)
+	if !isFixed || !p.ValueInterpreter.IsCollectingDiagnostics() {
+		return
+	}
+	prefix := expression[:strings.Index(expression, ":")+1]
+	p.ValueInterpreter.AddDiagnosticAt(vi.SeverityWarning, p.positions[obj], expression,
+		fmt.Sprintf("%s value of %d bytes used for an address-sized field of %d bytes", prefix, width, addressSize))
+}
+
+// lintStorageKeyCollisions flags the storage keys of an account that are written differently,
+// but resolve to the same bytes, see mj.FindStorageKeyCollisions. Only one of them takes effect,
+// so they are a warning in lint mode, and reject the scenario in strict mode.
+func (p *Parser) lintStorageKeyCollisions(scenario *mj.Scenario) error {
+	if !p.ValueInterpreter.IsCollectingDiagnostics() {
+		return nil
+	}
+	severity := vi.SeverityWarning
+	if p.ValueInterpreter.Strict {
+		severity = vi.SeverityError
+	}
+	var problems []*ParseError
+	for _, collision := range mj.FindStorageKeyCollisions(scenario) {
+		var position oj.Position
+		if collision.StepIndex < len(scenario.StepPositions) {
+			position = scenario.StepPositions[collision.StepIndex]
+		}
+		message := fmt.Sprintf("storage keys of account %s resolve to the same bytes, only one of them takes effect",
+			collision.AccountAddress)
+		p.ValueInterpreter.AddDiagnosticAt(severity, position, strings.Join(collision.Originals, ", "), message)
+		if p.ValueInterpreter.Strict {
+			problems = append(problems, &ParseError{
+				Position: position,
+				JSONPath: oj.ElementPath("steps", collision.StepIndex),
+				Err:      errors.New(collision.String()),
+			})
+		}
+	}
+	return problemsError(problems)
+}
+
+func stringValue(obj oj.OJsonObject) string {
+	str, isStr := obj.(*oj.OJsonString)
+	if !isStr {
+		return ""
+	}
+	return str.Value
+}
+
+// isDecimalLiteral detects unprefixed numbers, e.g. "1,000", as opposed to "0x03e8" or "u32:1000".
+func isDecimalLiteral(expression string) bool {
+	digits := strings.TrimLeft(expression, "+-")
+	if len(digits) == 0 || digits[0] < '0' || digits[0] > '9' {
+		return false
+	}
+	if strings.HasPrefix(digits, "0x") || strings.HasPrefix(digits, "0b") {
+		return false
+	}
+	for _, c := range digits {
+		if (c < '0' || c > '9') && !strings.ContainsRune("_,' ", c) {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,100 @@
+package denalijsonparse
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestLintByteWidths(t *testing.T) {
+	p := Parser{}
+	p.ValueInterpreter.Lint = true
+	_, err := p.ParseScenarioFile([]byte(`{
+	"invariants": {
+		"supply": {"value": {"account": "u32:1", "field": "balance"}, "expect": "0"}
+	},
+	"steps": [
+		{"step": "setState", "blockHashes": ["u64:7"]},
+		{
+			"step": "scCall",
+			"tx": {
+				"from": "address:owner",
+				"to": "address:contract",
+				"function": "deposit",
+				"arguments": ["u32:1", "5", "1,000,000,000,000", "0x05", "u64:2"],
+				"gasLimit": "5,000,000",
+				"gasPrice": "0"
+			}
+		}
+	]
+}`))
+	require.Nil(t, err)
+
+	var warnings []string
+	for _, diagnostic := range p.ValueInterpreter.TakeDiagnostics() {
+		warnings = append(warnings, diagnostic.String())
+	}
+	require.Equal(t, []string{
+		`line 3, column 35: warning: u32: value of 4 bytes used for an address-sized field of 32 bytes (in "u32:1")`,
+		`line 6, column 40: warning: u64: value of 8 bytes used for an address-sized field of 32 bytes (in "u64:7")`,
+		`line 13, column 28: warning: decimal argument is shorter than the fixed width arguments next to it, did you mean u32:5? (in "5")`,
+	}, warnings)
+
+	// only collected in lint and strict mode
+	p.ValueInterpreter.Lint = false
+	_, err = p.ParseScenarioStep(`{"step": "scCall", "tx": {"to": "address:a", "arguments": ["u32:1", "2"]}}`)
+	require.Nil(t, err)
+	require.Empty(t, p.ValueInterpreter.Diagnostics)
+}
+
+func TestLintDiagnosticsLocatedAtStep(t *testing.T) {
+	p := Parser{}
+	p.ValueInterpreter.Lint = true
+	_, err := p.ParseScenarioFile([]byte(`{
+	"steps": [
+		{"step": "setState", "accounts": {"address:owner": {"balance": "0x123"}}}
+	]
+}`))
+	require.Nil(t, err)
+	diagnostics := p.ValueInterpreter.TakeDiagnostics()
+	require.Equal(t, 1, len(diagnostics))
+	require.Equal(t, 3, diagnostics[0].Position.Line)
+	require.Equal(t, 3, diagnostics[0].Position.Column)
+}
+
+func TestIsDecimalLiteral(t *testing.T) {
+	require.True(t, isDecimalLiteral("5"))
+	require.True(t, isDecimalLiteral("-1,000"))
+	require.True(t, isDecimalLiteral("1_000"))
+	require.False(t, isDecimalLiteral("0x05"))
+	require.False(t, isDecimalLiteral("0b101"))
+	require.False(t, isDecimalLiteral("u32:5"))
+	require.False(t, isDecimalLiteral("str:5"))
+	require.False(t, isDecimalLiteral(""))
+}
+
+const scenarioWithStorageKeyCollision = `{
+	"steps": [
+		{"step": "setState", "accounts": {"address:owner": {"storage": {"0x01": "1", "u8:1": "2", "str:a": "3"}}}}
+	]
+}`
+
+func TestLintStorageKeyCollisions(t *testing.T) {
+	p := Parser{}
+	_, err := p.ParseScenarioFile([]byte(scenarioWithStorageKeyCollision))
+	require.Nil(t, err)
+	require.Empty(t, p.ValueInterpreter.Diagnostics)
+
+	p.ValueInterpreter.Lint = true
+	_, err = p.ParseScenarioFile([]byte(scenarioWithStorageKeyCollision))
+	require.Nil(t, err)
+	diagnostics := p.ValueInterpreter.TakeDiagnostics()
+	require.Equal(t, 1, len(diagnostics))
+	require.Equal(t, `line 3, column 3: warning: storage keys of account address:owner resolve to the same bytes, `+
+		`only one of them takes effect (in "0x01, u8:1")`, diagnostics[0].String())
+
+	p = Parser{Mode: ParseModeStrict}
+	_, err = p.ParseScenarioFile([]byte(scenarioWithStorageKeyCollision))
+	require.NotNil(t, err)
+	require.Contains(t, err.Error(), "step 0 (setState), account address:owner: storage keys 0x01, u8:1 all resolve to 0x01")
+}
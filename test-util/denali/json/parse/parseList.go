@@ -2,6 +2,7 @@ package denalijsonparse
 
 import (
 	"errors"
+	"fmt"
 
 	mj "github.com/numbatx/gn-vm-util/test-util/denali/json/model"
 	oj "github.com/numbatx/gn-vm-util/test-util/orderedjson"
@@ -23,6 +24,20 @@ func (p *Parser) processStringList(obj interface{}) ([]string, error) {
 	return result, nil
 }
 
+// processAllowedErrors only accepts known setState operations, so that typos do not go unnoticed.
+func (p *Parser) processAllowedErrors(obj interface{}) ([]string, error) {
+	operations, err := p.processStringList(obj)
+	if err != nil {
+		return nil, err
+	}
+	for _, operation := range operations {
+		if !mj.IsSetStateOperation(operation) {
+			return nil, fmt.Errorf("unknown setState operation: %s", operation)
+		}
+	}
+	return operations, nil
+}
+
 func (p *Parser) parseByteArrayList(obj interface{}) ([]mj.JSONBytesFromString, error) {
 	listRaw, listOk := obj.(*oj.OJsonList)
 	if !listOk {
@@ -38,14 +38,16 @@ func (p *Parser) processLogList(logsRaw oj.OJsonObject) ([]*mj.LogEntry, error)
 					return nil, fmt.Errorf("invalid log identifier: %w", err)
 				}
 				var identifierValue []byte
-				identifierValue, err = p.ValueInterpreter.InterpretString(strVal)
+				referencesBefore := p.fileReferenceCount()
+				identifierValue, err = p.interpretString(strVal)
 				if err != nil {
 					return nil, fmt.Errorf("invalid log identifier: %w", err)
 				}
 				if len(identifierValue) != 32 {
 					return nil, fmt.Errorf("invalid log identifier - should be 32 bytes in length")
 				}
-				logEntry.Identifier = mj.NewJSONBytesFromString(identifierValue, strVal)
+				logEntry.Identifier = mj.NewJSONBytesFromString(identifierValue,
+					p.inlinedString(strVal, identifierValue, referencesBefore))
 			case "topics":
 				logEntry.Topics, err = p.parseByteArrayList(kvp.Value)
 				if err != nil {
@@ -0,0 +1,104 @@
+package denalijsonparse
+
+import (
+	"errors"
+	"fmt"
+
+	mj "github.com/numbatx/gn-vm-util/test-util/denali/json/model"
+	oj "github.com/numbatx/gn-vm-util/test-util/orderedjson"
+)
+
+// unversionedSchemaVersion is assumed for scenarios without "schemaVersion": the last version before the field existed.
+const unversionedSchemaVersion = mj.FormatVersionStepParameters
+
+// migration upgrades the raw JSON of the scenarios written for versions before its own,
+// so that the parser, and the writer, only ever deal with the current format.
+// Migrations work on the raw JSON, before validation, so that renamed fields are not reported as unknown.
+type migration struct {
+	// version is the format version that changed the format.
+	version     mj.FormatVersion
+	description string
+	apply       func(topMap *oj.OJsonMap, comments *oj.Comments)
+}
+
+// migrations are applied in order. Format versions only added features so far, none of them renamed fields
+// or changed defaults, so there is nothing to migrate yet: scenarios only get stamped with the current version.
+// The first change that breaks older scenarios adds its migration here.
+var migrations []*migration
+
+// processSchemaVersion yields the version that the scenario declares, 0 if it declares none.
+func (p *Parser) processSchemaVersion(topMap *oj.OJsonMap) (mj.FormatVersion, error) {
+	for _, kvp := range topMap.OrderedKV {
+		if kvp.Key != "schemaVersion" {
+			continue
+		}
+		version, err := p.processUint64(kvp.Value)
+		if err != nil {
+			return 0, fmt.Errorf("bad schemaVersion: %w", err)
+		}
+		if version.Value == 0 {
+			return 0, errors.New("bad schemaVersion: versions start at 1")
+		}
+		if version.Value > uint64(mj.CurrentFormatVersion) {
+			return 0, fmt.Errorf(
+				"scenario is written for schema version %d, but this version of gn-vm-util only supports up to version %d, please upgrade gn-vm-util",
+				version.Value, mj.CurrentFormatVersion)
+		}
+		return mj.FormatVersion(version.Value), nil
+	}
+	return 0, nil
+}
+
+// migrateScenario brings the raw JSON of a scenario up to date, in place.
+// Yields the version the scenario declares, and the descriptions of the migrations applied.
+func (p *Parser) migrateScenario(topMap *oj.OJsonMap, comments *oj.Comments) (mj.FormatVersion, []string, error) {
+	declaredVersion, err := p.processSchemaVersion(topMap)
+	if err != nil {
+		return 0, nil, err
+	}
+	writtenFor := declaredVersion
+	if writtenFor == 0 {
+		writtenFor = unversionedSchemaVersion
+	}
+	var applied []string
+	for _, m := range migrations {
+		if writtenFor < m.version {
+			m.apply(topMap, comments)
+			applied = append(applied, fmt.Sprintf("version %d: %s", m.version, m.description))
+		}
+	}
+	return declaredVersion, applied, nil
+}
+
+// UpgradeScenario migrates a scenario written for an older schema version to the current one,
+// and stamps it with the current "schemaVersion", keeping its comments and layout otherwise.
+// Yields the upgraded JSON, and the migrations applied, described. The values are not interpreted.
+func (p *Parser) UpgradeScenario(jsonString []byte) ([]byte, []string, error) {
+	document, err := oj.ParseDocument(jsonString)
+	if err != nil {
+		return nil, nil, err
+	}
+	topMap, isMap := document.Root.(*oj.OJsonMap)
+	if !isMap {
+		return nil, nil, errors.New("unmarshalled test top level object is not a map")
+	}
+	_, applied, err := p.migrateScenario(topMap, document.Comments)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	currentVersion := &oj.OJsonString{Value: fmt.Sprintf("%d", mj.CurrentFormatVersion)}
+	stamped := false
+	for _, kvp := range topMap.OrderedKV {
+		if kvp.Key == "schemaVersion" {
+			kvp.Value = currentVersion
+			stamped = true
+		}
+	}
+	if !stamped {
+		// first, where readers look for it
+		topMap.OrderedKV = append([]*oj.OJsonKeyValuePair{{Key: "schemaVersion", Value: currentVersion}}, topMap.OrderedKV...)
+		topMap.RefreshKeySet()
+	}
+	return []byte(oj.JSONStringWithComments(topMap, document.Comments)), applied, nil
+}
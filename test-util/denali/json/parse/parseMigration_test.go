@@ -0,0 +1,93 @@
+package denalijsonparse
+
+import (
+	"fmt"
+	"testing"
+
+	mj "github.com/numbatx/gn-vm-util/test-util/denali/json/model"
+	oj "github.com/numbatx/gn-vm-util/test-util/orderedjson"
+	"github.com/stretchr/testify/require"
+)
+
+// withTestMigration pretends that version 18 renamed the scenario "title" to "name",
+// and stopped checking gas by default.
+func withTestMigration(t *testing.T) {
+	migrations = []*migration{{
+		version:     mj.FormatVersionSchemaVersion,
+		description: "title renamed to name, checkGas off by default",
+		apply: func(topMap *oj.OJsonMap, comments *oj.Comments) {
+			renameField(topMap, "", "title", "name", comments)
+			// the old default, written out
+			trueValue := oj.OJsonBool(true)
+			topMap.Put("checkGas", &trueValue)
+		},
+	}}
+	t.Cleanup(func() {
+		migrations = nil
+	})
+}
+
+// renameField renames a map key, keeping its position and its comments. Does nothing if the key is absent,
+// or if the new key is already there.
+func renameField(obj *oj.OJsonMap, path string, from string, to string, comments *oj.Comments) {
+	if !obj.KeySet[from] || obj.KeySet[to] {
+		return
+	}
+	for _, kvp := range obj.OrderedKV {
+		if kvp.Key == from {
+			kvp.Key = to
+		}
+	}
+	obj.RefreshKeySet()
+	comments.MovePath(oj.ChildPath(path, from), oj.ChildPath(path, to))
+}
+
+func TestParseMigratedScenario(t *testing.T) {
+	withTestMigration(t)
+	p := Parser{}
+
+	scenario, err := p.ParseScenarioFile([]byte(`{"schemaVersion": "17", "title": "old", "steps": []}`))
+	require.Nil(t, err)
+	require.Equal(t, "old", scenario.Name)
+	require.Equal(t, mj.CurrentFormatVersion, scenario.SchemaVersion)
+
+	// unversioned scenarios predate the field
+	scenario, err = p.ParseScenarioFile([]byte(`{"title": "unversioned", "steps": []}`))
+	require.Nil(t, err)
+	require.Equal(t, "unversioned", scenario.Name)
+	require.Equal(t, mj.FormatVersion(0), scenario.SchemaVersion)
+
+	_, err = p.ParseScenarioFile([]byte(`{"schemaVersion": "18", "title": "new", "steps": []}`))
+	require.NotNil(t, err)
+	require.Contains(t, err.Error(), `unknown scenario field "title"`)
+
+	_, err = p.ParseScenarioFile([]byte(`{"schemaVersion": "1000", "steps": []}`))
+	require.NotNil(t, err)
+	require.Contains(t, err.Error(), "please upgrade gn-vm-util")
+}
+
+func TestUpgradeScenario(t *testing.T) {
+	withTestMigration(t)
+	p := Parser{}
+
+	upgraded, applied, err := p.UpgradeScenario([]byte(`{
+    // shown in reports
+    "title": "old",
+    "steps": []
+}`))
+	require.Nil(t, err)
+	require.Equal(t, []string{"version 18: title renamed to name, checkGas off by default"}, applied)
+	require.Equal(t, fmt.Sprintf(`{
+    "schemaVersion": "%d",
+    // shown in reports
+    "name": "old",
+    "steps": [],
+    "checkGas": true
+}
+`, mj.CurrentFormatVersion), string(upgraded))
+
+	upgradedAgain, applied, err := p.UpgradeScenario(upgraded)
+	require.Nil(t, err)
+	require.Empty(t, applied)
+	require.Equal(t, string(upgraded), string(upgradedAgain))
+}
@@ -0,0 +1,66 @@
+package denalijsonparse
+
+import (
+	vi "github.com/numbatx/gn-vm-util/test-util/denali/json/valueinterpreter"
+)
+
+// ParseMode decides how forgiving the parser is, see Parser.Mode.
+type ParseMode int
+
+const (
+	// ParseModeDefault rejects unknown fields, and leaves the value interpreter settings as they are.
+	ParseModeDefault ParseMode = iota
+
+	// ParseModeStrict rejects unknown fields, deprecated syntax, and the values that the interpreter flags as errors.
+	// Meant for CI.
+	ParseModeStrict
+
+	// ParseModeLax accepts unknown fields, which are ignored, and deprecated syntax, with a warning for each.
+	// Meant for local experimentation.
+	ParseModeLax
+)
+
+// String yields the mode name.
+func (m ParseMode) String() string {
+	switch m {
+	case ParseModeStrict:
+		return "strict"
+	case ParseModeLax:
+		return "lax"
+	default:
+		return "default"
+	}
+}
+
+// startParseMode configures the value interpreter for the mode, while parsing a scenario.
+// Yields the function that restores the previous settings.
+func (p *Parser) startParseMode() func() {
+	interpreter := &p.ValueInterpreter
+	wasStrict, wasLint, wasFlagDeprecated := interpreter.Strict, interpreter.Lint, interpreter.FlagDeprecated
+	switch p.Mode {
+	case ParseModeStrict:
+		interpreter.Strict = true
+		interpreter.FlagDeprecated = true
+	case ParseModeLax:
+		// warnings only, even if the interpreter was strict
+		interpreter.Strict = false
+		interpreter.Lint = true
+		interpreter.FlagDeprecated = true
+	}
+	return func() {
+		interpreter.Strict, interpreter.Lint, interpreter.FlagDeprecated = wasStrict, wasLint, wasFlagDeprecated
+	}
+}
+
+// Warnings yields the warnings collected so far, e.g. the unknown fields ignored in lax mode,
+// for the runner to print. They stay in ValueInterpreter.Diagnostics, along with the other diagnostics,
+// until taken, see vi.ValueInterpreter.TakeDiagnostics.
+func (p *Parser) Warnings() []*vi.Diagnostic {
+	var warnings []*vi.Diagnostic
+	for _, diagnostic := range p.ValueInterpreter.Diagnostics {
+		if diagnostic.Severity == vi.SeverityWarning {
+			warnings = append(warnings, diagnostic)
+		}
+	}
+	return warnings
+}
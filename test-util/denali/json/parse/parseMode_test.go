@@ -0,0 +1,69 @@
+package denalijsonparse
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+const scenarioWithUnknownField = `{
+	"steps": [
+		{
+			"step": "setState",
+			"acounts": {},
+			"accounts": {
+				"address:owner": {"nonce": "0", "balance": "0", "storage": {"''key": "''value"}}
+			}
+		}
+	]
+}`
+
+func TestParseModeDefault(t *testing.T) {
+	p := Parser{}
+	_, err := p.ParseScenarioFile([]byte(scenarioWithUnknownField))
+	require.NotNil(t, err)
+	require.Contains(t, err.Error(), `unknown setState step field "acounts"`)
+	require.Empty(t, p.Warnings())
+}
+
+func TestParseModeLax(t *testing.T) {
+	p := Parser{Mode: ParseModeLax}
+	scenario, err := p.ParseScenarioFile([]byte(scenarioWithUnknownField))
+	require.Nil(t, err)
+	require.Len(t, scenario.Steps, 1)
+
+	warnings := p.Warnings()
+	require.Len(t, warnings, 3)
+	require.Equal(t, 5, warnings[0].Position.Line)
+	require.Contains(t, warnings[0].Message,
+		`steps[0].acounts ignored: unknown setState step field "acounts", did you mean "accounts"?`)
+	require.Equal(t, "'' is deprecated, use str: instead", warnings[1].Message)
+	require.Equal(t, "''key", warnings[1].Expression)
+
+	// restored after parsing
+	require.False(t, p.ValueInterpreter.Lint)
+	require.False(t, p.ValueInterpreter.FlagDeprecated)
+}
+
+func TestParseModeStrict(t *testing.T) {
+	p := Parser{Mode: ParseModeStrict}
+	_, err := p.ParseScenarioFile([]byte(scenarioWithUnknownField))
+	require.NotNil(t, err)
+	require.Contains(t, err.Error(), `unknown setState step field "acounts"`)
+
+	_, err = p.ParseScenarioFile([]byte(`{
+	"steps": [
+		{"step": "setState", "accounts": {"address:owner": {"storage": {"str:key": "''value"}}}}
+	]
+}`))
+	require.NotNil(t, err)
+	require.Contains(t, err.Error(), "'' is deprecated, use str: instead")
+
+	_, err = p.ParseScenarioFile([]byte(`{
+	"steps": [
+		{"step": "setState", "accounts": {"address:owner": {"storage": {"str:key": "str:value"}}}}
+	]
+}`))
+	require.Nil(t, err)
+	require.False(t, p.ValueInterpreter.Strict)
+}
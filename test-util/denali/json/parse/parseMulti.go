@@ -0,0 +1,117 @@
+package denalijsonparse
+
+import (
+	"bytes"
+	"fmt"
+
+	mj "github.com/numbatx/gn-vm-util/test-util/denali/json/model"
+	oj "github.com/numbatx/gn-vm-util/test-util/orderedjson"
+)
+
+// ScenarioDocument is one of the scenarios of a multi-document file, not parsed yet, see SplitScenarioDocuments.
+type ScenarioDocument struct {
+	// Index is the position of the scenario in the file, starting at 0.
+	Index int
+
+	document *oj.Document
+}
+
+// ScenarioDocumentError says which scenario of a multi-document file an error comes from.
+type ScenarioDocumentError struct {
+	Index int
+	Err   error
+}
+
+// Error yields the scenario index, followed by the cause.
+func (e *ScenarioDocumentError) Error() string {
+	return fmt.Sprintf("scenario %d: %s", e.Index, e.Err)
+}
+
+// Unwrap yields the cause, so that errors.As finds a *ParseError or *ValidationError.
+func (e *ScenarioDocumentError) Unwrap() error {
+	return e.Err
+}
+
+// ParseScenarioMulti parses a file holding several scenarios, as generators often emit:
+// either a JSON list of scenarios, or NDJSON, one scenario per line. A file holding a single scenario yields it alone.
+// Each scenario is parsed as if it had a file of its own, errors say which one failed, as *ScenarioDocumentError.
+func (p *Parser) ParseScenarioMulti(jsonString []byte) ([]*mj.Scenario, error) {
+	documents, err := SplitScenarioDocuments(jsonString)
+	if err != nil {
+		return nil, err
+	}
+	if documents == nil {
+		scenario, err := p.ParseScenarioFile(jsonString)
+		if err != nil {
+			return nil, err
+		}
+		return []*mj.Scenario{scenario}, nil
+	}
+
+	scenarios := make([]*mj.Scenario, len(documents))
+	for i, document := range documents {
+		scenarios[i], err = p.ParseScenarioDocument(document)
+		if err != nil {
+			return nil, err
+		}
+	}
+	return scenarios, nil
+}
+
+// SplitScenarioDocuments splits a multi-document file into its scenarios, for callers that handle each separately,
+// e.g. runners reporting each as a test of its own. Only JSON syntax errors are reported here.
+// Yields nil if the file holds a single scenario, to be parsed with ParseScenarioFile as usual.
+func SplitScenarioDocuments(jsonString []byte) ([]*ScenarioDocument, error) {
+	var documents []*oj.Document
+	switch {
+	case bytes.HasPrefix(bytes.TrimSpace(jsonString), []byte("[")):
+		document, err := oj.ParseDocument(jsonString)
+		if err != nil {
+			return nil, locateSyntaxError(err)
+		}
+		documents, _ = document.Elements()
+	case isNDJSON(jsonString):
+		var err error
+		documents, err = oj.ParseDocumentLines(jsonString)
+		if err != nil {
+			return nil, locateSyntaxError(err)
+		}
+	default:
+		return nil, nil
+	}
+
+	scenarioDocuments := make([]*ScenarioDocument, len(documents))
+	for i, document := range documents {
+		scenarioDocuments[i] = &ScenarioDocument{Index: i, document: document}
+	}
+	return scenarioDocuments, nil
+}
+
+// ParseScenarioDocument parses one of the scenarios of a multi-document file.
+// Errors are wrapped in a *ScenarioDocumentError, their positions refer to the whole file.
+func (p *Parser) ParseScenarioDocument(document *ScenarioDocument) (*mj.Scenario, error) {
+	scenario, err := p.parseScenarioDocument(document.document, nil)
+	if err != nil {
+		return nil, &ScenarioDocumentError{Index: document.Index, Err: err}
+	}
+	return scenario, nil
+}
+
+// isNDJSON detects files with a whole scenario on their first line, followed by more.
+// Pretty-printed scenarios, the usual single-scenario files, spread over several lines instead.
+func isNDJSON(jsonString []byte) bool {
+	trimmed := bytes.TrimLeft(jsonString, " \t\r\n")
+	lineEnd := bytes.IndexByte(trimmed, '\n')
+	if lineEnd < 0 {
+		return false
+	}
+	firstLine := bytes.TrimSpace(trimmed[:lineEnd])
+	if !bytes.HasPrefix(firstLine, []byte("{")) || !bytes.HasSuffix(firstLine, []byte("}")) {
+		return false
+	}
+	if len(bytes.TrimSpace(trimmed[lineEnd:])) == 0 {
+		return false
+	}
+	_, err := oj.ParseOrderedJSON(firstLine)
+	return err == nil
+}
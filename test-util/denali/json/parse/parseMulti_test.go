@@ -0,0 +1,69 @@
+package denalijsonparse
+
+import (
+	"errors"
+	"testing"
+
+	mj "github.com/numbatx/gn-vm-util/test-util/denali/json/model"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseScenarioMultiList(t *testing.T) {
+	p := Parser{}
+	scenarios, err := p.ParseScenarioMulti([]byte(`[
+	// the first one
+	{"name": "first", "steps": [{"step": "dumpState"}]},
+	{"name": "second", "steps": []}
+]`))
+	require.Nil(t, err)
+	require.Len(t, scenarios, 2)
+	require.Equal(t, "first", scenarios[0].Name)
+	require.Equal(t, mj.StepNameDumpState, scenarios[0].Steps[0].StepTypeName())
+	require.Equal(t, []string{"// the first one"}, scenarios[0].Comments.Before[""])
+	require.Equal(t, "second", scenarios[1].Name)
+
+	_, err = p.ParseScenarioMulti([]byte(`[
+	{"steps": []},
+	{"steps": [{"step": "dumpState", "comentt": ""}]}
+]`))
+	var documentErr *ScenarioDocumentError
+	require.True(t, errors.As(err, &documentErr))
+	require.Equal(t, 1, documentErr.Index)
+	var parseErr *ParseError
+	require.True(t, errors.As(err, &parseErr))
+	require.Equal(t, 3, parseErr.Position.Line)
+	require.Equal(t, "steps[0].comentt", parseErr.JSONPath)
+}
+
+func TestParseScenarioMultiNDJSON(t *testing.T) {
+	p := Parser{}
+	scenarios, err := p.ParseScenarioMulti([]byte(`{"name": "first", "steps": []}
+
+{"name": "second", "steps": [{"step": "dumpState"}]}
+`))
+	require.Nil(t, err)
+	require.Len(t, scenarios, 2)
+	require.Equal(t, "second", scenarios[1].Name)
+
+	_, err = p.ParseScenarioMulti([]byte(`{"steps": []}
+{"steps": [{"step": "dumpState"},]}
+`))
+	var parseErr *ParseError
+	require.True(t, errors.As(err, &parseErr))
+	require.Equal(t, 2, parseErr.Position.Line)
+}
+
+func TestParseScenarioMultiSingle(t *testing.T) {
+	p := Parser{}
+	scenarios, err := p.ParseScenarioMulti([]byte(`{
+	"name": "alone",
+	"steps": []
+}`))
+	require.Nil(t, err)
+	require.Len(t, scenarios, 1)
+	require.Equal(t, "alone", scenarios[0].Name)
+
+	documents, err := SplitScenarioDocuments([]byte(`{"name": "alone", "steps": []}`))
+	require.Nil(t, err)
+	require.Nil(t, documents)
+}
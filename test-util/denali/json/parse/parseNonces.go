@@ -0,0 +1,47 @@
+package denalijsonparse
+
+import (
+	"errors"
+
+	mj "github.com/numbatx/gn-vm-util/test-util/denali/json/model"
+	oj "github.com/numbatx/gn-vm-util/test-util/orderedjson"
+)
+
+func processAutoNonces(topMap *oj.OJsonMap) (bool, error) {
+	for _, kvp := range topMap.OrderedKV {
+		if kvp.Key != "autoNonces" {
+			continue
+		}
+		autoNoncesOJ, isBool := kvp.Value.(*oj.OJsonBool)
+		if !isBool {
+			return false, errors.New("scenario autoNonces flag is not boolean")
+		}
+		return bool(*autoNoncesOJ), nil
+	}
+	return false, nil
+}
+
+// assignNonces keeps track of the account nonces, step by step, and fills in the omitted transaction nonces.
+// setState resets the nonces of the accounts it sets.
+// Every transaction with the expected nonce increments it. Explicit nonces that differ from the expected one
+// are only useful for testing rejected transactions, so they leave the expected nonce unchanged.
+func (p *Parser) assignNonces(generalStep mj.Step) {
+	switch step := generalStep.(type) {
+	case *mj.SetStateStep:
+		for _, account := range step.Accounts {
+			p.nonces[string(account.Address.Value)] = account.Nonce.Value
+		}
+	case *mj.TxStep:
+		tx := step.Tx
+		if tx == nil || !tx.Type.HasSender() {
+			return
+		}
+		sender := string(tx.From.Value)
+		if len(tx.Nonce.Original) == 0 {
+			tx.Nonce.Value = p.nonces[sender]
+		}
+		if tx.Nonce.Value == p.nonces[sender] {
+			p.nonces[sender]++
+		}
+	}
+}
@@ -0,0 +1,150 @@
+package denalijsonparse
+
+import (
+	"fmt"
+
+	mj "github.com/numbatx/gn-vm-util/test-util/denali/json/model"
+	oj "github.com/numbatx/gn-vm-util/test-util/orderedjson"
+)
+
+// stepFilter selects steps by their index and raw JSON, before they are interpreted.
+type stepFilter func(index int, stepMap *oj.OJsonMap) bool
+
+// parsePartial parses the scenario-level fields, which steps depend on, e.g. constants and defines,
+// but only the steps selected by the filter. The other steps are neither checked nor interpreted,
+// so tools can look into big scenarios cheaply, and even into scenarios with broken steps.
+// With autoNonces, omitted nonces are assigned as if the selected steps were the only ones.
+func (p *Parser) parsePartial(jsonString []byte, filter stepFilter) (*mj.Scenario, error) {
+	p.stepFilter = filter
+	defer func() {
+		p.stepFilter = nil
+	}()
+	return p.ParseScenarioFile(jsonString)
+}
+
+func (p *Parser) isStepSelected(index int, stepObj oj.OJsonObject) bool {
+	if p.stepFilter == nil {
+		return true
+	}
+	stepMap, isMap := stepObj.(*oj.OJsonMap)
+	return isMap && p.stepFilter(index, stepMap)
+}
+
+// rawStepType yields the "step" field of a step that has not been interpreted yet,
+// the empty string if it is not a string.
+func rawStepType(stepMap *oj.OJsonMap) (string, bool) {
+	for _, kvp := range stepMap.OrderedKV {
+		if kvp.Key == "step" {
+			return stringValue(kvp.Value), true
+		}
+	}
+	return "", false
+}
+
+func rawStepComment(stepMap *oj.OJsonMap) string {
+	for _, kvp := range stepMap.OrderedKV {
+		if kvp.Key == "comment" {
+			return stringValue(kvp.Value)
+		}
+	}
+	return ""
+}
+
+func stepsOfType(stepTypeName string) stepFilter {
+	return func(_ int, stepMap *oj.OJsonMap) bool {
+		stepType, _ := rawStepType(stepMap)
+		return stepType == stepTypeName
+	}
+}
+
+// ParseSetStateSteps only parses the setState steps of a scenario, see parsePartial.
+func (p *Parser) ParseSetStateSteps(jsonString []byte) ([]*mj.SetStateStep, error) {
+	scenario, err := p.parsePartial(jsonString, stepsOfType(mj.StepNameSetState))
+	if err != nil {
+		return nil, err
+	}
+	steps := make([]*mj.SetStateStep, len(scenario.Steps))
+	for i, step := range scenario.Steps {
+		steps[i] = step.(*mj.SetStateStep)
+	}
+	return steps, nil
+}
+
+// ParseExternalSteps only parses the externalSteps steps of a scenario, see parsePartial.
+func (p *Parser) ParseExternalSteps(jsonString []byte) ([]*mj.ExternalStepsStep, error) {
+	scenario, err := p.parsePartial(jsonString, stepsOfType(mj.StepNameExternalSteps))
+	if err != nil {
+		return nil, err
+	}
+	steps := make([]*mj.ExternalStepsStep, len(scenario.Steps))
+	for i, step := range scenario.Steps {
+		steps[i] = step.(*mj.ExternalStepsStep)
+	}
+	return steps, nil
+}
+
+// ParseStepAt only parses the step with the given index, see parsePartial.
+func (p *Parser) ParseStepAt(jsonString []byte, stepIndex int) (mj.Step, error) {
+	scenario, err := p.parsePartial(jsonString, func(index int, _ *oj.OJsonMap) bool {
+		return index == stepIndex
+	})
+	if err != nil {
+		return nil, err
+	}
+	if len(scenario.Steps) == 0 {
+		return nil, fmt.Errorf("scenario has no step %d", stepIndex)
+	}
+	return scenario.Steps[0], nil
+}
+
+// ParseStepByComment only parses the first step with the given comment, see parsePartial.
+// Comments tag steps, so that tools can find them even after steps get added or removed.
+// Also yields the index of the step.
+func (p *Parser) ParseStepByComment(jsonString []byte, comment string) (mj.Step, int, error) {
+	stepIndex := -1
+	scenario, err := p.parsePartial(jsonString, func(index int, stepMap *oj.OJsonMap) bool {
+		if (stepIndex < 0 || stepIndex == index) && rawStepComment(stepMap) == comment {
+			stepIndex = index
+			return true
+		}
+		return false
+	})
+	if err != nil {
+		return nil, -1, err
+	}
+	if len(scenario.Steps) == 0 {
+		return nil, -1, fmt.Errorf("scenario has no step with comment \"%s\"", comment)
+	}
+	return scenario.Steps[0], stepIndex, nil
+}
+
+// ParseScenarioHeaders only parses the scenario-level fields of a scenario, e.g. its metadata, none of its steps,
+// see parsePartial. Files holding several scenarios yield the fields of each of them.
+func (p *Parser) ParseScenarioHeaders(jsonString []byte) ([]*mj.Scenario, error) {
+	p.stepFilter = func(int, *oj.OJsonMap) bool {
+		return false
+	}
+	defer func() {
+		p.stepFilter = nil
+	}()
+
+	documents, err := SplitScenarioDocuments(jsonString)
+	if err != nil {
+		return nil, err
+	}
+	if documents == nil {
+		scenario, err := p.ParseScenarioFile(jsonString)
+		if err != nil {
+			return nil, err
+		}
+		return []*mj.Scenario{scenario}, nil
+	}
+	headers := make([]*mj.Scenario, len(documents))
+	for i, document := range documents {
+		headers[i], err = p.ParseScenarioDocument(document)
+		if err != nil {
+			return nil, err
+		}
+	}
+	return headers, nil
+}
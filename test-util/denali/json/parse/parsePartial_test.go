@@ -0,0 +1,77 @@
+package denalijsonparse
+
+import (
+	"testing"
+
+	mj "github.com/numbatx/gn-vm-util/test-util/denali/json/model"
+	"github.com/stretchr/testify/require"
+)
+
+var partialScenarioJSON = []byte(`{
+	"defines": {"owner": "address:owner"},
+	"steps": [
+		{"step": "setState", "comment": "initial", "accounts": {"$owner": {"balance": "100"}}},
+		{"step": "externalSteps", "path": "deploy.steps.json"},
+		{"step": "scCall", "comment": "broken", "tx": {"from": "$owner", "to": "address:sc", "gasLimit": "not a number"}},
+		{"step": "setState", "comment": "later", "accounts": {"$owner": {"balance": "200"}}},
+		{"step": "scCal", "tx": {}}
+	]
+}`)
+
+func TestParseSetStateSteps(t *testing.T) {
+	p := Parser{}
+	steps, err := p.ParseSetStateSteps(partialScenarioJSON)
+	require.Nil(t, err)
+	require.Equal(t, 2, len(steps))
+	require.Equal(t, []byte("owner___________________________"), steps[0].Accounts[0].Address.Value)
+	require.Equal(t, int64(200), steps[1].Accounts[0].Balance.Value.Int64())
+
+	externalSteps, err := p.ParseExternalSteps(partialScenarioJSON)
+	require.Nil(t, err)
+	require.Equal(t, 1, len(externalSteps))
+	require.Equal(t, "deploy.steps.json", externalSteps[0].Path)
+
+	// the other steps are not even validated
+	_, err = p.ParseScenarioFile(partialScenarioJSON)
+	require.NotNil(t, err)
+}
+
+func TestParseSingleStep(t *testing.T) {
+	p := Parser{}
+	step, err := p.ParseStepAt(partialScenarioJSON, 3)
+	require.Nil(t, err)
+	require.Equal(t, "later", step.(*mj.SetStateStep).Comment)
+
+	_, err = p.ParseStepAt(partialScenarioJSON, 2)
+	require.NotNil(t, err)
+	require.Contains(t, err.Error(), "steps[2]")
+
+	_, err = p.ParseStepAt(partialScenarioJSON, 5)
+	require.NotNil(t, err)
+	require.Contains(t, err.Error(), "scenario has no step 5")
+
+	step, stepIndex, err := p.ParseStepByComment(partialScenarioJSON, "later")
+	require.Nil(t, err)
+	require.Equal(t, 3, stepIndex)
+	require.Equal(t, mj.StepNameSetState, step.StepTypeName())
+
+	_, _, err = p.ParseStepByComment(partialScenarioJSON, "missing")
+	require.NotNil(t, err)
+}
+
+func TestParseScenarioHeaders(t *testing.T) {
+	p := Parser{}
+	headers, err := p.ParseScenarioHeaders(partialScenarioJSON)
+	require.Nil(t, err)
+	require.Equal(t, 1, len(headers))
+	require.Empty(t, headers[0].Steps)
+
+	headers, err = p.ParseScenarioHeaders([]byte(`[
+		{"name": "first", "metadata": {"priority": "1"}, "steps": [{"step": "unknown"}]},
+		{"name": "second", "steps": []}
+	]`))
+	require.Nil(t, err)
+	require.Equal(t, 2, len(headers))
+	require.Equal(t, uint64(1), headers[0].Metadata.Priority.Value)
+	require.Equal(t, "second", headers[1].Name)
+}
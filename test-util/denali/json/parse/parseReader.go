@@ -0,0 +1,47 @@
+package denalijsonparse
+
+import (
+	"io"
+	"io/fs"
+
+	fr "github.com/numbatx/gn-vm-util/test-util/denali/json/fileresolver"
+	mj "github.com/numbatx/gn-vm-util/test-util/denali/json/model"
+)
+
+// NewFSParser provides a new Parser instance that loads "file:" values from a file system other than the OS one,
+// e.g. scenarios embedded with go:embed.
+func NewFSParser(fsys fs.FS) Parser {
+	return NewParser(fr.NewFSFileResolver(fsys))
+}
+
+// ParseScenarioReader is ParseScenarioFile, reading the scenario from a stream, e.g. an archive entry.
+// The file resolver context is left as it is, it should be set to where the scenario comes from.
+func (p *Parser) ParseScenarioReader(reader io.Reader) (*mj.Scenario, error) {
+	jsonString, err := io.ReadAll(reader)
+	if err != nil {
+		return nil, err
+	}
+	return p.ParseScenarioFile(jsonString)
+}
+
+// ParseScenarioFS is ParseScenarioFile, reading the scenario from a file system, e.g. one embedded with go:embed.
+// Relative "file:" values are resolved next to the scenario, so the parser should come from NewFSParser.
+func (p *Parser) ParseScenarioFS(fsys fs.FS, name string) (*mj.Scenario, error) {
+	jsonString, err := fs.ReadFile(fsys, name)
+	if err != nil {
+		return nil, err
+	}
+	if p.ValueInterpreter.FileResolver != nil {
+		p.ValueInterpreter.FileResolver.SetContext(name)
+	}
+	return p.ParseScenarioFile(jsonString)
+}
+
+// ParseTestReader is ParseTestFile, reading the tests from a stream.
+func (p *Parser) ParseTestReader(reader io.Reader) ([]*mj.Test, error) {
+	jsonString, err := io.ReadAll(reader)
+	if err != nil {
+		return nil, err
+	}
+	return p.ParseTestFile(jsonString)
+}
@@ -0,0 +1,33 @@
+package denalijsonparse
+
+import (
+	"strings"
+	"testing"
+	"testing/fstest"
+
+	mj "github.com/numbatx/gn-vm-util/test-util/denali/json/model"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseScenarioFS(t *testing.T) {
+	fsys := fstest.MapFS{
+		"scenarios/deploy.scen.json": &fstest.MapFile{Data: []byte(`{"steps": [
+			{"step": "setState", "accounts": {"address:sc": {"code": "file:../output/contract.wasm"}}}
+		]}`)},
+		"output/contract.wasm": &fstest.MapFile{Data: []byte("code")},
+	}
+	p := NewFSParser(fsys)
+	scenario, err := p.ParseScenarioFS(fsys, "scenarios/deploy.scen.json")
+	require.Nil(t, err)
+	require.Equal(t, []byte("code"), scenario.Steps[0].(*mj.SetStateStep).Accounts[0].Code.Value)
+
+	_, err = p.ParseScenarioFS(fsys, "scenarios/missing.scen.json")
+	require.NotNil(t, err)
+}
+
+func TestParseScenarioReader(t *testing.T) {
+	p := Parser{}
+	scenario, err := p.ParseScenarioReader(strings.NewReader(`{"name": "streamed", "steps": []}`))
+	require.Nil(t, err)
+	require.Equal(t, "streamed", scenario.Name)
+}
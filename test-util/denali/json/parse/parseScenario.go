@@ -3,81 +3,619 @@ package denalijsonparse
 import (
 	"errors"
 	"fmt"
+	"regexp"
+	"strings"
 
+	abi "github.com/numbatx/gn-vm-util/test-util/denali/json/abi"
 	mj "github.com/numbatx/gn-vm-util/test-util/denali/json/model"
+	vi "github.com/numbatx/gn-vm-util/test-util/denali/json/valueinterpreter"
 	oj "github.com/numbatx/gn-vm-util/test-util/orderedjson"
 )
 
-// ParseScenarioFile converts a scenario json string to scenario object representation
+// ParseScenarioFile converts a scenario json string to scenario object representation.
+// Errors in the JSON syntax, in top-level fields and in steps are located, as *ParseError.
+// Unknown, missing or conflicting fields are all reported together, as *ValidationError.
+// So are the broken fields and steps, when there are several: parsing goes on after them, where it can.
 func (p *Parser) ParseScenarioFile(jsonString []byte) (*mj.Scenario, error) {
-	jobj, err := oj.ParseOrderedJSON(jsonString)
+	return p.ParseScenarioFileWithArguments(jsonString, nil)
+}
+
+// ParseScenarioFileWithArguments parses a scenario included through externalSteps,
+// binding the arguments of the step to the parameters that the scenario declares.
+func (p *Parser) ParseScenarioFileWithArguments(jsonString []byte, arguments []*mj.NamedConstant) (*mj.Scenario, error) {
+	document, err := oj.ParseDocument(jsonString)
 	if err != nil {
-		return nil, err
+		return nil, locateSyntaxError(err)
 	}
+	return p.parseScenarioDocument(document, arguments)
+}
+
+// locateSyntaxError turns JSON syntax errors into *ParseError.
+func locateSyntaxError(err error) error {
+	var syntaxErr *oj.SyntaxError
+	if errors.As(err, &syntaxErr) {
+		return &ParseError{Position: syntaxErr.Position, Err: err}
+	}
+	return err
+}
 
-	topMap, isMap := jobj.(*oj.OJsonMap)
+func (p *Parser) parseScenarioDocument(document *oj.Document, arguments []*mj.NamedConstant) (*mj.Scenario, error) {
+	outerPositions := p.positions
+	p.positions = document.Positions
+	defer func() {
+		p.positions = outerPositions
+	}()
+	defer p.startParseMode()()
+	defer p.startInliningFiles()()
+	defer p.startCachingValues()()
+
+	topMap, isMap := document.Root.(*oj.OJsonMap)
 	if !isMap {
 		return nil, errors.New("unmarshalled test top level object is not a map")
 	}
 
+	// checked before anything else, so that newer syntax does not produce confusing errors
+	requiredVersion, err := p.processRequiredFormatVersion(topMap)
+	if err != nil {
+		return nil, err
+	}
+
+	// before validation, which only sees the values kept
+	if duplicateErr := p.processDuplicateKeys(document, false); duplicateErr != nil {
+		return nil, duplicateErr
+	}
+
+	// also before validation, so that fields renamed since are not reported as unknown
+	schemaVersion, _, err := p.migrateScenario(topMap, document.Comments)
+	if err != nil {
+		return nil, err
+	}
+
+	// all structural problems at once, instead of the first one the parser runs into
+	if validationErr := p.validateScenario(topMap); validationErr != nil {
+		return nil, validationErr
+	}
+
+	// the ABI is needed for typed literals anywhere in the scenario
+	abiPath, scenarioABI, err := p.processABI(topMap)
+	if err != nil {
+		return nil, err
+	}
+	suiteABI := p.ValueInterpreter.ABI
+	p.ValueInterpreter.ABI = scenarioABI
+	defer func() {
+		p.ValueInterpreter.ABI = suiteABI
+	}()
+
+	// constants are scoped to the scenario that defines them
+	suiteConstants := p.ValueInterpreter.Constants
+	p.ValueInterpreter.Constants = make(map[string][]byte)
+	for name, value := range suiteConstants {
+		p.ValueInterpreter.Constants[name] = value
+	}
+	defer func() {
+		p.ValueInterpreter.Constants = suiteConstants
+	}()
+
+	// so are defines
+	suiteDefines := p.ValueInterpreter.Defines
+	p.ValueInterpreter.Defines = make(map[string][]byte)
+	for name, value := range suiteDefines {
+		p.ValueInterpreter.Defines[name] = value
+	}
+	defer func() {
+		p.ValueInterpreter.Defines = suiteDefines
+	}()
+
+	// and gas presets
+	suiteGasPresets := p.ValueInterpreter.GasPresets
+	p.ValueInterpreter.GasPresets = make(map[string]uint64)
+	for name, value := range suiteGasPresets {
+		p.ValueInterpreter.GasPresets[name] = value
+	}
+	defer func() {
+		p.ValueInterpreter.GasPresets = suiteGasPresets
+	}()
+
+	// needed before the steps, wherever the flag is
+	autoNonces, err := processAutoNonces(topMap)
+	if err != nil {
+		return nil, err
+	}
+	suiteNonces := p.nonces
+	p.nonces = nil
+	if autoNonces {
+		p.nonces = make(map[string]uint64)
+	}
+	defer func() {
+		p.nonces = suiteNonces
+	}()
+
+	// also needed before the steps, bound like defines
+	parameters, err := p.processParameters(topMap, arguments)
+	if err != nil {
+		return nil, err
+	}
+
+	// the defaults are needed before the steps too, they can reference parameters
+	config, err := p.processConfig(topMap)
+	if err != nil {
+		return nil, err
+	}
+	defer p.startScenarioConfig(config)()
+
+	// the tree is final by now, migrated and validated
+	defer p.startKeepingSource(document)()
+
 	scenario := &mj.Scenario{
-		CheckGas: true,
+		RequiresFormatVersion: requiredVersion,
+		ABIPath:               abiPath,
+		ABI:                   scenarioABI,
+		CheckGas:              true,
+		Config:                config,
+		AutoNonces:            autoNonces,
+		Parameters:            parameters,
+	}
+	if schemaVersion > 0 {
+		// migrated
+		scenario.SchemaVersion = mj.CurrentFormatVersion
+	}
+	if !document.Comments.IsEmpty() {
+		scenario.Comments = document.Comments
+	}
+	// all broken fields and steps at once, where the parser can go on
+	var problems []*ParseError
+	declarationsBroken := false
+	for _, kvp := range declarationsFirst(topMap.OrderedKV) {
+		if kvp.Key == "steps" && declarationsBroken {
+			// the steps would only fail on the values they reference
+			continue
+		}
+		diagnosticsBefore := len(p.ValueInterpreter.Diagnostics)
+		err = p.processScenarioField(scenario, kvp)
+		p.locateDiagnostics(diagnosticsBefore, kvp.Value)
+		if err != nil {
+			problems = appendProblems(problems, p.locate(kvp.Key, kvp.Value, err))
+			declarationsBroken = declarationsBroken || stepsDependOn[kvp.Key]
+		}
+	}
+	if err = problemsError(problems); err != nil {
+		return nil, err
+	}
+	if err = p.lintStorageKeyCollisions(scenario); err != nil {
+		return nil, err
+	}
+	if scenario.IsView() {
+		err = checkViewScenarioSteps(scenario.Steps)
+		if err != nil {
+			return nil, err
+		}
+	}
+	scenario.InlinedFiles = p.inlinedFiles
+	p.recordSource(scenario, document.Root)
+	scenario.Source = p.source
+	return scenario, nil
+}
+
+// stepsDependOn are the scenario fields declaring values that steps reference.
+var stepsDependOn = map[string]bool{
+	"constants":  true,
+	"defines":    true,
+	"gasPresets": true,
+}
+
+// declarationOrder is the order in which the fields of stepsDependOn are processed,
+// defines can reference constants, gas presets both.
+var declarationOrder = []string{"constants", "defines", "gasPresets"}
+
+// declarationsFirst moves the fields of stepsDependOn to the front, in declarationOrder,
+// so that the fields referencing them can be written before them, e.g. "steps" before "defines".
+// The other fields keep their order.
+func declarationsFirst(fields []*oj.OJsonKeyValuePair) []*oj.OJsonKeyValuePair {
+	ordered := make([]*oj.OJsonKeyValuePair, 0, len(fields))
+	for _, key := range declarationOrder {
+		for _, kvp := range fields {
+			if kvp.Key == key {
+				ordered = append(ordered, kvp)
+			}
+		}
+	}
+	for _, kvp := range fields {
+		if !stepsDependOn[kvp.Key] {
+			ordered = append(ordered, kvp)
+		}
 	}
+	return ordered
+}
+
+func (p *Parser) processScenarioField(scenario *mj.Scenario, kvp *oj.OJsonKeyValuePair) error {
+	var err error
+	switch kvp.Key {
+	case "requiresFormatVersion":
+	case "schemaVersion":
+	case "abi":
+	case "autoNonces":
+	case "parameters":
+	case "config":
+	case "name":
+		scenario.Name, err = p.parseString(kvp.Value)
+		if err != nil {
+			return fmt.Errorf("bad scenario name: %w", err)
+		}
+	case "comment":
+		scenario.Comment, err = p.parseString(kvp.Value)
+		if err != nil {
+			return fmt.Errorf("bad scenario comment: %w", err)
+		}
+	case "metadata":
+		scenario.Metadata, err = p.processScenarioMetadata(kvp.Value)
+		if err != nil {
+			return fmt.Errorf("bad scenario metadata: %w", err)
+		}
+	case "gasSchedule":
+		scenario.GasSchedule, err = p.processGasSchedule(kvp.Value)
+		if err != nil {
+			return fmt.Errorf("bad gasSchedule: %w", err)
+		}
+	case "checkGas":
+		checkGasOJ, isBool := kvp.Value.(*oj.OJsonBool)
+		if !isBool {
+			return errors.New("scenario checkGas flag is not boolean")
+		}
+		scenario.CheckGas = bool(*checkGasOJ)
+	case "constants":
+		scenario.Constants, err = p.processConstants(kvp.Value)
+		if err != nil {
+			return fmt.Errorf("error processing constants: %w", err)
+		}
+	case "defines":
+		scenario.Defines, err = p.processDefines(kvp.Value)
+		if err != nil {
+			return fmt.Errorf("error processing defines: %w", err)
+		}
+	case "gasPresets":
+		scenario.GasPresets, err = p.processGasPresets(kvp.Value)
+		if err != nil {
+			return fmt.Errorf("error processing gas presets: %w", err)
+		}
+	case "invariants":
+		scenario.Invariants, err = p.processInvariants(kvp.Value)
+		if err != nil {
+			return fmt.Errorf("error processing invariants: %w", err)
+		}
+	case "steps":
+		// located at the step that failed
+		scenario.Steps, scenario.StepPositions, err = p.processScenarioStepList(kvp.Value)
+		if err != nil {
+			return err
+		}
+	default:
+		return fmt.Errorf("unknown step field: %s", kvp.Key)
+	}
+	return nil
+}
+
+func checkViewScenarioSteps(steps []mj.Step) error {
+	for i, step := range steps {
+		stepTypeName := step.StepTypeName()
+		if stepTypeName != mj.StepNameSetState && stepTypeName != mj.StepNameScQuery {
+			return fmt.Errorf("view scenarios can only contain setState and scQuery steps, step %d is %s",
+				i, stepTypeName)
+		}
+	}
+	return nil
+}
+
+func (p *Parser) processRequiredFormatVersion(topMap *oj.OJsonMap) (mj.FormatVersion, error) {
 	for _, kvp := range topMap.OrderedKV {
+		if kvp.Key != "requiresFormatVersion" {
+			continue
+		}
+		version, err := p.processUint64(kvp.Value)
+		if err != nil {
+			return 0, fmt.Errorf("bad requiresFormatVersion: %w", err)
+		}
+		if version.Value > uint64(mj.CurrentFormatVersion) {
+			return 0, fmt.Errorf(
+				"scenario requires format version %d, but this version of gn-vm-util only supports up to version %d, please upgrade gn-vm-util",
+				version.Value, mj.CurrentFormatVersion)
+		}
+		requiredVersion := mj.FormatVersion(version.Value)
+		// otherwise older versions of gn-vm-util would accept the scenario, then fail on the newer syntax
+		usedVersion, syntax, usedIn := vi.FormatVersionOfSubTree(topMap)
+		if usedVersion > requiredVersion {
+			return 0, p.locate("", usedIn, fmt.Errorf(
+				"scenario requires format version %d, but uses %s, introduced in format version %d, please raise requiresFormatVersion",
+				requiredVersion, syntax, usedVersion))
+		}
+		return requiredVersion, nil
+	}
+	return 0, nil
+}
+
+func (p *Parser) processABI(topMap *oj.OJsonMap) (string, *abi.ABI, error) {
+	for _, kvp := range topMap.OrderedKV {
+		if kvp.Key != "abi" {
+			continue
+		}
+		abiPath, err := p.parseString(kvp.Value)
+		if err != nil {
+			return "", nil, fmt.Errorf("bad abi path: %w", err)
+		}
+		if p.ValueInterpreter.FileResolver == nil {
+			return "", nil, errors.New("parser FileResolver not provided, cannot load ABI")
+		}
+		abiJSON, err := p.ValueInterpreter.FileResolver.ResolveFileValue(abiPath)
+		if err != nil {
+			return "", nil, fmt.Errorf("cannot load ABI: %w", err)
+		}
+		scenarioABI, err := abi.LoadABI(abiJSON)
+		if err != nil {
+			return "", nil, fmt.Errorf("cannot load ABI %s: %w", abiPath, err)
+		}
+		return abiPath, scenarioABI, nil
+	}
+	return "", nil, nil
+}
+
+// gasScheduleVersion matches the names of the schedules built into executors, e.g. "v4".
+var gasScheduleVersion = regexp.MustCompile(`^v[0-9]+$`)
+
+// processGasSchedule accepts a schedule version, or the path of a TOML gas config, which gets loaded and checked.
+func (p *Parser) processGasSchedule(obj oj.OJsonObject) (*mj.GasScheduleReference, error) {
+	original, err := p.parseString(obj)
+	if err != nil {
+		return nil, err
+	}
+	reference := &mj.GasScheduleReference{Original: original}
+	if gasScheduleVersion.MatchString(original) {
+		reference.Version = original
+		return reference, nil
+	}
+	if !strings.HasSuffix(original, ".toml") {
+		return nil, fmt.Errorf("expected a schedule version, e.g. \"v4\", or the path of a .toml gas config, got \"%s\"",
+			original)
+	}
+	if p.ValueInterpreter.FileResolver == nil {
+		return nil, errors.New("parser FileResolver not provided, cannot load gas schedule")
+	}
+	reference.Path = original
+	reference.Content, err = p.ValueInterpreter.FileResolver.ResolveFileValue(original)
+	if err != nil {
+		return nil, fmt.Errorf("cannot load gas schedule: %w", err)
+	}
+	reference.Costs, err = parseGasScheduleTOML(reference.Content)
+	if err != nil {
+		return nil, fmt.Errorf("invalid gas schedule %s: %w", original, err)
+	}
+	return reference, nil
+}
+
+func (p *Parser) processScenarioMetadata(obj oj.OJsonObject) (*mj.ScenarioMetadata, error) {
+	metadataMap, isMap := obj.(*oj.OJsonMap)
+	if !isMap {
+		return nil, errors.New("metadata not a JSON map")
+	}
+	metadata := &mj.ScenarioMetadata{}
+	var err error
+	for _, kvp := range metadataMap.OrderedKV {
 		switch kvp.Key {
-		case "name":
-			scenario.Name, err = p.parseString(kvp.Value)
+		case "owner":
+			metadata.Owner, err = p.parseString(kvp.Value)
 			if err != nil {
-				return nil, fmt.Errorf("bad scenario name: %w", err)
+				return nil, fmt.Errorf("bad owner: %w", err)
 			}
-		case "comment":
-			scenario.Comment, err = p.parseString(kvp.Value)
+		case "license":
+			metadata.License, err = p.parseString(kvp.Value)
 			if err != nil {
-				return nil, fmt.Errorf("bad scenario comment: %w", err)
+				return nil, fmt.Errorf("bad license: %w", err)
 			}
-		case "checkGas":
-			checkGasOJ, isBool := kvp.Value.(*oj.OJsonBool)
-			if !isBool {
-				return nil, errors.New("scenario checkGas flag is not boolean")
+		case "tags":
+			metadata.Tags, err = p.processStringList(kvp.Value)
+			if err != nil {
+				return nil, fmt.Errorf("bad tags: %w", err)
 			}
-			scenario.CheckGas = bool(*checkGasOJ)
-		case "steps":
-			scenario.Steps, err = p.processScenarioStepList(kvp.Value)
+		case "flavor":
+			metadata.Flavor, err = p.parseString(kvp.Value)
 			if err != nil {
-				return nil, fmt.Errorf("error processing steps: %w", err)
+				return nil, fmt.Errorf("bad flavor: %w", err)
+			}
+			if metadata.Flavor != mj.ScenarioFlavorView {
+				return nil, fmt.Errorf("unknown scenario flavor: %s", metadata.Flavor)
+			}
+		case "priority":
+			metadata.Priority, err = p.processUint64(kvp.Value)
+			if err != nil {
+				return nil, fmt.Errorf("bad priority: %w", err)
 			}
 		default:
-			return nil, fmt.Errorf("unknown step field: %s", kvp.Key)
+			return nil, fmt.Errorf("unknown metadata field: %s", kvp.Key)
 		}
 	}
-	return scenario, nil
+	return metadata, nil
+}
+
+func (p *Parser) processConstants(obj oj.OJsonObject) ([]*mj.NamedConstant, error) {
+	constantsMap, isMap := obj.(*oj.OJsonMap)
+	if !isMap {
+		return nil, errors.New("constants not a JSON map")
+	}
+	var constants []*mj.NamedConstant
+	for _, kvp := range constantsMap.OrderedKV {
+		value, err := p.processSubTreeAsByteArray(kvp.Value)
+		if err != nil {
+			return nil, fmt.Errorf("invalid value for constant %s: %w", kvp.Key, err)
+		}
+		// later constants can reference earlier ones
+		p.ValueInterpreter.SetConstant(kvp.Key, value.Value)
+		constants = append(constants, &mj.NamedConstant{
+			Name:  kvp.Key,
+			Value: value,
+		})
+	}
+	return constants, nil
 }
 
-func (p *Parser) processScenarioStepList(obj interface{}) ([]mj.Step, error) {
+// processDefines interprets each value once, later defines can reference earlier ones.
+func (p *Parser) processDefines(obj oj.OJsonObject) ([]*mj.NamedConstant, error) {
+	definesMap, isMap := obj.(*oj.OJsonMap)
+	if !isMap {
+		return nil, errors.New("defines not a JSON map")
+	}
+	var defines []*mj.NamedConstant
+	for _, kvp := range definesMap.OrderedKV {
+		value, err := p.processSubTreeAsByteArray(kvp.Value)
+		if err != nil {
+			return nil, fmt.Errorf("invalid value for define %s: %w", kvp.Key, err)
+		}
+		p.ValueInterpreter.SetDefine(kvp.Key, value.Value)
+		defines = append(defines, &mj.NamedConstant{
+			Name:  kvp.Key,
+			Value: value,
+		})
+	}
+	return defines, nil
+}
+
+// processParameters binds the arguments supplied by the including scenario to the declared parameters, as defines.
+// Every parameter needs an argument and every argument a parameter, so that misspelled names do not go unnoticed.
+func (p *Parser) processParameters(topMap *oj.OJsonMap, arguments []*mj.NamedConstant) ([]*mj.NamedConstant, error) {
+	var parametersObj oj.OJsonObject
+	var names []string
+	for _, kvp := range topMap.OrderedKV {
+		if kvp.Key != "parameters" {
+			continue
+		}
+		parametersObj = kvp.Value
+		var err error
+		names, err = p.processStringList(kvp.Value)
+		if err != nil {
+			return nil, p.locate(kvp.Key, kvp.Value, fmt.Errorf("bad scenario parameters: %w", err))
+		}
+	}
+
+	argumentsByName := make(map[string]*mj.NamedConstant, len(arguments))
+	for _, argument := range arguments {
+		argumentsByName[argument.Name] = argument
+	}
+	var parameters []*mj.NamedConstant
+	for _, name := range names {
+		argument, found := argumentsByName[name]
+		if !found {
+			return nil, p.locate("parameters", parametersObj,
+				fmt.Errorf("missing argument for parameter %s, the scenario must be included through externalSteps", name))
+		}
+		delete(argumentsByName, name)
+		p.ValueInterpreter.SetDefine(name, argument.Value.Value)
+		parameters = append(parameters, &mj.NamedConstant{
+			Name:  name,
+			Value: argument.Value,
+		})
+	}
+	for _, argument := range arguments {
+		if _, unused := argumentsByName[argument.Name]; unused {
+			return nil, fmt.Errorf("argument %s does not match any parameter of the included scenario", argument.Name)
+		}
+	}
+	return parameters, nil
+}
+
+// processArguments interprets the arguments of an externalSteps step, in the including scenario.
+func (p *Parser) processArguments(obj oj.OJsonObject) ([]*mj.NamedConstant, error) {
+	argumentsMap, isMap := obj.(*oj.OJsonMap)
+	if !isMap {
+		return nil, errors.New("arguments not a JSON map")
+	}
+	var arguments []*mj.NamedConstant
+	for _, kvp := range argumentsMap.OrderedKV {
+		value, err := p.processSubTreeAsByteArray(kvp.Value)
+		if err != nil {
+			return nil, fmt.Errorf("invalid value for argument %s: %w", kvp.Key, err)
+		}
+		arguments = append(arguments, &mj.NamedConstant{
+			Name:  kvp.Key,
+			Value: value,
+		})
+	}
+	return arguments, nil
+}
+
+func (p *Parser) processGasPresets(obj oj.OJsonObject) ([]*mj.GasPreset, error) {
+	presetsMap, isMap := obj.(*oj.OJsonMap)
+	if !isMap {
+		return nil, errors.New("gas presets not a JSON map")
+	}
+	var presets []*mj.GasPreset
+	for _, kvp := range presetsMap.OrderedKV {
+		value, err := p.processUint64(kvp.Value)
+		if err != nil {
+			return nil, fmt.Errorf("invalid value for gas preset %s: %w", kvp.Key, err)
+		}
+		p.ValueInterpreter.SetGasPreset(kvp.Key, value.Value)
+		presets = append(presets, &mj.GasPreset{
+			Name:  kvp.Key,
+			Value: value,
+		})
+	}
+	return presets, nil
+}
+
+func (p *Parser) processScenarioStepList(obj interface{}) ([]mj.Step, []oj.Position, error) {
 	listRaw, listOk := obj.(*oj.OJsonList)
 	if !listOk {
-		return nil, errors.New("steps not a JSON list")
+		return nil, nil, errors.New("steps not a JSON list")
 	}
 	var stepList []mj.Step
-	for _, elemRaw := range listRaw.AsList() {
+	var positions []oj.Position
+	// step ids are referenced from reports and filters, so they must be unique within the scenario
+	stepIndexByID := make(map[string]int)
+	// all broken steps are reported together
+	var problems []*ParseError
+	for i, elemRaw := range listRaw.AsList() {
+		if !p.isStepSelected(i, elemRaw) {
+			continue
+		}
+		diagnosticsBefore := len(p.ValueInterpreter.Diagnostics)
 		step, err := p.processScenarioStep(elemRaw)
+		p.locateDiagnostics(diagnosticsBefore, elemRaw)
 		if err != nil {
-			return nil, err
+			problems = appendProblems(problems, p.locate(fmt.Sprintf("steps[%d]", i), elemRaw, err))
+			continue
+		}
+		if metadata := mj.StepMetadataOf(step); metadata != nil && metadata.ID != "" {
+			id := metadata.ID
+			if previous, isDuplicate := stepIndexByID[id]; isDuplicate {
+				problems = appendProblems(problems, p.locate(fmt.Sprintf("steps[%d]", i), elemRaw,
+					fmt.Errorf("duplicate step id %s, already used by step %d", id, previous)))
+				continue
+			}
+			stepIndexByID[id] = i
+		}
+		if p.nonces != nil {
+			p.assignNonces(step)
 		}
+		p.recordSource(step, elemRaw)
 		stepList = append(stepList, step)
+		positions = append(positions, p.positions[elemRaw])
 	}
-	return stepList, nil
+	if err := problemsError(problems); err != nil {
+		return nil, nil, err
+	}
+	return stepList, positions, nil
 }
 
 // ParseScenarioStep parses a single scenario step, instead of an entire file.
 // Handy for tests, where step snippets can be embedded in code.
 func (p *Parser) ParseScenarioStep(jsonSnippet string) (mj.Step, error) {
-	jobj, err := oj.ParseOrderedJSON([]byte(jsonSnippet))
+	document, err := oj.ParseDocument([]byte(jsonSnippet))
 	if err != nil {
 		return nil, err
 	}
+	if duplicateErr := p.processDuplicateKeys(document, true); duplicateErr != nil {
+		return nil, duplicateErr
+	}
 
-	return p.processScenarioStep(jobj)
+	return p.processScenarioStep(document.Root)
 }
 
 func (p *Parser) processScenarioStep(stepObj oj.OJsonObject) (mj.Step, error) {
@@ -105,11 +643,24 @@ func (p *Parser) processScenarioStep(stepObj oj.OJsonObject) (mj.Step, error) {
 		for _, kvp := range stepMap.OrderedKV {
 			switch kvp.Key {
 			case "step":
+			case "id", "tags", "comment":
+				err = p.processStepMetadataField(&step.StepMetadata, kvp)
+				if err != nil {
+					return nil, err
+				}
 			case "path":
 				step.Path, err = p.parseString(kvp.Value)
 				if err != nil {
 					return nil, fmt.Errorf("bad externalSteps path: %w", err)
 				}
+				if p.ValueInterpreter.FileResolver != nil {
+					step.ResolvedPath = p.ValueInterpreter.FileResolver.ResolveAbsolutePath(step.Path)
+				}
+			case "arguments":
+				step.Arguments, err = p.processArguments(kvp.Value)
+				if err != nil {
+					return nil, fmt.Errorf("bad externalSteps arguments: %w", err)
+				}
 			default:
 				return nil, fmt.Errorf("invalid externalSteps field: %s", kvp.Key)
 			}
@@ -120,10 +671,10 @@ func (p *Parser) processScenarioStep(stepObj oj.OJsonObject) (mj.Step, error) {
 		for _, kvp := range stepMap.OrderedKV {
 			switch kvp.Key {
 			case "step":
-			case "comment":
-				step.Comment, err = p.parseString(kvp.Value)
+			case "id", "tags", "comment":
+				err = p.processStepMetadataField(&step.StepMetadata, kvp)
 				if err != nil {
-					return nil, fmt.Errorf("bad set state step comment: %w", err)
+					return nil, err
 				}
 			case "accounts":
 				step.Accounts, err = p.processAccountMap(kvp.Value)
@@ -150,20 +701,42 @@ func (p *Parser) processScenarioStep(stepObj oj.OJsonObject) (mj.Step, error) {
 				if err != nil {
 					return nil, fmt.Errorf("error parsing block hashes: %w", err)
 				}
+				if blockHashList, isList := kvp.Value.(*oj.OJsonList); isList {
+					for _, blockHashObj := range blockHashList.AsList() {
+						p.lintAddressSized(blockHashObj)
+					}
+				}
+			case "generateAccounts":
+				step.GenerateAccounts, err = p.processGenerateAccounts(kvp.Value)
+				if err != nil {
+					return nil, fmt.Errorf("error parsing generateAccounts: %w", err)
+				}
+			case "allowedErrors":
+				step.AllowedErrors, err = p.processAllowedErrors(kvp.Value)
+				if err != nil {
+					return nil, fmt.Errorf("error parsing allowedErrors: %w", err)
+				}
 			default:
 				return nil, fmt.Errorf("invalid set state field: %s", kvp.Key)
 			}
 		}
+		if step.GenerateAccounts != nil {
+			// expanded last, so that collisions with the explicit accounts are caught regardless of field order
+			step.Accounts, err = p.expandGenerateAccounts(step.GenerateAccounts, step.Accounts)
+			if err != nil {
+				return nil, fmt.Errorf("error generating accounts: %w", err)
+			}
+		}
 		return step, nil
 	case mj.StepNameCheckState:
 		step := &mj.CheckStateStep{}
 		for _, kvp := range stepMap.OrderedKV {
 			switch kvp.Key {
 			case "step":
-			case "comment":
-				step.Comment, err = p.parseString(kvp.Value)
+			case "id", "tags", "comment":
+				err = p.processStepMetadataField(&step.StepMetadata, kvp)
 				if err != nil {
-					return nil, fmt.Errorf("bad check state step comment: %w", err)
+					return nil, err
 				}
 			case "accounts":
 				step.CheckAccounts, err = p.processCheckAccountMap(kvp.Value)
@@ -180,10 +753,10 @@ func (p *Parser) processScenarioStep(stepObj oj.OJsonObject) (mj.Step, error) {
 		for _, kvp := range stepMap.OrderedKV {
 			switch kvp.Key {
 			case "step":
-			case "comment":
-				step.Comment, err = p.parseString(kvp.Value)
+			case "id", "tags", "comment":
+				err = p.processStepMetadataField(&step.StepMetadata, kvp)
 				if err != nil {
-					return nil, fmt.Errorf("bad check state step comment: %w", err)
+					return nil, err
 				}
 			default:
 				return nil, fmt.Errorf("invalid check state field: %s", kvp.Key)
@@ -198,11 +771,39 @@ func (p *Parser) processScenarioStep(stepObj oj.OJsonObject) (mj.Step, error) {
 		return p.parseTxStep(mj.Transfer, stepMap)
 	case mj.StepNameValidatorReward:
 		return p.parseTxStep(mj.ValidatorReward, stepMap)
+	case mj.StepNameScQuery:
+		return p.parseTxStep(mj.ScQuery, stepMap)
 	default:
 		return nil, fmt.Errorf("unknown step type: %s", stepTypeStr)
 	}
 }
 
+// processStepMetadataField parses the fields that all step types share, see mj.StepMetadata.
+func (p *Parser) processStepMetadataField(metadata *mj.StepMetadata, kvp *oj.OJsonKeyValuePair) error {
+	var err error
+	switch kvp.Key {
+	case "id":
+		metadata.ID, err = p.parseString(kvp.Value)
+		if err != nil {
+			return fmt.Errorf("bad step id: %w", err)
+		}
+		if metadata.ID == "" {
+			return errors.New("bad step id: empty")
+		}
+	case "tags":
+		metadata.Tags, err = p.processStringList(kvp.Value)
+		if err != nil {
+			return fmt.Errorf("bad step tags: %w", err)
+		}
+	case "comment":
+		metadata.Comment, err = p.parseString(kvp.Value)
+		if err != nil {
+			return fmt.Errorf("bad step comment: %w", err)
+		}
+	}
+	return nil
+}
+
 func (p *Parser) parseTxStep(txType mj.TransactionType, stepMap *oj.OJsonMap) (*mj.TxStep, error) {
 	step := &mj.TxStep{}
 	var err error
@@ -214,27 +815,51 @@ func (p *Parser) parseTxStep(txType mj.TransactionType, stepMap *oj.OJsonMap) (*
 			if err != nil {
 				return nil, fmt.Errorf("bad tx step id: %w", err)
 			}
-		case "comment":
-			step.Comment, err = p.parseString(kvp.Value)
+		case "id", "tags", "comment":
+			err = p.processStepMetadataField(&step.StepMetadata, kvp)
 			if err != nil {
-				return nil, fmt.Errorf("bad tx step comment: %w", err)
+				return nil, err
 			}
 		case "tx":
 			step.Tx, err = p.processTx(txType, kvp.Value)
 			if err != nil {
 				return nil, fmt.Errorf("cannot parse tx step transaction: %w", err)
 			}
+			p.recordSource(step.Tx, kvp.Value)
 		case "expect":
-			if !step.Tx.Type.IsSmartContractTx() {
-				return nil, fmt.Errorf("no expected result allowed for step of type %s", step.StepTypeName())
+			// the tx might come after the expected result
+			if !txType.IsSmartContractTx() {
+				stepTypeName := (&mj.TxStep{Tx: &mj.Transaction{Type: txType}}).StepTypeName()
+				return nil, fmt.Errorf("no expected result allowed for step of type %s", stepTypeName)
 			}
 			step.ExpectedResult, err = p.processTxExpectedResult(kvp.Value)
 			if err != nil {
 				return nil, fmt.Errorf("cannot parse tx expected result: %w", err)
 			}
+			p.recordSource(step.ExpectedResult, kvp.Value)
 		default:
 			return nil, fmt.Errorf("invalid tx step field: %s", kvp.Key)
 		}
 	}
 	return step, nil
 }
+
+// startKeepingSource collects the JSON nodes of the model objects, with KeepSource.
+// The returned function restores the previous state.
+func (p *Parser) startKeepingSource(document *oj.Document) func() {
+	outerSource := p.source
+	p.source = nil
+	if p.KeepSource {
+		p.source = mj.NewScenarioSource(document)
+	}
+	return func() {
+		p.source = outerSource
+	}
+}
+
+// recordSource remembers the JSON node of a model object, see Parser.KeepSource.
+func (p *Parser) recordSource(modelObject interface{}, node oj.OJsonObject) {
+	if p.source != nil {
+		p.source.Record(modelObject, node)
+	}
+}
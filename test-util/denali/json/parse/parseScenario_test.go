@@ -0,0 +1,497 @@
+package denalijsonparse
+
+import (
+	"errors"
+	"fmt"
+	"math/big"
+	"testing"
+
+	mj "github.com/numbatx/gn-vm-util/test-util/denali/json/model"
+	oj "github.com/numbatx/gn-vm-util/test-util/orderedjson"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseScenarioConstants(t *testing.T) {
+	scenarioJSON := `
+	{
+		"name": "constants",
+		"constants": {
+			"OWNER": "address:owner",
+			"MAX_SUPPLY": "1,000,000",
+			"KEY": "str:supply|const:MAX_SUPPLY"
+		},
+		"steps": [
+			{
+				"step": "setState",
+				"accounts": {
+					"const:OWNER": {
+						"nonce": "0",
+						"balance": "const:MAX_SUPPLY",
+						"storage": {
+							"const:KEY": "1"
+						},
+						"code": ""
+					}
+				}
+			}
+		]
+	}`
+
+	p := Parser{}
+	p.ValueInterpreter.SetConstant("SUITE", []byte{0x01})
+	scenario, err := p.ParseScenarioFile([]byte(scenarioJSON))
+	require.Nil(t, err)
+	require.Equal(t, 3, len(scenario.Constants))
+
+	setState := scenario.Steps[0].(*mj.SetStateStep)
+	account := setState.Accounts[0]
+	require.Equal(t, []byte("owner___________________________"), account.Address.Value)
+	require.Equal(t, int64(1000000), account.Balance.Value.Int64())
+	require.Equal(t, append([]byte("supply"), 0x0f, 0x42, 0x40), account.Storage[0].Key.Value)
+
+	// scenario constants do not leak into the suite
+	require.Equal(t, 1, len(p.ValueInterpreter.Constants))
+}
+
+func TestParseScenarioDefines(t *testing.T) {
+	scenarioJSON := `
+	{
+		"defines": {
+			"owner": "address:owner",
+			"token": "token:WEGLD-abcdef",
+			"key": "str:balance|$token"
+		},
+		"steps": [
+			{
+				"step": "setState",
+				"accounts": {
+					"$owner": {
+						"nonce": "0",
+						"balance": "0",
+						"storage": {
+							"$key": "1"
+						},
+						"code": ""
+					}
+				}
+			}
+		]
+	}`
+
+	p := Parser{}
+	scenario, err := p.ParseScenarioFile([]byte(scenarioJSON))
+	require.Nil(t, err)
+	require.Equal(t, 3, len(scenario.Defines))
+
+	account := scenario.Steps[0].(*mj.SetStateStep).Accounts[0]
+	require.Equal(t, []byte("owner___________________________"), account.Address.Value)
+	require.Equal(t, []byte("balanceWEGLD-abcdef"), account.Storage[0].Key.Value)
+
+	// defines do not leak into the suite, nor into other scenarios
+	require.Equal(t, 0, len(p.ValueInterpreter.Defines))
+	_, err = p.ParseScenarioFile([]byte(`{"steps": [{"step": "setState", "accounts": {"$owner": {}}}]}`))
+	require.NotNil(t, err)
+	require.Contains(t, err.Error(), "unknown define: owner")
+
+	// only earlier defines can be referenced
+	_, err = p.ParseScenarioFile([]byte(`{"defines": {"a": "$b", "b": "1"}, "steps": []}`))
+	require.NotNil(t, err)
+}
+
+func TestParseScenarioDeclarationsAfterSteps(t *testing.T) {
+	scenarioJSON := `
+	{
+		"steps": [
+			{
+				"step": "transfer",
+				"tx": {
+					"from": "$owner",
+					"to": "const:BANK",
+					"value": "const:AMOUNT",
+					"gasLimit": "preset:transfer",
+					"gasPrice": "0"
+				}
+			}
+		],
+		"gasPresets": {"transfer": "50,000"},
+		"defines": {"owner": "address:owner|const:SUFFIX"},
+		"constants": {
+			"BANK": "address:bank",
+			"AMOUNT": "1,000",
+			"SUFFIX": ""
+		}
+	}`
+
+	p := Parser{}
+	scenario, err := p.ParseScenarioFile([]byte(scenarioJSON))
+	require.Nil(t, err)
+	require.Equal(t, 3, len(scenario.Constants))
+	require.Equal(t, 1, len(scenario.Defines))
+	require.Equal(t, 1, len(scenario.GasPresets))
+
+	tx := scenario.Steps[0].(*mj.TxStep).Tx
+	require.Equal(t, []byte("owner___________________________"), tx.From.Value)
+	require.Equal(t, []byte("bank____________________________"), tx.To.Value)
+	require.Equal(t, int64(1000), tx.Value.Value.Int64())
+	require.Equal(t, uint64(50000), tx.GasLimit.Value)
+}
+
+func TestParseScenarioParameters(t *testing.T) {
+	libraryJSON := []byte(`{
+		"parameters": ["owner", "amount"],
+		"steps": [
+			{
+				"step": "setState",
+				"accounts": {
+					"$owner": {"nonce": "0", "balance": "$amount", "storage": {}, "code": ""}
+				}
+			}
+		]
+	}`)
+
+	p := Parser{}
+	includingScenario, err := p.ParseScenarioFile([]byte(`{
+		"defines": {"owner": "address:owner"},
+		"steps": [
+			{"step": "externalSteps", "path": "library.steps.json", "arguments": {"owner": "$owner", "amount": "1,000"}}
+		]
+	}`))
+	require.Nil(t, err)
+	arguments := includingScenario.Steps[0].(*mj.ExternalStepsStep).Arguments
+	require.Equal(t, 2, len(arguments))
+
+	scenario, err := p.ParseScenarioFileWithArguments(libraryJSON, arguments)
+	require.Nil(t, err)
+	require.Equal(t, 2, len(scenario.Parameters))
+	require.Equal(t, "$owner", scenario.Parameters[0].Value.Original.(*oj.OJsonString).Value)
+	account := scenario.Steps[0].(*mj.SetStateStep).Accounts[0]
+	require.Equal(t, []byte("owner___________________________"), account.Address.Value)
+	require.Equal(t, int64(1000), account.Balance.Value.Int64())
+	require.Equal(t, 0, len(p.ValueInterpreter.Defines))
+
+	_, err = p.ParseScenarioFile(libraryJSON)
+	require.NotNil(t, err)
+	require.Contains(t, err.Error(), "missing argument for parameter owner")
+
+	_, err = p.ParseScenarioFileWithArguments(libraryJSON, append(arguments, &mj.NamedConstant{Name: "ownr"}))
+	require.NotNil(t, err)
+	require.Contains(t, err.Error(), "argument ownr does not match any parameter")
+}
+
+func TestParseScenarioRequiresFormatVersion(t *testing.T) {
+	p := Parser{}
+	scenario, err := p.ParseScenarioFile([]byte(`{
+		"requiresFormatVersion": "3",
+		"steps": []
+	}`))
+	require.Nil(t, err)
+	require.Equal(t, mj.FormatVersionConstants, scenario.RequiresFormatVersion)
+
+	// the version check takes precedence over other errors
+	_, err = p.ParseScenarioFile([]byte(`{
+		"steps": [ { "step": "someFutureStep" } ],
+		"requiresFormatVersion": "1000"
+	}`))
+	require.NotNil(t, err)
+	require.Contains(t, err.Error(), "please upgrade gn-vm-util")
+}
+
+func TestParseScenarioRequiresFormatVersionOfValues(t *testing.T) {
+	p := Parser{}
+	p.ValueInterpreter.SetConstant("AMOUNT", []byte{0x05})
+	_, err := p.ParseScenarioFile([]byte(`{
+		"requiresFormatVersion": "3",
+		"name": "token:NOT-A-VALUE",
+		"steps": [{"step": "setState", "accounts": {"address:owner": {"balance": "const:AMOUNT"}}}]
+	}`))
+	require.Nil(t, err)
+
+	for _, testCase := range []struct {
+		value   string
+		syntax  string
+		version mj.FormatVersion
+	}{
+		{`"address:owner#1": {}`, "address:NAME#SHARD", mj.FormatVersionAddressShard},
+		{`"address:owner": {"balance": "u32:token:WEGLD"}`, "token:", mj.FormatVersionTokens},
+		{`"address:owner": {"balance": "str:a|left-pad:2:1"}`, "left-pad:", mj.FormatVersionByteOperators},
+		{`"address:owner": {"balance": "keccak256:bitand:(1),(timestamp:2024-01-01)"}`, "timestamp:", mj.FormatVersionTime},
+		{`"address:owner": {"storage": {"str:key": "multi:1;(base64:AQ==)"}}`, "multi:", mj.FormatVersionMulti},
+	} {
+		_, err = p.ParseScenarioFile([]byte(`{
+			"requiresFormatVersion": "3",
+			"steps": [{"step": "setState", "accounts": {` + testCase.value + `}}]
+		}`))
+		require.NotNil(t, err, testCase.value)
+		require.Contains(t, err.Error(), fmt.Sprintf(
+			"scenario requires format version 3, but uses %s, introduced in format version %d",
+			testCase.syntax, testCase.version))
+	}
+
+	_, err = p.ParseScenarioFile([]byte(`{
+		"requiresFormatVersion": "21",
+		"steps": [{"step": "checkState", "accounts": {"address:owner": {"storage": {"str:key": "*"}}, "+": ""}}]
+	}`))
+	require.NotNil(t, err)
+	require.Contains(t, err.Error(), "uses \"*\" storage value")
+}
+
+func TestStorageKeyCollisions(t *testing.T) {
+	p := Parser{}
+	scenario, err := p.ParseScenarioFile([]byte(`{
+		"steps": [
+			{
+				"step": "setState",
+				"accounts": {
+					"address:owner": {
+						"storage": {
+							"0x01": "1",
+							"u8:1": "2",
+							"1": "3",
+							"0x02": "4"
+						}
+					}
+				}
+			},
+			{
+				"step": "checkState",
+				"accounts": {
+					"address:owner": {
+						"storage": {
+							"str:a": "1",
+							"0x61": "1",
+							"0x02": "4"
+						}
+					}
+				}
+			}
+		]
+	}`))
+	require.Nil(t, err)
+
+	collisions := mj.FindStorageKeyCollisions(scenario)
+	require.Equal(t, 2, len(collisions))
+	require.Equal(t, 0, collisions[0].StepIndex)
+	require.Equal(t, []string{"0x01", "u8:1", "1"}, collisions[0].Originals)
+	require.Equal(t, []byte{0x01}, collisions[0].Key)
+	require.Equal(t, 1, collisions[1].StepIndex)
+	require.Equal(t, []string{"str:a", "0x61"}, collisions[1].Originals)
+	require.Equal(t, "step 1 (checkState), account address:owner: storage keys str:a, 0x61 all resolve to 0x61",
+		collisions[1].String())
+}
+
+func TestParseCheckStateAnyValue(t *testing.T) {
+	p := Parser{}
+	scenario, err := p.ParseScenarioFile([]byte(`{
+		"steps": [
+			{
+				"step": "checkState",
+				"accounts": {
+					"address:owner": {
+						"nonce": "any",
+						"storage": {
+							"str:set": "*",
+							"str:also-set": "any",
+							"str:exact": "5"
+						},
+						"code": "any"
+					}
+				}
+			}
+		]
+	}`))
+	require.Nil(t, err)
+
+	account := scenario.Steps[0].(*mj.CheckStateStep).CheckAccounts.Accounts[0]
+	require.True(t, account.Nonce.IsStar)
+	require.Equal(t, "any", account.Nonce.Original)
+	require.True(t, account.Code.IsStar)
+	require.False(t, account.Code.IsDefault())
+
+	require.Equal(t, 3, len(account.CheckStorage))
+	require.True(t, account.CheckStorage[0].AnyValue)
+	require.True(t, account.CheckStorage[0].CheckValue([]byte{1}))
+	require.False(t, account.CheckStorage[0].CheckValue([]byte{}))
+	require.True(t, account.CheckStorage[1].AnyValue)
+	require.False(t, account.CheckStorage[2].AnyValue)
+	require.True(t, account.CheckStorage[2].CheckValue([]byte{5}))
+	require.False(t, account.CheckStorage[2].CheckValue([]byte{6}))
+
+	_, err = p.ParseScenarioFile([]byte(`{
+		"steps": [
+			{
+				"step": "setState",
+				"accounts": {
+					"address:owner": {
+						"storage": {
+							"str:set": "*"
+						}
+					}
+				}
+			}
+		]
+	}`))
+	require.NotNil(t, err)
+}
+
+func TestParseCheckMatchers(t *testing.T) {
+	p := Parser{}
+	scenario, err := p.ParseScenarioFile([]byte(`{
+		"steps": [
+			{
+				"step": "scCall",
+				"txId": "1",
+				"tx": {
+					"from": "address:owner",
+					"to": "address:contract",
+					"function": "f",
+					"arguments": [],
+					"gasLimit": "1000",
+					"gasPrice": "0"
+				},
+				"expect": {
+					"out": ["match:regex:^0x01"],
+					"status": "match:range:0..0",
+					"gas": "match:range:100..200"
+				}
+			},
+			{
+				"step": "checkState",
+				"accounts": {
+					"address:owner": {
+						"nonce": "match:range:1..",
+						"balance": "match:regex:^9+$",
+						"storage": {
+							"str:counter": "match:range:..10"
+						}
+					}
+				}
+			}
+		]
+	}`))
+	require.Nil(t, err)
+
+	result := scenario.Steps[0].(*mj.TxStep).ExpectedResult
+	require.True(t, result.Out[0].Check([]byte{1, 2}))
+	require.False(t, result.Out[0].Check([]byte{2}))
+	require.True(t, result.Status.Check(big.NewInt(0)))
+	require.False(t, result.Status.Check(big.NewInt(4)))
+	require.True(t, result.Gas.Check(150))
+	require.False(t, result.Gas.Check(250))
+	require.False(t, result.Gas.IsDefault())
+	require.Equal(t, "match:range:100..200", result.Gas.Original)
+
+	account := scenario.Steps[1].(*mj.CheckStateStep).CheckAccounts.Accounts[0]
+	require.True(t, account.Nonce.Check(5))
+	require.False(t, account.Nonce.Check(0))
+	require.True(t, account.Balance.Check(big.NewInt(999)))
+	require.False(t, account.Balance.Check(big.NewInt(990)))
+	require.True(t, account.CheckStorage[0].CheckValue([]byte{10}))
+	require.False(t, account.CheckStorage[0].CheckValue([]byte{11}))
+
+	_, err = p.ParseScenarioFile([]byte(`{
+		"steps": [
+			{
+				"step": "checkState",
+				"accounts": {
+					"address:owner": {
+						"nonce": "match:range:5..1"
+					}
+				}
+			}
+		]
+	}`))
+	require.NotNil(t, err)
+}
+
+func TestParseErrorLocation(t *testing.T) {
+	p := Parser{}
+	var parseErr *ParseError
+
+	_, err := p.ParseScenarioFile([]byte("{\n\t\"steps\": [\n\t\t{\"step\": \"setState\"},,\n\t]\n}"))
+	require.True(t, errors.As(err, &parseErr))
+	require.Equal(t, 3, parseErr.Position.Line)
+	require.Equal(t, 24, parseErr.Position.Column)
+	require.Empty(t, parseErr.JSONPath)
+	require.Equal(t, "line 3, column 24: misplaced character", err.Error())
+
+	_, err = p.ParseScenarioFile([]byte(`{
+	"steps": [
+		{"step": "setState"},
+		{"step": "checkState", "acounts": {}}
+	]
+}`))
+	require.True(t, errors.As(err, &parseErr))
+	require.Equal(t, "steps[1].acounts", parseErr.JSONPath)
+	require.Equal(t, 4, parseErr.Position.Line)
+	require.Equal(t, 37, parseErr.Position.Column)
+	require.Equal(t, "invalid scenario, 1 problem:\n"+
+		"    line 4, column 37: steps[1].acounts: unknown checkState step field \"acounts\", did you mean \"accounts\"?",
+		err.Error())
+
+	_, err = p.ParseScenarioFile([]byte(`{"constants": [], "steps": []}`))
+	require.True(t, errors.As(err, &parseErr))
+	require.Equal(t, "constants", parseErr.JSONPath)
+	require.Equal(t, 15, parseErr.Position.Column)
+
+	parseErr.File = "scenario.json"
+	require.Equal(t, "scenario.json:1:15: constants: error processing constants: constants not a JSON map", parseErr.Error())
+}
+
+func TestParseDuplicateStepID(t *testing.T) {
+	p := Parser{}
+	_, err := p.ParseScenarioFile([]byte(`{
+	"steps": [
+		{"step": "dumpState", "id": "dump"},
+		{"step": "setState"},
+		{"step": "dumpState", "id": "dump"}
+	]
+}`))
+	var parseErr *ParseError
+	require.True(t, errors.As(err, &parseErr))
+	require.Equal(t, "steps[2]", parseErr.JSONPath)
+	require.Contains(t, err.Error(), "duplicate step id dump, already used by step 0")
+
+	_, err = p.ParseScenarioFile([]byte(`{"steps": [{"step": "dumpState", "id": ""}]}`))
+	require.NotNil(t, err)
+
+	_, err = p.ParseScenarioFile([]byte(`{"steps": [{"step": "dumpState", "tags": "debug"}]}`))
+	require.NotNil(t, err)
+	require.Contains(t, err.Error(), "bad step tags")
+}
+
+func TestParseReportsAllBrokenSteps(t *testing.T) {
+	p := Parser{}
+	_, err := p.ParseScenarioFile([]byte(`{
+	"name": ["not", "a", "string"],
+	"steps": [
+		{"step": "setState", "accounts": {"address:a": {"nonce": "five"}}},
+		{"step": "dumpState"},
+		{"step": "setState", "blockHashes": "0x01"},
+		{"step": "dumpState", "id": "dump"},
+		{"step": "dumpState", "id": "dump"}
+	]
+}`))
+	var validationErr *ValidationError
+	require.True(t, errors.As(err, &validationErr))
+	require.Len(t, validationErr.Problems, 4)
+	require.Equal(t, "name", validationErr.Problems[0].JSONPath)
+	require.Equal(t, "steps[0]", validationErr.Problems[1].JSONPath)
+	require.Equal(t, 4, validationErr.Problems[1].Position.Line)
+	require.Equal(t, "steps[2]", validationErr.Problems[2].JSONPath)
+	require.Equal(t, "steps[4]", validationErr.Problems[3].JSONPath)
+	require.Contains(t, err.Error(), "invalid scenario, 4 problems:")
+
+	// a single broken step is reported as it always was
+	_, err = p.ParseScenarioFile([]byte(`{"steps": [{"step": "setState", "blockHashes": "0x01"}]}`))
+	var parseErr *ParseError
+	require.True(t, errors.As(err, &parseErr))
+	require.False(t, errors.As(err, &validationErr))
+
+	// steps referencing broken constants are not reported
+	_, err = p.ParseScenarioFile([]byte(`{
+	"constants": {"x": "five"},
+	"steps": [{"step": "setState", "accounts": {"address:a": {"nonce": "const:x"}}}]
+}`))
+	require.True(t, errors.As(err, &parseErr))
+	require.Equal(t, "constants", parseErr.JSONPath)
+}
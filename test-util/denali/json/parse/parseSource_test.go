@@ -0,0 +1,86 @@
+package denalijsonparse
+
+import (
+	"testing"
+
+	mj "github.com/numbatx/gn-vm-util/test-util/denali/json/model"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseKeepSource(t *testing.T) {
+	scenarioJSON := `{
+	"name": "source",
+	"steps": [
+		{
+			"step": "setState",
+			"accounts": {
+				"address:owner": {
+					"nonce": "0",
+					"balance": "100"
+				}
+			}
+		},
+		{
+			"step": "scCall",
+			"txId": "1",
+			"tx": {
+				"from": "address:owner",
+				"to": "address:adder",
+				"function": "add",
+				"arguments": ["1"],
+				"gasLimit": "5,000,000",
+				"gasPrice": "0"
+			},
+			"expect": {
+				"out": [],
+				"status": "0"
+			}
+		}
+	]
+}`
+
+	p := Parser{KeepSource: true}
+	scenario, err := p.ParseScenarioFile([]byte(scenarioJSON))
+	require.Nil(t, err)
+	require.NotNil(t, scenario.Source)
+
+	root, found := scenario.Source.Node(scenario)
+	require.True(t, found)
+	require.Equal(t, "", root.Path)
+	require.Equal(t, 1, root.Position.Line)
+
+	account := scenario.Steps[0].(*mj.SetStateStep).Accounts[0]
+	accountNode, found := scenario.Source.Node(account)
+	require.True(t, found)
+	require.Equal(t, "steps[0].accounts.address:owner", accountNode.Path)
+	require.Equal(t, 7, accountNode.Position.Line)
+
+	callStep := scenario.Steps[1].(*mj.TxStep)
+	stepNode, found := scenario.Source.Node(callStep)
+	require.True(t, found)
+	require.Equal(t, "steps[1]", stepNode.Path)
+	require.Equal(t, 13, stepNode.Position.Line)
+
+	txNode, found := scenario.Source.Node(callStep.Tx)
+	require.True(t, found)
+	require.Equal(t, "steps[1].tx", txNode.Path)
+	require.Equal(t, 16, txNode.Position.Line)
+	require.Equal(t, scenarioJSON[txNode.Position.Offset], byte('{'))
+
+	expectNode, found := scenario.Source.Node(callStep.ExpectedResult)
+	require.True(t, found)
+	require.Equal(t, "steps[1].expect", expectNode.Path)
+
+	_, found = scenario.Source.Node(callStep.Tx.Arguments[0])
+	require.False(t, found)
+}
+
+func TestParseWithoutKeepSource(t *testing.T) {
+	p := Parser{}
+	scenario, err := p.ParseScenarioFile([]byte(`{"steps": []}`))
+	require.Nil(t, err)
+	require.Nil(t, scenario.Source)
+
+	_, found := scenario.Source.Node(scenario)
+	require.False(t, found)
+}
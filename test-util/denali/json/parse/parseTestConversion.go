@@ -0,0 +1,25 @@
+package denalijsonparse
+
+import (
+	"fmt"
+
+	mj "github.com/numbatx/gn-vm-util/test-util/denali/json/model"
+)
+
+// ConvertTestToScenario parses a file in the older test format and converts each of its tests to a scenario,
+// see mj.ConvertTest. The scenarios keep the original form of all values, so they can be written out as they were.
+func (p *Parser) ConvertTestToScenario(jsonString []byte) ([]*mj.Scenario, error) {
+	tests, err := p.ParseTestFile(jsonString)
+	if err != nil {
+		return nil, err
+	}
+	scenarios := make([]*mj.Scenario, 0, len(tests))
+	for _, test := range tests {
+		scenario, err := mj.ConvertTest(test)
+		if err != nil {
+			return nil, fmt.Errorf("cannot convert test %s: %w", test.TestName, err)
+		}
+		scenarios = append(scenarios, scenario)
+	}
+	return scenarios, nil
+}
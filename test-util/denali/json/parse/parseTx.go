@@ -66,6 +66,9 @@ func (p *Parser) processTx(txType mj.TransactionType, blrRaw oj.OJsonObject) (*m
 				return nil, errors.New("transaction function field not allowed for transfer transactions")
 			}
 		case "value":
+			if !txType.HasValueAndGas() {
+				return nil, errors.New("transaction value field not allowed for scQuery transactions")
+			}
 			blt.Value, err = p.processBigInt(kvp.Value, bigIntUnsignedBytes)
 			if err != nil {
 				return nil, fmt.Errorf("invalid block transaction value: %w", err)
@@ -75,9 +78,18 @@ func (p *Parser) processTx(txType mj.TransactionType, blrRaw oj.OJsonObject) (*m
 			if err != nil {
 				return nil, fmt.Errorf("invalid block transaction arguments: %w", err)
 			}
+			p.lintArguments(kvp.Value, blt.Arguments)
 			if txType == mj.Transfer && len(blt.Arguments) > 0 {
 				return nil, errors.New("function arguments not allowed for transfer transactions")
 			}
+		case "data":
+			if txType != mj.Transfer {
+				return nil, errors.New("transaction data field only allowed in transfer transactions")
+			}
+			blt.Data, err = p.processSubTreeAsByteArray(kvp.Value)
+			if err != nil {
+				return nil, fmt.Errorf("invalid block transaction data: %w", err)
+			}
 		case "contractCode":
 			blt.Code, err = p.processStringAsByteArray(kvp.Value)
 			if err != nil {
@@ -87,19 +99,26 @@ func (p *Parser) processTx(txType mj.TransactionType, blrRaw oj.OJsonObject) (*m
 				return nil, errors.New("transaction contractCode field only allowed int scDeploy transactions")
 			}
 		case "gasPrice":
+			if !txType.HasValueAndGas() {
+				return nil, errors.New("transaction gasPrice field not allowed for scQuery transactions")
+			}
 			blt.GasPrice, err = p.processUint64(kvp.Value)
 			if err != nil {
 				return nil, fmt.Errorf("invalid block transaction gasPrice: %w", err)
 			}
 		case "gasLimit":
+			if !txType.HasValueAndGas() {
+				return nil, errors.New("transaction gasLimit field not allowed for scQuery transactions")
+			}
 			blt.GasLimit, err = p.processUint64(kvp.Value)
 			if err != nil {
 				return nil, fmt.Errorf("invalid block transaction gasLimit: %w", err)
 			}
 		default:
-			return nil, fmt.Errorf("unknown field in transaction: %w", err)
+			return nil, fmt.Errorf("unknown field in transaction: %s", kvp.Key)
 		}
 	}
+	p.applyConfigDefaults(&blt, bltMap)
 
 	return &blt, nil
 }
@@ -2,10 +2,13 @@ package denalijsonparse
 
 import (
 	"errors"
+	"fmt"
 	"math/big"
+	"strings"
 
 	twos "github.com/numbatx/gn-bigint/twos-complement"
 	mj "github.com/numbatx/gn-vm-util/test-util/denali/json/model"
+	vi "github.com/numbatx/gn-vm-util/test-util/denali/json/valueinterpreter"
 	oj "github.com/numbatx/gn-vm-util/test-util/orderedjson"
 )
 
@@ -22,7 +25,13 @@ func (p *Parser) processCheckBigInt(obj oj.OJsonObject, format bigIntParseFormat
 		return mj.JSONCheckBigInt{
 			Value:    nil,
 			IsStar:   true,
-			Original: "*"}, nil
+			Original: starOriginal(obj)}, nil
+	}
+	if matcherStr, isMatcher := matcherString(obj); isMatcher {
+		matcher, err := p.ValueInterpreter.InterpretMatcher(matcherStr)
+		return mj.JSONCheckBigInt{
+			Matcher:  matcher,
+			Original: matcherStr}, err
 	}
 
 	jbi, err := p.processBigInt(obj, format)
@@ -42,7 +51,15 @@ func (p *Parser) processBigInt(obj oj.OJsonObject, format bigIntParseFormat) (mj
 		return mj.JSONBigInt{}, err
 	}
 
+	referencesBefore := p.fileReferenceCount()
 	bi, err := p.parseBigInt(strVal, format)
+	if err == nil && p.inlineFileReferences(strVal, referencesBefore) {
+		// decimal, like the values of both formats
+		return mj.JSONBigInt{
+			Value:    bi,
+			Original: bi.String(),
+		}, nil
+	}
 	return mj.JSONBigInt{
 		Value:    bi,
 		Original: strVal,
@@ -50,7 +67,7 @@ func (p *Parser) processBigInt(obj oj.OJsonObject, format bigIntParseFormat) (mj
 }
 
 func (p *Parser) parseBigInt(strRaw string, format bigIntParseFormat) (*big.Int, error) {
-	bytes, err := p.ValueInterpreter.InterpretString(strRaw)
+	bytes, err := p.interpretString(strRaw)
 	if err != nil {
 		return nil, err
 	}
@@ -58,6 +75,12 @@ func (p *Parser) parseBigInt(strRaw string, format bigIntParseFormat) (*big.Int,
 	case bigIntSignedBytes:
 		return twos.FromBytes(bytes), nil
 	case bigIntUnsignedBytes:
+		if strings.HasPrefix(strRaw, "-") {
+			p.ValueInterpreter.AddDiagnostic(vi.SeverityError, strRaw, "negative value in unsigned context")
+			if p.ValueInterpreter.Strict {
+				return nil, errors.New("negative value in unsigned context")
+			}
+		}
 		return big.NewInt(0).SetBytes(bytes), nil
 	default:
 		return nil, errors.New("unknown format requested")
@@ -70,7 +93,13 @@ func (p *Parser) processCheckUint64(obj oj.OJsonObject) (mj.JSONCheckUint64, err
 		return mj.JSONCheckUint64{
 			Value:    0,
 			IsStar:   true,
-			Original: "*"}, nil
+			Original: starOriginal(obj)}, nil
+	}
+	if matcherStr, isMatcher := matcherString(obj); isMatcher {
+		matcher, err := p.ValueInterpreter.InterpretMatcher(matcherStr)
+		return mj.JSONCheckUint64{
+			Matcher:  matcher,
+			Original: matcherStr}, err
 	}
 
 	ju, err := p.processUint64(obj)
@@ -91,7 +120,7 @@ func (p *Parser) processUint64(obj oj.OJsonObject) (mj.JSONUint64, error) {
 	}
 
 	if bi.Value == nil || !bi.Value.IsUint64() {
-		return mj.JSONUint64{}, errors.New("value is not uint64")
+		return mj.JSONUint64{}, fmt.Errorf("value out of range for uint64: %s", bi.Original)
 	}
 
 	return mj.JSONUint64{
@@ -102,7 +131,17 @@ func (p *Parser) processUint64(obj oj.OJsonObject) (mj.JSONUint64, error) {
 func (p *Parser) parseCheckBytes(obj oj.OJsonObject) (mj.JSONCheckBytes, error) {
 	if IsStar(obj) {
 		// "*" means any value, skip checking it
-		return mj.JSONCheckBytesExplicitStar(), nil
+		star := mj.JSONCheckBytesExplicitStar()
+		star.Original = obj
+		return star, nil
+	}
+	if matcherStr, isMatcher := matcherString(obj); isMatcher {
+		matcher, err := p.ValueInterpreter.InterpretMatcher(matcherStr)
+		return mj.JSONCheckBytes{
+			Value:    []byte{},
+			Matcher:  matcher,
+			Original: obj,
+		}, err
 	}
 
 	jb, err := p.processSubTreeAsByteArray(obj)
@@ -121,16 +160,27 @@ func (p *Parser) processStringAsByteArray(obj oj.OJsonObject) (mj.JSONBytesFromS
 	if err != nil {
 		return mj.JSONBytesFromString{}, err
 	}
-	result, err := p.ValueInterpreter.InterpretString(strVal)
-	return mj.NewJSONBytesFromString(result, strVal), err
+	referencesBefore := p.fileReferenceCount()
+	result, err := p.interpretString(strVal)
+	if err != nil {
+		return mj.NewJSONBytesFromString(result, strVal), err
+	}
+	return mj.NewJSONBytesFromString(result, p.inlinedString(strVal, result, referencesBefore)), nil
 }
 
 func (p *Parser) processSubTreeAsByteArray(obj oj.OJsonObject) (mj.JSONBytesFromTree, error) {
-	value, err := p.ValueInterpreter.InterpretSubTree(obj)
+	referencesBefore := p.fileReferenceCount()
+	value, err := p.interpretSubTree(obj)
+	if err != nil {
+		return mj.JSONBytesFromTree{
+			Value:    value,
+			Original: obj,
+		}, err
+	}
 	return mj.JSONBytesFromTree{
 		Value:    value,
-		Original: obj,
-	}, err
+		Original: p.inlinedTree(obj, value, referencesBefore),
+	}, nil
 }
 
 func (p *Parser) parseString(obj oj.OJsonObject) (string, error) {
@@ -141,11 +191,25 @@ func (p *Parser) parseString(obj oj.OJsonObject) (string, error) {
 	return str.Value, nil
 }
 
-// IsStar returns whether check object is othe form "*".
+// IsStar returns whether check object is of the form "*", or "any".
 func IsStar(obj oj.OJsonObject) bool {
 	str, isStr := obj.(*oj.OJsonString)
 	if !isStr {
 		return false
 	}
-	return str.Value == "*"
+	return vi.IsAnyValue(str.Value)
+}
+
+// matcherString yields the check object as a string, if it is a matcher, e.g. "match:range:1..5".
+func matcherString(obj oj.OJsonObject) (string, bool) {
+	str, isStr := obj.(*oj.OJsonString)
+	if !isStr || !vi.IsMatcher(str.Value) {
+		return "", false
+	}
+	return str.Value, true
+}
+
+// starOriginal yields "*" or "any", as written.
+func starOriginal(obj oj.OJsonObject) string {
+	return obj.(*oj.OJsonString).Value
 }
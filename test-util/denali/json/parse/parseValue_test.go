@@ -37,3 +37,15 @@ func TestBigInt(t *testing.T) {
 	require.Nil(t, err)
 	require.True(t, big.NewInt(0).Cmp(result) == 0)
 }
+
+func TestBigIntStrict(t *testing.T) {
+	p := Parser{}
+	p.ValueInterpreter.Strict = true
+	result, err := p.parseBigInt("-1", bigIntSignedBytes)
+	require.Nil(t, err)
+	require.Equal(t, big.NewInt(-1), result)
+
+	_, err = p.parseBigInt("-1", bigIntUnsignedBytes)
+	require.NotNil(t, err)
+	require.Equal(t, 1, len(p.ValueInterpreter.Diagnostics))
+}
@@ -2,12 +2,58 @@ package denalijsonparse
 
 import (
 	fr "github.com/numbatx/gn-vm-util/test-util/denali/json/fileresolver"
+	mj "github.com/numbatx/gn-vm-util/test-util/denali/json/model"
 	vi "github.com/numbatx/gn-vm-util/test-util/denali/json/valueinterpreter"
+	oj "github.com/numbatx/gn-vm-util/test-util/orderedjson"
 )
 
 // Parser performs parsing of both json tests (older) and scenarios (new).
 type Parser struct {
 	ValueInterpreter vi.ValueInterpreter
+
+	// InlineFiles causes values that load files, e.g. "file:adder.wasm" or "file:json:args.json",
+	// to keep their contents as originals instead of the file references, so that the scenario is self-contained
+	// and can be written and run without its fixture directory. The references are listed in Scenario.InlinedFiles.
+	// Paths of externalSteps are kept, since the steps are loaded by the runner, not by the parser.
+	InlineFiles bool
+
+	// KeepSource makes the parser keep the JSON tree of each scenario, in Scenario.Source, so that tools can map
+	// model objects back to the JSON nodes they were parsed from, with their positions.
+	KeepSource bool
+
+	// DuplicateKeys decides, per section, what happens to keys that appear more than once in the same map,
+	// e.g. {DuplicateKeySectionSetState: DuplicateKeyError}. Sections left out keep their default:
+	// DuplicateKeyError in checkState steps and expected results, DuplicateKeyWarn elsewhere.
+	DuplicateKeys map[string]DuplicateKeyMode
+
+	// Mode decides what happens to unknown fields and deprecated syntax, see ParseMode.
+	// Strict and lax mode also collect diagnostics, see Warnings.
+	Mode ParseMode
+
+	// CacheValues makes the parser interpret each distinct expression once per scenario, e.g. an address
+	// repeated in thousands of places, instead of every time it occurs. See ValueCacheStats.
+	CacheValues bool
+
+	// valueCache holds the values of the scenario being parsed, only with CacheValues
+	valueCache *valueCache
+
+	// source collects the JSON nodes of the model objects, only while parsing a scenario with KeepSource
+	source *mj.ScenarioSource
+
+	// inlinedFiles collects the values inlined so far, only while parsing a scenario with InlineFiles
+	inlinedFiles []*mj.InlinedFile
+
+	// config holds the defaults of the scenario being parsed, nil if it has none
+	config *mj.ScenarioConfig
+
+	// nonces tracks the next nonce of each sender, only while parsing a scenario with autoNonces
+	nonces map[string]uint64
+
+	// positions locates the values of the scenario being parsed in its file, for errors
+	positions oj.Positions
+
+	// stepFilter selects the steps to parse, only while parsing a scenario partially, see parsePartial
+	stepFilter stepFilter
 }
 
 // NewParser provides a new Parser instance.
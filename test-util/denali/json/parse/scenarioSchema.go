@@ -0,0 +1,273 @@
+package denalijsonparse
+
+import (
+	mj "github.com/numbatx/gn-vm-util/test-util/denali/json/model"
+)
+
+// schema describes a JSON value of the scenario format, for validateScenario.
+// Only maps and lists are described, the parser checks the values themselves.
+// A nil schema accepts anything.
+type schema struct {
+	// name designates maps in messages, e.g. "account".
+	name string
+
+	// fields are the keys allowed in a map with fixed keys, with the schema of their values.
+	fields map[string]*schema
+
+	// required fields must be present.
+	required []string
+
+	// exclusive holds pairs of fields that cannot be used together.
+	exclusive [][2]string
+
+	// entries is the schema of the values of a map with free keys, e.g. the accounts, keyed by address.
+	entries *schema
+
+	// elements is the schema of the elements of a list.
+	elements *schema
+}
+
+func mapOf(entries *schema) *schema {
+	return &schema{entries: entries}
+}
+
+func listOf(elements *schema) *schema {
+	return &schema{elements: elements}
+}
+
+var blockInfoSchema = &schema{
+	name: "block info",
+	fields: map[string]*schema{
+		"blockTimestamp": nil,
+		"blockNonce":     nil,
+		"blockRound":     nil,
+		"blockEpoch":     nil,
+	},
+}
+
+var accountSchema = &schema{
+	name: "account",
+	fields: map[string]*schema{
+		"comment":       nil,
+		"nonce":         nil,
+		"balance":       nil,
+		"storage":       nil,
+		"code":          nil,
+		"asyncCallData": nil,
+	},
+}
+
+var newAddressSchema = &schema{
+	name: "new address",
+	fields: map[string]*schema{
+		"creatorAddress": nil,
+		"creatorNonce":   nil,
+		"newAddress":     nil,
+	},
+	required: []string{"creatorAddress", "creatorNonce", "newAddress"},
+}
+
+var generateAccountsSchema = &schema{
+	name: "generateAccounts",
+	fields: map[string]*schema{
+		"count":   nil,
+		"prefix":  nil,
+		"balance": nil,
+	},
+	required: []string{"count", "prefix"},
+}
+
+var logSchema = &schema{
+	name: "log",
+	fields: map[string]*schema{
+		"address":    nil,
+		"identifier": nil,
+		"topics":     nil,
+		"data":       nil,
+	},
+}
+
+var txResultSchema = &schema{
+	name: "tx result",
+	fields: map[string]*schema{
+		"out":     nil,
+		"status":  nil,
+		"message": nil,
+		"logs":    listOf(logSchema),
+		"gas":     nil,
+		"refund":  nil,
+	},
+}
+
+var invariantQuantitySchema = &schema{
+	name: "quantity",
+	fields: map[string]*schema{
+		"account": nil,
+		"field":   nil,
+		"key":     nil,
+	},
+	required: []string{"account", "field"},
+}
+
+var invariantSchema = &schema{
+	name: "invariant",
+	fields: map[string]*schema{
+		"comment":     nil,
+		"constantSum": listOf(invariantQuantitySchema),
+		"value":       invariantQuantitySchema,
+		"expect":      nil,
+	},
+	exclusive: [][2]string{
+		{"constantSum", "value"},
+		{"constantSum", "expect"},
+	},
+}
+
+var metadataSchema = &schema{
+	name: "metadata",
+	fields: map[string]*schema{
+		"owner":    nil,
+		"license":  nil,
+		"tags":     nil,
+		"flavor":   nil,
+		"priority": nil,
+	},
+}
+
+var configSchema = &schema{
+	name: "config",
+	fields: map[string]*schema{
+		"gasPrice": nil,
+		"caller":   nil,
+		"chainID":  nil,
+		"seed":     nil,
+	},
+}
+
+// scenarioSchema describes the top level of a scenario. Steps are described by stepSchemas.
+var scenarioSchema = &schema{
+	name: "scenario",
+	fields: map[string]*schema{
+		"schemaVersion":         nil,
+		"requiresFormatVersion": nil,
+		"abi":                   nil,
+		"gasSchedule":           nil,
+		"config":                configSchema,
+		"autoNonces":            nil,
+		"name":                  nil,
+		"comment":               nil,
+		"metadata":              metadataSchema,
+		"checkGas":              nil,
+		"constants":             nil,
+		"defines":               nil,
+		"parameters":            nil,
+		"gasPresets":            nil,
+		"invariants":            mapOf(invariantSchema),
+		"steps":                 nil,
+	},
+}
+
+// stepSchemas describe the steps, by step type.
+var stepSchemas = map[string]*schema{
+	mj.StepNameExternalSteps: {
+		name: mj.StepNameExternalSteps + " step",
+		fields: map[string]*schema{
+			"step":      nil,
+			"id":        nil,
+			"tags":      nil,
+			"comment":   nil,
+			"path":      nil,
+			"arguments": nil,
+		},
+		required: []string{"path"},
+	},
+	mj.StepNameSetState: {
+		name: mj.StepNameSetState + " step",
+		fields: map[string]*schema{
+			"step":              nil,
+			"id":                nil,
+			"tags":              nil,
+			"comment":           nil,
+			"accounts":          mapOf(accountSchema),
+			"newAddresses":      listOf(newAddressSchema),
+			"previousBlockInfo": blockInfoSchema,
+			"currentBlockInfo":  blockInfoSchema,
+			"blockHashes":       nil,
+			"generateAccounts":  generateAccountsSchema,
+			"allowedErrors":     nil,
+		},
+	},
+	mj.StepNameCheckState: {
+		name: mj.StepNameCheckState + " step",
+		fields: map[string]*schema{
+			"step":    nil,
+			"id":      nil,
+			"tags":    nil,
+			"comment": nil,
+			// the "+" entry is a string, not an account, so it is not inspected
+			"accounts": mapOf(accountSchema),
+		},
+	},
+	mj.StepNameDumpState: {
+		name: mj.StepNameDumpState + " step",
+		fields: map[string]*schema{
+			"step":    nil,
+			"id":      nil,
+			"tags":    nil,
+			"comment": nil,
+		},
+	},
+	mj.StepNameScCall:          txStepSchema(mj.ScCall),
+	mj.StepNameScDeploy:        txStepSchema(mj.ScDeploy),
+	mj.StepNameTransfer:        txStepSchema(mj.Transfer),
+	mj.StepNameValidatorReward: txStepSchema(mj.ValidatorReward),
+	mj.StepNameScQuery:         txStepSchema(mj.ScQuery),
+}
+
+// txStepSchema only allows the transaction fields that the parser always rejects for the given type.
+// Fields only rejected for some of their values, e.g. a non-empty "function" in transfers, are left to the parser.
+func txStepSchema(txType mj.TransactionType) *schema {
+	step := &mj.TxStep{Tx: &mj.Transaction{Type: txType}}
+	txFields := map[string]*schema{
+		"nonce":        nil,
+		"to":           nil,
+		"function":     nil,
+		"arguments":    nil,
+		"contractCode": nil,
+	}
+	if txType.HasSender() {
+		txFields["from"] = nil
+	}
+	if txType.HasValueAndGas() {
+		txFields["value"] = nil
+		txFields["gasPrice"] = nil
+		txFields["gasLimit"] = nil
+	}
+	if txType == mj.Transfer {
+		txFields["data"] = nil
+	}
+	txSchema := &schema{
+		name:   step.StepTypeName() + " transaction",
+		fields: txFields,
+	}
+	if txType.HasReceiver() {
+		txSchema.required = []string{"to"}
+	}
+
+	stepFields := map[string]*schema{
+		"step":    nil,
+		"id":      nil,
+		"tags":    nil,
+		"txId":    nil,
+		"comment": nil,
+		"tx":      txSchema,
+	}
+	if txType.IsSmartContractTx() {
+		stepFields["expect"] = txResultSchema
+	}
+	return &schema{
+		name:     step.StepTypeName() + " step",
+		fields:   stepFields,
+		required: []string{"tx"},
+	}
+}
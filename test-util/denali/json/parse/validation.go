@@ -0,0 +1,254 @@
+package denalijsonparse
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	vi "github.com/numbatx/gn-vm-util/test-util/denali/json/valueinterpreter"
+	oj "github.com/numbatx/gn-vm-util/test-util/orderedjson"
+)
+
+// ValidationError lists all the problems found in a scenario, e.g. misspelled or missing fields,
+// or broken steps, instead of only the first one.
+type ValidationError struct {
+	Problems []*ParseError
+}
+
+// Error yields the number of problems, followed by each of them, on its own line.
+func (e *ValidationError) Error() string {
+	var sb strings.Builder
+	if len(e.Problems) == 1 {
+		sb.WriteString("invalid scenario, 1 problem:")
+	} else {
+		fmt.Fprintf(&sb, "invalid scenario, %d problems:", len(e.Problems))
+	}
+	for _, problem := range e.Problems {
+		sb.WriteString("\n    ")
+		sb.WriteString(problem.Error())
+	}
+	return sb.String()
+}
+
+// Unwrap yields the problems, so that errors.As finds the first one.
+func (e *ValidationError) Unwrap() []error {
+	errs := make([]error, len(e.Problems))
+	for i, problem := range e.Problems {
+		errs[i] = problem
+	}
+	return errs
+}
+
+// SetFile sets the file of all problems, see ParseError.File.
+func (e *ValidationError) SetFile(file string) {
+	for _, problem := range e.Problems {
+		problem.File = file
+	}
+}
+
+// validator collects the problems of a scenario, in document order.
+type validator struct {
+	positions  oj.Positions
+	stepFilter stepFilter
+	problems   []*ParseError
+
+	// ignoreUnknown collects the unknown fields, instead of reporting them, in lax mode
+	ignoreUnknown bool
+	unknown       []*unknownField
+}
+
+// unknownField is a field that the schema does not know, ignored in lax mode.
+type unknownField struct {
+	parent  *oj.OJsonMap
+	key     string
+	problem *ParseError
+}
+
+// validateScenario checks the keys of all the maps in a scenario against the scenario schema,
+// before the parser interprets any value. Nil if there is nothing wrong.
+func (p *Parser) validateScenario(topMap *oj.OJsonMap) *ValidationError {
+	v := &validator{
+		positions:     p.positions,
+		stepFilter:    p.stepFilter,
+		ignoreUnknown: p.Mode == ParseModeLax,
+	}
+	v.validateMap("", topMap, scenarioSchema)
+	for _, kvp := range topMap.OrderedKV {
+		if kvp.Key == "steps" {
+			v.validateSteps(kvp.Key, kvp.Value)
+		}
+	}
+	if len(v.problems) > 0 {
+		return &ValidationError{Problems: v.problems}
+	}
+
+	// only once the scenario is known to be valid otherwise, so that nothing gets removed from rejected scenarios
+	for _, unknown := range v.unknown {
+		p.ValueInterpreter.AddDiagnosticAt(vi.SeverityWarning, unknown.problem.Position, unknown.key,
+			fmt.Sprintf("%s ignored: %s", unknown.problem.JSONPath, unknown.problem.Err))
+		removeField(unknown.parent, unknown.key)
+	}
+	return nil
+}
+
+// removeField removes a map key, with its value.
+func removeField(obj *oj.OJsonMap, key string) {
+	kept := obj.OrderedKV[:0]
+	for _, kvp := range obj.OrderedKV {
+		if kvp.Key != key {
+			kept = append(kept, kvp)
+		}
+	}
+	obj.OrderedKV = kept
+	obj.RefreshKeySet()
+}
+
+func (v *validator) report(jsonPath string, obj oj.OJsonObject, format string, args ...interface{}) {
+	v.problems = append(v.problems, v.problem(jsonPath, obj, format, args...))
+}
+
+func (v *validator) problem(jsonPath string, obj oj.OJsonObject, format string, args ...interface{}) *ParseError {
+	return &ParseError{
+		Position: v.positions[obj],
+		JSONPath: jsonPath,
+		Err:      fmt.Errorf(format, args...),
+	}
+}
+
+// validate leaves values that are not of the expected kind to the parser, which explains what it expected.
+func (v *validator) validate(jsonPath string, obj oj.OJsonObject, s *schema) {
+	if s == nil {
+		return
+	}
+	switch specificObj := obj.(type) {
+	case *oj.OJsonMap:
+		v.validateMap(jsonPath, specificObj, s)
+	case *oj.OJsonList:
+		if s.elements != nil {
+			for i, elem := range specificObj.AsList() {
+				v.validate(oj.ElementPath(jsonPath, i), elem, s.elements)
+			}
+		}
+	}
+}
+
+func (v *validator) validateMap(jsonPath string, objMap *oj.OJsonMap, s *schema) {
+	if s.entries != nil {
+		for _, kvp := range objMap.OrderedKV {
+			v.validate(oj.ChildPath(jsonPath, kvp.Key), kvp.Value, s.entries)
+		}
+		return
+	}
+	if s.fields == nil {
+		return
+	}
+
+	present := make(map[string]bool, len(objMap.OrderedKV))
+	for _, kvp := range objMap.OrderedKV {
+		present[kvp.Key] = true
+		fieldPath := oj.ChildPath(jsonPath, kvp.Key)
+		fieldSchema, isKnown := s.fields[kvp.Key]
+		if !isKnown {
+			problem := v.problem(fieldPath, kvp.Value,
+				"unknown %s field \"%s\"%s", s.name, kvp.Key, suggestField(kvp.Key, s.fields))
+			if v.ignoreUnknown {
+				v.unknown = append(v.unknown, &unknownField{parent: objMap, key: kvp.Key, problem: problem})
+			} else {
+				v.problems = append(v.problems, problem)
+			}
+			continue
+		}
+		v.validate(fieldPath, kvp.Value, fieldSchema)
+	}
+	for _, field := range s.required {
+		if !present[field] {
+			v.report(jsonPath, objMap, "missing %s field \"%s\"", s.name, field)
+		}
+	}
+	for _, pair := range s.exclusive {
+		if present[pair[0]] && present[pair[1]] {
+			v.report(jsonPath, objMap, "%s fields \"%s\" and \"%s\" cannot be used together", s.name, pair[0], pair[1])
+		}
+	}
+}
+
+func (v *validator) validateSteps(jsonPath string, obj oj.OJsonObject) {
+	stepList, isList := obj.(*oj.OJsonList)
+	if !isList {
+		return
+	}
+	for i, stepObj := range stepList.AsList() {
+		stepPath := oj.ElementPath(jsonPath, i)
+		stepMap, isMap := stepObj.(*oj.OJsonMap)
+		if !isMap || !v.isStepSelected(i, stepMap) {
+			continue
+		}
+		stepType, hasStepType := rawStepType(stepMap)
+		if !hasStepType {
+			v.report(stepPath, stepMap, "missing step field \"step\"")
+			continue
+		}
+		stepSchema, isKnown := stepSchemas[stepType]
+		if !isKnown {
+			v.report(stepPath, stepMap, "unknown step type \"%s\"%s", stepType, suggestField(stepType, stepSchemas))
+			continue
+		}
+		v.validateMap(stepPath, stepMap, stepSchema)
+	}
+}
+
+// isStepSelected only checks the steps that a partial parse selects, see parsePartial.
+func (v *validator) isStepSelected(index int, stepMap *oj.OJsonMap) bool {
+	return v.stepFilter == nil || v.stepFilter(index, stepMap)
+}
+
+// suggestField yields ", did you mean ...?" for the closest allowed key, if it is close enough to be a typo.
+func suggestField(key string, fields map[string]*schema) string {
+	candidates := make([]string, 0, len(fields))
+	for field := range fields {
+		candidates = append(candidates, field)
+	}
+	sort.Strings(candidates)
+
+	best := ""
+	bestDistance := len(key)/3 + 2
+	for _, candidate := range candidates {
+		distance := editDistance(strings.ToLower(key), strings.ToLower(candidate))
+		if distance < bestDistance {
+			best = candidate
+			bestDistance = distance
+		}
+	}
+	if len(best) == 0 {
+		return ""
+	}
+	return fmt.Sprintf(", did you mean \"%s\"?", best)
+}
+
+// editDistance is the Levenshtein distance, in bytes.
+func editDistance(a string, b string) int {
+	previous := make([]int, len(b)+1)
+	current := make([]int, len(b)+1)
+	for j := range previous {
+		previous[j] = j
+	}
+	for i := 1; i <= len(a); i++ {
+		current[0] = i
+		for j := 1; j <= len(b); j++ {
+			cost := 1
+			if a[i-1] == b[j-1] {
+				cost = 0
+			}
+			current[j] = minInt(minInt(previous[j]+1, current[j-1]+1), previous[j-1]+cost)
+		}
+		previous, current = current, previous
+	}
+	return previous[len(b)]
+}
+
+func minInt(a int, b int) int {
+	if a < b {
+		return a
+	}
+	return b
+}
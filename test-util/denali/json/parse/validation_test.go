@@ -0,0 +1,58 @@
+package denalijsonparse
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseScenarioValidation(t *testing.T) {
+	p := Parser{}
+	_, err := p.ParseScenarioFile([]byte(`{
+	"nmae": "typo",
+	"invariants": {
+		"both": {"constantSum": [], "value": {"account": "address:a", "field": "balance"}}
+	},
+	"steps": [
+		{
+			"step": "checkState",
+			"accounts": {
+				"address:owner": {"balanace": "100"},
+				"+": ""
+			}
+		},
+		{"step": "scCal", "tx": {}},
+		{"step": "scCall", "tx": {"from": "address:owner", "function": "f"}},
+		{"step": "transfer", "tx": {"from": "address:owner", "to": "address:a"}, "expect": {}},
+		{"comment": "no type"}
+	]
+}`))
+	var validationErr *ValidationError
+	require.True(t, errors.As(err, &validationErr))
+
+	var problems []string
+	for _, problem := range validationErr.Problems {
+		problems = append(problems, problem.JSONPath+": "+problem.Err.Error())
+	}
+	require.Equal(t, []string{
+		`nmae: unknown scenario field "nmae", did you mean "name"?`,
+		`invariants.both: invariant fields "constantSum" and "value" cannot be used together`,
+		`steps[0].accounts.address:owner.balanace: unknown account field "balanace", did you mean "balance"?`,
+		`steps[1]: unknown step type "scCal", did you mean "scCall"?`,
+		`steps[2].tx: missing scCall transaction field "to"`,
+		`steps[3].expect: unknown transfer step field "expect"`,
+		`steps[4]: missing step field "step"`,
+	}, problems)
+	require.Equal(t, 2, validationErr.Problems[0].Position.Line)
+
+	validationErr.SetFile("scenario.json")
+	require.Contains(t, err.Error(), "invalid scenario, 7 problems:\n    scenario.json:2:10: nmae: ")
+}
+
+func TestSuggestField(t *testing.T) {
+	fields := map[string]*schema{"balance": nil, "nonce": nil, "storage": nil}
+	require.Equal(t, `, did you mean "balance"?`, suggestField("balanace", fields))
+	require.Equal(t, `, did you mean "nonce"?`, suggestField("Nonce", fields))
+	require.Equal(t, "", suggestField("somethingElse", fields))
+}
@@ -0,0 +1,90 @@
+package denalijsonparse
+
+import (
+	oj "github.com/numbatx/gn-vm-util/test-util/orderedjson"
+)
+
+// ValueCacheStats measures how well the value cache works, see Parser.CacheValues.
+type ValueCacheStats struct {
+	// Hits counts the expressions that did not need to be interpreted again.
+	Hits uint64
+
+	// Misses counts the expressions that were interpreted, and cached.
+	Misses uint64
+
+	// Bypassed counts the expressions interpreted without the cache, in lint or strict mode
+	// and while inlining files, where every occurrence has to be seen by the interpreter.
+	Bypassed uint64
+}
+
+// HitRate yields the share of the cached expressions that were hits, between 0 and 1.
+func (s ValueCacheStats) HitRate() float64 {
+	if s.Hits+s.Misses == 0 {
+		return 0
+	}
+	return float64(s.Hits) / float64(s.Hits+s.Misses)
+}
+
+// valueCache holds the values of the expressions interpreted so far, keyed on the raw expression.
+// Only valid within one scenario, since constants, defines, the ABI and relative paths are scoped to it.
+type valueCache struct {
+	values map[string][]byte
+	stats  ValueCacheStats
+}
+
+// ValueCacheStats yields the statistics of the value cache, accumulated over all scenarios parsed so far.
+func (p *Parser) ValueCacheStats() ValueCacheStats {
+	if p.valueCache == nil {
+		return ValueCacheStats{}
+	}
+	return p.valueCache.stats
+}
+
+// startCachingValues empties the cache for a new scenario, if caching is enabled.
+// The returned function stops caching, so that single steps parsed later are interpreted in their own context.
+func (p *Parser) startCachingValues() func() {
+	if !p.CacheValues {
+		return func() {}
+	}
+	if p.valueCache == nil {
+		p.valueCache = &valueCache{}
+	}
+	outerValues := p.valueCache.values
+	p.valueCache.values = make(map[string][]byte)
+	return func() {
+		p.valueCache.values = outerValues
+	}
+}
+
+// interpretString is ValueInterpreter.InterpretString, through the value cache.
+// Errors are not cached, they are reported where they occur.
+func (p *Parser) interpretString(expression string) ([]byte, error) {
+	cache := p.valueCache
+	if cache == nil || cache.values == nil {
+		return p.ValueInterpreter.InterpretString(expression)
+	}
+	if p.ValueInterpreter.IsCollectingDiagnostics() || p.ValueInterpreter.RecordFileReferences {
+		cache.stats.Bypassed++
+		return p.ValueInterpreter.InterpretString(expression)
+	}
+	if value, found := cache.values[expression]; found {
+		cache.stats.Hits++
+		// copied, the parsed values do not share memory
+		return append([]byte{}, value...), nil
+	}
+	value, err := p.ValueInterpreter.InterpretString(expression)
+	if err != nil {
+		return value, err
+	}
+	cache.stats.Misses++
+	cache.values[expression] = value
+	return append([]byte{}, value...), nil
+}
+
+// interpretSubTree is ValueInterpreter.InterpretSubTree, through the value cache for single expressions.
+func (p *Parser) interpretSubTree(obj oj.OJsonObject) ([]byte, error) {
+	if str, isStr := obj.(*oj.OJsonString); isStr {
+		return p.interpretString(str.Value)
+	}
+	return p.ValueInterpreter.InterpretSubTree(obj)
+}
@@ -0,0 +1,49 @@
+package denalijsonparse
+
+import (
+	"testing"
+
+	mj "github.com/numbatx/gn-vm-util/test-util/denali/json/model"
+	"github.com/stretchr/testify/require"
+)
+
+const repeatedValuesScenario = `{
+	"constants": {"OWNER": "address:owner"},
+	"steps": [
+		{"step": "setState", "accounts": {
+			"address:owner": {"nonce": "0", "balance": "1000"},
+			"address:other": {"nonce": "0", "balance": "1000"}
+		}},
+		{"step": "checkState", "accounts": {
+			"const:OWNER": {"nonce": "0", "balance": "1000"}
+		}}
+	]
+}`
+
+func TestCacheValues(t *testing.T) {
+	p := Parser{CacheValues: true}
+	scenario, err := p.ParseScenarioFile([]byte(repeatedValuesScenario))
+	require.Nil(t, err)
+	accounts := scenario.Steps[0].(*mj.SetStateStep).Accounts
+	checkAccounts := scenario.Steps[1].(*mj.CheckStateStep).CheckAccounts.Accounts
+	require.Equal(t, accounts[0].Address.Value, checkAccounts[0].Address.Value)
+
+	// the values are not shared
+	accounts[1].Balance.Value.SetInt64(5)
+	require.Equal(t, "1000", accounts[0].Balance.Value.String())
+	accounts[0].Address.Value[0] = 0xff
+	require.NotEqual(t, accounts[0].Address.Value, checkAccounts[0].Address.Value)
+
+	stats := p.ValueCacheStats()
+	require.Equal(t, ValueCacheStats{Hits: 5, Misses: 5}, stats)
+	require.Equal(t, 0.5, stats.HitRate())
+
+	// scoped to the scenario
+	_, err = p.ParseScenarioStep(`{"step": "checkState", "accounts": {"const:OWNER": {}}}`)
+	require.NotNil(t, err)
+
+	p.ValueInterpreter.Lint = true
+	_, err = p.ParseScenarioFile([]byte(repeatedValuesScenario))
+	require.Nil(t, err)
+	require.Equal(t, uint64(10), p.ValueCacheStats().Bypassed)
+}
@@ -0,0 +1,20 @@
+package denalivalueinterpreter
+
+import (
+	"fmt"
+
+	abi "github.com/numbatx/gn-vm-util/test-util/denali/json/abi"
+)
+
+// Basic types, such as "abi:u64:5", also work when the scenario references no ABI.
+func (vi *ValueInterpreter) interpretABILiteral(strRaw string) ([]byte, error) {
+	typeName, literal, ok := abi.ParseLiteral(strRaw)
+	if !ok {
+		return []byte{}, fmt.Errorf("invalid typed literal, expected abi:TYPE:VALUE: %s", strRaw)
+	}
+	result, err := vi.ABI.EncodeTopLevel(typeName, literal, vi.interpretString)
+	if err != nil {
+		return []byte{}, fmt.Errorf("cannot encode %s: %w", strRaw, err)
+	}
+	return result, nil
+}
@@ -0,0 +1,87 @@
+package denalivalueinterpreter
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// AddressLength is the length of the addresses generated by "address:".
+const AddressLength = 32
+
+// AddressLengthMode decides how "address:" handles names longer than AddressLength bytes.
+// Shorter names are always padded with "_".
+type AddressLengthMode int
+
+const (
+	// AddressTruncate keeps the first AddressLength bytes of the name.
+	// Names that only differ after that yield the same address.
+	AddressTruncate AddressLengthMode = iota
+
+	// AddressLengthError rejects long names.
+	AddressLengthError
+
+	// AddressKeccak derives the address as the keccak256 hash of the entire name.
+	// Long names can no longer collide, but their addresses are not readable.
+	AddressKeccak
+)
+
+// interpretAddress handles "address:NAME" and "address:NAME#SHARD".
+// A trailing "#" followed by decimal digits is a shard suffix, it sets the last byte of the address to the shard id.
+// Before the suffix was introduced, it was part of the name, so names like "pool#1" now yield another address.
+// Any other "#" stays part of the name, e.g. in "alice#" or "a#b#3", and is reported in lint mode.
+func (vi *ValueInterpreter) interpretAddress(addrName string) ([]byte, error) {
+	shardSeparatorIndex := shardSuffixIndex(addrName)
+	if shardSeparatorIndex < 0 {
+		vi.lintAddressName(addrName, addrName)
+		return vi.namedAddress(addrName)
+	}
+	shardID, err := strconv.ParseUint(addrName[shardSeparatorIndex+1:], 10, 8)
+	if err != nil {
+		return []byte{}, fmt.Errorf("invalid shard id in address %s: %w", addrName, err)
+	}
+	vi.lintAddressName(addrName, addrName[:shardSeparatorIndex])
+	result, err := vi.namedAddress(addrName[:shardSeparatorIndex])
+	if err != nil {
+		return []byte{}, err
+	}
+	// with up to 256 shards, the shard of an address is computed from its last byte
+	result[len(result)-1] = byte(shardID)
+	return result, nil
+}
+
+// shardSuffixIndex yields where the shard suffix of an address name starts, -1 if it has none.
+func shardSuffixIndex(addrName string) int {
+	shardSeparatorIndex := strings.LastIndex(addrName, addrShardSeparator)
+	if shardSeparatorIndex < 0 || !isDecimalDigits(addrName[shardSeparatorIndex+1:]) {
+		return -1
+	}
+	return shardSeparatorIndex
+}
+
+// lintAddressName flags a "#" left in the name, after taking off the shard suffix, likely a mistyped shard suffix.
+func (vi *ValueInterpreter) lintAddressName(addrName string, name string) {
+	if strings.Contains(name, addrShardSeparator) {
+		vi.AddDiagnostic(SeverityWarning, AddressPrefix+addrName,
+			"'#' in address name, only a trailing '#' followed by a shard id is a shard suffix")
+	}
+}
+
+// namedAddress yields a fresh slice, so that callers can change it.
+func (vi *ValueInterpreter) namedAddress(name string) ([]byte, error) {
+	if len(name) <= AddressLength {
+		return address([]byte(name))
+	}
+	switch vi.AddressLengthMode {
+	case AddressTruncate:
+		vi.AddDiagnostic(SeverityWarning, AddressPrefix+name,
+			fmt.Sprintf("address name longer than %d bytes, truncated", AddressLength))
+		return address([]byte(name))
+	case AddressLengthError:
+		return []byte{}, fmt.Errorf("address name longer than %d bytes: %s", AddressLength, name)
+	case AddressKeccak:
+		return keccak256([]byte(name))
+	default:
+		return []byte{}, fmt.Errorf("unknown address length mode: %d", vi.AddressLengthMode)
+	}
+}
@@ -0,0 +1,55 @@
+package denalivalueinterpreter
+
+import (
+	"bytes"
+	"errors"
+
+	oj "github.com/numbatx/gn-vm-util/test-util/orderedjson"
+)
+
+// Values that match anything, when used in checks.
+const (
+	AnyValueStar = "*"
+	AnyValueWord = "any"
+)
+
+// ErrAnyValue is wrapped by the error produced when a match-anything value is interpreted as bytes,
+// i.e. outside of a check.
+var ErrAnyValue = errors.New("matches any value, only allowed in checks")
+
+// IsAnyValue yields true for the values that match anything in checks, "*" and "any".
+func IsAnyValue(strRaw string) bool {
+	return strRaw == AnyValueStar || strRaw == AnyValueWord
+}
+
+// CheckValue is the result of interpreting a value that is only used in checks.
+// It either holds the expected bytes, a marker that matches any value, or a matcher.
+type CheckValue struct {
+	Value   []byte
+	Any     bool
+	Matcher Matcher
+}
+
+// Check yields true if the actual value matches.
+func (cv CheckValue) Check(actual []byte) bool {
+	if cv.Matcher != nil {
+		return cv.Matcher.MatchBytes(actual)
+	}
+	return cv.Any || bytes.Equal(cv.Value, actual)
+}
+
+// InterpretCheckSubTree interprets a value used in a check, such as an expected storage value.
+// A leaf consisting only of "*" or "any" yields the match-anything marker instead of bytes,
+// a "match:..." leaf yields a matcher, see InterpretMatcher.
+// Everything else is interpreted like in InterpretSubTree.
+func (vi *ValueInterpreter) InterpretCheckSubTree(obj oj.OJsonObject) (CheckValue, error) {
+	if str, isStr := obj.(*oj.OJsonString); isStr && IsAnyValue(str.Value) {
+		return CheckValue{Value: []byte{}, Any: true}, nil
+	}
+	if str, isStr := obj.(*oj.OJsonString); isStr && IsMatcher(str.Value) {
+		matcher, err := vi.InterpretMatcher(str.Value)
+		return CheckValue{Value: []byte{}, Matcher: matcher}, newValueError(RuleMatch, str.Value, err)
+	}
+	value, err := vi.InterpretSubTree(obj)
+	return CheckValue{Value: value}, err
+}
@@ -0,0 +1,58 @@
+package denalivalueinterpreter
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+)
+
+// wasmMagic is the header of every WASM module.
+var wasmMagic = []byte("\x00asm")
+
+// interpretCode loads a contract code file, like "file:", but rejects files that are not WASM modules,
+// so that path typos or wrong build outputs get reported before reaching the VM.
+func (vi *ValueInterpreter) interpretCode(path string) ([]byte, error) {
+	if vi.FileResolver == nil {
+		return []byte{}, errors.New("parser FileResolver not provided")
+	}
+	code, err := vi.FileResolver.ResolveFileValue(path)
+	if err != nil {
+		return []byte{}, err
+	}
+	vi.recordFileReference(path)
+	if !bytes.HasPrefix(code, wasmMagic) {
+		return []byte{}, fmt.Errorf("not a WASM module, the file does not start with the \\0asm header: %s", path)
+	}
+	if vi.RecordCodeHashes {
+		hash, err := vi.cachedKeccak256(code)
+		if err != nil {
+			return []byte{}, err
+		}
+		if vi.CodeHashes == nil {
+			vi.CodeHashes = make(map[string][]byte)
+		}
+		vi.CodeHashes[vi.FileResolver.ResolveAbsolutePath(path)] = hash
+	}
+	return code, nil
+}
+
+// recordedCodeHash yields the hash recorded when the code was loaded, for "keccak256:code:...".
+func (vi *ValueInterpreter) recordedCodeHash(path string) ([]byte, bool) {
+	if vi.CodeHashes == nil || vi.FileResolver == nil {
+		return nil, false
+	}
+	hash, found := vi.CodeHashes[vi.FileResolver.ResolveAbsolutePath(path)]
+	if !found {
+		return nil, false
+	}
+	// the hash stands for the file, as if it was loaded again
+	vi.recordFileReference(path)
+	return append([]byte{}, hash...), true
+}
+
+// recordFileReference records a file that a value loaded, see RecordFileReferences.
+func (vi *ValueInterpreter) recordFileReference(path string) {
+	if vi.RecordFileReferences {
+		vi.FileReferences = append(vi.FileReferences, vi.FileResolver.ResolveAbsolutePath(path))
+	}
+}
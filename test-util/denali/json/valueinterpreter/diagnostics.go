@@ -0,0 +1,147 @@
+package denalivalueinterpreter
+
+import (
+	"fmt"
+	"strings"
+
+	oj "github.com/numbatx/gn-vm-util/test-util/orderedjson"
+)
+
+// DiagnosticSeverity indicates how serious an interpreter diagnostic is.
+type DiagnosticSeverity int
+
+const (
+	// SeverityWarning flags values that are accepted, but are likely mistakes.
+	SeverityWarning DiagnosticSeverity = iota
+
+	// SeverityError flags values that are rejected in strict mode.
+	SeverityError
+)
+
+// String yields the severity name.
+func (s DiagnosticSeverity) String() string {
+	if s == SeverityError {
+		return "error"
+	}
+	return "warning"
+}
+
+// Diagnostic describes a problem found while interpreting a value.
+type Diagnostic struct {
+	Severity DiagnosticSeverity
+
+	// Expression is the (sub-)expression the diagnostic refers to.
+	Expression string
+
+	Message string
+
+	// Position locates the value in the scenario file, zero if unknown. Set by the parser.
+	Position oj.Position
+}
+
+// String yields a single line description of the diagnostic, starting with its position, if known.
+func (d *Diagnostic) String() string {
+	if d.Position.Line > 0 {
+		return fmt.Sprintf("%s: %s: %s (in \"%s\")", d.Position, d.Severity, d.Message, d.Expression)
+	}
+	return fmt.Sprintf("%s: %s (in \"%s\")", d.Severity, d.Message, d.Expression)
+}
+
+// DiagnosticsError is returned in strict mode, it holds all error diagnostics of an expression.
+type DiagnosticsError struct {
+	Diagnostics []*Diagnostic
+}
+
+// Error yields all diagnostics, separated by semicolons.
+func (de *DiagnosticsError) Error() string {
+	messages := make([]string, len(de.Diagnostics))
+	for i, d := range de.Diagnostics {
+		messages[i] = d.String()
+	}
+	return strings.Join(messages, "; ")
+}
+
+// AddDiagnostic records a diagnostic, if diagnostics are being collected (strict or lint mode).
+// It is also used by the parser, for problems only visible in context.
+func (vi *ValueInterpreter) AddDiagnostic(severity DiagnosticSeverity, expression string, message string) {
+	vi.AddDiagnosticAt(severity, oj.Position{}, expression, message)
+}
+
+// AddDiagnosticAt is AddDiagnostic, for callers that know where the value is in the scenario file.
+func (vi *ValueInterpreter) AddDiagnosticAt(severity DiagnosticSeverity, position oj.Position, expression string, message string) {
+	if !vi.IsCollectingDiagnostics() {
+		return
+	}
+	vi.Diagnostics = append(vi.Diagnostics, &Diagnostic{
+		Severity:   severity,
+		Expression: expression,
+		Message:    message,
+		Position:   position,
+	})
+}
+
+// flagDeprecated reports deprecated syntax, see FlagDeprecated.
+func (vi *ValueInterpreter) flagDeprecated(expression string, syntax string, replacement string) {
+	if !vi.FlagDeprecated {
+		return
+	}
+	severity := SeverityWarning
+	if vi.Strict {
+		severity = SeverityError
+	}
+	vi.AddDiagnostic(severity, expression, fmt.Sprintf("%s is deprecated, use %s instead", syntax, replacement))
+}
+
+// IsCollectingDiagnostics returns true in strict and lint mode,
+// callers can skip checks that would only produce diagnostics otherwise.
+func (vi *ValueInterpreter) IsCollectingDiagnostics() bool {
+	return vi.Strict || vi.Lint
+}
+
+// LintString interprets a value and yields the diagnostics about it, separately from the interpretation error.
+// Error diagnostics do not cause the value to be rejected, even in strict mode.
+// The diagnostics are not added to Diagnostics.
+func (vi *ValueInterpreter) LintString(strRaw string) ([]*Diagnostic, error) {
+	diagnosticsBefore := len(vi.Diagnostics)
+	wasLint := vi.Lint
+	vi.Lint = true
+	_, err := vi.interpretString(strRaw)
+	vi.Lint = wasLint
+
+	findings := append([]*Diagnostic{}, vi.Diagnostics[diagnosticsBefore:]...)
+	vi.Diagnostics = vi.Diagnostics[:diagnosticsBefore]
+	return findings, withExpression(err, strRaw)
+}
+
+// TakeDiagnostics yields all diagnostics collected so far and clears them.
+func (vi *ValueInterpreter) TakeDiagnostics() []*Diagnostic {
+	diagnostics := vi.Diagnostics
+	vi.Diagnostics = nil
+	return diagnostics
+}
+
+func errorDiagnostics(diagnostics []*Diagnostic) []*Diagnostic {
+	var errs []*Diagnostic
+	for _, d := range diagnostics {
+		if d.Severity == SeverityError {
+			errs = append(errs, d)
+		}
+	}
+	return errs
+}
+
+// looksLikePrefix detects values such as "u12:5", that were probably meant to use a prefix.
+func looksLikePrefix(strRaw string) (string, bool) {
+	colonIndex := strings.Index(strRaw, ":")
+	if colonIndex <= 0 {
+		return "", false
+	}
+	for i, c := range strRaw[:colonIndex] {
+		isLetter := (c >= 'a' && c <= 'z') || (c >= 'A' && c <= 'Z')
+		isDigit := c >= '0' && c <= '9'
+		if !(isLetter || (i > 0 && (isDigit || c == '-' || c == '_'))) {
+			return "", false
+		}
+	}
+	return strRaw[:colonIndex+1], true
+}
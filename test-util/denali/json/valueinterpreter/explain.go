@@ -0,0 +1,97 @@
+package denalivalueinterpreter
+
+import (
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// Explanation describes how a value, or a part of it, was interpreted.
+type Explanation struct {
+	Expression string
+
+	// Rule names the interpretation rule applied, one of the Rule... constants. Empty for empty values.
+	Rule string
+
+	Value []byte
+	Err   error
+
+	// Parts explain the sub-expressions, e.g. concatenated values, operator operands or hashed values.
+	Parts []*Explanation
+}
+
+// String yields the explanation as an indented tree, one line per (sub-)expression.
+func (e *Explanation) String() string {
+	var sb strings.Builder
+	e.writeTo(&sb, 0)
+	return sb.String()
+}
+
+func (e *Explanation) writeTo(sb *strings.Builder, depth int) {
+	sb.WriteString(strings.Repeat("  ", depth))
+	sb.WriteString(fmt.Sprintf("%q", e.Expression))
+	if len(e.Rule) > 0 {
+		sb.WriteString(" " + e.Rule)
+	}
+	if e.Err != nil {
+		// the expression is already shown
+		cause := e.Err
+		var valueErr *ValueError
+		if errors.As(cause, &valueErr) {
+			cause = valueErr.Err
+		}
+		sb.WriteString(" error: " + cause.Error())
+	} else {
+		sb.WriteString(" = 0x" + hex.EncodeToString(e.Value))
+	}
+	for _, part := range e.Parts {
+		sb.WriteString("\n")
+		part.writeTo(sb, depth+1)
+	}
+}
+
+// ExplainString interprets a value just like InterpretString,
+// and also yields a tree describing how each part of the value was interpreted.
+// Runners can show it when a check fails, to help figure out why a value is not the expected one.
+func (vi *ValueInterpreter) ExplainString(strRaw string) (*Explanation, error) {
+	root := &Explanation{}
+	vi.explainStack = []*Explanation{root}
+	defer func() {
+		vi.explainStack = nil
+	}()
+
+	result, err := vi.InterpretString(strRaw)
+	explanation := root.Parts[0]
+	explanation.Value = result
+	explanation.Err = err
+	return explanation, err
+}
+
+// explainPart records a sub-expression about to be interpreted, if explaining.
+func (vi *ValueInterpreter) explainPart(strRaw string) *Explanation {
+	if len(vi.explainStack) == 0 {
+		return nil
+	}
+	part := &Explanation{Expression: strRaw}
+	parent := vi.explainStack[len(vi.explainStack)-1]
+	parent.Parts = append(parent.Parts, part)
+	vi.explainStack = append(vi.explainStack, part)
+	return part
+}
+
+func (vi *ValueInterpreter) explainPartDone(part *Explanation, result []byte, err error) {
+	if part == nil {
+		return
+	}
+	part.Value = result
+	part.Err = err
+	vi.explainStack = vi.explainStack[:len(vi.explainStack)-1]
+}
+
+// explainRule records the rule applied to the sub-expression being interpreted, if explaining.
+func (vi *ValueInterpreter) explainRule(rule string) {
+	if len(vi.explainStack) > 0 {
+		vi.explainStack[len(vi.explainStack)-1].Rule = rule
+	}
+}
@@ -0,0 +1,53 @@
+package denalivalueinterpreter
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestExplainString(t *testing.T) {
+	vi := ValueInterpreter{}
+	explanation, err := vi.ExplainString("u32:keccak256:str:foo|u8:5")
+	require.Nil(t, err)
+	require.Equal(t, RuleConcat, explanation.Rule)
+	require.Equal(t, 2, len(explanation.Parts))
+
+	fixedWidth := explanation.Parts[0]
+	require.Equal(t, "u32:keccak256:str:foo", fixedWidth.Expression)
+	require.Equal(t, RuleFixedWidth, fixedWidth.Rule)
+	require.Equal(t, 1, len(fixedWidth.Parts))
+	hash := fixedWidth.Parts[0]
+	require.Equal(t, RuleKeccak256, hash.Rule)
+	require.Equal(t, 32, len(hash.Value))
+	require.Equal(t, hash.Value[28:], fixedWidth.Value)
+	require.Equal(t, RuleString, hash.Parts[0].Rule)
+	require.Equal(t, []byte("foo"), hash.Parts[0].Value)
+
+	require.Equal(t, RuleFixedWidth, explanation.Parts[1].Rule)
+	require.Equal(t, []byte{5}, explanation.Parts[1].Value)
+	require.Equal(t, append(append([]byte{}, hash.Value[28:]...), 5), explanation.Value)
+
+	require.Equal(t, `"u32:keccak256:str:foo|u8:5" concat = 0xde098c4d05
+  "u32:keccak256:str:foo" fixed-width = 0xde098c4d
+    "keccak256:str:foo" keccak256 = 0x41b1a0649752af1b28b3dc29a1556eee781e4a4c3a1f7f53f90fa834de098c4d
+      "str:foo" str = 0x666f6f
+  "u8:5" fixed-width = 0x05`, explanation.String())
+
+	// regular interpretation is not affected
+	result, err := vi.InterpretString("str:a")
+	require.Nil(t, err)
+	require.Equal(t, []byte("a"), result)
+	require.Nil(t, vi.explainStack)
+}
+
+func TestExplainStringError(t *testing.T) {
+	vi := ValueInterpreter{}
+	explanation, err := vi.ExplainString("str:a|left-pad:4:const:B")
+	require.NotNil(t, err)
+	require.Equal(t, err, explanation.Err)
+	require.Equal(t, `"str:a|left-pad:4:const:B" concat error: unknown constant: B
+  "str:a" str = 0x61
+  "left-pad:4:const:B" operator error: unknown constant: B
+    "const:B" const error: unknown constant: B`, explanation.String())
+}
@@ -0,0 +1,58 @@
+package denalivalueinterpreter
+
+import (
+	"fmt"
+	"strings"
+
+	oj "github.com/numbatx/gn-vm-util/test-util/orderedjson"
+)
+
+// DefaultMaxFileDepth is how deeply "file:json:" files can reference each other by default.
+const DefaultMaxFileDepth = 16
+
+// interpretFileJSON interprets a JSON file holding a value, e.g. a list of arguments.
+// Values in the file can load other files, relative to it, but cycles and very deep chains are rejected,
+// so that malformed fixtures fail right away, with the entire chain of files in the error.
+func (vi *ValueInterpreter) interpretFileJSON(path string) ([]byte, error) {
+	absolutePath := vi.FileResolver.ResolveAbsolutePath(path)
+	for _, includingPath := range vi.fileStack {
+		if includingPath == absolutePath {
+			return []byte{}, fmt.Errorf("cyclic file:json: reference: %s",
+				strings.Join(append(vi.fileStack, absolutePath), " -> "))
+		}
+	}
+	maxDepth := vi.MaxFileDepth
+	if maxDepth == 0 {
+		maxDepth = DefaultMaxFileDepth
+	}
+	if len(vi.fileStack) >= maxDepth {
+		return []byte{}, fmt.Errorf("file:json: references nested more than %d deep: %s",
+			maxDepth, strings.Join(append(vi.fileStack, absolutePath), " -> "))
+	}
+
+	contents, err := vi.FileResolver.ResolveFileValue(path)
+	if err != nil {
+		return []byte{}, err
+	}
+	vi.recordFileReference(path)
+	jobj, err := oj.ParseOrderedJSON(contents)
+	if err != nil {
+		return []byte{}, fmt.Errorf("invalid JSON in %s: %w", path, err)
+	}
+
+	// relative paths in the file are relative to it
+	includingResolver := vi.FileResolver
+	vi.FileResolver = includingResolver.Clone()
+	vi.FileResolver.SetContext(absolutePath)
+	vi.fileStack = append(vi.fileStack, absolutePath)
+	defer func() {
+		vi.FileResolver = includingResolver
+		vi.fileStack = vi.fileStack[:len(vi.fileStack)-1]
+	}()
+
+	result, err := vi.interpretSubTree(jobj, vi.interpretString)
+	if err != nil {
+		return []byte{}, fmt.Errorf("in %s: %w", path, err)
+	}
+	return result, nil
+}
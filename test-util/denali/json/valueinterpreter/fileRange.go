@@ -0,0 +1,125 @@
+package denalivalueinterpreter
+
+import (
+	"bytes"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// fileRange selects part of a file: either bytes [start, end), or lines [firstLine, lastLine], 1-based.
+type fileRange struct {
+	lines bool
+	start int
+	end   int // -1 means until the end of the file
+}
+
+func (vi *ValueInterpreter) interpretFile(fileValue string) ([]byte, error) {
+	path, selection, err := splitFileRange(fileValue)
+	if err != nil {
+		return []byte{}, err
+	}
+	fileContents, err := vi.FileResolver.ResolveFileValue(path)
+	if err != nil {
+		return []byte{}, err
+	}
+	vi.recordFileReference(path)
+	if selection == nil {
+		return fileContents, nil
+	}
+	part, err := selection.apply(fileContents)
+	if err != nil {
+		return []byte{}, fmt.Errorf("cannot select %s: %w", fileValue, err)
+	}
+	// copy, so that large files do not stay in memory because of a small slice
+	return append([]byte{}, part...), nil
+}
+
+// splitFileRange separates the optional range suffix from the file path.
+// Suffixes that do not look like ranges are considered part of the path.
+func splitFileRange(fileValue string) (string, *fileRange, error) {
+	separatorIndex := strings.LastIndex(fileValue, fileRangeSeparator)
+	if separatorIndex < 0 {
+		return fileValue, nil, nil
+	}
+	path := fileValue[:separatorIndex]
+	rangeStr := fileValue[separatorIndex+len(fileRangeSeparator):]
+
+	if strings.HasPrefix(rangeStr, "L") {
+		// line range, GitHub style: "L3-L10", or a single line "L3"
+		bounds := strings.SplitN(rangeStr, "-", 2)
+		first, err := strconv.Atoi(bounds[0][1:])
+		if err != nil {
+			return fileValue, nil, nil
+		}
+		last := first
+		if len(bounds) == 2 {
+			if !strings.HasPrefix(bounds[1], "L") {
+				return "", nil, fmt.Errorf("invalid line range: %s", rangeStr)
+			}
+			last, err = strconv.Atoi(bounds[1][1:])
+			if err != nil {
+				return "", nil, fmt.Errorf("invalid line range: %s", rangeStr)
+			}
+		}
+		if first < 1 || last < first {
+			return "", nil, fmt.Errorf("invalid line range: %s", rangeStr)
+		}
+		return path, &fileRange{lines: true, start: first, end: last}, nil
+	}
+
+	// byte range: "0:1024", or "1024:" until the end of the file
+	bounds := strings.SplitN(rangeStr, ":", 2)
+	if len(bounds) != 2 || !isDecimalDigits(bounds[0]) {
+		return fileValue, nil, nil
+	}
+	start, err := strconv.Atoi(bounds[0])
+	if err != nil {
+		return "", nil, fmt.Errorf("invalid byte range: %s", rangeStr)
+	}
+	end := -1
+	if len(bounds[1]) > 0 {
+		end, err = strconv.Atoi(bounds[1])
+		if err != nil || end < start {
+			return "", nil, fmt.Errorf("invalid byte range: %s", rangeStr)
+		}
+	}
+	return path, &fileRange{start: start, end: end}, nil
+}
+
+func (r *fileRange) apply(contents []byte) ([]byte, error) {
+	if r.lines {
+		return r.applyLines(contents)
+	}
+	end := r.end
+	if end < 0 {
+		end = len(contents)
+	}
+	if r.start > len(contents) || end > len(contents) {
+		return nil, fmt.Errorf("byte range out of bounds, file has %d bytes", len(contents))
+	}
+	return contents[r.start:end], nil
+}
+
+// applyLines yields the selected lines, including the line terminator of all but the last one.
+func (r *fileRange) applyLines(contents []byte) ([]byte, error) {
+	lineStart := 0
+	selectionStart := -1
+	for lineNr := 1; ; lineNr++ {
+		if lineNr == r.start {
+			selectionStart = lineStart
+		}
+		newlineIndex := bytes.IndexByte(contents[lineStart:], '\n')
+		lineEnd := len(contents)
+		if newlineIndex >= 0 {
+			lineEnd = lineStart + newlineIndex
+		}
+		if lineNr == r.end {
+			return bytes.TrimSuffix(contents[selectionStart:lineEnd], []byte{'\r'}), nil
+		}
+		if newlineIndex < 0 {
+			return nil, fmt.Errorf("line range out of bounds, file has %d lines", lineNr)
+		}
+		lineStart = lineEnd + 1
+	}
+}
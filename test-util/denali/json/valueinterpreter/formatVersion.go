@@ -0,0 +1,170 @@
+package denalivalueinterpreter
+
+import (
+	"strings"
+
+	mj "github.com/numbatx/gn-vm-util/test-util/denali/json/model"
+	oj "github.com/numbatx/gn-vm-util/test-util/orderedjson"
+)
+
+// Syntax that is not a prefix, reported by FormatVersionOf.
+const (
+	AddressShardSyntax = "address:NAME#SHARD"
+	FileRangeSyntax    = "file:PATH#RANGE"
+)
+
+// FormatVersionOf yields the oldest scenario format version that can interpret the expression,
+// and the syntax that requires it, e.g. "token:". Expressions in the original format yield
+// mj.FormatVersionInitial and "".
+// Only the syntax is looked at, so the constants, defines and files referenced need not be known.
+func FormatVersionOf(expression string) (mj.FormatVersion, string) {
+	usage := formatVersionUsage{version: mj.FormatVersionInitial}
+	usage.scan(expression)
+	return usage.version, usage.syntax
+}
+
+// FormatVersionOfSubTree is FormatVersionOf for all the strings of a JSON subtree, map keys included,
+// except those of mj.TextFields, and "*" storage values, which need mj.FormatVersionAnyValue. It also yields the string, or the value of the key, that requires the version,
+// nil for subtrees in the original format.
+func FormatVersionOfSubTree(obj oj.OJsonObject) (mj.FormatVersion, string, oj.OJsonObject) {
+	version, syntax, found := mj.FormatVersionInitial, "", oj.OJsonObject(nil)
+	require := func(expression string, where oj.OJsonObject) {
+		expressionVersion, expressionSyntax := FormatVersionOf(expression)
+		if expressionVersion > version {
+			version, syntax, found = expressionVersion, expressionSyntax, where
+		}
+	}
+	// "*" only matched entire storage maps, before it could match single storage values
+	walkStorage := func(storage oj.OJsonObject) {
+		storageMap, isMap := storage.(*oj.OJsonMap)
+		if !isMap {
+			return
+		}
+		for _, kvp := range storageMap.OrderedKV {
+			if str, isStr := kvp.Value.(*oj.OJsonString); isStr && str.Value == AnyValueStar &&
+				version < mj.FormatVersionAnyValue {
+				version, syntax, found = mj.FormatVersionAnyValue, "\"*\" storage value", str
+			}
+		}
+	}
+	var walk func(obj oj.OJsonObject)
+	walk = func(obj oj.OJsonObject) {
+		switch value := obj.(type) {
+		case *oj.OJsonString:
+			require(value.Value, value)
+		case *oj.OJsonList:
+			for _, item := range value.AsList() {
+				walk(item)
+			}
+		case *oj.OJsonMap:
+			for _, kvp := range value.OrderedKV {
+				if mj.TextFields[kvp.Key] {
+					continue
+				}
+				require(kvp.Key, kvp.Value)
+				if kvp.Key == "storage" {
+					walkStorage(kvp.Value)
+				}
+				walk(kvp.Value)
+			}
+		}
+	}
+	walk(obj)
+	return version, syntax, found
+}
+
+// formatVersionUsage keeps the newest syntax found so far.
+type formatVersionUsage struct {
+	version mj.FormatVersion
+	syntax  string
+}
+
+func (u *formatVersionUsage) require(version mj.FormatVersion, syntax string) {
+	if version > u.version {
+		u.version = version
+		u.syntax = syntax
+	}
+}
+
+func (u *formatVersionUsage) requirePrefixOf(strRaw string) {
+	if info := findPrefix(strRaw, prefixTable); info != nil {
+		u.require(info.FormatVersion, info.Name)
+	}
+}
+
+// scan follows the order of interpretRules, so that each part is taken for what the interpreter takes it for.
+func (u *formatVersionUsage) scan(strRaw string) {
+	switch {
+	case len(strRaw) == 0:
+	case IsAnyValue(strRaw):
+		u.requirePrefixOf(strRaw)
+	case IsMatcher(strRaw):
+		u.require(mj.FormatVersionMatchers, MatchPrefix)
+	case strings.HasPrefix(strRaw, FileJSONPrefix), strings.HasPrefix(strRaw, CodePrefix),
+		strings.HasPrefix(strRaw, ABIPrefix):
+		u.requirePrefixOf(strRaw)
+	case strings.HasPrefix(strRaw, FilePrefix):
+		_, selection, err := splitFileRange(strRaw[len(FilePrefix):])
+		if selection != nil || err != nil {
+			u.require(mj.FormatVersionFileRanges, FileRangeSyntax)
+		}
+	case strings.HasPrefix(strRaw, Keccak256Prefix):
+		u.scan(strRaw[len(Keccak256Prefix):])
+	case u.scanOperator(strRaw):
+	case strings.HasPrefix(strRaw, MultiPrefix):
+		u.requirePrefixOf(strRaw)
+		items, _ := splitMultiItems(strRaw[len(MultiPrefix):])
+		for _, item := range items {
+			u.scan(stripEnclosingParentheses(item))
+		}
+	case strings.IndexByte(strRaw, '|') >= 0:
+		for _, part := range strings.Split(strRaw, "|") {
+			u.scan(part)
+		}
+	case isStringLiteral(strRaw):
+	case strings.HasPrefix(strRaw, AddressPrefix):
+		if shardSuffixIndex(strRaw[len(AddressPrefix):]) >= 0 {
+			u.require(mj.FormatVersionAddressShard, AddressShardSyntax)
+		}
+	default:
+		if _, isFixedWidth := FixedWidth(strRaw); isFixedWidth {
+			u.scan(strRaw[strings.IndexByte(strRaw, ':')+1:])
+			return
+		}
+		// named values, tokens, base64, percentages and time
+		u.requirePrefixOf(strRaw)
+	}
+}
+
+// scanOperator scans the operands of the operators, yields false if the expression is not an operator.
+func (u *formatVersionUsage) scanOperator(strRaw string) bool {
+	for _, info := range prefixTable {
+		if info.Rule != RuleOperator || !strings.HasPrefix(strRaw, info.Name) {
+			continue
+		}
+		u.require(info.FormatVersion, info.Name)
+		argsStr := strRaw[len(info.Name):]
+		switch info.Name {
+		case BitAndPrefix, BitOrPrefix, BitXorPrefix:
+			operands, _ := splitParenthesizedOperands(argsStr)
+			for _, operand := range operands {
+				u.scan(operand)
+			}
+		default:
+			if _, operand, ok := splitOperatorArgs(argsStr, info.Arity-1); ok {
+				u.scan(operand)
+			}
+		}
+		return true
+	}
+	return false
+}
+
+func isStringLiteral(strRaw string) bool {
+	for _, strPrefix := range strPrefixes {
+		if strings.HasPrefix(strRaw, strPrefix) {
+			return true
+		}
+	}
+	return false
+}
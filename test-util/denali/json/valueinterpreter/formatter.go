@@ -0,0 +1,105 @@
+package denalivalueinterpreter
+
+import (
+	"encoding/hex"
+	"fmt"
+	"math/big"
+	"strings"
+)
+
+// ValueFormatter converts byte values back to human-readable Denali expressions.
+// It is the reverse of the ValueInterpreter: interpreting the result yields the original bytes.
+type ValueFormatter struct {
+	aliases map[string]string
+}
+
+// NewValueFormatter creates a new ValueFormatter instance, with an empty alias table.
+func NewValueFormatter() *ValueFormatter {
+	return &ValueFormatter{
+		aliases: make(map[string]string),
+	}
+}
+
+// AddAlias registers the expression that originally produced a value.
+// Aliases take precedence over the default formatting rules.
+// Only the first alias registered for a value is kept.
+func (vf *ValueFormatter) AddAlias(value []byte, expression string) {
+	if len(expression) == 0 {
+		return
+	}
+	key := string(value)
+	if _, exists := vf.aliases[key]; !exists {
+		vf.aliases[key] = expression
+	}
+}
+
+// Alias yields the expression registered for a value, if any.
+func (vf *ValueFormatter) Alias(value []byte) (string, bool) {
+	alias, found := vf.aliases[string(value)]
+	return alias, found
+}
+
+// Format yields a Denali expression for the given value. Rules, in order:
+// - registered aliases
+// - "" for empty values
+// - "address:..." for 32 byte values that look like generated addresses
+// - "str:..." for printable ASCII strings of at least 3 characters
+// - decimal numbers, for values of up to 8 bytes with no leading zeroes
+// - hex, otherwise.
+func (vf *ValueFormatter) Format(value []byte) string {
+	if alias, found := vf.Alias(value); found {
+		return alias
+	}
+	if len(value) == 0 {
+		return ""
+	}
+	if addrName, isAddress := formatAsAddress(value); isAddress {
+		return AddressPrefix + addrName
+	}
+	if len(value) >= 3 && isPrintableASCII(value) {
+		return strPrefixes[0] + string(value)
+	}
+	if len(value) <= 8 && value[0] != 0 {
+		return big.NewInt(0).SetBytes(value).String()
+	}
+	return "0x" + hex.EncodeToString(value)
+}
+
+// FormatList formats each value in a list, useful for error messages.
+func (vf *ValueFormatter) FormatList(values [][]byte) string {
+	formatted := make([]string, len(values))
+	for i, value := range values {
+		formatted[i] = vf.Format(value)
+	}
+	return "[" + strings.Join(formatted, ", ") + "]"
+}
+
+func formatAsAddress(value []byte) (string, bool) {
+	if len(value) != 32 {
+		return "", false
+	}
+	shardSuffix := ""
+	lastByte := value[len(value)-1]
+	if lastByte < 0x20 {
+		// low last byte, most likely a shard id
+		shardSuffix = fmt.Sprintf("%s%d", addrShardSeparator, lastByte)
+		value = value[:len(value)-1]
+	}
+	if value[len(value)-1] != '_' {
+		return "", false
+	}
+	name := strings.TrimRight(string(value), "_")
+	if len(name) == 0 || !isPrintableASCII([]byte(name)) || strings.Contains(name, addrShardSeparator) {
+		return "", false
+	}
+	return name + shardSuffix, true
+}
+
+func isPrintableASCII(value []byte) bool {
+	for _, b := range value {
+		if b < 0x20 || b > 0x7e || b == '|' {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,43 @@
+package denalivalueinterpreter
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func requireFormatRoundTrip(t *testing.T, vf *ValueFormatter, expected string, value []byte) {
+	formatted := vf.Format(value)
+	require.Equal(t, expected, formatted)
+
+	vi := ValueInterpreter{}
+	interpreted, err := vi.InterpretString(formatted)
+	require.Nil(t, err)
+	require.Equal(t, value, interpreted)
+}
+
+func TestFormat(t *testing.T) {
+	vf := NewValueFormatter()
+	requireFormatRoundTrip(t, vf, "", []byte{})
+	requireFormatRoundTrip(t, vf, "5", []byte{0x05})
+	requireFormatRoundTrip(t, vf, "256", []byte{0x01, 0x00})
+	requireFormatRoundTrip(t, vf, "0x0005", []byte{0x00, 0x05})
+	requireFormatRoundTrip(t, vf, "str:abc", []byte("abc"))
+	requireFormatRoundTrip(t, vf, "0x616263647c6566676869", []byte("abcd|efghi"))
+	requireFormatRoundTrip(t, vf, "address:an_address", []byte("an_address______________________"))
+	requireFormatRoundTrip(t, vf, "str:smart_contract_address________s1", []byte("smart_contract_address________s1"))
+	requireFormatRoundTrip(t, vf, "0x010203040506070809", []byte{1, 2, 3, 4, 5, 6, 7, 8, 9})
+}
+
+func TestFormatAlias(t *testing.T) {
+	vf := NewValueFormatter()
+	vf.AddAlias([]byte{0x05}, "u8:5")
+	vf.AddAlias([]byte{0x05}, "5")
+	require.Equal(t, "u8:5", vf.Format([]byte{0x05}))
+	require.Equal(t, "[u8:5, 6]", vf.FormatList([][]byte{{0x05}, {0x06}}))
+}
+
+func TestFormatAddressWithShard(t *testing.T) {
+	vf := NewValueFormatter()
+	requireFormatRoundTrip(t, vf, "address:alice#2", append([]byte("alice__________________________"), 0x02))
+}
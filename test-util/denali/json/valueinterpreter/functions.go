@@ -1,6 +1,10 @@
 package denalivalueinterpreter
 
 import (
+	"encoding/base64"
+	"fmt"
+	"strings"
+
 	"golang.org/x/crypto/sha3"
 )
 
@@ -28,3 +32,28 @@ func address(data []byte) ([]byte, error) {
 	}
 	return result[:], nil
 }
+
+func isDecimalDigits(str string) bool {
+	if len(str) == 0 {
+		return false
+	}
+	for _, c := range str {
+		if c < '0' || c > '9' {
+			return false
+		}
+	}
+	return true
+}
+
+// Decodes both standard and URL-safe base64, with or without padding.
+func decodeBase64(encoded string) ([]byte, error) {
+	encoding := base64.RawStdEncoding
+	if strings.ContainsAny(encoded, "-_") {
+		encoding = base64.RawURLEncoding
+	}
+	result, err := encoding.DecodeString(strings.TrimRight(encoded, "="))
+	if err != nil {
+		return []byte{}, fmt.Errorf("invalid base64 value: %s", encoded)
+	}
+	return result, nil
+}
@@ -1,35 +1,156 @@
 package denalivalueinterpreter
 
 import (
+	"encoding/binary"
 	"encoding/hex"
 	"errors"
 	"fmt"
 	"math/big"
+	"strconv"
 	"strings"
 
 	twos "github.com/numbatx/gn-bigint/twos-complement"
+	abi "github.com/numbatx/gn-vm-util/test-util/denali/json/abi"
 	fr "github.com/numbatx/gn-vm-util/test-util/denali/json/fileresolver"
 	oj "github.com/numbatx/gn-vm-util/test-util/orderedjson"
 )
 
-var strPrefixes = []string{"str:", "``", "''"}
+// Prefixes of the Denali value format, see SupportedPrefixes for a description of each.
+const (
+	StrPrefix         = "str:"
+	AddressPrefix     = "address:"
+	FilePrefix        = "file:"
+	FileJSONPrefix    = "file:json:"
+	CodePrefix        = "code:"
+	Keccak256Prefix   = "keccak256:"
+	ConstPrefix       = "const:"
+	DefinePrefix      = "$"
+	PresetPrefix      = "preset:"
+	TokenPrefix       = "token:"
+	Base64Prefix      = "base64:"
+	ABIPrefix         = abi.LiteralPrefix
+	LeftPadPrefix     = "left-pad:"
+	RightPadPrefix    = "right-pad:"
+	SlicePrefix       = "slice:"
+	RepeatPrefix      = "repeat:"
+	BitAndPrefix      = "bitand:"
+	BitOrPrefix       = "bitor:"
+	BitXorPrefix      = "bitxor:"
+	ShiftLeftPrefix   = "shl:"
+	ShiftRightPrefix  = "shr:"
+	PercentPrefix     = "percent:"
+	BasisPointsPrefix = "bp:"
+	TimestampPrefix   = "timestamp:"
+	DurationPrefix    = "duration:"
+
+	U64Prefix = "u64:"
+	U32Prefix = "u32:"
+	U16Prefix = "u16:"
+	U8Prefix  = "u8:"
+	I64Prefix = "i64:"
+	I32Prefix = "i32:"
+	I16Prefix = "i16:"
+	I8Prefix  = "i8:"
+)
 
-const addrPrefix = "address:"
-const filePrefix = "file:"
-const keccak256Prefix = "keccak256:"
+var strPrefixes = []string{StrPrefix, "``", "''"}
 
-const u64Prefix = "u64:"
-const u32Prefix = "u32:"
-const u16Prefix = "u16:"
-const u8Prefix = "u8:"
-const i64Prefix = "i64:"
-const i32Prefix = "i32:"
-const i16Prefix = "i16:"
-const i8Prefix = "i8:"
+const addrShardSeparator = "#"
+const fileRangeSeparator = "#"
 
 // ValueInterpreter provides context for computing Denali values.
 type ValueInterpreter struct {
 	FileResolver fr.FileResolver
+
+	// Constants holds the values that "const:NAME" expressions resolve to.
+	Constants map[string][]byte
+
+	// Defines holds the values that "$NAME" expressions resolve to, declared by the scenario "defines".
+	Defines map[string][]byte
+
+	// GasPresets holds the gas values that "preset:NAME" expressions resolve to.
+	// Presets can be set for an entire suite, or declared by a scenario.
+	GasPresets map[string]uint64
+
+	// Seed makes generated values, such as token identifier suffixes, differ between scenarios,
+	// while keeping them deterministic.
+	Seed string
+
+	// AddressLengthMode decides what "address:" does with names longer than an address.
+	// Defaults to AddressTruncate.
+	AddressLengthMode AddressLengthMode
+
+	// RecordCodeHashes causes "code:" values to record the hash of the loaded code, in CodeHashes,
+	// so that "keccak256:code:..." does not load and hash the file again.
+	RecordCodeHashes bool
+
+	// CodeHashes holds the recorded code hashes, keyed on the absolute path of the code file.
+	CodeHashes map[string][]byte
+
+	// RecordFileReferences causes the interpreter to record the files that values load, in FileReferences.
+	RecordFileReferences bool
+
+	// FileReferences holds the absolute paths of the recorded files, in the order they were loaded.
+	// Files referenced several times are listed every time.
+	FileReferences []string
+
+	// MaxFileDepth limits how deeply "file:json:" files can reference each other.
+	// Defaults to DefaultMaxFileDepth.
+	MaxFileDepth int
+
+	// PercentScale is the integer value that represents 1%, used by "percent:" and "bp:".
+	// Defaults to DefaultPercentScale, i.e. values are expressed in basis points.
+	PercentScale uint64
+
+	// ABI describes the types used in "abi:TYPE:..." literals. Nil if the scenario references no ABI.
+	ABI *abi.ABI
+
+	// Strict causes the interpreter to collect diagnostics about suspicious values
+	// and to reject values that produced error diagnostics.
+	Strict bool
+
+	// Lint causes the interpreter to collect diagnostics about suspicious values, like in strict mode,
+	// but without rejecting any values. Runners can print them as warnings.
+	Lint bool
+
+	// FlagDeprecated causes the interpreter to report deprecated syntax, e.g. the "''" string prefix,
+	// superseded by "str:": as errors in strict mode, so that such values get rejected, as warnings in lint mode.
+	FlagDeprecated bool
+
+	// Diagnostics collected in strict or lint mode, across all interpreted values.
+	Diagnostics []*Diagnostic
+
+	keccak256Cache *keccak256Cache
+
+	// fileStack holds the absolute paths of the "file:json:" files being interpreted, outermost first.
+	fileStack []string
+
+	// explainStack holds the explanations being built, innermost last. Only set by ExplainString.
+	explainStack []*Explanation
+}
+
+// SetConstant defines or redefines a named constant.
+func (vi *ValueInterpreter) SetConstant(name string, value []byte) {
+	if vi.Constants == nil {
+		vi.Constants = make(map[string][]byte)
+	}
+	vi.Constants[name] = value
+}
+
+// SetDefine defines or redefines a "$NAME" variable.
+func (vi *ValueInterpreter) SetDefine(name string, value []byte) {
+	if vi.Defines == nil {
+		vi.Defines = make(map[string][]byte)
+	}
+	vi.Defines[name] = value
+}
+
+// SetGasPreset defines or redefines a named gas preset.
+func (vi *ValueInterpreter) SetGasPreset(name string, value uint64) {
+	if vi.GasPresets == nil {
+		vi.GasPresets = make(map[string]uint64)
+	}
+	vi.GasPresets[name] = value
 }
 
 // InterpretSubTree attempts to produce a value based on a JSON subtree.
@@ -39,14 +160,18 @@ type ValueInterpreter struct {
 // Maps are evaluated by concatenating their values' representations (keys are ignored).
 // See InterpretString on how strings are being interpreted.
 func (vi *ValueInterpreter) InterpretSubTree(obj oj.OJsonObject) ([]byte, error) {
+	return vi.interpretSubTree(obj, vi.InterpretString)
+}
+
+func (vi *ValueInterpreter) interpretSubTree(obj oj.OJsonObject, interpretLeaf func(string) ([]byte, error)) ([]byte, error) {
 	if str, isStr := obj.(*oj.OJsonString); isStr {
-		return vi.InterpretString(str.Value)
+		return interpretLeaf(str.Value)
 	}
 
 	if list, isList := obj.(*oj.OJsonList); isList {
 		var concat []byte
 		for _, item := range list.AsList() {
-			value, err := vi.InterpretSubTree(item)
+			value, err := vi.interpretSubTree(item, interpretLeaf)
 			if err != nil {
 				return []byte{}, err
 			}
@@ -59,7 +184,7 @@ func (vi *ValueInterpreter) InterpretSubTree(obj oj.OJsonObject) ([]byte, error)
 		var concat []byte
 		for _, kvp := range mp.OrderedKV {
 			// keys are ignored, they do not form the value but act like documentation
-			value, err := vi.InterpretSubTree(kvp.Value)
+			value, err := vi.interpretSubTree(kvp.Value, interpretLeaf)
 			if err != nil {
 				return []byte{}, err
 			}
@@ -68,103 +193,317 @@ func (vi *ValueInterpreter) InterpretSubTree(obj oj.OJsonObject) ([]byte, error)
 		return concat, nil
 	}
 
-	return []byte{}, errors.New("cannot interpret given JSON subtree as value")
+	return []byte{}, &ValueError{
+		Rule: RuleSubTree,
+		Err:  errors.New("cannot interpret given JSON subtree as value"),
+	}
 }
 
 // InterpretString resolves a string to a byte slice according to the Denali value format.
 // Supported rules are:
 // - numbers: decimal, hex, binary, signed/unsigned
-// - fixed length numbers: "u32:5", "i8:-3", etc.
+// - fixed length numbers: "u32:5", "i8:-3", etc., also casting other values, e.g. "u32:keccak256:str:abc"
 // - ascii strings as "str:...", "“...", "”..."
 // - "true"/"false"
-// - "address:..."
-// - "file:..."
+// - "address:...", optionally ending with a shard id: "address:...#2", long names according to AddressLengthMode
+// - "file:...", optionally followed by a byte range "file:...#0:1024" or a line range "file:...#L3-L10"
+// - "code:...", like "file:", but only for WASM modules
+// - "file:json:...", a JSON file holding a value, interpreted like a JSON subtree, relative to that file
 // - "keccak256:..."
+// - "left-pad:N:...", "right-pad:N:...", "slice:START:END:...", "repeat:N:..."
+// - "bitand:(...),(...)", "bitor:(...),(...)", "bitxor:(...),(...)", "shl:N:...", "shr:N:..."
+// - "const:..."
+// - "$NAME", scenario variables, see Defines
+// - "preset:...", named gas presets
+// - "abi:TYPE:...", typed literals encoded according to the ABI, e.g. "abi:u64:5", "abi:MyStruct:{...}"
+// - "token:TICKER-abcdef", or "token:TICKER", with a generated suffix
+// - "percent:2.5", "bp:250", scaled according to PercentScale
+// - "timestamp:2024-01-01T00:00:00Z" as unix seconds, "duration:3d12h" as seconds, both encoded like numbers
+// - "base64:...", standard or URL-safe alphabet, padding optional
+// - "multi:a;b;...", items separated by ';', each with a 4 byte length prefix, like variadic results
 // - concatenation using |
+// "*", "any" and matchers ("match:regex:...", "match:range:LOW..HIGH") are only allowed in checks,
+// see InterpretCheckSubTree.
+// Values that cannot be interpreted yield a *ValueError, possibly wrapped.
+// In strict mode, all error diagnostics are returned together, as a *DiagnosticsError.
 func (vi *ValueInterpreter) InterpretString(strRaw string) ([]byte, error) {
+	if !vi.Strict {
+		result, err := vi.interpretString(strRaw)
+		return result, withExpression(err, strRaw)
+	}
+
+	diagnosticsBefore := len(vi.Diagnostics)
+	result, err := vi.interpretString(strRaw)
+	if err != nil {
+		return result, withExpression(err, strRaw)
+	}
+	errs := errorDiagnostics(vi.Diagnostics[diagnosticsBefore:])
+	if len(errs) > 0 {
+		return []byte{}, &DiagnosticsError{Diagnostics: errs}
+	}
+	return result, nil
+}
+
+func (vi *ValueInterpreter) interpretString(strRaw string) ([]byte, error) {
+	part := vi.explainPart(strRaw)
+	result, err := vi.interpretRules(strRaw)
+	vi.explainPartDone(part, result, err)
+	return result, err
+}
+
+func (vi *ValueInterpreter) interpretRules(strRaw string) ([]byte, error) {
 	if len(strRaw) == 0 {
 		return []byte{}, nil
 	}
 
+	if IsAnyValue(strRaw) {
+		vi.explainRule(RuleAny)
+		return []byte{}, newValueError(RuleAny, strRaw, ErrAnyValue)
+	}
+
+	if IsMatcher(strRaw) {
+		vi.explainRule(RuleMatch)
+		return []byte{}, newValueError(RuleMatch, strRaw, ErrMatcher)
+	}
+
+	// JSON files holding values, interpreted like subtrees
+	if strings.HasPrefix(strRaw, FileJSONPrefix) {
+		vi.explainRule(RuleFile)
+		if vi.FileResolver == nil {
+			return []byte{}, newValueError(RuleFile, strRaw, errors.New("parser FileResolver not provided"))
+		}
+		result, err := vi.interpretFileJSON(strRaw[len(FileJSONPrefix):])
+		return result, newValueError(RuleFile, strRaw, err)
+	}
+
 	// file contents
 	// TODO: make this part of a proper parser
-	if strings.HasPrefix(strRaw, filePrefix) {
+	if strings.HasPrefix(strRaw, FilePrefix) {
+		vi.explainRule(RuleFile)
 		if vi.FileResolver == nil {
-			return []byte{}, errors.New("parser FileResolver not provided")
+			return []byte{}, newValueError(RuleFile, strRaw, errors.New("parser FileResolver not provided"))
 		}
-		fileContents, err := vi.FileResolver.ResolveFileValue(strRaw[len(filePrefix):])
-		if err != nil {
-			return []byte{}, err
-		}
-		return fileContents, nil
+		result, err := vi.interpretFile(strRaw[len(FilePrefix):])
+		return result, newValueError(RuleFile, strRaw, err)
+	}
+
+	// contract code, checked to be WASM
+	if strings.HasPrefix(strRaw, CodePrefix) {
+		vi.explainRule(RuleCode)
+		result, err := vi.interpretCode(strRaw[len(CodePrefix):])
+		return result, newValueError(RuleCode, strRaw, err)
 	}
 
 	// keccak256
 	// TODO: make this part of a proper parser
-	if strings.HasPrefix(strRaw, keccak256Prefix) {
-		arg, err := vi.InterpretString(strRaw[len(keccak256Prefix):])
+	if strings.HasPrefix(strRaw, Keccak256Prefix) {
+		vi.explainRule(RuleKeccak256)
+		if strings.HasPrefix(strRaw[len(Keccak256Prefix):], CodePrefix) {
+			hash, recorded := vi.recordedCodeHash(strRaw[len(Keccak256Prefix)+len(CodePrefix):])
+			if recorded {
+				return hash, nil
+			}
+		}
+		arg, err := vi.interpretString(strRaw[len(Keccak256Prefix):])
 		if err != nil {
-			return []byte{}, fmt.Errorf("cannot parse keccak256 argument: %w", err)
+			return []byte{}, newValueError(RuleKeccak256, strRaw, fmt.Errorf("cannot parse keccak256 argument: %w", err))
 		}
-		hash, err := keccak256(arg)
+		hash, err := vi.cachedKeccak256(arg)
 		if err != nil {
-			return []byte{}, fmt.Errorf("error computing keccak256: %w", err)
+			return []byte{}, newValueError(RuleKeccak256, strRaw, fmt.Errorf("error computing keccak256: %w", err))
 		}
 		return hash, nil
 	}
 
+	// typed literals, the literal is the entire rest of the expression
+	if strings.HasPrefix(strRaw, ABIPrefix) {
+		vi.explainRule(RuleABI)
+		result, err := vi.interpretABILiteral(strRaw)
+		return result, newValueError(RuleABI, strRaw, err)
+	}
+
+	// operators, they apply to the entire rest of the expression, like keccak256
+	parsed, result, err := vi.tryInterpretOperator(strRaw)
+	if parsed || err != nil {
+		vi.explainRule(RuleOperator)
+	}
+	if err != nil {
+		return []byte{}, newValueError(RuleOperator, strRaw, err)
+	}
+	if parsed {
+		return result, nil
+	}
+
+	// multi-value lists, the items are the entire rest of the expression
+	if strings.HasPrefix(strRaw, MultiPrefix) {
+		vi.explainRule(RuleMulti)
+		result, err := vi.interpretMulti(strRaw[len(MultiPrefix):])
+		return result, newValueError(RuleMulti, strRaw, err)
+	}
+
 	// concatenate values of different formats
 	// TODO: make this part of a proper parser
-	parts := strings.Split(strRaw, "|")
-	if len(parts) > 1 {
-		concat := make([]byte, 0)
-		for _, part := range parts {
-			eval, err := vi.InterpretString(part)
+	// parts are found by scanning, instead of strings.Split, to avoid allocating on this hot path
+	if strings.IndexByte(strRaw, '|') >= 0 {
+		vi.explainRule(RuleConcat)
+		concat := make([]byte, 0, len(strRaw))
+		rest := strRaw
+		for {
+			part := rest
+			separatorIndex := strings.IndexByte(rest, '|')
+			if separatorIndex >= 0 {
+				part = rest[:separatorIndex]
+			}
+			if len(part) == 0 {
+				vi.AddDiagnostic(SeverityWarning, strRaw, "empty part in concatenation, probably a stray |")
+			}
+			eval, err := vi.interpretString(part)
 			if err != nil {
 				return []byte{}, err
 			}
 			concat = append(concat, eval...)
+			if separatorIndex < 0 {
+				return concat, nil
+			}
+			rest = rest[separatorIndex+1:]
 		}
-		return concat, nil
 	}
 
 	if strRaw == "false" {
+		vi.explainRule(RuleBool)
 		return []byte{}, nil
 	}
 
 	if strRaw == "true" {
+		vi.explainRule(RuleBool)
 		return []byte{0x01}, nil
 	}
 
 	// allow ascii strings, for readability
 	for _, strPrefix := range strPrefixes {
 		if strings.HasPrefix(strRaw, strPrefix) {
+			vi.explainRule(RuleString)
+			if strPrefix != StrPrefix {
+				vi.flagDeprecated(strRaw, strPrefix, StrPrefix)
+			}
 			str := strRaw[len(strPrefix):]
+			if strings.HasPrefix(str, "0x") && isHexDigits(str[2:]) {
+				vi.AddDiagnostic(SeverityWarning, strRaw,
+					"string looks like a hex number, its characters get encoded, not the number")
+			}
 			return []byte(str), nil
 		}
 	}
 
-	// address
-	if strings.HasPrefix(strRaw, addrPrefix) {
-		addrName := strRaw[len(addrPrefix):]
-		return address([]byte(addrName))
+	// named constants
+	if strings.HasPrefix(strRaw, ConstPrefix) {
+		vi.explainRule(RuleConst)
+		constName := strRaw[len(ConstPrefix):]
+		value, found := vi.Constants[constName]
+		if !found {
+			return []byte{}, newValueError(RuleConst, strRaw, fmt.Errorf("unknown constant: %s", constName))
+		}
+		return append([]byte{}, value...), nil
+	}
+
+	// scenario variables
+	if strings.HasPrefix(strRaw, DefinePrefix) {
+		vi.explainRule(RuleDefine)
+		defineName := strRaw[len(DefinePrefix):]
+		value, found := vi.Defines[defineName]
+		if !found {
+			return []byte{}, newValueError(RuleDefine, strRaw, fmt.Errorf("unknown define: %s", defineName))
+		}
+		return append([]byte{}, value...), nil
+	}
+
+	// named gas presets
+	if strings.HasPrefix(strRaw, PresetPrefix) {
+		vi.explainRule(RulePreset)
+		presetName := strRaw[len(PresetPrefix):]
+		value, found := vi.GasPresets[presetName]
+		if !found {
+			return []byte{}, newValueError(RulePreset, strRaw, fmt.Errorf("unknown gas preset: %s", presetName))
+		}
+		return big.NewInt(0).SetUint64(value).Bytes(), nil
+	}
+
+	// token identifiers
+	if strings.HasPrefix(strRaw, TokenPrefix) {
+		vi.explainRule(RuleToken)
+		result, err := vi.tokenIdentifier(strRaw[len(TokenPrefix):])
+		return result, newValueError(RuleToken, strRaw, err)
+	}
+
+	// base64, standard or URL-safe
+	if strings.HasPrefix(strRaw, Base64Prefix) {
+		vi.explainRule(RuleBase64)
+		result, err := decodeBase64(strRaw[len(Base64Prefix):])
+		return result, newValueError(RuleBase64, strRaw, err)
+	}
+
+	// percentages and basis points
+	if strings.HasPrefix(strRaw, PercentPrefix) {
+		vi.explainRule(RulePercent)
+		result, err := vi.scaledPercentage(strRaw[len(PercentPrefix):], 1)
+		return result, newValueError(RulePercent, strRaw, err)
+	}
+	if strings.HasPrefix(strRaw, BasisPointsPrefix) {
+		vi.explainRule(RulePercent)
+		result, err := vi.scaledPercentage(strRaw[len(BasisPointsPrefix):], 100)
+		return result, newValueError(RulePercent, strRaw, err)
+	}
+
+	// points in time and time spans, in seconds
+	if strings.HasPrefix(strRaw, TimestampPrefix) {
+		vi.explainRule(RuleTime)
+		result, err := timestampSeconds(strRaw[len(TimestampPrefix):])
+		return result, newValueError(RuleTime, strRaw, err)
+	}
+	if strings.HasPrefix(strRaw, DurationPrefix) {
+		vi.explainRule(RuleTime)
+		result, err := durationSeconds(strRaw[len(DurationPrefix):])
+		return result, newValueError(RuleTime, strRaw, err)
+	}
+
+	// address, optionally with a shard suffix: "address:name#shard"
+	if strings.HasPrefix(strRaw, AddressPrefix) {
+		vi.explainRule(RuleAddress)
+		result, err := vi.interpretAddress(strRaw[len(AddressPrefix):])
+		return result, newValueError(RuleAddress, strRaw, err)
 	}
 
 	// fixed width numbers
-	parsed, result, err := vi.tryInterpretFixedWidth(strRaw)
+	parsed, result, err = vi.tryInterpretFixedWidth(strRaw)
+	if parsed || err != nil {
+		vi.explainRule(RuleFixedWidth)
+	}
 	if err != nil {
-		return nil, err
+		return []byte{}, newValueError(RuleFixedWidth, strRaw, err)
 	}
 	if parsed {
 		return result, nil
 	}
 
+	vi.explainRule(RuleNumber)
+	if prefix, isPrefix := looksLikePrefix(strRaw); isPrefix {
+		vi.AddDiagnostic(SeverityError, strRaw, fmt.Sprintf("unknown prefix %s", prefix))
+		if vi.Strict {
+			return []byte{}, nil
+		}
+	}
+
 	// general numbers, arbitrary length
-	return vi.interpretNumber(strRaw, 0)
+	result, err = vi.interpretNumber(strRaw, 0)
+	return result, newValueError(RuleNumber, strRaw, err)
 }
 
 // targetWidth = 0 means minimum length that can contain the result
 func (vi *ValueInterpreter) interpretNumber(strRaw string, targetWidth int) ([]byte, error) {
+	if len(strRaw) == 0 {
+		return []byte{}, errors.New("could not parse number: empty value")
+	}
+
 	// signed numbers
 	if strRaw[0] == '-' || strRaw[0] == '+' {
 		numberBytes, err := vi.interpretUnsignedNumber(strRaw[1:])
@@ -190,44 +529,102 @@ func (vi *ValueInterpreter) interpretNumber(strRaw string, targetWidth int) ([]b
 	return vi.interpretUnsignedNumberFixedWidth(strRaw, targetWidth)
 }
 
+// interpretUnsignedNumber handles the base and digit grouping, the sign is handled by the caller.
+// Base prefixes: "0x" for hex, "0b" for binary, decimal otherwise.
+// Digits can be grouped using any of the digitSeparators, in any base.
+// Hex values keep their leading zeroes, the others yield the minimal representation.
 func (vi *ValueInterpreter) interpretUnsignedNumber(strRaw string) ([]byte, error) {
-	str := strings.ReplaceAll(strRaw, "_", "") // allow underscores, to group digits
-	str = strings.ReplaceAll(str, ",", "")     // also allow commas to group digits
+	base, baseName, digits := splitNumberBase(strRaw)
+	if len(strRaw) == 0 || strRaw[0] < '0' || strRaw[0] > '9' {
+		// separators only go between digits, not before the number or after the sign
+		return []byte{}, fmt.Errorf("could not parse %s value: %s", baseName, strRaw)
+	}
+	digits = removeDigitSeparators(digits)
 
-	// hex, the usual representation
-	if strings.HasPrefix(strRaw, "0x") || strings.HasPrefix(strRaw, "0X") {
-		str := strRaw[2:]
-		if len(str)%2 == 1 {
-			str = "0" + str
+	if base == 16 {
+		if len(digits)%2 == 1 {
+			vi.AddDiagnostic(SeverityWarning, strRaw, "hex value has an odd number of digits")
+			digits = "0" + digits
+		}
+		result, err := hex.DecodeString(digits)
+		if err != nil {
+			return []byte{}, fmt.Errorf("could not parse %s value: %s", baseName, strRaw)
 		}
-		return hex.DecodeString(str)
+		return result, nil
 	}
 
-	// binary representation
-	if strings.HasPrefix(strRaw, "0b") || strings.HasPrefix(strRaw, "0B") {
-		result := new(big.Int)
-		var parseOk bool
-		result, parseOk = result.SetString(str[2:], 2)
-		if !parseOk {
-			return []byte{}, fmt.Errorf("could not parse binary value: %s", strRaw)
+	if len(digits) == 0 || digits[0] == '-' || digits[0] == '+' {
+		// big.Int would accept a second sign
+		return []byte{}, fmt.Errorf("could not parse %s value: %s", baseName, strRaw)
+	}
+	if len(digits) <= maxUint64Digits(base) {
+		// fast path, most numbers in scenarios are small
+		value, err := strconv.ParseUint(digits, base, 64)
+		if err != nil {
+			return []byte{}, fmt.Errorf("could not parse %s value: %s", baseName, strRaw)
 		}
+		return minimalUint64Bytes(value), nil
+	}
+	result, parseOk := new(big.Int).SetString(digits, base)
+	if !parseOk {
+		return []byte{}, fmt.Errorf("could not parse %s value: %s", baseName, strRaw)
+	}
+	return result.Bytes(), nil
+}
 
-		return result.Bytes(), nil
+// digitSeparators group digits for readability, whatever the locale: "1_000", "1,000", "1'000", "1 000".
+// The decimal separator is never accepted, since "1.000" means 1 in some locales and 1000 in others.
+const digitSeparators = "_,' "
+
+// maxUint64Digits yields the number of digits that always fit in a uint64, in base 10 or 2.
+func maxUint64Digits(base int) int {
+	if base == 2 {
+		return 64
 	}
+	return 19
+}
 
-	// default: parse as BigInt, base 10
-	result := new(big.Int)
-	var parseOk bool
-	result, parseOk = result.SetString(str, 10)
-	if !parseOk {
-		return []byte{}, fmt.Errorf("could not parse base 10 value: %s", strRaw)
+// minimalUint64Bytes yields the big endian representation, without leading zeroes, like big.Int.Bytes.
+func minimalUint64Bytes(value uint64) []byte {
+	var buffer [8]byte
+	binary.BigEndian.PutUint64(buffer[:], value)
+	start := 0
+	for start < len(buffer) && buffer[start] == 0 {
+		start++
 	}
+	return append([]byte{}, buffer[start:]...)
+}
 
-	return result.Bytes(), nil
+func removeDigitSeparators(digits string) string {
+	return strings.Map(func(r rune) rune {
+		if strings.ContainsRune(digitSeparators, r) {
+			return -1
+		}
+		return r
+	}, digits)
+}
+
+func splitNumberBase(strRaw string) (int, string, string) {
+	if strings.HasPrefix(strRaw, "0x") || strings.HasPrefix(strRaw, "0X") {
+		return 16, "hex", strRaw[2:]
+	}
+	if strings.HasPrefix(strRaw, "0b") || strings.HasPrefix(strRaw, "0B") {
+		return 2, "binary", strRaw[2:]
+	}
+	return 10, "base 10", strRaw
 }
 
 func (vi *ValueInterpreter) interpretUnsignedNumberFixedWidth(strRaw string, targetWidth int) ([]byte, error) {
-	numberBytes, err := vi.interpretUnsignedNumber(strRaw)
+	digits := strRaw
+	switch {
+	case strings.HasPrefix(strRaw, "+"):
+		digits = strRaw[1:]
+	case strings.HasPrefix(strRaw, "-"):
+		// tolerated outside of strict mode, the sign is ignored
+		vi.AddDiagnostic(SeverityError, strRaw, "negative value in unsigned fixed width number")
+		digits = strRaw[1:]
+	}
+	numberBytes, err := vi.interpretUnsignedNumber(digits)
 	if err != nil {
 		return []byte{}, err
 	}
@@ -241,40 +638,95 @@ func (vi *ValueInterpreter) interpretUnsignedNumberFixedWidth(strRaw string, tar
 	return twos.CopyAlignRight(numberBytes, targetWidth), nil
 }
 
+var fixedWidthPrefixes = []struct {
+	prefix string
+	width  int
+	signed bool
+}{
+	{U64Prefix, 8, false},
+	{U32Prefix, 4, false},
+	{U16Prefix, 2, false},
+	{U8Prefix, 1, false},
+	{I64Prefix, 8, true},
+	{I32Prefix, 4, true},
+	{I16Prefix, 2, true},
+	{I8Prefix, 1, true},
+}
+
+// FixedWidth yields the width of values written with a fixed width prefix, e.g. 4 for "u32:5".
+func FixedWidth(strRaw string) (int, bool) {
+	for _, fixedWidth := range fixedWidthPrefixes {
+		if strings.HasPrefix(strRaw, fixedWidth.prefix) {
+			return fixedWidth.width, true
+		}
+	}
+	return 0, false
+}
+
 func (vi *ValueInterpreter) tryInterpretFixedWidth(strRaw string) (bool, []byte, error) {
-	if strings.HasPrefix(strRaw, u64Prefix) {
-		r, err := vi.interpretUnsignedNumberFixedWidth(strRaw[len(u64Prefix):], 8)
-		return true, r, err
+	for _, fixedWidth := range fixedWidthPrefixes {
+		if strings.HasPrefix(strRaw, fixedWidth.prefix) {
+			r, err := vi.interpretFixedWidth(strRaw[len(fixedWidth.prefix):], fixedWidth.width, fixedWidth.signed)
+			return true, r, err
+		}
 	}
-	if strings.HasPrefix(strRaw, u32Prefix) {
-		r, err := vi.interpretUnsignedNumberFixedWidth(strRaw[len(u32Prefix):], 4)
-		return true, r, err
+	return false, []byte{}, nil
+}
+
+// interpretFixedWidth handles the operand of the fixed width prefixes.
+// Number literals must fit in the given width.
+// Any other expression, e.g. "u32:keccak256:str:abc", is interpreted first,
+// then padded or truncated to the width, keeping the low-order bytes, like a cast.
+func (vi *ValueInterpreter) interpretFixedWidth(strRaw string, targetWidth int, signed bool) ([]byte, error) {
+	if isNumberLiteral(strRaw) {
+		if signed {
+			return vi.interpretNumber(strRaw, targetWidth)
+		}
+		return vi.interpretUnsignedNumberFixedWidth(strRaw, targetWidth)
 	}
-	if strings.HasPrefix(strRaw, u16Prefix) {
-		r, err := vi.interpretUnsignedNumberFixedWidth(strRaw[len(u16Prefix):], 2)
-		return true, r, err
+
+	value, err := vi.interpretString(strRaw)
+	if err != nil {
+		return []byte{}, err
 	}
-	if strings.HasPrefix(strRaw, u8Prefix) {
-		r, err := vi.interpretUnsignedNumberFixedWidth(strRaw[len(u8Prefix):], 1)
-		return true, r, err
+	if len(value) > targetWidth {
+		if !allZero(value[:len(value)-targetWidth]) {
+			vi.AddDiagnostic(SeverityWarning, strRaw,
+				fmt.Sprintf("value truncated to its last %d bytes", targetWidth))
+		}
+		return append([]byte{}, value[len(value)-targetWidth:]...), nil
 	}
-
-	if strings.HasPrefix(strRaw, i64Prefix) {
-		r, err := vi.interpretNumber(strRaw[len(i64Prefix):], 8)
-		return true, r, err
+	if signed {
+		return twos.ToBytesOfLength(twos.FromBytes(value), targetWidth)
 	}
-	if strings.HasPrefix(strRaw, i32Prefix) {
-		r, err := vi.interpretNumber(strRaw[len(i32Prefix):], 4)
-		return true, r, err
+	return twos.CopyAlignRight(value, targetWidth), nil
+}
+
+func isNumberLiteral(strRaw string) bool {
+	if len(strRaw) == 0 {
+		return true // rejected as an empty number, by interpretNumber or interpretUnsignedNumber
 	}
-	if strings.HasPrefix(strRaw, i16Prefix) {
-		r, err := vi.interpretNumber(strRaw[len(i16Prefix):], 2)
-		return true, r, err
+	first := strRaw[0]
+	return (first >= '0' && first <= '9') || first == '-' || first == '+'
+}
+
+func isHexDigits(str string) bool {
+	if len(str) == 0 {
+		return false
 	}
-	if strings.HasPrefix(strRaw, i8Prefix) {
-		r, err := vi.interpretNumber(strRaw[len(i8Prefix):], 1)
-		return true, r, err
+	for _, c := range str {
+		if !((c >= '0' && c <= '9') || (c >= 'a' && c <= 'f') || (c >= 'A' && c <= 'F')) {
+			return false
+		}
 	}
+	return true
+}
 
-	return false, []byte{}, nil
+func allZero(data []byte) bool {
+	for _, b := range data {
+		if b != 0 {
+			return false
+		}
+	}
+	return true
 }
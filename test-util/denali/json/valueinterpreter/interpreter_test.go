@@ -2,9 +2,17 @@ package denalivalueinterpreter
 
 import (
 	"encoding/hex"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"math/big"
+	"os"
+	"path/filepath"
+	"strings"
 	"testing"
 
 	fr "github.com/numbatx/gn-vm-util/test-util/denali/json/fileresolver"
+	mj "github.com/numbatx/gn-vm-util/test-util/denali/json/model"
 	oj "github.com/numbatx/gn-vm-util/test-util/orderedjson"
 	"github.com/stretchr/testify/require"
 )
@@ -163,6 +171,99 @@ func TestSignedNumber(t *testing.T) {
 	require.Equal(t, []byte{0xfb}, result)
 }
 
+func TestNumberSignBaseSeparators(t *testing.T) {
+	vi := ValueInterpreter{}
+	result, err := vi.InterpretString("-0b1010")
+	require.Nil(t, err)
+	require.Equal(t, []byte{0xf6}, result)
+
+	result, err = vi.InterpretString("+0B1010_1010")
+	require.Nil(t, err)
+	require.Equal(t, []byte{0x00, 0xaa}, result)
+
+	result, err = vi.InterpretString("0x12_34")
+	require.Nil(t, err)
+	require.Equal(t, []byte{0x12, 0x34}, result)
+
+	result, err = vi.InterpretString("-0x12_34")
+	require.Nil(t, err)
+	require.Equal(t, []byte{0xed, 0xcc}, result)
+
+	result, err = vi.InterpretString("0X_ff")
+	require.Nil(t, err)
+	require.Equal(t, []byte{0xff}, result)
+
+	result, err = vi.InterpretString("-1,000")
+	require.Nil(t, err)
+	require.Equal(t, []byte{0xfc, 0x18}, result)
+
+	result, err = vi.InterpretString("i16:-0b1")
+	require.Nil(t, err)
+	require.Equal(t, []byte{0xff, 0xff}, result)
+
+	result, err = vi.InterpretString("u16:0x0_1")
+	require.Nil(t, err)
+	require.Equal(t, []byte{0x00, 0x01}, result)
+
+	_, err = vi.InterpretString("--5")
+	require.NotNil(t, err)
+	_, err = vi.InterpretString("+-5")
+	require.NotNil(t, err)
+	_, err = vi.InterpretString("0x-5")
+	require.NotNil(t, err)
+	_, err = vi.InterpretString("0b+1")
+	require.NotNil(t, err)
+	_, err = vi.InterpretString("0b12")
+	require.NotNil(t, err)
+	_, err = vi.InterpretString("0x1g")
+	require.NotNil(t, err)
+}
+
+func TestNumberSeparatorsAllBasesAndSigns(t *testing.T) {
+	// all representations of 1000, with a placeholder where the separator goes
+	digitGroups := []string{
+		"1#000",
+		"0x03#e8",
+		"0X03#E8",
+		"0b11#1110#1000",
+		"0B11#1110#1000",
+	}
+	vi := ValueInterpreter{}
+	for _, digits := range digitGroups {
+		for _, separator := range []string{"", "_", ",", "'", " "} {
+			number := strings.ReplaceAll(digits, "#", separator)
+			for _, sign := range []string{"", "+", "-"} {
+				expected := []byte{0x03, 0xe8}
+				expectedFixedWidth := []byte{0x00, 0x00, 0x03, 0xe8}
+				if sign == "-" {
+					expected = []byte{0xfc, 0x18}
+					expectedFixedWidth = []byte{0xff, 0xff, 0xfc, 0x18}
+				}
+
+				result, err := vi.InterpretString(sign + number)
+				require.Nil(t, err, sign+number)
+				require.Equal(t, expected, result, sign+number)
+
+				result, err = vi.InterpretString("i32:" + sign + number)
+				require.Nil(t, err, "i32:"+sign+number)
+				require.Equal(t, expectedFixedWidth, result, "i32:"+sign+number)
+
+				if sign != "-" {
+					result, err = vi.InterpretString("u32:" + sign + number)
+					require.Nil(t, err, "u32:"+sign+number)
+					require.Equal(t, expectedFixedWidth, result, "u32:"+sign+number)
+				}
+			}
+		}
+	}
+
+	// separators cannot split the base prefix or the sign, and there is no decimal separator
+	for _, invalid := range []string{"0_x03e8", "0'b1", "- 1000", "_-1000", "1.000", "0x03.e8"} {
+		_, err := vi.InterpretString(invalid)
+		require.NotNil(t, err, invalid)
+	}
+}
+
 func TestUnsignedFixedWidth(t *testing.T) {
 	vi := ValueInterpreter{}
 	result, err := vi.InterpretString("u8:0")
@@ -267,6 +368,62 @@ func TestSignedFixedWidth(t *testing.T) {
 	require.Equal(t, []byte{0xfb}, result)
 }
 
+func TestFixedWidthEmptyOperand(t *testing.T) {
+	vi := ValueInterpreter{}
+	for _, prefix := range []string{"u64:", "u32:", "u16:", "u8:", "i64:", "i32:", "i16:", "i8:"} {
+		require.NotPanics(t, func() {
+			_, err := vi.InterpretString(prefix)
+			require.NotNil(t, err, prefix)
+		}, prefix)
+	}
+}
+
+func TestFixedWidthExpression(t *testing.T) {
+	vi := ValueInterpreter{}
+	hash, _ := keccak256([]byte("foo"))
+	result, err := vi.InterpretString("u32:keccak256:str:foo")
+	require.Nil(t, err)
+	require.Equal(t, hash[28:], result)
+
+	result, err = vi.InterpretString("i8:keccak256:str:foo")
+	require.Nil(t, err)
+	require.Equal(t, hash[31:], result)
+
+	vi.SetConstant("SMALL", []byte{0x01, 0x02})
+	vi.SetConstant("NEGATIVE", []byte{0xff})
+	result, err = vi.InterpretString("u64:const:SMALL")
+	require.Nil(t, err)
+	require.Equal(t, []byte{0, 0, 0, 0, 0, 0, 0x01, 0x02}, result)
+
+	result, err = vi.InterpretString("i16:const:NEGATIVE")
+	require.Nil(t, err)
+	require.Equal(t, []byte{0xff, 0xff}, result)
+
+	result, err = vi.InterpretString("u16:const:NEGATIVE")
+	require.Nil(t, err)
+	require.Equal(t, []byte{0x00, 0xff}, result)
+
+	result, err = vi.InterpretString("u8:str:")
+	require.Nil(t, err)
+	require.Equal(t, []byte{0x00}, result)
+
+	result, err = vi.InterpretString("u16:str:A|u8:5")
+	require.Nil(t, err)
+	require.Equal(t, []byte{0x00, 'A', 0x05}, result)
+
+	// literals still have to fit
+	_, err = vi.InterpretString("u8:256")
+	require.NotNil(t, err)
+
+	_, err = vi.InterpretString("u32:const:UNKNOWN")
+	require.NotNil(t, err)
+
+	vi.Strict = true
+	_, err = vi.InterpretString("u32:keccak256:str:foo")
+	require.Nil(t, err)
+	require.Equal(t, SeverityWarning, vi.Diagnostics[len(vi.Diagnostics)-1].Severity)
+}
+
 func TestConcat(t *testing.T) {
 	vi := ValueInterpreter{}
 	result, err := vi.InterpretString("0x01|5")
@@ -388,6 +545,148 @@ func TestFile(t *testing.T) {
 	require.Equal(t, []byte("hello!"), result)
 }
 
+func TestCode(t *testing.T) {
+	dir := t.TempDir()
+	wasm := []byte("\x00asm\x01\x00\x00\x00")
+	require.Nil(t, ioutil.WriteFile(filepath.Join(dir, "contract.wasm"), wasm, 0644))
+	require.Nil(t, ioutil.WriteFile(filepath.Join(dir, "contract.txt"), []byte("hello!"), 0644))
+	fileResolver := fr.NewDefaultFileResolver()
+	fileResolver.SetContext(filepath.Join(dir, "test.scen.json"))
+
+	vi := ValueInterpreter{FileResolver: fileResolver}
+	result, err := vi.InterpretString("code:contract.wasm")
+	require.Nil(t, err)
+	require.Equal(t, wasm, result)
+	require.Nil(t, vi.CodeHashes)
+
+	_, err = vi.InterpretString("code:contract.txt")
+	require.NotNil(t, err)
+	_, err = vi.InterpretString("code:missing.wasm")
+	require.NotNil(t, err)
+	_, err = vi.InterpretString("code:")
+	require.NotNil(t, err)
+	_, err = (&ValueInterpreter{}).InterpretString("code:contract.wasm")
+	require.NotNil(t, err)
+
+	vi = ValueInterpreter{FileResolver: fileResolver, RecordCodeHashes: true}
+	_, err = vi.InterpretString("code:contract.wasm")
+	require.Nil(t, err)
+	expectedHash, _ := keccak256(wasm)
+	require.Equal(t, expectedHash, vi.CodeHashes[filepath.Join(dir, "contract.wasm")])
+
+	// the recorded hash is reused, the file is not read again
+	require.Nil(t, ioutil.WriteFile(filepath.Join(dir, "contract.wasm"), []byte("\x00asm\x02"), 0644))
+	result, err = vi.InterpretString("keccak256:code:contract.wasm")
+	require.Nil(t, err)
+	require.Equal(t, expectedHash, result)
+
+	// without a recorded hash, the code is loaded and hashed
+	vi = ValueInterpreter{FileResolver: fileResolver}
+	result, err = vi.InterpretString("keccak256:code:contract.wasm")
+	require.Nil(t, err)
+	newHash, _ := keccak256([]byte("\x00asm\x02"))
+	require.Equal(t, newHash, result)
+}
+
+func TestFileJSON(t *testing.T) {
+	dir := t.TempDir()
+	writeFile := func(name string, contents string) {
+		path := filepath.Join(dir, filepath.FromSlash(name))
+		require.Nil(t, os.MkdirAll(filepath.Dir(path), 0755))
+		require.Nil(t, ioutil.WriteFile(path, []byte(contents), 0644))
+	}
+	writeFile("args.json", `["u8:1", {"nested": "file:json:sub/more.json"}]`)
+	writeFile("sub/more.json", `["str:a", "file:raw.txt"]`)
+	writeFile("sub/raw.txt", "b")
+	writeFile("cycle-a.json", `["u8:1", "file:json:cycle-b.json"]`)
+	writeFile("cycle-b.json", `"file:json:cycle-a.json"`)
+	writeFile("self.json", `"file:json:self.json"`)
+	writeFile("invalid.json", `[`)
+	for i := 0; i < 5; i++ {
+		writeFile(fmt.Sprintf("chain%d.json", i), fmt.Sprintf(`"file:json:chain%d.json"`, i+1))
+	}
+	writeFile("chain5.json", `"u8:5"`)
+
+	fileResolver := fr.NewDefaultFileResolver()
+	fileResolver.SetContext(filepath.Join(dir, "test.scen.json"))
+	vi := ValueInterpreter{FileResolver: fileResolver}
+
+	// relative paths are relative to the file that contains them
+	result, err := vi.InterpretString("file:json:args.json")
+	require.Nil(t, err)
+	require.Equal(t, []byte("\x01ab"), result)
+	require.Empty(t, vi.fileStack)
+	require.Equal(t, fileResolver, vi.FileResolver)
+
+	result, err = vi.InterpretString("file:json:chain0.json")
+	require.Nil(t, err)
+	require.Equal(t, []byte{5}, result)
+
+	_, err = vi.InterpretString("file:json:cycle-a.json")
+	require.NotNil(t, err)
+	require.Contains(t, err.Error(), "cyclic")
+	require.Contains(t, err.Error(), "cycle-a.json -> "+filepath.Join(dir, "cycle-b.json")+" -> ")
+	require.Empty(t, vi.fileStack)
+
+	_, err = vi.InterpretString("file:json:self.json")
+	require.NotNil(t, err)
+	require.Contains(t, err.Error(), "cyclic")
+
+	_, err = vi.InterpretString("file:json:invalid.json")
+	require.NotNil(t, err)
+	_, err = vi.InterpretString("file:json:missing.json")
+	require.NotNil(t, err)
+
+	vi.MaxFileDepth = 3
+	_, err = vi.InterpretString("file:json:chain0.json")
+	require.NotNil(t, err)
+	require.Contains(t, err.Error(), "nested more than 3 deep")
+	require.Equal(t, fileResolver, vi.FileResolver)
+}
+
+func TestFileRange(t *testing.T) {
+	vi := ValueInterpreter{
+		FileResolver: fr.NewDefaultFileResolver(),
+	}
+	result, err := vi.InterpretString("file:../integrationTests/exampleFile.txt#0:4")
+	require.Nil(t, err)
+	require.Equal(t, []byte("hell"), result)
+
+	result, err = vi.InterpretString("file:../integrationTests/exampleFile.txt#4:")
+	require.Nil(t, err)
+	require.Equal(t, []byte("o!"), result)
+
+	result, err = vi.InterpretString("file:../integrationTests/exampleFile.txt#2:2")
+	require.Nil(t, err)
+	require.Equal(t, []byte{}, result)
+
+	result, err = vi.InterpretString("file:../integrationTests/exampleLines.txt#L2-L3")
+	require.Nil(t, err)
+	require.Equal(t, []byte("line 2\r\nline 3"), result)
+
+	result, err = vi.InterpretString("file:../integrationTests/exampleLines.txt#L2")
+	require.Nil(t, err)
+	require.Equal(t, []byte("line 2"), result)
+
+	result, err = vi.InterpretString("file:../integrationTests/exampleLines.txt#L5")
+	require.Nil(t, err)
+	require.Equal(t, []byte{}, result)
+
+	_, err = vi.InterpretString("file:../integrationTests/exampleFile.txt#0:7")
+	require.NotNil(t, err)
+	_, err = vi.InterpretString("file:../integrationTests/exampleFile.txt#4:2")
+	require.NotNil(t, err)
+	_, err = vi.InterpretString("file:../integrationTests/exampleLines.txt#L3-L2")
+	require.NotNil(t, err)
+	_, err = vi.InterpretString("file:../integrationTests/exampleLines.txt#L6")
+	require.NotNil(t, err)
+
+	// not a range, part of the file name
+	_, err = vi.InterpretString("file:../integrationTests/exampleFile.txt#abc")
+	require.NotNil(t, err)
+	require.Contains(t, err.Error(), "exampleFile.txt#abc")
+}
+
 func TestInterpretSubTree1(t *testing.T) {
 	vi := ValueInterpreter{}
 	jobj, err := oj.ParseOrderedJSON([]byte(`
@@ -421,3 +720,830 @@ func TestInterpretSubTree2(t *testing.T) {
 	expected = append(expected, []byte("field2elem3b")...)
 	require.Equal(t, expected, result)
 }
+
+func TestConst(t *testing.T) {
+	vi := ValueInterpreter{}
+	_, err := vi.InterpretString("const:MAX_SUPPLY")
+	require.NotNil(t, err)
+
+	vi.SetConstant("MAX_SUPPLY", []byte{0x03, 0xe8})
+	vi.SetConstant("OWNER", []byte("owner"))
+	result, err := vi.InterpretString("const:MAX_SUPPLY")
+	require.Nil(t, err)
+	require.Equal(t, []byte{0x03, 0xe8}, result)
+
+	result, err = vi.InterpretString("const:OWNER|const:MAX_SUPPLY")
+	require.Nil(t, err)
+	require.Equal(t, []byte{'o', 'w', 'n', 'e', 'r', 0x03, 0xe8}, result)
+
+	result, err = vi.InterpretString("keccak256:const:OWNER")
+	require.Nil(t, err)
+	expected, _ := keccak256([]byte("owner"))
+	require.Equal(t, expected, result)
+}
+
+func TestGasPreset(t *testing.T) {
+	vi := ValueInterpreter{}
+	_, err := vi.InterpretString("preset:deploy-heavy")
+	require.NotNil(t, err)
+	var valueErr *ValueError
+	require.True(t, errors.As(err, &valueErr))
+	require.Equal(t, RulePreset, valueErr.Rule)
+
+	vi.SetGasPreset("deploy-heavy", 50000000)
+	vi.SetGasPreset("free", 0)
+	result, err := vi.InterpretString("preset:deploy-heavy")
+	require.Nil(t, err)
+	require.Equal(t, []byte{0x02, 0xfa, 0xf0, 0x80}, result)
+
+	result, err = vi.InterpretString("preset:free")
+	require.Nil(t, err)
+	require.Equal(t, []byte{}, result)
+}
+
+func TestStrict(t *testing.T) {
+	vi := ValueInterpreter{}
+	result, err := vi.InterpretString("u8:-1")
+	require.Nil(t, err)
+	require.Equal(t, []byte{0x01}, result)
+	require.Nil(t, vi.Diagnostics)
+
+	vi.Strict = true
+	_, err = vi.InterpretString("u8:-1")
+	require.NotNil(t, err)
+
+	result, err = vi.InterpretString("0x123")
+	require.Nil(t, err)
+	require.Equal(t, []byte{0x01, 0x23}, result)
+
+	_, err = vi.InterpretString("u12:5|u8:-1|0x1")
+	require.NotNil(t, err)
+	diagnosticsErr, isDiagnosticsErr := err.(*DiagnosticsError)
+	require.True(t, isDiagnosticsErr)
+	require.Equal(t, 2, len(diagnosticsErr.Diagnostics))
+	require.Equal(t, "u12:5", diagnosticsErr.Diagnostics[0].Expression)
+	require.Equal(t, "-1", diagnosticsErr.Diagnostics[1].Expression)
+
+	diagnostics := vi.TakeDiagnostics()
+	require.Equal(t, 5, len(diagnostics))
+	require.Equal(t, SeverityWarning, diagnostics[1].Severity)
+	require.Equal(t, SeverityWarning, diagnostics[4].Severity)
+	require.Nil(t, vi.Diagnostics)
+}
+
+func TestAddressWithShard(t *testing.T) {
+	vi := ValueInterpreter{}
+	result, err := vi.InterpretString("address:alice#2")
+	require.Nil(t, err)
+	require.Equal(t, append([]byte("alice__________________________"), 0x02), result)
+
+	result, err = vi.InterpretString("address:alice#0")
+	require.Nil(t, err)
+	require.Equal(t, append([]byte("alice__________________________"), 0x00), result)
+
+	result, err = vi.InterpretString("address:12345678901234567890123456789012#1")
+	require.Nil(t, err)
+	require.Equal(t, append([]byte("1234567890123456789012345678901"), 0x01), result)
+
+	result, err = vi.InterpretString("address:a#b#3")
+	require.Nil(t, err)
+	require.Equal(t, append([]byte("a#b____________________________"), 0x03), result)
+
+	_, err = vi.InterpretString("address:alice#256")
+	require.NotNil(t, err)
+
+	// no shard id, the separator is simply part of the name
+	result, err = vi.InterpretString("address:alice#")
+	require.Nil(t, err)
+	require.Equal(t, []byte("alice#__________________________"), result)
+}
+
+func TestAddressLintHashInName(t *testing.T) {
+	vi := ValueInterpreter{Lint: true}
+	_, err := vi.InterpretString("address:alice#2")
+	require.Nil(t, err)
+	require.Empty(t, vi.TakeDiagnostics())
+
+	for _, value := range []string{"address:alice#", "address:a#b#3", "address:a#b"} {
+		_, err = vi.InterpretString(value)
+		require.Nil(t, err)
+		diagnostics := vi.TakeDiagnostics()
+		require.Equal(t, 1, len(diagnostics), value)
+		require.Equal(t, SeverityWarning, diagnostics[0].Severity)
+		require.Equal(t, value, diagnostics[0].Expression)
+		require.Contains(t, diagnostics[0].Message, "'#' in address name")
+	}
+}
+
+func TestAddressLengthMode(t *testing.T) {
+	longName := "123456789012345678901234567890123"
+	otherLongName := "123456789012345678901234567890124"
+
+	vi := ValueInterpreter{Strict: true}
+	result, err := vi.InterpretString("address:" + longName)
+	require.Nil(t, err)
+	require.Equal(t, []byte("12345678901234567890123456789012"), result)
+	diagnostics := vi.TakeDiagnostics()
+	require.Equal(t, 1, len(diagnostics))
+	require.Equal(t, SeverityWarning, diagnostics[0].Severity)
+
+	vi = ValueInterpreter{AddressLengthMode: AddressLengthError}
+	_, err = vi.InterpretString("address:" + longName)
+	require.NotNil(t, err)
+	_, err = vi.InterpretString("address:" + longName + "#1")
+	require.NotNil(t, err)
+	result, err = vi.InterpretString("address:12345678901234567890123456789012")
+	require.Nil(t, err)
+	require.Equal(t, []byte("12345678901234567890123456789012"), result)
+
+	vi = ValueInterpreter{AddressLengthMode: AddressKeccak}
+	result, err = vi.InterpretString("address:" + longName)
+	require.Nil(t, err)
+	expected, _ := keccak256([]byte(longName))
+	require.Equal(t, expected, result)
+	otherResult, err := vi.InterpretString("address:" + otherLongName)
+	require.Nil(t, err)
+	require.NotEqual(t, result, otherResult)
+
+	result, err = vi.InterpretString("address:" + longName + "#3")
+	require.Nil(t, err)
+	require.Equal(t, append(expected[:AddressLength-1:AddressLength-1], 0x03), result)
+
+	// short names are not affected
+	result, err = vi.InterpretString("address:a")
+	require.Nil(t, err)
+	require.Equal(t, []byte("a_______________________________"), result)
+}
+
+func TestKeccak256Cache(t *testing.T) {
+	cache := newKeccak256Cache(2, DefaultKeccak256CacheBytes)
+	for _, data := range []string{"a", "b", "a", "c"} {
+		result, err := cache.hash([]byte(data))
+		require.Nil(t, err)
+		expected, _ := keccak256([]byte(data))
+		require.Equal(t, expected, result)
+	}
+	require.Equal(t, 2, cache.len())
+
+	// "b" was the least recently used
+	_, bCached := cache.entries["b"]
+	require.False(t, bCached)
+	_, aCached := cache.entries["a"]
+	require.True(t, aCached)
+}
+
+func TestKeccak256CacheByteBudget(t *testing.T) {
+	// room for two entries of 4 bytes and their hashes, inputs of at most 5 bytes
+	cache := newKeccak256Cache(10, 80)
+	for _, data := range []string{"a", "b"} {
+		_, err := cache.hash([]byte(strings.Repeat(data, 4)))
+		require.Nil(t, err)
+	}
+	require.Equal(t, 2, cache.len())
+
+	_, err := cache.hash([]byte(strings.Repeat("c", 4)))
+	require.Nil(t, err)
+	require.Equal(t, 2, cache.len())
+	require.Equal(t, 72, cache.bytes)
+
+	// too large to be cached, still hashed
+	large := []byte(strings.Repeat("d", 6))
+	result, err := cache.hash(large)
+	require.Nil(t, err)
+	expected, _ := keccak256(large)
+	require.Equal(t, expected, result)
+	_, largeCached := cache.entries[string(large)]
+	require.False(t, largeCached)
+}
+
+func keccak256BenchmarkExpression() string {
+	return "keccak256:str:" + strings.Repeat("large contract code ", 10000)
+}
+
+func BenchmarkKeccak256Cached(b *testing.B) {
+	vi := ValueInterpreter{}
+	expression := keccak256BenchmarkExpression()
+	for i := 0; i < b.N; i++ {
+		_, _ = vi.InterpretString(expression)
+	}
+}
+
+func BenchmarkKeccak256Uncached(b *testing.B) {
+	expression := keccak256BenchmarkExpression()
+	for i := 0; i < b.N; i++ {
+		vi := ValueInterpreter{}
+		_, _ = vi.InterpretString(expression)
+	}
+}
+
+func TestToken(t *testing.T) {
+	vi := ValueInterpreter{}
+	result, err := vi.InterpretString("token:WNUMB-a1b2c3")
+	require.Nil(t, err)
+	require.Equal(t, []byte("WNUMB-a1b2c3"), result)
+
+	generated, err := vi.InterpretString("token:WNUMB")
+	require.Nil(t, err)
+	require.Equal(t, len("WNUMB-123456"), len(generated))
+	require.Equal(t, []byte("WNUMB-"), generated[:6])
+
+	again, err := vi.InterpretString("token:WNUMB")
+	require.Nil(t, err)
+	require.Equal(t, generated, again)
+
+	vi.Seed = "other scenario"
+	reseeded, err := vi.InterpretString("token:WNUMB")
+	require.Nil(t, err)
+	require.NotEqual(t, generated, reseeded)
+
+	_, err = vi.InterpretString("token:wnumb-a1b2c3")
+	require.NotNil(t, err)
+	_, err = vi.InterpretString("token:AB")
+	require.NotNil(t, err)
+	_, err = vi.InterpretString("token:ABCDEFGHIJK")
+	require.NotNil(t, err)
+	_, err = vi.InterpretString("token:WNUMB-A1B2C3")
+	require.NotNil(t, err)
+	_, err = vi.InterpretString("token:WNUMB-a1b2")
+	require.NotNil(t, err)
+}
+
+func TestPercent(t *testing.T) {
+	vi := ValueInterpreter{}
+	result, err := vi.InterpretString("percent:2.5")
+	require.Nil(t, err)
+	require.Equal(t, []byte{250}, result)
+
+	result, err = vi.InterpretString("bp:250")
+	require.Nil(t, err)
+	require.Equal(t, []byte{250}, result)
+
+	result, err = vi.InterpretString("percent:100")
+	require.Nil(t, err)
+	require.Equal(t, []byte{0x27, 0x10}, result)
+
+	result, err = vi.InterpretString("percent:0")
+	require.Nil(t, err)
+	require.Equal(t, []byte{}, result)
+
+	_, err = vi.InterpretString("percent:2.555")
+	require.NotNil(t, err)
+	_, err = vi.InterpretString("bp:2.5")
+	require.NotNil(t, err)
+	_, err = vi.InterpretString("percent:-1")
+	require.NotNil(t, err)
+	_, err = vi.InterpretString("percent:abc")
+	require.NotNil(t, err)
+
+	vi.PercentScale = 1000
+	result, err = vi.InterpretString("percent:2.555")
+	require.Nil(t, err)
+	require.Equal(t, []byte{0x09, 0xfb}, result)
+
+	result, err = vi.InterpretString("bp:250")
+	require.Nil(t, err)
+	require.Equal(t, []byte{0x09, 0xc4}, result)
+}
+
+func TestTimestampAndDuration(t *testing.T) {
+	vi := ValueInterpreter{}
+	// 1704067200 = 0x65920080
+	result, err := vi.InterpretString("timestamp:2024-01-01T00:00:00Z")
+	require.Nil(t, err)
+	require.Equal(t, []byte{0x65, 0x92, 0x00, 0x80}, result)
+
+	result, err = vi.InterpretString("timestamp:2024-01-01")
+	require.Nil(t, err)
+	require.Equal(t, []byte{0x65, 0x92, 0x00, 0x80}, result)
+
+	result, err = vi.InterpretString("timestamp:2024-01-01T02:00:00+02:00")
+	require.Nil(t, err)
+	require.Equal(t, []byte{0x65, 0x92, 0x00, 0x80}, result)
+
+	result, err = vi.InterpretString("timestamp:1970-01-01T00:00:00Z")
+	require.Nil(t, err)
+	require.Equal(t, []byte{}, result)
+
+	result, err = vi.InterpretString("u64:timestamp:2024-01-01T00:00:00Z")
+	require.Nil(t, err)
+	require.Equal(t, []byte{0x00, 0x00, 0x00, 0x00, 0x65, 0x92, 0x00, 0x80}, result)
+
+	// 3 * 86400 + 12 * 3600 = 302400 = 0x049d40
+	result, err = vi.InterpretString("duration:3d12h")
+	require.Nil(t, err)
+	require.Equal(t, []byte{0x04, 0x9d, 0x40}, result)
+
+	result, err = vi.InterpretString("duration:1w")
+	require.Nil(t, err)
+	require.Equal(t, []byte{0x09, 0x3a, 0x80}, result)
+
+	result, err = vi.InterpretString("duration:1h30m15s")
+	require.Nil(t, err)
+	require.Equal(t, []byte{0x15, 0x27}, result)
+
+	result, err = vi.InterpretString("duration:0s")
+	require.Nil(t, err)
+	require.Equal(t, []byte{}, result)
+
+	for _, invalid := range []string{
+		"timestamp:",
+		"timestamp:2024-13-01",
+		"timestamp:2024-01-01 00:00:00",
+		"timestamp:2024-01-01T00:00:00.5Z",
+		"timestamp:1969-12-31T23:59:59Z",
+		"duration:",
+		"duration:3",
+		"duration:d",
+		"duration:3x",
+		"duration:-3d",
+		"duration:1.5h",
+		"duration:99999999999999999999s",
+		"duration:40000000000000w",
+	} {
+		_, err = vi.InterpretString(invalid)
+		require.NotNil(t, err, invalid)
+	}
+}
+
+func TestPadding(t *testing.T) {
+	vi := ValueInterpreter{}
+	result, err := vi.InterpretString("left-pad:4:0x0102")
+	require.Nil(t, err)
+	require.Equal(t, []byte{0x00, 0x00, 0x01, 0x02}, result)
+
+	result, err = vi.InterpretString("right-pad:4:(0x0102)")
+	require.Nil(t, err)
+	require.Equal(t, []byte{0x01, 0x02, 0x00, 0x00}, result)
+
+	result, err = vi.InterpretString("left-pad:4:str:a|str:b")
+	require.Nil(t, err)
+	require.Equal(t, []byte{0x00, 0x00, 'a', 'b'}, result)
+
+	result, err = vi.InterpretString("str:key|left-pad:2:5")
+	require.Nil(t, err)
+	require.Equal(t, []byte{'k', 'e', 'y', 0x00, 0x05}, result)
+
+	result, err = vi.InterpretString("left-pad:0:")
+	require.Nil(t, err)
+	require.Equal(t, []byte{}, result)
+
+	_, err = vi.InterpretString("left-pad:1:0x0102")
+	require.NotNil(t, err)
+	_, err = vi.InterpretString("left-pad:x:0x0102")
+	require.NotNil(t, err)
+	_, err = vi.InterpretString("right-pad:4")
+	require.NotNil(t, err)
+	_, err = vi.InterpretString("left-pad:99999999999:1")
+	var valueErr *ValueError
+	require.ErrorAs(t, err, &valueErr)
+	require.Contains(t, err.Error(), "padding length larger than")
+	_, err = vi.InterpretString("right-pad:99999999999:1")
+	require.ErrorAs(t, err, &valueErr)
+}
+
+func TestSlice(t *testing.T) {
+	vi := ValueInterpreter{}
+	result, err := vi.InterpretString("slice:1:3:0x01020304")
+	require.Nil(t, err)
+	require.Equal(t, []byte{0x02, 0x03}, result)
+
+	result, err = vi.InterpretString("slice:0:4:(keccak256:str:abc)")
+	require.Nil(t, err)
+	expected, _ := keccak256([]byte("abc"))
+	require.Equal(t, expected[:4], result)
+
+	// parentheses only group the entire operand
+	_, err = vi.InterpretString("slice:0:2:(str:ab)|(str:cd)")
+	require.NotNil(t, err)
+
+	_, err = vi.InterpretString("slice:0:5:0x01020304")
+	require.NotNil(t, err)
+	_, err = vi.InterpretString("slice:3:1:0x01020304")
+	require.NotNil(t, err)
+	_, err = vi.InterpretString("slice:1:0x01020304")
+	require.NotNil(t, err)
+}
+
+func TestBitwise(t *testing.T) {
+	vi := ValueInterpreter{}
+	result, err := vi.InterpretString("bitor:(u8:1),(u8:4),(u8:0x10)")
+	require.Nil(t, err)
+	require.Equal(t, []byte{0x15}, result)
+
+	result, err = vi.InterpretString("bitand:(0xff0f),(0x3c)")
+	require.Nil(t, err)
+	require.Equal(t, []byte{0x00, 0x0c}, result)
+
+	result, err = vi.InterpretString("bitxor:(u16:0x0ff0),(u16:0x00ff)")
+	require.Nil(t, err)
+	require.Equal(t, []byte{0x0f, 0x0f}, result)
+
+	result, err = vi.InterpretString("bitor:(shl:8:(u8:1)),(u8:2)")
+	require.Nil(t, err)
+	require.Equal(t, []byte{0x01, 0x02}, result)
+
+	result, err = vi.InterpretString("str:flags|bitor:(u8:1),(u8:2)")
+	require.Nil(t, err)
+	require.Equal(t, []byte("flags\x03"), result)
+
+	_, err = vi.InterpretString("bitor:(u8:1)")
+	require.NotNil(t, err)
+	_, err = vi.InterpretString("bitor:u8:1,u8:2")
+	require.NotNil(t, err)
+	_, err = vi.InterpretString("bitor:(u8:1),(u8:2")
+	require.NotNil(t, err)
+	_, err = vi.InterpretString("bitor:(u8:1)(u8:2)")
+	require.NotNil(t, err)
+}
+
+func TestRepeat(t *testing.T) {
+	vi := ValueInterpreter{}
+	result, err := vi.InterpretString("repeat:32:0x00")
+	require.Nil(t, err)
+	require.Equal(t, make([]byte, 32), result)
+
+	result, err = vi.InterpretString("repeat:3:str:ab")
+	require.Nil(t, err)
+	require.Equal(t, []byte("ababab"), result)
+
+	result, err = vi.InterpretString("str:key|repeat:2:(u8:1)")
+	require.Nil(t, err)
+	require.Equal(t, []byte{'k', 'e', 'y', 0x01, 0x01}, result)
+
+	result, err = vi.InterpretString("repeat:0:0xff")
+	require.Nil(t, err)
+	require.Equal(t, []byte{}, result)
+
+	_, err = vi.InterpretString("repeat:-1:0xff")
+	require.NotNil(t, err)
+	_, err = vi.InterpretString("repeat:x:0xff")
+	require.NotNil(t, err)
+	_, err = vi.InterpretString("repeat:4")
+	require.NotNil(t, err)
+	_, err = vi.InterpretString("repeat:1000000000:0xffff")
+	require.NotNil(t, err)
+}
+
+func TestShift(t *testing.T) {
+	vi := ValueInterpreter{}
+	result, err := vi.InterpretString("shl:4:u8:1")
+	require.Nil(t, err)
+	require.Equal(t, []byte{0x10}, result)
+
+	result, err = vi.InterpretString("shl:8:u8:1")
+	require.Nil(t, err)
+	require.Equal(t, []byte{0x01, 0x00}, result)
+
+	result, err = vi.InterpretString("shr:4:u16:0x0100")
+	require.Nil(t, err)
+	require.Equal(t, []byte{0x00, 0x10}, result)
+
+	result, err = vi.InterpretString("shr:16:(u16:0x0100)")
+	require.Nil(t, err)
+	require.Equal(t, []byte{0x00, 0x00}, result)
+
+	_, err = vi.InterpretString("shl:-1:u8:1")
+	require.NotNil(t, err)
+	_, err = vi.InterpretString("shl:4294967295:1")
+	require.NotNil(t, err)
+	_, err = vi.InterpretString("shr:4")
+	require.NotNil(t, err)
+}
+
+func TestBase64(t *testing.T) {
+	vi := ValueInterpreter{}
+	result, err := vi.InterpretString("base64:aGVsbG8=")
+	require.Nil(t, err)
+	require.Equal(t, []byte("hello"), result)
+
+	result, err = vi.InterpretString("base64:aGVsbG8")
+	require.Nil(t, err)
+	require.Equal(t, []byte("hello"), result)
+
+	result, err = vi.InterpretString("base64:+/8=")
+	require.Nil(t, err)
+	require.Equal(t, []byte{0xfb, 0xff}, result)
+
+	result, err = vi.InterpretString("base64:-_8")
+	require.Nil(t, err)
+	require.Equal(t, []byte{0xfb, 0xff}, result)
+
+	result, err = vi.InterpretString("base64:")
+	require.Nil(t, err)
+	require.Equal(t, []byte{}, result)
+
+	result, err = vi.InterpretString("base64:aGk=|str:!")
+	require.Nil(t, err)
+	require.Equal(t, []byte("hi!"), result)
+
+	_, err = vi.InterpretString("base64:a")
+	require.NotNil(t, err)
+	_, err = vi.InterpretString("base64:+/-_")
+	require.NotNil(t, err)
+	_, err = vi.InterpretString("base64:a$==")
+	require.NotNil(t, err)
+}
+
+func TestValueError(t *testing.T) {
+	vi := ValueInterpreter{}
+	_, err := vi.InterpretString("str:abc|u8:256|0x01")
+	var valueErr *ValueError
+	require.True(t, errors.As(err, &valueErr))
+	require.Equal(t, "str:abc|u8:256|0x01", valueErr.Expression)
+	require.Equal(t, "u8:256", valueErr.SubExpression)
+	require.Equal(t, RuleFixedWidth, valueErr.Rule)
+	require.Contains(t, err.Error(), "str:abc|u8:256|0x01")
+
+	// the innermost sub-expression is reported
+	_, err = vi.InterpretString("keccak256:str:a|const:MISSING")
+	require.True(t, errors.As(err, &valueErr))
+	require.Equal(t, "const:MISSING", valueErr.SubExpression)
+	require.Equal(t, RuleConst, valueErr.Rule)
+	require.Contains(t, err.Error(), "cannot parse keccak256 argument")
+
+	_, err = vi.InterpretString("0xzz")
+	require.True(t, errors.As(err, &valueErr))
+	require.Equal(t, "0xzz", valueErr.Expression)
+	require.Equal(t, "0xzz", valueErr.SubExpression)
+	require.Equal(t, RuleNumber, valueErr.Rule)
+	require.Equal(t, valueErr.Err.Error(), err.Error())
+
+	_, err = vi.InterpretString("address:a#300")
+	require.True(t, errors.As(err, &valueErr))
+	require.Equal(t, RuleAddress, valueErr.Rule)
+}
+
+func TestAnyValue(t *testing.T) {
+	vi := ValueInterpreter{}
+	for _, anyValue := range []string{"*", "any"} {
+		_, err := vi.InterpretString(anyValue)
+		require.True(t, errors.Is(err, ErrAnyValue))
+		var valueErr *ValueError
+		require.True(t, errors.As(err, &valueErr))
+		require.Equal(t, RuleAny, valueErr.Rule)
+
+		checkValue, err := vi.InterpretCheckSubTree(&oj.OJsonString{Value: anyValue})
+		require.Nil(t, err)
+		require.True(t, checkValue.Any)
+		require.True(t, checkValue.Check([]byte("whatever")))
+		require.True(t, checkValue.Check([]byte{}))
+	}
+
+	_, err := vi.InterpretString("str:a|*")
+	require.True(t, errors.Is(err, ErrAnyValue))
+
+	// only whole leaves match anything
+	_, err = vi.InterpretCheckSubTree(&oj.OJsonList{&oj.OJsonString{Value: "*"}})
+	require.True(t, errors.Is(err, ErrAnyValue))
+
+	checkValue, err := vi.InterpretCheckSubTree(&oj.OJsonString{Value: "str:any"})
+	require.Nil(t, err)
+	require.False(t, checkValue.Any)
+	require.True(t, checkValue.Check([]byte("any")))
+	require.False(t, checkValue.Check([]byte("*")))
+}
+
+func TestSupportedPrefixes(t *testing.T) {
+	vi := ValueInterpreter{}
+	for _, info := range vi.SupportedPrefixes() {
+		require.False(t, info.RequiresFileResolver)
+		require.NotEmpty(t, info.Description)
+	}
+	_, found := vi.LookupPrefix("file:a.txt")
+	require.False(t, found)
+
+	vi.FileResolver = fr.NewDefaultFileResolver()
+	info, found := vi.LookupPrefix("file:json:values.json")
+	require.True(t, found)
+	require.Equal(t, FileJSONPrefix, info.Name)
+	require.Equal(t, RuleFile, info.Rule)
+
+	info, found = vi.LookupPrefix("slice:0:2:str:abc")
+	require.True(t, found)
+	require.Equal(t, 3, info.Arity)
+	require.Equal(t, RuleOperator, info.Rule)
+
+	info, found = vi.LookupPrefix("any")
+	require.True(t, found)
+	require.True(t, info.CheckOnly)
+
+	for _, expression := range []string{"5", "0x1234", "anything", "unknown:5", ""} {
+		_, found = vi.LookupPrefix(expression)
+		require.False(t, found, expression)
+	}
+
+	// every listed prefix is interpreted by the rule it is listed with
+	for _, info := range vi.SupportedPrefixes() {
+		if info.Arity == 0 {
+			continue
+		}
+		explanation, _ := vi.ExplainString(info.Name + "x")
+		require.Equal(t, info.Rule, explanation.Rule, info.Name)
+	}
+}
+
+func TestMatcher(t *testing.T) {
+	vi := ValueInterpreter{}
+
+	matcher, err := vi.InterpretMatcher("match:regex:^0x00.*")
+	require.Nil(t, err)
+	require.True(t, matcher.MatchBytes([]byte{0, 1, 2}))
+	require.False(t, matcher.MatchBytes([]byte{1, 0}))
+	require.Equal(t, "match:regex:^0x00.*", matcher.String())
+
+	matcher, err = vi.InterpretMatcher("match:regex:^1[0-9]{2}$")
+	require.Nil(t, err)
+	require.True(t, matcher.MatchBigInt(big.NewInt(150)))
+	require.False(t, matcher.MatchBigInt(big.NewInt(1500)))
+
+	matcher, err = vi.InterpretMatcher("match:range:100..200")
+	require.Nil(t, err)
+	require.True(t, matcher.MatchBigInt(big.NewInt(100)))
+	require.True(t, matcher.MatchBigInt(big.NewInt(200)))
+	require.False(t, matcher.MatchBigInt(big.NewInt(99)))
+	require.False(t, matcher.MatchBigInt(big.NewInt(201)))
+	require.True(t, matcher.MatchBytes([]byte{150}))
+	require.False(t, matcher.MatchBytes([]byte{1, 0}))
+
+	matcher, err = vi.InterpretMatcher("match:range:-5..")
+	require.Nil(t, err)
+	require.True(t, matcher.MatchBigInt(big.NewInt(-5)))
+	require.True(t, matcher.MatchBigInt(big.NewInt(1000000)))
+	require.False(t, matcher.MatchBigInt(big.NewInt(-6)))
+
+	matcher, err = vi.InterpretMatcher("match:range:..duration:1h")
+	require.Nil(t, err)
+	require.True(t, matcher.MatchBigInt(big.NewInt(3600)))
+	require.False(t, matcher.MatchBigInt(big.NewInt(3601)))
+
+	matcher, err = vi.InterpretMatcher("match:range:timestamp:2024-01-01..timestamp:2024-01-02")
+	require.Nil(t, err)
+	require.True(t, matcher.MatchBigInt(big.NewInt(1704067200+60)))
+	require.False(t, matcher.MatchBigInt(big.NewInt(1704067200-60)))
+
+	for _, invalid := range []string{
+		"match:regex:(",
+		"match:range:5",
+		"match:range:1..2..3",
+		"match:range:200..100",
+		"match:range:abc..",
+		"match:other:5",
+	} {
+		_, err = vi.InterpretMatcher(invalid)
+		require.NotNil(t, err, invalid)
+	}
+
+	_, err = vi.InterpretString("match:range:1..2")
+	require.True(t, errors.Is(err, ErrMatcher))
+
+	checkValue, err := vi.InterpretCheckSubTree(&oj.OJsonString{Value: "match:range:1..2"})
+	require.Nil(t, err)
+	require.True(t, checkValue.Check([]byte{2}))
+	require.False(t, checkValue.Check([]byte{3}))
+}
+
+func TestMulti(t *testing.T) {
+	vi := ValueInterpreter{}
+	result, err := vi.InterpretString("multi:str:abc;5;0x")
+	require.Nil(t, err)
+	require.Equal(t, []byte{0, 0, 0, 3, 'a', 'b', 'c', 0, 0, 0, 1, 5, 0, 0, 0, 0}, result)
+
+	// digit separators, concatenation and operand lists belong to the item
+	result, err = vi.InterpretString("multi:1,000;str:a|str:b;bitor:(1),(2)")
+	require.Nil(t, err)
+	require.Equal(t, []byte{0, 0, 0, 2, 0x03, 0xe8, 0, 0, 0, 2, 'a', 'b', 0, 0, 0, 1, 3}, result)
+
+	// so do separators within parentheses
+	result, err = vi.InterpretString("multi:(str:a;b);u8:1")
+	require.Nil(t, err)
+	require.Equal(t, []byte{0, 0, 0, 3, 'a', ';', 'b', 0, 0, 0, 1, 1}, result)
+
+	result, err = vi.InterpretString("multi:")
+	require.Nil(t, err)
+	require.Equal(t, []byte{}, result)
+
+	_, err = vi.InterpretString("multi:1;(2")
+	require.NotNil(t, err)
+
+	_, err = vi.InterpretString("multi:1;const:MISSING")
+	var valueErr *ValueError
+	require.True(t, errors.As(err, &valueErr))
+	require.Equal(t, RuleConst, valueErr.Rule)
+}
+
+func TestLint(t *testing.T) {
+	vi := ValueInterpreter{}
+	findings, err := vi.LintString("u32:-5")
+	require.Nil(t, err)
+	require.Equal(t, 1, len(findings))
+	require.Equal(t, SeverityError, findings[0].Severity)
+
+	findings, err = vi.LintString("str:0x1234")
+	require.Nil(t, err)
+	require.Equal(t, 1, len(findings))
+	require.Equal(t, SeverityWarning, findings[0].Severity)
+	require.Contains(t, findings[0].Message, "hex number")
+
+	for _, strayConcat := range []string{"str:a||str:b", "|str:a", "str:a|"} {
+		findings, err = vi.LintString(strayConcat)
+		require.Nil(t, err)
+		require.Equal(t, 1, len(findings), strayConcat)
+		require.Contains(t, findings[0].Message, "empty part")
+	}
+
+	findings, err = vi.LintString("str:0xyz|u32:5")
+	require.Nil(t, err)
+	require.Empty(t, findings)
+
+	// errors are returned separately from the findings
+	findings, err = vi.LintString("str:0x12|const:MISSING")
+	require.NotNil(t, err)
+	require.Equal(t, 1, len(findings))
+
+	// findings are not kept, strict mode does not reject values when linting
+	vi.Strict = true
+	findings, err = vi.LintString("u32:-5")
+	require.Nil(t, err)
+	require.Equal(t, 1, len(findings))
+	require.Empty(t, vi.Diagnostics)
+
+	// lint mode collects diagnostics without rejecting values
+	vi = ValueInterpreter{Lint: true}
+	result, err := vi.InterpretString("u32:-5")
+	require.Nil(t, err)
+	require.Equal(t, []byte{0, 0, 0, 5}, result)
+	require.Equal(t, 1, len(vi.TakeDiagnostics()))
+}
+
+// benchmarkValues are typical of large scenario suites.
+var benchmarkValues = []string{
+	"0",
+	"1,000,000,000,000,000,000",
+	"0x0000000000000000000000000000000000000000000000000000000000000001",
+	"address:owner",
+	"address:a_rather_long_account_name_that_gets_cut",
+	"str:balance",
+	"u32:5|u64:1000|str:abc",
+	"i64:-1",
+	"keccak256:str:storage_key",
+	"str:counter|address:owner|u8:1",
+	"true",
+	"''deposit",
+	"-1234567890",
+}
+
+func BenchmarkInterpretString(b *testing.B) {
+	vi := ValueInterpreter{}
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		for _, value := range benchmarkValues {
+			_, _ = vi.InterpretString(value)
+		}
+	}
+}
+
+func BenchmarkInterpretSubTree(b *testing.B) {
+	var items oj.OJsonList
+	for i := 0; i < 10000; i++ {
+		items = append(items, &oj.OJsonString{Value: benchmarkValues[i%len(benchmarkValues)]})
+	}
+	vi := ValueInterpreter{}
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		_, _ = vi.InterpretSubTree(&items)
+	}
+}
+
+func TestFormatVersionOf(t *testing.T) {
+	for expression, expected := range map[string]mj.FormatVersion{
+		"":                             mj.FormatVersionInitial,
+		"1,000":                        mj.FormatVersionInitial,
+		"*":                            mj.FormatVersionInitial,
+		"str:token:ABC|address:a#b":    mj.FormatVersionInitial,
+		"u32:keccak256:file:a.wasm":    mj.FormatVersionInitial,
+		"abi:u32:5":                    mj.FormatVersionABI,
+		"const:A":                      mj.FormatVersionConstants,
+		"preset:call":                  mj.FormatVersionGasPresets,
+		"$owner":                       mj.FormatVersionDefines,
+		"address:owner#2":              mj.FormatVersionAddressShard,
+		"token:WEGLD":                  mj.FormatVersionTokens,
+		"bp:25":                        mj.FormatVersionPercent,
+		"slice:0:2:(str:abc)":          mj.FormatVersionByteOperators,
+		"shr:8:repeat:2:0xff":          mj.FormatVersionBitOperators,
+		"file:a.txt#L1-L2":             mj.FormatVersionFileRanges,
+		"str:a|base64:AQ==":            mj.FormatVersionBase64,
+		"duration:3d":                  mj.FormatVersionTime,
+		"keccak256:code:a.wasm":        mj.FormatVersionCode,
+		"file:json:a.json":             mj.FormatVersionFileJSON,
+		"any":                          mj.FormatVersionAnyValue,
+		"match:range:1..2":             mj.FormatVersionMatchers,
+		"multi:1;(str:a|u8:1)":         mj.FormatVersionMulti,
+		"right-pad:4:(multi:1;(u8:2))": mj.FormatVersionMulti,
+		"bitor:(u8:1),(i16:$negative)": mj.FormatVersionBitOperators,
+	} {
+		version, syntax := FormatVersionOf(expression)
+		require.Equal(t, expected, version, expression)
+		require.Equal(t, version == mj.FormatVersionInitial, len(syntax) == 0, expression)
+	}
+}
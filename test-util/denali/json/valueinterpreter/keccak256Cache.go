@@ -0,0 +1,96 @@
+package denalivalueinterpreter
+
+import (
+	"container/list"
+	"sync"
+)
+
+// DefaultKeccak256CacheSize is the number of hashes the interpreter keeps by default.
+const DefaultKeccak256CacheSize = 1024
+
+// DefaultKeccak256CacheBytes bounds the memory of the cache, since it keeps the hashed bytes too,
+// which can be whole contracts.
+const DefaultKeccak256CacheBytes = 16 << 20
+
+// keccak256CacheMaxInputShare makes inputs larger than that fraction of the byte budget bypass the cache,
+// so that a single large value does not evict all the others.
+const keccak256CacheMaxInputShare = 16
+
+// keccak256Cache is a LRU cache of keccak256 results, keyed on the hashed bytes.
+// Scenarios tend to hash the same values (code, storage key components) many times over.
+type keccak256Cache struct {
+	mutex    sync.Mutex
+	capacity int
+	maxBytes int
+	bytes    int
+	order    *list.List
+	entries  map[string]*list.Element
+}
+
+type keccak256CacheEntry struct {
+	key  string
+	hash []byte
+}
+
+func newKeccak256Cache(capacity int, maxBytes int) *keccak256Cache {
+	return &keccak256Cache{
+		capacity: capacity,
+		maxBytes: maxBytes,
+		order:    list.New(),
+		entries:  make(map[string]*list.Element),
+	}
+}
+
+func (c *keccak256Cache) hash(data []byte) ([]byte, error) {
+	if len(data) > c.maxBytes/keccak256CacheMaxInputShare {
+		return keccak256(data)
+	}
+	key := string(data)
+
+	c.mutex.Lock()
+	if elem, found := c.entries[key]; found {
+		c.order.MoveToFront(elem)
+		hash := elem.Value.(*keccak256CacheEntry).hash
+		c.mutex.Unlock()
+		return append([]byte{}, hash...), nil
+	}
+	c.mutex.Unlock()
+
+	hash, err := keccak256(data)
+	if err != nil {
+		return nil, err
+	}
+
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	if _, found := c.entries[key]; !found {
+		c.entries[key] = c.order.PushFront(&keccak256CacheEntry{key: key, hash: hash})
+		c.bytes += entrySize(key, hash)
+		for c.order.Len() > c.capacity || c.bytes > c.maxBytes {
+			oldest := c.order.Back()
+			c.order.Remove(oldest)
+			entry := oldest.Value.(*keccak256CacheEntry)
+			delete(c.entries, entry.key)
+			c.bytes -= entrySize(entry.key, entry.hash)
+		}
+	}
+	return append([]byte{}, hash...), nil
+}
+
+// entrySize is what an entry counts against the byte budget.
+func entrySize(key string, hash []byte) int {
+	return len(key) + len(hash)
+}
+
+func (c *keccak256Cache) len() int {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	return c.order.Len()
+}
+
+func (vi *ValueInterpreter) cachedKeccak256(data []byte) ([]byte, error) {
+	if vi.keccak256Cache == nil {
+		vi.keccak256Cache = newKeccak256Cache(DefaultKeccak256CacheSize, DefaultKeccak256CacheBytes)
+	}
+	return vi.keccak256Cache.hash(data)
+}
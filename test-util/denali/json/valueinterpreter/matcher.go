@@ -0,0 +1,142 @@
+package denalivalueinterpreter
+
+import (
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"math/big"
+	"regexp"
+	"strings"
+
+	twos "github.com/numbatx/gn-bigint/twos-complement"
+)
+
+// Prefixes of the matchers, values that are only allowed in checks.
+const (
+	MatchPrefix      = "match:"
+	MatchRegexPrefix = "match:regex:"
+	MatchRangePrefix = "match:range:"
+)
+
+const rangeSeparator = ".."
+
+// ErrMatcher is wrapped by the error produced when a matcher is interpreted as bytes, i.e. outside of a check.
+var ErrMatcher = errors.New("matchers are only allowed in checks")
+
+// Matcher is a check condition more tolerant than equality.
+type Matcher interface {
+	// MatchBytes checks a value such as a storage value or a result.
+	MatchBytes(actual []byte) bool
+
+	// MatchBigInt checks a number such as a balance, a nonce or gas.
+	MatchBigInt(actual *big.Int) bool
+
+	// String yields the matcher, as written.
+	String() string
+}
+
+// IsMatcher yields true for values of the form "match:...".
+func IsMatcher(strRaw string) bool {
+	return strings.HasPrefix(strRaw, MatchPrefix)
+}
+
+// InterpretMatcher interprets values such as "match:regex:^0x00.*" or "match:range:100..200".
+// Regular expressions are matched against the hex representation of byte values, e.g. "0x0102",
+// and against the decimal representation of numbers.
+// Range bounds are inclusive, either of them can be left out, e.g. "match:range:100..".
+// Bounds are numbers, or any other value, taken as unsigned, e.g. "match:range:timestamp:2024-01-01..".
+func (vi *ValueInterpreter) InterpretMatcher(strRaw string) (Matcher, error) {
+	switch {
+	case strings.HasPrefix(strRaw, MatchRegexPrefix):
+		re, err := regexp.Compile(strRaw[len(MatchRegexPrefix):])
+		if err != nil {
+			return nil, fmt.Errorf("invalid regular expression: %w", err)
+		}
+		return &regexMatcher{original: strRaw, re: re}, nil
+	case strings.HasPrefix(strRaw, MatchRangePrefix):
+		return vi.interpretRangeMatcher(strRaw)
+	default:
+		return nil, fmt.Errorf("unknown matcher, expected %s or %s: %s", MatchRegexPrefix, MatchRangePrefix, strRaw)
+	}
+}
+
+func (vi *ValueInterpreter) interpretRangeMatcher(strRaw string) (Matcher, error) {
+	bounds := strings.Split(strRaw[len(MatchRangePrefix):], rangeSeparator)
+	if len(bounds) != 2 {
+		return nil, fmt.Errorf("range must be of the form LOW..HIGH: %s", strRaw)
+	}
+	matcher := &rangeMatcher{original: strRaw}
+	var err error
+	if len(bounds[0]) > 0 {
+		matcher.low, err = vi.interpretRangeBound(bounds[0])
+		if err != nil {
+			return nil, fmt.Errorf("invalid range start: %w", err)
+		}
+	}
+	if len(bounds[1]) > 0 {
+		matcher.high, err = vi.interpretRangeBound(bounds[1])
+		if err != nil {
+			return nil, fmt.Errorf("invalid range end: %w", err)
+		}
+	}
+	if matcher.low != nil && matcher.high != nil && matcher.low.Cmp(matcher.high) > 0 {
+		return nil, fmt.Errorf("empty range, %s is greater than %s", matcher.low, matcher.high)
+	}
+	return matcher, nil
+}
+
+// interpretRangeBound allows negative bounds, which other values cannot express.
+func (vi *ValueInterpreter) interpretRangeBound(strRaw string) (*big.Int, error) {
+	if strings.HasPrefix(strRaw, "-") {
+		value, err := vi.interpretNumber(strRaw, 0)
+		if err != nil {
+			return nil, err
+		}
+		return twos.FromBytes(value), nil
+	}
+	value, err := vi.interpretString(strRaw)
+	if err != nil {
+		return nil, err
+	}
+	return big.NewInt(0).SetBytes(value), nil
+}
+
+type regexMatcher struct {
+	original string
+	re       *regexp.Regexp
+}
+
+func (m *regexMatcher) MatchBytes(actual []byte) bool {
+	return m.re.MatchString("0x" + hex.EncodeToString(actual))
+}
+
+func (m *regexMatcher) MatchBigInt(actual *big.Int) bool {
+	return m.re.MatchString(actual.String())
+}
+
+func (m *regexMatcher) String() string {
+	return m.original
+}
+
+// rangeMatcher has nil bounds where they were left out.
+type rangeMatcher struct {
+	original string
+	low      *big.Int
+	high     *big.Int
+}
+
+// MatchBytes takes the value as an unsigned number.
+func (m *rangeMatcher) MatchBytes(actual []byte) bool {
+	return m.MatchBigInt(big.NewInt(0).SetBytes(actual))
+}
+
+func (m *rangeMatcher) MatchBigInt(actual *big.Int) bool {
+	if m.low != nil && actual.Cmp(m.low) < 0 {
+		return false
+	}
+	return m.high == nil || actual.Cmp(m.high) <= 0
+}
+
+func (m *rangeMatcher) String() string {
+	return m.original
+}
@@ -0,0 +1,63 @@
+package denalivalueinterpreter
+
+import (
+	"encoding/binary"
+	"fmt"
+)
+
+// MultiPrefix encodes a list of values the way contracts return variadic results.
+const MultiPrefix = "multi:"
+
+// interpretMulti handles "multi:a;b;c": each item is interpreted on its own,
+// and written with a 4 byte big endian length prefix, like the items of a top-level list.
+// Items are separated by ';', which values do not contain otherwise, unlike ',' and '|',
+// e.g. "multi:1,000;str:a|str:b" has 2 items. Separators within parentheses are part of the item,
+// e.g. "multi:(str:a;b);u32:5" has 2 items, and the parentheses enclosing an item are removed.
+func (vi *ValueInterpreter) interpretMulti(itemsStr string) ([]byte, error) {
+	result := make([]byte, 0)
+	if len(itemsStr) == 0 {
+		return result, nil
+	}
+	items, ok := splitMultiItems(itemsStr)
+	if !ok {
+		return []byte{}, fmt.Errorf("unbalanced parentheses in multi-value list: %s", itemsStr)
+	}
+	for i, item := range items {
+		value, err := vi.interpretString(stripEnclosingParentheses(item))
+		if err != nil {
+			return []byte{}, fmt.Errorf("multi-value item %d: %w", i, err)
+		}
+		lengthPrefix := make([]byte, 4)
+		binary.BigEndian.PutUint32(lengthPrefix, uint32(len(value)))
+		result = append(result, lengthPrefix...)
+		result = append(result, value...)
+	}
+	return result, nil
+}
+
+const multiItemSeparator = ';'
+
+// splitMultiItems splits on the separators outside of parentheses.
+func splitMultiItems(str string) ([]string, bool) {
+	var items []string
+	depth := 0
+	start := 0
+	for i, c := range str {
+		switch {
+		case c == '(':
+			depth++
+		case c == ')':
+			depth--
+			if depth < 0 {
+				return nil, false
+			}
+		case depth == 0 && c == multiItemSeparator:
+			items = append(items, str[start:i])
+			start = i + 1
+		}
+	}
+	if depth != 0 {
+		return nil, false
+	}
+	return append(items, str[start:]), true
+}
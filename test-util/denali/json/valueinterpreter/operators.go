@@ -0,0 +1,272 @@
+package denalivalueinterpreter
+
+import (
+	"bytes"
+	"fmt"
+	"math/big"
+	"strconv"
+	"strings"
+)
+
+func (vi *ValueInterpreter) tryInterpretOperator(strRaw string) (bool, []byte, error) {
+	if strings.HasPrefix(strRaw, LeftPadPrefix) {
+		r, err := vi.interpretPadding(strRaw[len(LeftPadPrefix):], true)
+		return true, r, err
+	}
+	if strings.HasPrefix(strRaw, RightPadPrefix) {
+		r, err := vi.interpretPadding(strRaw[len(RightPadPrefix):], false)
+		return true, r, err
+	}
+	if strings.HasPrefix(strRaw, SlicePrefix) {
+		r, err := vi.interpretSlice(strRaw[len(SlicePrefix):])
+		return true, r, err
+	}
+	if strings.HasPrefix(strRaw, RepeatPrefix) {
+		r, err := vi.interpretRepeat(strRaw[len(RepeatPrefix):])
+		return true, r, err
+	}
+	if strings.HasPrefix(strRaw, BitAndPrefix) {
+		r, err := vi.interpretBitwise(strRaw[len(BitAndPrefix):], func(a, b byte) byte { return a & b })
+		return true, r, err
+	}
+	if strings.HasPrefix(strRaw, BitOrPrefix) {
+		r, err := vi.interpretBitwise(strRaw[len(BitOrPrefix):], func(a, b byte) byte { return a | b })
+		return true, r, err
+	}
+	if strings.HasPrefix(strRaw, BitXorPrefix) {
+		r, err := vi.interpretBitwise(strRaw[len(BitXorPrefix):], func(a, b byte) byte { return a ^ b })
+		return true, r, err
+	}
+	if strings.HasPrefix(strRaw, ShiftLeftPrefix) {
+		r, err := vi.interpretShift(strRaw[len(ShiftLeftPrefix):], true)
+		return true, r, err
+	}
+	if strings.HasPrefix(strRaw, ShiftRightPrefix) {
+		r, err := vi.interpretShift(strRaw[len(ShiftRightPrefix):], false)
+		return true, r, err
+	}
+	return false, []byte{}, nil
+}
+
+// "left-pad:32:expr" and "right-pad:32:expr" pad with zero bytes up to the given length.
+func (vi *ValueInterpreter) interpretPadding(argsStr string, padLeft bool) ([]byte, error) {
+	args, operand, ok := splitOperatorArgs(argsStr, 1)
+	if !ok {
+		return []byte{}, fmt.Errorf("padding requires a length argument: %s", argsStr)
+	}
+	length, err := strconv.Atoi(args[0])
+	if err != nil || length < 0 {
+		return []byte{}, fmt.Errorf("invalid padding length: %s", args[0])
+	}
+	if length > maxRepeatLength {
+		return []byte{}, fmt.Errorf("padding length larger than %d bytes: %s", maxRepeatLength, args[0])
+	}
+
+	value, err := vi.interpretString(operand)
+	if err != nil {
+		return []byte{}, err
+	}
+	if len(value) > length {
+		return []byte{}, fmt.Errorf("value of length %d does not fit in %d bytes: %s", len(value), length, operand)
+	}
+
+	result := make([]byte, length)
+	if padLeft {
+		copy(result[length-len(value):], value)
+	} else {
+		copy(result, value)
+	}
+	return result, nil
+}
+
+// "slice:start:end:expr" yields bytes [start, end) of the operand.
+func (vi *ValueInterpreter) interpretSlice(argsStr string) ([]byte, error) {
+	args, operand, ok := splitOperatorArgs(argsStr, 2)
+	if !ok {
+		return []byte{}, fmt.Errorf("slice requires start and end arguments: %s", argsStr)
+	}
+	start, startErr := strconv.Atoi(args[0])
+	end, endErr := strconv.Atoi(args[1])
+	if startErr != nil || endErr != nil || start < 0 || end < start {
+		return []byte{}, fmt.Errorf("invalid slice bounds: %s:%s", args[0], args[1])
+	}
+
+	value, err := vi.interpretString(operand)
+	if err != nil {
+		return []byte{}, err
+	}
+	if end > len(value) {
+		return []byte{}, fmt.Errorf("slice end %d out of range, value has length %d: %s", end, len(value), operand)
+	}
+	return append([]byte{}, value[start:end]...), nil
+}
+
+// maxRepeatLength is the largest value "repeat:", "left-pad:" and "right-pad:" can yield, in bytes: 64 MiB.
+const maxRepeatLength = 64 * 1024 * 1024
+
+// "repeat:N:expr" yields N copies of the operand.
+func (vi *ValueInterpreter) interpretRepeat(argsStr string) ([]byte, error) {
+	args, operand, ok := splitOperatorArgs(argsStr, 1)
+	if !ok {
+		return []byte{}, fmt.Errorf("repeat requires a count argument: %s", argsStr)
+	}
+	count, err := strconv.Atoi(args[0])
+	if err != nil || count < 0 {
+		return []byte{}, fmt.Errorf("invalid repeat count: %s", args[0])
+	}
+
+	value, err := vi.interpretString(operand)
+	if err != nil {
+		return []byte{}, err
+	}
+	if len(value) > 0 && count > maxRepeatLength/len(value) {
+		return []byte{}, fmt.Errorf("repeated value longer than %d bytes: %d times %s", maxRepeatLength, count, operand)
+	}
+	return bytes.Repeat(value, count), nil
+}
+
+// "bitand:(a),(b),..." and the like combine operands byte by byte.
+// Operands are aligned to the right, as numbers, the shorter ones are padded with zeroes.
+// The result has the length of the longest operand.
+func (vi *ValueInterpreter) interpretBitwise(argsStr string, op func(a, b byte) byte) ([]byte, error) {
+	operandStrs, ok := splitParenthesizedOperands(argsStr)
+	if !ok || len(operandStrs) < 2 {
+		return []byte{}, fmt.Errorf("bitwise operations require at least 2 parenthesized operands, e.g. (u8:1),(u8:2): %s", argsStr)
+	}
+
+	operands := make([][]byte, len(operandStrs))
+	maxLength := 0
+	for i, operandStr := range operandStrs {
+		operand, err := vi.interpretString(operandStr)
+		if err != nil {
+			return []byte{}, err
+		}
+		operands[i] = operand
+		if len(operand) > maxLength {
+			maxLength = len(operand)
+		}
+	}
+
+	result := make([]byte, maxLength)
+	copy(result[maxLength-len(operands[0]):], operands[0])
+	for _, operand := range operands[1:] {
+		offset := maxLength - len(operand)
+		for i := range result {
+			var b byte
+			if i >= offset {
+				b = operand[i-offset]
+			}
+			result[i] = op(result[i], b)
+		}
+	}
+	return result, nil
+}
+
+// maxShiftBits bounds "shl:" and "shr:", so that left shifts stay within the length allowed for "repeat:".
+const maxShiftBits = 8 * maxRepeatLength
+
+// "shl:N:expr" and "shr:N:expr" shift the operand as an unsigned big endian number.
+// Right shifts keep the length of the operand, left shifts extend it if the result does not fit.
+func (vi *ValueInterpreter) interpretShift(argsStr string, left bool) ([]byte, error) {
+	args, operand, ok := splitOperatorArgs(argsStr, 1)
+	if !ok {
+		return []byte{}, fmt.Errorf("shift requires a number of bits: %s", argsStr)
+	}
+	bits, err := strconv.ParseUint(args[0], 10, 32)
+	if err != nil {
+		return []byte{}, fmt.Errorf("invalid number of bits to shift: %s", args[0])
+	}
+	if bits > maxShiftBits {
+		return []byte{}, fmt.Errorf("cannot shift by more than %d bits: %s", maxShiftBits, args[0])
+	}
+
+	value, err := vi.interpretString(operand)
+	if err != nil {
+		return []byte{}, err
+	}
+
+	number := big.NewInt(0).SetBytes(value)
+	if left {
+		number.Lsh(number, uint(bits))
+	} else {
+		number.Rsh(number, uint(bits))
+	}
+
+	resultBytes := number.Bytes()
+	if len(resultBytes) >= len(value) {
+		return resultBytes, nil
+	}
+	result := make([]byte, len(value))
+	copy(result[len(value)-len(resultBytes):], resultBytes)
+	return result, nil
+}
+
+// Splits "(a),(b),(c)" into "a", "b", "c". Nested parentheses are allowed within operands.
+func splitParenthesizedOperands(str string) ([]string, bool) {
+	var operands []string
+	depth := 0
+	start := 0
+	expectOpen := true
+	for i, c := range str {
+		if expectOpen {
+			if c != '(' {
+				return nil, false
+			}
+			expectOpen = false
+			depth = 1
+			start = i + 1
+			continue
+		}
+		switch {
+		case depth == 0:
+			if c != ',' {
+				return nil, false
+			}
+			expectOpen = true
+		case c == '(':
+			depth++
+		case c == ')':
+			depth--
+			if depth == 0 {
+				operands = append(operands, str[start:i])
+			}
+		}
+	}
+	if expectOpen || depth != 0 {
+		return nil, false
+	}
+	return operands, true
+}
+
+// Splits the given number of ':'-separated arguments from the operand.
+// The operand can optionally be enclosed in parentheses.
+func splitOperatorArgs(argsStr string, nrArgs int) ([]string, string, bool) {
+	parts := strings.SplitN(argsStr, ":", nrArgs+1)
+	if len(parts) <= nrArgs {
+		return nil, "", false
+	}
+	return parts[:nrArgs], stripEnclosingParentheses(parts[nrArgs]), true
+}
+
+func stripEnclosingParentheses(str string) string {
+	if len(str) < 2 || str[0] != '(' || str[len(str)-1] != ')' {
+		return str
+	}
+	depth := 0
+	for i, c := range str {
+		switch c {
+		case '(':
+			depth++
+		case ')':
+			depth--
+			if depth == 0 && i < len(str)-1 {
+				// the first parenthesis closes before the end, e.g. "(a)|(b)"
+				return str
+			}
+		}
+	}
+	if depth != 0 {
+		return str
+	}
+	return str[1 : len(str)-1]
+}
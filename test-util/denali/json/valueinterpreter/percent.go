@@ -0,0 +1,33 @@
+package denalivalueinterpreter
+
+import (
+	"fmt"
+	"math/big"
+)
+
+// DefaultPercentScale expresses percentages in basis points: 1% = 100.
+const DefaultPercentScale = 100
+
+// Converts a decimal amount of (1/divisor) percent to an integer, according to the percent scale.
+// E.g. with the default scale, "percent:2.5" and "bp:250" both yield 250.
+func (vi *ValueInterpreter) scaledPercentage(amountStr string, divisor int64) ([]byte, error) {
+	amount, parseOk := new(big.Rat).SetString(amountStr)
+	if !parseOk {
+		return []byte{}, fmt.Errorf("could not parse percentage: %s", amountStr)
+	}
+	if amount.Sign() < 0 {
+		return []byte{}, fmt.Errorf("negative percentage not allowed: %s", amountStr)
+	}
+
+	scale := vi.PercentScale
+	if scale == 0 {
+		scale = DefaultPercentScale
+	}
+	scaled := new(big.Rat).Mul(amount, new(big.Rat).SetFrac(
+		new(big.Int).SetUint64(scale),
+		big.NewInt(divisor)))
+	if !scaled.IsInt() {
+		return []byte{}, fmt.Errorf("percentage %s cannot be represented exactly with scale %d", amountStr, scale)
+	}
+	return scaled.Num().Bytes(), nil
+}
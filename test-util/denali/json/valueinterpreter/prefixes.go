@@ -0,0 +1,168 @@
+package denalivalueinterpreter
+
+import (
+	"strings"
+
+	mj "github.com/numbatx/gn-vm-util/test-util/denali/json/model"
+)
+
+// PrefixInfo describes a prefix, or another special form, of the Denali value format.
+// Editors, linters and the CLI can use it for autocompletion and validation.
+type PrefixInfo struct {
+	// Name is the prefix as written, e.g. "left-pad:", or the whole value for forms without arguments, e.g. "*".
+	Name string
+
+	// Rule names the interpretation rule that handles the prefix, one of the Rule... constants.
+	Rule string
+
+	// Arity is the number of arguments, including the value the prefix applies to, if any.
+	// E.g. 1 for "keccak256:VALUE", 2 for "left-pad:N:VALUE", 0 for "*".
+	// Minimum number of operands for the bitwise operators and "multi:", which accept any number of them.
+	Arity int
+
+	// FormatVersion is the scenario format version that introduced the prefix, see FormatVersionOf.
+	// Zero for the prefixes of the original format.
+	FormatVersion mj.FormatVersion
+
+	// Syntax shows the arguments, e.g. "left-pad:N:VALUE".
+	Syntax string
+
+	Description string
+
+	// CheckOnly is set for values that are only allowed in checks.
+	CheckOnly bool
+
+	// RequiresFileResolver is set for prefixes that load files.
+	RequiresFileResolver bool
+}
+
+var prefixTable = []*PrefixInfo{
+	{Name: StrPrefix, Rule: RuleString, Arity: 1, Syntax: "str:TEXT",
+		Description: "ASCII/UTF-8 string"},
+	{Name: "``", Rule: RuleString, Arity: 1, Syntax: "``TEXT",
+		Description: "ASCII/UTF-8 string, same as str:, deprecated"},
+	{Name: "''", Rule: RuleString, Arity: 1, Syntax: "''TEXT",
+		Description: "ASCII/UTF-8 string, same as str:, deprecated"},
+	{Name: AddressPrefix, Rule: RuleAddress, Arity: 1, Syntax: "address:NAME[#SHARD]",
+		Description: "test address generated from a name, optionally in the given shard"},
+	{Name: FileJSONPrefix, Rule: RuleFile, Arity: 1, Syntax: "file:json:PATH",
+		Description: "JSON file holding a value, interpreted like a JSON subtree", RequiresFileResolver: true,
+		FormatVersion: mj.FormatVersionFileJSON},
+	{Name: FilePrefix, Rule: RuleFile, Arity: 1, Syntax: "file:PATH[#START:END|#LSTART-LEND]",
+		Description: "file contents, optionally only a byte or line range", RequiresFileResolver: true},
+	{Name: CodePrefix, Rule: RuleCode, Arity: 1, Syntax: "code:PATH",
+		Description: "contract code file, checked to be a WASM module", RequiresFileResolver: true,
+		FormatVersion: mj.FormatVersionCode},
+	{Name: Keccak256Prefix, Rule: RuleKeccak256, Arity: 1, Syntax: "keccak256:VALUE",
+		Description: "Keccak-256 hash of the value"},
+	{Name: ABIPrefix, Rule: RuleABI, Arity: 2, Syntax: "abi:TYPE:LITERAL",
+		Description: "typed literal, encoded according to the ABI", FormatVersion: mj.FormatVersionABI},
+	{Name: LeftPadPrefix, Rule: RuleOperator, Arity: 2, Syntax: "left-pad:N:VALUE",
+		Description: "value padded with zeros on the left to N bytes", FormatVersion: mj.FormatVersionByteOperators},
+	{Name: RightPadPrefix, Rule: RuleOperator, Arity: 2, Syntax: "right-pad:N:VALUE",
+		Description: "value padded with zeros on the right to N bytes", FormatVersion: mj.FormatVersionByteOperators},
+	{Name: SlicePrefix, Rule: RuleOperator, Arity: 3, Syntax: "slice:START:END:VALUE",
+		Description: "bytes START to END of the value", FormatVersion: mj.FormatVersionByteOperators},
+	{Name: RepeatPrefix, Rule: RuleOperator, Arity: 2, Syntax: "repeat:N:VALUE",
+		Description: "N copies of the value, e.g. repeat:32:0xff", FormatVersion: mj.FormatVersionByteOperators},
+	{Name: BitAndPrefix, Rule: RuleOperator, Arity: 2, Syntax: "bitand:(VALUE),(VALUE),...",
+		Description:   "bitwise and of two or more values, aligned to the right",
+		FormatVersion: mj.FormatVersionBitOperators},
+	{Name: BitOrPrefix, Rule: RuleOperator, Arity: 2, Syntax: "bitor:(VALUE),(VALUE),...",
+		Description:   "bitwise or of two or more values, aligned to the right",
+		FormatVersion: mj.FormatVersionBitOperators},
+	{Name: BitXorPrefix, Rule: RuleOperator, Arity: 2, Syntax: "bitxor:(VALUE),(VALUE),...",
+		Description:   "bitwise xor of two or more values, aligned to the right",
+		FormatVersion: mj.FormatVersionBitOperators},
+	{Name: ShiftLeftPrefix, Rule: RuleOperator, Arity: 2, Syntax: "shl:N:VALUE",
+		Description: "value shifted left by N bits", FormatVersion: mj.FormatVersionBitOperators},
+	{Name: ShiftRightPrefix, Rule: RuleOperator, Arity: 2, Syntax: "shr:N:VALUE",
+		Description: "value shifted right by N bits", FormatVersion: mj.FormatVersionBitOperators},
+	{Name: MultiPrefix, Rule: RuleMulti, Arity: 1, Syntax: "multi:VALUE;VALUE;...",
+		Description:   "multi-value list, each value with a 4 byte length prefix, like variadic results",
+		FormatVersion: mj.FormatVersionMulti},
+	{Name: ConstPrefix, Rule: RuleConst, Arity: 1, Syntax: "const:NAME",
+		Description: "named constant", FormatVersion: mj.FormatVersionConstants},
+	{Name: DefinePrefix, Rule: RuleDefine, Arity: 1, Syntax: "$NAME",
+		Description: "scenario variable, declared in \"defines\"", FormatVersion: mj.FormatVersionDefines},
+	{Name: PresetPrefix, Rule: RulePreset, Arity: 1, Syntax: "preset:NAME",
+		Description: "named gas preset", FormatVersion: mj.FormatVersionGasPresets},
+	{Name: TokenPrefix, Rule: RuleToken, Arity: 1, Syntax: "token:TICKER[-SUFFIX]",
+		Description:   "token identifier, with a generated suffix if none is given",
+		FormatVersion: mj.FormatVersionTokens},
+	{Name: Base64Prefix, Rule: RuleBase64, Arity: 1, Syntax: "base64:DATA",
+		Description:   "base64 data, standard or URL-safe alphabet, padding optional",
+		FormatVersion: mj.FormatVersionBase64},
+	{Name: PercentPrefix, Rule: RulePercent, Arity: 1, Syntax: "percent:NUMBER",
+		Description: "percentage, scaled according to PercentScale", FormatVersion: mj.FormatVersionPercent},
+	{Name: BasisPointsPrefix, Rule: RulePercent, Arity: 1, Syntax: "bp:NUMBER",
+		Description: "basis points, scaled according to PercentScale", FormatVersion: mj.FormatVersionPercent},
+	{Name: TimestampPrefix, Rule: RuleTime, Arity: 1, Syntax: "timestamp:DATE",
+		Description: "RFC 3339 date-time or YYYY-MM-DD date, as unix seconds", FormatVersion: mj.FormatVersionTime},
+	{Name: DurationPrefix, Rule: RuleTime, Arity: 1, Syntax: "duration:DURATION",
+		Description: "duration such as 3d12h, in seconds", FormatVersion: mj.FormatVersionTime},
+	{Name: U64Prefix, Rule: RuleFixedWidth, Arity: 1, Syntax: "u64:VALUE",
+		Description: "unsigned number or value, 8 bytes"},
+	{Name: U32Prefix, Rule: RuleFixedWidth, Arity: 1, Syntax: "u32:VALUE",
+		Description: "unsigned number or value, 4 bytes"},
+	{Name: U16Prefix, Rule: RuleFixedWidth, Arity: 1, Syntax: "u16:VALUE",
+		Description: "unsigned number or value, 2 bytes"},
+	{Name: U8Prefix, Rule: RuleFixedWidth, Arity: 1, Syntax: "u8:VALUE",
+		Description: "unsigned number or value, 1 byte"},
+	{Name: I64Prefix, Rule: RuleFixedWidth, Arity: 1, Syntax: "i64:VALUE",
+		Description: "signed number or value, 8 bytes"},
+	{Name: I32Prefix, Rule: RuleFixedWidth, Arity: 1, Syntax: "i32:VALUE",
+		Description: "signed number or value, 4 bytes"},
+	{Name: I16Prefix, Rule: RuleFixedWidth, Arity: 1, Syntax: "i16:VALUE",
+		Description: "signed number or value, 2 bytes"},
+	{Name: I8Prefix, Rule: RuleFixedWidth, Arity: 1, Syntax: "i8:VALUE",
+		Description: "signed number or value, 1 byte"},
+	{Name: AnyValueStar, Rule: RuleAny, Arity: 0, Syntax: AnyValueStar,
+		Description: "matches any value", CheckOnly: true},
+	{Name: AnyValueWord, Rule: RuleAny, Arity: 0, Syntax: AnyValueWord,
+		Description: "matches any value, same as *", CheckOnly: true, FormatVersion: mj.FormatVersionAnyValue},
+	{Name: MatchRegexPrefix, Rule: RuleMatch, Arity: 1, Syntax: "match:regex:PATTERN",
+		Description: "matches values by regular expression, bytes as 0x... hex, numbers as decimal", CheckOnly: true,
+		FormatVersion: mj.FormatVersionMatchers},
+	{Name: MatchRangePrefix, Rule: RuleMatch, Arity: 1, Syntax: "match:range:[LOW]..[HIGH]",
+		Description: "matches numbers in an inclusive range, bounds can be left out", CheckOnly: true,
+		FormatVersion: mj.FormatVersionMatchers},
+}
+
+// SupportedPrefixes lists the prefixes and special values that the interpreter accepts, as currently configured.
+// Prefixes that load files are only listed if a FileResolver is set.
+// The result can be modified freely.
+func (vi *ValueInterpreter) SupportedPrefixes() []*PrefixInfo {
+	var result []*PrefixInfo
+	for _, info := range prefixTable {
+		if info.RequiresFileResolver && vi.FileResolver == nil {
+			continue
+		}
+		infoCopy := *info
+		result = append(result, &infoCopy)
+	}
+	return result
+}
+
+// LookupPrefix finds the prefix that an expression starts with, among the supported prefixes.
+// The longest match wins, e.g. "file:json:" over "file:".
+// Yields false for expressions without a prefix, such as numbers, or with an unsupported prefix.
+func (vi *ValueInterpreter) LookupPrefix(expression string) (*PrefixInfo, bool) {
+	found := findPrefix(expression, vi.SupportedPrefixes())
+	return found, found != nil
+}
+
+// findPrefix yields the longest of the prefixes that the expression starts with, nil if there is none.
+func findPrefix(expression string, infos []*PrefixInfo) *PrefixInfo {
+	var found *PrefixInfo
+	for _, info := range infos {
+		matches := strings.HasPrefix(expression, info.Name)
+		if info.Arity == 0 {
+			matches = expression == info.Name
+		}
+		if matches && (found == nil || len(info.Name) > len(found.Name)) {
+			found = info
+		}
+	}
+	return found
+}
@@ -0,0 +1,69 @@
+package denalivalueinterpreter
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"math/big"
+	"strconv"
+	"time"
+)
+
+// durationUnits are the units accepted by "duration:", in seconds.
+var durationUnits = map[byte]uint64{
+	'w': 7 * 24 * 3600,
+	'd': 24 * 3600,
+	'h': 3600,
+	'm': 60,
+	's': 1,
+}
+
+// Converts an RFC 3339 timestamp, e.g. "2024-01-01T00:00:00Z", or a date, e.g. "2024-01-01", to unix seconds.
+// Dates are taken as midnight UTC.
+func timestampSeconds(timestampStr string) ([]byte, error) {
+	timestamp, err := time.Parse(time.RFC3339, timestampStr)
+	if err != nil {
+		timestamp, err = time.Parse("2006-01-02", timestampStr)
+	}
+	if err != nil {
+		return []byte{}, fmt.Errorf("invalid timestamp, expected RFC 3339 format, e.g. 2024-01-01T00:00:00Z: %s", timestampStr)
+	}
+	if timestamp.Nanosecond() != 0 {
+		return []byte{}, fmt.Errorf("timestamp has a fraction of a second: %s", timestampStr)
+	}
+	seconds := timestamp.Unix()
+	if seconds < 0 {
+		return []byte{}, fmt.Errorf("timestamp before 1970: %s", timestampStr)
+	}
+	return big.NewInt(seconds).Bytes(), nil
+}
+
+// Converts a duration such as "3d12h" or "1w" to a number of seconds.
+// Units are w, d, h, m and s, each one preceded by a whole number.
+func durationSeconds(durationStr string) ([]byte, error) {
+	if len(durationStr) == 0 {
+		return []byte{}, errors.New("empty duration")
+	}
+	var total uint64
+	rest := durationStr
+	for len(rest) > 0 {
+		digitCount := 0
+		for digitCount < len(rest) && rest[digitCount] >= '0' && rest[digitCount] <= '9' {
+			digitCount++
+		}
+		if digitCount == 0 || digitCount == len(rest) {
+			return []byte{}, fmt.Errorf("invalid duration, expected e.g. 3d12h: %s", durationStr)
+		}
+		unitSeconds, knownUnit := durationUnits[rest[digitCount]]
+		if !knownUnit {
+			return []byte{}, fmt.Errorf("unknown duration unit %c, expected w, d, h, m or s: %s", rest[digitCount], durationStr)
+		}
+		amount, err := strconv.ParseUint(rest[:digitCount], 10, 64)
+		if err != nil || amount > (math.MaxUint64-total)/unitSeconds {
+			return []byte{}, fmt.Errorf("duration out of range for u64: %s", durationStr)
+		}
+		total += amount * unitSeconds
+		rest = rest[digitCount+1:]
+	}
+	return big.NewInt(0).SetUint64(total).Bytes(), nil
+}
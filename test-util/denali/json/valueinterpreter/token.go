@@ -0,0 +1,68 @@
+package denalivalueinterpreter
+
+import (
+	"encoding/hex"
+	"fmt"
+	"strings"
+)
+
+const tokenTickerMinLength = 3
+const tokenTickerMaxLength = 10
+const tokenSuffixLength = 6
+
+// Produces a token identifier in the canonical "TICKER-abcdef" format.
+// If no suffix is provided, it is derived from the ticker and the interpreter seed.
+func (vi *ValueInterpreter) tokenIdentifier(identifier string) ([]byte, error) {
+	ticker := identifier
+	suffix := ""
+	separatorIndex := strings.Index(identifier, "-")
+	if separatorIndex >= 0 {
+		ticker = identifier[:separatorIndex]
+		suffix = identifier[separatorIndex+1:]
+		if !isTokenSuffix(suffix) {
+			return []byte{}, fmt.Errorf(
+				"invalid token identifier %s: suffix should be %d lowercase hex characters",
+				identifier, tokenSuffixLength)
+		}
+	}
+
+	if !isTokenTicker(ticker) {
+		return []byte{}, fmt.Errorf(
+			"invalid token identifier %s: ticker should be %d to %d uppercase alphanumeric characters",
+			identifier, tokenTickerMinLength, tokenTickerMaxLength)
+	}
+
+	if separatorIndex < 0 {
+		hash, err := vi.cachedKeccak256([]byte(vi.Seed + "|" + ticker))
+		if err != nil {
+			return []byte{}, err
+		}
+		suffix = hex.EncodeToString(hash)[:tokenSuffixLength]
+	}
+
+	return []byte(ticker + "-" + suffix), nil
+}
+
+func isTokenTicker(ticker string) bool {
+	if len(ticker) < tokenTickerMinLength || len(ticker) > tokenTickerMaxLength {
+		return false
+	}
+	for _, c := range ticker {
+		if !((c >= 'A' && c <= 'Z') || (c >= '0' && c <= '9')) {
+			return false
+		}
+	}
+	return true
+}
+
+func isTokenSuffix(suffix string) bool {
+	if len(suffix) != tokenSuffixLength {
+		return false
+	}
+	for _, c := range suffix {
+		if !((c >= 'a' && c <= 'f') || (c >= '0' && c <= '9')) {
+			return false
+		}
+	}
+	return true
+}
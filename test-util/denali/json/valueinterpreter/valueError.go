@@ -0,0 +1,94 @@
+package denalivalueinterpreter
+
+import (
+	"errors"
+	"fmt"
+)
+
+// Names of the interpretation rules, as reported in ValueError.Rule and Explanation.Rule.
+const (
+	RuleFile       = "file"
+	RuleCode       = "code"
+	RuleKeccak256  = "keccak256"
+	RuleABI        = "abi"
+	RuleOperator   = "operator"
+	RuleConst      = "const"
+	RuleDefine     = "define"
+	RulePreset     = "preset"
+	RuleToken      = "token"
+	RuleBase64     = "base64"
+	RulePercent    = "percent"
+	RuleTime       = "time"
+	RuleAddress    = "address"
+	RuleFixedWidth = "fixed-width"
+	RuleNumber     = "number"
+	RuleSubTree    = "subtree"
+	RuleConcat     = "concat"
+	RuleMulti      = "multi"
+	RuleBool       = "bool"
+	RuleString     = "str"
+	RuleAny        = "any"
+	RuleMatch      = "match"
+)
+
+// ValueError describes a value that could not be interpreted.
+// Tooling can use it to point at the exact part of a value that is wrong.
+type ValueError struct {
+	// Expression is the entire value, as written in the scenario.
+	Expression string
+
+	// SubExpression is the innermost part of the expression that could not be interpreted,
+	// e.g. one of several concatenated values. Same as Expression if the whole value is wrong.
+	SubExpression string
+
+	// Rule names the interpretation rule that failed, one of the Rule... constants.
+	Rule string
+
+	Err error
+}
+
+// Error yields the cause, followed by the entire expression if the error is only about part of it.
+func (ve *ValueError) Error() string {
+	if len(ve.Expression) == 0 || ve.Expression == ve.SubExpression {
+		return ve.Err.Error()
+	}
+	return fmt.Sprintf("%s, in \"%s\"", ve.Err.Error(), ve.Expression)
+}
+
+// Unwrap yields the cause.
+func (ve *ValueError) Unwrap() error {
+	return ve.Err
+}
+
+// newValueError wraps an error produced by a rule. Errors already wrapped by a nested rule are kept as they are,
+// so that the innermost sub-expression gets reported.
+func newValueError(rule string, subExpression string, err error) error {
+	if err == nil {
+		return nil
+	}
+	var valueErr *ValueError
+	if errors.As(err, &valueErr) {
+		return err
+	}
+	return &ValueError{
+		SubExpression: subExpression,
+		Rule:          rule,
+		Err:           err,
+	}
+}
+
+// withExpression records the entire expression in the ValueError, once interpretation is done.
+func withExpression(err error, expression string) error {
+	if err == nil {
+		// avoids allocating valueErr, which escapes to errors.As
+		return nil
+	}
+	var valueErr *ValueError
+	if errors.As(err, &valueErr) && len(valueErr.Expression) == 0 {
+		valueErr.Expression = expression
+		if len(valueErr.SubExpression) == 0 {
+			valueErr.SubExpression = expression
+		}
+	}
+	return err
+}
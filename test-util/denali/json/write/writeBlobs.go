@@ -0,0 +1,199 @@
+package denalijsonwrite
+
+import (
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"strings"
+
+	mj "github.com/numbatx/gn-vm-util/test-util/denali/json/model"
+	oj "github.com/numbatx/gn-vm-util/test-util/orderedjson"
+)
+
+// BlobOptions configures ExternalizeBlobs.
+type BlobOptions struct {
+	// MinSize is the size, in bytes, from which values are moved out of the scenario.
+	MinSize int
+
+	// SaveCode stores contract code, see DirectoryCodeSaver.
+	SaveCode CodeSaver
+
+	// SaveValue stores the other values, see DirectoryBlobSaver.
+	SaveValue CodeSaver
+}
+
+// ExternalizeBlobs yields a shallow copy of the scenario where large values written inline are saved separately
+// and referenced as "file:...", to keep scenario files readable: contract code, as ExternalizeCode does,
+// and the values written as raw hex in storage, checked storage, and transaction arguments.
+// Values written in any other form are readable already, and kept. Identical values are only saved once.
+// Both savers are required. The original scenario is not modified.
+func ExternalizeBlobs(scenario *mj.Scenario, options BlobOptions) (*mj.Scenario, error) {
+	if options.SaveCode == nil || options.SaveValue == nil {
+		return nil, errors.New("cannot externalize blobs: both SaveCode and SaveValue are required")
+	}
+	minSize := options.MinSize
+	if minSize < 1 {
+		minSize = 1
+	}
+	result, err := externalizeCode(scenario, minSize, options.SaveCode)
+	if err != nil {
+		return nil, err
+	}
+
+	savedPaths := make(map[string]string)
+	return transformValues(result, func(value mj.JSONBytesFromTree, nameHint string) (mj.JSONBytesFromTree, error) {
+		original, isString := value.Original.(*oj.OJsonString)
+		if !isString || !rawHexValue.MatchString(original.Value) || len(value.Value) < minSize {
+			return value, nil
+		}
+		savedPath, alreadySaved := savedPaths[string(value.Value)]
+		if !alreadySaved {
+			var err error
+			savedPath, err = options.SaveValue(value.Value, nameHint)
+			if err != nil {
+				return value, fmt.Errorf("cannot save value of %s: %w", nameHint, err)
+			}
+			savedPaths[string(value.Value)] = savedPath
+		}
+		return mj.JSONBytesFromTree{
+			Value:    value.Value,
+			Original: &oj.OJsonString{Value: codeFilePrefix + savedPath},
+		}, nil
+	})
+}
+
+// InlineBlobs is the inverse of ExternalizeBlobs: values loaded from a single file, code included,
+// of at most maxSize bytes, are written inline, as hex, so that the scenario is self-contained.
+// The original scenario is not modified.
+func InlineBlobs(scenario *mj.Scenario, maxSize int) *mj.Scenario {
+	result, _ := transformValues(InlineCode(scenario, maxSize),
+		func(value mj.JSONBytesFromTree, _ string) (mj.JSONBytesFromTree, error) {
+			original, isString := value.Original.(*oj.OJsonString)
+			if !isString || !strings.HasPrefix(original.Value, codeFilePrefix) || len(value.Value) > maxSize {
+				return value, nil
+			}
+			return mj.JSONBytesFromTree{
+				Value:    value.Value,
+				Original: &oj.OJsonString{Value: "0x" + hex.EncodeToString(value.Value)},
+			}, nil
+		})
+	return result
+}
+
+type valueTransform func(value mj.JSONBytesFromTree, nameHint string) (mj.JSONBytesFromTree, error)
+
+// transformValues is transformCode, for storage values, checked storage values, and transaction arguments.
+func transformValues(scenario *mj.Scenario, transform valueTransform) (*mj.Scenario, error) {
+	result := *scenario
+	result.Steps = make([]mj.Step, len(scenario.Steps))
+	for i, generalStep := range scenario.Steps {
+		result.Steps[i] = generalStep
+		switch step := generalStep.(type) {
+		case *mj.SetStateStep:
+			var accounts []*mj.Account
+			for j, account := range step.Accounts {
+				storage, err := transformStorage(account.Address, account.Storage, transform)
+				if err != nil {
+					return nil, err
+				}
+				if storage == nil {
+					continue
+				}
+				if accounts == nil {
+					accounts = append([]*mj.Account{}, step.Accounts...)
+				}
+				transformedAccount := *account
+				transformedAccount.Storage = storage
+				accounts[j] = &transformedAccount
+			}
+			if accounts != nil {
+				transformedStep := *step
+				transformedStep.Accounts = accounts
+				result.Steps[i] = &transformedStep
+			}
+		case *mj.CheckStateStep:
+			if step.CheckAccounts == nil {
+				continue
+			}
+			var accounts []*mj.CheckAccount
+			for j, account := range step.CheckAccounts.Accounts {
+				storage, err := transformStorage(account.Address, account.CheckStorage, transform)
+				if err != nil {
+					return nil, err
+				}
+				if storage == nil {
+					continue
+				}
+				if accounts == nil {
+					accounts = append([]*mj.CheckAccount{}, step.CheckAccounts.Accounts...)
+				}
+				transformedAccount := *account
+				transformedAccount.CheckStorage = storage
+				accounts[j] = &transformedAccount
+			}
+			if accounts != nil {
+				checkAccounts := *step.CheckAccounts
+				checkAccounts.Accounts = accounts
+				transformedStep := *step
+				transformedStep.CheckAccounts = &checkAccounts
+				result.Steps[i] = &transformedStep
+			}
+		case *mj.TxStep:
+			if step.Tx == nil {
+				continue
+			}
+			stepName := "step-" + step.TxIdent
+			if len(step.TxIdent) == 0 {
+				stepName = fmt.Sprintf("step-%d", i)
+			}
+			var arguments []mj.JSONBytesFromTree
+			for j, argument := range step.Tx.Arguments {
+				transformed, err := transform(argument, codeNameHint(fmt.Sprintf("%s-argument-%d", stepName, j)))
+				if err != nil {
+					return nil, err
+				}
+				if transformed.Original == argument.Original {
+					continue
+				}
+				if arguments == nil {
+					arguments = append([]mj.JSONBytesFromTree{}, step.Tx.Arguments...)
+				}
+				arguments[j] = transformed
+			}
+			if arguments != nil {
+				transformedTx := *step.Tx
+				transformedTx.Arguments = arguments
+				transformedStep := *step
+				transformedStep.Tx = &transformedTx
+				result.Steps[i] = &transformedStep
+			}
+		}
+	}
+	return &result, nil
+}
+
+// transformStorage yields the transformed storage of an account, nil if no value changed.
+// Values that are only checked to be set, or to match, are left alone.
+func transformStorage(address mj.JSONBytesFromString, storage []*mj.StorageKeyValuePair, transform valueTransform) ([]*mj.StorageKeyValuePair, error) {
+	var result []*mj.StorageKeyValuePair
+	for i, kvp := range storage {
+		if kvp.AnyValue || kvp.Matcher != nil {
+			continue
+		}
+		nameHint := codeNameHint(address.Original + "-" + kvp.Key.Original)
+		transformed, err := transform(kvp.Value, nameHint)
+		if err != nil {
+			return nil, err
+		}
+		if transformed.Original == kvp.Value.Original {
+			continue
+		}
+		if result == nil {
+			result = append([]*mj.StorageKeyValuePair{}, storage...)
+		}
+		transformedKVP := *kvp
+		transformedKVP.Value = transformed
+		result[i] = &transformedKVP
+	}
+	return result, nil
+}
@@ -0,0 +1,227 @@
+package denalijsonwrite
+
+import (
+	"bytes"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+
+	mj "github.com/numbatx/gn-vm-util/test-util/denali/json/model"
+	oj "github.com/numbatx/gn-vm-util/test-util/orderedjson"
+)
+
+const codeFilePrefix = "file:"
+const checkedCodeFilePrefix = "code:"
+
+func isCodeFileReference(original string) bool {
+	return strings.HasPrefix(original, codeFilePrefix) || strings.HasPrefix(original, checkedCodeFilePrefix)
+}
+
+// CodeSaver stores contract code that gets moved out of a scenario,
+// and yields the path that the scenario should reference it by.
+// The name hint is derived from the account address or from the deploy transaction id.
+type CodeSaver func(code []byte, nameHint string) (string, error)
+
+// DirectoryCodeSaver saves code as "<nameHint>.wasm" files in codeDir, relative to the scenario directory.
+// Existing files with different contents are not overwritten, a numeric suffix is added instead.
+func DirectoryCodeSaver(scenarioDir string, codeDir string) CodeSaver {
+	return directorySaver(scenarioDir, codeDir, ".wasm")
+}
+
+// DirectoryBlobSaver is DirectoryCodeSaver, for values other than code, saved as "<nameHint>.bin" files.
+func DirectoryBlobSaver(scenarioDir string, blobDir string) CodeSaver {
+	return directorySaver(scenarioDir, blobDir, ".bin")
+}
+
+func directorySaver(scenarioDir string, dir string, extension string) CodeSaver {
+	return func(code []byte, nameHint string) (string, error) {
+		err := os.MkdirAll(filepath.Join(scenarioDir, dir), os.ModePerm)
+		if err != nil {
+			return "", err
+		}
+		for i := 1; ; i++ {
+			fileName := nameHint + extension
+			if i > 1 {
+				fileName = fmt.Sprintf("%s-%d%s", nameHint, i, extension)
+			}
+			relativePath := path.Join(filepath.ToSlash(dir), fileName)
+			fullPath := filepath.Join(scenarioDir, filepath.FromSlash(relativePath))
+			existing, err := ioutil.ReadFile(fullPath)
+			if err == nil && !bytes.Equal(existing, code) {
+				continue
+			}
+			if err == nil {
+				return relativePath, nil
+			}
+			return relativePath, ioutil.WriteFile(fullPath, code, 0644)
+		}
+	}
+}
+
+// ExternalizeCode yields a shallow copy of the scenario where contract code written inline,
+// in setState and checkState accounts and in scDeploy transactions, is saved separately and referenced as "file:...".
+// Identical code is only saved once. The original scenario is not modified.
+func ExternalizeCode(scenario *mj.Scenario, saveCode CodeSaver) (*mj.Scenario, error) {
+	if saveCode == nil {
+		return nil, errors.New("cannot externalize code: no code saver")
+	}
+	return externalizeCode(scenario, 1, saveCode)
+}
+
+// externalizeCode is ExternalizeCode, only for code of at least minSize bytes.
+func externalizeCode(scenario *mj.Scenario, minSize int, saveCode CodeSaver) (*mj.Scenario, error) {
+	savedPaths := make(map[string]string)
+	return transformCode(scenario, func(code mj.JSONBytesFromString, nameHint string) (mj.JSONBytesFromString, error) {
+		if len(code.Value) == 0 || len(code.Value) < minSize || isCodeFileReference(code.Original) {
+			return code, nil
+		}
+		savedPath, alreadySaved := savedPaths[string(code.Value)]
+		if !alreadySaved {
+			var err error
+			savedPath, err = saveCode(code.Value, nameHint)
+			if err != nil {
+				return code, fmt.Errorf("cannot save code of %s: %w", nameHint, err)
+			}
+			savedPaths[string(code.Value)] = savedPath
+		}
+		return mj.NewJSONBytesFromString(code.Value, codeFilePrefix+savedPath), nil
+	})
+}
+
+// InlineCode yields a shallow copy of the scenario where contract code loaded from files
+// ("file:..." or "code:...") of at most maxSize bytes is written inline, as hex, so that the scenario is self-contained.
+// The original scenario is not modified.
+func InlineCode(scenario *mj.Scenario, maxSize int) *mj.Scenario {
+	result, _ := transformCode(scenario, func(code mj.JSONBytesFromString, _ string) (mj.JSONBytesFromString, error) {
+		if !isCodeFileReference(code.Original) || len(code.Value) > maxSize {
+			return code, nil
+		}
+		return mj.NewJSONBytesFromString(code.Value, "0x"+hex.EncodeToString(code.Value)), nil
+	})
+	return result
+}
+
+// PlaceholderCode yields a shallow copy of the scenario where contract code is replaced by synthetic placeholders,
+// "str:code1", "str:code2" and so on, so that it can be shared without the code. Identical code gets the same placeholder.
+// Also yields the placeholders, by the original form of the code they replace. The original scenario is not modified.
+func PlaceholderCode(scenario *mj.Scenario) (*mj.Scenario, map[string]string) {
+	placeholders := make(map[string]string)
+	replacements := make(map[string]string)
+	result, _ := transformCode(scenario, func(code mj.JSONBytesFromString, _ string) (mj.JSONBytesFromString, error) {
+		if len(code.Value) == 0 {
+			return code, nil
+		}
+		placeholder, found := placeholders[string(code.Value)]
+		if !found {
+			placeholder = fmt.Sprintf("code%d", len(placeholders)+1)
+			placeholders[string(code.Value)] = placeholder
+		}
+		replacements[code.Original] = "str:" + placeholder
+		return mj.NewJSONBytesFromString([]byte(placeholder), "str:"+placeholder), nil
+	})
+	return result, replacements
+}
+
+type codeTransform func(code mj.JSONBytesFromString, nameHint string) (mj.JSONBytesFromString, error)
+
+// transformCode copies only the steps, accounts and transactions whose code changes.
+func transformCode(scenario *mj.Scenario, transform codeTransform) (*mj.Scenario, error) {
+	result := *scenario
+	result.Steps = make([]mj.Step, len(scenario.Steps))
+	for i, generalStep := range scenario.Steps {
+		result.Steps[i] = generalStep
+		switch step := generalStep.(type) {
+		case *mj.SetStateStep:
+			var accounts []*mj.Account
+			for j, account := range step.Accounts {
+				code, err := transform(account.Code, codeNameHint(account.Address.Original))
+				if err != nil {
+					return nil, err
+				}
+				if code.Original == account.Code.Original {
+					continue
+				}
+				if accounts == nil {
+					accounts = append([]*mj.Account{}, step.Accounts...)
+				}
+				transformedAccount := *account
+				transformedAccount.Code = code
+				accounts[j] = &transformedAccount
+			}
+			if accounts != nil {
+				transformedStep := *step
+				transformedStep.Accounts = accounts
+				result.Steps[i] = &transformedStep
+			}
+		case *mj.CheckStateStep:
+			if step.CheckAccounts == nil {
+				continue
+			}
+			var accounts []*mj.CheckAccount
+			for j, account := range step.CheckAccounts.Accounts {
+				original, isString := account.Code.Original.(*oj.OJsonString)
+				if account.Code.IsStar || !isString {
+					continue
+				}
+				code, err := transform(
+					mj.NewJSONBytesFromString(account.Code.Value, original.Value),
+					codeNameHint(account.Address.Original))
+				if err != nil {
+					return nil, err
+				}
+				if code.Original == original.Value {
+					continue
+				}
+				if accounts == nil {
+					accounts = append([]*mj.CheckAccount{}, step.CheckAccounts.Accounts...)
+				}
+				transformedAccount := *account
+				transformedAccount.Code.Original = &oj.OJsonString{Value: code.Original}
+				accounts[j] = &transformedAccount
+			}
+			if accounts != nil {
+				checkAccounts := *step.CheckAccounts
+				checkAccounts.Accounts = accounts
+				transformedStep := *step
+				transformedStep.CheckAccounts = &checkAccounts
+				result.Steps[i] = &transformedStep
+			}
+		case *mj.TxStep:
+			if step.Tx == nil || step.Tx.Type != mj.ScDeploy {
+				continue
+			}
+			nameHint := "deploy-" + step.TxIdent
+			if len(step.TxIdent) == 0 {
+				nameHint = fmt.Sprintf("deploy-step-%d", i)
+			}
+			code, err := transform(step.Tx.Code, codeNameHint(nameHint))
+			if err != nil {
+				return nil, err
+			}
+			if code.Original != step.Tx.Code.Original {
+				transformedTx := *step.Tx
+				transformedTx.Code = code
+				transformedStep := *step
+				transformedStep.Tx = &transformedTx
+				result.Steps[i] = &transformedStep
+			}
+		}
+	}
+	return &result, nil
+}
+
+// codeNameHint turns "address:my-contract" into "my-contract", and replaces characters that do not belong in file names.
+func codeNameHint(name string) string {
+	name = strings.TrimPrefix(name, "address:")
+	return strings.Map(func(r rune) rune {
+		if (r >= 'a' && r <= 'z') || (r >= 'A' && r <= 'Z') || (r >= '0' && r <= '9') || r == '-' || r == '_' {
+			return r
+		}
+		return '_'
+	}, name)
+}
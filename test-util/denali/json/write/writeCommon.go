@@ -8,6 +8,26 @@ import (
 	oj "github.com/numbatx/gn-vm-util/test-util/orderedjson"
 )
 
+func metadataToOJ(metadata *mj.ScenarioMetadata) oj.OJsonObject {
+	metadataOJ := oj.NewMap()
+	if len(metadata.Owner) > 0 {
+		metadataOJ.Put("owner", stringToOJ(metadata.Owner))
+	}
+	if len(metadata.License) > 0 {
+		metadataOJ.Put("license", stringToOJ(metadata.License))
+	}
+	if len(metadata.Tags) > 0 {
+		metadataOJ.Put("tags", stringListToOJ(metadata.Tags))
+	}
+	if len(metadata.Flavor) > 0 {
+		metadataOJ.Put("flavor", stringToOJ(metadata.Flavor))
+	}
+	if len(metadata.Priority.Original) > 0 {
+		metadataOJ.Put("priority", uint64ToOJ(metadata.Priority))
+	}
+	return metadataOJ
+}
+
 func accountsToOJ(accounts []*mj.Account) oj.OJsonObject {
 	acctsOJ := oj.NewMap()
 	for _, account := range accounts {
@@ -33,6 +53,28 @@ func accountsToOJ(accounts []*mj.Account) oj.OJsonObject {
 	return acctsOJ
 }
 
+// explicitAccounts filters out the accounts expanded from a generateAccounts template,
+// which gets written instead.
+func explicitAccounts(accounts []*mj.Account) []*mj.Account {
+	var explicit []*mj.Account
+	for _, account := range accounts {
+		if !account.Generated {
+			explicit = append(explicit, account)
+		}
+	}
+	return explicit
+}
+
+func generateAccountsToOJ(generate *mj.GenerateAccounts) oj.OJsonObject {
+	generateOJ := oj.NewMap()
+	generateOJ.Put("count", uint64ToOJ(generate.Count))
+	generateOJ.Put("prefix", stringToOJ(generate.Prefix))
+	if len(generate.Balance.Original) > 0 {
+		generateOJ.Put("balance", bigIntToOJ(generate.Balance))
+	}
+	return generateOJ
+}
+
 func checkAccountsToOJ(checkAccounts *mj.CheckAccounts) oj.OJsonObject {
 	acctsOJ := oj.NewMap()
 	for _, checkAccount := range checkAccounts.Accounts {
@@ -216,3 +258,12 @@ func checkUint64ToOJ(i mj.JSONCheckUint64) oj.OJsonObject {
 func stringToOJ(str string) oj.OJsonObject {
 	return &oj.OJsonString{Value: str}
 }
+
+func stringListToOJ(strs []string) oj.OJsonObject {
+	var strsOJ []oj.OJsonObject
+	for _, str := range strs {
+		strsOJ = append(strsOJ, stringToOJ(str))
+	}
+	strList := oj.OJsonList(strsOJ)
+	return &strList
+}
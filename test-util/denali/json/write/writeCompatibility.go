@@ -0,0 +1,322 @@
+package denalijsonwrite
+
+import (
+	"fmt"
+
+	mj "github.com/numbatx/gn-vm-util/test-util/denali/json/model"
+	vi "github.com/numbatx/gn-vm-util/test-util/denali/json/valueinterpreter"
+)
+
+// ExternalStepsLoader loads the scenario referenced by an externalSteps step.
+type ExternalStepsLoader func(path string) (*mj.Scenario, error)
+
+// CompatibilityOptions configures how a scenario gets downgraded for older parsers.
+type CompatibilityOptions struct {
+	// TargetVersion is the newest format version the consumer of the output can parse.
+	TargetVersion mj.FormatVersion
+
+	// LoadExternalSteps is required when externalSteps need to be inlined.
+	LoadExternalSteps ExternalStepsLoader
+}
+
+// ScenarioToCompatibleJSONString converts a scenario object to a JSON representation
+// that only uses features available in the target format version.
+func ScenarioToCompatibleJSONString(scenario *mj.Scenario, options CompatibilityOptions) (string, error) {
+	compatible, err := DowngradeScenario(scenario, options)
+	if err != nil {
+		return "", err
+	}
+	return ScenarioToJSONString(compatible), nil
+}
+
+// DowngradeScenario yields a shallow copy of the scenario, rewritten to only use features
+// available in the target format version. The original scenario is not modified.
+// Values and matchers using syntax newer than the target version make it fail, e.g. "token:" or "any".
+func DowngradeScenario(scenario *mj.Scenario, options CompatibilityOptions) (*mj.Scenario, error) {
+	if !options.TargetVersion.IsValid() {
+		return nil, fmt.Errorf("unknown target format version: %d", options.TargetVersion)
+	}
+
+	result := *scenario
+	if options.TargetVersion < mj.FormatVersionConstants && len(scenario.Constants) > 0 {
+		return nil, fmt.Errorf("scenario constants cannot be expressed in format version %d", options.TargetVersion)
+	}
+	if options.TargetVersion < mj.FormatVersionABI && len(scenario.ABIPath) > 0 {
+		return nil, fmt.Errorf("scenario ABI references cannot be expressed in format version %d", options.TargetVersion)
+	}
+	if options.TargetVersion < mj.FormatVersionGasSchedule && scenario.GasSchedule != nil {
+		// dropping it would check gas against whatever schedule the executor defaults to
+		return nil, fmt.Errorf("scenario gas schedule cannot be expressed in format version %d", options.TargetVersion)
+	}
+	if options.TargetVersion < mj.FormatVersionConfig && scenario.Config != nil &&
+		(len(scenario.Config.ChainID.Original) > 0 || len(scenario.Config.Seed) > 0) {
+		// the seed changes generated values, the chain ID what executors run with
+		return nil, fmt.Errorf("scenario config chainID and seed cannot be expressed in format version %d", options.TargetVersion)
+	}
+	if options.TargetVersion < mj.FormatVersionGasPresets && len(scenario.GasPresets) > 0 {
+		return nil, fmt.Errorf("scenario gas presets cannot be expressed in format version %d", options.TargetVersion)
+	}
+	if options.TargetVersion < mj.FormatVersionTransferData && hasTransferData(scenario.Steps) {
+		return nil, fmt.Errorf("transfer data cannot be expressed in format version %d", options.TargetVersion)
+	}
+	if options.TargetVersion < mj.FormatVersionStepParameters &&
+		(len(scenario.Parameters) > 0 || hasExternalStepsArguments(scenario.Steps)) {
+		return nil, fmt.Errorf("externalSteps parameters cannot be expressed in format version %d", options.TargetVersion)
+	}
+	if options.TargetVersion < mj.FormatVersionComments {
+		// only informative, safe to drop
+		result.Comments = nil
+	}
+	if options.TargetVersion < mj.FormatVersionDefines && len(scenario.Defines) > 0 {
+		return nil, fmt.Errorf("scenario defines cannot be expressed in format version %d", options.TargetVersion)
+	}
+	if options.TargetVersion < mj.FormatVersionInvariants && len(scenario.Invariants) > 0 {
+		return nil, fmt.Errorf("scenario invariants cannot be expressed in format version %d", options.TargetVersion)
+	}
+	if options.TargetVersion < mj.FormatVersionAllowedErrors && hasAllowedErrors(scenario.Steps) {
+		// dropping them would make the steps stricter
+		return nil, fmt.Errorf("setState allowedErrors cannot be expressed in format version %d", options.TargetVersion)
+	}
+	if options.TargetVersion < mj.FormatVersionPriority && scenario.Metadata != nil &&
+		len(scenario.Metadata.Priority.Original) > 0 {
+		// only a hint for runners, safe to drop
+		metadata := *scenario.Metadata
+		metadata.Priority = mj.JSONUint64{}
+		result.Metadata = &metadata
+	}
+	if options.TargetVersion < mj.FormatVersionViewScenarios {
+		if hasQuerySteps(scenario.Steps) {
+			return nil, fmt.Errorf("scQuery steps cannot be expressed in format version %d", options.TargetVersion)
+		}
+		if result.Metadata != nil && len(result.Metadata.Flavor) > 0 {
+			metadata := *result.Metadata
+			metadata.Flavor = ""
+			result.Metadata = &metadata
+		}
+	}
+	if options.TargetVersion < mj.FormatVersionMetadata {
+		// only informative, safe to drop
+		result.Metadata = nil
+	}
+	if options.TargetVersion < mj.FormatVersionSchemaVersion {
+		result.SchemaVersion = 0
+	} else if result.SchemaVersion > options.TargetVersion {
+		result.SchemaVersion = options.TargetVersion
+	}
+	if options.TargetVersion < mj.FormatVersionRequiresFormatVersion {
+		result.RequiresFormatVersion = 0
+	} else if result.RequiresFormatVersion > options.TargetVersion {
+		result.RequiresFormatVersion = options.TargetVersion
+	}
+	if options.TargetVersion < mj.FormatVersionExternalSteps {
+		var err error
+		result.Steps, err = inlineExternalSteps(scenario.Steps, options.LoadExternalSteps, nil)
+		if err != nil {
+			return nil, err
+		}
+	}
+	if options.TargetVersion < mj.FormatVersionGenerateAccounts {
+		result.Steps = writeGeneratedAccountsExplicitly(result.Steps)
+	}
+	if options.TargetVersion < mj.FormatVersionAutoNonces && scenario.AutoNonces {
+		result.AutoNonces = false
+		result.Steps = writeAssignedNoncesExplicitly(result.Steps)
+	}
+	if options.TargetVersion < mj.FormatVersionConfig && scenario.Config != nil {
+		result.Config = nil
+		result.Steps = writeConfigDefaultsExplicitly(result.Steps)
+	}
+	if options.TargetVersion < mj.FormatVersionStepMetadata {
+		// only informative, safe to drop, also from the steps inlined above
+		result.Steps = dropStepMetadata(result.Steps)
+	}
+
+	// values cannot be rewritten, they are written as they were, matchers have no exact value to write instead
+	version, syntax, _ := vi.FormatVersionOfSubTree(ScenarioToOrderedJSON(&result))
+	if version > options.TargetVersion {
+		return nil, fmt.Errorf("values using %s cannot be expressed in format version %d, they need format version %d",
+			syntax, options.TargetVersion, version)
+	}
+
+	return &result, nil
+}
+
+// dropStepMetadata drops the step ids and tags, and the comments of externalSteps,
+// which older format versions do not have.
+func dropStepMetadata(steps []mj.Step) []mj.Step {
+	result := make([]mj.Step, len(steps))
+	for i, generalStep := range steps {
+		result[i] = generalStep
+		metadata := mj.StepMetadataOf(generalStep)
+		if metadata == nil || (len(metadata.ID) == 0 && len(metadata.Tags) == 0 && !isExternalSteps(generalStep)) {
+			continue
+		}
+		var stripped mj.Step
+		switch step := generalStep.(type) {
+		case *mj.ExternalStepsStep:
+			copied := *step
+			copied.Comment = ""
+			stripped = &copied
+		case *mj.SetStateStep:
+			copied := *step
+			stripped = &copied
+		case *mj.CheckStateStep:
+			copied := *step
+			stripped = &copied
+		case *mj.DumpStateStep:
+			copied := *step
+			stripped = &copied
+		case *mj.TxStep:
+			copied := *step
+			stripped = &copied
+		default:
+			continue
+		}
+		strippedMetadata := mj.StepMetadataOf(stripped)
+		strippedMetadata.ID = ""
+		strippedMetadata.Tags = nil
+		result[i] = stripped
+	}
+	return result
+}
+
+func isExternalSteps(step mj.Step) bool {
+	_, isExternal := step.(*mj.ExternalStepsStep)
+	return isExternal
+}
+
+// writeGeneratedAccountsExplicitly drops the generateAccounts templates,
+// so that the accounts expanded from them get written out one by one.
+func writeGeneratedAccountsExplicitly(steps []mj.Step) []mj.Step {
+	result := make([]mj.Step, len(steps))
+	for i, generalStep := range steps {
+		result[i] = generalStep
+		setStateStep, isSetState := generalStep.(*mj.SetStateStep)
+		if isSetState && setStateStep.GenerateAccounts != nil {
+			explicitStep := *setStateStep
+			explicitStep.GenerateAccounts = nil
+			result[i] = &explicitStep
+		}
+	}
+	return result
+}
+
+// writeConfigDefaultsExplicitly writes the transaction fields that the parser filled in from the scenario config.
+func writeConfigDefaultsExplicitly(steps []mj.Step) []mj.Step {
+	result := make([]mj.Step, len(steps))
+	for i, generalStep := range steps {
+		result[i] = generalStep
+		txStep, isTx := generalStep.(*mj.TxStep)
+		if isTx && txStep.Tx != nil && (txStep.Tx.FromConfig || txStep.Tx.GasPriceConfig) {
+			explicitTx := *txStep.Tx
+			explicitTx.FromConfig = false
+			explicitTx.GasPriceConfig = false
+			explicitStep := *txStep
+			explicitStep.Tx = &explicitTx
+			result[i] = &explicitStep
+		}
+	}
+	return result
+}
+
+// FreezeAssignedNonces yields a copy of a scenario with the nonces assigned by the parser written explicitly,
+// for tools that remove steps from scenarios with autoNonces, which would otherwise get different nonces.
+func FreezeAssignedNonces(scenario *mj.Scenario) *mj.Scenario {
+	if !scenario.AutoNonces {
+		return scenario
+	}
+	result := *scenario
+	result.Steps = writeAssignedNoncesExplicitly(scenario.Steps)
+	return &result
+}
+
+// writeAssignedNoncesExplicitly gives the nonces assigned by the parser an original form,
+// so that they get written out.
+func writeAssignedNoncesExplicitly(steps []mj.Step) []mj.Step {
+	result := make([]mj.Step, len(steps))
+	for i, generalStep := range steps {
+		result[i] = generalStep
+		txStep, isTx := generalStep.(*mj.TxStep)
+		if isTx && txStep.Tx != nil && txStep.Tx.Type.HasSender() && len(txStep.Tx.Nonce.Original) == 0 {
+			explicitTx := *txStep.Tx
+			explicitTx.Nonce.Original = fmt.Sprintf("%d", explicitTx.Nonce.Value)
+			explicitStep := *txStep
+			explicitStep.Tx = &explicitTx
+			result[i] = &explicitStep
+		}
+	}
+	return result
+}
+
+func hasTransferData(steps []mj.Step) bool {
+	for _, generalStep := range steps {
+		txStep, isTx := generalStep.(*mj.TxStep)
+		if isTx && txStep.Tx != nil && txStep.Tx.Data.Original != nil {
+			return true
+		}
+	}
+	return false
+}
+
+func hasAllowedErrors(steps []mj.Step) bool {
+	for _, generalStep := range steps {
+		setStateStep, isSetState := generalStep.(*mj.SetStateStep)
+		if isSetState && len(setStateStep.AllowedErrors) > 0 {
+			return true
+		}
+	}
+	return false
+}
+
+func hasExternalStepsArguments(steps []mj.Step) bool {
+	for _, generalStep := range steps {
+		externalStep, isExternal := generalStep.(*mj.ExternalStepsStep)
+		if isExternal && len(externalStep.Arguments) > 0 {
+			return true
+		}
+	}
+	return false
+}
+
+func hasQuerySteps(steps []mj.Step) bool {
+	for _, step := range steps {
+		if step.StepTypeName() == mj.StepNameScQuery {
+			return true
+		}
+	}
+	return false
+}
+
+func inlineExternalSteps(steps []mj.Step, loader ExternalStepsLoader, includeStack []string) ([]mj.Step, error) {
+	var result []mj.Step
+	for _, generalStep := range steps {
+		externalStep, isExternal := generalStep.(*mj.ExternalStepsStep)
+		if !isExternal {
+			result = append(result, generalStep)
+			continue
+		}
+
+		if loader == nil {
+			return nil, fmt.Errorf("cannot inline externalSteps %s: no loader provided", externalStep.Path)
+		}
+		for _, includedPath := range includeStack {
+			if includedPath == externalStep.Path {
+				return nil, fmt.Errorf("cyclic externalSteps reference: %s", externalStep.Path)
+			}
+		}
+
+		externalScenario, err := loader(externalStep.Path)
+		if err != nil {
+			return nil, fmt.Errorf("cannot load externalSteps %s: %w", externalStep.Path, err)
+		}
+		inlined, err := inlineExternalSteps(
+			externalScenario.Steps,
+			loader,
+			append(includeStack, externalStep.Path))
+		if err != nil {
+			return nil, err
+		}
+		result = append(result, inlined...)
+	}
+	return result, nil
+}
@@ -0,0 +1,379 @@
+package denalijsonwrite
+
+import (
+	"fmt"
+	"go/format"
+	"math/big"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+
+	mj "github.com/numbatx/gn-vm-util/test-util/denali/json/model"
+	oj "github.com/numbatx/gn-vm-util/test-util/orderedjson"
+)
+
+// GoTestOptions configures ScenarioToGoTest.
+type GoTestOptions struct {
+	// PackageName is the package of the generated file. Defaults to "scenarios".
+	PackageName string
+
+	// TestName is the name of the generated test function. Defaults to "Test" followed by the scenario name,
+	// in camel case, or to "TestScenario" for scenarios without a name.
+	TestName string
+
+	// RunnerFunction is the function of the test package that yields the runner, given the *testing.T,
+	// typically with the executor of the VM under test. Defaults to "newScenarioRunner".
+	RunnerFunction string
+
+	// ScenarioPath is the path of the JSON scenario, relative to the test package.
+	// The scenario runs with the file resolver pointing there, e.g. for externalSteps.
+	ScenarioPath string
+}
+
+// goTestSkippedFields are the scenario fields that only matter to the parser and the writer.
+// The ABI is not generated either, only its path.
+var goTestSkippedFields = map[string]bool{
+	"ABI":           true,
+	"Comments":      true,
+	"InlinedFiles":  true,
+	"Source":        true,
+	"StepPositions": true,
+}
+
+// ScenarioToGoTest generates a Go test that builds the scenario in code, as model structs,
+// and runs it with the runner of the test package, see ScenarioRunner.RunScenario. Meant for teams
+// moving from JSON scenarios to native Go tests: the generated code is a starting point, to be edited from then on.
+// The values keep their original forms, so that the scenario can still be written back as JSON.
+// Scenarios with values that cannot be written in Go, e.g. "match:" checks, yield an error.
+func ScenarioToGoTest(scenario *mj.Scenario, options GoTestOptions) (string, error) {
+	if len(options.PackageName) == 0 {
+		options.PackageName = "scenarios"
+	}
+	if len(options.TestName) == 0 {
+		options.TestName = "Test" + goIdentifier(scenario.Name, "Scenario")
+	}
+	if len(options.RunnerFunction) == 0 {
+		options.RunnerFunction = "newScenarioRunner"
+	}
+
+	g := &goWriter{imports: make(map[string]bool)}
+	scenarioLiteral, err := g.scenario(scenario)
+	if err != nil {
+		return "", err
+	}
+
+	var sb strings.Builder
+	if len(options.ScenarioPath) > 0 {
+		fmt.Fprintf(&sb, "// Code generated from %s. Edit as needed, it is not regenerated.\n\n", options.ScenarioPath)
+	}
+	fmt.Fprintf(&sb, "package %s\n\n", options.PackageName)
+	sb.WriteString("import (\n")
+	if g.imports["math/big"] {
+		sb.WriteString("\t\"math/big\"\n")
+	}
+	sb.WriteString("\t\"testing\"\n\n")
+	fmt.Fprintf(&sb, "\tmj %s\n", strconv.Quote(goModelPackage))
+	if g.imports[goOJPackage] {
+		fmt.Fprintf(&sb, "\toj %s\n", strconv.Quote(goOJPackage))
+	}
+	sb.WriteString(")\n\n")
+	fmt.Fprintf(&sb, "func %s(t *testing.T) {\n", options.TestName)
+	fmt.Fprintf(&sb, "\tscenario := %s\n", scenarioLiteral)
+	fmt.Fprintf(&sb, "\terr := %s(t).RunScenario(%s, scenario)\n", options.RunnerFunction, strconv.Quote(options.ScenarioPath))
+	sb.WriteString("\tif err != nil {\n\t\tt.Fatal(err)\n\t}\n}\n")
+
+	formatted, err := format.Source([]byte(sb.String()))
+	if err != nil {
+		return "", fmt.Errorf("generated code does not compile: %w", err)
+	}
+	return string(formatted), nil
+}
+
+var (
+	goModelPackage = reflect.TypeOf(mj.Scenario{}).PkgPath()
+	goOJPackage    = reflect.TypeOf(oj.OJsonString{}).PkgPath()
+	goBigIntType   = reflect.TypeOf(big.Int{})
+)
+
+// goTransactionTypes names the transaction types, so that generated code reads like hand-written code.
+var goTransactionTypes = map[mj.TransactionType]string{
+	mj.ScDeploy:        "mj.ScDeploy",
+	mj.ScCall:          "mj.ScCall",
+	mj.Transfer:        "mj.Transfer",
+	mj.ValidatorReward: "mj.ValidatorReward",
+	mj.ScQuery:         "mj.ScQuery",
+}
+
+// goPackageNames are the packages that generated code can reference, by the names they are imported as.
+var goPackageNames = map[string]string{
+	goModelPackage: "mj",
+	goOJPackage:    "oj",
+	"math/big":     "big",
+}
+
+// goWriter writes values as Go expressions, collecting the packages they reference.
+type goWriter struct {
+	imports map[string]bool
+}
+
+func (g *goWriter) scenario(scenario *mj.Scenario) (string, error) {
+	value := reflect.ValueOf(scenario).Elem()
+	var sb strings.Builder
+	sb.WriteString("&mj.Scenario{\n")
+	for i := 0; i < value.NumField(); i++ {
+		field := value.Type().Field(i)
+		if goTestSkippedFields[field.Name] || value.Field(i).IsZero() {
+			continue
+		}
+		fieldLiteral, err := g.value(value.Field(i), field.Name)
+		if err != nil {
+			return "", err
+		}
+		fmt.Fprintf(&sb, "%s: %s,\n", field.Name, fieldLiteral)
+	}
+	sb.WriteString("}")
+	return sb.String(), nil
+}
+
+// value writes any value of the model, the path naming it in errors.
+func (g *goWriter) value(v reflect.Value, path string) (string, error) {
+	switch v.Kind() {
+	case reflect.Bool:
+		return g.converted(v.Type(), strconv.FormatBool(v.Bool()))
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		if txType, isTxType := v.Interface().(mj.TransactionType); isTxType && len(goTransactionTypes[txType]) > 0 {
+			return goTransactionTypes[txType], nil
+		}
+		return g.converted(v.Type(), strconv.FormatInt(v.Int(), 10))
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return g.converted(v.Type(), strconv.FormatUint(v.Uint(), 10))
+	case reflect.String:
+		return g.converted(v.Type(), strconv.Quote(v.String()))
+	case reflect.Slice:
+		if v.IsNil() {
+			return "nil", nil
+		}
+		if v.Type().Elem().Kind() == reflect.Uint8 && v.Type().Elem().PkgPath() == "" {
+			return g.converted(v.Type(), strconv.Quote(string(v.Bytes())))
+		}
+		return g.elements(v, path)
+	case reflect.Array:
+		return g.elements(v, path)
+	case reflect.Map:
+		return g.mapLiteral(v, path)
+	case reflect.Ptr:
+		if v.IsNil() {
+			return "nil", nil
+		}
+		if v.Elem().Type() == goBigIntType {
+			return g.bigInt(v.Interface().(*big.Int)), nil
+		}
+		elem, err := g.value(v.Elem(), path)
+		if err != nil {
+			return "", err
+		}
+		if v.Elem().Kind() == reflect.Struct {
+			return "&" + elem, nil
+		}
+		// no literal to take the address of
+		typeName, err := g.typeName(v.Elem().Type(), path)
+		if err != nil {
+			return "", err
+		}
+		return fmt.Sprintf("func() *%s { v := %s; return &v }()", typeName, elem), nil
+	case reflect.Interface:
+		if v.IsNil() {
+			return "nil", nil
+		}
+		return g.value(v.Elem(), path)
+	case reflect.Struct:
+		return g.structLiteral(v, path)
+	default:
+		return "", fmt.Errorf("%s cannot be written in Go, it is a %s", path, v.Type())
+	}
+}
+
+// converted writes a basic value, converted to its type if it is a named one, e.g. mj.FormatVersion(21).
+func (g *goWriter) converted(t reflect.Type, literal string) (string, error) {
+	if len(t.Name()) == 0 || len(t.PkgPath()) == 0 {
+		if t.Kind() == reflect.Slice {
+			// []byte
+			return "[]byte(" + literal + ")", nil
+		}
+		return literal, nil
+	}
+	typeName, err := g.typeName(t, t.String())
+	if err != nil {
+		return "", err
+	}
+	return fmt.Sprintf("%s(%s)", typeName, literal), nil
+}
+
+func (g *goWriter) structLiteral(v reflect.Value, path string) (string, error) {
+	typeName, err := g.typeName(v.Type(), path)
+	if err != nil {
+		return "", err
+	}
+	var sb strings.Builder
+	sb.WriteString(typeName + "{")
+	for i := 0; i < v.NumField(); i++ {
+		field := v.Type().Field(i)
+		if v.Field(i).IsZero() {
+			continue
+		}
+		if !field.IsExported() {
+			return "", fmt.Errorf("%s cannot be written in Go, %s has unexported state", path, v.Type())
+		}
+		fieldLiteral, err := g.value(v.Field(i), path+"."+field.Name)
+		if err != nil {
+			return "", err
+		}
+		fmt.Fprintf(&sb, "\n%s: %s,", field.Name, fieldLiteral)
+	}
+	if strings.HasSuffix(sb.String(), ",") {
+		sb.WriteString("\n")
+	}
+	sb.WriteString("}")
+	return sb.String(), nil
+}
+
+func (g *goWriter) elements(v reflect.Value, path string) (string, error) {
+	typeName, err := g.typeName(v.Type(), path)
+	if err != nil {
+		return "", err
+	}
+	var sb strings.Builder
+	sb.WriteString(typeName + "{")
+	for i := 0; i < v.Len(); i++ {
+		elem, err := g.value(v.Index(i), fmt.Sprintf("%s[%d]", path, i))
+		if err != nil {
+			return "", err
+		}
+		fmt.Fprintf(&sb, "\n%s,", g.elideType(elem, v.Type().Elem()))
+	}
+	if v.Len() > 0 {
+		sb.WriteString("\n")
+	}
+	sb.WriteString("}")
+	return sb.String(), nil
+}
+
+// mapLiteral writes the entries sorted by key, so that the generated code does not change from one run to the next.
+func (g *goWriter) mapLiteral(v reflect.Value, path string) (string, error) {
+	if v.IsNil() {
+		return "nil", nil
+	}
+	typeName, err := g.typeName(v.Type(), path)
+	if err != nil {
+		return "", err
+	}
+	var entries []string
+	for _, key := range v.MapKeys() {
+		keyLiteral, err := g.value(key, path)
+		if err != nil {
+			return "", err
+		}
+		valueLiteral, err := g.value(v.MapIndex(key), fmt.Sprintf("%s[%v]", path, key.Interface()))
+		if err != nil {
+			return "", err
+		}
+		entries = append(entries, fmt.Sprintf("\n%s: %s,", keyLiteral, g.elideType(valueLiteral, v.Type().Elem())))
+	}
+	sort.Strings(entries)
+	if len(entries) > 0 {
+		entries = append(entries, "\n")
+	}
+	return typeName + "{" + strings.Join(entries, "") + "}", nil
+}
+
+// elideType leaves out the type of the elements of slices and maps, where Go allows it, e.g. "&mj.Account{...}".
+func (g *goWriter) elideType(literal string, elemType reflect.Type) string {
+	structType, prefix := elemType, ""
+	if elemType.Kind() == reflect.Ptr {
+		structType, prefix = elemType.Elem(), "&"
+	}
+	if structType.Kind() != reflect.Struct || structType == goBigIntType {
+		return literal
+	}
+	typeName, err := g.typeName(structType, "")
+	if err != nil {
+		return literal
+	}
+	return strings.TrimPrefix(literal, prefix+typeName)
+}
+
+func (g *goWriter) bigInt(value *big.Int) string {
+	g.imports["math/big"] = true
+	if value.IsInt64() {
+		return fmt.Sprintf("big.NewInt(%d)", value.Int64())
+	}
+	return fmt.Sprintf("func() *big.Int { v, _ := new(big.Int).SetString(%s, 10); return v }()", strconv.Quote(value.String()))
+}
+
+// typeName writes a type as generated code references it, e.g. "[]*mj.Account".
+func (g *goWriter) typeName(t reflect.Type, path string) (string, error) {
+	if len(t.Name()) > 0 {
+		if len(t.PkgPath()) == 0 {
+			return t.Name(), nil
+		}
+		packageName, known := goPackageNames[t.PkgPath()]
+		if !known {
+			return "", fmt.Errorf("%s cannot be written in Go, it is a %s", path, t)
+		}
+		g.imports[t.PkgPath()] = true
+		return packageName + "." + t.Name(), nil
+	}
+	switch t.Kind() {
+	case reflect.Slice, reflect.Ptr:
+		elemName, err := g.typeName(t.Elem(), path)
+		if err != nil {
+			return "", err
+		}
+		if t.Kind() == reflect.Ptr {
+			return "*" + elemName, nil
+		}
+		return "[]" + elemName, nil
+	case reflect.Array:
+		elemName, err := g.typeName(t.Elem(), path)
+		if err != nil {
+			return "", err
+		}
+		return fmt.Sprintf("[%d]%s", t.Len(), elemName), nil
+	case reflect.Map:
+		keyName, err := g.typeName(t.Key(), path)
+		if err != nil {
+			return "", err
+		}
+		elemName, err := g.typeName(t.Elem(), path)
+		if err != nil {
+			return "", err
+		}
+		return fmt.Sprintf("map[%s]%s", keyName, elemName), nil
+	default:
+		return "", fmt.Errorf("%s cannot be written in Go, it is a %s", path, t)
+	}
+}
+
+// goIdentifier turns a scenario name into camel case, e.g. "deposit twice" into "DepositTwice".
+func goIdentifier(name string, fallback string) string {
+	var sb strings.Builder
+	upper := true
+	for _, r := range name {
+		switch {
+		case (r >= 'a' && r <= 'z') || (r >= 'A' && r <= 'Z') || (r >= '0' && r <= '9' && sb.Len() > 0):
+			if upper {
+				sb.WriteString(strings.ToUpper(string(r)))
+			} else {
+				sb.WriteRune(r)
+			}
+			upper = false
+		default:
+			upper = true
+		}
+	}
+	if sb.Len() == 0 {
+		return fallback
+	}
+	return sb.String()
+}
@@ -0,0 +1,262 @@
+package denalijsonwrite
+
+import (
+	"fmt"
+	"strings"
+
+	mj "github.com/numbatx/gn-vm-util/test-util/denali/json/model"
+	vi "github.com/numbatx/gn-vm-util/test-util/denali/json/valueinterpreter"
+	oj "github.com/numbatx/gn-vm-util/test-util/orderedjson"
+)
+
+// markdownMaxValueLength is the length above which values are summarized in the document, e.g. contract code.
+const markdownMaxValueLength = 66
+
+// ScenarioToMarkdown renders a scenario as a Markdown document, for contract documentation and audit reports:
+// a table of the steps, one of the expected transaction results, and one of the balances of the accounts,
+// as set before the transactions and as checked after them. Values written as raw hex are decoded.
+// The document cannot be parsed back into a scenario.
+func ScenarioToMarkdown(scenario *mj.Scenario) string {
+	w := &markdownWriter{formatter: vi.NewValueFormatter()}
+	w.writeScenario(scenario)
+	return w.String()
+}
+
+type markdownWriter struct {
+	strings.Builder
+	formatter *vi.ValueFormatter
+}
+
+func (w *markdownWriter) writeScenario(scenario *mj.Scenario) {
+	title := scenario.Name
+	if len(title) == 0 {
+		title = "Scenario"
+	}
+	w.WriteString("# " + title + "\n")
+	if len(scenario.Comment) > 0 {
+		w.WriteString("\n" + scenario.Comment + "\n")
+	}
+
+	w.WriteString("\n## Steps\n\n")
+	var rows [][]string
+	for i, step := range scenario.Steps {
+		rows = append(rows, []string{fmt.Sprintf("%d", i), step.StepTypeName(), stepID(step), w.describeStep(step)})
+	}
+	w.writeTable([]string{"#", "Step", "Id", "Description"}, rows)
+
+	rows = nil
+	for i, generalStep := range scenario.Steps {
+		txStep, isTx := generalStep.(*mj.TxStep)
+		if !isTx || txStep.ExpectedResult == nil {
+			continue
+		}
+		expected := txStep.ExpectedResult
+		var out []string
+		for _, value := range expected.Out {
+			out = append(out, w.checkBytes(value))
+		}
+		rows = append(rows, []string{
+			fmt.Sprintf("%d", i),
+			txStep.ID,
+			w.checkBigInt(expected.Status),
+			w.checkBytes(expected.Message),
+			strings.Join(out, ", "),
+		})
+	}
+	if len(rows) > 0 {
+		w.WriteString("\n## Expected results\n\n")
+		w.writeTable([]string{"#", "Id", "Status", "Message", "Out"}, rows)
+	}
+
+	balances := w.balances(scenario)
+	if len(balances) > 0 {
+		w.WriteString("\n## Balances\n\n")
+		w.writeTable([]string{"Account", "Before", "After"}, balances)
+	}
+}
+
+func stepID(step mj.Step) string {
+	if metadata := mj.StepMetadataOf(step); metadata != nil {
+		return metadata.ID
+	}
+	return ""
+}
+
+func (w *markdownWriter) describeStep(generalStep mj.Step) string {
+	switch step := generalStep.(type) {
+	case *mj.ExternalStepsStep:
+		return "runs the steps of " + code(step.Path)
+	case *mj.SetStateStep:
+		return fmt.Sprintf("sets %d accounts", len(step.Accounts))
+	case *mj.CheckStateStep:
+		if step.CheckAccounts == nil {
+			return "checks no accounts"
+		}
+		return fmt.Sprintf("checks %d accounts", len(step.CheckAccounts.Accounts))
+	case *mj.DumpStateStep:
+		return "prints the state"
+	case *mj.TxStep:
+		return w.describeTx(step.Tx)
+	default:
+		return ""
+	}
+}
+
+func (w *markdownWriter) describeTx(tx *mj.Transaction) string {
+	var description string
+	switch tx.Type {
+	case mj.ScDeploy:
+		description = fmt.Sprintf("%s deploys %s%s", code(w.bytes(tx.From)), w.contractCode(tx.Code), w.arguments(tx.Arguments))
+	case mj.ScCall:
+		description = fmt.Sprintf("%s calls %s on %s", code(w.bytes(tx.From)), code(tx.Function+w.arguments(tx.Arguments)), code(w.bytes(tx.To)))
+	case mj.ScQuery:
+		return fmt.Sprintf("queries %s on %s", code(tx.Function+w.arguments(tx.Arguments)), code(w.bytes(tx.To)))
+	case mj.Transfer:
+		return fmt.Sprintf("%s sends %s to %s", code(w.bytes(tx.From)), bigInt(tx.Value), code(w.bytes(tx.To)))
+	case mj.ValidatorReward:
+		return fmt.Sprintf("%s receives a reward of %s", code(w.bytes(tx.To)), bigInt(tx.Value))
+	}
+	if tx.Value.Value != nil && tx.Value.Value.Sign() != 0 {
+		description += ", with value " + bigInt(tx.Value)
+	}
+	return description
+}
+
+func (w *markdownWriter) arguments(arguments []mj.JSONBytesFromTree) string {
+	formatted := make([]string, len(arguments))
+	for i, argument := range arguments {
+		formatted[i] = w.bytesFromTree(argument)
+	}
+	return "(" + strings.Join(formatted, ", ") + ")"
+}
+
+// contractCode names the code file, if loaded from one, the code itself being too long for the document.
+func (w *markdownWriter) contractCode(contractCode mj.JSONBytesFromString) string {
+	if !rawHexValue.MatchString(contractCode.Original) && len(contractCode.Original) <= markdownMaxValueLength {
+		return code(contractCode.Original)
+	}
+	return fmt.Sprintf("%d bytes of code", len(contractCode.Value))
+}
+
+// balances lists the accounts in order of appearance, with the balance that setState steps give them,
+// and the one that the last checkState step checking them expects.
+func (w *markdownWriter) balances(scenario *mj.Scenario) [][]string {
+	var addresses [][]byte
+	before := make(map[string]string)
+	after := make(map[string]string)
+	addAddress := func(address mj.JSONBytesFromString) string {
+		key := string(address.Value)
+		if _, found := before[key]; !found {
+			if _, found := after[key]; !found {
+				addresses = append(addresses, address.Value)
+			}
+		}
+		return key
+	}
+	for _, generalStep := range scenario.Steps {
+		switch step := generalStep.(type) {
+		case *mj.SetStateStep:
+			for _, account := range step.Accounts {
+				before[addAddress(account.Address)] = bigInt(account.Balance)
+			}
+		case *mj.CheckStateStep:
+			if step.CheckAccounts == nil {
+				continue
+			}
+			for _, account := range step.CheckAccounts.Accounts {
+				after[addAddress(account.Address)] = w.checkBigInt(account.Balance)
+			}
+		}
+	}
+
+	rows := make([][]string, len(addresses))
+	for i, address := range addresses {
+		rows[i] = []string{code(w.formatter.Format(address)), before[string(address)], after[string(address)]}
+	}
+	return rows
+}
+
+func (w *markdownWriter) writeTable(header []string, rows [][]string) {
+	w.writeRow(header)
+	separator := make([]string, len(header))
+	for i := range separator {
+		separator[i] = "---"
+	}
+	w.writeRow(separator)
+	for _, row := range rows {
+		w.writeRow(row)
+	}
+}
+
+func (w *markdownWriter) writeRow(cells []string) {
+	w.WriteString("|")
+	for _, cell := range cells {
+		w.WriteString(" " + strings.ReplaceAll(cell, "|", "\\|") + " |")
+	}
+	w.WriteString("\n")
+}
+
+// readable yields the value as written, unless written as raw hex, which is decoded.
+func (w *markdownWriter) readable(original string, value []byte) string {
+	if !rawHexValue.MatchString(original) {
+		return original
+	}
+	return w.decode(value)
+}
+
+// decode yields the readable form of a value, or its length for long values, e.g. contract code.
+func (w *markdownWriter) decode(value []byte) string {
+	formatted := w.formatter.Format(value)
+	if len(formatted) > markdownMaxValueLength {
+		return fmt.Sprintf("%d bytes", len(value))
+	}
+	return formatted
+}
+
+func (w *markdownWriter) bytes(value mj.JSONBytesFromString) string {
+	return w.readable(value.Original, value.Value)
+}
+
+// bytesFromTree decodes values written as lists or maps, which are concatenated.
+func (w *markdownWriter) bytesFromTree(value mj.JSONBytesFromTree) string {
+	if str, isString := value.Original.(*oj.OJsonString); isString {
+		return w.readable(str.Value, value.Value)
+	}
+	return w.decode(value.Value)
+}
+
+func (w *markdownWriter) checkBytes(value mj.JSONCheckBytes) string {
+	switch original := value.Original.(type) {
+	case nil:
+		return ""
+	case *oj.OJsonString:
+		if value.IsStar || value.Matcher != nil {
+			return original.Value
+		}
+		return w.readable(original.Value, value.Value)
+	default:
+		return w.decode(value.Value)
+	}
+}
+
+func (w *markdownWriter) checkBigInt(value mj.JSONCheckBigInt) string {
+	if value.IsStar || value.Matcher != nil || value.Value == nil {
+		return value.Original
+	}
+	return value.Value.String()
+}
+
+func bigInt(value mj.JSONBigInt) string {
+	if value.Value == nil {
+		return "0"
+	}
+	return value.Value.String()
+}
+
+// code writes a value as inline code, unless empty.
+func code(text string) string {
+	if len(text) == 0 {
+		return ""
+	}
+	return "`" + text + "`"
+}
@@ -0,0 +1,92 @@
+package denalijsonwrite
+
+import (
+	mj "github.com/numbatx/gn-vm-util/test-util/denali/json/model"
+	vi "github.com/numbatx/gn-vm-util/test-util/denali/json/valueinterpreter"
+	oj "github.com/numbatx/gn-vm-util/test-util/orderedjson"
+)
+
+// KeyOrder decides the order of the fields in the written scenarios.
+type KeyOrder int
+
+const (
+	// KeyOrderCanonical writes the fields in the order of the format, e.g. "step" first, then the step fields.
+	// Free keys, such as accounts and storage keys, keep the order of the scenario.
+	KeyOrderCanonical KeyOrder = iota
+
+	// KeyOrderAlphabetical writes all fields and free keys in alphabetical order.
+	KeyOrderAlphabetical
+)
+
+// WriterOptions configures the layout of the written scenarios, so that teams can enforce their canonical style.
+// The zero value is the layout of ScenarioToJSONString.
+type WriterOptions struct {
+	// IndentWidth is the number of spaces per indentation level. Defaults to 4.
+	IndentWidth int
+
+	// MaxLineLength, if set, keeps lists of values on one line, e.g. transaction arguments, as long as they fit.
+	// Lists are otherwise broken, one value per line.
+	MaxLineLength int
+
+	KeyOrder KeyOrder
+
+	// CompactSingleValueMaps writes maps holding a single value on one line, e.g. a storage with one key.
+	CompactSingleValueMaps bool
+
+	// ReadableValues writes the values stored as raw hex in a readable form, where there is one,
+	// e.g. "address:owner", "str:token" or decimal numbers, see vi.ValueFormatter. The bytes stay the same.
+	// Values written in any other form are kept as they are.
+	ReadableValues bool
+}
+
+// DefaultWriterOptions is the layout of ScenarioToJSONString.
+var DefaultWriterOptions = WriterOptions{IndentWidth: oj.DefaultIndentWidth}
+
+// CompactWriterOptions is a denser layout, for large generated scenarios.
+var CompactWriterOptions = WriterOptions{
+	IndentWidth:            2,
+	MaxLineLength:          100,
+	CompactSingleValueMaps: true,
+}
+
+// ScenarioToJSONStringWithOptions is ScenarioToJSONString, with a configurable layout.
+// Comments are kept, the values they are attached to are always broken over several lines.
+func ScenarioToJSONStringWithOptions(scenario *mj.Scenario, options WriterOptions) string {
+	jobj, comments := scenarioTree(scenario, options)
+	return oj.JSONStringWithOptions(jobj, comments, options.formatOptions())
+}
+
+// ScenarioToJSONStringPatched writes a scenario as an edit of the file it was parsed from, or of an older version of it:
+// only the values that changed are written anew, the rest of the text keeps its comments and layout,
+// for tools that modify scenarios to produce reviewable diffs, see oj.JSONStringPatched.
+// The options only apply to the values written anew, the key order of the original text is kept.
+func ScenarioToJSONStringPatched(original []byte, scenario *mj.Scenario, options WriterOptions) (string, error) {
+	jobj, comments := scenarioTree(scenario, options)
+	return oj.JSONStringPatched(original, jobj, comments, options.formatOptions())
+}
+
+// scenarioTree yields the tree to write, and its comments.
+func scenarioTree(scenario *mj.Scenario, options WriterOptions) (oj.OJsonObject, *oj.Comments) {
+	jobj := ScenarioToOrderedJSON(scenario)
+	comments := scenario.Comments
+	if options.ReadableValues {
+		// values get rewritten, keys renamed along with the paths of their comments, but the scenario keeps its own
+		jobj = oj.DeepCopy(jobj)
+		comments = comments.Copy()
+		rewriter := &readableRewriter{
+			formatter: vi.NewValueFormatter(),
+			comments:  comments,
+		}
+		rewriter.rewrite(jobj, "", "")
+	}
+	return jobj, comments
+}
+
+func (o WriterOptions) formatOptions() oj.FormatOptions {
+	return oj.FormatOptions{
+		IndentWidth:            o.IndentWidth,
+		MaxLineLength:          o.MaxLineLength,
+		SortKeys:               o.KeyOrder == KeyOrderAlphabetical,
+		CompactSingleValueMaps: o.CompactSingleValueMaps,
+	}
+}
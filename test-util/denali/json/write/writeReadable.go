@@ -0,0 +1,116 @@
+package denalijsonwrite
+
+import (
+	"encoding/hex"
+	"math/big"
+	"regexp"
+
+	vi "github.com/numbatx/gn-vm-util/test-util/denali/json/valueinterpreter"
+	oj "github.com/numbatx/gn-vm-util/test-util/orderedjson"
+)
+
+// rawHexValue matches the values written as raw hex, the only ones rewritten in readable form.
+var rawHexValue = regexp.MustCompile(`^0x([0-9a-fA-F]{2})*$`)
+
+// readableTextFields hold names or free text, not values, so they are left as they are.
+var readableTextFields = map[string]bool{
+	"step":          true,
+	"id":            true,
+	"tags":          true,
+	"comment":       true,
+	"name":          true,
+	"txId":          true,
+	"path":          true,
+	"function":      true,
+	"parameters":    true,
+	"abi":           true,
+	"gasSchedule":   true,
+	"seed":          true,
+	"owner":         true,
+	"license":       true,
+	"flavor":        true,
+	"allowedErrors": true,
+	"prefix":        true,
+	"field":         true,
+	"logs":          true, // as a string, the hash of the logs
+}
+
+// readableNumberFields hold numbers, written in decimal rather than in the form the formatter picks for bytes.
+var readableNumberFields = map[string]bool{
+	"nonce":          true,
+	"balance":        true,
+	"value":          true,
+	"gasLimit":       true,
+	"gasPrice":       true,
+	"status":         true,
+	"gas":            true,
+	"refund":         true,
+	"creatorNonce":   true,
+	"blockTimestamp": true,
+	"blockNonce":     true,
+	"blockRound":     true,
+	"blockEpoch":     true,
+	"count":          true,
+	"priority":       true,
+	"expect":         true, // as a string, the value of an invariant
+}
+
+// readableKeyMaps are the maps keyed by values, e.g. the accounts, keyed by address.
+var readableKeyMaps = map[string]bool{
+	"accounts": true,
+	"storage":  true,
+}
+
+// readableRewriter replaces the raw hex values of a scenario tree by readable forms, see WriterOptions.ReadableValues.
+type readableRewriter struct {
+	formatter *vi.ValueFormatter
+	comments  *oj.Comments
+}
+
+func (r *readableRewriter) rewrite(obj oj.OJsonObject, path string, key string) {
+	switch value := obj.(type) {
+	case *oj.OJsonMap:
+		for _, kvp := range value.OrderedKV {
+			childPath := oj.ChildPath(path, kvp.Key)
+			if readableKeyMaps[key] {
+				if readableKey, isRaw := r.readable(kvp.Key, false); isRaw && !value.KeySet[readableKey] {
+					r.comments.MovePath(childPath, oj.ChildPath(path, readableKey))
+					kvp.Key = readableKey
+					childPath = oj.ChildPath(path, readableKey)
+				}
+			}
+			r.rewriteChild(kvp.Value, childPath, kvp.Key, key == "gasPresets")
+		}
+		value.RefreshKeySet()
+	case *oj.OJsonList:
+		for i, element := range value.AsList() {
+			r.rewriteChild(element, oj.ElementPath(path, i), key, false)
+		}
+	}
+}
+
+func (r *readableRewriter) rewriteChild(obj oj.OJsonObject, path string, key string, isNumber bool) {
+	str, isString := obj.(*oj.OJsonString)
+	if !isString {
+		r.rewrite(obj, path, key)
+		return
+	}
+	if readableTextFields[key] {
+		return
+	}
+	if readable, isRaw := r.readable(str.Value, isNumber || readableNumberFields[key]); isRaw {
+		str.Value = readable
+	}
+}
+
+// readable yields the readable form of a raw hex value, false for values written otherwise.
+func (r *readableRewriter) readable(expression string, isNumber bool) (string, bool) {
+	if !rawHexValue.MatchString(expression) {
+		return "", false
+	}
+	value, _ := hex.DecodeString(expression[2:])
+	if isNumber {
+		return big.NewInt(0).SetBytes(value).String(), true
+	}
+	return r.formatter.Format(value), true
+}
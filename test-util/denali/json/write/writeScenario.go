@@ -1,20 +1,27 @@
 package denalijsonwrite
 
 import (
+	"fmt"
+
 	mj "github.com/numbatx/gn-vm-util/test-util/denali/json/model"
 	oj "github.com/numbatx/gn-vm-util/test-util/orderedjson"
 )
 
 // ScenarioToJSONString converts a scenario object to its JSON representation.
+// Comments from the original file are written back next to the values they were attached to.
 func ScenarioToJSONString(scenario *mj.Scenario) string {
 	jobj := ScenarioToOrderedJSON(scenario)
-	return oj.JSONString(jobj)
+	return oj.JSONStringWithComments(jobj, scenario.Comments)
 }
 
 // ScenarioToOrderedJSON converts a scenario object to an ordered JSON object.
 func ScenarioToOrderedJSON(scenario *mj.Scenario) oj.OJsonObject {
 	scenarioOJ := oj.NewMap()
 
+	if scenario.SchemaVersion > 0 {
+		scenarioOJ.Put("schemaVersion", stringToOJ(fmt.Sprintf("%d", scenario.SchemaVersion)))
+	}
+
 	if len(scenario.Name) > 0 {
 		scenarioOJ.Put("name", stringToOJ(scenario.Name))
 	}
@@ -23,25 +30,106 @@ func ScenarioToOrderedJSON(scenario *mj.Scenario) oj.OJsonObject {
 		scenarioOJ.Put("comment", stringToOJ(scenario.Comment))
 	}
 
+	if scenario.Metadata != nil {
+		scenarioOJ.Put("metadata", metadataToOJ(scenario.Metadata))
+	}
+
+	if scenario.RequiresFormatVersion > 0 {
+		scenarioOJ.Put("requiresFormatVersion", stringToOJ(fmt.Sprintf("%d", scenario.RequiresFormatVersion)))
+	}
+
+	if len(scenario.ABIPath) > 0 {
+		scenarioOJ.Put("abi", stringToOJ(scenario.ABIPath))
+	}
+
+	if scenario.GasSchedule != nil {
+		scenarioOJ.Put("gasSchedule", stringToOJ(scenario.GasSchedule.Original))
+	}
+
+	if scenario.Config != nil {
+		scenarioOJ.Put("config", configToOJ(scenario.Config))
+	}
+
 	if !scenario.CheckGas {
 		ojFalse := oj.OJsonBool(false)
 		scenarioOJ.Put("checkGas", &ojFalse)
 	}
 
+	if scenario.AutoNonces {
+		ojTrue := oj.OJsonBool(true)
+		scenarioOJ.Put("autoNonces", &ojTrue)
+	}
+
+	if len(scenario.Constants) > 0 {
+		constantsOJ := oj.NewMap()
+		for _, constant := range scenario.Constants {
+			constantsOJ.Put(constant.Name, bytesFromTreeToOJ(constant.Value))
+		}
+		scenarioOJ.Put("constants", constantsOJ)
+	}
+
+	if len(scenario.Defines) > 0 {
+		definesOJ := oj.NewMap()
+		for _, define := range scenario.Defines {
+			definesOJ.Put(define.Name, bytesFromTreeToOJ(define.Value))
+		}
+		scenarioOJ.Put("defines", definesOJ)
+	}
+
+	if len(scenario.Parameters) > 0 {
+		var parameterNames []string
+		for _, parameter := range scenario.Parameters {
+			parameterNames = append(parameterNames, parameter.Name)
+		}
+		scenarioOJ.Put("parameters", stringListToOJ(parameterNames))
+	}
+
+	if len(scenario.GasPresets) > 0 {
+		gasPresetsOJ := oj.NewMap()
+		for _, preset := range scenario.GasPresets {
+			gasPresetsOJ.Put(preset.Name, uint64ToOJ(preset.Value))
+		}
+		scenarioOJ.Put("gasPresets", gasPresetsOJ)
+	}
+
+	if len(scenario.Invariants) > 0 {
+		scenarioOJ.Put("invariants", invariantsToOJ(scenario.Invariants))
+	}
+
 	var stepOJList []oj.OJsonObject
 
 	for _, generalStep := range scenario.Steps {
 		stepOJ := oj.NewMap()
 		stepOJ.Put("step", stringToOJ(generalStep.StepTypeName()))
+		if metadata := mj.StepMetadataOf(generalStep); metadata != nil {
+			stepMetadataToOJ(metadata, stepOJ)
+		}
 		switch step := generalStep.(type) {
 		case *mj.ExternalStepsStep:
+			if len(step.Comment) > 0 {
+				stepOJ.Put("comment", stringToOJ(step.Comment))
+			}
 			stepOJ.Put("path", stringToOJ(step.Path))
+			if len(step.Arguments) > 0 {
+				argumentsOJ := oj.NewMap()
+				for _, argument := range step.Arguments {
+					argumentsOJ.Put(argument.Name, bytesFromTreeToOJ(argument.Value))
+				}
+				stepOJ.Put("arguments", argumentsOJ)
+			}
 		case *mj.SetStateStep:
 			if len(step.Comment) > 0 {
 				stepOJ.Put("comment", stringToOJ(step.Comment))
 			}
-			if len(step.Accounts) > 0 {
-				stepOJ.Put("accounts", accountsToOJ(step.Accounts))
+			accounts := step.Accounts
+			if step.GenerateAccounts != nil {
+				accounts = explicitAccounts(accounts)
+			}
+			if len(accounts) > 0 {
+				stepOJ.Put("accounts", accountsToOJ(accounts))
+			}
+			if step.GenerateAccounts != nil {
+				stepOJ.Put("generateAccounts", generateAccountsToOJ(step.GenerateAccounts))
 			}
 			if len(step.NewAddressMocks) > 0 {
 				stepOJ.Put("newAddresses", newAddressMocksToOJ(step.NewAddressMocks))
@@ -55,6 +143,9 @@ func ScenarioToOrderedJSON(scenario *mj.Scenario) oj.OJsonObject {
 			if len(step.BlockHashes) > 0 {
 				stepOJ.Put("blockHashes", blockHashesToOJ(step.BlockHashes))
 			}
+			if len(step.AllowedErrors) > 0 {
+				stepOJ.Put("allowedErrors", stringListToOJ(step.AllowedErrors))
+			}
 		case *mj.CheckStateStep:
 			if len(step.Comment) > 0 {
 				stepOJ.Put("comment", stringToOJ(step.Comment))
@@ -86,23 +177,50 @@ func ScenarioToOrderedJSON(scenario *mj.Scenario) oj.OJsonObject {
 	return scenarioOJ
 }
 
+func configToOJ(config *mj.ScenarioConfig) oj.OJsonObject {
+	configOJ := oj.NewMap()
+	if len(config.GasPrice.Original) > 0 {
+		configOJ.Put("gasPrice", uint64ToOJ(config.GasPrice))
+	}
+	if len(config.Caller.Original) > 0 {
+		configOJ.Put("caller", bytesFromStringToOJ(config.Caller))
+	}
+	if len(config.ChainID.Original) > 0 {
+		configOJ.Put("chainID", bytesFromStringToOJ(config.ChainID))
+	}
+	if len(config.Seed) > 0 {
+		configOJ.Put("seed", stringToOJ(config.Seed))
+	}
+	return configOJ
+}
+
 func transactionToScenarioOJ(tx *mj.Transaction) oj.OJsonObject {
 	transactionOJ := oj.NewMap()
-	if tx.Type.HasSender() {
+	if tx.Type.HasSender() && !tx.FromConfig {
 		transactionOJ.Put("from", bytesFromStringToOJ(tx.From))
 	}
+	if tx.Type.HasSender() {
+		if len(tx.Nonce.Original) > 0 {
+			transactionOJ.Put("nonce", uint64ToOJ(tx.Nonce))
+		}
+	}
 	if tx.Type.HasReceiver() {
 		transactionOJ.Put("to", bytesFromStringToOJ(tx.To))
 	}
-	transactionOJ.Put("value", bigIntToOJ(tx.Value))
-	if tx.Type == mj.ScCall {
+	if tx.Type.HasValueAndGas() {
+		transactionOJ.Put("value", bigIntToOJ(tx.Value))
+	}
+	if tx.Type == mj.Transfer && tx.Data.Original != nil {
+		transactionOJ.Put("data", bytesFromTreeToOJ(tx.Data))
+	}
+	if tx.Type == mj.ScCall || tx.Type == mj.ScQuery {
 		transactionOJ.Put("function", stringToOJ(tx.Function))
 	}
 	if tx.Type == mj.ScDeploy {
 		transactionOJ.Put("contractCode", bytesFromStringToOJ(tx.Code))
 	}
 
-	if tx.Type == mj.ScCall || tx.Type == mj.ScDeploy {
+	if tx.Type.IsSmartContractTx() {
 		var argList []oj.OJsonObject
 		for _, arg := range tx.Arguments {
 			argList = append(argList, bytesFromTreeToOJ(arg))
@@ -111,9 +229,20 @@ func transactionToScenarioOJ(tx *mj.Transaction) oj.OJsonObject {
 		transactionOJ.Put("arguments", &argOJ)
 	}
 
-	if tx.Type.IsSmartContractTx() {
+	if tx.Type.IsSmartContractTx() && tx.Type.HasValueAndGas() {
 		transactionOJ.Put("gasLimit", uint64ToOJ(tx.GasLimit))
-		transactionOJ.Put("gasPrice", uint64ToOJ(tx.GasPrice))
+		if !tx.GasPriceConfig {
+			transactionOJ.Put("gasPrice", uint64ToOJ(tx.GasPrice))
+		}
+	}
+	if tx.Type == mj.Transfer {
+		// optional for transfers
+		if len(tx.GasLimit.Original) > 0 {
+			transactionOJ.Put("gasLimit", uint64ToOJ(tx.GasLimit))
+		}
+		if len(tx.GasPrice.Original) > 0 && !tx.GasPriceConfig {
+			transactionOJ.Put("gasPrice", uint64ToOJ(tx.GasPrice))
+		}
 	}
 
 	return transactionOJ
@@ -149,3 +278,48 @@ func blockInfoToOJ(blockInfo *mj.BlockInfo) oj.OJsonObject {
 
 	return blockInfoOJ
 }
+
+func invariantsToOJ(invariants []*mj.Invariant) oj.OJsonObject {
+	invariantsOJ := oj.NewMap()
+	for _, invariant := range invariants {
+		invariantOJ := oj.NewMap()
+		if len(invariant.Comment) > 0 {
+			invariantOJ.Put("comment", stringToOJ(invariant.Comment))
+		}
+		if invariant.IsConstantSum() {
+			var quantitiesOJ []oj.OJsonObject
+			for _, quantity := range invariant.ConstantSum {
+				quantitiesOJ = append(quantitiesOJ, invariantQuantityToOJ(quantity))
+			}
+			quantityList := oj.OJsonList(quantitiesOJ)
+			invariantOJ.Put("constantSum", &quantityList)
+		}
+		if invariant.Value != nil {
+			invariantOJ.Put("value", invariantQuantityToOJ(invariant.Value))
+			invariantOJ.Put("expect", checkBigIntToOJ(invariant.Expect))
+		}
+		invariantsOJ.Put(invariant.Name, invariantOJ)
+	}
+	return invariantsOJ
+}
+
+func invariantQuantityToOJ(quantity *mj.InvariantQuantity) oj.OJsonObject {
+	quantityOJ := oj.NewMap()
+	quantityOJ.Put("account", bytesFromStringToOJ(quantity.Account))
+	quantityOJ.Put("field", stringToOJ(quantity.Field))
+	if len(quantity.Key.Original) > 0 {
+		quantityOJ.Put("key", bytesFromStringToOJ(quantity.Key))
+	}
+	return quantityOJ
+}
+
+// stepMetadataToOJ writes the step id and tags, right after the step type, where readers look for them.
+// The comment is written by each step type, in its usual place.
+func stepMetadataToOJ(metadata *mj.StepMetadata, stepOJ *oj.OJsonMap) {
+	if len(metadata.ID) > 0 {
+		stepOJ.Put("id", stringToOJ(metadata.ID))
+	}
+	if len(metadata.Tags) > 0 {
+		stepOJ.Put("tags", stringListToOJ(metadata.Tags))
+	}
+}
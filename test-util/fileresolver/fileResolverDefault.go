@@ -1,4 +1,4 @@
-package denalifileresolver
+package fileresolver
 
 import (
 	"io/ioutil"
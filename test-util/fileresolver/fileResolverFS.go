@@ -0,0 +1,49 @@
+package fileresolver
+
+import (
+	"io/fs"
+	"path"
+)
+
+var _ FileResolver = (*FSFileResolver)(nil)
+
+// FSFileResolver loads file contents from a file system other than the OS one,
+// e.g. files embedded with go:embed, or the contents of an archive.
+// Paths are slash-separated and relative to the root of the file system, as fs.FS requires.
+type FSFileResolver struct {
+	fsys        fs.FS
+	contextPath string
+}
+
+// NewFSFileResolver yields a new FSFileResolver instance.
+func NewFSFileResolver(fsys fs.FS) *FSFileResolver {
+	return &FSFileResolver{
+		fsys: fsys,
+	}
+}
+
+// Clone creates new instance of the same type.
+func (fr *FSFileResolver) Clone() FileResolver {
+	return &FSFileResolver{
+		fsys:        fr.fsys,
+		contextPath: fr.contextPath,
+	}
+}
+
+// SetContext sets directory where the test runs, to help resolve relative paths.
+func (fr *FSFileResolver) SetContext(contextPath string) {
+	fr.contextPath = contextPath
+}
+
+// ResolveAbsolutePath yields the path within the file system, based on context.
+func (fr *FSFileResolver) ResolveAbsolutePath(value string) string {
+	return path.Join(path.Dir(fr.contextPath), value)
+}
+
+// ResolveFileValue converts a value prefixed with "file:" and replaces it with the file contents.
+func (fr *FSFileResolver) ResolveFileValue(value string) ([]byte, error) {
+	if len(value) == 0 {
+		return []byte{}, nil
+	}
+	return fs.ReadFile(fr.fsys, fr.ResolveAbsolutePath(value))
+}
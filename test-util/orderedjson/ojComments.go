@@ -0,0 +1,163 @@
+package orderedjson
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Comments holds the "//" and "/* */" comments of a JSON document, as written, markers included.
+// They are attached to the values they precede by JSON path, e.g. "steps[3].tx.from",
+// so that they survive a rebuild of the tree, as long as the paths stay the same.
+// The root value has the path "".
+type Comments struct {
+	// Before holds the comments preceding a value, or, for values in maps, preceding their key.
+	Before map[string][]string
+
+	// End holds the comments preceding the closing brace or bracket of a map or list.
+	End map[string][]string
+
+	// Trailing holds the comments after the root value.
+	Trailing []string
+}
+
+// NewComments creates an empty comment set.
+func NewComments() *Comments {
+	return &Comments{
+		Before: make(map[string][]string),
+		End:    make(map[string][]string),
+	}
+}
+
+// IsEmpty returns true if there are no comments.
+func (c *Comments) IsEmpty() bool {
+	return c == nil || (len(c.Before) == 0 && len(c.End) == 0 && len(c.Trailing) == 0)
+}
+
+// Copy yields an independent copy of the comments, to be changed without affecting the original.
+func (c *Comments) Copy() *Comments {
+	result := NewComments()
+	if c == nil {
+		return result
+	}
+	for path, commentLines := range c.Before {
+		result.Before[path] = commentLines
+	}
+	for path, commentLines := range c.End {
+		result.End[path] = commentLines
+	}
+	result.Trailing = c.Trailing
+	return result
+}
+
+// MovePath reattaches the comments of a value, and of the values nested in it, to another path,
+// e.g. when a map key gets renamed.
+func (c *Comments) MovePath(fromPath string, toPath string) {
+	if c == nil {
+		return
+	}
+	movePath(c.Before, fromPath, toPath)
+	movePath(c.End, fromPath, toPath)
+}
+
+func movePath(comments map[string][]string, fromPath string, toPath string) {
+	var moved []string
+	for commentPath := range comments {
+		if commentPath == fromPath ||
+			strings.HasPrefix(commentPath, fromPath+".") ||
+			strings.HasPrefix(commentPath, fromPath+"[") {
+			moved = append(moved, commentPath)
+		}
+	}
+	for _, commentPath := range moved {
+		commentLines := comments[commentPath]
+		delete(comments, commentPath)
+		comments[toPath+commentPath[len(fromPath):]] = commentLines
+	}
+}
+
+// ChildPath yields the path of a value in a map.
+func ChildPath(path string, key string) string {
+	if len(path) == 0 {
+		return key
+	}
+	return path + "." + key
+}
+
+// ElementPath yields the path of a value in a list.
+func ElementPath(path string, index int) string {
+	return fmt.Sprintf("%s[%d]", path, index)
+}
+
+// sourceComment is a comment found by extractComments, at the given offset.
+type sourceComment struct {
+	offset int
+	text   string
+}
+
+// extractComments finds the comments outside strings and blanks them out, keeping line breaks,
+// so that the parser only sees JSON, at unchanged positions.
+// The input is only copied if it contains comments.
+func extractComments(input []byte) ([]byte, []sourceComment, error) {
+	var comments []sourceComment
+	output := input
+	inString := false
+	for i := 0; i < len(input); i++ {
+		c := input[i]
+		if inString {
+			if c == '"' && input[i-1] != '\\' {
+				inString = false
+			}
+			continue
+		}
+		if c == '"' {
+			inString = true
+			continue
+		}
+		if c != '/' || i+1 >= len(input) || (input[i+1] != '/' && input[i+1] != '*') {
+			continue
+		}
+
+		end := 0
+		if input[i+1] == '/' {
+			end = len(input)
+			if lineEnd := strings.IndexByte(string(input[i:]), '\n'); lineEnd >= 0 {
+				end = i + lineEnd
+			}
+		} else {
+			blockEnd := strings.Index(string(input[i+2:]), "*/")
+			if blockEnd < 0 {
+				return nil, nil, newSyntaxError(positionAt(input, i), "unterminated comment")
+			}
+			end = i + 2 + blockEnd + 2
+		}
+
+		if comments == nil {
+			output = append([]byte{}, input...)
+		}
+		comments = append(comments, sourceComment{
+			offset: i,
+			text:   strings.TrimRight(string(input[i:end]), "\r"),
+		})
+		for j := i; j < end; j++ {
+			if output[j] != '\n' {
+				output[j] = ' '
+			}
+		}
+		i = end - 1
+	}
+	return output, comments, nil
+}
+
+// positionAt is only used for errors, the parser keeps track of positions as it goes.
+func positionAt(input []byte, offset int) Position {
+	pos := Position{Offset: offset, Line: 1, Column: 1}
+	for _, c := range input[:offset] {
+		if c == '\n' {
+			pos.Line++
+			pos.Column = 1
+		} else {
+			pos.Column++
+		}
+	}
+	return pos
+}
@@ -0,0 +1,102 @@
+package orderedjson
+
+import (
+	"bytes"
+	"errors"
+	"strings"
+)
+
+// Elements splits a document whose root is a list into one document per element, as if each was parsed alone.
+// Positions still refer to the whole input. Yields false if the root is not a list.
+func (d *Document) Elements() ([]*Document, bool) {
+	list, isList := d.Root.(*OJsonList)
+	if !isList {
+		return nil, false
+	}
+	elements := make([]*Document, len(list.AsList()))
+	for i, element := range list.AsList() {
+		elementPath := ElementPath("", i)
+		elementDocument := &Document{
+			Root:      element,
+			Positions: d.Positions,
+			Comments:  d.Comments.subtree(elementPath),
+		}
+		for _, duplicate := range d.DuplicateKeys {
+			if relativePath, isInElement := relativeTo(duplicate.Path, elementPath); isInElement {
+				rebased := *duplicate
+				rebased.Path = relativePath
+				elementDocument.DuplicateKeys = append(elementDocument.DuplicateKeys, &rebased)
+			}
+		}
+		elements[i] = elementDocument
+	}
+	return elements, true
+}
+
+// ParseDocumentLines parses newline delimited JSON (NDJSON), one document per line. Blank lines are skipped.
+// Positions, including those of syntax errors, refer to the whole input, not to the line.
+func ParseDocumentLines(input []byte) ([]*Document, error) {
+	var documents []*Document
+	offset := 0
+	for lineIndex, line := range bytes.SplitAfter(input, []byte("\n")) {
+		lineOffset := offset
+		offset += len(line)
+		if len(bytes.TrimSpace(line)) == 0 {
+			continue
+		}
+		document, err := ParseDocument(line)
+		if err != nil {
+			var syntaxErr *SyntaxError
+			if errors.As(err, &syntaxErr) {
+				return nil, newSyntaxError(shiftPosition(syntaxErr.Position, lineIndex, lineOffset), syntaxErr.Message)
+			}
+			return nil, err
+		}
+		for obj, position := range document.Positions {
+			document.Positions[obj] = shiftPosition(position, lineIndex, lineOffset)
+		}
+		documents = append(documents, document)
+	}
+	return documents, nil
+}
+
+func shiftPosition(position Position, lines int, offset int) Position {
+	return Position{
+		Offset: position.Offset + offset,
+		Line:   position.Line + lines,
+		Column: position.Column,
+	}
+}
+
+// subtree yields the comments of a value and of the values nested in it, as if it were the root.
+func (c *Comments) subtree(path string) *Comments {
+	result := NewComments()
+	if c == nil {
+		return result
+	}
+	for commentPath, commentLines := range c.Before {
+		if relativePath, isInSubtree := relativeTo(commentPath, path); isInSubtree {
+			result.Before[relativePath] = commentLines
+		}
+	}
+	for commentPath, commentLines := range c.End {
+		if relativePath, isInSubtree := relativeTo(commentPath, path); isInSubtree {
+			result.End[relativePath] = commentLines
+		}
+	}
+	return result
+}
+
+// relativeTo yields "b[1]" for "[0].b[1]" relative to "[0]", if the path is the base path or nested in it.
+func relativeTo(path string, basePath string) (string, bool) {
+	if path == basePath {
+		return "", true
+	}
+	if strings.HasPrefix(path, basePath+"[") {
+		return path[len(basePath):], true
+	}
+	if strings.HasPrefix(path, basePath+".") {
+		return path[len(basePath)+1:], true
+	}
+	return "", false
+}
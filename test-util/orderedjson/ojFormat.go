@@ -0,0 +1,119 @@
+package orderedjson
+
+import (
+	"sort"
+	"strings"
+)
+
+// DefaultIndentWidth is the number of spaces per indentation level, unless configured otherwise.
+const DefaultIndentWidth = 4
+
+// FormatOptions configures the layout of the JSON output. The zero value is the layout of JSONString.
+type FormatOptions struct {
+	// IndentWidth is the number of spaces per indentation level. Defaults to DefaultIndentWidth.
+	IndentWidth int
+
+	// MaxLineLength, if set, lets lists of strings and booleans stay on one line, as long as it fits.
+	// Lists are otherwise broken, one element per line.
+	MaxLineLength int
+
+	// SortKeys writes map keys in alphabetical order, instead of the order of the tree.
+	SortKeys bool
+
+	// CompactSingleValueMaps writes maps with a single string or boolean value on one line,
+	// e.g. {"key": "value"}, as long as it fits within MaxLineLength, if set.
+	CompactSingleValueMaps bool
+}
+
+func (w *jsonWriter) indentWidth() int {
+	if w.options.IndentWidth <= 0 {
+		return DefaultIndentWidth
+	}
+	return w.options.IndentWidth
+}
+
+// orderedKV yields the entries of a map in the order they are written.
+func (w *jsonWriter) orderedKV(j *OJsonMap) []*OJsonKeyValuePair {
+	if !w.options.SortKeys {
+		return j.OrderedKV
+	}
+	sorted := make([]*OJsonKeyValuePair, len(j.OrderedKV))
+	copy(sorted, j.OrderedKV)
+	sort.SliceStable(sorted, func(i, k int) bool {
+		return sorted[i].Key < sorted[k].Key
+	})
+	return sorted
+}
+
+// column yields the length of the line being written so far.
+func (w *jsonWriter) column() int {
+	written := w.String()
+	return len(written) - strings.LastIndexByte(written, '\n') - 1
+}
+
+// fits returns true if text can be appended to the current line, within MaxLineLength.
+// Anything fits if no maximum is configured, the trailing comma is accounted for.
+func (w *jsonWriter) fits(text string) bool {
+	return w.options.MaxLineLength <= 0 || w.column()+len(text)+1 <= w.options.MaxLineLength
+}
+
+// hasInnerComments returns true if comments are attached to the children of a value, or to its end.
+func (w *jsonWriter) hasInnerComments(path string) bool {
+	if w.comments == nil {
+		return false
+	}
+	return len(w.end(path)) > 0 ||
+		hasCommentsBelow(w.comments.Before, path) ||
+		hasCommentsBelow(w.comments.End, path)
+}
+
+func hasCommentsBelow(comments map[string][]string, path string) bool {
+	for commentPath := range comments {
+		if relativePath, isInSubtree := relativeTo(commentPath, path); isInSubtree && len(relativePath) > 0 {
+			return true
+		}
+	}
+	return false
+}
+
+// inlineScalar yields the JSON of strings and booleans, which are always written on one line.
+func inlineScalar(j OJsonObject) (string, bool) {
+	switch j.(type) {
+	case *OJsonString, *OJsonBool:
+		w := &jsonWriter{}
+		j.writeJSON(w, 0, "")
+		return w.String(), true
+	default:
+		return "", false
+	}
+}
+
+// inlineList yields the list on one line, if the options allow it.
+func (w *jsonWriter) inlineList(j *OJsonList, path string) (string, bool) {
+	if w.options.MaxLineLength <= 0 || w.hasInnerComments(path) {
+		return "", false
+	}
+	elements := make([]string, len(j.AsList()))
+	for i, element := range j.AsList() {
+		var isScalar bool
+		elements[i], isScalar = inlineScalar(element)
+		if !isScalar {
+			return "", false
+		}
+	}
+	inline := "[" + strings.Join(elements, ", ") + "]"
+	return inline, w.fits(inline)
+}
+
+// inlineMap yields a single-value map on one line, if the options allow it.
+func (w *jsonWriter) inlineMap(j *OJsonMap, path string) (string, bool) {
+	if !w.options.CompactSingleValueMaps || len(j.OrderedKV) != 1 || w.hasInnerComments(path) {
+		return "", false
+	}
+	value, isScalar := inlineScalar(j.OrderedKV[0].Value)
+	if !isScalar {
+		return "", false
+	}
+	inline := "{\"" + j.OrderedKV[0].Key + "\": " + value + "}"
+	return inline, w.fits(inline)
+}
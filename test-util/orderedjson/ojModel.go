@@ -1,12 +1,8 @@
 package orderedjson
 
-import (
-	"strings"
-)
-
 // OJsonObject is an ordered JSON tree object interface.
 type OJsonObject interface {
-	writeJSON(sb *strings.Builder, indent int)
+	writeJSON(w *jsonWriter, indent int, path string)
 }
 
 // OJsonKeyValuePair is a key-value pair in a JSON map.
@@ -66,3 +62,29 @@ func (j *OJsonMap) RefreshKeySet() {
 func (j *OJsonList) AsList() []OJsonObject {
 	return []OJsonObject(*j)
 }
+
+// DeepCopy yields a copy of a tree that shares no nodes with it, to be changed without affecting the original.
+func DeepCopy(obj OJsonObject) OJsonObject {
+	switch value := obj.(type) {
+	case *OJsonMap:
+		result := NewMap()
+		for _, kv := range value.OrderedKV {
+			result.Put(kv.Key, DeepCopy(kv.Value))
+		}
+		return result
+	case *OJsonList:
+		elements := make([]OJsonObject, len(value.AsList()))
+		for i, element := range value.AsList() {
+			elements[i] = DeepCopy(element)
+		}
+		result := OJsonList(elements)
+		return &result
+	case *OJsonString:
+		return &OJsonString{Value: value.Value}
+	case *OJsonBool:
+		result := *value
+		return &result
+	default:
+		return obj
+	}
+}
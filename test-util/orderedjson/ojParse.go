@@ -2,7 +2,6 @@ package orderedjson
 
 import (
 	"bytes"
-	"errors"
 	"strings"
 )
 
@@ -15,6 +14,7 @@ type jsonParserStateAnyObjPlaceholder struct {
 type jsonParserStateSingleValue struct {
 	buffer       bytes.Buffer
 	stringEscape bool
+	start        Position
 }
 
 type jsonParserStateMap struct {
@@ -35,13 +35,86 @@ func isWhitespace(c byte) bool {
 	return c == ' ' || c == '\n' || c == '\r' || c == '\t'
 }
 
-// ParseOrderedJSON parses JSON preserving order in maps
+// Document is a parsed JSON input, together with what the tree alone does not hold.
+type Document struct {
+	Root      OJsonObject
+	Positions Positions
+	Comments  *Comments
+
+	// DuplicateKeys lists the keys that appear more than once in the same map, in document order.
+	// The maps keep the first value, see OJsonMap.Put.
+	DuplicateKeys []*DuplicateKey
+}
+
+// DuplicateKey is a repeated key of a map, with the value that the map did not keep.
+type DuplicateKey struct {
+	Map *OJsonMap
+
+	// Path is the JSON path of the key, the same for all its occurrences, see Comments.
+	Path string
+
+	Key   string
+	Value OJsonObject
+}
+
+// ParseOrderedJSON parses JSON preserving order in maps. Comments are allowed, but dropped.
+// Invalid JSON yields a *SyntaxError.
 func ParseOrderedJSON(input []byte) (OJsonObject, error) {
+	document, err := ParseDocument(input)
+	if err != nil {
+		return nil, err
+	}
+	return document.Root, nil
+}
+
+// ParseOrderedJSONWithPositions is ParseOrderedJSON, also yielding where each value starts,
+// so that errors found later, when interpreting the tree, can point to the input.
+func ParseOrderedJSONWithPositions(input []byte) (OJsonObject, Positions, error) {
+	document, err := ParseDocument(input)
+	if err != nil {
+		return nil, nil, err
+	}
+	return document.Root, document.Positions, nil
+}
+
+// ParseDocument is ParseOrderedJSON, also yielding the positions of the values and the comments,
+// see JSONStringWithComments for writing them back.
+func ParseDocument(input []byte) (*Document, error) {
+	input, sourceComments, err := extractComments(input)
+	if err != nil {
+		return nil, err
+	}
+	comments := NewComments()
+	nextComment := 0
+	var pendingComments []string
+	attachComments := func(target map[string][]string, path string) {
+		if len(pendingComments) > 0 {
+			target[path] = append(target[path], pendingComments...)
+			pendingComments = nil
+		}
+	}
+
 	stateStack := &jsonParserStateStack{}
 	stateStack.push(&jsonParserStateAnyObjPlaceholder{})
 	var pendingResult OJsonObject
+	var duplicateKeys []*DuplicateKey
+	positions := make(Positions)
+	pos := Position{Line: 1, Column: 1}
 
 	for i, c := range input {
+		pos.Offset = i
+		if i > 0 {
+			if input[i-1] == '\n' {
+				pos.Line++
+				pos.Column = 1
+			} else {
+				pos.Column++
+			}
+		}
+		for nextComment < len(sourceComments) && sourceComments[nextComment].offset < i {
+			pendingComments = append(pendingComments, sourceComments[nextComment].text)
+			nextComment++
+		}
 		done := false
 		for !done {
 			done = true
@@ -50,7 +123,7 @@ func ParseOrderedJSON(input []byte) (OJsonObject, error) {
 				if isWhitespace(c) {
 					continue
 				} else {
-					return nil, errors.New("unexpected characters at the end")
+					return nil, newSyntaxError(pos, "unexpected characters at the end")
 				}
 			}
 
@@ -58,21 +131,28 @@ func ParseOrderedJSON(input []byte) (OJsonObject, error) {
 			switch specificState := state.(type) {
 			case *jsonParserStateAnyObjPlaceholder:
 				if pendingResult != nil {
-					return nil, errors.New("invalid state")
+					return nil, newSyntaxError(pos, "invalid state")
 				}
 				if isWhitespace(c) {
 					// leading whitespace, ignore
 				} else if c == '{' {
+					attachComments(comments.Before, stateStack.path())
 					// replace with map state
-					stateStack.replaceTop(&jsonParserStateMap{currentMap: NewMap()})
+					mapState := &jsonParserStateMap{currentMap: NewMap()}
+					positions[mapState.currentMap] = pos
+					stateStack.replaceTop(mapState)
 				} else if c == '[' {
+					attachComments(comments.Before, stateStack.path())
 					// replace with list state
-					stateStack.replaceTop(&jsonParserStateList{})
+					listState := &jsonParserStateList{}
+					positions[&listState.list] = pos
+					stateStack.replaceTop(listState)
 				} else if c == ']' || c == '}' || c == ',' {
-					return nil, errors.New("misplaced character")
+					return nil, newSyntaxError(pos, "misplaced character")
 				} else {
+					attachComments(comments.Before, stateStack.path())
 					// replace with single value
-					stateStack.replaceTop(&jsonParserStateSingleValue{})
+					stateStack.replaceTop(&jsonParserStateSingleValue{start: pos})
 					done = false
 				}
 			case *jsonParserStateSingleValue:
@@ -86,7 +166,7 @@ func ParseOrderedJSON(input []byte) (OJsonObject, error) {
 						if c == '"' && prevChar != '\\' {
 							stateStack.pop()
 							var err error
-							pendingResult, err = specificState.finalize()
+							pendingResult, err = specificState.finalize(positions)
 							if err != nil {
 								return nil, err
 							}
@@ -95,7 +175,7 @@ func ParseOrderedJSON(input []byte) (OJsonObject, error) {
 						if c == ']' || c == '}' || c == ',' || isWhitespace(c) {
 							stateStack.pop()
 							var err error
-							pendingResult, err = specificState.finalize()
+							pendingResult, err = specificState.finalize(positions)
 							if err != nil {
 								return nil, err
 							}
@@ -114,8 +194,9 @@ func ParseOrderedJSON(input []byte) (OJsonObject, error) {
 					// ignore
 				} else {
 					if c == ']' {
-						pendingResult = &specificState.list
 						stateStack.pop()
+						attachComments(comments.End, stateStack.path())
+						pendingResult = &specificState.list
 					} else if len(specificState.list) == 0 {
 						// new empty list
 						stateStack.push(&jsonParserStateAnyObjPlaceholder{})
@@ -128,6 +209,7 @@ func ParseOrderedJSON(input []byte) (OJsonObject, error) {
 				if isWhitespace(c) {
 					// ignore
 				} else if c == '}' {
+					attachComments(comments.End, stateStack.path())
 					pendingResult = specificState.currentMap
 					stateStack.pop()
 				} else if c == ',' {
@@ -136,7 +218,7 @@ func ParseOrderedJSON(input []byte) (OJsonObject, error) {
 					stateStack.push(&jsonStateMapKeyValue{})
 					done = false
 				} else {
-					return nil, errors.New("invalid map state")
+					return nil, newSyntaxError(pos, "invalid map state")
 				}
 			case *jsonStateMapKeyValue:
 				switch specificState.state {
@@ -146,7 +228,7 @@ func ParseOrderedJSON(input []byte) (OJsonObject, error) {
 							// ignore
 						} else {
 							if c != '"' {
-								return nil, errors.New("map key must start with a quote")
+								return nil, newSyntaxError(pos, "map key must start with a quote")
 							}
 							specificState.keyBuffer.WriteByte(c)
 						}
@@ -155,6 +237,7 @@ func ParseOrderedJSON(input []byte) (OJsonObject, error) {
 						prevChar := input[i-1]
 						if c == '"' && prevChar != '\\' {
 							specificState.state = 1
+							attachComments(comments.Before, stateStack.path())
 						}
 					}
 				case 1: // ':'
@@ -164,56 +247,87 @@ func ParseOrderedJSON(input []byte) (OJsonObject, error) {
 						specificState.state = 2
 						stateStack.push(&jsonParserStateAnyObjPlaceholder{})
 					} else {
-						return nil, errors.New("invalid character in map definition, colon expected")
+						return nil, newSyntaxError(pos, "invalid character in map definition, colon expected")
 					}
 				case 2: // value
 					if pendingResult == nil {
-						return nil, errors.New("missing value in map")
+						return nil, newSyntaxError(pos, "missing value in map")
 					}
 					key := specificState.keyBuffer.String()
 					if !strings.HasPrefix(key, "\"") || !strings.HasSuffix(key, "\"") {
-						return nil, errors.New("map key should be a string enclosed in quotes")
+						return nil, newSyntaxError(pos, "map key should be a string enclosed in quotes")
 					}
 					key = key[1 : len(key)-1]
+					keyPath := stateStack.path()
 					stateStack.pop()
 					mapState, isMap := stateStack.peek().(*jsonParserStateMap)
 					if !isMap {
-						return nil, errors.New("map key value state, but no map state underneath")
+						return nil, newSyntaxError(pos, "map key value state, but no map state underneath")
+					}
+					if mapState.currentMap.KeySet[key] {
+						duplicateKeys = append(duplicateKeys, &DuplicateKey{
+							Map:   mapState.currentMap,
+							Path:  keyPath,
+							Key:   key,
+							Value: pendingResult,
+						})
 					}
 					mapState.currentMap.Put(key, pendingResult)
 					pendingResult = nil
 					done = false
 				default:
-					return nil, errors.New("unknown jsonStateMapKeyValue state")
+					return nil, newSyntaxError(pos, "unknown jsonStateMapKeyValue state")
 				}
 			default:
-				return nil, errors.New("invalid parser state")
+				return nil, newSyntaxError(pos, "invalid parser state")
 			}
 		}
 	}
 
 	if stateStack.size() != 0 {
-		return nil, errors.New("state stack should be empty at the end")
+		// points right after the last character
+		if len(input) > 0 {
+			pos.Offset = len(input)
+			if input[len(input)-1] == '\n' {
+				pos.Line++
+				pos.Column = 1
+			} else {
+				pos.Column++
+			}
+		}
+		return nil, newSyntaxError(pos, "unexpected end of input")
 	}
 
-	return pendingResult, nil
+	for ; nextComment < len(sourceComments); nextComment++ {
+		pendingComments = append(pendingComments, sourceComments[nextComment].text)
+	}
+	comments.Trailing = pendingComments
+
+	return &Document{
+		Root:          pendingResult,
+		Positions:     positions,
+		Comments:      comments,
+		DuplicateKeys: duplicateKeys,
+	}, nil
 }
 
-func (s *jsonParserStateSingleValue) finalize() (OJsonObject, error) {
+func (s *jsonParserStateSingleValue) finalize(positions Positions) (OJsonObject, error) {
+	var result OJsonObject
 	str := s.buffer.String()
-	if strings.HasPrefix(str, "\"") && strings.HasSuffix(str, "\"") {
-		str = str[1 : len(str)-1]
-		return &OJsonString{Value: str}, nil
-	}
-	if str == "true" {
-		result := OJsonBool(true)
-		return &result, nil
-	}
-	if str == "false" {
-		result := OJsonBool(false)
-		return &result, nil
+	switch {
+	case strings.HasPrefix(str, "\"") && strings.HasSuffix(str, "\""):
+		result = &OJsonString{Value: str[1 : len(str)-1]}
+	case str == "true":
+		boolResult := OJsonBool(true)
+		result = &boolResult
+	case str == "false":
+		boolResult := OJsonBool(false)
+		result = &boolResult
+	default:
+		return nil, newSyntaxError(s.start, "Invalid value: "+str)
 	}
-	return nil, errors.New("Invalid value: " + str)
+	positions[result] = s.start
+	return result, nil
 }
 
 type jsonParserStateStack struct {
@@ -238,6 +352,21 @@ func (s *jsonParserStateStack) pop() jsonParserState {
 	return top
 }
 
+// path yields the JSON path of the value being parsed, see Comments.
+func (s *jsonParserStateStack) path() string {
+	path := ""
+	for _, state := range s.stack {
+		switch specificState := state.(type) {
+		case *jsonParserStateList:
+			path = ElementPath(path, len(specificState.list))
+		case *jsonStateMapKeyValue:
+			key := specificState.keyBuffer.String()
+			path = ChildPath(path, strings.Trim(key, "\""))
+		}
+	}
+	return path
+}
+
 func (s *jsonParserStateStack) size() int {
 	return len(s.stack)
 }
@@ -0,0 +1,353 @@
+package orderedjson
+
+import (
+	"sort"
+	"strings"
+)
+
+// JSONStringPatched writes an updated tree as an edit of the JSON text the original tree was parsed from:
+// only the values that changed get written anew, the rest of the text stays as it is, including comments,
+// spacing, and the order of the keys, so that tools rewriting files produce reviewable diffs.
+// New keys are appended to their maps. The options, and the comments of the updated tree,
+// only apply to the values written anew.
+func JSONStringPatched(original []byte, updated OJsonObject, comments *Comments, options FormatOptions) (string, error) {
+	document, err := ParseDocument(original)
+	if err != nil {
+		return "", err
+	}
+	// comments are blanked out, so that they do not get in the way of finding where values end
+	input, _, err := extractComments(original)
+	if err != nil {
+		return "", err
+	}
+	p := &jsonPatcher{
+		input:     input,
+		positions: document.Positions,
+		comments:  comments,
+		options:   options,
+	}
+	p.patchValue(document.Root, updated, "")
+	return p.apply(original), nil
+}
+
+// jsonPatcher collects the edits turning the original text into the updated tree.
+type jsonPatcher struct {
+	input     []byte
+	positions Positions
+	comments  *Comments
+	options   FormatOptions
+	edits     []*textEdit
+}
+
+// textEdit replaces the original text between two offsets. Insertions have the same start and end.
+type textEdit struct {
+	start int
+	end   int
+	text  string
+}
+
+// textSpan is where a list element, or a map entry, including its key, is written in the original text.
+type textSpan struct {
+	start int
+	end   int
+}
+
+func (p *jsonPatcher) patchValue(old OJsonObject, updated OJsonObject, path string) {
+	if equalJSON(old, updated) {
+		return
+	}
+	switch oldValue := old.(type) {
+	case *OJsonMap:
+		if updatedMap, isMap := updated.(*OJsonMap); isMap {
+			p.patchMap(oldValue, updatedMap, path)
+			return
+		}
+	case *OJsonList:
+		if updatedList, isList := updated.(*OJsonList); isList {
+			p.patchList(oldValue, updatedList, path)
+			return
+		}
+	}
+	p.replaceValue(old, updated, path)
+}
+
+// patchMap patches the values of the keys present in both maps, removes the missing keys, and appends the new ones.
+func (p *jsonPatcher) patchMap(old *OJsonMap, updated *OJsonMap, path string) {
+	if old.Size() == 0 {
+		p.replaceValue(old, updated, path)
+		return
+	}
+	spans := make([]textSpan, len(old.OrderedKV))
+	removed := make([]bool, len(old.OrderedKV))
+	lastKept := -1
+	for i, kv := range old.OrderedKV {
+		valueStart := p.positions[kv.Value].Offset
+		spans[i] = textSpan{start: p.keyStart(valueStart), end: p.valueEnd(valueStart)}
+		removed[i] = !updated.KeySet[kv.Key]
+		if !removed[i] {
+			lastKept = i
+		}
+	}
+	if lastKept < 0 {
+		p.replaceValue(old, updated, path)
+		return
+	}
+
+	for i, kv := range old.OrderedKV {
+		if removed[i] {
+			continue
+		}
+		updatedValue, _ := mapValue(updated, kv.Key)
+		p.patchValue(kv.Value, updatedValue, ChildPath(path, kv.Key))
+	}
+	for first := 0; first < len(removed); first++ {
+		if !removed[first] {
+			continue
+		}
+		last := first
+		for last+1 < len(removed) && removed[last+1] {
+			last++
+		}
+		p.removeSpans(spans, first, last)
+		first = last
+	}
+
+	var added []string
+	for _, kv := range updated.OrderedKV {
+		if !old.KeySet[kv.Key] {
+			entryStart := spans[lastKept].start
+			added = append(added, quoteKey(kv.Key)+": "+p.render(kv.Value, ChildPath(path, kv.Key), entryStart))
+		}
+	}
+	if len(added) > 0 {
+		p.insertAfter(old, spans, lastKept, added)
+	}
+}
+
+// patchList keeps the elements that did not change at both ends of the list, patches the changed elements
+// in between, pairwise, then removes or inserts the elements that only one of the lists has.
+func (p *jsonPatcher) patchList(old *OJsonList, updated *OJsonList, path string) {
+	oldElements, updatedElements := old.AsList(), updated.AsList()
+	if len(oldElements) == 0 || len(updatedElements) == 0 {
+		p.replaceValue(old, updated, path)
+		return
+	}
+	prefix := 0
+	for prefix < len(oldElements) && prefix < len(updatedElements) &&
+		equalJSON(oldElements[prefix], updatedElements[prefix]) {
+		prefix++
+	}
+	suffix := 0
+	for suffix < len(oldElements)-prefix && suffix < len(updatedElements)-prefix &&
+		equalJSON(oldElements[len(oldElements)-1-suffix], updatedElements[len(updatedElements)-1-suffix]) {
+		suffix++
+	}
+	oldEnd, updatedEnd := len(oldElements)-suffix, len(updatedElements)-suffix
+	paired := prefix
+	for paired < oldEnd && paired < updatedEnd {
+		p.patchValue(oldElements[paired], updatedElements[paired], ElementPath(path, paired))
+		paired++
+	}
+
+	spans := make([]textSpan, len(oldElements))
+	for i, element := range oldElements {
+		start := p.positions[element].Offset
+		spans[i] = textSpan{start: start, end: p.valueEnd(start)}
+	}
+	if paired < oldEnd {
+		p.removeSpans(spans, paired, oldEnd-1)
+		return
+	}
+	if paired < updatedEnd {
+		var inserted []string
+		for i := paired; i < updatedEnd; i++ {
+			inserted = append(inserted, p.render(updatedElements[i], ElementPath(path, i), spans[0].start))
+		}
+		if paired > 0 {
+			p.insertAfter(old, spans, paired-1, inserted)
+		} else {
+			separator := p.separator(old, spans, 0)
+			p.addEdit(spans[0].start, spans[0].start, strings.Join(inserted, separator)+separator)
+		}
+	}
+}
+
+// removeSpans removes the elements first to last, which are not all of them, along with one of the commas around.
+func (p *jsonPatcher) removeSpans(spans []textSpan, first int, last int) {
+	if first > 0 {
+		p.addEdit(spans[first-1].end, spans[last].end, "")
+	} else {
+		p.addEdit(spans[first].start, spans[last+1].start, "")
+	}
+}
+
+// insertAfter inserts elements, or map entries, after an existing one.
+func (p *jsonPatcher) insertAfter(container OJsonObject, spans []textSpan, index int, inserted []string) {
+	separator := p.separator(container, spans, index)
+	p.addEdit(spans[index].end, spans[index].end, separator+strings.Join(inserted, separator))
+}
+
+// separator yields what comes between the elements of a list, or the entries of a map:
+// a comma and a line break, as the writer does, unless the container is on one line in the original text.
+func (p *jsonPatcher) separator(container OJsonObject, spans []textSpan, index int) string {
+	between := p.input[p.positions[container].Offset:spans[0].start]
+	if len(spans) > 1 {
+		between = p.input[spans[0].end:spans[1].start]
+	}
+	if !strings.Contains(string(between), "\n") {
+		return ", "
+	}
+	return ",\n" + p.lineIndent(spans[index].start)
+}
+
+func (p *jsonPatcher) replaceValue(old OJsonObject, updated OJsonObject, path string) {
+	start := p.positions[old].Offset
+	p.addEdit(start, p.valueEnd(start), p.render(updated, path, start))
+}
+
+func (p *jsonPatcher) addEdit(start int, end int, text string) {
+	p.edits = append(p.edits, &textEdit{start: start, end: end, text: text})
+}
+
+// render writes a value, indented as the line of the original text where it goes.
+func (p *jsonPatcher) render(value OJsonObject, path string, at int) string {
+	// the comments before the value stay where they are in the original text
+	comments := p.comments.subtree(path)
+	delete(comments.Before, "")
+	text := strings.TrimSuffix(JSONStringWithOptions(value, comments, p.options), "\n")
+	return strings.ReplaceAll(text, "\n", "\n"+p.lineIndent(at))
+}
+
+// lineIndent yields the whitespace at the start of the line holding an offset.
+func (p *jsonPatcher) lineIndent(offset int) string {
+	lineStart := strings.LastIndexByte(string(p.input[:offset]), '\n') + 1
+	lineEnd := lineStart
+	for lineEnd < offset && (p.input[lineEnd] == ' ' || p.input[lineEnd] == '\t') {
+		lineEnd++
+	}
+	return string(p.input[lineStart:lineEnd])
+}
+
+// keyStart finds the opening quote of the key of a map value.
+func (p *jsonPatcher) keyStart(valueStart int) int {
+	i := valueStart - 1
+	for i > 0 && (isWhitespace(p.input[i]) || p.input[i] == ':') {
+		i--
+	}
+	// i is at the closing quote of the key
+	for i--; i > 0; i-- {
+		if p.input[i] == '"' && p.input[i-1] != '\\' {
+			break
+		}
+	}
+	return i
+}
+
+// valueEnd finds the offset right after a value, escapes being handled the way the parser does.
+func (p *jsonPatcher) valueEnd(start int) int {
+	input := p.input
+	switch input[start] {
+	case '"':
+		for i := start + 1; i < len(input); i++ {
+			if input[i] == '"' && input[i-1] != '\\' {
+				return i + 1
+			}
+		}
+	case '{', '[':
+		depth := 0
+		inString := false
+		for i := start; i < len(input); i++ {
+			c := input[i]
+			switch {
+			case inString:
+				inString = !(c == '"' && input[i-1] != '\\')
+			case c == '"':
+				inString = true
+			case c == '{' || c == '[':
+				depth++
+			case c == '}' || c == ']':
+				depth--
+				if depth == 0 {
+					return i + 1
+				}
+			}
+		}
+	default:
+		for i := start; i < len(input); i++ {
+			c := input[i]
+			if c == ']' || c == '}' || c == ',' || isWhitespace(c) {
+				return i
+			}
+		}
+	}
+	return len(input)
+}
+
+// apply performs the edits, which never overlap. Insertions come before replacements starting at the same offset.
+func (p *jsonPatcher) apply(original []byte) string {
+	sort.SliceStable(p.edits, func(i, k int) bool {
+		if p.edits[i].start != p.edits[k].start {
+			return p.edits[i].start < p.edits[k].start
+		}
+		return p.edits[i].end < p.edits[k].end
+	})
+	var result strings.Builder
+	offset := 0
+	for _, edit := range p.edits {
+		result.Write(original[offset:edit.start])
+		result.WriteString(edit.text)
+		offset = edit.end
+	}
+	result.Write(original[offset:])
+	return result.String()
+}
+
+func mapValue(j *OJsonMap, key string) (OJsonObject, bool) {
+	for _, kv := range j.OrderedKV {
+		if kv.Key == key {
+			return kv.Value, true
+		}
+	}
+	return nil, false
+}
+
+func quoteKey(key string) string {
+	return "\"" + key + "\""
+}
+
+// equalJSON compares two trees. The order of the keys does not matter, since patching keeps the original one.
+func equalJSON(a OJsonObject, b OJsonObject) bool {
+	switch aValue := a.(type) {
+	case *OJsonMap:
+		bValue, isMap := b.(*OJsonMap)
+		if !isMap || aValue.Size() != bValue.Size() {
+			return false
+		}
+		for _, kv := range aValue.OrderedKV {
+			bChild, found := mapValue(bValue, kv.Key)
+			if !found || !equalJSON(kv.Value, bChild) {
+				return false
+			}
+		}
+		return true
+	case *OJsonList:
+		bValue, isList := b.(*OJsonList)
+		if !isList || len(aValue.AsList()) != len(bValue.AsList()) {
+			return false
+		}
+		for i, element := range aValue.AsList() {
+			if !equalJSON(element, bValue.AsList()[i]) {
+				return false
+			}
+		}
+		return true
+	case *OJsonString:
+		bValue, isString := b.(*OJsonString)
+		return isString && aValue.Value == bValue.Value
+	case *OJsonBool:
+		bValue, isBool := b.(*OJsonBool)
+		return isBool && *aValue == *bValue
+	default:
+		return false
+	}
+}
@@ -0,0 +1,34 @@
+package orderedjson
+
+import "fmt"
+
+// Position locates a character of the JSON input.
+// Lines and columns start at 1, columns count bytes.
+type Position struct {
+	Offset int
+	Line   int
+	Column int
+}
+
+// String yields the line and column, e.g. "line 3, column 12".
+func (p Position) String() string {
+	return fmt.Sprintf("line %d, column %d", p.Line, p.Column)
+}
+
+// Positions holds where each value of a parsed tree starts, see ParseOrderedJSONWithPositions.
+type Positions map[OJsonObject]Position
+
+// SyntaxError is invalid JSON.
+type SyntaxError struct {
+	Position Position
+	Message  string
+}
+
+// Error yields the position and what is wrong.
+func (e *SyntaxError) Error() string {
+	return fmt.Sprintf("%s: %s", e.Position, e.Message)
+}
+
+func newSyntaxError(position Position, message string) *SyntaxError {
+	return &SyntaxError{Position: position, Message: message}
+}
@@ -7,66 +7,149 @@ import (
 
 // JSONString returns a formatted string representation of an ordered JSON
 func JSONString(j OJsonObject) string {
-	var sb strings.Builder
-	j.writeJSON(&sb, 0)
-	sb.WriteString("\n")
-	return sb.String()
+	return JSONStringWithComments(j, nil)
 }
 
-func addIndent(sb *strings.Builder, indent int) {
-	for i := 0; i < indent; i++ {
-		sb.WriteString("    ")
+// JSONStringWithComments is JSONString, with the comments placed back where they were parsed,
+// each on its own line. Comments on paths missing from the tree are dropped.
+func JSONStringWithComments(j OJsonObject, comments *Comments) string {
+	return JSONStringWithOptions(j, comments, FormatOptions{})
+}
+
+// JSONStringWithOptions is JSONStringWithComments, with a configurable layout, see FormatOptions.
+// Values with comments inside are always written one element per line, so that the comments have a place.
+func JSONStringWithOptions(j OJsonObject, comments *Comments, options FormatOptions) string {
+	w := &jsonWriter{options: options}
+	if !comments.IsEmpty() {
+		w.comments = comments
+	}
+	for _, comment := range w.before("") {
+		w.WriteString(comment)
+		w.WriteString("\n")
+	}
+	j.writeJSON(w, 0, "")
+	w.WriteString("\n")
+	if w.comments != nil {
+		for _, comment := range w.comments.Trailing {
+			w.WriteString(comment)
+			w.WriteString("\n")
+		}
+	}
+	return w.String()
+}
+
+// jsonWriter accumulates the output. Paths are only kept track of when there are comments to place.
+type jsonWriter struct {
+	strings.Builder
+	comments *Comments
+	options  FormatOptions
+}
+
+func (w *jsonWriter) childPath(path string, key string) string {
+	if w.comments == nil {
+		return ""
+	}
+	return ChildPath(path, key)
+}
+
+func (w *jsonWriter) elementPath(path string, index int) string {
+	if w.comments == nil {
+		return ""
+	}
+	return ElementPath(path, index)
+}
+
+func (w *jsonWriter) before(path string) []string {
+	if w.comments == nil {
+		return nil
+	}
+	return w.comments.Before[path]
+}
+
+func (w *jsonWriter) end(path string) []string {
+	if w.comments == nil {
+		return nil
+	}
+	return w.comments.End[path]
+}
+
+// writeCommentLines writes each comment on a new line.
+func (w *jsonWriter) writeCommentLines(comments []string, indent int) {
+	for _, comment := range comments {
+		w.WriteString("\n")
+		addIndent(w, indent)
+		w.WriteString(comment)
 	}
 }
 
-func (j *OJsonMap) writeJSON(sb *strings.Builder, indent int) {
-	if j.Size() == 0 {
-		sb.WriteString("{}")
+func addIndent(w *jsonWriter, indent int) {
+	w.WriteString(strings.Repeat(" ", indent*w.indentWidth()))
+}
+
+func (j *OJsonMap) writeJSON(w *jsonWriter, indent int, path string) {
+	endComments := w.end(path)
+	if j.Size() == 0 && len(endComments) == 0 {
+		w.WriteString("{}")
+		return
+	}
+	if inline, isInline := w.inlineMap(j, path); isInline {
+		w.WriteString(inline)
 		return
 	}
 
-	sb.WriteString("{")
-	for i, child := range j.OrderedKV {
-		sb.WriteString("\n")
-		addIndent(sb, indent+1)
-		sb.WriteString("\"")
-		sb.WriteString(child.Key)
-		sb.WriteString("\": ")
-		child.Value.writeJSON(sb, indent+1)
+	w.WriteString("{")
+	for i, child := range w.orderedKV(j) {
+		childPath := w.childPath(path, child.Key)
+		w.writeCommentLines(w.before(childPath), indent+1)
+		w.WriteString("\n")
+		addIndent(w, indent+1)
+		w.WriteString("\"")
+		w.WriteString(child.Key)
+		w.WriteString("\": ")
+		child.Value.writeJSON(w, indent+1, childPath)
 		if i < len(j.OrderedKV)-1 {
-			sb.WriteString(",")
+			w.WriteString(",")
 		}
 	}
-	sb.WriteString("\n")
-	addIndent(sb, indent)
-	sb.WriteString("}")
+	w.writeCommentLines(endComments, indent+1)
+	w.WriteString("\n")
+	addIndent(w, indent)
+	w.WriteString("}")
 }
 
-func (j *OJsonList) writeJSON(sb *strings.Builder, indent int) {
+func (j *OJsonList) writeJSON(w *jsonWriter, indent int, path string) {
 	collection := j.AsList()
-	if len(collection) == 0 {
-		sb.WriteString("[]")
+	endComments := w.end(path)
+	if len(collection) == 0 && len(endComments) == 0 {
+		w.WriteString("[]")
+		return
+	}
+	if inline, isInline := w.inlineList(j, path); isInline {
+		w.WriteString(inline)
 		return
 	}
 
-	sb.WriteString("[")
+	w.WriteString("[")
 	for i, child := range collection {
-		sb.WriteString("\n")
-		addIndent(sb, indent+1)
-		child.writeJSON(sb, indent+1)
+		childPath := w.elementPath(path, i)
+		w.writeCommentLines(w.before(childPath), indent+1)
+		w.WriteString("\n")
+		addIndent(w, indent+1)
+		child.writeJSON(w, indent+1, childPath)
 		if i < len(collection)-1 {
-			sb.WriteString(",")
+			w.WriteString(",")
 		}
 	}
-	sb.WriteString("\n")
-	addIndent(sb, indent)
-	sb.WriteString("]")
+	w.writeCommentLines(endComments, indent+1)
+	w.WriteString("\n")
+	addIndent(w, indent)
+	w.WriteString("]")
 }
 
-func (j *OJsonString) writeJSON(sb *strings.Builder, indent int) {
-	sb.WriteString(fmt.Sprintf("\"%s\"", j.Value))
+func (j *OJsonString) writeJSON(w *jsonWriter, indent int, path string) {
+	w.WriteString(fmt.Sprintf("\"%s\"", j.Value))
 }
 
-func (j *OJsonBool) writeJSON(sb *strings.Builder, indent int) {
-	sb.WriteString(fmt.Sprintf("%v", bool(*j)))
+func (j *OJsonBool) writeJSON(w *jsonWriter, indent int, path string) {
+	w.WriteString(fmt.Sprintf("%v", bool(*j)))
 }